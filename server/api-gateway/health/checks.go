@@ -0,0 +1,28 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// HTTPCheck probes an upstream by requesting url and expecting a 2xx status.
+func HTTPCheck(client *http.Client, url string) CheckFunc {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("%s returned %s", strings.TrimSuffix(url, "/"), resp.Status)
+		}
+		return nil
+	}
+}
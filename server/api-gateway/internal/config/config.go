@@ -0,0 +1,100 @@
+package config
+
+import (
+	"io"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// Config is the complete api-gateway configuration.
+type Config struct {
+	Env  string `env:"APP_ENV" default:"development"`
+	Port string `env:"PORT" default:"8080"`
+
+	// ReadinessTimeout bounds each /readyz dependency check;
+	// ShutdownDrainDelay is how long /readyz fails before the server stops.
+	ReadinessTimeout   time.Duration `env:"READINESS_TIMEOUT" default:"2s"`
+	ShutdownDrainDelay time.Duration `env:"SHUTDOWN_DRAIN_DELAY" default:"5s"`
+
+	AuthServiceURL    string `env:"AUTH_SERVICE_URL" default:"http://localhost:8083"`
+	PaymentServiceURL string `env:"PAYMENT_SERVICE_URL" default:"http://localhost:8084"`
+
+	CORSAllowedOrigins []string `env:"CORS_ALLOWED_ORIGINS" default:"http://localhost:3000"`
+
+	LogLevel        string   `env:"LOG_LEVEL" default:"info"`
+	LogFormat       string   `env:"LOG_FORMAT" default:"json"`
+	LogRedactFields []string `env:"LOG_REDACT_FIELDS"`
+	TracesExporter  string   `env:"OTEL_TRACES_EXPORTER" default:"none"`
+
+	sources map[string]string
+}
+
+// LoadEnv loads ../.env outside Docker. It runs before the logger is
+// configured, so failures are returned rather than logged.
+func LoadEnv() error {
+	if os.Getenv("RUNNING_IN_DOCKER") == "true" {
+		return nil
+	}
+	return godotenv.Load("../.env")
+}
+
+// Load reads the configuration from the environment, *_FILE secrets and the
+// optional CONFIG_FILE, then validates it. The returned Config is populated
+// even when validation fails so it can still be printed; every problem is
+// reported in the returned error.
+func Load() (*Config, error) {
+	cfg := &Config{}
+	l := newLoader()
+	l.fill(cfg)
+	cfg.sources = l.sources
+
+	v := &validator{errs: l.errs}
+	cfg.validate(v)
+	return cfg, v.err()
+}
+
+func (c *Config) validate(v *validator) {
+	switch c.Env {
+	case "development", "staging", "production":
+	default:
+		v.errorf("APP_ENV must be development, staging or production, got %q", c.Env)
+	}
+
+	if c.ReadinessTimeout <= 0 {
+		v.errorf("READINESS_TIMEOUT must be positive")
+	}
+	if c.ShutdownDrainDelay < 0 {
+		v.errorf("SHUTDOWN_DRAIN_DELAY must not be negative")
+	}
+
+	for name, raw := range map[string]string{
+		"AUTH_SERVICE_URL":    c.AuthServiceURL,
+		"PAYMENT_SERVICE_URL": c.PaymentServiceURL,
+	} {
+		u, err := url.Parse(raw)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			v.errorf("%s must be an absolute URL, got %q", name, raw)
+		}
+	}
+
+	if c.IsProduction() {
+		for _, origin := range c.CORSAllowedOrigins {
+			if origin == "*" {
+				v.errorf("CORS_ALLOWED_ORIGINS must not contain * in production")
+			}
+		}
+	}
+}
+
+// IsProduction reports whether APP_ENV is production.
+func (c *Config) IsProduction() bool {
+	return c.Env == "production"
+}
+
+// Print writes the effective configuration with secrets masked.
+func (c *Config) Print(w io.Writer) error {
+	return printConfig(w, c, c.sources)
+}
@@ -0,0 +1,66 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// loadWith runs Load with only env set among the variables Config reads.
+func loadWith(t *testing.T, env map[string]string) (*Config, error) {
+	t.Helper()
+	t.Setenv("CONFIG_FILE", "")
+	ct := reflect.TypeOf(Config{})
+	for i := 0; i < ct.NumField(); i++ {
+		for _, name := range strings.Split(ct.Field(i).Tag.Get("env"), ",") {
+			if name != "" {
+				t.Setenv(name, "")
+				t.Setenv(name+"_FILE", "")
+			}
+		}
+	}
+	for k, v := range env {
+		t.Setenv(k, v)
+	}
+	return Load()
+}
+
+func TestLoadValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr []string
+	}{
+		{name: "defaults", env: nil},
+		{name: "production", env: map[string]string{"APP_ENV": "production", "CORS_ALLOWED_ORIGINS": "https://app.example.com"}},
+		{name: "unknown environment", env: map[string]string{"APP_ENV": "prod"}, wantErr: []string{`APP_ENV must be development, staging or production, got "prod"`}},
+		{name: "readiness timeout", env: map[string]string{"READINESS_TIMEOUT": "0s"}, wantErr: []string{"READINESS_TIMEOUT must be positive"}},
+		{name: "drain delay", env: map[string]string{"SHUTDOWN_DRAIN_DELAY": "-1s"}, wantErr: []string{"SHUTDOWN_DRAIN_DELAY must not be negative"}},
+		{
+			name:    "relative upstreams",
+			env:     map[string]string{"AUTH_SERVICE_URL": "auth:8083", "PAYMENT_SERVICE_URL": "/payments"},
+			wantErr: []string{`AUTH_SERVICE_URL must be an absolute URL, got "auth:8083"`, `PAYMENT_SERVICE_URL must be an absolute URL, got "/payments"`},
+		},
+		{name: "production default origins", env: map[string]string{"APP_ENV": "production"}},
+		{name: "production wildcard origin", env: map[string]string{"APP_ENV": "production", "CORS_ALLOWED_ORIGINS": "*"}, wantErr: []string{"CORS_ALLOWED_ORIGINS must not contain * in production"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadWith(t, tt.env)
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("no error, want %q", tt.wantErr)
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error lacks %q:\n%v", want, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package logging
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// Redacted replaces the value of any attribute considered sensitive.
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys are matched case-insensitively as substrings of attribute
+// keys, so "db_password", "jwt_secret" and "access_token" are all caught.
+var sensitiveKeys = []string{
+	"password",
+	"passwd",
+	"secret",
+	"token",
+	"authorization",
+	"cookie",
+	"api_key",
+	"apikey",
+	"signature",
+}
+
+// maskedKeys hold account identifiers: they are logged with all but the last
+// four characters masked so they stay useful for support.
+var maskedKeys = []string{
+	"account",
+	"vpa",
+	"card",
+}
+
+// sensitiveHeaders are stripped from http.Header values before logging.
+var sensitiveHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "X-Razorpay-Signature"}
+
+type redactor struct {
+	extra []string
+}
+
+func newRedactor(extra []string) redactor {
+	lowered := make([]string, len(extra))
+	for i, k := range extra {
+		lowered[i] = strings.ToLower(k)
+	}
+	return redactor{extra: lowered}
+}
+
+func (rd redactor) replaceAttr(_ []string, a slog.Attr) slog.Attr {
+	key := strings.ToLower(a.Key)
+
+	if containsAny(key, sensitiveKeys) || containsAny(key, rd.extra) {
+		return slog.String(a.Key, Redacted)
+	}
+	if containsAny(key, maskedKeys) && a.Value.Kind() == slog.KindString {
+		return slog.String(a.Key, maskAccount(a.Value.String()))
+	}
+
+	if a.Value.Kind() == slog.KindAny {
+		switch v := a.Value.Any().(type) {
+		case http.Header:
+			return slog.Any(a.Key, redactHeader(v))
+		case *http.Header:
+			if v != nil {
+				return slog.Any(a.Key, redactHeader(*v))
+			}
+		}
+	}
+	return a
+}
+
+// maskAccount masks all but the last four characters of an account number,
+// VPA or card number.
+func maskAccount(s string) string {
+	if len(s) <= 4 {
+		return strings.Repeat("*", len(s))
+	}
+	return strings.Repeat("*", len(s)-4) + s[len(s)-4:]
+}
+
+func redactHeader(h http.Header) http.Header {
+	clean := h.Clone()
+	for _, name := range sensitiveHeaders {
+		if clean.Get(name) != "" {
+			clean.Set(name, Redacted)
+		}
+	}
+	return clean
+}
+
+func containsAny(key string, needles []string) bool {
+	for _, n := range needles {
+		if strings.Contains(key, n) {
+			return true
+		}
+	}
+	return false
+}
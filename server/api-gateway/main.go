@@ -1,31 +1,47 @@
 package main
 
 import (
-	"log"
+	"context"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
+	"github.com/RaginiSharma01/gopay-lite/api-gateway/health"
+	"github.com/RaginiSharma01/gopay-lite/api-gateway/internal/config"
+	"github.com/RaginiSharma01/gopay-lite/api-gateway/logging"
+	"github.com/RaginiSharma01/gopay-lite/api-gateway/metrics"
+	"github.com/RaginiSharma01/gopay-lite/api-gateway/middleware"
 	"github.com/RaginiSharma01/gopay-lite/api-gateway/routes"
+	"github.com/RaginiSharma01/gopay-lite/api-gateway/tracing"
 	"github.com/gorilla/mux"
 )
 
-func enableCORS(next http.Handler) http.Handler {
+func enableCORS(origins []string) mux.MiddlewareFunc {
+	allowedOrigins := make(map[string]bool, len(origins))
+	for _, origin := range origins {
+		allowedOrigins[origin] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return corsHandler(next, allowedOrigins)
+	}
+}
+
+func corsHandler(next http.Handler, allowedOrigins map[string]bool) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Set your frontend origin explicitly
 		origin := r.Header.Get("Origin")
-		allowedOrigins := map[string]bool{
-			"http://localhost:3000": true,
-			// Add other allowed origins here
-		}
-
 		if allowedOrigins[origin] {
 			w.Header().Set("Access-Control-Allow-Origin", origin)
 			w.Header().Set("Access-Control-Allow-Credentials", "true")
 		}
 
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Request-ID")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID")
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusNoContent)
@@ -35,14 +51,54 @@ func enableCORS(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// statementWriteTimeout is how long a statement download may go without a
+// write before the connection is closed; payment-service allows as long.
+const statementWriteTimeout = 30 * time.Second
+
 func main() {
-	// Load service URLs from environment
-	authURL := getEnv("AUTH_SERVICE_URL", "http://localhost:8083")
-	paymentURL := getEnv("PAYMENT_SERVICE_URL", "http://localhost:8084")
+	envErr := config.LoadEnv()
+	cfg, cfgErr := config.Load()
+
+	// `api-gateway config print` shows the effective configuration
+	if len(os.Args) > 2 && os.Args[1] == "config" && os.Args[2] == "print" {
+		cfg.Print(os.Stdout)
+		if cfgErr != nil {
+			fmt.Fprintf(os.Stderr, "\ninvalid configuration:\n%v\n", cfgErr)
+			os.Exit(1)
+		}
+		return
+	}
+
+	logging.Init("api-gateway", logging.Options{
+		Level:        cfg.LogLevel,
+		Format:       cfg.LogFormat,
+		RedactFields: cfg.LogRedactFields,
+	})
+	if envErr != nil {
+		slog.Debug("No .env file loaded", "error", envErr)
+	}
+	if cfgErr != nil {
+		slog.Error("Invalid configuration", "error", cfgErr)
+		os.Exit(1)
+	}
+
+	authURL := cfg.AuthServiceURL
+	paymentURL := cfg.PaymentServiceURL
+
+	// Tracing
+	shutdownTracing, err := tracing.Init(context.Background(), cfg.TracesExporter)
+	if err != nil {
+		slog.Error("Tracing setup failed", "error", err)
+		os.Exit(1)
+	}
 
 	r := mux.NewRouter()
-	r.Use(enableCORS)
+	r.Use(middleware.RequestID)
+	r.Use(tracing.Middleware)
+	r.Use(enableCORS(cfg.CORSAllowedOrigins))
 	r.Use(loggingMiddleware)
+	r.Use(metrics.Middleware)
 
 	// Route configurations
 	r.PathPrefix("/api/v1/auth/").Handler(
@@ -53,41 +109,142 @@ func main() {
 		routes.NewReverseProxy(paymentURL, "/api/v1", "/api/v1"),
 	)
 
-	// Health check endpoint
-	r.HandleFunc("/health", healthCheck).Methods("GET")
+	r.PathPrefix("/api/v1/payments/").Handler(
+		routes.NewReverseProxy(paymentURL, "/api/v1", "/api/v1"),
+	)
+
+	r.PathPrefix("/api/v1/beneficiaries").Handler(
+		routes.NewReverseProxy(paymentURL, "/api/v1", "/api/v1"),
+	)
+
+	r.PathPrefix("/api/v1/schedules").Handler(
+		routes.NewReverseProxy(paymentURL, "/api/v1", "/api/v1"),
+	)
+
+	r.PathPrefix("/api/v1/plans").Handler(
+		routes.NewReverseProxy(paymentURL, "/api/v1", "/api/v1"),
+	)
+
+	r.PathPrefix("/api/v1/subscriptions").Handler(
+		routes.NewReverseProxy(paymentURL, "/api/v1", "/api/v1"),
+	)
+
+	r.PathPrefix("/api/v1/links").Handler(
+		routes.NewReverseProxy(paymentURL, "/api/v1", "/api/v1"),
+	)
+
+	r.PathPrefix("/api/v1/invoices").Handler(
+		routes.NewReverseProxy(paymentURL, "/api/v1", "/api/v1"),
+	)
+
+	// Statement exports stream for as long as they have rows, past the
+	// server's WriteTimeout
+	r.PathPrefix("/api/v1/statements").Handler(routes.Streaming(
+		routes.NewReverseProxy(paymentURL, "/api/v1", "/api/v1"),
+		statementWriteTimeout,
+	))
+
+	r.PathPrefix("/api/v1/limits").Handler(
+		routes.NewReverseProxy(paymentURL, "/api/v1", "/api/v1"),
+	)
+
+	r.PathPrefix("/api/v1/currencies").Handler(
+		routes.NewReverseProxy(paymentURL, "/api/v1", "/api/v1"),
+	)
+
+	r.PathPrefix("/api/v1/fx/").Handler(
+		routes.NewReverseProxy(paymentURL, "/api/v1", "/api/v1"),
+	)
+
+	r.PathPrefix("/api/v1/disputes").Handler(
+		routes.NewReverseProxy(paymentURL, "/api/v1", "/api/v1"),
+	)
+
+	r.PathPrefix("/api/v1/webhooks/").Handler(
+		routes.NewReverseProxy(paymentURL, "/api/v1", "/api/v1"),
+	)
+
+	// Operator endpoints; payment-service checks the admin role claim
+	r.PathPrefix("/api/v1/admin/").Handler(
+		routes.NewReverseProxy(paymentURL, "/api/v1", "/api/v1"),
+	)
+
+	// Public payment link pages; no token required
+	r.PathPrefix("/l/").Handler(
+		routes.NewReverseProxy(paymentURL, "/l", "/l"),
+	)
+
+	// Inbound payment gateway notifications; verified by payment-service
+	r.PathPrefix("/webhooks/razorpay").Handler(
+		routes.NewReverseProxy(paymentURL, "/webhooks", "/webhooks"),
+	)
+
+	// Probes: /livez never touches dependencies, /readyz checks that both
+	// upstream services are reachable
+	checker := health.New(cfg.ReadinessTimeout)
+	probeClient := &http.Client{Timeout: cfg.ReadinessTimeout}
+	checker.Register("auth-service", health.HTTPCheck(probeClient, authURL+"/livez"))
+	checker.Register("payment-service", health.HTTPCheck(probeClient, paymentURL+"/livez"))
+
+	r.HandleFunc("/livez", checker.LiveHandler).Methods("GET")
+	r.HandleFunc("/readyz", checker.ReadyHandler).Methods("GET")
+	r.HandleFunc("/health", checker.LiveHandler).Methods("GET") // kept for existing probes
+
+	// Prometheus metrics
+	r.Handle("/metrics", metrics.Handler()).Methods("GET")
 
-	// Server configuration
+	// Server configuration; streamed routes move their own write deadline
+	// past WriteTimeout, see routes.Streaming
 	server := &http.Server{
-		Addr:         ":" + getEnv("PORT", "8080"),
+		Addr:         ":" + cfg.Port,
 		Handler:      r,
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
 
-	log.Printf("🚀 API Gateway running on %s", server.Addr)
-	if err := server.ListenAndServe(); err != nil {
-		log.Fatalf("💥 Server failed: %v", err)
-	}
-}
+	// Graceful shutdown
+	done := make(chan os.Signal, 1)
+	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 
-// Helper functions
-func getEnv(key, fallback string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+	go func() {
+		slog.Info("API Gateway running", "addr", server.Addr, "auth_url", authURL, "payment_url", paymentURL)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			slog.Error("Server failed", "error", err)
+			os.Exit(1)
+		}
+	}()
+
+	<-done
+	slog.Info("Shutting down API Gateway...", "drain_delay", cfg.ShutdownDrainDelay)
+
+	// Fail readiness first so load balancers stop routing new traffic
+	checker.SetDraining()
+	time.Sleep(cfg.ShutdownDrainDelay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		slog.Error("Shutdown error", "error", err)
+		os.Exit(1)
+	}
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Warn("Tracing shutdown error", "error", err)
 	}
-	return fallback
+	slog.Info("Stopped gracefully")
 }
 
-func healthCheck(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("OK"))
-}
+// Helper functions
 
 func loggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 		next.ServeHTTP(w, r)
-		log.Printf("%s %s %v", r.Method, r.URL.Path, time.Since(start))
+		slog.InfoContext(r.Context(), "Request completed",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"duration", time.Since(start),
+		)
 	})
 }
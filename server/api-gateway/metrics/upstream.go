@@ -0,0 +1,18 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var upstreamErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "upstream_errors_total",
+	Help:      "Requests the gateway failed to proxy, by upstream host.",
+}, []string{"upstream"})
+
+// UpstreamError counts a failed proxy attempt to the given upstream host.
+func UpstreamError(upstream string) {
+	upstreamErrors.WithLabelValues(upstream).Inc()
+}
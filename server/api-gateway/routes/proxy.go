@@ -1,12 +1,18 @@
 package routes
 
 import (
-	"log"
+	"encoding/json"
+	"log/slog"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"os"
 	"strings"
 	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/api-gateway/metrics"
+	"github.com/RaginiSharma01/gopay-lite/api-gateway/middleware"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
 type ReverseProxy struct {
@@ -16,16 +22,19 @@ type ReverseProxy struct {
 func NewReverseProxy(targetHost, fromPrefix, toPrefix string) http.Handler {
 	target, err := url.Parse(targetHost)
 	if err != nil {
-		log.Fatalf("Invalid target host: %v", err)
+		slog.Error("Invalid target host", "target", targetHost, "error", err)
+		os.Exit(1)
 	}
 
 	proxy := &httputil.ReverseProxy{
-		Transport: &http.Transport{
+		// otelhttp starts a client span for the upstream hop and injects
+		// the W3C traceparent header into the proxied request
+		Transport: otelhttp.NewTransport(&http.Transport{
 			MaxIdleConns:          100,
 			IdleConnTimeout:       90 * time.Second,
 			TLSHandshakeTimeout:   10 * time.Second,
 			ExpectContinueTimeout: 1 * time.Second,
-		},
+		}),
 		Director: func(req *http.Request) {
 			// Store original for logging
 			originalURL := *req.URL
@@ -46,9 +55,11 @@ func NewReverseProxy(targetHost, fromPrefix, toPrefix string) http.Handler {
 
 			// Debug logging
 			if originalURL.Path != req.URL.Path {
-				log.Printf("Rewrote %s%s → %s%s",
-					originalURL.Host, originalURL.Path,
-					req.URL.Host, req.URL.Path)
+				slog.DebugContext(req.Context(), "Rewrote upstream path",
+					"from", originalURL.Path,
+					"to", req.URL.Path,
+					"upstream", req.URL.Host,
+				)
 			}
 		},
 	}
@@ -60,6 +71,9 @@ func NewReverseProxy(targetHost, fromPrefix, toPrefix string) http.Handler {
 		resp.Header.Del("Access-Control-Allow-Credentials")
 		resp.Header.Del("Access-Control-Expose-Headers")
 
+		// The gateway already echoes the request ID it forwarded
+		resp.Header.Del(middleware.RequestIDHeader)
+
 		// Ensure no cache for API responses
 		if strings.HasPrefix(resp.Request.URL.Path, "/api/") {
 			resp.Header.Set("Cache-Control", "no-store, max-age=0")
@@ -70,21 +84,33 @@ func NewReverseProxy(targetHost, fromPrefix, toPrefix string) http.Handler {
 
 	// Enhanced error handling
 	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
-		log.Printf(" Proxy error for %s %s: %v", r.Method, r.URL.Path, err)
+		requestID := middleware.GetRequestID(r.Context())
+		slog.ErrorContext(r.Context(), "Proxy error",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"upstream", target.Host,
+			"error", err,
+		)
+		metrics.UpstreamError(target.Host)
 
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusBadGateway)
-		w.Write([]byte(`{
-			"error": "service_unavailable",
-			"message": "Backend service not responding"
-		}`))
+		json.NewEncoder(w).Encode(map[string]string{
+			"error":      "service_unavailable",
+			"message":    "Backend service not responding",
+			"request_id": requestID,
+		})
 	}
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 		defer func() {
-			log.Printf("⇨ %s %s (%v)",
-				r.Method, r.URL.Path, time.Since(start))
+			slog.InfoContext(r.Context(), "Proxied request",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"upstream", target.Host,
+				"duration", time.Since(start),
+			)
 		}()
 
 		// Preflight request short-circuit
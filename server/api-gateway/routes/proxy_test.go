@@ -0,0 +1,90 @@
+package routes
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/RaginiSharma01/gopay-lite/api-gateway/tracing"
+)
+
+func TestProxyPropagatesTrace(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	shutdown := tracing.InitWithExporter(exporter)
+	defer shutdown(context.Background())
+
+	var path string
+	var received trace.SpanContext
+	auth := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		ctx := propagation.TraceContext{}.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		received = trace.SpanContextFromContext(ctx)
+	}))
+	defer auth.Close()
+
+	r := mux.NewRouter()
+	r.Use(tracing.Middleware)
+	r.PathPrefix("/api/v1/auth/").Handler(NewReverseProxy(auth.URL, "/api/v1/auth", "/api/v1"))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || path != "/api/v1/login" {
+		t.Fatalf("proxied to %q with status %d", path, rec.Code)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("%d spans recorded, want 2", len(spans))
+	}
+	upstream, server := spans[0], spans[1]
+	if server.SpanKind != trace.SpanKindServer || server.SpanContext.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("server span %q (%s) in trace %s, want the client's trace", server.Name, server.SpanKind, server.SpanContext.TraceID())
+	}
+	if upstream.SpanKind != trace.SpanKindClient || upstream.Parent.SpanID() != server.SpanContext.SpanID() {
+		t.Errorf("upstream span %q (%s) is not a client child of the server span", upstream.Name, upstream.SpanKind)
+	}
+	// The service continues the same trace from the upstream hop
+	if received.TraceID() != server.SpanContext.TraceID() || received.SpanID() != upstream.SpanContext.SpanID() {
+		t.Errorf("upstream received %s/%s, want %s/%s",
+			received.TraceID(), received.SpanID(), server.SpanContext.TraceID(), upstream.SpanContext.SpanID())
+	}
+}
+
+func TestStreamingOutlastsWriteTimeout(t *testing.T) {
+	// The upstream writes a row more often than the gateway's write
+	// timeout, for longer than it
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for range 6 {
+			w.Write([]byte("row\n"))
+			http.NewResponseController(w).Flush()
+			time.Sleep(50 * time.Millisecond)
+		}
+	}))
+	defer upstream.Close()
+
+	gateway := httptest.NewUnstartedServer(Streaming(NewReverseProxy(upstream.URL, "/api/v1", "/api/v1"), 200*time.Millisecond))
+	gateway.Config.WriteTimeout = 100 * time.Millisecond
+	gateway.Start()
+	defer gateway.Close()
+
+	resp, err := http.Get(gateway.URL + "/api/v1/statements")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil || strings.Count(string(body), "row\n") != 6 {
+		t.Errorf("read %q (%v), want all 6 rows", body, err)
+	}
+}
@@ -0,0 +1,41 @@
+package routes
+
+import (
+	"net/http"
+	"time"
+)
+
+// Streaming lets next write for longer than the server's WriteTimeout, as
+// long as it keeps writing: the write deadline is moved to idle from now
+// when the request starts and again with every write. It is meant for
+// downloads the upstream streams, such as statement exports.
+func Streaming(next http.Handler, idle time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := &deadlineWriter{ResponseWriter: w, rc: http.NewResponseController(w), idle: idle}
+		sw.extend()
+		next.ServeHTTP(sw, r)
+	})
+}
+
+// deadlineWriter moves the write deadline forward before each write.
+type deadlineWriter struct {
+	http.ResponseWriter
+	rc   *http.ResponseController
+	idle time.Duration
+}
+
+func (d *deadlineWriter) extend() {
+	// Writers that cannot set a deadline keep the server's
+	d.rc.SetWriteDeadline(time.Now().Add(d.idle))
+}
+
+func (d *deadlineWriter) Write(p []byte) (int, error) {
+	d.extend()
+	return d.ResponseWriter.Write(p)
+}
+
+// Unwrap lets http.ResponseController, which the proxy flushes through,
+// reach the underlying writer.
+func (d *deadlineWriter) Unwrap() http.ResponseWriter {
+	return d.ResponseWriter
+}
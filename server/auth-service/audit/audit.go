@@ -0,0 +1,209 @@
+// Package audit appends sign-ups, logins and account changes to the audit
+// log shared with payment-service, which owns the table and querying and
+// verifying it.
+//
+// Entries are written unchained; payment-service's chainer links them into
+// the hash chain with its own entries shortly after they commit, so nothing
+// here takes the chain's lock.
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"gopay-lite/middleware"
+	"gopay-lite/tracing"
+)
+
+// service is the value of the service column for entries written here.
+const service = "auth"
+
+// Outcome is whether an audited action succeeded.
+type Outcome string
+
+const (
+	OutcomeSuccess Outcome = "success"
+	OutcomeFailure Outcome = "failure"
+)
+
+// Actor types used by this service.
+const (
+	ActorUser      = "user"
+	ActorAdmin     = "admin"
+	ActorAnonymous = "anonymous" // a caller not yet signed in
+	ActorSystem    = "system"    // the command line
+)
+
+// Actor is who performed an action.
+type Actor struct {
+	Type  string
+	ID    *int
+	Email string
+}
+
+// Event is an action to audit. The client and request ID are taken from
+// the context it is recorded with.
+type Event struct {
+	Action  string
+	Outcome Outcome // default success
+	// Actor defaults to anonymous within a request and the system outside
+	// one.
+	Actor        Actor
+	ResourceType string
+	ResourceID   string
+	// Before and After are marshalled to JSON; nil is stored as NULL.
+	Before any
+	After  any
+	Detail string
+}
+
+// entry is a row of the audit log.
+type entry struct {
+	CreatedAt    time.Time
+	Action       string
+	Outcome      Outcome
+	ActorType    string
+	ActorID      *int
+	ActorEmail   string
+	IP           string
+	UserAgent    string
+	RequestID    string
+	ResourceType string
+	ResourceID   string
+	Before       json.RawMessage
+	After        json.RawMessage
+	Detail       string
+}
+
+// Append adds e to the log in tx, so it is kept only if tx commits.
+func Append(ctx context.Context, tx *sql.Tx, e Event) error {
+	en, err := newEntry(ctx, e)
+	if err != nil {
+		return err
+	}
+
+	const insert = `INSERT INTO audit_log
+		(created_at, service, action, outcome, actor_type, actor_id, actor_email, ip, user_agent,
+		 request_id, resource_type, resource_id, before, after, detail)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)`
+	spanCtx, span := tracing.StartDBSpan(ctx, "INSERT", "audit_log", insert)
+	_, err = tx.ExecContext(spanCtx, insert,
+		en.CreatedAt,
+		service,
+		en.Action,
+		string(en.Outcome),
+		en.ActorType,
+		nullInt(en.ActorID),
+		en.ActorEmail,
+		en.IP,
+		en.UserAgent,
+		en.RequestID,
+		en.ResourceType,
+		en.ResourceID,
+		nullJSON(en.Before),
+		nullJSON(en.After),
+		en.Detail,
+	)
+	tracing.End(span, err)
+	if err != nil {
+		return fmt.Errorf("insert audit entry %s: %w", e.Action, err)
+	}
+	return nil
+}
+
+// Record appends e in a transaction of its own.
+func Record(ctx context.Context, db *sql.DB, e Event) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := Append(ctx, tx, e); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// newEntry fills an entry for e from ctx.
+func newEntry(ctx context.Context, e Event) (entry, error) {
+	en := entry{
+		CreatedAt:    time.Now().UTC(),
+		Action:       e.Action,
+		Outcome:      e.Outcome,
+		ActorType:    e.Actor.Type,
+		ActorID:      e.Actor.ID,
+		ActorEmail:   e.Actor.Email,
+		RequestID:    middleware.GetRequestID(ctx),
+		ResourceType: e.ResourceType,
+		ResourceID:   e.ResourceID,
+		Detail:       e.Detail,
+	}
+	if en.Outcome == "" {
+		en.Outcome = OutcomeSuccess
+	}
+	c, inRequest := middleware.GetClient(ctx)
+	if inRequest {
+		if c.IP.IsValid() {
+			en.IP = c.IP.String()
+		}
+		en.UserAgent = cleanText(c.UserAgent, 512)
+	}
+	if en.ActorType == "" {
+		en.ActorType = ActorSystem
+		if inRequest {
+			en.ActorType = ActorAnonymous
+		}
+	}
+	// Emails come from request bodies
+	en.ActorEmail = cleanText(en.ActorEmail, 320)
+
+	var err error
+	if en.Before, err = marshal(e.Before); err != nil {
+		return entry{}, fmt.Errorf("audit %s before: %w", e.Action, err)
+	}
+	if en.After, err = marshal(e.After); err != nil {
+		return entry{}, fmt.Errorf("audit %s after: %w", e.Action, err)
+	}
+	return en, nil
+}
+
+// marshal encodes v as JSON, nil for a nil v.
+func marshal(v any) (json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil || string(raw) == "null" {
+		return nil, err
+	}
+	return raw, nil
+}
+
+// cleanText makes client-supplied s storable: valid UTF-8 without NULs,
+// at most max bytes.
+func cleanText(s string, max int) string {
+	s = strings.ToValidUTF8(strings.ReplaceAll(s, "\x00", ""), "")
+	if len(s) > max {
+		s = strings.ToValidUTF8(s[:max], "")
+	}
+	return s
+}
+
+func nullInt(v *int) sql.NullInt64 {
+	if v == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: int64(*v), Valid: true}
+}
+
+func nullJSON(raw json.RawMessage) any {
+	if raw == nil {
+		return nil
+	}
+	return string(raw)
+}
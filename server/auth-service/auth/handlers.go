@@ -1,16 +1,26 @@
 package auth
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
-	"os"
+	"strconv"
 	"strings"
 	"time"
 
+	"gopay-lite/audit"
+	"gopay-lite/blocklist"
 	"gopay-lite/db"
+	"gopay-lite/internal/config"
+	"gopay-lite/metrics"
+	"gopay-lite/middleware"
+	"gopay-lite/tracing"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/lib/pq"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -20,16 +30,90 @@ type User struct {
 	Name     string `json:"name"`
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	// IANA time zone such as Asia/Kolkata; defaults to UTC
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// LogValue keeps the password out of logs when a User is logged directly.
+func (u User) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("name", u.Name),
+		slog.String("email", u.Email),
+	)
 }
 
 type AuthResponse struct {
-	Message string `json:"message"`
-	Token   string `json:"token,omitempty"`
+	Message   string `json:"message"`
+	Token     string `json:"token,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
 }
 
 type MeResponse struct {
-	UserID int    `json:"user_id"`
-	Email  string `json:"email"`
+	UserID   int    `json:"user_id"`
+	Email    string `json:"email"`
+	Role     string `json:"role"`
+	Name     string `json:"name"`
+	Timezone string `json:"timezone"`
+	// When the account was registered
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UpdateMeRequest changes the caller's profile; omitted fields are kept.
+type UpdateMeRequest struct {
+	Name     *string `json:"name,omitempty"`
+	Timezone *string `json:"timezone,omitempty"`
+}
+
+// defaultTimezone is used when a user has not chosen one.
+const defaultTimezone = "UTC"
+
+// Roles stored in users.role and issued in the JWT "role" claim.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// blocked is the blocklist registrations and logins are screened against;
+// nil screens nothing.
+var blocked *blocklist.List
+
+// SetBlocklist sets the blocklist Register and Login screen against.
+func SetBlocklist(l *blocklist.List) {
+	blocked = l
+}
+
+// blockedBy returns the entry blocking email or the address r came from,
+// if one does. It returns blocklist.ErrNotLoaded until the list is first
+// fetched, so nothing gets through unscreened.
+func blockedBy(r *http.Request, email string) (blocklist.Entry, bool, error) {
+	if blocked == nil {
+		return blocklist.Entry{}, false, nil
+	}
+	if !blocked.Loaded() {
+		return blocklist.Entry{}, false, blocklist.ErrNotLoaded
+	}
+	if e, ok := blocked.Email(email); ok {
+		return e, true, nil
+	}
+	e, ok := blocked.IP(middleware.ClientIP(r))
+	return e, ok, nil
+}
+
+// recordAudit adds e to the audit log. The action it describes stands if
+// recording fails, so the failure is only logged.
+func recordAudit(r *http.Request, e audit.Event) {
+	if err := audit.Record(r.Context(), db.DB, e); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to record audit entry", "action", e.Action, "error", err)
+	}
+}
+
+// userActor is the signed-in user id as an audit actor.
+func userActor(id int, email, role string) audit.Actor {
+	a := audit.Actor{Type: audit.ActorUser, ID: &id, Email: email}
+	if role == RoleAdmin {
+		a.Type = audit.ActorAdmin
+	}
+	return a
 }
 
 // ========== Register ==========
@@ -44,47 +128,101 @@ type MeResponse struct {
 // @Param user body User true "User data"
 // @Success 201 {object} AuthResponse
 // @Failure 400 {object} AuthResponse
+// @Failure 403 {object} AuthResponse
 // @Failure 409 {object} AuthResponse
 // @Failure 500 {object} AuthResponse
+// @Failure 503 {object} AuthResponse
 // @Router /api/v1/register [post]
 func Register(w http.ResponseWriter, r *http.Request) {
 	var u User
 	if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
-		sendErrorResponse(w, "Invalid JSON format", http.StatusBadRequest)
+		sendErrorResponse(w, r, "Invalid JSON format", http.StatusBadRequest)
 		return
 	}
 
 	u.Name = strings.TrimSpace(u.Name)
 	u.Email = strings.TrimSpace(u.Email)
 	u.Password = strings.TrimSpace(u.Password)
+	u.Timezone = strings.TrimSpace(u.Timezone)
 
 	if u.Name == "" || u.Email == "" || u.Password == "" {
-		sendErrorResponse(w, "Name, email, and password are required", http.StatusBadRequest)
+		sendErrorResponse(w, r, "Name, email, and password are required", http.StatusBadRequest)
+		return
+	}
+	if u.Timezone == "" {
+		u.Timezone = defaultTimezone
+	}
+	if !validTimezone(u.Timezone) {
+		sendErrorResponse(w, r, "Unknown timezone", http.StatusBadRequest)
+		return
+	}
+	e, isBlocked, err := blockedBy(r, u.Email)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Registration refused", "error", err)
+		sendErrorResponse(w, r, "Registration is unavailable; try again shortly", http.StatusServiceUnavailable)
+		return
+	}
+	if isBlocked {
+		slog.WarnContext(r.Context(), "Registration blocklisted", "kind", e.Kind, "reason", e.Reason)
+		recordAudit(r, audit.Event{
+			Action:  "auth.register",
+			Outcome: audit.OutcomeFailure,
+			Actor:   audit.Actor{Email: u.Email},
+			Detail:  "blocklisted " + e.Kind,
+		})
+		sendErrorResponse(w, r, "Registration not allowed", http.StatusForbidden)
 		return
 	}
 
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
 	if err != nil {
-		sendErrorResponse(w, "Error processing password", http.StatusInternalServerError)
+		sendErrorResponse(w, r, "Error processing password", http.StatusInternalServerError)
 		return
 	}
 
 	// Insert user and get ID
+	const insertUser = "INSERT INTO users (name, email, password, timezone) VALUES ($1, $2, $3, $4) RETURNING id"
 	var userID int
-	err = db.DB.QueryRow(
-		"INSERT INTO users (name, email, password) VALUES ($1, $2, $3) RETURNING id",
-		u.Name, u.Email, string(hashedPassword),
+	ctx, span := tracing.StartDBSpan(r.Context(), "INSERT", "users", insertUser)
+	err = db.DB.QueryRowContext(ctx, insertUser,
+		u.Name, u.Email, string(hashedPassword), u.Timezone,
 	).Scan(&userID)
-
+	tracing.End(span, err)
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		recordAudit(r, audit.Event{
+			Action:  "auth.register",
+			Outcome: audit.OutcomeFailure,
+			Actor:   audit.Actor{Email: u.Email},
+			Detail:  "email already registered",
+		})
+		sendErrorResponse(w, r, "Email already registered", http.StatusConflict)
+		return
+	}
 	if err != nil {
-		sendErrorResponse(w, "Email already registered", http.StatusConflict)
+		slog.ErrorContext(r.Context(), "Failed to create user", "error", err)
+		recordAudit(r, audit.Event{
+			Action:  "auth.register",
+			Outcome: audit.OutcomeFailure,
+			Actor:   audit.Actor{Email: u.Email},
+			Detail:  "database error",
+		})
+		sendErrorResponse(w, r, "Registration failed", http.StatusInternalServerError)
 		return
 	}
+	recordAudit(r, audit.Event{
+		Action:       "auth.register",
+		Actor:        userActor(userID, u.Email, RoleUser),
+		ResourceType: "user",
+		ResourceID:   strconv.Itoa(userID),
+		After:        map[string]any{"name": u.Name, "email": u.Email, "timezone": u.Timezone, "role": RoleUser},
+	})
 
 	// Generate JWT
-	token, err := generateToken(u.Email, userID)
+	token, err := generateToken(u.Email, userID, RoleUser)
 	if err != nil {
-		sendErrorResponse(w, "Token generation failed", http.StatusInternalServerError)
+		sendErrorResponse(w, r, "Token generation failed", http.StatusInternalServerError)
 		return
 	}
 
@@ -109,12 +247,14 @@ func Register(w http.ResponseWriter, r *http.Request) {
 // @Success 200 {object} AuthResponse
 // @Failure 400 {object} AuthResponse
 // @Failure 401 {object} AuthResponse
+// @Failure 403 {object} AuthResponse
 // @Failure 500 {object} AuthResponse
+// @Failure 503 {object} AuthResponse
 // @Router /api/v1/login [post]
 func Login(w http.ResponseWriter, r *http.Request) {
 	var u User
 	if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
-		sendErrorResponse(w, "Invalid JSON format", http.StatusBadRequest)
+		sendErrorResponse(w, r, "Invalid JSON format", http.StatusBadRequest)
 		return
 	}
 
@@ -122,30 +262,76 @@ func Login(w http.ResponseWriter, r *http.Request) {
 	u.Password = strings.TrimSpace(u.Password)
 
 	if u.Email == "" || u.Password == "" {
-		sendErrorResponse(w, "Email and password are required", http.StatusBadRequest)
+		sendErrorResponse(w, r, "Email and password are required", http.StatusBadRequest)
+		return
+	}
+	// Checked before the password so the answer does not reveal whether
+	// the account exists
+	e, isBlocked, err := blockedBy(r, u.Email)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Login refused", "error", err)
+		sendErrorResponse(w, r, "Login is unavailable; try again shortly", http.StatusServiceUnavailable)
+		return
+	}
+	if isBlocked {
+		metrics.LoginFailed()
+		slog.WarnContext(r.Context(), "Login blocklisted", "kind", e.Kind, "reason", e.Reason)
+		recordAudit(r, audit.Event{
+			Action:  "auth.login",
+			Outcome: audit.OutcomeFailure,
+			Actor:   audit.Actor{Email: u.Email},
+			Detail:  "blocklisted " + e.Kind,
+		})
+		sendErrorResponse(w, r, "Login not allowed", http.StatusForbidden)
 		return
 	}
 
 	var userID int
-	var hashedPassword string
+	var hashedPassword, role string
 
-	err := db.DB.QueryRow("SELECT id, password FROM users WHERE email = $1", u.Email).Scan(&userID, &hashedPassword)
+	const selectUser = "SELECT id, password, role FROM users WHERE email = $1"
+	ctx, span := tracing.StartDBSpan(r.Context(), "SELECT", "users", selectUser)
+	err = db.DB.QueryRowContext(ctx, selectUser, u.Email).Scan(&userID, &hashedPassword, &role)
+	tracing.End(span, err)
 	if err != nil {
-		sendErrorResponse(w, "Invalid email or password", http.StatusUnauthorized)
+		metrics.LoginFailed()
+		recordAudit(r, audit.Event{
+			Action:  "auth.login",
+			Outcome: audit.OutcomeFailure,
+			Actor:   audit.Actor{Email: u.Email},
+			Detail:  "unknown email",
+		})
+		sendErrorResponse(w, r, "Invalid email or password", http.StatusUnauthorized)
 		return
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(u.Password)); err != nil {
-		sendErrorResponse(w, "Invalid email or password", http.StatusUnauthorized)
+		metrics.LoginFailed()
+		recordAudit(r, audit.Event{
+			Action:       "auth.login",
+			Outcome:      audit.OutcomeFailure,
+			Actor:        audit.Actor{Email: u.Email},
+			ResourceType: "user",
+			ResourceID:   strconv.Itoa(userID),
+			Detail:       "wrong password",
+		})
+		sendErrorResponse(w, r, "Invalid email or password", http.StatusUnauthorized)
 		return
 	}
 
-	token, err := generateToken(u.Email, userID)
+	token, err := generateToken(u.Email, userID, role)
 	if err != nil {
-		sendErrorResponse(w, "Token generation failed", http.StatusInternalServerError)
+		sendErrorResponse(w, r, "Token generation failed", http.StatusInternalServerError)
 		return
 	}
 
+	metrics.LoginSucceeded()
+	recordAudit(r, audit.Event{
+		Action:       "auth.login",
+		Actor:        userActor(userID, u.Email, role),
+		ResourceType: "user",
+		ResourceID:   strconv.Itoa(userID),
+	})
 	json.NewEncoder(w).Encode(AuthResponse{
 		Message: "Login successful",
 		Token:   token,
@@ -157,56 +343,163 @@ func Login(w http.ResponseWriter, r *http.Request) {
 // Me handler returns user info from token
 //
 // @Summary Get user info
-// @Description Returns email and user ID from JWT token
+// @Description Returns the user ID, email and role from the JWT token with the profile's name and timezone
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Success 200 {object} MeResponse
 // @Failure 401 {object} AuthResponse
+// @Failure 404 {object} AuthResponse
 // @Failure 500 {object} AuthResponse
 // @Router /api/v1/me [get]
 func Me(w http.ResponseWriter, r *http.Request) {
-	authHeader := r.Header.Get("Authorization")
-	if authHeader == "" {
-		sendErrorResponse(w, "Missing Authorization header", http.StatusUnauthorized)
+	me, ok := tokenUser(w, r)
+	if !ok {
 		return
 	}
+	if !loadProfile(w, r, &me) {
+		return
+	}
+	json.NewEncoder(w).Encode(me)
+}
 
-	tokenString := extractToken(authHeader)
-	if tokenString == "" {
-		sendErrorResponse(w, "Invalid Authorization header format", http.StatusUnauthorized)
+// UpdateMe changes the caller's name or timezone
+//
+// @Summary Update user profile
+// @Description Changes the name and/or IANA timezone of the authenticated user
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param profile body UpdateMeRequest true "Fields to change"
+// @Success 200 {object} MeResponse
+// @Failure 400 {object} AuthResponse
+// @Failure 401 {object} AuthResponse
+// @Failure 404 {object} AuthResponse
+// @Failure 500 {object} AuthResponse
+// @Router /api/v1/me [patch]
+func UpdateMe(w http.ResponseWriter, r *http.Request) {
+	me, ok := tokenUser(w, r)
+	if !ok {
 		return
 	}
 
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		sendErrorResponse(w, "JWT_SECRET not set", http.StatusInternalServerError)
+	var req UpdateMeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendErrorResponse(w, r, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+	if req.Name != nil {
+		*req.Name = strings.TrimSpace(*req.Name)
+		if *req.Name == "" {
+			sendErrorResponse(w, r, "Name must not be empty", http.StatusBadRequest)
+			return
+		}
+	}
+	if req.Timezone != nil {
+		*req.Timezone = strings.TrimSpace(*req.Timezone)
+		if !validTimezone(*req.Timezone) {
+			sendErrorResponse(w, r, "Unknown timezone", http.StatusBadRequest)
+			return
+		}
+	}
+
+	before := me
+	if !loadProfile(w, r, &before) {
 		return
 	}
 
+	const updateUser = `UPDATE users SET name = COALESCE($2, name), timezone = COALESCE($3, timezone)
+		WHERE id = $1 RETURNING name, timezone, created_at`
+	ctx, span := tracing.StartDBSpan(r.Context(), "UPDATE", "users", updateUser)
+	err := db.DB.QueryRowContext(ctx, updateUser, me.UserID, req.Name, req.Timezone).Scan(&me.Name, &me.Timezone, &me.CreatedAt)
+	tracing.End(span, err)
+	if errors.Is(err, sql.ErrNoRows) {
+		sendErrorResponse(w, r, "User not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		sendErrorResponse(w, r, "Failed to update profile", http.StatusInternalServerError)
+		return
+	}
+	recordAudit(r, audit.Event{
+		Action:       "auth.profile_updated",
+		Actor:        userActor(me.UserID, me.Email, me.Role),
+		ResourceType: "user",
+		ResourceID:   strconv.Itoa(me.UserID),
+		Before:       map[string]any{"name": before.Name, "timezone": before.Timezone},
+		After:        map[string]any{"name": me.Name, "timezone": me.Timezone},
+	})
+	json.NewEncoder(w).Encode(me)
+}
+
+// tokenUser reads the caller from the bearer token, writing a 401 if it is
+// missing or invalid.
+func tokenUser(w http.ResponseWriter, r *http.Request) (MeResponse, bool) {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		sendErrorResponse(w, r, "Missing Authorization header", http.StatusUnauthorized)
+		return MeResponse{}, false
+	}
+
+	tokenString := extractToken(authHeader)
+	if tokenString == "" {
+		sendErrorResponse(w, r, "Invalid Authorization header format", http.StatusUnauthorized)
+		return MeResponse{}, false
+	}
+
 	claims := jwt.MapClaims{}
 	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(secret), nil
-	})
+		return []byte(config.GetJWTSecret()), nil
+	}, jwt.WithValidMethods([]string{"HS256"}))
 	if err != nil {
-		sendErrorResponse(w, "Invalid token", http.StatusUnauthorized)
-		return
+		sendErrorResponse(w, r, "Invalid token", http.StatusUnauthorized)
+		return MeResponse{}, false
 	}
 
 	email, _ := claims["email"].(string)
 	userID, _ := claims["user_id"].(float64)
+	role, _ := claims["role"].(string)
+	if role == "" {
+		role = RoleUser // tokens issued before roles existed
+	}
+	return MeResponse{UserID: int(userID), Email: email, Role: role}, true
+}
 
-	json.NewEncoder(w).Encode(MeResponse{
-		UserID: int(userID),
-		Email:  email,
-	})
+// loadProfile fills in the profile stored for me.UserID, writing
+// a 404 if the user no longer exists.
+func loadProfile(w http.ResponseWriter, r *http.Request, me *MeResponse) bool {
+	const selectProfile = "SELECT name, timezone, created_at FROM users WHERE id = $1"
+	ctx, span := tracing.StartDBSpan(r.Context(), "SELECT", "users", selectProfile)
+	err := db.DB.QueryRowContext(ctx, selectProfile, me.UserID).Scan(&me.Name, &me.Timezone, &me.CreatedAt)
+	tracing.End(span, err)
+	if errors.Is(err, sql.ErrNoRows) {
+		sendErrorResponse(w, r, "User not found", http.StatusNotFound)
+		return false
+	}
+	if err != nil {
+		sendErrorResponse(w, r, "Failed to load profile", http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+// validTimezone reports whether tz names an IANA time zone. "Local" is
+// rejected because it means the server's zone, not the user's.
+func validTimezone(tz string) bool {
+	if tz == "" || tz == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(tz)
+	return err == nil
 }
 
 // ========== JWT Utility ==========
 
-func generateToken(email string, userID int) (string, error) {
-	secret := os.Getenv("JWT_SECRET")
+func generateToken(email string, userID int, role string) (string, error) {
+	cfg := config.Get()
+	secret := cfg.JWTSecret
 	if secret == "" {
 		return "", fmt.Errorf("JWT_SECRET not set")
 	}
@@ -214,7 +507,8 @@ func generateToken(email string, userID int) (string, error) {
 	claims := jwt.MapClaims{
 		"email":   email,
 		"user_id": userID,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(),
+		"role":    role,
+		"exp":     time.Now().Add(cfg.JWTTTL).Unix(),
 		"iat":     time.Now().Unix(),
 		"iss":     "gopay-lite",
 	}
@@ -233,7 +527,15 @@ func extractToken(authHeader string) string {
 
 // ========== Helper ==========
 
-func sendErrorResponse(w http.ResponseWriter, msg string, code int) {
+func sendErrorResponse(w http.ResponseWriter, r *http.Request, msg string, code int) {
+	requestID := middleware.GetRequestID(r.Context())
+	slog.WarnContext(r.Context(), "Request failed",
+		"method", r.Method,
+		"path", r.URL.Path,
+		"status", code,
+		"reason", msg,
+	)
+
 	w.WriteHeader(code)
-	json.NewEncoder(w).Encode(AuthResponse{Message: msg})
+	json.NewEncoder(w).Encode(AuthResponse{Message: msg, RequestID: requestID})
 }
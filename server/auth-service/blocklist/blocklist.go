@@ -0,0 +1,271 @@
+// Package blocklist screens registrations and logins against the
+// compliance blocklist. payment-service owns the list; this service keeps
+// an in-memory copy of its email and IP entries, polling for changes.
+package blocklist
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopay-lite/internal/config"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// ErrNotLoaded is returned while no copy of the list has been fetched.
+var ErrNotLoaded = errors.New("blocklist not loaded yet")
+
+// Entry kinds this service screens on; payment-service also lists account
+// and VPA entries, which are ignored here.
+const (
+	KindEmail = "email" // an address, or "@domain" for a whole domain
+	KindIP    = "ip"    // a CIDR network
+)
+
+// Entry is a blocklisted value as payment-service serves it.
+type Entry struct {
+	Kind   string `json:"kind"`
+	Value  string `json:"value"`
+	Reason string `json:"reason"`
+	// ExpiresAt is nil for an entry that never expires.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+func (e Entry) active(now time.Time) bool {
+	return e.ExpiresAt == nil || now.Before(*e.ExpiresAt)
+}
+
+type snapshot struct {
+	Revision int64   `json:"revision"`
+	Entries  []Entry `json:"entries"`
+}
+
+// List is the in-memory copy of the blocklist. Until the first successful
+// refresh it is not loaded, and callers must refuse what it would screen;
+// if payment-service cannot be reached after that, the last copy fetched
+// stays in use.
+type List struct {
+	url      string
+	client   *http.Client
+	interval time.Duration
+
+	mu       sync.RWMutex
+	revision int64
+	// fetchedAt is when payment-service last confirmed the copy current.
+	fetchedAt time.Time
+	emails    map[string]Entry
+	// networks holds IP entries by prefix length, lengths longest first.
+	networks map[int]map[netip.Prefix]Entry
+	lengths  []int
+}
+
+// NewList fetches the blocklist from the payment-service at baseURL,
+// giving up on each request after timeout. Run refreshes it every
+// interval.
+func NewList(baseURL string, timeout, interval time.Duration) *List {
+	return &List{
+		url: strings.TrimRight(baseURL, "/") + "/api/v1/internal/blocklist",
+		client: &http.Client{
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
+			Timeout:   timeout,
+		},
+		interval: interval,
+	}
+}
+
+// Refresh fetches the list if it changed since the last fetch.
+func (l *List) Refresh(ctx context.Context) error {
+	token, err := serviceToken()
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, l.url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+	l.mu.RLock()
+	if l.emails != nil {
+		req.Header.Set("If-None-Match", fmt.Sprintf(`"%d"`, l.revision))
+	}
+	l.mu.RUnlock()
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("payment-service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		l.mu.Lock()
+		l.fetchedAt = time.Now()
+		l.mu.Unlock()
+		return nil
+	case http.StatusOK:
+	default:
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+		return fmt.Errorf("payment-service responded %s", resp.Status)
+	}
+
+	var s snapshot
+	if err := json.NewDecoder(resp.Body).Decode(&s); err != nil {
+		return fmt.Errorf("decode blocklist: %w", err)
+	}
+	l.load(s)
+	return nil
+}
+
+// load replaces the copy with s's email and IP entries.
+func (l *List) load(s snapshot) {
+	emails := make(map[string]Entry)
+	networks := make(map[int]map[netip.Prefix]Entry)
+	var lengths []int
+	for _, e := range s.Entries {
+		switch e.Kind {
+		case KindEmail:
+			emails[e.Value] = e
+		case KindIP:
+			prefix, err := netip.ParsePrefix(e.Value)
+			if err != nil {
+				slog.Warn("Skipping unparseable blocklisted network", "value", e.Value)
+				continue
+			}
+			if networks[prefix.Bits()] == nil {
+				networks[prefix.Bits()] = make(map[netip.Prefix]Entry)
+				lengths = append(lengths, prefix.Bits())
+			}
+			networks[prefix.Bits()][prefix] = e
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(lengths)))
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.fetchedAt = time.Now()
+	if l.emails != nil && s.Revision < l.revision {
+		return
+	}
+	l.revision, l.emails, l.networks, l.lengths = s.Revision, emails, networks, lengths
+}
+
+// Loaded reports whether a copy of the list has been fetched.
+func (l *List) Loaded() bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.emails != nil
+}
+
+// Check is a readiness check failing until the list is first loaded. A
+// stale copy passes; Run logs while it is.
+func (l *List) Check(ctx context.Context) error {
+	if !l.Loaded() {
+		return ErrNotLoaded
+	}
+	return nil
+}
+
+// Run refreshes the list every interval until ctx is cancelled.
+func (l *List) Run(ctx context.Context) {
+	slog.Info("Blocklist refresher started", "interval", l.interval)
+
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Blocklist refresher stopped")
+			return
+		case <-ticker.C:
+		}
+		if err := l.Refresh(ctx); err != nil && ctx.Err() == nil {
+			l.logFailure(err)
+		}
+	}
+}
+
+// logFailure reports a failed refresh and how stale it leaves the list.
+func (l *List) logFailure(err error) {
+	l.mu.RLock()
+	loaded, fetchedAt := l.emails != nil, l.fetchedAt
+	l.mu.RUnlock()
+	if !loaded {
+		slog.Error("Blocklist not loaded; refusing registrations and logins", "error", err)
+		return
+	}
+	slog.Error("Blocklist refresh failed; screening with a stale copy",
+		"fetched_at", fetchedAt, "stale_for", time.Since(fetchedAt).Round(time.Second), "error", err)
+}
+
+// Email returns the entry blocking email, or its domain, if one does.
+func (l *List) Email(email string) (Entry, bool) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return Entry{}, false
+	}
+	now := time.Now()
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if e, ok := l.emails[email]; ok && e.active(now) {
+		return e, true
+	}
+	if i := strings.LastIndex(email, "@"); i >= 0 {
+		if e, ok := l.emails[email[i:]]; ok && e.active(now) {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// IP returns the entry for the most specific network containing addr, if
+// one does.
+func (l *List) IP(addr netip.Addr) (Entry, bool) {
+	if !addr.IsValid() {
+		return Entry{}, false
+	}
+	addr = addr.Unmap()
+	now := time.Now()
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	for _, bits := range l.lengths {
+		if bits > addr.BitLen() {
+			continue
+		}
+		prefix, err := addr.Prefix(bits)
+		if err != nil {
+			continue
+		}
+		if e, ok := l.networks[bits][prefix]; ok && e.active(now) {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// serviceToken signs a short-lived token identifying this service to
+// payment-service's internal routes.
+func serviceToken() (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"email":   "auth-service",
+		"user_id": 0,
+		"role":    "service",
+		"exp":     now.Add(time.Minute).Unix(),
+		"iat":     now.Unix(),
+		"iss":     "gopay-lite",
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(config.GetJWTSecret()))
+}
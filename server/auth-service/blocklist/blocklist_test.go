@@ -0,0 +1,138 @@
+package blocklist
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestListMatching(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	l := NewList("http://payment-service", time.Second, time.Minute)
+	l.load(snapshot{Revision: 3, Entries: []Entry{
+		{Kind: KindEmail, Value: "fraud@example.com", Reason: "chargebacks"},
+		{Kind: KindEmail, Value: "@throwaway.example", Reason: "disposable domain", ExpiresAt: &future},
+		{Kind: KindEmail, Value: "lapsed@example.com", Reason: "expired", ExpiresAt: &past},
+		{Kind: KindIP, Value: "198.51.100.0/24", Reason: "botnet range"},
+		{Kind: KindIP, Value: "198.51.100.7/32", Reason: "single host"},
+		{Kind: KindIP, Value: "2001:db8::/32", Reason: "v6 range"},
+		{Kind: KindIP, Value: "203.0.113.0/24", Reason: "expired range", ExpiresAt: &past},
+		{Kind: KindIP, Value: "not-a-network", Reason: "skipped"},
+		{Kind: "account", Value: "50100123456789", Reason: "screened by payment-service"},
+	}})
+
+	emails := []struct {
+		email  string
+		reason string // empty when not blocked
+	}{
+		{"fraud@example.com", "chargebacks"},
+		{"  FRAUD@Example.com ", "chargebacks"},
+		{"anyone@throwaway.example", "disposable domain"},
+		{"someone@sub.throwaway.example", ""},
+		{"lapsed@example.com", ""},
+		{"honest@example.com", ""},
+		{"", ""},
+	}
+	for _, tt := range emails {
+		e, ok := l.Email(tt.email)
+		if ok != (tt.reason != "") || e.Reason != tt.reason {
+			t.Errorf("Email(%q) = %q, %t; want %q", tt.email, e.Reason, ok, tt.reason)
+		}
+	}
+
+	ips := []struct {
+		ip     string
+		reason string
+	}{
+		{"198.51.100.7", "single host"}, // the most specific network wins
+		{"198.51.100.8", "botnet range"},
+		{"::ffff:198.51.100.9", "botnet range"},
+		{"2001:db8:1::5", "v6 range"},
+		{"203.0.113.5", ""},
+		{"192.0.2.1", ""},
+	}
+	for _, tt := range ips {
+		e, ok := l.IP(netip.MustParseAddr(tt.ip))
+		if ok != (tt.reason != "") || e.Reason != tt.reason {
+			t.Errorf("IP(%s) = %q, %t; want %q", tt.ip, e.Reason, ok, tt.reason)
+		}
+	}
+	if _, ok := l.IP(netip.Addr{}); ok {
+		t.Error("an invalid address matched")
+	}
+}
+
+func TestListRefresh(t *testing.T) {
+	var status, revision atomic.Int64
+	revision.Store(1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if code := status.Load(); code != 0 {
+			w.WriteHeader(int(code))
+			return
+		}
+		rev := revision.Load()
+		if r.Header.Get("If-None-Match") == fmt.Sprintf(`"%d"`, rev) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		json.NewEncoder(w).Encode(snapshot{Revision: rev, Entries: []Entry{
+			{Kind: KindEmail, Value: "fraud@example.com", Reason: fmt.Sprintf("revision %d", rev)},
+		}})
+	}))
+	defer server.Close()
+	ctx := context.Background()
+
+	l := NewList(server.URL, time.Second, time.Minute)
+	if l.Loaded() || !errors.Is(l.Check(ctx), ErrNotLoaded) {
+		t.Fatal("a new list reports itself loaded")
+	}
+
+	// Failing before the first load leaves it unloaded
+	status.Store(http.StatusServiceUnavailable)
+	if err := l.Refresh(ctx); err == nil {
+		t.Fatal("refresh succeeded against a failing server")
+	}
+	if l.Loaded() {
+		t.Fatal("list loaded from a failed refresh")
+	}
+
+	status.Store(0)
+	if err := l.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !l.Loaded() || l.Check(ctx) != nil {
+		t.Fatal("list not loaded after a successful refresh")
+	}
+	if e, ok := l.Email("fraud@example.com"); !ok || e.Reason != "revision 1" {
+		t.Fatalf("after first refresh: %q, %t", e.Reason, ok)
+	}
+
+	// Unchanged, then changed
+	if err := l.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	revision.Store(2)
+	if err := l.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if e, _ := l.Email("fraud@example.com"); e.Reason != "revision 2" {
+		t.Fatalf("after change: %q, want revision 2", e.Reason)
+	}
+
+	// An unreachable payment-service keeps the last copy in use
+	status.Store(http.StatusBadGateway)
+	if err := l.Refresh(ctx); err == nil {
+		t.Fatal("refresh succeeded against a failing server")
+	}
+	if e, ok := l.Email("fraud@example.com"); !ok || e.Reason != "revision 2" || l.Check(ctx) != nil {
+		t.Fatalf("stale copy dropped: %q, %t", e.Reason, ok)
+	}
+}
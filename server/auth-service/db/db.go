@@ -2,8 +2,8 @@ package db
 
 import (
 	"database/sql"
-	"fmt"
-	"log"
+	"log/slog"
+	"net/url"
 	"os"
 
 	_ "github.com/lib/pq"
@@ -11,25 +11,28 @@ import (
 
 var DB *sql.DB
 
-func Init() {
-	dbUser := os.Getenv("DB_USER")
-	dbPassword := os.Getenv("DB_PASSWORD")
-	dbHost := os.Getenv("DB_HOST")
-	dbPort := os.Getenv("DB_PORT")
-	dbName := os.Getenv("DB_NAME")
-
-	connStr := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable",
-		dbUser, dbPassword, dbHost, dbPort, dbName)
-
+// Init opens the connection pool for dsn and verifies it with a ping.
+func Init(dsn string) {
 	var err error
-	DB, err = sql.Open("postgres", connStr)
+	DB, err = sql.Open("postgres", dsn)
 	if err != nil {
-		log.Fatal("Failed to open DB:", err)
+		slog.Error("Failed to open DB", "error", err)
+		os.Exit(1)
 	}
 
 	if err = DB.Ping(); err != nil {
-		log.Fatal("Failed to connect to DB:", err)
+		slog.Error("Failed to connect to DB", "url", redactURL(dsn), "error", err)
+		os.Exit(1)
 	}
 
-	log.Println("✅ Connected to PostgreSQL database")
+	slog.Info("Connected to PostgreSQL database", "url", redactURL(dsn))
+}
+
+// redactURL hides the password in a connection URL before it is logged.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "[unparseable]"
+	}
+	return u.Redacted()
 }
@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS users (
+    id         SERIAL PRIMARY KEY,
+    name       VARCHAR(255) NOT NULL,
+    email      VARCHAR(255) NOT NULL,
+    password   VARCHAR(255) NOT NULL, -- bcrypt hash
+    created_at TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT users_email_key UNIQUE (email),
+    CONSTRAINT users_name_not_blank CHECK (length(trim(name)) > 0),
+    CONSTRAINT users_email_format CHECK (position('@' IN email) > 1)
+);
+
+-- Keeps updated_at current on every UPDATE; shared by later auth tables.
+CREATE OR REPLACE FUNCTION auth_touch_updated_at() RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = NOW();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS users_touch_updated_at ON users;
+CREATE TRIGGER users_touch_updated_at
+    BEFORE UPDATE ON users
+    FOR EACH ROW EXECUTE FUNCTION auth_touch_updated_at();
@@ -0,0 +1,6 @@
+-- Role carried in the JWT "role" claim. Admins are promoted out of band
+-- with `auth-service user set-role <email> admin`.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(16) NOT NULL DEFAULT 'user';
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE users ADD CONSTRAINT users_role_check CHECK (role IN ('user', 'admin'));
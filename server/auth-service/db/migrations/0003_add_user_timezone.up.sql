@@ -0,0 +1,3 @@
+-- IANA time zone the user's documents (receipts, statements) are shown in.
+-- Validated by the service with time.LoadLocation.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT 'UTC';
@@ -1,41 +1,201 @@
 package config
 
 import (
-	"log"
+	"io"
+	"net/netip"
+	"net/url"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
+// Config is the complete auth-service configuration.
+type Config struct {
+	Env  string `env:"APP_ENV" default:"development"`
+	Port string `env:"PORT" default:"8083"`
+
+	// ReadinessTimeout bounds each /readyz dependency check;
+	// ShutdownDrainDelay is how long /readyz fails before the server stops.
+	ReadinessTimeout   time.Duration `env:"READINESS_TIMEOUT" default:"2s"`
+	ShutdownDrainDelay time.Duration `env:"SHUTDOWN_DRAIN_DELAY" default:"5s"`
+
+	// DatabaseURL takes precedence; otherwise the DSN is built from DB_*.
+	DatabaseURL string `env:"DATABASE_URL" secret:"true"`
+	DBHost      string `env:"DB_HOST" default:"localhost"`
+	DBPort      string `env:"DB_PORT" default:"5432"`
+	DBUser      string `env:"DB_USER"`
+	DBPassword  string `env:"DB_PASSWORD" secret:"true"`
+	DBName      string `env:"DB_NAME"`
+	DBSSLMode   string `env:"DB_SSLMODE" default:"disable"`
+	AutoMigrate bool   `env:"AUTO_MIGRATE" default:"false"`
+
+	JWTSecret string        `env:"JWT_SECRET" secret:"true"`
+	JWTTTL    time.Duration `env:"JWT_TTL" default:"24h"`
+
+	// Registrations and logins are screened against the blocklist kept by
+	// payment-service, fetched every BlocklistRefreshInterval.
+	PaymentServiceURL        string        `env:"PAYMENT_SERVICE_URL" default:"http://localhost:8084"`
+	PaymentServiceTimeout    time.Duration `env:"PAYMENT_SERVICE_TIMEOUT" default:"5s"`
+	BlocklistRefreshInterval time.Duration `env:"BLOCKLIST_REFRESH_INTERVAL" default:"10s"`
+
+	// Requests from TrustedProxies, such as the API gateway, may name the
+	// client in X-Forwarded-For; from anywhere else the header is ignored.
+	// Entries are CIDRs or single addresses.
+	TrustedProxies []string `env:"TRUSTED_PROXIES" default:"127.0.0.1/32,::1/128"`
+
+	CORSAllowedOrigins []string `env:"CORS_ALLOWED_ORIGINS" default:"http://localhost:3000"`
+
+	LogLevel        string   `env:"LOG_LEVEL" default:"info"`
+	LogFormat       string   `env:"LOG_FORMAT" default:"json"`
+	LogRedactFields []string `env:"LOG_REDACT_FIELDS"`
+	TracesExporter  string   `env:"OTEL_TRACES_EXPORTER" default:"none"`
+
+	sources        map[string]string
+	trustedProxies []netip.Prefix
+}
+
+var current = &Config{}
+
 // LoadEnv loads environment variables.
 // In dev: loads from ../.env using godotenv.
 // In Docker: relies on env vars passed at runtime.
-func LoadEnv() {
-	if os.Getenv("RUNNING_IN_DOCKER") != "true" {
-		err := godotenv.Load("../.env")
+// It runs before the logger is configured, so failures are returned rather
+// than logged.
+func LoadEnv() error {
+	if os.Getenv("RUNNING_IN_DOCKER") == "true" {
+		return nil
+	}
+	return godotenv.Load("../.env")
+}
+
+// Load reads the configuration from the environment, *_FILE secrets and the
+// optional CONFIG_FILE, then validates it. The returned Config is populated
+// even when validation fails so it can still be printed; every problem is
+// reported in the returned error.
+func Load() (*Config, error) {
+	cfg := &Config{}
+	l := newLoader()
+	l.fill(cfg)
+	cfg.sources = l.sources
+
+	v := &validator{errs: l.errs}
+	cfg.validate(v)
+
+	current = cfg
+	return cfg, v.err()
+}
+
+func (c *Config) validate(v *validator) {
+	switch c.Env {
+	case "development", "staging", "production":
+	default:
+		v.errorf("APP_ENV must be development, staging or production, got %q", c.Env)
+	}
+
+	if c.ReadinessTimeout <= 0 {
+		v.errorf("READINESS_TIMEOUT must be positive")
+	}
+	if c.ShutdownDrainDelay < 0 {
+		v.errorf("SHUTDOWN_DRAIN_DELAY must not be negative")
+	}
+
+	if c.DatabaseURL == "" {
+		v.require("DB_USER (or DATABASE_URL)", c.DBUser)
+		v.require("DB_NAME (or DATABASE_URL)", c.DBName)
+	} else if _, err := url.Parse(c.DatabaseURL); err != nil {
+		v.errorf("DATABASE_URL is not a valid URL")
+	}
+
+	if c.JWTTTL <= 0 {
+		v.errorf("JWT_TTL must be positive")
+	}
+
+	if u, err := url.Parse(c.PaymentServiceURL); err != nil || u.Scheme == "" || u.Host == "" {
+		v.errorf("PAYMENT_SERVICE_URL must be an absolute URL")
+	}
+	if c.PaymentServiceTimeout <= 0 {
+		v.errorf("PAYMENT_SERVICE_TIMEOUT must be positive")
+	}
+	if c.BlocklistRefreshInterval < time.Second {
+		v.errorf("BLOCKLIST_REFRESH_INTERVAL must be at least 1s")
+	}
+
+	c.trustedProxies = make([]netip.Prefix, 0, len(c.TrustedProxies))
+	for _, entry := range c.TrustedProxies {
+		prefix, err := parsePrefix(strings.TrimSpace(entry))
 		if err != nil {
-			log.Println("⚠️  Warning: Could not load .env file (for dev only)")
-		} else {
-			log.Println("✅ .env file loaded successfully for dev")
+			v.errorf("TRUSTED_PROXIES entry %q must be a CIDR or an IP address", entry)
+			continue
+		}
+		c.trustedProxies = append(c.trustedProxies, prefix)
+	}
+
+	if c.IsProduction() {
+		v.strongSecret("JWT_SECRET", c.JWTSecret)
+		if c.DatabaseURL == "" {
+			v.require("DB_PASSWORD", c.DBPassword)
+		}
+		for _, origin := range c.CORSAllowedOrigins {
+			if origin == "*" {
+				v.errorf("CORS_ALLOWED_ORIGINS must not contain * in production")
+			}
 		}
 	} else {
-		log.Println("🚀 Running in Docker – using passed env variables")
+		v.require("JWT_SECRET", c.JWTSecret)
 	}
+}
 
-	// 🔍 Debug env values (you can remove this after testing)
-	log.Println("RUNNING_IN_DOCKER:", os.Getenv("RUNNING_IN_DOCKER"))
-	log.Println("DB_HOST:", os.Getenv("DB_HOST"))
-	log.Println("DB_PORT:", os.Getenv("DB_PORT"))
-	log.Println("DB_USER:", os.Getenv("DB_USER"))
-	log.Println("DB_NAME:", os.Getenv("DB_NAME"))
-	log.Println("DB_PASSWORD:", os.Getenv("DB_PASSWORD"))
+// IsProduction reports whether APP_ENV is production.
+func (c *Config) IsProduction() bool {
+	return c.Env == "production"
 }
 
-// GetJWTSecret retrieves the JWT secret from environment variables.
-func GetJWTSecret() string {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		log.Println("⚠️  Warning: JWT_SECRET is not set")
+// TrustedProxyPrefixes returns the parsed TRUSTED_PROXIES.
+func (c *Config) TrustedProxyPrefixes() []netip.Prefix {
+	return c.trustedProxies
+}
+
+// parsePrefix parses a CIDR, or an address as a prefix of just itself.
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		return prefix.Masked(), err
 	}
-	return secret
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()), nil
+}
+
+// DSN returns the Postgres connection string.
+func (c *Config) DSN() string {
+	if c.DatabaseURL != "" {
+		return c.DatabaseURL
+	}
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.DBUser, c.DBPassword),
+		Host:     c.DBHost + ":" + c.DBPort,
+		Path:     "/" + c.DBName,
+		RawQuery: "sslmode=" + c.DBSSLMode,
+	}
+	return u.String()
+}
+
+// Print writes the effective configuration with secrets masked.
+func (c *Config) Print(w io.Writer) error {
+	return printConfig(w, c, c.sources)
+}
+
+// Get returns the configuration most recently returned by Load.
+func Get() *Config {
+	return current
+}
+
+// GetJWTSecret returns the JWT signing secret from the loaded configuration.
+func GetJWTSecret() string {
+	return current.JWTSecret
 }
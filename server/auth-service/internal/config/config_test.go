@@ -0,0 +1,112 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// loadWith runs Load with only env set among the variables Config reads.
+func loadWith(t *testing.T, env map[string]string) (*Config, error) {
+	t.Helper()
+	t.Setenv("CONFIG_FILE", "")
+	ct := reflect.TypeOf(Config{})
+	for i := 0; i < ct.NumField(); i++ {
+		for _, name := range strings.Split(ct.Field(i).Tag.Get("env"), ",") {
+			if name != "" {
+				t.Setenv(name, "")
+				t.Setenv(name+"_FILE", "")
+			}
+		}
+	}
+	for k, v := range env {
+		t.Setenv(k, v)
+	}
+	prev := current
+	t.Cleanup(func() { current = prev })
+	return Load()
+}
+
+// with returns base plus extra.
+func with(base map[string]string, extra ...string) map[string]string {
+	env := make(map[string]string, len(base)+len(extra)/2)
+	for k, v := range base {
+		env[k] = v
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		env[extra[i]] = extra[i+1]
+	}
+	return env
+}
+
+var (
+	devEnv = map[string]string{
+		"DB_USER":    "gopay",
+		"DB_NAME":    "gopay",
+		"JWT_SECRET": "dev",
+	}
+	prodEnv = with(devEnv,
+		"APP_ENV", "production",
+		"JWT_SECRET", strings.Repeat("s", 40),
+		"DB_PASSWORD", "db-password",
+		"CORS_ALLOWED_ORIGINS", "https://app.example.com",
+	)
+)
+
+func TestLoadValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr []string
+	}{
+		{name: "development", env: devEnv},
+		{name: "production", env: prodEnv},
+		{name: "database url instead of parts", env: with(devEnv, "DB_USER", "", "DB_NAME", "", "DATABASE_URL", "postgres://u:p@db/gopay")},
+		{
+			name:    "nothing set",
+			env:     nil,
+			wantErr: []string{"DB_USER (or DATABASE_URL) is required", "DB_NAME (or DATABASE_URL) is required", "JWT_SECRET is required"},
+		},
+		{name: "unknown environment", env: with(devEnv, "APP_ENV", "prod"), wantErr: []string{`APP_ENV must be development, staging or production, got "prod"`}},
+		{name: "bad duration", env: with(devEnv, "JWT_TTL", "a day"), wantErr: []string{`JWT_TTL: invalid duration "a day"`}},
+		{name: "negative ttl", env: with(devEnv, "JWT_TTL", "-1h"), wantErr: []string{"JWT_TTL must be positive"}},
+		{name: "payment service url", env: with(devEnv, "PAYMENT_SERVICE_URL", "payment-service:8084"), wantErr: []string{"PAYMENT_SERVICE_URL must be an absolute URL"}},
+		{name: "blocklist refresh", env: with(devEnv, "BLOCKLIST_REFRESH_INTERVAL", "100ms"), wantErr: []string{"BLOCKLIST_REFRESH_INTERVAL must be at least 1s"}},
+		{name: "trusted proxy", env: with(devEnv, "TRUSTED_PROXIES", "10.0.0.0/8,gateway"), wantErr: []string{`TRUSTED_PROXIES entry "gateway"`}},
+		{name: "production placeholder secret", env: with(prodEnv, "JWT_SECRET", "changeme"), wantErr: []string{"JWT_SECRET uses a well-known placeholder value"}},
+		{name: "production short secret", env: with(prodEnv, "JWT_SECRET", "not-quite-long-enough"), wantErr: []string{"JWT_SECRET must be at least 32 characters in production"}},
+		{name: "production without secrets", env: with(prodEnv, "JWT_SECRET", "", "DB_PASSWORD", ""), wantErr: []string{"JWT_SECRET is required in production", "DB_PASSWORD is required"}},
+		{name: "production database url", env: with(prodEnv, "DB_PASSWORD", "", "DATABASE_URL", "postgres://u:p@db/gopay")},
+		{name: "production any origin", env: with(prodEnv, "CORS_ALLOWED_ORIGINS", "*"), wantErr: []string{"CORS_ALLOWED_ORIGINS must not contain * in production"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadWith(t, tt.env)
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("no error, want %q", tt.wantErr)
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error lacks %q:\n%v", want, err)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadSecretFile(t *testing.T) {
+	path := writeFile(t, "jwt_secret", "from-a-secret-file\n")
+	cfg, err := loadWith(t, with(devEnv, "JWT_SECRET", "", "JWT_SECRET_FILE", path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.JWTSecret != "from-a-secret-file" || GetJWTSecret() != cfg.JWTSecret {
+		t.Errorf("JWT secret %q, GetJWTSecret %q; want the file's contents", cfg.JWTSecret, GetJWTSecret())
+	}
+}
@@ -0,0 +1,195 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type sample struct {
+	Name   string        `env:"CFGTEST_NAME,CFGTEST_OLD_NAME" default:"fallback"`
+	Count  int           `env:"CFGTEST_COUNT" default:"1"`
+	Secret string        `env:"CFGTEST_SECRET" secret:"true"`
+	Items  []string      `env:"CFGTEST_ITEMS"`
+	Wait   time.Duration `env:"CFGTEST_WAIT" default:"1s"`
+}
+
+// sampleEnv clears every variable sample reads so only env applies.
+func sampleEnv(t *testing.T, env map[string]string) {
+	t.Helper()
+	for _, name := range []string{"CONFIG_FILE", "CFGTEST_NAME", "CFGTEST_OLD_NAME", "CFGTEST_COUNT", "CFGTEST_SECRET", "CFGTEST_ITEMS", "CFGTEST_WAIT"} {
+		t.Setenv(name, "")
+		t.Setenv(name+"_FILE", "")
+	}
+	for k, v := range env {
+		t.Setenv(k, v)
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFill(t *testing.T) {
+	secretFile := writeFile(t, "secret", "from-file\n")
+	jsonFile := writeFile(t, "config.json", `{"CFGTEST_NAME": "json", "CFGTEST_ITEMS": ["a", "b"], "CFGTEST_COUNT": 7}`)
+	envFile := writeFile(t, "config.env", "CFGTEST_NAME=dotenv\nCFGTEST_WAIT=5m\n")
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    sample
+		source  string // of CFGTEST_NAME
+		wantErr string
+	}{
+		{
+			name:   "defaults",
+			want:   sample{Name: "fallback", Count: 1, Wait: time.Second},
+			source: "default",
+		},
+		{
+			name:   "environment",
+			env:    map[string]string{"CFGTEST_NAME": "env", "CFGTEST_COUNT": "3", "CFGTEST_ITEMS": " a, ,b ", "CFGTEST_WAIT": "2m"},
+			want:   sample{Name: "env", Count: 3, Items: []string{"a", "b"}, Wait: 2 * time.Minute},
+			source: "env CFGTEST_NAME",
+		},
+		{
+			name:   "deprecated alias",
+			env:    map[string]string{"CFGTEST_OLD_NAME": "old"},
+			want:   sample{Name: "old", Count: 1, Wait: time.Second},
+			source: "env CFGTEST_OLD_NAME (deprecated, use CFGTEST_NAME)",
+		},
+		{
+			name:   "current name wins over alias",
+			env:    map[string]string{"CFGTEST_NAME": "new", "CFGTEST_OLD_NAME": "old"},
+			want:   sample{Name: "new", Count: 1, Wait: time.Second},
+			source: "env CFGTEST_NAME",
+		},
+		{
+			name:   "secret file",
+			env:    map[string]string{"CFGTEST_SECRET_FILE": secretFile},
+			want:   sample{Name: "fallback", Count: 1, Secret: "from-file", Wait: time.Second},
+			source: "default",
+		},
+		{
+			name:   "json config file",
+			env:    map[string]string{"CONFIG_FILE": jsonFile},
+			want:   sample{Name: "json", Count: 7, Items: []string{"a", "b"}, Wait: time.Second},
+			source: "config file",
+		},
+		{
+			name:   "env config file",
+			env:    map[string]string{"CONFIG_FILE": envFile},
+			want:   sample{Name: "dotenv", Count: 1, Wait: 5 * time.Minute},
+			source: "config file",
+		},
+		{
+			name:   "environment wins over config file",
+			env:    map[string]string{"CONFIG_FILE": jsonFile, "CFGTEST_NAME": "env"},
+			want:   sample{Name: "env", Count: 7, Items: []string{"a", "b"}, Wait: time.Second},
+			source: "env CFGTEST_NAME",
+		},
+		{
+			name:    "bad integer",
+			env:     map[string]string{"CFGTEST_COUNT": "many"},
+			want:    sample{Name: "fallback", Wait: time.Second},
+			source:  "default",
+			wantErr: `CFGTEST_COUNT: invalid integer "many"`,
+		},
+		{
+			name:    "missing secret file",
+			env:     map[string]string{"CFGTEST_SECRET_FILE": filepath.Join(t.TempDir(), "absent")},
+			want:    sample{Name: "fallback", Count: 1, Wait: time.Second},
+			source:  "default",
+			wantErr: "CFGTEST_SECRET_FILE",
+		},
+		{
+			name:    "missing config file",
+			env:     map[string]string{"CONFIG_FILE": filepath.Join(t.TempDir(), "absent.json")},
+			want:    sample{Name: "fallback", Count: 1, Wait: time.Second},
+			source:  "default",
+			wantErr: "CONFIG_FILE",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sampleEnv(t, tt.env)
+			var got sample
+			l := newLoader()
+			l.fill(&got)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("filled %+v, want %+v", got, tt.want)
+			}
+			if l.sources["CFGTEST_NAME"] != tt.source {
+				t.Errorf("CFGTEST_NAME from %q, want %q", l.sources["CFGTEST_NAME"], tt.source)
+			}
+			err := (&validator{errs: l.errs}).err()
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("err = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStrongSecret(t *testing.T) {
+	tests := []struct {
+		value   string
+		wantErr string
+	}{
+		{"", "is required in production"},
+		{"changeme", "well-known placeholder"},
+		{"Your-Secret-Key", "well-known placeholder"},
+		{"short-but-not-a-placeholder", "at least 32 characters"},
+		{strings.Repeat("k", minSecretLength), ""},
+	}
+	for _, tt := range tests {
+		v := &validator{}
+		v.strongSecret("JWT_SECRET", tt.value)
+		err := v.err()
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%q: unexpected error: %v", tt.value, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("%q: err = %v, want it to mention %q", tt.value, err, tt.wantErr)
+		}
+	}
+}
+
+func TestPrintMasksSecrets(t *testing.T) {
+	cfg := sample{Name: "visible", Secret: "hunter2-hunter2"}
+	var buf bytes.Buffer
+	if err := printConfig(&buf, &cfg, map[string]string{"CFGTEST_NAME": "env CFGTEST_NAME"}); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if strings.Contains(out, "hunter2") {
+		t.Errorf("secret printed:\n%s", out)
+	}
+	for _, want := range []string{"visible", "env CFGTEST_NAME", "******** (15 chars)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+
+	cfg.Secret = ""
+	buf.Reset()
+	if err := printConfig(&buf, &cfg, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "(unset)") {
+		t.Errorf("unset secret not shown as such:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,99 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// logged logs attr through a logger from New and returns the decoded record.
+func logged(t *testing.T, ctx context.Context, attr slog.Attr) map[string]any {
+	t.Helper()
+	var buf bytes.Buffer
+	New(&buf, "test", Options{RedactFields: []string{"OTP"}}).LogAttrs(ctx, slog.LevelInfo, "msg", attr)
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("%s: %v", buf.String(), err)
+	}
+	return record
+}
+
+// lookup follows the dotted path through nested groups.
+func lookup(record map[string]any, path string) any {
+	var v any = record
+	for _, k := range strings.Split(path, ".") {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = m[k]
+	}
+	return v
+}
+
+func TestRedact(t *testing.T) {
+	header := http.Header{}
+	header.Set("Authorization", "Bearer abc.def.ghi")
+	header.Set("X-Request-Id", "req-1")
+
+	tests := []struct {
+		name string
+		attr slog.Attr
+		path string
+		want any
+	}{
+		{"password", slog.String("password", "hunter2"), "password", Redacted},
+		{"key case and substring", slog.String("DB_Password", "hunter2"), "DB_Password", Redacted},
+		{"token", slog.String("access_token", "abc"), "access_token", Redacted},
+		{"signature", slog.String("razorpay_signature", "abc"), "razorpay_signature", Redacted},
+		{"non-string secret", slog.Int("secret_version", 3), "secret_version", Redacted},
+		{"extra field", slog.String("otp_code", "123456"), "otp_code", Redacted},
+		{"account", slog.String("from_account", "50100123456789"), "from_account", "**********6789"},
+		{"short vpa", slog.String("vpa", "a@b"), "vpa", "***"},
+		{"card", slog.String("card_number", "4111111111111111"), "card_number", "************1111"},
+		{"non-string account", slog.Int("account_count", 2), "account_count", float64(2)},
+		{"unrelated", slog.String("order_id", "order_123"), "order_id", "order_123"},
+		{"group", slog.Group("req", slog.String("token", "abc")), "req.token", Redacted},
+		{"group account", slog.Group("req", slog.String("to_account", "50100987654321")), "req.to_account", "**********4321"},
+		{"header", slog.Any("headers", header), "headers.Authorization", []any{Redacted}},
+		{"header pointer", slog.Any("headers", &header), "headers.Authorization", []any{Redacted}},
+		{"other header", slog.Any("headers", header), "headers.X-Request-Id", []any{"req-1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := lookup(logged(t, context.Background(), tt.attr), tt.path)
+			if b, _ := json.Marshal(got); string(b) != mustJSON(t, tt.want) {
+				t.Errorf("%s = %s, want %s", tt.path, b, mustJSON(t, tt.want))
+			}
+		})
+	}
+
+	// Redacting a header must not change the request's own header
+	if header.Get("Authorization") != "Bearer abc.def.ghi" {
+		t.Errorf("header redacted in place: %q", header.Get("Authorization"))
+	}
+}
+
+func TestRedactContextAttrs(t *testing.T) {
+	ctx := WithAttrs(context.Background(), slog.String("session_token", "abc"), slog.String("request_id", "req-1"))
+	record := logged(t, ctx, slog.String("event", "login"))
+	if got := record["session_token"]; got != Redacted {
+		t.Errorf("session_token = %v, want %s", got, Redacted)
+	}
+	if got := record["request_id"]; got != "req-1" {
+		t.Errorf("request_id = %v, want req-1", got)
+	}
+}
+
+func mustJSON(t *testing.T, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
@@ -2,22 +2,29 @@ package main
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
+	_ "time/tzdata" // user timezones must resolve in minimal images
 
 	"gopay-lite/auth"
+	"gopay-lite/blocklist"
 	"gopay-lite/db"
 	_ "gopay-lite/docs" // Swagger generated docs
+	"gopay-lite/health"
 	"gopay-lite/internal/config"
+	"gopay-lite/logging"
+	"gopay-lite/metrics"
+	"gopay-lite/middleware"
+	"gopay-lite/tracing"
 
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
 	httpSwagger "github.com/swaggo/http-swagger/v2"
-	"go.uber.org/zap"
 )
 
 // @title GoPay-Lite Auth API
@@ -37,16 +44,89 @@ import (
 
 func main() {
 	//  Load env using internal/config
-	config.LoadEnv()
+	envErr := config.LoadEnv()
+	cfg, cfgErr := config.Load()
+
+	// `auth-service config print` shows the effective configuration
+	if len(os.Args) > 2 && os.Args[1] == "config" && os.Args[2] == "print" {
+		cfg.Print(os.Stdout)
+		if cfgErr != nil {
+			fmt.Fprintf(os.Stderr, "\ninvalid configuration:\n%v\n", cfgErr)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Structured logging
+	logging.Init("auth-service", logging.Options{
+		Level:        cfg.LogLevel,
+		Format:       cfg.LogFormat,
+		RedactFields: cfg.LogRedactFields,
+	})
+	if envErr != nil {
+		slog.Warn("Could not load .env file (for dev only)", "error", envErr)
+	}
+	if cfgErr != nil {
+		slog.Error("Invalid configuration", "error", cfgErr)
+		os.Exit(1)
+	}
+
+	db.Init(cfg.DSN())
+
+	// `auth-service migrate ...` manages the schema and exits
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(context.Background(), db.DB, os.Args[2:]); err != nil {
+			slog.Error("Migration failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if cfg.AutoMigrate {
+		if err := autoMigrate(context.Background(), db.DB); err != nil {
+			slog.Error("Auto-migration failed", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	// `auth-service user set-role <email> <role>` promotes or demotes a user
+	if len(os.Args) > 1 && os.Args[1] == "user" {
+		if err := runUser(context.Background(), db.DB, os.Args[2:]); err != nil {
+			slog.Error("User command failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
 
-	// Now your DB_*, JWT_SECRET etc. should be available to os.Getenv()
-	db.Init()
+	metrics.RegisterDB(db.DB)
+
+	// Tracing
+	shutdownTracing, err := tracing.Init(context.Background(), cfg.TracesExporter)
+	if err != nil {
+		slog.Error("Tracing setup failed", "error", err)
+		os.Exit(1)
+	}
+
+	// Registrations and logins are screened against payment-service's
+	// blocklist. Until it is first fetched they are refused and /readyz
+	// fails, so nobody gets through unscreened.
+	refreshCtx, stopRefresh := context.WithCancel(context.Background())
+	defer stopRefresh()
+	list := blocklist.NewList(cfg.PaymentServiceURL, cfg.PaymentServiceTimeout, cfg.BlocklistRefreshInterval)
+	if err := list.Refresh(refreshCtx); err != nil {
+		slog.Error("Blocklist not loaded; refusing registrations and logins until it is", "error", err)
+	}
+	auth.SetBlocklist(list)
+	go list.Run(refreshCtx)
 
 	// Router setup
 	r := mux.NewRouter()
 
 	// Middleware
+	r.Use(middleware.RequestID)
+	r.Use(middleware.ClientInfo)
+	r.Use(tracing.Middleware)
 	r.Use(loggingMiddleware)
+	r.Use(metrics.Middleware)
 	r.Use(mux.CORSMethodMiddleware(r))
 
 	// Swagger docs
@@ -54,8 +134,17 @@ func main() {
 		httpSwagger.URL("/swagger/doc.json"), // Adjust if needed
 	))
 
+	// Probes: /livez never touches dependencies, /readyz checks the DB and
+	// that the blocklist has loaded
+	checker := health.New(cfg.ReadinessTimeout)
+	checker.Register("database", health.DBCheck(db.DB))
+	checker.Register("blocklist", list.Check)
+
 	// Base handlers
-	r.HandleFunc("/health", healthCheck).Methods("GET")
+	r.HandleFunc("/livez", checker.LiveHandler).Methods("GET")
+	r.HandleFunc("/readyz", checker.ReadyHandler).Methods("GET")
+	r.HandleFunc("/health", checker.LiveHandler).Methods("GET") // kept for existing probes
+	r.Handle("/metrics", metrics.Handler()).Methods("GET")
 	r.HandleFunc("/", rootHandler).Methods("GET")
 
 	// Auth API group with versioned prefix
@@ -63,17 +152,16 @@ func main() {
 	api.HandleFunc("/register", auth.Register).Methods("POST")
 	api.HandleFunc("/login", auth.Login).Methods("POST")
 	api.HandleFunc("/me", auth.Me).Methods("GET")
+	api.HandleFunc("/me", auth.UpdateMe).Methods("PATCH")
 
 	// Server setup
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8083"
-	}
+	port := cfg.Port
 
 	corsOpts := handlers.CORS(
-		handlers.AllowedOrigins([]string{"http://localhost:3000"}), // or "*" for any origin (not for production)
-		handlers.AllowedMethods([]string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}),
-		handlers.AllowedHeaders([]string{"Content-Type", "Authorization"}),
+		handlers.AllowedOrigins(cfg.CORSAllowedOrigins),
+		handlers.AllowedMethods([]string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}),
+		handlers.AllowedHeaders([]string{"Content-Type", "Authorization", "X-Request-ID"}),
+		handlers.ExposedHeaders([]string{"X-Request-ID"}),
 	)
 
 	srv := &http.Server{
@@ -87,38 +175,39 @@ func main() {
 	done := make(chan os.Signal, 1)
 	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 
-	// Logging
-	logger, _ := zap.NewProduction()
-	defer logger.Sync()
-	logger.Info("Starting Auth Service", zap.String("port", port), zap.Time("started_at", time.Now()))
-
 	go func() {
-		log.Printf("Auth Service running at http://localhost:%s", port)
-		log.Printf("Swagger: http://localhost:%s/swagger/index.html", port)
+		slog.Info("Auth Service running",
+			"port", port,
+			"swagger", "http://localhost:"+port+"/swagger/index.html",
+		)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server error: %v", err)
+			slog.Error("Server error", "error", err)
+			os.Exit(1)
 		}
 	}()
 
 	<-done
-	log.Println("Shutting down...")
+	slog.Info("Shutting down...", "drain_delay", cfg.ShutdownDrainDelay)
+
+	// Fail readiness first so load balancers stop routing new traffic
+	checker.SetDraining()
+	time.Sleep(cfg.ShutdownDrainDelay)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatalf("Shutdown error: %v", err)
+		slog.Error("Shutdown error", "error", err)
+		os.Exit(1)
 	}
-	log.Println("Stopped gracefully")
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Warn("Tracing shutdown error", "error", err)
+	}
+	slog.Info("Stopped gracefully")
 }
 
 //============ Handlers ============
 
-func healthCheck(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("OK"))
-}
-
 func rootHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("GoPay-Lite Auth Service - See /swagger for docs"))
 }
@@ -128,8 +217,12 @@ func rootHandler(w http.ResponseWriter, r *http.Request) {
 func loggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
-		log.Printf("[REQUEST] %s %s", r.Method, r.URL.Path)
+		slog.DebugContext(r.Context(), "Request started", "method", r.Method, "path", r.URL.Path)
 		next.ServeHTTP(w, r)
-		log.Printf("[COMPLETED] %s %s in %v", r.Method, r.URL.Path, time.Since(start))
+		slog.InfoContext(r.Context(), "Request completed",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"duration", time.Since(start),
+		)
 	})
 }
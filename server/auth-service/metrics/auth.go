@@ -0,0 +1,31 @@
+package metrics
+
+import (
+	"database/sql"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var loginAttempts = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "login_attempts_total",
+	Help:      "Login attempts, by result (success or failure).",
+}, []string{"result"})
+
+// LoginSucceeded counts a successful login.
+func LoginSucceeded() {
+	loginAttempts.WithLabelValues("success").Inc()
+}
+
+// LoginFailed counts a rejected login.
+func LoginFailed() {
+	loginAttempts.WithLabelValues("failure").Inc()
+}
+
+// RegisterDB exports connection pool stats from sql.DB.Stats().
+func RegisterDB(db *sql.DB) {
+	prometheus.MustRegister(collectors.NewDBStatsCollector(db, "auth"))
+}
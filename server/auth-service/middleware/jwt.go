@@ -2,9 +2,10 @@ package middleware
 
 import (
 	"net/http"
-	"os"
 	"strings"
 
+	"gopay-lite/internal/config"
+
 	"github.com/golang-jwt/jwt/v5"
 )
 
@@ -24,8 +25,7 @@ func JWTAuth(next http.Handler) http.Handler {
 			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 				return nil, jwt.ErrSignatureInvalid
 			}
-			return []byte(os.Getenv("JWT_SECRET")), nil
-			// Match secret in auth.go
+			return []byte(config.GetJWTSecret()), nil
 		})
 
 		if err != nil || !token.Valid {
@@ -0,0 +1,52 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSpans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	shutdown := InitWithExporter(exporter)
+	defer shutdown(context.Background())
+
+	errNoRows := errors.New("sql: no rows in result set")
+	r := mux.NewRouter()
+	r.Use(Middleware)
+	r.HandleFunc("/api/v1/login", func(w http.ResponseWriter, r *http.Request) {
+		_, span := StartDBSpan(r.Context(), "SELECT", "users", "SELECT id FROM users WHERE email = $1")
+		End(span, errNoRows)
+		w.WriteHeader(http.StatusUnauthorized)
+	}).Methods(http.MethodPost)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/login", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("%d spans recorded, want 2", len(spans))
+	}
+	db, server := spans[0], spans[1]
+	if server.Name != "POST /api/v1/login" || server.SpanKind != trace.SpanKindServer {
+		t.Errorf("server span %q (%s), want POST /api/v1/login (server)", server.Name, server.SpanKind)
+	}
+	// The gateway's trace continues here
+	if server.SpanContext.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" || !server.Parent.IsRemote() {
+		t.Errorf("server span is in trace %s with parent %s, not the caller's", server.SpanContext.TraceID(), server.Parent.SpanID())
+	}
+	if db.Name != "SELECT users" || db.Parent.SpanID() != server.SpanContext.SpanID() {
+		t.Errorf("DB span %q is not a child of the server span", db.Name)
+	}
+	if db.Status.Code != codes.Error || db.Status.Description != errNoRows.Error() {
+		t.Errorf("DB span status %v, want the error", db.Status)
+	}
+}
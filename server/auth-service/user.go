@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopay-lite/audit"
+	"gopay-lite/auth"
+)
+
+const userUsage = `usage: auth-service user <command>
+
+commands:
+  set-role <email> <user|admin>   change a user's role; takes effect at next login`
+
+// runUser implements the `user` subcommand.
+func runUser(ctx context.Context, database *sql.DB, args []string) error {
+	if len(args) != 3 || args[0] != "set-role" {
+		return errors.New(userUsage)
+	}
+
+	email, role := strings.TrimSpace(args[1]), args[2]
+	if role != auth.RoleUser && role != auth.RoleAdmin {
+		return fmt.Errorf("invalid role %q: must be %s or %s", role, auth.RoleUser, auth.RoleAdmin)
+	}
+
+	tx, err := database.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var id int
+	var previous string
+	err = tx.QueryRowContext(ctx, "SELECT id, role FROM users WHERE email = $1 FOR UPDATE", email).Scan(&id, &previous)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("no user with email %q", email)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE users SET role = $1 WHERE id = $2", role, id); err != nil {
+		return err
+	}
+	err = audit.Append(ctx, tx, audit.Event{
+		Action:       "admin.user.role_changed",
+		ResourceType: "user",
+		ResourceID:   strconv.Itoa(id),
+		Before:       map[string]string{"role": previous},
+		After:        map[string]string{"role": role},
+		Detail:       "set from the command line",
+	})
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	fmt.Printf("%s is now %s\n", email, role)
+	return nil
+}
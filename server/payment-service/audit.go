@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/audit"
+)
+
+const auditUsage = `usage: payment-service audit <command>
+
+commands:
+  verify  check the audit log's hash chain; exits non-zero if it is broken`
+
+// runAudit implements the `audit` subcommand.
+func runAudit(ctx context.Context, database *sql.DB, args []string) error {
+	if len(args) != 1 || args[0] != "verify" {
+		return errors.New(auditUsage)
+	}
+
+	v, err := audit.Verify(ctx, database)
+	if err != nil {
+		return err
+	}
+	if !v.Intact() {
+		return fmt.Errorf("audit log broken at entry %d (%s) after %d entries checked", v.BrokenAt, v.Problem, v.Checked)
+	}
+	fmt.Printf("audit log intact: %d entries, last hash %s, %d waiting to be chained\n", v.Checked, v.LastHash, v.Pending)
+	return nil
+}
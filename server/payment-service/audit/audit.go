@@ -0,0 +1,331 @@
+// Package audit appends to the audit log shared with auth-service: who did
+// what to which resource, when, from where, and what it looked like before
+// and after.
+//
+// The log is append-only in the database, and each entry's hash covers its
+// content and the hash of the entry before it, so an edited, removed or
+// reordered entry breaks the chain from that point. Entries are written
+// unchained, by whatever transaction makes the change, and a Chainer links
+// them afterwards in short transactions of its own; only those take the
+// chain's lock. auth-service writes its entries the same way and leaves
+// chaining to this service.
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/middleware"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/tracing"
+)
+
+// lockKey is the advisory lock serialising chaining across replicas.
+const lockKey = 72817
+
+// service is the value of the service column for entries written here.
+const service = "payment"
+
+// genesisHash is the previous hash of the first entry.
+var genesisHash = strings.Repeat("0", 64)
+
+// Outcome is whether an audited action succeeded.
+type Outcome string
+
+const (
+	OutcomeSuccess Outcome = "success"
+	OutcomeFailure Outcome = "failure"
+)
+
+// Actor types.
+const (
+	ActorUser      = "user"
+	ActorAdmin     = "admin"
+	ActorService   = "service"   // another service with a service token
+	ActorRazorpay  = "razorpay"  // a gateway notification
+	ActorAnonymous = "anonymous" // an unauthenticated request, e.g. a payment link
+	ActorSystem    = "system"    // a background worker or the command line
+)
+
+// Actor is who performed an action.
+type Actor struct {
+	Type  string
+	ID    *int
+	Email string
+}
+
+type actorKey struct{}
+
+// WithActor attributes actions taken with ctx to a, overriding the token's
+// user.
+func WithActor(ctx context.Context, a Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, a)
+}
+
+// actorFrom returns who ctx acts for: an actor set with WithActor, else the
+// token's user, else anonymous within a request and the system outside one.
+func actorFrom(ctx context.Context) Actor {
+	if a, ok := ctx.Value(actorKey{}).(Actor); ok {
+		return a
+	}
+	if id, ok := ctx.Value(middleware.UserIDKey).(int); ok {
+		a := Actor{Type: ActorUser, ID: &id, Email: middleware.GetEmail(ctx)}
+		switch role, _ := ctx.Value(middleware.RoleKey).(string); role {
+		case middleware.RoleAdmin:
+			a.Type = ActorAdmin
+		case middleware.RoleService:
+			a.Type = ActorService
+		}
+		return a
+	}
+	if _, ok := middleware.GetClient(ctx); ok {
+		return Actor{Type: ActorAnonymous}
+	}
+	return Actor{Type: ActorSystem}
+}
+
+// Event is an action to audit. The actor, client and request ID are taken
+// from the context it is recorded with.
+type Event struct {
+	// Action is dot-separated, e.g. payment.created or admin.currency.updated.
+	Action  string
+	Outcome Outcome // default success
+	// ResourceType and ResourceID name what was acted on, e.g. payment 42.
+	ResourceType string
+	ResourceID   string
+	// Before and After are marshalled to JSON; nil is stored as NULL.
+	Before any
+	After  any
+	// Detail is free text, such as an admin's reason.
+	Detail string
+}
+
+// Entry is an audit log entry.
+type Entry struct {
+	ID           int64
+	CreatedAt    time.Time
+	Service      string
+	Action       string
+	Outcome      Outcome
+	ActorType    string
+	ActorID      *int
+	ActorEmail   string
+	IP           string
+	UserAgent    string
+	RequestID    string
+	ResourceType string
+	ResourceID   string
+	Before       json.RawMessage // nil when absent
+	After        json.RawMessage
+	Detail       string
+	// Seq is the entry's place in the chain, and PrevHash and Hash link it
+	// there; all are zero until the entry is chained.
+	Seq      int64
+	PrevHash string
+	Hash     string
+}
+
+// ID formats a numeric resource ID.
+func ID(id int64) string {
+	return strconv.FormatInt(id, 10)
+}
+
+// Append adds e to the log in tx, so it is kept only if tx commits. It
+// is chained once committed.
+func Append(ctx context.Context, tx *sql.Tx, e Event) (Entry, error) {
+	entry, err := newEntry(ctx, e)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	const insert = `INSERT INTO audit_log
+		(created_at, service, action, outcome, actor_type, actor_id, actor_email, ip, user_agent,
+		 request_id, resource_type, resource_id, before, after, detail)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		RETURNING id`
+	spanCtx, span := tracing.StartDBSpan(ctx, "INSERT", "audit_log", insert)
+	err = tx.QueryRowContext(spanCtx, insert,
+		entry.CreatedAt,
+		entry.Service,
+		entry.Action,
+		string(entry.Outcome),
+		entry.ActorType,
+		nullInt(entry.ActorID),
+		entry.ActorEmail,
+		entry.IP,
+		entry.UserAgent,
+		entry.RequestID,
+		entry.ResourceType,
+		entry.ResourceID,
+		nullJSON(entry.Before),
+		nullJSON(entry.After),
+		entry.Detail,
+	).Scan(&entry.ID)
+	tracing.End(span, err)
+	if err != nil {
+		return Entry{}, fmt.Errorf("insert audit entry %s: %w", e.Action, err)
+	}
+	return entry, nil
+}
+
+// Record appends e in a transaction of its own, for actions that have
+// already been committed or that change nothing.
+func Record(ctx context.Context, db *sql.DB, e Event) (Entry, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return Entry{}, err
+	}
+	defer tx.Rollback()
+
+	entry, err := Append(ctx, tx, e)
+	if err != nil {
+		return Entry{}, err
+	}
+	return entry, tx.Commit()
+}
+
+// newEntry fills an entry for e from ctx, up to its place in the chain.
+func newEntry(ctx context.Context, e Event) (Entry, error) {
+	actor := actorFrom(ctx)
+	entry := Entry{
+		// Postgres keeps microseconds; hash what will be read back
+		CreatedAt:    time.Now().UTC().Truncate(time.Microsecond),
+		Service:      service,
+		Action:       e.Action,
+		Outcome:      e.Outcome,
+		ActorType:    actor.Type,
+		ActorID:      actor.ID,
+		ActorEmail:   actor.Email,
+		RequestID:    middleware.GetRequestID(ctx),
+		ResourceType: e.ResourceType,
+		ResourceID:   e.ResourceID,
+		Detail:       e.Detail,
+	}
+	if entry.Outcome == "" {
+		entry.Outcome = OutcomeSuccess
+	}
+	if c, ok := middleware.GetClient(ctx); ok {
+		if c.IP.IsValid() {
+			entry.IP = c.IP.String()
+		}
+		entry.UserAgent = cleanText(c.UserAgent, 512)
+	}
+
+	var err error
+	if entry.Before, err = marshal(e.Before); err != nil {
+		return Entry{}, fmt.Errorf("audit %s before: %w", e.Action, err)
+	}
+	if entry.After, err = marshal(e.After); err != nil {
+		return Entry{}, fmt.Errorf("audit %s after: %w", e.Action, err)
+	}
+	return entry, nil
+}
+
+// hashed is the content an entry's hash covers, in a fixed field order.
+type hashed struct {
+	CreatedAt    string          `json:"created_at"`
+	Service      string          `json:"service"`
+	Action       string          `json:"action"`
+	Outcome      Outcome         `json:"outcome"`
+	ActorType    string          `json:"actor_type"`
+	ActorID      *int            `json:"actor_id"`
+	ActorEmail   string          `json:"actor_email"`
+	IP           string          `json:"ip"`
+	UserAgent    string          `json:"user_agent"`
+	RequestID    string          `json:"request_id"`
+	ResourceType string          `json:"resource_type"`
+	ResourceID   string          `json:"resource_id"`
+	Before       json.RawMessage `json:"before"`
+	After        json.RawMessage `json:"after"`
+	Detail       string          `json:"detail"`
+}
+
+// computeHash returns the hex SHA-256 of e's previous hash and content.
+func (e Entry) computeHash() (string, error) {
+	before, err := canonical(e.Before)
+	if err != nil {
+		return "", err
+	}
+	after, err := canonical(e.After)
+	if err != nil {
+		return "", err
+	}
+	content, err := json.Marshal(hashed{
+		CreatedAt:    e.CreatedAt.UTC().Format(time.RFC3339Nano),
+		Service:      e.Service,
+		Action:       e.Action,
+		Outcome:      e.Outcome,
+		ActorType:    e.ActorType,
+		ActorID:      e.ActorID,
+		ActorEmail:   e.ActorEmail,
+		IP:           e.IP,
+		UserAgent:    e.UserAgent,
+		RequestID:    e.RequestID,
+		ResourceType: e.ResourceType,
+		ResourceID:   e.ResourceID,
+		Before:       before,
+		After:        after,
+		Detail:       e.Detail,
+	})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(append([]byte(e.PrevHash+"\n"), content...))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// marshal encodes v as canonical JSON, nil for a nil v.
+func marshal(v any) (json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return canonical(raw)
+}
+
+// canonical re-encodes raw with sorted keys and Go's number formatting, so
+// JSON stored as JSONB, which reorders keys and respaces, hashes the same
+// when read back. null and empty input are nil.
+func canonical(raw json.RawMessage) (json.RawMessage, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// cleanText makes client-supplied s storable: valid UTF-8 without NULs,
+// at most max bytes.
+func cleanText(s string, max int) string {
+	s = strings.ToValidUTF8(strings.ReplaceAll(s, "\x00", ""), "")
+	if len(s) > max {
+		s = strings.ToValidUTF8(s[:max], "")
+	}
+	return s
+}
+
+func nullInt(v *int) sql.NullInt64 {
+	if v == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: int64(*v), Valid: true}
+}
+
+func nullJSON(raw json.RawMessage) any {
+	if raw == nil {
+		return nil
+	}
+	return string(raw)
+}
@@ -0,0 +1,278 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/internal/dbtest"
+)
+
+func TestComputeHashIgnoresJSONLayout(t *testing.T) {
+	e := Entry{
+		CreatedAt: time.Date(2026, 3, 1, 10, 0, 0, 123000, time.UTC),
+		Service:   service,
+		Action:    "payment.created",
+		Outcome:   OutcomeSuccess,
+		ActorType: ActorUser,
+		After:     json.RawMessage(`{"status":"created","amount":100}`),
+		PrevHash:  genesisHash,
+	}
+	want, err := e.computeHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// JSONB hands back reordered keys and its own spacing
+	e.After = json.RawMessage(`{"amount": 100, "status": "created"}`)
+	if got, _ := e.computeHash(); got != want {
+		t.Errorf("hash changed with JSON layout: %s, want %s", got, want)
+	}
+
+	for name, change := range map[string]func(*Entry){
+		"content":   func(e *Entry) { e.After = json.RawMessage(`{"amount":101,"status":"created"}`) },
+		"previous":  func(e *Entry) { e.PrevHash = strings.Repeat("1", 64) },
+		"time":      func(e *Entry) { e.CreatedAt = e.CreatedAt.Add(time.Microsecond) },
+		"actor":     func(e *Entry) { e.ActorType = ActorAdmin },
+		"no before": func(e *Entry) { e.Before = json.RawMessage(`{}`) },
+	} {
+		changed := e
+		change(&changed)
+		if got, _ := changed.computeHash(); got == want {
+			t.Errorf("%s: hash unchanged", name)
+		}
+	}
+}
+
+func record(t *testing.T, database *sql.DB, action string) Entry {
+	t.Helper()
+	e, err := Record(context.Background(), database, Event{
+		Action:       action,
+		ResourceType: "payment",
+		ResourceID:   "1",
+		After:        map[string]any{"status": "created"},
+	})
+	if err != nil {
+		t.Fatalf("record %s: %v", action, err)
+	}
+	return e
+}
+
+func chain(t *testing.T, database *sql.DB, want int) {
+	t.Helper()
+	n, err := Chain(context.Background(), database, 100)
+	if err != nil {
+		t.Fatalf("chain: %v", err)
+	}
+	if n != want {
+		t.Fatalf("chained %d entries, want %d", n, want)
+	}
+}
+
+func verify(t *testing.T, database *sql.DB) Verification {
+	t.Helper()
+	v, err := Verify(context.Background(), database)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	return v
+}
+
+// tamper runs stmt with the append-only triggers off, as someone with
+// rights over the table could.
+func tamper(t *testing.T, database *sql.DB, stmt string, args ...any) {
+	t.Helper()
+	if _, err := database.Exec("ALTER TABLE audit_log DISABLE TRIGGER audit_log_no_change"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.Exec(stmt, args...); err != nil {
+		t.Fatalf("%s: %v", stmt, err)
+	}
+	if _, err := database.Exec("ALTER TABLE audit_log ENABLE TRIGGER audit_log_no_change"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestChainAndVerify(t *testing.T) {
+	database := dbtest.Open(t)
+
+	first := record(t, database, "payment.created")
+	record(t, database, "payment.status_changed")
+	third := record(t, database, "payment.refunded")
+	if first.Seq != 0 || first.Hash != "" {
+		t.Fatalf("entry chained on append: seq %d, hash %q", first.Seq, first.Hash)
+	}
+
+	v := verify(t, database)
+	if !v.Intact() || v.Checked != 0 || v.Pending != 3 {
+		t.Fatalf("before chaining: %+v, want intact with 0 checked and 3 pending", v)
+	}
+
+	chain(t, database, 3)
+	chain(t, database, 0)
+	v = verify(t, database)
+	if !v.Intact() || v.Checked != 3 || v.Pending != 0 {
+		t.Fatalf("after chaining: %+v, want intact with 3 checked", v)
+	}
+
+	entries, err := List(context.Background(), database, Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || entries[0].ID != third.ID {
+		t.Fatalf("listed %d entries, newest %d; want 3, newest %d", len(entries), entries[0].ID, third.ID)
+	}
+	for i, e := range entries {
+		if want := int64(3 - i); e.Seq != want {
+			t.Errorf("entry %d seq = %d, want %d", e.ID, e.Seq, want)
+		}
+	}
+	if entries[0].Hash != v.LastHash || entries[0].PrevHash != entries[1].Hash {
+		t.Errorf("entries not linked: %+v", entries)
+	}
+	if entries[2].PrevHash != genesisHash {
+		t.Errorf("first entry follows %s, want the genesis hash", entries[2].PrevHash)
+	}
+
+	// Later entries extend the same chain
+	record(t, database, "payment.created")
+	chain(t, database, 1)
+	if v := verify(t, database); !v.Intact() || v.Checked != 4 {
+		t.Fatalf("after a second chaining: %+v", v)
+	}
+}
+
+func TestChainFollowsCommitOrder(t *testing.T) {
+	database := dbtest.Open(t)
+	ctx := context.Background()
+
+	// An entry whose transaction commits late takes a lower ID than one
+	// chained before it commits
+	tx, err := database.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	late, err := Append(ctx, tx, Event{Action: "payment.created"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	early := record(t, database, "payment.status_changed")
+	if late.ID > early.ID {
+		t.Fatalf("late entry %d after early entry %d", late.ID, early.ID)
+	}
+	chain(t, database, 1)
+
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	chain(t, database, 1)
+
+	if v := verify(t, database); !v.Intact() || v.Checked != 2 {
+		t.Fatalf("verify = %+v, want intact with 2 checked", v)
+	}
+	entries, err := List(ctx, database, Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	seqs := map[int64]int64{}
+	for _, e := range entries {
+		seqs[e.ID] = e.Seq
+	}
+	if seqs[early.ID] != 1 || seqs[late.ID] != 2 {
+		t.Errorf("seq by ID = %v, want %d first and %d second", seqs, early.ID, late.ID)
+	}
+}
+
+func TestVerifyFindsTampering(t *testing.T) {
+	tests := []struct {
+		name    string
+		tamper  string
+		broken  int // index of the entry reported
+		problem string
+	}{
+		{
+			name:    "edited content",
+			tamper:  "UPDATE audit_log SET detail = 'nothing to see' WHERE id = $1",
+			broken:  1,
+			problem: "content does not match its hash",
+		},
+		{
+			name:    "edited snapshot",
+			tamper:  `UPDATE audit_log SET after = '{"status":"refunded"}' WHERE id = $1`,
+			broken:  1,
+			problem: "content does not match its hash",
+		},
+		{
+			name:    "removed entry",
+			tamper:  "DELETE FROM audit_log WHERE id = $1",
+			broken:  2,
+			problem: "does not follow the previous entry",
+		},
+		{
+			name:    "rehashed entry",
+			tamper:  "UPDATE audit_log SET detail = 'x', hash = md5('x') || md5('y') WHERE id = $1",
+			broken:  1,
+			problem: "content does not match its hash",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database := dbtest.Open(t)
+			var entries []Entry
+			for _, action := range []string{"payment.created", "payment.status_changed", "payment.refunded"} {
+				entries = append(entries, record(t, database, action))
+			}
+			chain(t, database, 3)
+
+			tamper(t, database, tt.tamper, entries[1].ID)
+
+			v := verify(t, database)
+			if v.Intact() {
+				t.Fatalf("verify reported an intact chain after %s", tt.name)
+			}
+			if v.BrokenAt != entries[tt.broken].ID || v.Problem != tt.problem {
+				t.Errorf("broken at %d (%s), want %d (%s)", v.BrokenAt, v.Problem, entries[tt.broken].ID, tt.problem)
+			}
+		})
+	}
+}
+
+func TestAppendOnly(t *testing.T) {
+	database := dbtest.Open(t)
+	chained := record(t, database, "payment.created")
+	chain(t, database, 1)
+	pending := record(t, database, "payment.status_changed")
+
+	for _, tt := range []struct {
+		name string
+		stmt string
+		id   int64
+	}{
+		{"update chained", "UPDATE audit_log SET detail = 'edited' WHERE id = $1", chained.ID},
+		{"update pending", "UPDATE audit_log SET detail = 'edited' WHERE id = $1", pending.ID},
+		{"rechain", "UPDATE audit_log SET seq = 9, prev_hash = hash, hash = hash WHERE id = $1", chained.ID},
+		{"chain and edit", "UPDATE audit_log SET seq = 2, prev_hash = hash, hash = hash, detail = 'x' WHERE id = $1", pending.ID},
+		{"delete chained", "DELETE FROM audit_log WHERE id = $1", chained.ID},
+		{"delete pending", "DELETE FROM audit_log WHERE id = $1", pending.ID},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := database.Exec(tt.stmt, tt.id)
+			if err == nil || !strings.Contains(err.Error(), "append-only") {
+				t.Errorf("%s: err = %v, want append-only", tt.stmt, err)
+			}
+		})
+	}
+	if _, err := database.Exec("TRUNCATE audit_log"); err == nil || !strings.Contains(err.Error(), "append-only") {
+		t.Errorf("TRUNCATE: err = %v, want append-only", err)
+	}
+
+	// Chaining is the one change allowed
+	chain(t, database, 1)
+	if v := verify(t, database); !v.Intact() || v.Checked != 2 {
+		t.Errorf("verify = %+v, want intact with 2 checked", v)
+	}
+}
@@ -0,0 +1,135 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/tracing"
+)
+
+// Chain links up to limit committed entries not yet in the chain, oldest
+// first, in a transaction of its own, and returns how many it linked. The
+// chain's lock is held only while it does, so entries are chained in the
+// order they became visible, which need not be ID order.
+func Chain(ctx context.Context, db *sql.DB, limit int) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", lockKey); err != nil {
+		return 0, fmt.Errorf("lock audit chain: %w", err)
+	}
+
+	var seq int64
+	prevHash := genesisHash
+	const last = "SELECT seq, hash FROM audit_log WHERE seq IS NOT NULL ORDER BY seq DESC LIMIT 1"
+	spanCtx, span := tracing.StartDBSpan(ctx, "SELECT", "audit_log", last)
+	err = tx.QueryRowContext(spanCtx, last).Scan(&seq, &prevHash)
+	tracing.End(span, err)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("read audit chain: %w", err)
+	}
+
+	query := "SELECT " + entryColumns + " FROM audit_log WHERE seq IS NULL ORDER BY id LIMIT $1"
+	spanCtx, span = tracing.StartDBSpan(ctx, "SELECT", "audit_log", query)
+	rows, err := tx.QueryContext(spanCtx, query, limit)
+	tracing.End(span, err)
+	if err != nil {
+		return 0, err
+	}
+	var pending []Entry
+	for rows.Next() {
+		e, err := scan(rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		pending = append(pending, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(pending) == 0 {
+		return 0, nil
+	}
+
+	const link = "UPDATE audit_log SET seq = $2, prev_hash = $3, hash = $4 WHERE id = $1 AND seq IS NULL"
+	for _, e := range pending {
+		seq++
+		e.Seq, e.PrevHash = seq, prevHash
+		if e.Hash, err = e.computeHash(); err != nil {
+			return 0, fmt.Errorf("hash audit entry %d: %w", e.ID, err)
+		}
+		spanCtx, span = tracing.StartDBSpan(ctx, "UPDATE", "audit_log", link)
+		_, err = tx.ExecContext(spanCtx, link, e.ID, e.Seq, e.PrevHash, e.Hash)
+		tracing.End(span, err)
+		if err != nil {
+			return 0, fmt.Errorf("chain audit entry %d: %w", e.ID, err)
+		}
+		prevHash = e.Hash
+	}
+	return len(pending), tx.Commit()
+}
+
+// ChainerOptions tunes a Chainer. Zero values take the defaults noted per
+// field.
+type ChainerOptions struct {
+	Interval  time.Duration // 5s
+	BatchSize int           // 500 entries per transaction
+}
+
+// Chainer links entries written by either service into the chain.
+type Chainer struct {
+	db   *sql.DB
+	opts ChainerOptions
+}
+
+// NewChainer creates a chainer for the audit log in db.
+func NewChainer(db *sql.DB, opts ChainerOptions) *Chainer {
+	if opts.Interval <= 0 {
+		opts.Interval = 5 * time.Second
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 500
+	}
+	return &Chainer{db: db, opts: opts}
+}
+
+// Run chains until ctx is cancelled.
+func (c *Chainer) Run(ctx context.Context) {
+	slog.Info("Audit chainer started", "interval", c.opts.Interval)
+
+	ticker := time.NewTicker(c.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		c.drain(ctx)
+
+		select {
+		case <-ctx.Done():
+			slog.Info("Audit chainer stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// drain chains while batches come back full.
+func (c *Chainer) drain(ctx context.Context) {
+	for {
+		n, err := Chain(ctx, c.db, c.opts.BatchSize)
+		if err != nil && ctx.Err() == nil {
+			slog.Error("Failed to chain audit log", "error", err)
+		}
+		if err != nil || n < c.opts.BatchSize {
+			return
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/tracing"
+)
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+const entryColumns = `id, created_at, service, action, outcome, actor_type, actor_id, actor_email, ip,
+	user_agent, request_id, resource_type, resource_id, before, after, detail, seq, COALESCE(prev_hash, ''),
+	COALESCE(hash, '')`
+
+func scan(row interface{ Scan(...any) error }) (Entry, error) {
+	var e Entry
+	var outcome string
+	var actorID, seq sql.NullInt64
+	var before, after []byte
+	err := row.Scan(&e.ID, &e.CreatedAt, &e.Service, &e.Action, &outcome, &e.ActorType, &actorID, &e.ActorEmail, &e.IP,
+		&e.UserAgent, &e.RequestID, &e.ResourceType, &e.ResourceID, &before, &after, &e.Detail, &seq, &e.PrevHash,
+		&e.Hash)
+	if err != nil {
+		return Entry{}, err
+	}
+	e.CreatedAt = e.CreatedAt.UTC()
+	e.Outcome = Outcome(outcome)
+	e.Seq = seq.Int64
+	if actorID.Valid {
+		id := int(actorID.Int64)
+		e.ActorID = &id
+	}
+	if before != nil {
+		e.Before = before
+	}
+	if after != nil {
+		e.After = after
+	}
+	return e, nil
+}
+
+// Filter narrows List. Zero fields do not filter.
+type Filter struct {
+	Service string
+	// Action matches exactly, or every action under it when it ends in a
+	// dot, e.g. "admin.".
+	Action       string
+	Outcome      Outcome
+	ActorID      int
+	ResourceType string
+	ResourceID   string
+	RequestID    string
+	Since        time.Time // inclusive
+	Until        time.Time // exclusive
+	// BeforeID pages back from a previous page's last ID.
+	BeforeID int64
+	Limit    int // default 50, at most 100
+}
+
+// List returns the entries matching f, newest first.
+func List(ctx context.Context, q queryer, f Filter) ([]Entry, error) {
+	if f.Limit <= 0 || f.Limit > 100 {
+		f.Limit = 50
+	}
+	var since, until sql.NullTime
+	if !f.Since.IsZero() {
+		since = sql.NullTime{Time: f.Since, Valid: true}
+	}
+	if !f.Until.IsZero() {
+		until = sql.NullTime{Time: f.Until, Valid: true}
+	}
+	query := "SELECT " + entryColumns + ` FROM audit_log
+		WHERE ($1 = '' OR service = $1)
+		  AND ($2 = '' OR action = $2 OR (RIGHT($2, 1) = '.' AND LEFT(action, LENGTH($2)) = $2))
+		  AND ($3 = '' OR outcome = $3)
+		  AND ($4 = 0 OR actor_id = $4)
+		  AND ($5 = '' OR resource_type = $5)
+		  AND ($6 = '' OR resource_id = $6)
+		  AND ($7 = '' OR request_id = $7)
+		  AND ($8::timestamptz IS NULL OR created_at >= $8)
+		  AND ($9::timestamptz IS NULL OR created_at < $9)
+		  AND ($10 = 0 OR id < $10)
+		ORDER BY id DESC
+		LIMIT $11`
+
+	spanCtx, span := tracing.StartDBSpan(ctx, "SELECT", "audit_log", query)
+	rows, err := q.QueryContext(spanCtx, query,
+		f.Service, f.Action, string(f.Outcome), f.ActorID, f.ResourceType, f.ResourceID, f.RequestID,
+		since, until, f.BeforeID, f.Limit)
+	tracing.End(span, err)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []Entry
+	for rows.Next() {
+		e, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// Verification is the result of checking the chain.
+type Verification struct {
+	// Checked is how many entries were read, up to and including a broken
+	// one.
+	Checked int64
+	// BrokenAt is the first entry that does not match its hash or does not
+	// follow the entry before it; 0 when the chain is intact.
+	BrokenAt int64
+	Problem  string
+	// LastHash is the hash of the last entry checked, which an intact
+	// chain's later entries build on.
+	LastHash string
+	// Pending is how many entries were still waiting to be chained, and
+	// so not checked.
+	Pending int64
+}
+
+// Intact reports whether no break was found.
+func (v Verification) Intact() bool {
+	return v.BrokenAt == 0
+}
+
+// verifyBatch is how many entries Verify reads at a time.
+const verifyBatch = 500
+
+// Verify recomputes every chained entry's hash from the start of the
+// chain, stopping at the first entry that was altered or whose predecessor
+// was altered, removed or reordered. Entries chained while it runs are
+// checked too.
+func Verify(ctx context.Context, q queryer) (Verification, error) {
+	v := Verification{LastHash: genesisHash}
+	query := "SELECT " + entryColumns + " FROM audit_log WHERE seq > $1 ORDER BY seq LIMIT $2"
+
+	var after int64
+	for {
+		spanCtx, span := tracing.StartDBSpan(ctx, "SELECT", "audit_log", query)
+		rows, err := q.QueryContext(spanCtx, query, after, verifyBatch)
+		tracing.End(span, err)
+		if err != nil {
+			return Verification{}, err
+		}
+		var batch []Entry
+		for rows.Next() {
+			e, err := scan(rows)
+			if err != nil {
+				rows.Close()
+				return Verification{}, err
+			}
+			batch = append(batch, e)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return Verification{}, err
+		}
+
+		for _, e := range batch {
+			v.Checked++
+			if e.PrevHash != v.LastHash {
+				v.BrokenAt, v.Problem = e.ID, "does not follow the previous entry"
+				return v, nil
+			}
+			hash, err := e.computeHash()
+			if err != nil {
+				v.BrokenAt, v.Problem = e.ID, "unreadable: "+err.Error()
+				return v, nil
+			}
+			if hash != e.Hash {
+				v.BrokenAt, v.Problem = e.ID, "content does not match its hash"
+				return v, nil
+			}
+			v.LastHash = e.Hash
+			after = e.Seq
+		}
+		if len(batch) < verifyBatch {
+			break
+		}
+	}
+
+	const pending = "SELECT COUNT(*) FROM audit_log WHERE seq IS NULL"
+	spanCtx, span := tracing.StartDBSpan(ctx, "SELECT", "audit_log", pending)
+	err := q.QueryRowContext(spanCtx, pending).Scan(&v.Pending)
+	tracing.End(span, err)
+	if err != nil {
+		return Verification{}, err
+	}
+	return v, nil
+}
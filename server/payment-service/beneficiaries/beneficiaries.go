@@ -0,0 +1,186 @@
+// Package beneficiaries stores the payees users save for /pay: bank
+// accounts (account number and IFSC) and UPI addresses.
+package beneficiaries
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/models"
+	"github.com/lib/pq"
+)
+
+// ErrNotFound is returned when a beneficiary does not exist, was deleted or
+// belongs to another user.
+var ErrNotFound = errors.New("beneficiary not found")
+
+// ErrDuplicateNickname is returned when the user already has a beneficiary
+// with the nickname.
+var ErrDuplicateNickname = errors.New("a beneficiary with this nickname already exists")
+
+// Type is the kind of payee.
+type Type string
+
+const (
+	TypeBankAccount Type = "bank_account"
+	TypeVPA         Type = "vpa"
+)
+
+// Beneficiary is a saved payee.
+type Beneficiary struct {
+	ID       int64
+	UserID   int
+	Nickname string
+	Type     Type
+
+	// Bank accounts only
+	AccountNumber     string
+	IFSC              string
+	AccountHolderName string
+
+	// UPI only
+	VPA string
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Payee returns the destination recorded as a payment's to_account.
+func (b Beneficiary) Payee() string {
+	if b.Type == TypeVPA {
+		return b.VPA
+	}
+	return b.AccountNumber
+}
+
+var (
+	// Four letters for the bank, a reserved 0, six characters for the branch.
+	ifscPattern = regexp.MustCompile(`^[A-Z]{4}0[A-Z0-9]{6}$`)
+	// Indian bank account numbers are 9 to 18 digits.
+	accountPattern = regexp.MustCompile(`^[0-9]{9,18}$`)
+	// handle@provider as accepted by NPCI.
+	vpaPattern = regexp.MustCompile(`^[a-zA-Z0-9.\-_]{2,256}@[a-zA-Z][a-zA-Z0-9]{2,64}$`)
+)
+
+// Normalize trims and canonicalises b's fields and checks them, returning a
+// description of the first problem found.
+func Normalize(b *Beneficiary) error {
+	b.Nickname = strings.TrimSpace(b.Nickname)
+	if b.Nickname == "" || len(b.Nickname) > 64 {
+		return errors.New("nickname is required and must be at most 64 characters")
+	}
+
+	switch b.Type {
+	case TypeBankAccount:
+		b.AccountNumber = strings.ReplaceAll(strings.TrimSpace(b.AccountNumber), " ", "")
+		b.IFSC = strings.ToUpper(strings.TrimSpace(b.IFSC))
+		b.AccountHolderName = strings.TrimSpace(b.AccountHolderName)
+		if b.VPA != "" {
+			return errors.New("vpa must be empty for a bank_account beneficiary")
+		}
+		if !accountPattern.MatchString(b.AccountNumber) {
+			return errors.New("account_number must be 9 to 18 digits")
+		}
+		if !ifscPattern.MatchString(b.IFSC) {
+			return errors.New("ifsc must look like HDFC0001234")
+		}
+		if len(b.AccountHolderName) > 128 {
+			return errors.New("account_holder_name must be at most 128 characters")
+		}
+	case TypeVPA:
+		b.VPA = strings.ToLower(strings.TrimSpace(b.VPA))
+		if b.AccountNumber != "" || b.IFSC != "" {
+			return errors.New("account_number and ifsc must be empty for a vpa beneficiary")
+		}
+		// The VPA is paid as the payment's to_account
+		if len(b.VPA) > models.MaxAccountLength {
+			return fmt.Errorf("vpa must be at most %d characters", models.MaxAccountLength)
+		}
+		if !vpaPattern.MatchString(b.VPA) {
+			return errors.New("vpa must look like name@bank")
+		}
+	default:
+		return fmt.Errorf("type must be %s or %s", TypeBankAccount, TypeVPA)
+	}
+	return nil
+}
+
+// queryer is satisfied by *sql.DB and *sql.Tx.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+const columns = `id, user_id, nickname, type, COALESCE(account_number, ''), COALESCE(ifsc, ''),
+	COALESCE(account_holder_name, ''), COALESCE(vpa, ''), created_at, updated_at`
+
+func scan(row interface{ Scan(...any) error }) (Beneficiary, error) {
+	var b Beneficiary
+	err := row.Scan(&b.ID, &b.UserID, &b.Nickname, &b.Type, &b.AccountNumber, &b.IFSC,
+		&b.AccountHolderName, &b.VPA, &b.CreatedAt, &b.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Beneficiary{}, ErrNotFound
+	}
+	return b, err
+}
+
+// Create stores b, which must have passed Normalize, for b.UserID.
+func Create(ctx context.Context, q queryer, b Beneficiary) (Beneficiary, error) {
+	created, err := scan(q.QueryRowContext(ctx, `INSERT INTO beneficiaries
+			(user_id, nickname, type, account_number, ifsc, account_holder_name, vpa)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''))
+		RETURNING `+columns,
+		b.UserID, b.Nickname, string(b.Type), b.AccountNumber, b.IFSC, b.AccountHolderName, b.VPA))
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return Beneficiary{}, ErrDuplicateNickname
+	}
+	return created, err
+}
+
+// List returns userID's beneficiaries, newest first.
+func List(ctx context.Context, q queryer, userID int) ([]Beneficiary, error) {
+	rows, err := q.QueryContext(ctx, `SELECT `+columns+` FROM beneficiaries
+		WHERE user_id = $1 AND deleted_at IS NULL
+		ORDER BY id DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Beneficiary
+	for rows.Next() {
+		b, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, b)
+	}
+	return list, rows.Err()
+}
+
+// Get returns one of userID's beneficiaries.
+func Get(ctx context.Context, q queryer, userID int, id int64) (Beneficiary, error) {
+	return scan(q.QueryRowContext(ctx, `SELECT `+columns+` FROM beneficiaries
+		WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`, id, userID))
+}
+
+// Delete hides one of userID's beneficiaries. The row is kept so payments
+// made to it still resolve.
+func Delete(ctx context.Context, q queryer, userID int, id int64) error {
+	res, err := q.ExecContext(ctx, `UPDATE beneficiaries SET deleted_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`, id, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
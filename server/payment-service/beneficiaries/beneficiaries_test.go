@@ -0,0 +1,60 @@
+package beneficiaries
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		b       Beneficiary
+		want    Beneficiary // compared when problem is empty
+		problem string
+	}{
+		{
+			name: "bank account",
+			b:    Beneficiary{Nickname: " Rent ", Type: TypeBankAccount, AccountNumber: " 5010 0123 4567 89", IFSC: "hdfc0001234", AccountHolderName: " A Landlord "},
+			want: Beneficiary{Nickname: "Rent", Type: TypeBankAccount, AccountNumber: "50100123456789", IFSC: "HDFC0001234", AccountHolderName: "A Landlord"},
+		},
+		{
+			name: "vpa",
+			b:    Beneficiary{Nickname: "Chai", Type: TypeVPA, VPA: " Chai.Wala-1@OKBank "},
+			want: Beneficiary{Nickname: "Chai", Type: TypeVPA, VPA: "chai.wala-1@okbank"},
+		},
+		{name: "no nickname", b: Beneficiary{Nickname: "  ", Type: TypeVPA, VPA: "a1@okbank"}, problem: "nickname"},
+		{name: "long nickname", b: Beneficiary{Nickname: strings.Repeat("n", 65), Type: TypeVPA, VPA: "a1@okbank"}, problem: "nickname"},
+		{name: "unknown type", b: Beneficiary{Nickname: "x", Type: "card"}, problem: "type must be"},
+		{name: "short account number", b: Beneficiary{Nickname: "x", Type: TypeBankAccount, AccountNumber: "12345678", IFSC: "HDFC0001234"}, problem: "account_number"},
+		{name: "account number with letters", b: Beneficiary{Nickname: "x", Type: TypeBankAccount, AccountNumber: "50100ABC4567", IFSC: "HDFC0001234"}, problem: "account_number"},
+		{name: "ifsc without the reserved 0", b: Beneficiary{Nickname: "x", Type: TypeBankAccount, AccountNumber: "50100123456789", IFSC: "HDFC1001234"}, problem: "ifsc"},
+		{name: "short ifsc", b: Beneficiary{Nickname: "x", Type: TypeBankAccount, AccountNumber: "50100123456789", IFSC: "HDFC000123"}, problem: "ifsc"},
+		{name: "bank account with a vpa", b: Beneficiary{Nickname: "x", Type: TypeBankAccount, AccountNumber: "50100123456789", IFSC: "HDFC0001234", VPA: "a1@okbank"}, problem: "vpa must be empty"},
+		{name: "long holder name", b: Beneficiary{Nickname: "x", Type: TypeBankAccount, AccountNumber: "50100123456789", IFSC: "HDFC0001234", AccountHolderName: strings.Repeat("h", 129)}, problem: "account_holder_name"},
+		{name: "vpa without a provider", b: Beneficiary{Nickname: "x", Type: TypeVPA, VPA: "name@"}, problem: "name@bank"},
+		{name: "vpa with a one-letter handle", b: Beneficiary{Nickname: "x", Type: TypeVPA, VPA: "a@okbank"}, problem: "name@bank"},
+		{name: "vpa provider starting with a digit", b: Beneficiary{Nickname: "x", Type: TypeVPA, VPA: "name@1bank"}, problem: "name@bank"},
+		{name: "vpa with an ifsc", b: Beneficiary{Nickname: "x", Type: TypeVPA, VPA: "a1@okbank", IFSC: "HDFC0001234"}, problem: "must be empty"},
+		{name: "vpa longer than to_account", b: Beneficiary{Nickname: "x", Type: TypeVPA, VPA: strings.Repeat("a", 300) + "@okbank"}, problem: "at most 128"},
+	}
+	for _, tt := range tests {
+		b := tt.b
+		err := Normalize(&b)
+		switch {
+		case tt.problem != "":
+			if err == nil || !strings.Contains(err.Error(), tt.problem) {
+				t.Errorf("%s: err = %v, want %q", tt.name, err, tt.problem)
+			}
+		case err != nil:
+			t.Errorf("%s: %v", tt.name, err)
+		case b != tt.want:
+			t.Errorf("%s: normalized to %+v, want %+v", tt.name, b, tt.want)
+		}
+	}
+
+	// The longest VPA that still fits is accepted
+	b := Beneficiary{Nickname: "x", Type: TypeVPA, VPA: strings.Repeat("a", 121) + "@okbank"}
+	if err := Normalize(&b); err != nil {
+		t.Errorf("128-character vpa: %v", err)
+	}
+}
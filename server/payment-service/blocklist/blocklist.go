@@ -0,0 +1,344 @@
+// Package blocklist keeps the compliance blocklist of payee accounts, VPAs,
+// emails and IP ranges.
+//
+// Entries are managed by admins, singly or imported from CSV, and carry a
+// reason, an optional expiry and who listed them. Payments are checked
+// against an in-memory Cache rather than the table; every change bumps the
+// list's revision, which caches poll to reload when it moves.
+package blocklist
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/tracing"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrNotFound is returned when no live entry matches a lookup.
+	ErrNotFound = errors.New("blocklist entry not found")
+	// ErrDuplicate is returned when the value is already on the list.
+	ErrDuplicate = errors.New("value already blocklisted")
+)
+
+// Kind is what an entry's value is matched against.
+type Kind string
+
+const (
+	KindAccount Kind = "account" // payer or payee bank account number
+	KindVPA     Kind = "vpa"     // payer or payee UPI address
+	KindEmail   Kind = "email"   // user email, or "@domain" for a whole domain
+	KindIP      Kind = "ip"      // client address, as a CIDR network
+)
+
+// Kinds lists every entry kind.
+var Kinds = []Kind{KindAccount, KindVPA, KindEmail, KindIP}
+
+// Entry is a blocklisted value.
+type Entry struct {
+	ID     int64
+	Kind   Kind
+	Value  string
+	Reason string
+	// ExpiresAt is nil for an entry that never expires.
+	ExpiresAt *time.Time
+	CreatedBy int
+	CreatedAt time.Time
+	// RemovedAt is set once an admin has delisted the entry.
+	RemovedAt *time.Time
+	RemovedBy *int
+	Revision  int64
+}
+
+// Active reports whether e blocks at now.
+func (e Entry) Active(now time.Time) bool {
+	return e.RemovedAt == nil && (e.ExpiresAt == nil || now.Before(*e.ExpiresAt))
+}
+
+var (
+	emailPattern  = regexp.MustCompile(`^[^@\s]+@[a-z0-9.\-]+\.[a-z]{2,}$`)
+	domainPattern = regexp.MustCompile(`^@[a-z0-9.\-]+\.[a-z]{2,}$`)
+	vpaPattern    = regexp.MustCompile(`^[a-z0-9.\-_]{2,256}@[a-z][a-z0-9]{2,64}$`)
+)
+
+// Normalize trims and canonicalises e's kind, value and reason and checks
+// them, returning a description of the first problem found.
+func Normalize(e *Entry) error {
+	e.Kind = Kind(strings.ToLower(strings.TrimSpace(string(e.Kind))))
+	e.Reason = strings.TrimSpace(e.Reason)
+	value, err := NormalizeValue(e.Kind, e.Value)
+	if err != nil {
+		return err
+	}
+	e.Value = value
+	if e.Reason == "" {
+		return errors.New("reason is required")
+	}
+	if e.ExpiresAt != nil {
+		utc := e.ExpiresAt.UTC()
+		e.ExpiresAt = &utc
+	}
+	return nil
+}
+
+// NormalizeValue returns value in the form it is stored and looked up in
+// for kind.
+func NormalizeValue(kind Kind, value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", errors.New("value is required")
+	}
+	switch kind {
+	case KindAccount:
+		value = strings.ToUpper(strings.ReplaceAll(value, " ", ""))
+		if strings.Contains(value, "@") || len(value) > 64 {
+			return "", errors.New("account must be an account number of at most 64 characters")
+		}
+	case KindVPA:
+		value = strings.ToLower(value)
+		if !vpaPattern.MatchString(value) {
+			return "", errors.New("vpa must look like name@bank")
+		}
+	case KindEmail:
+		value = strings.ToLower(value)
+		if !emailPattern.MatchString(value) && !domainPattern.MatchString(value) {
+			return "", errors.New("email must be an address or @domain")
+		}
+	case KindIP:
+		prefix, err := parsePrefix(value)
+		if err != nil {
+			return "", errors.New("ip must be an address or CIDR network")
+		}
+		value = prefix.String()
+	default:
+		return "", fmt.Errorf("kind must be one of %s, %s, %s or %s", KindAccount, KindVPA, KindEmail, KindIP)
+	}
+	return value, nil
+}
+
+// parsePrefix parses a CIDR network, or a single address as the network
+// holding only it, masking any host bits.
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		if prefix.Addr().Is4In6() && prefix.Bits() >= 96 {
+			prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+		}
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// queryer is satisfied by *sql.DB and *sql.Tx.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+const entryColumns = `id, kind, value, reason, expires_at, created_by, created_at, removed_at, removed_by, revision`
+
+func scan(row interface{ Scan(...any) error }) (Entry, error) {
+	var e Entry
+	var expiresAt, removedAt sql.NullTime
+	var removedBy sql.NullInt64
+	err := row.Scan(&e.ID, &e.Kind, &e.Value, &e.Reason, &expiresAt, &e.CreatedBy, &e.CreatedAt, &removedAt, &removedBy, &e.Revision)
+	if err != nil {
+		return Entry{}, err
+	}
+	if expiresAt.Valid {
+		e.ExpiresAt = &expiresAt.Time
+	}
+	if removedAt.Valid {
+		e.RemovedAt = &removedAt.Time
+		by := int(removedBy.Int64)
+		e.RemovedBy = &by
+	}
+	return e, nil
+}
+
+// Get returns entry id, removed or not.
+func Get(ctx context.Context, q queryer, id int64) (Entry, error) {
+	query := "SELECT " + entryColumns + " FROM blocklist_entries WHERE id = $1"
+
+	spanCtx, span := tracing.StartDBSpan(ctx, "SELECT", "blocklist_entries", query)
+	e, err := scan(q.QueryRowContext(spanCtx, query, id))
+	tracing.End(span, err)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Entry{}, ErrNotFound
+	}
+	return e, err
+}
+
+// Filter narrows List. Zero fields do not filter.
+type Filter struct {
+	Kind Kind
+	// Search matches values containing it.
+	Search string
+	// Removed lists delisted entries instead of live ones.
+	Removed bool
+	Limit   int // default 50, at most 100
+}
+
+// List returns the entries matching f, newest first.
+func List(ctx context.Context, q queryer, f Filter) ([]Entry, error) {
+	if f.Limit <= 0 || f.Limit > 100 {
+		f.Limit = 50
+	}
+	query := "SELECT " + entryColumns + ` FROM blocklist_entries
+		WHERE ($1 = '' OR kind = $1)
+		  AND ($2 = '' OR value LIKE '%' || $2 || '%')
+		  AND (removed_at IS NOT NULL) = $3
+		ORDER BY id DESC
+		LIMIT $4`
+
+	spanCtx, span := tracing.StartDBSpan(ctx, "SELECT", "blocklist_entries", query)
+	rows, err := q.QueryContext(spanCtx, query, string(f.Kind), strings.ToLower(f.Search), f.Removed, f.Limit)
+	tracing.End(span, err)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []Entry
+	for rows.Next() {
+		e, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// live returns every entry that has not been removed or expired, with the
+// revision of the list they make up.
+func live(ctx context.Context, q queryer) ([]Entry, int64, error) {
+	revision, err := Revision(ctx, q)
+	if err != nil {
+		return nil, 0, err
+	}
+	query := "SELECT " + entryColumns + ` FROM blocklist_entries
+		WHERE removed_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())`
+
+	spanCtx, span := tracing.StartDBSpan(ctx, "SELECT", "blocklist_entries", query)
+	rows, err := q.QueryContext(spanCtx, query)
+	tracing.End(span, err)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var entries []Entry
+	for rows.Next() {
+		e, err := scan(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, revision, rows.Err()
+}
+
+// Revision returns the revision of the latest change to the list, 0 if it
+// was never changed.
+func Revision(ctx context.Context, q queryer) (int64, error) {
+	const query = "SELECT COALESCE(MAX(revision), 0) FROM blocklist_entries"
+
+	var revision int64
+	spanCtx, span := tracing.StartDBSpan(ctx, "SELECT", "blocklist_entries", query)
+	err := q.QueryRowContext(spanCtx, query).Scan(&revision)
+	tracing.End(span, err)
+	return revision, err
+}
+
+// change runs fn in a transaction holding the list's write lock, so
+// revisions are committed in the order they are taken and a cache that has
+// seen a revision has seen every change before it.
+func change(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "LOCK TABLE blocklist_entries IN EXCLUSIVE MODE"); err != nil {
+		return fmt.Errorf("lock blocklist: %w", err)
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Add lists e, which must have been normalised. It returns ErrDuplicate if
+// its value is already listed.
+func Add(ctx context.Context, db *sql.DB, e Entry) (Entry, error) {
+	query := `INSERT INTO blocklist_entries (kind, value, reason, expires_at, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING ` + entryColumns
+
+	var added Entry
+	err := change(ctx, db, func(tx *sql.Tx) error {
+		spanCtx, span := tracing.StartDBSpan(ctx, "INSERT", "blocklist_entries", query)
+		var err error
+		added, err = scan(tx.QueryRowContext(spanCtx, query, string(e.Kind), e.Value, e.Reason, nullTime(e.ExpiresAt), e.CreatedBy))
+		tracing.End(span, err)
+		return err
+	})
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return Entry{}, ErrDuplicate
+	}
+	if err != nil {
+		return Entry{}, fmt.Errorf("insert blocklist entry: %w", err)
+	}
+	return added, nil
+}
+
+// Remove delists entry id on by's behalf. It returns ErrNotFound if there
+// is no such live entry.
+func Remove(ctx context.Context, db *sql.DB, id int64, by int) (Entry, error) {
+	query := `UPDATE blocklist_entries
+		SET removed_at = NOW(), removed_by = $2, revision = nextval('blocklist_revision_seq')
+		WHERE id = $1 AND removed_at IS NULL
+		RETURNING ` + entryColumns
+
+	var removed Entry
+	err := change(ctx, db, func(tx *sql.Tx) error {
+		spanCtx, span := tracing.StartDBSpan(ctx, "UPDATE", "blocklist_entries", query)
+		var err error
+		removed, err = scan(tx.QueryRowContext(spanCtx, query, id, by))
+		tracing.End(span, err)
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return Entry{}, ErrNotFound
+	}
+	if err != nil {
+		return Entry{}, fmt.Errorf("remove blocklist entry %d: %w", id, err)
+	}
+	return removed, nil
+}
+
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *t, Valid: true}
+}
@@ -0,0 +1,193 @@
+package blocklist
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"net/netip"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// set is an immutable copy of the live entries, indexed for lookup.
+type set struct {
+	revision int64
+	entries  []Entry
+	// values holds account, VPA and email entries by kind and value.
+	values map[Kind]map[string]Entry
+	// networks holds IP entries by prefix length, lengths longest first,
+	// so a lookup is one map probe per length in use.
+	networks map[int]map[netip.Prefix]Entry
+	lengths  []int
+}
+
+func newSet(entries []Entry, revision int64) *set {
+	s := &set{
+		revision: revision,
+		entries:  entries,
+		values:   make(map[Kind]map[string]Entry),
+		networks: make(map[int]map[netip.Prefix]Entry),
+	}
+	for _, e := range entries {
+		if e.Kind != KindIP {
+			if s.values[e.Kind] == nil {
+				s.values[e.Kind] = make(map[string]Entry)
+			}
+			s.values[e.Kind][e.Value] = e
+			continue
+		}
+		prefix, err := netip.ParsePrefix(e.Value)
+		if err != nil {
+			slog.Warn("Skipping unparseable blocklisted network", "id", e.ID, "value", e.Value)
+			continue
+		}
+		if s.networks[prefix.Bits()] == nil {
+			s.networks[prefix.Bits()] = make(map[netip.Prefix]Entry)
+			s.lengths = append(s.lengths, prefix.Bits())
+		}
+		s.networks[prefix.Bits()][prefix] = e
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(s.lengths)))
+	return s
+}
+
+// Cache is an in-memory copy of the live blocklist. It is reloaded by the
+// process that changes the list as soon as it has, and by Run when another
+// process has.
+type Cache struct {
+	db       *sql.DB
+	interval time.Duration
+
+	mu  sync.RWMutex
+	set *set
+}
+
+// NewCache creates an empty cache of the list in db; Reload fills it. Run
+// checks for changes every interval.
+func NewCache(db *sql.DB, interval time.Duration) *Cache {
+	return &Cache{db: db, interval: interval, set: newSet(nil, 0)}
+}
+
+// Reload replaces the cached entries with the live ones.
+func (c *Cache) Reload(ctx context.Context) error {
+	entries, revision, err := live(ctx, c.db)
+	if err != nil {
+		return err
+	}
+	s := newSet(entries, revision)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// A concurrent reload may already have seen a later revision
+	if s.revision >= c.set.revision {
+		c.set = s
+	}
+	return nil
+}
+
+// Refresh reloads the cache if the list changed since it was last loaded.
+func (c *Cache) Refresh(ctx context.Context) error {
+	revision, err := Revision(ctx, c.db)
+	if err != nil {
+		return err
+	}
+	if revision == c.current().revision {
+		return nil
+	}
+	return c.Reload(ctx)
+}
+
+// Run refreshes the cache every interval until ctx is cancelled.
+func (c *Cache) Run(ctx context.Context) {
+	slog.Info("Blocklist refresher started", "interval", c.interval)
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Blocklist refresher stopped")
+			return
+		case <-ticker.C:
+		}
+		if err := c.Refresh(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("Blocklist refresh failed", "error", err)
+		}
+	}
+}
+
+func (c *Cache) current() *set {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.set
+}
+
+// Snapshot returns the cached entries and the revision they were loaded at.
+// Entries that expired since are included.
+func (c *Cache) Snapshot() (revision int64, entries []Entry) {
+	s := c.current()
+	return s.revision, s.entries
+}
+
+// Payee returns the entry blocking the account number or VPA account, if
+// one does.
+func (c *Cache) Payee(account string) (Entry, bool) {
+	kind := KindAccount
+	if strings.Contains(account, "@") {
+		kind = KindVPA
+	}
+	value, err := NormalizeValue(kind, account)
+	if err != nil {
+		return Entry{}, false
+	}
+	return c.value(kind, value)
+}
+
+// Email returns the entry blocking email, or its domain, if one does.
+func (c *Cache) Email(email string) (Entry, bool) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return Entry{}, false
+	}
+	if e, ok := c.value(KindEmail, email); ok {
+		return e, true
+	}
+	if i := strings.LastIndex(email, "@"); i >= 0 {
+		return c.value(KindEmail, email[i:])
+	}
+	return Entry{}, false
+}
+
+// IP returns the entry for the most specific network containing addr, if
+// one does.
+func (c *Cache) IP(addr netip.Addr) (Entry, bool) {
+	if !addr.IsValid() {
+		return Entry{}, false
+	}
+	addr = addr.Unmap()
+	s, now := c.current(), time.Now()
+	for _, bits := range s.lengths {
+		if bits > addr.BitLen() {
+			continue
+		}
+		prefix, err := addr.Prefix(bits)
+		if err != nil {
+			continue
+		}
+		if e, ok := s.networks[bits][prefix]; ok && e.Active(now) {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+func (c *Cache) value(kind Kind, value string) (Entry, bool) {
+	e, ok := c.current().values[kind][value]
+	if !ok || !e.Active(time.Now()) {
+		return Entry{}, false
+	}
+	return e, true
+}
@@ -0,0 +1,169 @@
+package blocklist
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/internal/dbtest"
+)
+
+func TestCacheMatching(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	c := NewCache(nil, time.Minute)
+	c.set = newSet([]Entry{
+		{ID: 1, Kind: KindAccount, Value: "50100123456789", Reason: "mule account"},
+		{ID: 2, Kind: KindVPA, Value: "mule@okbank", Reason: "mule VPA"},
+		{ID: 3, Kind: KindAccount, Value: "ACC-LAPSED", Reason: "expired", ExpiresAt: &past},
+		{ID: 4, Kind: KindAccount, Value: "ACC-DELISTED", Reason: "removed", RemovedAt: &past},
+		{ID: 5, Kind: KindEmail, Value: "fraud@example.com", Reason: "chargebacks"},
+		{ID: 6, Kind: KindEmail, Value: "@throwaway.example", Reason: "disposable domain", ExpiresAt: &future},
+		{ID: 7, Kind: KindIP, Value: "198.51.100.0/24", Reason: "botnet range"},
+		{ID: 8, Kind: KindIP, Value: "198.51.100.7/32", Reason: "single host"},
+		{ID: 9, Kind: KindIP, Value: "2001:db8::/32", Reason: "v6 range"},
+		{ID: 10, Kind: KindIP, Value: "not-a-network", Reason: "skipped"},
+	}, 3)
+
+	payees := []struct {
+		account string
+		reason  string // empty when not blocked
+	}{
+		{"50100123456789", "mule account"},
+		{"5010 0123 4567 89", "mule account"},
+		{"Mule@OKBank", "mule VPA"},
+		{"mule@otherbank", ""},
+		{"ACC-LAPSED", ""},
+		{"acc-delisted", ""},
+		{"ACC-HONEST", ""},
+		{"", ""},
+	}
+	for _, tt := range payees {
+		e, ok := c.Payee(tt.account)
+		if ok != (tt.reason != "") || e.Reason != tt.reason {
+			t.Errorf("Payee(%q) = %q, %t; want %q", tt.account, e.Reason, ok, tt.reason)
+		}
+	}
+	// An account number is not an email, however it is written
+	if _, ok := c.Email("50100123456789"); ok {
+		t.Error("an account entry matched as an email")
+	}
+
+	emails := []struct {
+		email  string
+		reason string
+	}{
+		{" FRAUD@Example.com ", "chargebacks"},
+		{"anyone@throwaway.example", "disposable domain"},
+		{"someone@sub.throwaway.example", ""},
+		{"honest@example.com", ""},
+		{"", ""},
+	}
+	for _, tt := range emails {
+		e, ok := c.Email(tt.email)
+		if ok != (tt.reason != "") || e.Reason != tt.reason {
+			t.Errorf("Email(%q) = %q, %t; want %q", tt.email, e.Reason, ok, tt.reason)
+		}
+	}
+
+	ips := []struct {
+		ip     string
+		reason string
+	}{
+		{"198.51.100.7", "single host"}, // the most specific network wins
+		{"198.51.100.8", "botnet range"},
+		{"::ffff:198.51.100.9", "botnet range"},
+		{"2001:db8:1::5", "v6 range"},
+		{"192.0.2.1", ""},
+	}
+	for _, tt := range ips {
+		e, ok := c.IP(netip.MustParseAddr(tt.ip))
+		if ok != (tt.reason != "") || e.Reason != tt.reason {
+			t.Errorf("IP(%s) = %q, %t; want %q", tt.ip, e.Reason, ok, tt.reason)
+		}
+	}
+	if _, ok := c.IP(netip.Addr{}); ok {
+		t.Error("an invalid address matched")
+	}
+
+	if revision, entries := c.Snapshot(); revision != 3 || len(entries) != 10 {
+		t.Errorf("Snapshot = revision %d with %d entries, want 3 with 10", revision, len(entries))
+	}
+}
+
+func TestNormalizeValue(t *testing.T) {
+	tests := []struct {
+		kind  Kind
+		value string
+		want  string // empty when invalid
+	}{
+		{KindAccount, " 5010 0123 4567 89 ", "50100123456789"},
+		{KindAccount, "acc-1", "ACC-1"},
+		{KindAccount, "name@bank", ""},
+		{KindVPA, "Name.Surname@OKBank", "name.surname@okbank"},
+		{KindVPA, "name@", ""},
+		{KindEmail, "Fraud@Example.COM", "fraud@example.com"},
+		{KindEmail, "@Throwaway.Example", "@throwaway.example"},
+		{KindEmail, "not-an-email", ""},
+		{KindIP, "198.51.100.7", "198.51.100.7/32"},
+		{KindIP, "198.51.100.7/24", "198.51.100.0/24"},
+		{KindIP, "::ffff:198.51.100.7", "198.51.100.7/32"},
+		{KindIP, "::ffff:198.51.100.0/120", "198.51.100.0/24"},
+		{KindIP, "2001:DB8::1/32", "2001:db8::/32"},
+		{KindIP, "198.51.100.300", ""},
+		{"phone", "9876543210", ""},
+		{KindAccount, "  ", ""},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeValue(tt.kind, tt.value)
+		if (err == nil) != (tt.want != "") || got != tt.want {
+			t.Errorf("NormalizeValue(%s, %q) = %q, %v; want %q", tt.kind, tt.value, got, err, tt.want)
+		}
+	}
+}
+
+func TestCacheRefresh(t *testing.T) {
+	database := dbtest.Open(t)
+	ctx := context.Background()
+	c := NewCache(database, time.Minute)
+	if err := c.Reload(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	e := Entry{Kind: KindAccount, Value: "50100123456789", Reason: "mule account", CreatedBy: 1}
+	if err := Normalize(&e); err != nil {
+		t.Fatal(err)
+	}
+	added, err := Add(ctx, database, e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Add(ctx, database, e); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("listing a value twice: err = %v, want ErrDuplicate", err)
+	}
+	// Another process changed the list; the cache sees it once refreshed
+	if _, ok := c.Payee("50100123456789"); ok {
+		t.Fatal("cache matched before refreshing")
+	}
+	if err := c.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := c.Payee("50100123456789"); !ok || got.ID != added.ID {
+		t.Fatalf("after refresh: %+v, %t; want entry %d", got, ok, added.ID)
+	}
+
+	if _, err := Remove(ctx, database, added.ID, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Payee("50100123456789"); ok {
+		t.Error("delisted account still matches")
+	}
+	if _, err := Remove(ctx, database, added.ID, 2); !errors.Is(err, ErrNotFound) {
+		t.Errorf("delisting twice: err = %v, want ErrNotFound", err)
+	}
+}
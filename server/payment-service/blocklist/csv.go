@@ -0,0 +1,144 @@
+package blocklist
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/tracing"
+)
+
+// csvColumns is the header a blocklist file must start with.
+var csvColumns = []string{"kind", "value", "reason", "expires_at"}
+
+// ParseCSV reads entries in the blocklist file format: a header row
+// "kind,value,reason,expires_at", then one entry per row. expires_at is
+// empty for an entry that never expires, an RFC 3339 time, or a date,
+// meaning the start of that day in UTC. Blank lines and lines starting
+// with # are ignored. Errors name the offending line.
+func ParseCSV(r io.Reader) ([]Entry, error) {
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	cr.FieldsPerRecord = len(csvColumns)
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("missing header row")
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i, col := range csvColumns {
+		if !strings.EqualFold(strings.TrimSpace(header[i]), col) {
+			return nil, fmt.Errorf("header must be %s", strings.Join(csvColumns, ","))
+		}
+	}
+
+	var entries []Entry
+	seen := make(map[Kind]map[string]bool)
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+
+		e := Entry{Kind: Kind(record[0]), Value: record[1], Reason: record[2]}
+		if s := strings.TrimSpace(record[3]); s != "" {
+			expiresAt, err := parseExpiry(s)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: expires_at must be an RFC 3339 time or a YYYY-MM-DD date", line)
+			}
+			e.ExpiresAt = &expiresAt
+		}
+		if err := Normalize(&e); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if seen[e.Kind] == nil {
+			seen[e.Kind] = make(map[string]bool)
+		}
+		if seen[e.Kind][e.Value] {
+			return nil, fmt.Errorf("line %d: %s %s is listed twice", line, e.Kind, e.Value)
+		}
+		seen[e.Kind][e.Value] = true
+		entries = append(entries, e)
+	}
+}
+
+func parseExpiry(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.UTC(), nil
+	}
+	return time.Parse(time.DateOnly, s)
+}
+
+// Import lists entries, which must have been normalised, on by's behalf in
+// one change. An entry whose value is already listed replaces it, taking
+// its reason, expiry and creator. It returns how many entries were added
+// and how many replaced.
+func Import(ctx context.Context, db *sql.DB, entries []Entry, by int) (added, replaced int, err error) {
+	const query = `INSERT INTO blocklist_entries (kind, value, reason, expires_at, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (kind, value) WHERE removed_at IS NULL DO UPDATE SET
+			reason = EXCLUDED.reason,
+			expires_at = EXCLUDED.expires_at,
+			created_by = EXCLUDED.created_by,
+			created_at = NOW(),
+			revision = nextval('blocklist_revision_seq')
+		RETURNING xmax = 0`
+
+	err = change(ctx, db, func(tx *sql.Tx) error {
+		stmt, err := tx.PrepareContext(ctx, query)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		spanCtx, span := tracing.StartDBSpan(ctx, "INSERT", "blocklist_entries", query)
+		for _, e := range entries {
+			var inserted bool
+			err := stmt.QueryRowContext(spanCtx, string(e.Kind), e.Value, e.Reason, nullTime(e.ExpiresAt), by).Scan(&inserted)
+			if err != nil {
+				tracing.End(span, err)
+				return fmt.Errorf("import %s %s: %w", e.Kind, e.Value, err)
+			}
+			if inserted {
+				added++
+			} else {
+				replaced++
+			}
+		}
+		tracing.End(span, nil)
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return added, replaced, nil
+}
+
+// ImportFile parses the blocklist file at path and imports it on by's
+// behalf.
+func ImportFile(ctx context.Context, db *sql.DB, path string, by int) (added, replaced int, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	entries, err := ParseCSV(f)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s: %w", path, err)
+	}
+	return Import(ctx, db, entries, by)
+}
@@ -0,0 +1,212 @@
+// Package currencies is the registry of currencies payments may use and the
+// number of minor-unit digits Razorpay expects for each.
+package currencies
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when a currency is not in the registry.
+var ErrNotFound = errors.New("currency not found")
+
+// ErrUnsupported is returned when a currency is unknown or disabled.
+var ErrUnsupported = errors.New("currency is not supported")
+
+// MaxExponent is the most minor-unit digits a currency can have; payment
+// amounts are stored with two decimals.
+const MaxExponent = 2
+
+// Currency is a registry entry.
+type Currency struct {
+	Code     string
+	Name     string
+	Exponent int
+	Enabled  bool
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// ToMinor converts amount to minor units, rounding to the nearest unit.
+func (c Currency) ToMinor(amount float64) int64 {
+	return int64(math.Round(amount * math.Pow10(c.Exponent)))
+}
+
+// FromMinor converts minor units back to the major unit.
+func (c Currency) FromMinor(minor int64) float64 {
+	return float64(minor) / math.Pow10(c.Exponent)
+}
+
+// Round rounds amount to the currency's precision.
+func (c Currency) Round(amount float64) float64 {
+	return c.FromMinor(c.ToMinor(amount))
+}
+
+// Decimal formats minor units as a plain signed decimal with the
+// currency's digits, e.g. -1234.50 in INR or -1234 in JPY, which
+// spreadsheets and accounting imports parse without locale issues.
+func (c Currency) Decimal(minor int64) string {
+	return c.format(minor, false)
+}
+
+// Format formats minor units with the currency's digits and thousands
+// separators, e.g. 1,234,567.89, for documents shown to people.
+func (c Currency) Format(minor int64) string {
+	return c.format(minor, true)
+}
+
+func (c Currency) format(minor int64, group bool) string {
+	sign := ""
+	if minor < 0 {
+		sign, minor = "-", -minor
+	}
+	unit := int64(math.Pow10(c.Exponent))
+	whole := strconv.FormatInt(minor/unit, 10)
+	var b strings.Builder
+	b.WriteString(sign)
+	for i, r := range whole {
+		if group && i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(r)
+	}
+	if c.Exponent > 0 {
+		fmt.Fprintf(&b, ".%0*d", c.Exponent, minor%unit)
+	}
+	return b.String()
+}
+
+// Precise reports whether amount has no more decimals than the currency
+// has minor units, so 10.5 is fine in INR but not in JPY.
+func (c Currency) Precise(amount float64) bool {
+	minor := amount * math.Pow10(c.Exponent)
+	return math.Abs(minor-math.Round(minor)) < 1e-6
+}
+
+var codePattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// NormalizeCode upper-cases code and checks it is three letters.
+func NormalizeCode(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if !codePattern.MatchString(code) {
+		return "", errors.New("currency must be a three-letter ISO 4217 code")
+	}
+	return code, nil
+}
+
+// Normalize trims and checks c, returning a description of the first
+// problem found.
+func Normalize(c *Currency) error {
+	code, err := NormalizeCode(c.Code)
+	if err != nil {
+		return err
+	}
+	c.Code = code
+	c.Name = strings.TrimSpace(c.Name)
+	if c.Name == "" || len(c.Name) > 64 {
+		return errors.New("name is required and must be at most 64 characters")
+	}
+	if c.Exponent < 0 || c.Exponent > MaxExponent {
+		return fmt.Errorf("exponent must be between 0 and %d", MaxExponent)
+	}
+	return nil
+}
+
+// queryer is satisfied by *sql.DB and *sql.Tx.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+const columns = `code, name, exponent, enabled, created_at, updated_at`
+
+func scan(row interface{ Scan(...any) error }) (Currency, error) {
+	var c Currency
+	err := row.Scan(&c.Code, &c.Name, &c.Exponent, &c.Enabled, &c.CreatedAt, &c.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Currency{}, ErrNotFound
+	}
+	return c, err
+}
+
+// List returns the registry ordered by code, optionally only the enabled
+// currencies.
+func List(ctx context.Context, q queryer, enabledOnly bool) ([]Currency, error) {
+	rows, err := q.QueryContext(ctx, `SELECT `+columns+` FROM currencies
+		WHERE enabled OR NOT $1
+		ORDER BY code`, enabledOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Currency
+	for rows.Next() {
+		c, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, c)
+	}
+	return list, rows.Err()
+}
+
+// Get returns the currency with code.
+func Get(ctx context.Context, q queryer, code string) (Currency, error) {
+	return scan(q.QueryRowContext(ctx, `SELECT `+columns+` FROM currencies WHERE code = $1`, code))
+}
+
+// Supported returns the currency with code if payments may use it, and an
+// error wrapping ErrUnsupported if it is unknown or disabled.
+func Supported(ctx context.Context, q queryer, code string) (Currency, error) {
+	c, err := Get(ctx, q, code)
+	if errors.Is(err, ErrNotFound) || (err == nil && !c.Enabled) {
+		return Currency{}, fmt.Errorf("%w: %s", ErrUnsupported, code)
+	}
+	return c, err
+}
+
+// Put adds c to the registry or replaces the entry with its code. c must
+// have passed Normalize.
+func Put(ctx context.Context, q queryer, c Currency) (Currency, error) {
+	return scan(q.QueryRowContext(ctx, `INSERT INTO currencies (code, name, exponent, enabled)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (code) DO UPDATE SET name = EXCLUDED.name, exponent = EXCLUDED.exponent, enabled = EXCLUDED.enabled
+		RETURNING `+columns, c.Code, c.Name, c.Exponent, c.Enabled))
+}
+
+// Registry is a snapshot of the registry keyed by code.
+type Registry map[string]Currency
+
+// Load returns a snapshot of the whole registry, disabled currencies
+// included.
+func Load(ctx context.Context, q queryer) (Registry, error) {
+	list, err := List(ctx, q, false)
+	if err != nil {
+		return nil, err
+	}
+	reg := make(Registry, len(list))
+	for _, c := range list {
+		reg[c.Code] = c
+	}
+	return reg, nil
+}
+
+// Lookup returns the currency with code, or a two-digit stand-in for codes
+// missing from the registry, such as orders created outside this service.
+func (reg Registry) Lookup(code string) Currency {
+	code = strings.ToUpper(code)
+	if c, ok := reg[code]; ok {
+		return c
+	}
+	return Currency{Code: code, Name: code, Exponent: MaxExponent}
+}
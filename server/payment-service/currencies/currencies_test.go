@@ -0,0 +1,75 @@
+package currencies
+
+import "testing"
+
+var (
+	inr = Currency{Code: "INR", Exponent: 2}
+	jpy = Currency{Code: "JPY", Exponent: 0}
+	one = Currency{Code: "XXX", Exponent: 1}
+)
+
+func TestMinorUnits(t *testing.T) {
+	tests := []struct {
+		cur    Currency
+		amount float64
+		minor  int64
+	}{
+		{inr, 0.29, 29}, // not 28.999...
+		{inr, 1234.5, 123450},
+		{inr, 0.005, 1},
+		{jpy, 1500, 1500},
+		{jpy, 1500.5, 1501},
+		{one, 12.3, 123},
+	}
+	for _, tt := range tests {
+		if got := tt.cur.ToMinor(tt.amount); got != tt.minor {
+			t.Errorf("%s ToMinor(%v) = %d, want %d", tt.cur.Code, tt.amount, got, tt.minor)
+		}
+	}
+
+	if got := jpy.FromMinor(1500); got != 1500 {
+		t.Errorf("JPY FromMinor(1500) = %v, want 1500", got)
+	}
+	if got := inr.FromMinor(123450); got != 1234.5 {
+		t.Errorf("INR FromMinor(123450) = %v, want 1234.5", got)
+	}
+	if !inr.Precise(10.5) || jpy.Precise(10.5) || !jpy.Precise(10) {
+		t.Error("Precise disagrees with the exponent")
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		cur     Currency
+		minor   int64
+		decimal string
+		format  string
+	}{
+		{inr, 0, "0.00", "0.00"},
+		{inr, 5, "0.05", "0.05"},
+		{inr, 123456789, "1234567.89", "1,234,567.89"},
+		{inr, -100050, "-1000.50", "-1,000.50"},
+		{jpy, 1500, "1500", "1,500"},
+		{jpy, -999, "-999", "-999"},
+		{jpy, 1000000, "1000000", "1,000,000"},
+		{one, 12345, "1234.5", "1,234.5"},
+	}
+	for _, tt := range tests {
+		if got := tt.cur.Decimal(tt.minor); got != tt.decimal {
+			t.Errorf("%s Decimal(%d) = %q, want %q", tt.cur.Code, tt.minor, got, tt.decimal)
+		}
+		if got := tt.cur.Format(tt.minor); got != tt.format {
+			t.Errorf("%s Format(%d) = %q, want %q", tt.cur.Code, tt.minor, got, tt.format)
+		}
+	}
+}
+
+func TestRegistryLookup(t *testing.T) {
+	reg := Registry{"JPY": jpy}
+	if c := reg.Lookup("jpy"); c.Exponent != 0 {
+		t.Errorf("Lookup(jpy) exponent = %d, want 0", c.Exponent)
+	}
+	if c := reg.Lookup("xyz"); c.Code != "XYZ" || c.Exponent != MaxExponent {
+		t.Errorf("Lookup(xyz) = %+v, want a two-digit XYZ", c)
+	}
+}
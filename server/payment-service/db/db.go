@@ -2,19 +2,18 @@ package db
 
 import (
 	"database/sql"
-	"log"
-	"os"
+	"log/slog"
+	"net/url"
 
 	_ "github.com/lib/pq"
 )
 
 var DB *sql.DB
 
-func Init() error {
+// Init opens the connection pool for dbURL and verifies it with a ping.
+func Init(dbURL string) error {
 	var err error
-	dbURL := os.Getenv("DATABASE_URL") // ✅ Correct key
-
-	log.Println("Connecting to DB with URL:", dbURL)
+	slog.Info("Connecting to DB", "url", redactURL(dbURL))
 
 	DB, err = sql.Open("postgres", dbURL)
 	if err != nil {
@@ -25,16 +24,25 @@ func Init() error {
 		return err
 	}
 
-	log.Println("Database connected successfully")
+	slog.Info("Database connected successfully")
 	return nil
 }
 
 func Close() {
 	if DB != nil {
 		if err := DB.Close(); err != nil {
-			log.Printf("Error closing DB: %v", err)
+			slog.Error("Error closing DB", "error", err)
 		} else {
-			log.Println("Database connection closed.")
+			slog.Info("Database connection closed")
 		}
 	}
 }
+
+// redactURL hides the password in a connection URL before it is logged.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "[unparseable]"
+	}
+	return u.Redacted()
+}
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS payments;
+DROP FUNCTION IF EXISTS payment_touch_updated_at();
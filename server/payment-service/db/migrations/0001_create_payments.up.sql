@@ -0,0 +1,38 @@
+-- Payments created through POST /api/v1/pay. user_id references users owned
+-- by auth-service, so it is not a foreign key.
+CREATE TABLE IF NOT EXISTS payments (
+    id                BIGSERIAL PRIMARY KEY,
+    user_id           INTEGER        NOT NULL,
+    amount            NUMERIC(18, 2) NOT NULL,
+    currency          CHAR(3)        NOT NULL DEFAULT 'INR',
+    from_account      VARCHAR(128)   NOT NULL,
+    to_account        VARCHAR(128)   NOT NULL,
+    razorpay_order_id VARCHAR(64)    NOT NULL,
+    status            VARCHAR(32)    NOT NULL DEFAULT 'created',
+    description       TEXT,
+    created_at        TIMESTAMPTZ    NOT NULL DEFAULT NOW(),
+    updated_at        TIMESTAMPTZ    NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT payments_amount_positive CHECK (amount > 0),
+    CONSTRAINT payments_currency_format CHECK (currency ~ '^[A-Z]{3}$'),
+    CONSTRAINT payments_status_check CHECK (
+        status IN ('created', 'pending', 'completed', 'failed', 'refunded')
+    ),
+    CONSTRAINT payments_razorpay_order_id_key UNIQUE (razorpay_order_id)
+);
+
+CREATE INDEX IF NOT EXISTS payments_user_id_created_at_idx ON payments (user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS payments_status_created_at_idx ON payments (status, created_at);
+
+-- Keeps updated_at current on every UPDATE; shared by later payment tables.
+CREATE OR REPLACE FUNCTION payment_touch_updated_at() RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = NOW();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS payments_touch_updated_at ON payments;
+CREATE TRIGGER payments_touch_updated_at
+    BEFORE UPDATE ON payments
+    FOR EACH ROW EXECUTE FUNCTION payment_touch_updated_at();
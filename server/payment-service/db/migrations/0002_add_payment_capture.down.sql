@@ -0,0 +1,9 @@
+UPDATE payments SET status = 'completed' WHERE status = 'captured';
+
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS payments_status_check;
+ALTER TABLE payments ADD CONSTRAINT payments_status_check CHECK (
+    status IN ('created', 'pending', 'completed', 'failed', 'refunded')
+);
+
+DROP INDEX IF EXISTS payments_razorpay_payment_id_key;
+ALTER TABLE payments DROP COLUMN IF EXISTS razorpay_payment_id;
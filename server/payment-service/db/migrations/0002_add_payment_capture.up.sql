@@ -0,0 +1,11 @@
+-- Razorpay reports captures, failures and refunds against its own payment
+-- ID; keep it next to the order so refund events can be matched back.
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS razorpay_payment_id VARCHAR(64);
+
+CREATE UNIQUE INDEX IF NOT EXISTS payments_razorpay_payment_id_key
+    ON payments (razorpay_payment_id) WHERE razorpay_payment_id IS NOT NULL;
+
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS payments_status_check;
+ALTER TABLE payments ADD CONSTRAINT payments_status_check CHECK (
+    status IN ('created', 'pending', 'captured', 'completed', 'failed', 'refunded')
+);
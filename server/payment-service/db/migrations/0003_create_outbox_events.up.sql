@@ -0,0 +1,30 @@
+-- Transactional outbox. Domain events are inserted in the same transaction
+-- as the state change they describe and published afterwards by the relay,
+-- so an event exists if and only if the change was committed.
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id              BIGSERIAL PRIMARY KEY,
+    event_id        VARCHAR(64)  NOT NULL,
+    event_type      VARCHAR(64)  NOT NULL,
+    aggregate_type  VARCHAR(32)  NOT NULL,
+    aggregate_id    VARCHAR(64)  NOT NULL,
+    payload         JSONB        NOT NULL,
+    created_at      TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    published_at    TIMESTAMPTZ,
+    attempts        INTEGER      NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    last_error      TEXT,
+
+    CONSTRAINT outbox_events_event_id_key UNIQUE (event_id)
+);
+
+-- The relay only ever scans unpublished rows.
+CREATE INDEX IF NOT EXISTS outbox_events_pending_idx
+    ON outbox_events (next_attempt_at, id) WHERE published_at IS NULL;
+
+-- Per-aggregate ordering looks up earlier unpublished events.
+CREATE INDEX IF NOT EXISTS outbox_events_aggregate_pending_idx
+    ON outbox_events (aggregate_type, aggregate_id, id) WHERE published_at IS NULL;
+
+-- Retention pruning of delivered events.
+CREATE INDEX IF NOT EXISTS outbox_events_published_at_idx
+    ON outbox_events (published_at) WHERE published_at IS NOT NULL;
@@ -0,0 +1,71 @@
+-- Merchant webhook endpoints. The signing secret has to be usable for
+-- HMAC, so it is stored as-is; previous_secret keeps signing alongside the
+-- new one until previous_secret_expires_at after a rotation.
+CREATE TABLE IF NOT EXISTS webhook_endpoints (
+    id                         BIGSERIAL PRIMARY KEY,
+    user_id                    INTEGER      NOT NULL,
+    url                        TEXT         NOT NULL,
+    description                TEXT,
+    -- Empty means every event type
+    event_types                TEXT[]       NOT NULL DEFAULT '{}',
+    secret                     VARCHAR(128) NOT NULL,
+    previous_secret            VARCHAR(128),
+    previous_secret_expires_at TIMESTAMPTZ,
+    active                     BOOLEAN      NOT NULL DEFAULT TRUE,
+    created_at                 TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    updated_at                 TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS webhook_endpoints_user_id_idx ON webhook_endpoints (user_id) WHERE active;
+
+DROP TRIGGER IF EXISTS webhook_endpoints_touch_updated_at ON webhook_endpoints;
+CREATE TRIGGER webhook_endpoints_touch_updated_at
+    BEFORE UPDATE ON webhook_endpoints
+    FOR EACH ROW EXECUTE FUNCTION payment_touch_updated_at();
+
+-- One delivery per (endpoint, event); the unique key absorbs outbox
+-- redeliveries.
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id              BIGSERIAL PRIMARY KEY,
+    endpoint_id     BIGINT      NOT NULL REFERENCES webhook_endpoints (id) ON DELETE CASCADE,
+    event_id        VARCHAR(64) NOT NULL,
+    event_type      VARCHAR(64) NOT NULL,
+    payload         JSONB       NOT NULL,
+    status          VARCHAR(16) NOT NULL DEFAULT 'pending',
+    attempts        INTEGER     NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_attempt_at TIMESTAMPTZ,
+    response_code   INTEGER,
+    latency_ms      INTEGER,
+    last_error      TEXT,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT webhook_deliveries_status_check CHECK (status IN ('pending', 'succeeded', 'failed')),
+    CONSTRAINT webhook_deliveries_endpoint_event_key UNIQUE (endpoint_id, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx
+    ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS webhook_deliveries_endpoint_created_at_idx
+    ON webhook_deliveries (endpoint_id, created_at DESC);
+
+DROP TRIGGER IF EXISTS webhook_deliveries_touch_updated_at ON webhook_deliveries;
+CREATE TRIGGER webhook_deliveries_touch_updated_at
+    BEFORE UPDATE ON webhook_deliveries
+    FOR EACH ROW EXECUTE FUNCTION payment_touch_updated_at();
+
+-- Every HTTP attempt, kept for debugging failed integrations.
+CREATE TABLE IF NOT EXISTS webhook_delivery_attempts (
+    id            BIGSERIAL PRIMARY KEY,
+    delivery_id   BIGINT      NOT NULL REFERENCES webhook_deliveries (id) ON DELETE CASCADE,
+    attempt       INTEGER     NOT NULL,
+    response_code INTEGER,
+    response_body TEXT,
+    latency_ms    INTEGER     NOT NULL,
+    error         TEXT,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS webhook_delivery_attempts_delivery_id_idx
+    ON webhook_delivery_attempts (delivery_id, attempt);
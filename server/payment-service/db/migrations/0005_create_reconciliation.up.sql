@@ -0,0 +1,43 @@
+-- One row per reconciliation pass against Razorpay.
+CREATE TABLE IF NOT EXISTS reconciliation_runs (
+    id            BIGSERIAL PRIMARY KEY,
+    trigger       VARCHAR(16) NOT NULL,
+    status        VARCHAR(16) NOT NULL DEFAULT 'running',
+    window_start  TIMESTAMPTZ NOT NULL,
+    window_end    TIMESTAMPTZ NOT NULL,
+    scanned       INTEGER     NOT NULL DEFAULT 0,
+    corrected     INTEGER     NOT NULL DEFAULT 0,
+    discrepancies INTEGER     NOT NULL DEFAULT 0,
+    errors        INTEGER     NOT NULL DEFAULT 0, -- payments the gateway could not be asked about
+    error         TEXT,
+    started_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    finished_at   TIMESTAMPTZ,
+
+    CONSTRAINT reconciliation_runs_trigger_check CHECK (trigger IN ('scheduled', 'manual', 'cli')),
+    CONSTRAINT reconciliation_runs_status_check CHECK (status IN ('running', 'completed', 'failed'))
+);
+
+CREATE INDEX IF NOT EXISTS reconciliation_runs_started_at_idx ON reconciliation_runs (started_at DESC);
+
+-- Findings of a run. status_corrected rows record fixes the run applied;
+-- the other kinds need a human to look at them.
+CREATE TABLE IF NOT EXISTS reconciliation_discrepancies (
+    id                BIGSERIAL PRIMARY KEY,
+    run_id            BIGINT         NOT NULL REFERENCES reconciliation_runs (id) ON DELETE CASCADE,
+    kind              VARCHAR(32)    NOT NULL,
+    payment_id        BIGINT,
+    razorpay_order_id VARCHAR(64),
+    local_status      VARCHAR(32),
+    gateway_status    VARCHAR(32),
+    local_amount      NUMERIC(18, 2),
+    gateway_amount    NUMERIC(18, 2),
+    currency          CHAR(3),
+    details           TEXT,
+    created_at        TIMESTAMPTZ    NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT reconciliation_discrepancies_kind_check CHECK (
+        kind IN ('local_only', 'gateway_only', 'amount_mismatch', 'status_corrected')
+    )
+);
+
+CREATE INDEX IF NOT EXISTS reconciliation_discrepancies_run_id_idx ON reconciliation_discrepancies (run_id);
@@ -0,0 +1,9 @@
+UPDATE payments SET status = 'failed' WHERE status = 'expired';
+
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS payments_status_check;
+ALTER TABLE payments ADD CONSTRAINT payments_status_check CHECK (
+    status IN ('created', 'pending', 'captured', 'completed', 'failed', 'refunded')
+);
+
+DROP INDEX IF EXISTS payments_expiry_idx;
+ALTER TABLE payments DROP COLUMN IF EXISTS expires_at;
@@ -0,0 +1,16 @@
+-- Unpaid orders expire: the sweeper moves `created` payments past
+-- expires_at to `expired`, and verification against them is refused.
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;
+
+-- Existing unpaid payments get a day from creation rather than expiring
+-- the moment this is deployed.
+UPDATE payments SET expires_at = GREATEST(created_at + INTERVAL '1 day', NOW() + INTERVAL '1 hour')
+    WHERE status = 'created' AND expires_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS payments_expiry_idx
+    ON payments (expires_at) WHERE status = 'created';
+
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS payments_status_check;
+ALTER TABLE payments ADD CONSTRAINT payments_status_check CHECK (
+    status IN ('created', 'pending', 'captured', 'completed', 'failed', 'refunded', 'expired')
+);
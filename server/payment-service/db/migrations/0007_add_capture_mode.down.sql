@@ -0,0 +1,16 @@
+UPDATE payments SET status = 'pending' WHERE status = 'authorized';
+UPDATE payments SET status = 'failed' WHERE status = 'voided';
+
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS payments_status_check;
+ALTER TABLE payments ADD CONSTRAINT payments_status_check CHECK (
+    status IN ('created', 'pending', 'captured', 'completed', 'failed', 'refunded', 'expired')
+);
+
+DROP INDEX IF EXISTS payments_release_pending_idx;
+DROP INDEX IF EXISTS payments_authorized_at_idx;
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS payments_captured_amount_check;
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS payments_capture_mode_check;
+ALTER TABLE payments DROP COLUMN IF EXISTS authorization_released_at;
+ALTER TABLE payments DROP COLUMN IF EXISTS captured_amount;
+ALTER TABLE payments DROP COLUMN IF EXISTS authorized_at;
+ALTER TABLE payments DROP COLUMN IF EXISTS capture_mode;
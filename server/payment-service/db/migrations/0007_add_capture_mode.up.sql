@@ -0,0 +1,30 @@
+-- Manual capture: Razorpay authorizes the payment and the merchant captures
+-- (all or part of) it later, or voids it.
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS capture_mode VARCHAR(8) NOT NULL DEFAULT 'auto';
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS authorized_at TIMESTAMPTZ;
+-- NULL means the full amount was captured.
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS captured_amount NUMERIC(18, 2);
+-- Voiding an authorization is local: Razorpay has no call to release one and
+-- refunds uncaptured authorizations itself at the end of its auto-refund
+-- window. authorization_released_at records when it reported doing so; a
+-- voided payment without it still holds the payer's funds.
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS authorization_released_at TIMESTAMPTZ;
+
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS payments_capture_mode_check;
+ALTER TABLE payments ADD CONSTRAINT payments_capture_mode_check CHECK (capture_mode IN ('auto', 'manual'));
+
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS payments_captured_amount_check;
+ALTER TABLE payments ADD CONSTRAINT payments_captured_amount_check CHECK (
+    captured_amount IS NULL OR (captured_amount > 0 AND captured_amount <= amount)
+);
+
+CREATE INDEX IF NOT EXISTS payments_authorized_at_idx
+    ON payments (authorized_at) WHERE status = 'authorized';
+
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS payments_status_check;
+ALTER TABLE payments ADD CONSTRAINT payments_status_check CHECK (
+    status IN ('created', 'pending', 'authorized', 'captured', 'completed', 'failed', 'refunded', 'expired', 'voided')
+);
+
+CREATE INDEX IF NOT EXISTS payments_release_pending_idx
+    ON payments (id) WHERE status = 'voided' AND authorization_released_at IS NULL;
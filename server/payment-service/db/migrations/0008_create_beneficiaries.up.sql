@@ -0,0 +1,32 @@
+-- Saved payees. Rows are soft-deleted so payments that referenced them keep
+-- pointing at what was actually paid.
+CREATE TABLE IF NOT EXISTS beneficiaries (
+    id                  BIGSERIAL PRIMARY KEY,
+    user_id             INTEGER      NOT NULL,
+    nickname            VARCHAR(64)  NOT NULL,
+    type                VARCHAR(16)  NOT NULL,
+    account_number      VARCHAR(18),
+    ifsc                CHAR(11),
+    account_holder_name VARCHAR(128),
+    vpa                 VARCHAR(255),
+    created_at          TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    updated_at          TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    deleted_at          TIMESTAMPTZ,
+
+    CONSTRAINT beneficiaries_type_check CHECK (type IN ('bank_account', 'vpa')),
+    CONSTRAINT beneficiaries_details_check CHECK (
+        (type = 'bank_account' AND account_number IS NOT NULL AND ifsc IS NOT NULL AND vpa IS NULL) OR
+        (type = 'vpa' AND vpa IS NOT NULL AND account_number IS NULL AND ifsc IS NULL)
+    )
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS beneficiaries_user_nickname_key
+    ON beneficiaries (user_id, lower(nickname)) WHERE deleted_at IS NULL;
+
+DROP TRIGGER IF EXISTS beneficiaries_touch_updated_at ON beneficiaries;
+CREATE TRIGGER beneficiaries_touch_updated_at
+    BEFORE UPDATE ON beneficiaries
+    FOR EACH ROW EXECUTE FUNCTION payment_touch_updated_at();
+
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS beneficiary_id BIGINT
+    REFERENCES beneficiaries (id) ON DELETE SET NULL;
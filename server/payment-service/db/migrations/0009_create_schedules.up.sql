@@ -0,0 +1,68 @@
+-- Scheduled and recurring payments. Each occurrence is created through the
+-- same path as POST /api/v1/pay when it falls due.
+CREATE TABLE IF NOT EXISTS schedules (
+    id              BIGSERIAL PRIMARY KEY,
+    user_id         INTEGER        NOT NULL,
+    amount          NUMERIC(18, 2) NOT NULL,
+    currency        CHAR(3)        NOT NULL DEFAULT 'INR',
+    from_account    VARCHAR(128)   NOT NULL,
+    to_account      VARCHAR(128),
+    beneficiary_id  BIGINT REFERENCES beneficiaries (id) ON DELETE SET NULL,
+    capture_mode    VARCHAR(16)    NOT NULL DEFAULT 'auto',
+    -- How long each occurrence's order stays payable; NULL uses the
+    -- currency's configured TTL
+    ttl_seconds     INTEGER,
+    description     TEXT,
+    frequency       VARCHAR(16)    NOT NULL,
+    start_at        TIMESTAMPTZ    NOT NULL,
+    end_at          TIMESTAMPTZ,
+    max_occurrences INTEGER,
+    -- Occurrences already scheduled, run or skipped; the next one is
+    -- occurrence number occurrences + 1, due at next_run_at
+    occurrences     INTEGER        NOT NULL DEFAULT 0,
+    next_run_at     TIMESTAMPTZ,
+    status          VARCHAR(16)    NOT NULL DEFAULT 'active',
+    created_at      TIMESTAMPTZ    NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMPTZ    NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT schedules_amount_positive CHECK (amount > 0),
+    CONSTRAINT schedules_currency_format CHECK (currency ~ '^[A-Z]{3}$'),
+    CONSTRAINT schedules_frequency_check CHECK (frequency IN ('once', 'daily', 'weekly', 'monthly')),
+    CONSTRAINT schedules_status_check CHECK (status IN ('active', 'paused', 'completed', 'cancelled')),
+    CONSTRAINT schedules_max_occurrences_positive CHECK (max_occurrences IS NULL OR max_occurrences > 0)
+);
+
+CREATE INDEX IF NOT EXISTS schedules_user_id_idx ON schedules (user_id, id DESC);
+CREATE INDEX IF NOT EXISTS schedules_due_idx ON schedules (next_run_at) WHERE status = 'active';
+
+DROP TRIGGER IF EXISTS schedules_touch_updated_at ON schedules;
+CREATE TRIGGER schedules_touch_updated_at
+    BEFORE UPDATE ON schedules
+    FOR EACH ROW EXECUTE FUNCTION payment_touch_updated_at();
+
+-- One row per occurrence; the unique key makes each occurrence run at most
+-- once however many replicas poll.
+CREATE TABLE IF NOT EXISTS schedule_executions (
+    id              BIGSERIAL PRIMARY KEY,
+    schedule_id     BIGINT      NOT NULL REFERENCES schedules (id) ON DELETE CASCADE,
+    occurrence      INTEGER     NOT NULL,
+    scheduled_for   TIMESTAMPTZ NOT NULL,
+    status          VARCHAR(16) NOT NULL DEFAULT 'pending',
+    attempts        INTEGER     NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_error      TEXT,
+    payment_id      BIGINT REFERENCES payments (id) ON DELETE SET NULL,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT schedule_executions_status_check CHECK (status IN ('pending', 'succeeded', 'failed', 'skipped')),
+    CONSTRAINT schedule_executions_occurrence_key UNIQUE (schedule_id, occurrence)
+);
+
+CREATE INDEX IF NOT EXISTS schedule_executions_due_idx
+    ON schedule_executions (next_attempt_at) WHERE status = 'pending';
+
+DROP TRIGGER IF EXISTS schedule_executions_touch_updated_at ON schedule_executions;
+CREATE TRIGGER schedule_executions_touch_updated_at
+    BEFORE UPDATE ON schedule_executions
+    FOR EACH ROW EXECUTE FUNCTION payment_touch_updated_at();
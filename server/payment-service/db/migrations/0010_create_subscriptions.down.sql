@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS subscription_invoices;
+DROP TABLE IF EXISTS subscriptions;
+DROP TABLE IF EXISTS plans;
@@ -0,0 +1,84 @@
+-- Recurring plans sold through Razorpay. Razorpay plans are immutable, so a
+-- price change is a new plan and the old one is deactivated.
+CREATE TABLE IF NOT EXISTS plans (
+    id               BIGSERIAL PRIMARY KEY,
+    razorpay_plan_id VARCHAR(64)    NOT NULL,
+    name             VARCHAR(128)   NOT NULL,
+    description      TEXT,
+    amount           NUMERIC(18, 2) NOT NULL,
+    currency         CHAR(3)        NOT NULL DEFAULT 'INR',
+    period           VARCHAR(16)    NOT NULL,
+    -- Billed every interval periods
+    interval_count   INTEGER        NOT NULL DEFAULT 1,
+    trial_days       INTEGER        NOT NULL DEFAULT 0,
+    active           BOOLEAN        NOT NULL DEFAULT TRUE,
+    created_at       TIMESTAMPTZ    NOT NULL DEFAULT NOW(),
+    updated_at       TIMESTAMPTZ    NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT plans_amount_positive CHECK (amount > 0),
+    CONSTRAINT plans_currency_format CHECK (currency ~ '^[A-Z]{3}$'),
+    CONSTRAINT plans_period_check CHECK (period IN ('daily', 'weekly', 'monthly', 'yearly')),
+    CONSTRAINT plans_interval_positive CHECK (interval_count > 0),
+    CONSTRAINT plans_trial_days_check CHECK (trial_days >= 0),
+    CONSTRAINT plans_razorpay_plan_id_key UNIQUE (razorpay_plan_id)
+);
+
+DROP TRIGGER IF EXISTS plans_touch_updated_at ON plans;
+CREATE TRIGGER plans_touch_updated_at
+    BEFORE UPDATE ON plans
+    FOR EACH ROW EXECUTE FUNCTION payment_touch_updated_at();
+
+-- Local copy of Razorpay subscriptions, kept current by subscription.*
+-- webhooks. gateway_updated_at is the creation time of the last event
+-- applied, so older events delivered late are ignored.
+CREATE TABLE IF NOT EXISTS subscriptions (
+    id                       BIGSERIAL PRIMARY KEY,
+    user_id                  INTEGER     NOT NULL,
+    plan_id                  BIGINT      NOT NULL REFERENCES plans (id),
+    razorpay_subscription_id VARCHAR(64) NOT NULL,
+    status                   VARCHAR(16) NOT NULL DEFAULT 'created',
+    quantity                 INTEGER     NOT NULL DEFAULT 1,
+    total_count              INTEGER     NOT NULL,
+    paid_count               INTEGER     NOT NULL DEFAULT 0,
+    short_url                TEXT,
+    trial_ends_at            TIMESTAMPTZ,
+    current_start            TIMESTAMPTZ,
+    current_end              TIMESTAMPTZ,
+    charge_at                TIMESTAMPTZ,
+    ended_at                 TIMESTAMPTZ,
+    cancel_at_period_end     BOOLEAN     NOT NULL DEFAULT FALSE,
+    gateway_updated_at       TIMESTAMPTZ,
+    created_at               TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at               TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT subscriptions_status_check CHECK (status IN (
+        'created', 'authenticated', 'active', 'pending', 'halted', 'paused', 'cancelled', 'completed', 'expired'
+    )),
+    CONSTRAINT subscriptions_razorpay_subscription_id_key UNIQUE (razorpay_subscription_id)
+);
+
+CREATE INDEX IF NOT EXISTS subscriptions_user_id_idx ON subscriptions (user_id, id DESC);
+
+DROP TRIGGER IF EXISTS subscriptions_touch_updated_at ON subscriptions;
+CREATE TRIGGER subscriptions_touch_updated_at
+    BEFORE UPDATE ON subscriptions
+    FOR EACH ROW EXECUTE FUNCTION payment_touch_updated_at();
+
+-- One row per paid billing cycle, from subscription.charged.
+CREATE TABLE IF NOT EXISTS subscription_invoices (
+    id                  BIGSERIAL PRIMARY KEY,
+    subscription_id     BIGINT         NOT NULL REFERENCES subscriptions (id) ON DELETE CASCADE,
+    cycle               INTEGER        NOT NULL,
+    razorpay_invoice_id VARCHAR(64),
+    razorpay_payment_id VARCHAR(64)    NOT NULL,
+    amount              NUMERIC(18, 2) NOT NULL,
+    currency            CHAR(3)        NOT NULL,
+    period_start        TIMESTAMPTZ,
+    period_end          TIMESTAMPTZ,
+    created_at          TIMESTAMPTZ    NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT subscription_invoices_razorpay_payment_id_key UNIQUE (razorpay_payment_id)
+);
+
+CREATE INDEX IF NOT EXISTS subscription_invoices_subscription_id_idx
+    ON subscription_invoices (subscription_id, cycle DESC);
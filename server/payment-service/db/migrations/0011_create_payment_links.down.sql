@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS payment_link_payments;
+DROP TABLE IF EXISTS payment_links;
+ALTER TABLE payments DROP COLUMN IF EXISTS direction;
@@ -0,0 +1,62 @@
+-- Payment links: a user requests money with a shareable /l/{code} URL and
+-- each payer's order is created through the same path as POST /api/v1/pay.
+-- The payment belongs to the link's owner, who is the payee, so it is stored
+-- as incoming: it is money the owner received, not money they sent.
+CREATE TABLE IF NOT EXISTS payment_links (
+    id           BIGSERIAL PRIMARY KEY,
+    user_id      INTEGER        NOT NULL,
+    code         VARCHAR(16)    NOT NULL,
+    -- Either a fixed amount or bounds for an amount the payer chooses
+    amount       NUMERIC(18, 2),
+    min_amount   NUMERIC(18, 2),
+    max_amount   NUMERIC(18, 2),
+    currency     CHAR(3)        NOT NULL DEFAULT 'INR',
+    to_account   VARCHAR(128)   NOT NULL,
+    description  TEXT,
+    expires_at   TIMESTAMPTZ,
+    -- NULL accepts payments until the link expires or is cancelled
+    max_uses     INTEGER,
+    -- expired is not stored: an active link past expires_at reads as expired
+    status       VARCHAR(16)    NOT NULL DEFAULT 'active',
+    paid_at      TIMESTAMPTZ,
+    cancelled_at TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ    NOT NULL DEFAULT NOW(),
+    updated_at   TIMESTAMPTZ    NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT payment_links_code_key UNIQUE (code),
+    CONSTRAINT payment_links_amount_check CHECK (
+        (amount > 0 AND min_amount IS NULL AND max_amount IS NULL) OR
+        (amount IS NULL AND min_amount > 0 AND max_amount >= min_amount)
+    ),
+    CONSTRAINT payment_links_currency_format CHECK (currency ~ '^[A-Z]{3}$'),
+    CONSTRAINT payment_links_status_check CHECK (status IN ('active', 'paid', 'cancelled')),
+    CONSTRAINT payment_links_max_uses_positive CHECK (max_uses IS NULL OR max_uses > 0)
+);
+
+CREATE INDEX IF NOT EXISTS payment_links_user_id_idx ON payment_links (user_id, id DESC);
+
+DROP TRIGGER IF EXISTS payment_links_touch_updated_at ON payment_links;
+CREATE TRIGGER payment_links_touch_updated_at
+    BEFORE UPDATE ON payment_links
+    FOR EACH ROW EXECUTE FUNCTION payment_touch_updated_at();
+
+-- Every order created through a link, with what the payer told us
+CREATE TABLE IF NOT EXISTS payment_link_payments (
+    id            BIGSERIAL PRIMARY KEY,
+    link_id       BIGINT       NOT NULL REFERENCES payment_links (id) ON DELETE CASCADE,
+    payment_id    BIGINT       NOT NULL REFERENCES payments (id) ON DELETE CASCADE,
+    payer_name    VARCHAR(128),
+    payer_email   VARCHAR(255),
+    payer_contact VARCHAR(32),
+    created_at    TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT payment_link_payments_payment_id_key UNIQUE (payment_id)
+);
+
+CREATE INDEX IF NOT EXISTS payment_link_payments_link_id_idx ON payment_link_payments (link_id, id DESC);
+
+-- outgoing payments were sent by user_id; incoming ones were paid to user_id
+-- through a link, by someone without an account
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS direction VARCHAR(8) NOT NULL DEFAULT 'outgoing';
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS payments_direction_check;
+ALTER TABLE payments ADD CONSTRAINT payments_direction_check CHECK (direction IN ('outgoing', 'incoming'));
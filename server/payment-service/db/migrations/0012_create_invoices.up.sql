@@ -0,0 +1,81 @@
+-- Invoices users raise for their customers. Amounts are in the minor unit
+-- of the invoice currency, e.g. paise for INR and whole yen for JPY, and
+-- computed by the service from the line items; GST is split into CGST and
+-- SGST when the place of supply is the supplier's state, IGST otherwise.
+CREATE TABLE IF NOT EXISTS invoices (
+    id               BIGSERIAL PRIMARY KEY,
+    user_id          INTEGER      NOT NULL,
+    -- Assigned on issue from invoice_counters, so drafts leave no gaps
+    number           INTEGER,
+    status           VARCHAR(16)  NOT NULL DEFAULT 'draft',
+    currency         CHAR(3)      NOT NULL DEFAULT 'INR',
+
+    supplier_name    VARCHAR(128) NOT NULL,
+    supplier_gstin   CHAR(15),
+    supplier_address TEXT,
+    -- Two-digit GST state codes
+    supplier_state   CHAR(2)      NOT NULL,
+    place_of_supply  CHAR(2)      NOT NULL,
+
+    customer_name    VARCHAR(128) NOT NULL,
+    customer_email   VARCHAR(255),
+    customer_gstin   CHAR(15),
+    customer_address TEXT,
+
+    notes            TEXT,
+    subtotal_minor   BIGINT       NOT NULL DEFAULT 0,
+    cgst_minor       BIGINT       NOT NULL DEFAULT 0,
+    sgst_minor       BIGINT       NOT NULL DEFAULT 0,
+    igst_minor       BIGINT       NOT NULL DEFAULT 0,
+    total_minor      BIGINT       NOT NULL DEFAULT 0,
+
+    due_at           TIMESTAMPTZ,
+    issued_at        TIMESTAMPTZ,
+    paid_at          TIMESTAMPTZ,
+    voided_at        TIMESTAMPTZ,
+    -- Set when a payment is linked; the invoice is paid once it is captured
+    payment_id       BIGINT REFERENCES payments (id) ON DELETE SET NULL,
+    created_at       TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    updated_at       TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT invoices_status_check CHECK (status IN ('draft', 'issued', 'paid', 'void')),
+    CONSTRAINT invoices_currency_format CHECK (currency ~ '^[A-Z]{3}$'),
+    CONSTRAINT invoices_number_issued CHECK (status = 'draft' OR status = 'void' OR number IS NOT NULL),
+    CONSTRAINT invoices_user_number_key UNIQUE (user_id, number),
+    CONSTRAINT invoices_payment_id_key UNIQUE (payment_id)
+);
+
+CREATE INDEX IF NOT EXISTS invoices_user_id_idx ON invoices (user_id, id DESC);
+
+DROP TRIGGER IF EXISTS invoices_touch_updated_at ON invoices;
+CREATE TRIGGER invoices_touch_updated_at
+    BEFORE UPDATE ON invoices
+    FOR EACH ROW EXECUTE FUNCTION payment_touch_updated_at();
+
+CREATE TABLE IF NOT EXISTS invoice_items (
+    id               BIGSERIAL PRIMARY KEY,
+    invoice_id       BIGINT         NOT NULL REFERENCES invoices (id) ON DELETE CASCADE,
+    position         INTEGER        NOT NULL,
+    description      VARCHAR(255)   NOT NULL,
+    -- HSN code for goods or SAC code for services
+    hsn_sac          VARCHAR(8),
+    quantity         NUMERIC(12, 3) NOT NULL,
+    unit_price_minor BIGINT         NOT NULL,
+    -- Percent, e.g. 18.00
+    tax_rate         NUMERIC(5, 2)  NOT NULL DEFAULT 0,
+    amount_minor     BIGINT         NOT NULL,
+    cgst_minor       BIGINT         NOT NULL DEFAULT 0,
+    sgst_minor       BIGINT         NOT NULL DEFAULT 0,
+    igst_minor       BIGINT         NOT NULL DEFAULT 0,
+
+    CONSTRAINT invoice_items_quantity_positive CHECK (quantity > 0),
+    CONSTRAINT invoice_items_unit_price_check CHECK (unit_price_minor >= 0),
+    CONSTRAINT invoice_items_tax_rate_check CHECK (tax_rate >= 0 AND tax_rate <= 100),
+    CONSTRAINT invoice_items_position_key UNIQUE (invoice_id, position)
+);
+
+-- Last invoice number issued per user
+CREATE TABLE IF NOT EXISTS invoice_counters (
+    user_id     INTEGER PRIMARY KEY,
+    last_number INTEGER NOT NULL
+);
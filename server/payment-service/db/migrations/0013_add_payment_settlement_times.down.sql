@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS payments_user_refunded_at_idx;
+DROP INDEX IF EXISTS payments_user_captured_at_idx;
+ALTER TABLE payments DROP COLUMN IF EXISTS refunded_at;
+ALTER TABLE payments DROP COLUMN IF EXISTS captured_at;
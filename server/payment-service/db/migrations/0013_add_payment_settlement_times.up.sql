@@ -0,0 +1,19 @@
+-- When money moved, so statements can post entries on the right day.
+-- Existing rows are backfilled from the best timestamps available: a
+-- captured payment's last change was its capture, and a refunded payment's
+-- last change was its refund.
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS captured_at TIMESTAMPTZ;
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS refunded_at TIMESTAMPTZ;
+
+UPDATE payments SET captured_at = COALESCE(updated_at, created_at)
+WHERE status IN ('captured', 'completed') AND captured_at IS NULL;
+
+UPDATE payments SET captured_at = COALESCE(authorized_at, created_at),
+                    refunded_at = COALESCE(updated_at, created_at)
+WHERE status = 'refunded' AND captured_at IS NULL;
+
+-- Statement queries, per user and period
+CREATE INDEX IF NOT EXISTS payments_user_captured_at_idx
+    ON payments (user_id, captured_at) WHERE captured_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS payments_user_refunded_at_idx
+    ON payments (user_id, refunded_at) WHERE refunded_at IS NOT NULL;
@@ -0,0 +1,79 @@
+-- Currencies /pay accepts. exponent is the number of minor-unit digits
+-- Razorpay expects (2 for INR paise, 0 for JPY); payment amounts are stored
+-- with two decimals, so three-digit currencies are not supported.
+CREATE TABLE IF NOT EXISTS currencies (
+    code       CHAR(3)     PRIMARY KEY,
+    name       VARCHAR(64) NOT NULL,
+    exponent   SMALLINT    NOT NULL DEFAULT 2,
+    enabled    BOOLEAN     NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT currencies_code_format CHECK (code ~ '^[A-Z]{3}$'),
+    CONSTRAINT currencies_exponent_check CHECK (exponent BETWEEN 0 AND 2)
+);
+
+DROP TRIGGER IF EXISTS currencies_touch_updated_at ON currencies;
+CREATE TRIGGER currencies_touch_updated_at
+    BEFORE UPDATE ON currencies
+    FOR EACH ROW EXECUTE FUNCTION payment_touch_updated_at();
+
+INSERT INTO currencies (code, name, exponent, enabled) VALUES
+    ('INR', 'Indian Rupee', 2, TRUE),
+    ('USD', 'US Dollar', 2, TRUE),
+    ('EUR', 'Euro', 2, TRUE),
+    ('GBP', 'Pound Sterling', 2, TRUE),
+    ('AED', 'UAE Dirham', 2, TRUE),
+    ('SGD', 'Singapore Dollar', 2, TRUE),
+    ('AUD', 'Australian Dollar', 2, TRUE),
+    ('CAD', 'Canadian Dollar', 2, TRUE),
+    ('JPY', 'Japanese Yen', 0, FALSE)
+ON CONFLICT (code) DO NOTHING;
+
+-- Any currency already used by a payment stays usable
+INSERT INTO currencies (code, name)
+SELECT DISTINCT currency, currency FROM payments
+ON CONFLICT (code) DO NOTHING;
+
+-- Exchange rates: 1 base = rate quote from effective_at until the next rate
+-- for the pair takes effect. Rows are never updated, so past quotes keep
+-- pointing at the rate they used.
+CREATE TABLE IF NOT EXISTS fx_rates (
+    id            BIGSERIAL      PRIMARY KEY,
+    base          CHAR(3)        NOT NULL REFERENCES currencies (code),
+    quote         CHAR(3)        NOT NULL REFERENCES currencies (code),
+    rate          NUMERIC(24, 10) NOT NULL,
+    effective_at  TIMESTAMPTZ    NOT NULL,
+    source        VARCHAR(16)    NOT NULL,
+    created_by    INTEGER,
+    created_at    TIMESTAMPTZ    NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT fx_rates_rate_positive CHECK (rate > 0),
+    CONSTRAINT fx_rates_distinct_pair CHECK (base <> quote),
+    CONSTRAINT fx_rates_source_check CHECK (source IN ('file', 'admin')),
+    CONSTRAINT fx_rates_pair_effective_key UNIQUE (base, quote, effective_at)
+);
+
+-- Quotes lock a rate for a conversion until expires_at. A payment redeems
+-- a quote once: it is charged amount in from_currency and the payee is
+-- credited converted_amount in to_currency.
+CREATE TABLE IF NOT EXISTS fx_quotes (
+    id               BIGSERIAL       PRIMARY KEY,
+    user_id          INTEGER         NOT NULL,
+    rate_id          BIGINT          NOT NULL REFERENCES fx_rates (id),
+    from_currency    CHAR(3)         NOT NULL,
+    to_currency      CHAR(3)         NOT NULL,
+    rate             NUMERIC(24, 10) NOT NULL,
+    amount           NUMERIC(18, 2)  NOT NULL,
+    converted_amount NUMERIC(18, 2)  NOT NULL,
+    expires_at       TIMESTAMPTZ     NOT NULL,
+    payment_id       BIGINT          REFERENCES payments (id),
+    used_at          TIMESTAMPTZ,
+    created_at       TIMESTAMPTZ     NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT fx_quotes_amounts_positive CHECK (amount > 0 AND converted_amount > 0),
+    CONSTRAINT fx_quotes_payment_key UNIQUE (payment_id),
+    CONSTRAINT fx_quotes_used_check CHECK ((payment_id IS NULL) = (used_at IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS fx_quotes_user_created_at_idx ON fx_quotes (user_id, created_at DESC);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS velocity_limits;
+DROP TABLE IF EXISTS currency_limits;
@@ -0,0 +1,34 @@
+-- Transaction limits. user_id 0 holds the defaults every user gets; a row
+-- for a user overrides the default for that currency (or for the hourly
+-- count). A NULL column means no limit.
+CREATE TABLE IF NOT EXISTS currency_limits (
+    user_id         INTEGER        NOT NULL,
+    currency        CHAR(3)        NOT NULL REFERENCES currencies (code),
+    per_transaction NUMERIC(18, 2),
+    daily           NUMERIC(18, 2),
+    monthly         NUMERIC(18, 2),
+    updated_by      INTEGER,
+    updated_at      TIMESTAMPTZ    NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (user_id, currency),
+    CONSTRAINT currency_limits_positive CHECK (
+        (per_transaction IS NULL OR per_transaction > 0) AND
+        (daily IS NULL OR daily > 0) AND
+        (monthly IS NULL OR monthly > 0))
+);
+
+CREATE TABLE IF NOT EXISTS velocity_limits (
+    user_id      INTEGER     PRIMARY KEY,
+    hourly_count INTEGER,
+    updated_by   INTEGER,
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT velocity_limits_positive CHECK (hourly_count IS NULL OR hourly_count > 0)
+);
+
+INSERT INTO currency_limits (user_id, currency, per_transaction, daily, monthly) VALUES
+    (0, 'INR', 100000, 200000, 1000000)
+ON CONFLICT DO NOTHING;
+
+INSERT INTO velocity_limits (user_id, hourly_count) VALUES (0, 30)
+ON CONFLICT DO NOTHING;
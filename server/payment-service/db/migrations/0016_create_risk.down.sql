@@ -0,0 +1,15 @@
+DROP TABLE IF EXISTS risk_decisions;
+DROP TABLE IF EXISTS risk_assessments;
+DROP TABLE IF EXISTS ip_ranges;
+
+DROP INDEX IF EXISTS payments_pending_review_idx;
+
+UPDATE payments SET status = 'failed' WHERE status = 'pending_review';
+UPDATE payments SET razorpay_order_id = 'held_' || id WHERE razorpay_order_id IS NULL;
+
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS payments_order_check;
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS payments_status_check;
+ALTER TABLE payments ADD CONSTRAINT payments_status_check CHECK (
+    status IN ('created', 'pending', 'authorized', 'captured', 'completed', 'failed', 'refunded', 'expired', 'voided')
+);
+ALTER TABLE payments ALTER COLUMN razorpay_order_id SET NOT NULL;
@@ -0,0 +1,61 @@
+-- Risk screening. A payment whose score calls for review is stored as
+-- pending_review without a Razorpay order; the order is created when an
+-- admin approves it, and a rejected payment fails without ever having one.
+ALTER TABLE payments ALTER COLUMN razorpay_order_id DROP NOT NULL;
+
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS payments_status_check;
+ALTER TABLE payments ADD CONSTRAINT payments_status_check CHECK (
+    status IN ('created', 'pending', 'authorized', 'captured', 'completed', 'failed', 'refunded', 'expired', 'voided', 'pending_review')
+);
+
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS payments_order_check;
+ALTER TABLE payments ADD CONSTRAINT payments_order_check CHECK (
+    razorpay_order_id IS NOT NULL OR status IN ('pending_review', 'failed')
+);
+
+CREATE INDEX IF NOT EXISTS payments_pending_review_idx
+    ON payments (created_at) WHERE status = 'pending_review';
+
+-- Country of each network, for the geography rule. Imported as a whole
+-- from a file; the most specific network containing an address wins.
+CREATE TABLE IF NOT EXISTS ip_ranges (
+    network CIDR    PRIMARY KEY,
+    country CHAR(2) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS ip_ranges_network_idx ON ip_ranges USING gist (network inet_ops);
+
+-- Every screening, including blocked attempts that never became a payment.
+CREATE TABLE IF NOT EXISTS risk_assessments (
+    id         BIGSERIAL      PRIMARY KEY,
+    payment_id BIGINT         UNIQUE REFERENCES payments (id),
+    user_id    INTEGER        NOT NULL,
+    amount     NUMERIC(18, 2) NOT NULL,
+    currency   CHAR(3)        NOT NULL,
+    to_account VARCHAR(255)   NOT NULL,
+    ip         INET,
+    country    CHAR(2),
+    score      INTEGER        NOT NULL,
+    outcome    VARCHAR(8)     NOT NULL,
+    -- [{"rule": ..., "score": ..., "detail": ...}] for each rule that fired
+    rules      JSONB          NOT NULL DEFAULT '[]',
+    request_id VARCHAR(128),
+    created_at TIMESTAMPTZ    NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT risk_assessments_outcome_check CHECK (outcome IN ('allow', 'review', 'block'))
+);
+
+CREATE INDEX IF NOT EXISTS risk_assessments_user_id_created_at_idx ON risk_assessments (user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS risk_assessments_created_at_idx ON risk_assessments (created_at DESC);
+
+-- An admin's verdict on a held payment.
+CREATE TABLE IF NOT EXISTS risk_decisions (
+    id            BIGSERIAL   PRIMARY KEY,
+    assessment_id BIGINT      NOT NULL UNIQUE REFERENCES risk_assessments (id),
+    decision      VARCHAR(8)  NOT NULL,
+    reason        TEXT        NOT NULL DEFAULT '',
+    decided_by    INTEGER     NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT risk_decisions_decision_check CHECK (decision IN ('approve', 'reject'))
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS blocklist_entries;
+DROP SEQUENCE IF EXISTS blocklist_revision_seq;
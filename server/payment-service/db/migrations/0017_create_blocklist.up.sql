@@ -0,0 +1,29 @@
+-- Compliance blocklist of payee accounts, VPAs, emails and IP ranges.
+-- Entries are removed by stamping removed_at so the history of who listed
+-- and delisted what is kept. Every change takes the next revision, which
+-- services poll to know when to reload their in-memory copy.
+CREATE SEQUENCE IF NOT EXISTS blocklist_revision_seq;
+
+CREATE TABLE IF NOT EXISTS blocklist_entries (
+    id         BIGSERIAL    PRIMARY KEY,
+    kind       VARCHAR(8)   NOT NULL,
+    -- Normalised: upper-case account, lower-case VPA or email ("@domain"
+    -- for a whole domain), or a masked CIDR network
+    value      VARCHAR(320) NOT NULL,
+    reason     TEXT         NOT NULL,
+    -- NULL never expires
+    expires_at TIMESTAMPTZ,
+    created_by INTEGER      NOT NULL,
+    created_at TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    removed_at TIMESTAMPTZ,
+    removed_by INTEGER,
+    revision   BIGINT       NOT NULL DEFAULT nextval('blocklist_revision_seq'),
+
+    CONSTRAINT blocklist_entries_kind_check CHECK (kind IN ('account', 'vpa', 'email', 'ip'))
+);
+
+-- At most one live entry per value; removed ones are history.
+CREATE UNIQUE INDEX IF NOT EXISTS blocklist_entries_kind_value_idx
+    ON blocklist_entries (kind, value) WHERE removed_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS blocklist_entries_revision_idx ON blocklist_entries (revision);
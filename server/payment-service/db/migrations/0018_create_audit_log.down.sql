@@ -0,0 +1,3 @@
+-- The audit log is shared with auth-service and must outlive schema
+-- rollbacks, so it is deliberately left in place.
+SELECT 1;
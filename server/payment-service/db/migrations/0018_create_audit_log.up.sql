@@ -0,0 +1,70 @@
+-- Append-only audit log shared with auth-service, which writes to it but
+-- leaves its schema to these migrations.
+
+-- Each entry's hash covers its content and the previous entry's hash, so
+-- editing, removing or reordering entries breaks the chain from there on.
+-- Entries are written unchained by the transaction that makes the change,
+-- then linked by payment-service's chainer in short transactions of its
+-- own, so no business transaction holds the audit lock. seq is an entry's
+-- place in the chain; it follows the order entries were chained in, which
+-- need not be id order.
+CREATE TABLE IF NOT EXISTS audit_log (
+    id            BIGSERIAL    PRIMARY KEY,
+    created_at    TIMESTAMPTZ  NOT NULL,
+    service       VARCHAR(16)  NOT NULL,
+    action        VARCHAR(64)  NOT NULL,
+    outcome       VARCHAR(8)   NOT NULL,
+    -- user, admin, service, razorpay, anonymous or system
+    actor_type    VARCHAR(16)  NOT NULL,
+    actor_id      INTEGER,
+    actor_email   VARCHAR(320) NOT NULL DEFAULT '',
+    -- Kept as the text that was hashed rather than INET
+    ip            VARCHAR(45)  NOT NULL DEFAULT '',
+    user_agent    TEXT         NOT NULL DEFAULT '',
+    request_id    VARCHAR(128) NOT NULL DEFAULT '',
+    resource_type VARCHAR(32)  NOT NULL DEFAULT '',
+    resource_id   VARCHAR(64)  NOT NULL DEFAULT '',
+    before        JSONB,
+    after         JSONB,
+    detail        TEXT         NOT NULL DEFAULT '',
+    seq           BIGINT       UNIQUE,
+    prev_hash     CHAR(64),
+    hash          CHAR(64)     UNIQUE,
+
+    CONSTRAINT audit_log_outcome_check CHECK (outcome IN ('success', 'failure')),
+    CONSTRAINT audit_log_chained_check CHECK (
+        (seq IS NULL AND prev_hash IS NULL AND hash IS NULL)
+        OR (seq IS NOT NULL AND prev_hash IS NOT NULL AND hash IS NOT NULL)
+    )
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_created_at_idx ON audit_log (created_at DESC);
+CREATE INDEX IF NOT EXISTS audit_log_actor_id_idx ON audit_log (actor_id, id DESC);
+CREATE INDEX IF NOT EXISTS audit_log_resource_idx ON audit_log (resource_type, resource_id, id DESC);
+CREATE INDEX IF NOT EXISTS audit_log_action_idx ON audit_log (action, id DESC);
+CREATE INDEX IF NOT EXISTS audit_log_request_id_idx ON audit_log (request_id) WHERE request_id <> '';
+CREATE INDEX IF NOT EXISTS audit_log_unchained_idx ON audit_log (id) WHERE seq IS NULL;
+
+-- Entries can only be added, and chained once; chaining may fill seq,
+-- prev_hash and hash but change nothing else.
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'UPDATE' THEN
+        IF OLD.seq IS NULL AND NEW.seq IS NOT NULL
+            AND to_jsonb(OLD) - 'seq' - 'prev_hash' - 'hash' = to_jsonb(NEW) - 'seq' - 'prev_hash' - 'hash' THEN
+            RETURN NEW;
+        END IF;
+    END IF;
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_log_no_change ON audit_log;
+CREATE TRIGGER audit_log_no_change
+    BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+
+DROP TRIGGER IF EXISTS audit_log_no_truncate ON audit_log;
+CREATE TRIGGER audit_log_no_truncate
+    BEFORE TRUNCATE ON audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
@@ -0,0 +1,14 @@
+DROP TABLE IF EXISTS dispute_evidence;
+DROP TABLE IF EXISTS disputes;
+
+DROP INDEX IF EXISTS payments_user_charged_back_at_idx;
+
+UPDATE payments SET status = 'captured' WHERE status = 'charged_back';
+
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS payments_status_check;
+ALTER TABLE payments ADD CONSTRAINT payments_status_check CHECK (
+    status IN ('created', 'pending', 'authorized', 'captured', 'completed', 'failed', 'refunded', 'expired', 'voided', 'pending_review')
+);
+
+ALTER TABLE payments DROP COLUMN IF EXISTS charged_back_amount;
+ALTER TABLE payments DROP COLUMN IF EXISTS charged_back_at;
@@ -0,0 +1,70 @@
+-- Cardholder disputes reported by Razorpay. A lost dispute charges the
+-- payment back: it moves to charged_back and the disputed amount is
+-- returned to the payer, which statements post as a credit.
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS charged_back_at TIMESTAMPTZ;
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS charged_back_amount NUMERIC(18, 2);
+
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS payments_status_check;
+ALTER TABLE payments ADD CONSTRAINT payments_status_check CHECK (
+    status IN ('created', 'pending', 'authorized', 'captured', 'completed', 'failed', 'refunded', 'expired', 'voided', 'pending_review', 'charged_back')
+);
+
+CREATE INDEX IF NOT EXISTS payments_user_charged_back_at_idx
+    ON payments (user_id, charged_back_at) WHERE charged_back_at IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS disputes (
+    id                  BIGSERIAL      PRIMARY KEY,
+    razorpay_dispute_id VARCHAR(64)    NOT NULL,
+    payment_id          BIGINT         NOT NULL REFERENCES payments (id),
+    -- The payer, copied from the payment for listing
+    user_id             INTEGER        NOT NULL,
+    amount              NUMERIC(18, 2) NOT NULL,
+    currency            CHAR(3)        NOT NULL,
+    amount_deducted     NUMERIC(18, 2) NOT NULL DEFAULT 0,
+    reason_code         VARCHAR(64)    NOT NULL DEFAULT '',
+    reason_description  TEXT           NOT NULL DEFAULT '',
+    -- Razorpay's escalation stage: retrieval, chargeback, pre_arbitration,
+    -- arbitration or fraud
+    gateway_phase       VARCHAR(32)    NOT NULL DEFAULT '',
+    phase               VARCHAR(16)    NOT NULL,
+    -- Evidence is due by then
+    respond_by          TIMESTAMPTZ,
+    resolved_at         TIMESTAMPTZ,
+    -- created_at of the last Razorpay event applied, so older ones are skipped
+    gateway_updated_at  TIMESTAMPTZ    NOT NULL,
+    created_at          TIMESTAMPTZ    NOT NULL DEFAULT NOW(),
+    updated_at          TIMESTAMPTZ    NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT disputes_razorpay_dispute_id_key UNIQUE (razorpay_dispute_id),
+    CONSTRAINT disputes_phase_check CHECK (phase IN ('created', 'under_review', 'won', 'lost', 'closed'))
+);
+
+CREATE INDEX IF NOT EXISTS disputes_user_id_idx ON disputes (user_id, id DESC);
+CREATE INDEX IF NOT EXISTS disputes_payment_id_idx ON disputes (payment_id);
+CREATE INDEX IF NOT EXISTS disputes_open_respond_by_idx
+    ON disputes (respond_by) WHERE phase IN ('created', 'under_review');
+
+-- Evidence the payer submits against a dispute: a note, or a file kept in
+-- the database with its checksum.
+CREATE TABLE IF NOT EXISTS dispute_evidence (
+    id           BIGSERIAL    PRIMARY KEY,
+    dispute_id   BIGINT       NOT NULL REFERENCES disputes (id),
+    kind         VARCHAR(8)   NOT NULL,
+    category     VARCHAR(32)  NOT NULL,
+    note         TEXT         NOT NULL DEFAULT '',
+    filename     VARCHAR(255),
+    content_type VARCHAR(100),
+    size_bytes   INTEGER,
+    sha256       CHAR(64),
+    content      BYTEA,
+    uploaded_by  INTEGER      NOT NULL,
+    created_at   TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT dispute_evidence_kind_check CHECK (kind IN ('text', 'file')),
+    CONSTRAINT dispute_evidence_content_check CHECK (
+        (kind = 'text' AND content IS NULL AND note <> '') OR
+        (kind = 'file' AND content IS NOT NULL AND filename IS NOT NULL)
+    )
+);
+
+CREATE INDEX IF NOT EXISTS dispute_evidence_dispute_id_idx ON dispute_evidence (dispute_id, id);
@@ -0,0 +1,343 @@
+// Package disputes tracks cardholder disputes of payments and the evidence
+// users submit to contest them. Razorpay decides a dispute; local state
+// follows its payment.dispute.* webhooks, and a lost dispute charges the
+// payment back.
+package disputes
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/audit"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/currencies"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/events"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/payments"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/razorpay"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/tracing"
+)
+
+var (
+	// ErrNotFound is returned when a dispute does not exist, belongs to
+	// another user, or disputes a payment not created by this service.
+	ErrNotFound = errors.New("dispute not found")
+	// ErrUnknownStatus is returned for a webhook whose dispute status is
+	// not one this service tracks.
+	ErrUnknownStatus = errors.New("unknown dispute status")
+)
+
+// Phase is where a dispute stands.
+type Phase string
+
+const (
+	PhaseCreated     Phase = "created"      // evidence is being collected
+	PhaseUnderReview Phase = "under_review" // evidence submitted, the bank is deciding
+	PhaseWon         Phase = "won"
+	PhaseLost        Phase = "lost"
+	PhaseClosed      Phase = "closed" // accepted or withdrawn without a ruling
+)
+
+// Final reports whether p is a resolution.
+func (p Phase) Final() bool {
+	return p == PhaseWon || p == PhaseLost || p == PhaseClosed
+}
+
+// transitions lists the phases each phase may move to.
+var transitions = map[Phase][]Phase{
+	PhaseCreated:     {PhaseUnderReview, PhaseWon, PhaseLost, PhaseClosed},
+	PhaseUnderReview: {PhaseWon, PhaseLost, PhaseClosed},
+}
+
+// phaseOf maps a Razorpay dispute status to its phase.
+func phaseOf(status string) (Phase, bool) {
+	switch status {
+	case "open":
+		return PhaseCreated, true
+	case "under_review":
+		return PhaseUnderReview, true
+	case "won":
+		return PhaseWon, true
+	case "lost":
+		return PhaseLost, true
+	case "closed":
+		return PhaseClosed, true
+	}
+	return "", false
+}
+
+// Dispute is a cardholder's dispute of a payment.
+type Dispute struct {
+	ID                int64
+	RazorpayDisputeID string
+	PaymentID         int
+	UserID            int
+	Amount            float64
+	Currency          string
+	// AmountDeducted is what Razorpay held back from settlements while the
+	// dispute is open.
+	AmountDeducted    float64
+	ReasonCode        string
+	ReasonDescription string
+	// GatewayPhase is Razorpay's escalation stage, e.g. chargeback or
+	// arbitration.
+	GatewayPhase string
+	Phase        Phase
+	// RespondBy is when evidence is due; nil when Razorpay set no deadline.
+	RespondBy  *time.Time
+	ResolvedAt *time.Time
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// AcceptsEvidence reports whether evidence can still be added at now: the
+// dispute is open and its deadline has not passed.
+func (d Dispute) AcceptsEvidence(now time.Time) bool {
+	return !d.Phase.Final() && (d.RespondBy == nil || now.Before(*d.RespondBy))
+}
+
+// queryer is satisfied by *sql.DB and *sql.Tx.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+const columns = `id, razorpay_dispute_id, payment_id, user_id, amount, currency, amount_deducted,
+	reason_code, reason_description, gateway_phase, phase, respond_by, resolved_at, created_at, updated_at`
+
+func scan(row interface{ Scan(...any) error }) (Dispute, error) {
+	var d Dispute
+	err := row.Scan(&d.ID, &d.RazorpayDisputeID, &d.PaymentID, &d.UserID, &d.Amount, &d.Currency,
+		&d.AmountDeducted, &d.ReasonCode, &d.ReasonDescription, &d.GatewayPhase, &d.Phase,
+		&d.RespondBy, &d.ResolvedAt, &d.CreatedAt, &d.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Dispute{}, ErrNotFound
+	}
+	return d, err
+}
+
+// Get returns dispute id. A userID of 0 matches any user's dispute.
+func Get(ctx context.Context, q queryer, userID int, id int64) (Dispute, error) {
+	query := "SELECT " + columns + " FROM disputes WHERE id = $1 AND ($2 = 0 OR user_id = $2)"
+
+	spanCtx, span := tracing.StartDBSpan(ctx, "SELECT", "disputes", query)
+	d, err := scan(q.QueryRowContext(spanCtx, query, id, userID))
+	tracing.End(span, err)
+	return d, err
+}
+
+// lock returns dispute id locked until tx ends. A userID of 0 matches any
+// user's dispute.
+func lock(ctx context.Context, tx *sql.Tx, userID int, id int64) (Dispute, error) {
+	query := "SELECT " + columns + " FROM disputes WHERE id = $1 AND ($2 = 0 OR user_id = $2) FOR UPDATE"
+
+	spanCtx, span := tracing.StartDBSpan(ctx, "SELECT", "disputes", query)
+	d, err := scan(tx.QueryRowContext(spanCtx, query, id, userID))
+	tracing.End(span, err)
+	return d, err
+}
+
+// Filter narrows List. Zero fields do not filter.
+type Filter struct {
+	UserID    int
+	PaymentID int
+	Phase     Phase
+	// DueBefore lists only open disputes whose evidence is due before it,
+	// soonest first; otherwise disputes are listed newest first.
+	DueBefore time.Time
+	Limit     int // default 50, at most 100
+}
+
+// List returns the disputes matching f.
+func List(ctx context.Context, q queryer, f Filter) ([]Dispute, error) {
+	if f.Limit <= 0 || f.Limit > 100 {
+		f.Limit = 50
+	}
+	order := "id DESC"
+	var due sql.NullTime
+	if !f.DueBefore.IsZero() {
+		due = sql.NullTime{Time: f.DueBefore, Valid: true}
+		order = "respond_by, id"
+	}
+
+	query := "SELECT " + columns + ` FROM disputes
+		WHERE ($1 = 0 OR user_id = $1)
+		  AND ($2 = 0 OR payment_id = $2)
+		  AND ($3 = '' OR phase = $3)
+		  AND ($4::TIMESTAMPTZ IS NULL OR (phase IN ('created', 'under_review') AND respond_by < $4))
+		ORDER BY ` + order + `
+		LIMIT $5`
+
+	spanCtx, span := tracing.StartDBSpan(ctx, "SELECT", "disputes", query)
+	rows, err := q.QueryContext(spanCtx, query, f.UserID, f.PaymentID, string(f.Phase), due, f.Limit)
+	tracing.End(span, err)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var disputes []Dispute
+	for rows.Next() {
+		d, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		disputes = append(disputes, d)
+	}
+	return disputes, rows.Err()
+}
+
+// ApplyWebhook records the dispute named in a payment.dispute.* event, or
+// moves it to the event's phase, in tx. A newly lost dispute charges its
+// payment back by the disputed amount, unless the payment has already been
+// refunded or charged back. It returns ErrNotFound for disputes of payments
+// not created by this service. changed is false when the event was older
+// than the last one applied, repeated the dispute's phase, or would reopen
+// a resolved dispute.
+func ApplyWebhook(ctx context.Context, tx *sql.Tx, event razorpay.WebhookEvent) (d Dispute, changed bool, err error) {
+	if event.Payload.Dispute == nil {
+		return Dispute{}, false, ErrNotFound
+	}
+	entity := event.Payload.Dispute.Entity
+	phase, ok := phaseOf(entity.Status)
+	if !ok {
+		return Dispute{}, false, fmt.Errorf("%w: %q", ErrUnknownStatus, entity.Status)
+	}
+	eventAt := time.Unix(event.CreatedAt, 0)
+
+	// The payment is locked first, as every payment update does
+	payment, err := payments.LockByRazorpayPaymentID(ctx, tx, entity.PaymentID)
+	if errors.Is(err, payments.ErrNotFound) {
+		return Dispute{}, false, ErrNotFound
+	}
+	if err != nil {
+		return Dispute{}, false, err
+	}
+	registry, err := currencies.Load(ctx, tx)
+	if err != nil {
+		return Dispute{}, false, fmt.Errorf("load currencies: %w", err)
+	}
+	currency := registry.Lookup(entity.Currency)
+	amount := currency.FromMinor(entity.Amount)
+	deducted := currency.FromMinor(entity.AmountDeducted)
+	var respondBy *time.Time
+	if entity.RespondBy != 0 {
+		t := time.Unix(entity.RespondBy, 0).UTC()
+		respondBy = &t
+	}
+	var resolvedAt *time.Time
+	if phase.Final() {
+		resolvedAt = &eventAt
+	}
+
+	var lastApplied time.Time
+	query := "SELECT " + columns + ", gateway_updated_at FROM disputes WHERE razorpay_dispute_id = $1 FOR UPDATE"
+	spanCtx, span := tracing.StartDBSpan(ctx, "SELECT", "disputes", query)
+	err = tx.QueryRowContext(spanCtx, query, entity.ID).Scan(&d.ID, &d.RazorpayDisputeID, &d.PaymentID,
+		&d.UserID, &d.Amount, &d.Currency, &d.AmountDeducted, &d.ReasonCode, &d.ReasonDescription,
+		&d.GatewayPhase, &d.Phase, &d.RespondBy, &d.ResolvedAt, &d.CreatedAt, &d.UpdatedAt, &lastApplied)
+	tracing.End(span, err)
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		const insert = `INSERT INTO disputes
+				(razorpay_dispute_id, payment_id, user_id, amount, currency, amount_deducted, reason_code,
+				 reason_description, gateway_phase, phase, respond_by, resolved_at, gateway_updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+			RETURNING ` + columns
+		spanCtx, span := tracing.StartDBSpan(ctx, "INSERT", "disputes", insert)
+		d, err = scan(tx.QueryRowContext(spanCtx, insert,
+			entity.ID, payment.ID, payment.UserID, amount, currency.Code, deducted, entity.ReasonCode,
+			entity.ReasonDescription, entity.Phase, string(phase), respondBy, resolvedAt, eventAt))
+		tracing.End(span, err)
+		if err != nil {
+			return Dispute{}, false, fmt.Errorf("insert dispute %s: %w", entity.ID, err)
+		}
+		if _, err := events.RecordPayment(ctx, tx, events.PaymentDisputed, payment, payment.Status); err != nil {
+			return Dispute{}, false, err
+		}
+		_, err = audit.Append(ctx, tx, audit.Event{
+			Action:       "dispute.created",
+			ResourceType: "dispute",
+			ResourceID:   audit.ID(d.ID),
+			After:        auditState(d),
+			Detail:       fmt.Sprintf("payment %d", payment.ID),
+		})
+		if err != nil {
+			return Dispute{}, false, err
+		}
+
+	case err != nil:
+		return Dispute{}, false, fmt.Errorf("load dispute %s: %w", entity.ID, err)
+
+	default:
+		// Razorpay does not guarantee delivery order; an older event must
+		// not overwrite newer state
+		if eventAt.Before(lastApplied) || d.Phase.Final() {
+			return d, false, nil
+		}
+		if phase != d.Phase && !slices.Contains(transitions[d.Phase], phase) {
+			return d, false, nil
+		}
+		before := d
+		const update = `UPDATE disputes SET
+				amount = $2,
+				amount_deducted = $3,
+				reason_code = $4,
+				reason_description = $5,
+				gateway_phase = $6,
+				phase = $7,
+				respond_by = $8,
+				resolved_at = $9,
+				gateway_updated_at = $10,
+				updated_at = NOW()
+			WHERE id = $1
+			RETURNING ` + columns
+		spanCtx, span := tracing.StartDBSpan(ctx, "UPDATE", "disputes", update)
+		d, err = scan(tx.QueryRowContext(spanCtx, update,
+			d.ID, amount, deducted, entity.ReasonCode, entity.ReasonDescription, entity.Phase,
+			string(phase), respondBy, resolvedAt, eventAt))
+		tracing.End(span, err)
+		if err != nil {
+			return Dispute{}, false, fmt.Errorf("update dispute %d: %w", before.ID, err)
+		}
+		if phase == before.Phase {
+			// A repeat, or a new deadline; nothing to report
+			return d, false, nil
+		}
+		_, err = audit.Append(ctx, tx, audit.Event{
+			Action:       "dispute.phase_changed",
+			ResourceType: "dispute",
+			ResourceID:   audit.ID(d.ID),
+			Before:       auditState(before),
+			After:        auditState(d),
+		})
+		if err != nil {
+			return Dispute{}, false, err
+		}
+	}
+
+	if d.Phase == PhaseLost {
+		_, err := payments.ChargeBack(ctx, tx, &payment, d.Amount)
+		var transitionErr *payments.TransitionError
+		if err != nil && !errors.As(err, &transitionErr) {
+			return Dispute{}, false, fmt.Errorf("charge back payment %d: %w", payment.ID, err)
+		}
+	}
+	return d, true, nil
+}
+
+// auditState is what the audit log records of d.
+func auditState(d Dispute) map[string]any {
+	return map[string]any{
+		"phase":           d.Phase,
+		"gateway_phase":   d.GatewayPhase,
+		"amount":          d.Amount,
+		"amount_deducted": d.AmountDeducted,
+		"currency":        d.Currency,
+		"respond_by":      d.RespondBy,
+	}
+}
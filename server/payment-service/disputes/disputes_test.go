@@ -0,0 +1,204 @@
+package disputes
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/internal/dbtest"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/models"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/payments"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/razorpay"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/statements"
+)
+
+func TestAcceptsEvidence(t *testing.T) {
+	now := time.Now()
+	later, earlier := now.Add(time.Hour), now.Add(-time.Hour)
+	tests := []struct {
+		name string
+		d    Dispute
+		want bool
+	}{
+		{"open without a deadline", Dispute{Phase: PhaseCreated}, true},
+		{"under review before the deadline", Dispute{Phase: PhaseUnderReview, RespondBy: &later}, true},
+		{"past the deadline", Dispute{Phase: PhaseCreated, RespondBy: &earlier}, false},
+		{"lost", Dispute{Phase: PhaseLost, RespondBy: &later}, false},
+		{"closed", Dispute{Phase: PhaseClosed}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.d.AcceptsEvidence(now); got != tt.want {
+			t.Errorf("%s: AcceptsEvidence = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
+
+// insertCaptured stores a payment of 500 INR captured an hour ago.
+func insertCaptured(t *testing.T, database *sql.DB, razorpayPaymentID, status string) int {
+	t.Helper()
+	var id int
+	err := database.QueryRowContext(context.Background(), `INSERT INTO payments
+			(user_id, amount, currency, from_account, to_account, razorpay_order_id, razorpay_payment_id,
+			 status, created_at, captured_at, captured_amount)
+		VALUES (4, 500, 'INR', 'ACC-FROM-1234', 'ACC-TO-5678', $1, $2, $3, NOW() - INTERVAL '2 hours', NOW() - INTERVAL '1 hour', 500)
+		RETURNING id`, "order_"+razorpayPaymentID, razorpayPaymentID, status).Scan(&id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+// apply applies a dispute webhook for razorpayPaymentID in its own
+// transaction, as the webhook handler does.
+func apply(t *testing.T, database *sql.DB, razorpayPaymentID, status string, at time.Time) (Dispute, bool) {
+	t.Helper()
+	var event razorpay.WebhookEvent
+	event.Event = "payment.dispute." + status
+	event.CreatedAt = at.Unix()
+	event.Payload.Dispute = &struct {
+		Entity razorpay.DisputeEntity `json:"entity"`
+	}{razorpay.DisputeEntity{
+		ID:             "disp_" + razorpayPaymentID,
+		PaymentID:      razorpayPaymentID,
+		Amount:         20000, // 200 of the 500 rupees
+		Currency:       "INR",
+		AmountDeducted: 20000,
+		ReasonCode:     "goods_not_received",
+		Status:         status,
+		Phase:          "chargeback",
+	}}
+
+	ctx := context.Background()
+	tx, err := database.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	d, changed, err := ApplyWebhook(ctx, tx, event)
+	if err != nil {
+		t.Fatalf("apply %s: %v", status, err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	return d, changed
+}
+
+// ledger records the entries of an exported statement.
+type ledger struct {
+	entries []statements.Entry
+	closing int64
+}
+
+func (l *ledger) Begin(statements.Section) error { return nil }
+func (l *ledger) Entry(e statements.Entry) error {
+	l.entries = append(l.entries, e)
+	return nil
+}
+func (l *ledger) End(s statements.Section) error {
+	l.closing = s.ClosingMinor
+	return nil
+}
+func (l *ledger) Close() error { return nil }
+
+func TestLostDisputeChargesBack(t *testing.T) {
+	database := dbtest.Open(t)
+	ctx := context.Background()
+	id := insertCaptured(t, database, "pay_disputed", "captured")
+	opened := time.Now().Add(-30 * time.Minute)
+
+	d, changed := apply(t, database, "pay_disputed", "open", opened)
+	if !changed || d.Phase != PhaseCreated || d.Amount != 200 || d.PaymentID != id || d.UserID != 4 {
+		t.Fatalf("opened dispute %+v (changed %t)", d, changed)
+	}
+	apply(t, database, "pay_disputed", "under_review", opened.Add(time.Minute))
+	d, changed = apply(t, database, "pay_disputed", "lost", opened.Add(2*time.Minute))
+	if !changed || d.Phase != PhaseLost || d.ResolvedAt == nil {
+		t.Fatalf("lost dispute %+v (changed %t)", d, changed)
+	}
+
+	p, err := payments.Get(ctx, database, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Status != string(models.PaymentStatusChargedBack) || p.ChargedBackAt == nil ||
+		p.ChargedBackAmount == nil || *p.ChargedBackAmount != 200 {
+		t.Fatalf("payment is %s, charged back %v at %v; want charged_back 200", p.Status, p.ChargedBackAmount, p.ChargedBackAt)
+	}
+
+	// Late and repeated events change nothing once the dispute is lost
+	if d, changed := apply(t, database, "pay_disputed", "under_review", opened.Add(time.Minute)); changed || d.Phase != PhaseLost {
+		t.Errorf("late under_review event: %s (changed %t)", d.Phase, changed)
+	}
+	if d, changed := apply(t, database, "pay_disputed", "won", opened.Add(3*time.Minute)); changed || d.Phase != PhaseLost {
+		t.Errorf("won after lost: %s (changed %t)", d.Phase, changed)
+	}
+	apply(t, database, "pay_disputed", "lost", opened.Add(2*time.Minute))
+
+	// The statement debits the capture and credits the disputed amount once
+	st, err := statements.Query(ctx, database, 4, statements.Period{From: time.Now().Add(-24 * time.Hour), To: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	var l ledger
+	if err := st.Export(&l); err != nil {
+		t.Fatal(err)
+	}
+	if len(l.entries) != 2 {
+		t.Fatalf("statement has %d entries, want the payment and its chargeback", len(l.entries))
+	}
+	payment, chargeback := l.entries[0], l.entries[1]
+	if payment.Kind != statements.KindPayment || payment.AmountMinor != -50000 {
+		t.Errorf("first entry %s %d, want payment -50000", payment.Kind, payment.AmountMinor)
+	}
+	if chargeback.Kind != statements.KindChargeback || chargeback.AmountMinor != 20000 || chargeback.PaymentID != id {
+		t.Errorf("second entry %s %d for payment %d, want chargeback 20000 for %d", chargeback.Kind, chargeback.AmountMinor, chargeback.PaymentID, id)
+	}
+	if chargeback.BalanceMinor != -30000 || l.closing != -30000 {
+		t.Errorf("balance %d, closing %d; want -30000", chargeback.BalanceMinor, l.closing)
+	}
+}
+
+func TestLostDisputeOfRefundedPayment(t *testing.T) {
+	database := dbtest.Open(t)
+	ctx := context.Background()
+	id := insertCaptured(t, database, "pay_refunded", "refunded")
+
+	// The payer already has their money back; the dispute is still recorded
+	d, changed := apply(t, database, "pay_refunded", "lost", time.Now())
+	if !changed || d.Phase != PhaseLost {
+		t.Fatalf("lost dispute %+v (changed %t)", d, changed)
+	}
+	p, err := payments.Get(ctx, database, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Status != string(models.PaymentStatusRefunded) || p.ChargedBackAmount != nil {
+		t.Errorf("refunded payment is %s, charged back %v", p.Status, p.ChargedBackAmount)
+	}
+}
+
+func TestDisputeOfUnknownPayment(t *testing.T) {
+	database := dbtest.Open(t)
+	ctx := context.Background()
+	tx, err := database.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	var event razorpay.WebhookEvent
+	event.Payload.Dispute = &struct {
+		Entity razorpay.DisputeEntity `json:"entity"`
+	}{razorpay.DisputeEntity{ID: "disp_1", PaymentID: "pay_elsewhere", Status: "open"}}
+	if _, _, err := ApplyWebhook(ctx, tx, event); !errors.Is(err, ErrNotFound) {
+		t.Errorf("dispute of another integration's payment: err = %v, want ErrNotFound", err)
+	}
+	event.Payload.Dispute.Entity.Status = "escalated"
+	if _, _, err := ApplyWebhook(ctx, tx, event); !errors.Is(err, ErrUnknownStatus) {
+		t.Errorf("unknown status: err = %v, want ErrUnknownStatus", err)
+	}
+}
@@ -0,0 +1,242 @@
+package disputes
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/audit"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/tracing"
+)
+
+var (
+	// ErrEvidenceNotFound is returned when a dispute has no such evidence,
+	// or the evidence is a note with no file.
+	ErrEvidenceNotFound = errors.New("evidence not found")
+	// ErrClosed is returned when evidence is added to a resolved dispute.
+	ErrClosed = errors.New("dispute is resolved")
+	// ErrPastDeadline is returned when evidence is added after the dispute's
+	// respond-by time.
+	ErrPastDeadline = errors.New("evidence deadline has passed")
+	// ErrTooMuchEvidence is returned when a dispute already has
+	// MaxEvidence items.
+	ErrTooMuchEvidence = errors.New("too much evidence")
+)
+
+// MaxEvidence is the most evidence items a dispute can hold.
+const MaxEvidence = 20
+
+// EvidenceKind is whether evidence is a note or a file.
+type EvidenceKind string
+
+const (
+	KindText EvidenceKind = "text"
+	KindFile EvidenceKind = "file"
+)
+
+// Categories are the kinds of document Razorpay accepts as evidence.
+var Categories = []string{
+	"explanation_letter",
+	"proof_of_delivery",
+	"proof_of_service",
+	"customer_communication",
+	"billing_proof",
+	"refund_confirmation",
+	"cancellation_proof",
+	"others",
+}
+
+// Evidence is a note or file submitted against a dispute. A file's content
+// is read separately with EvidenceFile.
+type Evidence struct {
+	ID          int64
+	DisputeID   int64
+	Kind        EvidenceKind
+	Category    string
+	Note        string
+	Filename    string
+	ContentType string
+	Size        int
+	SHA256      string
+	UploadedBy  int
+	CreatedAt   time.Time
+}
+
+// ValidateEvidence trims and checks e's fields for a note, or a file when
+// content is non-nil, returning a description of the first problem found.
+func ValidateEvidence(e *Evidence, content []byte) error {
+	e.Category = strings.TrimSpace(strings.ToLower(e.Category))
+	if e.Category == "" {
+		e.Category = "others"
+	}
+	if !slices.Contains(Categories, e.Category) {
+		return fmt.Errorf("category must be one of %s", strings.Join(Categories, ", "))
+	}
+	e.Note = strings.TrimSpace(e.Note)
+	if len(e.Note) > 4000 {
+		return errors.New("note must be at most 4000 characters")
+	}
+	if content == nil {
+		e.Kind = KindText
+		if e.Note == "" {
+			return errors.New("note is required when no file is uploaded")
+		}
+		return nil
+	}
+
+	e.Kind = KindFile
+	if len(content) == 0 {
+		return errors.New("file is empty")
+	}
+	e.Filename = strings.Map(func(r rune) rune {
+		// Kept out of Content-Disposition headers and paths
+		if r < 0x20 || r == 0x7f || r == '"' || r == '/' || r == '\\' {
+			return -1
+		}
+		return r
+	}, strings.ToValidUTF8(e.Filename, ""))
+	e.Filename = strings.TrimSpace(e.Filename)
+	if e.Filename == "" || len(e.Filename) > 255 {
+		return errors.New("file name is required and must be at most 255 characters")
+	}
+	if e.ContentType == "" || len(e.ContentType) > 100 {
+		e.ContentType = "application/octet-stream"
+	}
+	return nil
+}
+
+const evidenceColumns = `id, dispute_id, kind, category, note, COALESCE(filename, ''),
+	COALESCE(content_type, ''), COALESCE(size_bytes, 0), COALESCE(sha256, ''), uploaded_by, created_at`
+
+func scanEvidence(row interface{ Scan(...any) error }) (Evidence, error) {
+	var e Evidence
+	err := row.Scan(&e.ID, &e.DisputeID, &e.Kind, &e.Category, &e.Note, &e.Filename,
+		&e.ContentType, &e.Size, &e.SHA256, &e.UploadedBy, &e.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Evidence{}, ErrEvidenceNotFound
+	}
+	return e, err
+}
+
+// AddEvidence stores e, which must have passed ValidateEvidence with
+// content, against dispute id in tx. A userID of 0 adds to any user's
+// dispute. It returns ErrClosed or ErrPastDeadline once the dispute no
+// longer takes evidence, and ErrTooMuchEvidence when it is full.
+func AddEvidence(ctx context.Context, tx *sql.Tx, userID int, id int64, e Evidence, content []byte) (Evidence, error) {
+	d, err := lock(ctx, tx, userID, id)
+	if err != nil {
+		return Evidence{}, err
+	}
+	if d.Phase.Final() {
+		return Evidence{}, ErrClosed
+	}
+	if !d.AcceptsEvidence(time.Now()) {
+		return Evidence{}, ErrPastDeadline
+	}
+
+	var count int
+	const counted = "SELECT COUNT(*) FROM dispute_evidence WHERE dispute_id = $1"
+	spanCtx, span := tracing.StartDBSpan(ctx, "SELECT", "dispute_evidence", counted)
+	err = tx.QueryRowContext(spanCtx, counted, d.ID).Scan(&count)
+	tracing.End(span, err)
+	if err != nil {
+		return Evidence{}, err
+	}
+	if count >= MaxEvidence {
+		return Evidence{}, ErrTooMuchEvidence
+	}
+
+	var filename, contentType, checksum sql.NullString
+	var size sql.NullInt64
+	// A nil []byte would be stored as an empty value, not NULL
+	var data any
+	if e.Kind == KindFile {
+		sum := sha256.Sum256(content)
+		filename = sql.NullString{String: e.Filename, Valid: true}
+		contentType = sql.NullString{String: e.ContentType, Valid: true}
+		checksum = sql.NullString{String: hex.EncodeToString(sum[:]), Valid: true}
+		size = sql.NullInt64{Int64: int64(len(content)), Valid: true}
+		data = content
+	}
+
+	const insert = `INSERT INTO dispute_evidence
+			(dispute_id, kind, category, note, filename, content_type, size_bytes, sha256, content, uploaded_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING ` + evidenceColumns
+	spanCtx, span = tracing.StartDBSpan(ctx, "INSERT", "dispute_evidence", insert)
+	e, err = scanEvidence(tx.QueryRowContext(spanCtx, insert,
+		d.ID, string(e.Kind), e.Category, e.Note, filename, contentType, size, checksum, data, e.UploadedBy))
+	tracing.End(span, err)
+	if err != nil {
+		return Evidence{}, fmt.Errorf("insert evidence for dispute %d: %w", d.ID, err)
+	}
+
+	_, err = audit.Append(ctx, tx, audit.Event{
+		Action:       "dispute.evidence_added",
+		ResourceType: "dispute",
+		ResourceID:   audit.ID(d.ID),
+		After: map[string]any{
+			"evidence_id": e.ID,
+			"kind":        e.Kind,
+			"category":    e.Category,
+			"filename":    e.Filename,
+			"size_bytes":  e.Size,
+			"sha256":      e.SHA256,
+		},
+	})
+	if err != nil {
+		return Evidence{}, err
+	}
+	return e, nil
+}
+
+// ListEvidence returns the evidence submitted against dispute id, oldest
+// first, without file contents.
+func ListEvidence(ctx context.Context, q queryer, id int64) ([]Evidence, error) {
+	query := "SELECT " + evidenceColumns + " FROM dispute_evidence WHERE dispute_id = $1 ORDER BY id"
+
+	spanCtx, span := tracing.StartDBSpan(ctx, "SELECT", "dispute_evidence", query)
+	rows, err := q.QueryContext(spanCtx, query, id)
+	tracing.End(span, err)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var evidence []Evidence
+	for rows.Next() {
+		e, err := scanEvidence(rows)
+		if err != nil {
+			return nil, err
+		}
+		evidence = append(evidence, e)
+	}
+	return evidence, rows.Err()
+}
+
+// EvidenceFile returns file evidence evidenceID of dispute id with its
+// content.
+func EvidenceFile(ctx context.Context, q queryer, id, evidenceID int64) (Evidence, []byte, error) {
+	query := "SELECT " + evidenceColumns + `, content FROM dispute_evidence
+		WHERE id = $1 AND dispute_id = $2 AND kind = 'file'`
+
+	var e Evidence
+	var content []byte
+	spanCtx, span := tracing.StartDBSpan(ctx, "SELECT", "dispute_evidence", query)
+	err := q.QueryRowContext(spanCtx, query, evidenceID, id).Scan(&e.ID, &e.DisputeID, &e.Kind, &e.Category,
+		&e.Note, &e.Filename, &e.ContentType, &e.Size, &e.SHA256, &e.UploadedBy, &e.CreatedAt, &content)
+	tracing.End(span, err)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Evidence{}, nil, ErrEvidenceNotFound
+	}
+	if err != nil {
+		return Evidence{}, nil, err
+	}
+	return e, content, nil
+}
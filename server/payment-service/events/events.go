@@ -0,0 +1,125 @@
+// Package events defines the payment-service domain events and records them
+// in the transactional outbox (the outbox_events table).
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/tracing"
+)
+
+// Type names a domain event, e.g. payment.created.
+type Type string
+
+const (
+	PaymentCreated  Type = "payment.created"
+	PaymentCaptured Type = "payment.captured"
+	PaymentFailed   Type = "payment.failed"
+	PaymentRefunded Type = "payment.refunded"
+	PaymentExpired  Type = "payment.expired"
+	// Manual capture only. Voiding is local; payment.authorization_released
+	// follows when Razorpay returns the held funds
+	PaymentAuthorized            Type = "payment.authorized"
+	PaymentVoided                Type = "payment.voided"
+	PaymentAuthorizationReleased Type = "payment.authorization_released"
+	// Held by risk screening for review
+	PaymentHeld Type = "payment.held"
+	// A cardholder disputed the payment; losing the dispute returns the
+	// money to them
+	PaymentDisputed    Type = "payment.disputed"
+	PaymentChargedBack Type = "payment.charged_back"
+)
+
+// Types lists the event types merchants can subscribe to.
+var Types = []Type{
+	PaymentCreated,
+	PaymentCaptured,
+	PaymentFailed,
+	PaymentRefunded,
+	PaymentExpired,
+	PaymentAuthorized,
+	PaymentVoided,
+	PaymentAuthorizationReleased,
+	PaymentHeld,
+	PaymentDisputed,
+	PaymentChargedBack,
+}
+
+// WebhookTest is sent only by the webhook test endpoint.
+const WebhookTest Type = "webhook.test"
+
+// IsKnown reports whether t is one of Types.
+func IsKnown(t Type) bool {
+	for _, known := range Types {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// AggregatePayment is the aggregate type of payment events.
+const AggregatePayment = "payment"
+
+// Event is one outbox entry as delivered to publishers.
+type Event struct {
+	// ID is the outbox row ID; it orders events and is not published.
+	ID int64 `json:"-"`
+
+	// EventID uniquely identifies the event so consumers can deduplicate
+	// redeliveries.
+	EventID       string          `json:"id"`
+	Type          Type            `json:"type"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id"`
+	Data          json.RawMessage `json:"data"`
+	CreatedAt     time.Time       `json:"created_at"`
+
+	// Attempts counts earlier delivery attempts.
+	Attempts int `json:"-"`
+}
+
+// Record inserts an event into the outbox using tx, so it is published only
+// if the caller's state change commits.
+func Record(ctx context.Context, tx *sql.Tx, typ Type, aggregateType, aggregateID string, data any) (Event, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, fmt.Errorf("encode %s event: %w", typ, err)
+	}
+
+	e := Event{
+		EventID:       NewEventID(),
+		Type:          typ,
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		Data:          payload,
+	}
+
+	const insertEvent = `INSERT INTO outbox_events
+		(event_id, event_type, aggregate_type, aggregate_id, payload)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+
+	ctx, span := tracing.StartDBSpan(ctx, "INSERT", "outbox_events", insertEvent)
+	err = tx.QueryRowContext(ctx, insertEvent,
+		e.EventID, e.Type, e.AggregateType, e.AggregateID, []byte(e.Data),
+	).Scan(&e.ID, &e.CreatedAt)
+	tracing.End(span, err)
+	if err != nil {
+		return Event{}, fmt.Errorf("record %s event: %w", typ, err)
+	}
+	return e, nil
+}
+
+// NewEventID returns a random event ID.
+func NewEventID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return "evt_" + hex.EncodeToString(b)
+}
@@ -0,0 +1,55 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/models"
+)
+
+// PaymentData is the payload of every payment.* event. Account numbers are
+// masked; consumers that need them look the payment up by ID.
+type PaymentData struct {
+	PaymentID         int        `json:"payment_id"`
+	UserID            int        `json:"user_id"`
+	Amount            float64    `json:"amount"`
+	Currency          string     `json:"currency"`
+	CaptureMode       string     `json:"capture_mode,omitempty"`
+	CapturedAmount    *float64   `json:"captured_amount,omitempty"`
+	Status            string     `json:"status"`
+	PreviousStatus    string     `json:"previous_status,omitempty"`
+	FromAccount       string     `json:"from_account"`
+	ToAccount         string     `json:"to_account"`
+	BeneficiaryID     *int64     `json:"beneficiary_id,omitempty"`
+	RazorpayOrderID   string     `json:"razorpay_order_id"`
+	RazorpayPaymentID string     `json:"razorpay_payment_id,omitempty"`
+	ExpiresAt         *time.Time `json:"expires_at,omitempty"`
+	OccurredAt        time.Time  `json:"occurred_at"`
+}
+
+// RecordPayment records a payment event describing p after a change from
+// previousStatus ("" for a new payment).
+func RecordPayment(ctx context.Context, tx *sql.Tx, typ Type, p models.Payment, previousStatus string) (Event, error) {
+	data := PaymentData{
+		PaymentID:       p.ID,
+		UserID:          p.UserID,
+		Amount:          p.Amount,
+		Currency:        p.Currency,
+		CaptureMode:     p.CaptureMode,
+		CapturedAmount:  p.CapturedAmount,
+		Status:          p.Status,
+		PreviousStatus:  previousStatus,
+		FromAccount:     models.MaskAccount(p.FromAccount),
+		ToAccount:       models.MaskAccount(p.ToAccount),
+		BeneficiaryID:   p.BeneficiaryID,
+		RazorpayOrderID: p.RazorpayOrderID,
+		ExpiresAt:       p.ExpiresAt,
+		OccurredAt:      time.Now().UTC(),
+	}
+	if p.RazorpayPaymentID != nil {
+		data.RazorpayPaymentID = *p.RazorpayPaymentID
+	}
+	return Record(ctx, tx, typ, AggregatePayment, strconv.Itoa(p.ID), data)
+}
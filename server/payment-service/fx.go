@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/fx"
+)
+
+const fxUsage = `usage: payment-service fx <command>
+
+commands:
+  import <file>          add the rates in a CSV file (base,quote,rate,effective_at);
+                         rates already stored are skipped
+  rates                  print the rate in effect now for every pair`
+
+// runFX implements the `fx` subcommand.
+func runFX(ctx context.Context, database *sql.DB, args []string) error {
+	if len(args) == 0 {
+		return errors.New(fxUsage)
+	}
+
+	switch args[0] {
+	case "import":
+		if len(args) != 2 {
+			return errors.New(fxUsage)
+		}
+		inserted, err := fx.ImportFile(ctx, database, args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("imported %d new rates from %s\n", inserted, args[1])
+		return nil
+
+	case "rates":
+		if len(args) != 1 {
+			return errors.New(fxUsage)
+		}
+		rates, err := fx.LatestRates(ctx, database, time.Now())
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "BASE\tQUOTE\tRATE\tEFFECTIVE\tSOURCE")
+		for _, r := range rates {
+			fmt.Fprintf(tw, "%s\t%s\t%g\t%s\t%s\n",
+				r.Base, r.Quote, r.Rate, r.EffectiveAt.Format("2006-01-02 15:04 MST"), r.Source)
+		}
+		return tw.Flush()
+
+	default:
+		return errors.New(fxUsage)
+	}
+}
@@ -0,0 +1,81 @@
+package fx
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// fileColumns is the header a rates file must start with.
+var fileColumns = []string{"base", "quote", "rate", "effective_at"}
+
+// ParseCSV reads rates in the rates-file format: a header row
+// "base,quote,rate,effective_at", then one rate per row with effective_at
+// in RFC 3339. Blank lines and lines starting with # are ignored. Errors
+// name the offending line.
+func ParseCSV(r io.Reader) ([]Rate, error) {
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	cr.FieldsPerRecord = len(fileColumns)
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("missing header row")
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i, col := range fileColumns {
+		if !strings.EqualFold(strings.TrimSpace(header[i]), col) {
+			return nil, fmt.Errorf("header must be %s", strings.Join(fileColumns, ","))
+		}
+	}
+
+	var rates []Rate
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return rates, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+
+		r := Rate{Base: record[0], Quote: record[1], Source: SourceFile}
+		if r.Rate, err = strconv.ParseFloat(strings.TrimSpace(record[2]), 64); err != nil {
+			return nil, fmt.Errorf("line %d: rate must be a number", line)
+		}
+		if r.EffectiveAt, err = time.Parse(time.RFC3339, strings.TrimSpace(record[3])); err != nil {
+			return nil, fmt.Errorf("line %d: effective_at must be an RFC 3339 time", line)
+		}
+		if err := Normalize(&r); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rates = append(rates, r)
+	}
+}
+
+// ImportFile parses the rates file at path and saves its rates, returning
+// how many were new.
+func ImportFile(ctx context.Context, db *sql.DB, path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	rates, err := ParseCSV(f)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", path, err)
+	}
+	return SaveRates(ctx, db, rates)
+}
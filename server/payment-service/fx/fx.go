@@ -0,0 +1,206 @@
+// Package fx stores exchange rates and the quotes that lock a rate for a
+// cross-currency transfer.
+//
+// A rate says 1 base = Rate quote from EffectiveAt until the next rate for
+// the pair takes effect. Rates are only ever added, so a quote's rate_id
+// always points at the rate it was priced with. A pair with no direct rate
+// falls back to the inverse of the opposite pair.
+package fx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/currencies"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/tracing"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrNoRate is returned when a pair has no rate in effect, or only one
+	// older than the allowed age.
+	ErrNoRate = errors.New("no current exchange rate")
+	// ErrUnknownCurrency is returned when a rate names a currency missing
+	// from the registry.
+	ErrUnknownCurrency = errors.New("unknown currency")
+	// ErrQuoteNotFound is returned when a quote does not exist or belongs
+	// to another user.
+	ErrQuoteNotFound = errors.New("quote not found")
+	ErrQuoteExpired  = errors.New("quote has expired")
+	ErrQuoteUsed     = errors.New("quote has already been used")
+	// ErrTooSmall is returned when an amount converts to less than one
+	// minor unit.
+	ErrTooSmall = errors.New("amount is too small to convert")
+)
+
+// Source records where a rate came from.
+type Source string
+
+const (
+	SourceFile  Source = "file"
+	SourceAdmin Source = "admin"
+)
+
+// Rate is an exchange rate: 1 Base = Rate Quote.
+type Rate struct {
+	ID          int64
+	Base        string
+	Quote       string
+	Rate        float64
+	EffectiveAt time.Time
+	Source      Source
+	CreatedBy   *int // admin who entered it; nil for file imports
+	CreatedAt   time.Time
+}
+
+// Normalize upper-cases the pair and checks r, returning a description of
+// the first problem found.
+func Normalize(r *Rate) error {
+	var err error
+	if r.Base, err = currencies.NormalizeCode(r.Base); err != nil {
+		return fmt.Errorf("base: %w", err)
+	}
+	if r.Quote, err = currencies.NormalizeCode(r.Quote); err != nil {
+		return fmt.Errorf("quote: %w", err)
+	}
+	if r.Base == r.Quote {
+		return errors.New("base and quote must differ")
+	}
+	if !(r.Rate > 0) || math.IsInf(r.Rate, 0) {
+		return errors.New("rate must be a positive number")
+	}
+	if r.EffectiveAt.IsZero() {
+		return errors.New("effective_at is required")
+	}
+	r.EffectiveAt = r.EffectiveAt.UTC()
+	return nil
+}
+
+// queryer is satisfied by *sql.DB and *sql.Tx.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+const rateColumns = `id, base, quote, rate, effective_at, source, created_by, created_at`
+
+func scanRate(row interface{ Scan(...any) error }) (Rate, error) {
+	var r Rate
+	var createdBy sql.NullInt64
+	err := row.Scan(&r.ID, &r.Base, &r.Quote, &r.Rate, &r.EffectiveAt, &r.Source, &createdBy, &r.CreatedAt)
+	if err != nil {
+		return Rate{}, err
+	}
+	if createdBy.Valid {
+		id := int(createdBy.Int64)
+		r.CreatedBy = &id
+	}
+	return r, nil
+}
+
+// SaveRates stores rates, which must have passed Normalize, in one
+// transaction and returns how many were new. A rate for a pair and
+// effective time already stored is skipped, so importing the same file
+// twice is harmless.
+func SaveRates(ctx context.Context, db *sql.DB, rates []Rate) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO fx_rates (base, quote, rate, effective_at, source, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (base, quote, effective_at) DO NOTHING`
+	spanCtx, span := tracing.StartDBSpan(ctx, "INSERT", "fx_rates", query)
+	inserted := 0
+	for _, r := range rates {
+		res, err := tx.ExecContext(spanCtx, query, r.Base, r.Quote, r.Rate, r.EffectiveAt, r.Source, r.CreatedBy)
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+			err = fmt.Errorf("%w in %s/%s", ErrUnknownCurrency, r.Base, r.Quote)
+		}
+		if err != nil {
+			tracing.End(span, err)
+			return 0, err
+		}
+		n, _ := res.RowsAffected()
+		inserted += int(n)
+	}
+	tracing.End(span, nil)
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return inserted, nil
+}
+
+// LatestRates returns the rate in effect at now for every stored pair,
+// ordered by pair.
+func LatestRates(ctx context.Context, q queryer, now time.Time) ([]Rate, error) {
+	rows, err := q.QueryContext(ctx, `SELECT DISTINCT ON (base, quote) `+rateColumns+`
+		FROM fx_rates
+		WHERE effective_at <= $1
+		ORDER BY base, quote, effective_at DESC`, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Rate
+	for rows.Next() {
+		r, err := scanRate(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, r)
+	}
+	return list, rows.Err()
+}
+
+// latest returns the rate for base/quote in effect at now.
+func latest(ctx context.Context, q queryer, base, quote string, now time.Time) (Rate, bool, error) {
+	r, err := scanRate(q.QueryRowContext(ctx, `SELECT `+rateColumns+` FROM fx_rates
+		WHERE base = $1 AND quote = $2 AND effective_at <= $3
+		ORDER BY effective_at DESC
+		LIMIT 1`, base, quote, now))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Rate{}, false, nil
+	}
+	return r, err == nil, err
+}
+
+// Current returns the rate to convert from into to at now, and how many to
+// one from is worth. The direct pair is used unless the inverse pair has a
+// newer rate. Errors wrap ErrNoRate when neither pair has a rate in effect
+// or the newest is older than maxAge.
+func Current(ctx context.Context, q queryer, from, to string, now time.Time, maxAge time.Duration) (Rate, float64, error) {
+	direct, haveDirect, err := latest(ctx, q, from, to, now)
+	if err != nil {
+		return Rate{}, 0, err
+	}
+	inverse, haveInverse, err := latest(ctx, q, to, from, now)
+	if err != nil {
+		return Rate{}, 0, err
+	}
+
+	var r Rate
+	var mult float64
+	switch {
+	case haveDirect && (!haveInverse || !inverse.EffectiveAt.After(direct.EffectiveAt)):
+		r, mult = direct, direct.Rate
+	case haveInverse:
+		r, mult = inverse, 1/inverse.Rate
+	default:
+		return Rate{}, 0, fmt.Errorf("%w for %s/%s", ErrNoRate, from, to)
+	}
+	if now.Sub(r.EffectiveAt) > maxAge {
+		return Rate{}, 0, fmt.Errorf("%w for %s/%s: newest rate is from %s", ErrNoRate, from, to, r.EffectiveAt.Format(time.RFC3339))
+	}
+	return r, mult, nil
+}
@@ -0,0 +1,121 @@
+package fx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/currencies"
+)
+
+// Quote locks a rate for converting Amount in From to ConvertedAmount in To
+// until ExpiresAt. A payment redeems it once.
+type Quote struct {
+	ID              int64
+	UserID          int
+	RateID          int64
+	From            string
+	To              string
+	Rate            float64 // 1 From = Rate To
+	Amount          float64
+	ConvertedAmount float64
+	ExpiresAt       time.Time
+	PaymentID       *int64
+	UsedAt          *time.Time
+	CreatedAt       time.Time
+}
+
+// Expired reports whether the quote can no longer be redeemed at now.
+func (q Quote) Expired(now time.Time) bool {
+	return !now.Before(q.ExpiresAt)
+}
+
+const quoteColumns = `id, user_id, rate_id, from_currency, to_currency, rate, amount, converted_amount,
+	expires_at, payment_id, used_at, created_at`
+
+func scanQuote(row interface{ Scan(...any) error }) (Quote, error) {
+	var q Quote
+	var paymentID sql.NullInt64
+	var usedAt sql.NullTime
+	err := row.Scan(&q.ID, &q.UserID, &q.RateID, &q.From, &q.To, &q.Rate, &q.Amount, &q.ConvertedAmount,
+		&q.ExpiresAt, &paymentID, &usedAt, &q.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Quote{}, ErrQuoteNotFound
+	}
+	if err != nil {
+		return Quote{}, err
+	}
+	if paymentID.Valid {
+		q.PaymentID = &paymentID.Int64
+	}
+	if usedAt.Valid {
+		q.UsedAt = &usedAt.Time
+	}
+	return q, nil
+}
+
+// NewQuote prices amount of from in to at the current rate and stores a
+// quote for userID that expires after ttl. The converted amount is rounded
+// to to's minor unit. Errors wrap ErrNoRate (see Current) and ErrTooSmall.
+func NewQuote(ctx context.Context, q queryer, userID int, from, to currencies.Currency, amount float64, now time.Time, ttl, maxAge time.Duration) (Quote, error) {
+	rate, mult, err := Current(ctx, q, from.Code, to.Code, now, maxAge)
+	if err != nil {
+		return Quote{}, err
+	}
+	converted := to.Round(amount * mult)
+	if converted <= 0 {
+		return Quote{}, fmt.Errorf("%w: %v %s", ErrTooSmall, amount, from.Code)
+	}
+
+	return scanQuote(q.QueryRowContext(ctx, `INSERT INTO fx_quotes
+			(user_id, rate_id, from_currency, to_currency, rate, amount, converted_amount, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING `+quoteColumns,
+		userID, rate.ID, from.Code, to.Code, mult, amount, converted, now.Add(ttl)))
+}
+
+// GetQuote returns userID's quote id.
+func GetQuote(ctx context.Context, q queryer, userID int, id int64) (Quote, error) {
+	return scanQuote(q.QueryRowContext(ctx, `SELECT `+quoteColumns+` FROM fx_quotes
+		WHERE id = $1 AND user_id = $2`, id, userID))
+}
+
+// ForPayment returns the quote redeemed by paymentID, or ErrQuoteNotFound
+// if the payment was not converted.
+func ForPayment(ctx context.Context, q queryer, paymentID int64) (Quote, error) {
+	return scanQuote(q.QueryRowContext(ctx, `SELECT `+quoteColumns+` FROM fx_quotes
+		WHERE payment_id = $1`, paymentID))
+}
+
+// Usable returns ErrQuoteUsed or ErrQuoteExpired if quote cannot be
+// redeemed at now.
+func (q Quote) Usable(now time.Time) error {
+	if q.PaymentID != nil {
+		return ErrQuoteUsed
+	}
+	if q.Expired(now) {
+		return ErrQuoteExpired
+	}
+	return nil
+}
+
+// Redeem marks userID's quote id as used by paymentID. It locks the quote,
+// so of two payments racing for it exactly one succeeds; the other gets
+// ErrQuoteUsed. tx should be the transaction that stores the payment.
+func Redeem(ctx context.Context, tx *sql.Tx, userID int, id, paymentID int64, now time.Time) (Quote, error) {
+	quote, err := scanQuote(tx.QueryRowContext(ctx, `SELECT `+quoteColumns+` FROM fx_quotes
+		WHERE id = $1 AND user_id = $2
+		FOR UPDATE`, id, userID))
+	if err != nil {
+		return Quote{}, err
+	}
+	if err := quote.Usable(now); err != nil {
+		return Quote{}, err
+	}
+
+	return scanQuote(tx.QueryRowContext(ctx, `UPDATE fx_quotes SET payment_id = $2, used_at = $3
+		WHERE id = $1
+		RETURNING `+quoteColumns, id, paymentID, now))
+}
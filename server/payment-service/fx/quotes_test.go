@@ -0,0 +1,227 @@
+package fx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/currencies"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/internal/dbtest"
+)
+
+var (
+	usd = currencies.Currency{Code: "USD", Exponent: 2}
+	inr = currencies.Currency{Code: "INR", Exponent: 2}
+	jpy = currencies.Currency{Code: "JPY", Exponent: 0}
+)
+
+func TestQuoteUsable(t *testing.T) {
+	now := time.Now()
+	paymentID := int64(7)
+	tests := []struct {
+		name  string
+		quote Quote
+		want  error
+	}{
+		{"fresh", Quote{ExpiresAt: now.Add(time.Minute)}, nil},
+		{"expires now", Quote{ExpiresAt: now}, ErrQuoteExpired},
+		{"used", Quote{ExpiresAt: now.Add(time.Minute), PaymentID: &paymentID}, ErrQuoteUsed},
+		{"used and expired", Quote{ExpiresAt: now.Add(-time.Minute), PaymentID: &paymentID}, ErrQuoteUsed},
+	}
+	for _, tt := range tests {
+		if err := tt.quote.Usable(now); !errors.Is(err, tt.want) {
+			t.Errorf("%s: Usable = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func saveRates(t *testing.T, database *sql.DB, rates ...Rate) {
+	t.Helper()
+	for i := range rates {
+		rates[i].Source = SourceFile
+		if err := Normalize(&rates[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := SaveRates(context.Background(), database, rates); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCurrent(t *testing.T) {
+	database := dbtest.Open(t)
+	ctx := context.Background()
+	now := time.Now()
+	saveRates(t, database,
+		Rate{Base: "USD", Quote: "INR", Rate: 83, EffectiveAt: now.Add(-2 * time.Hour)},
+		Rate{Base: "USD", Quote: "INR", Rate: 84, EffectiveAt: now.Add(time.Hour)}, // not yet in effect
+		Rate{Base: "EUR", Quote: "USD", Rate: 1.25, EffectiveAt: now.Add(-time.Hour)},
+		Rate{Base: "USD", Quote: "EUR", Rate: 0.9, EffectiveAt: now.Add(-3 * time.Hour)},
+		Rate{Base: "GBP", Quote: "INR", Rate: 105, EffectiveAt: now.Add(-48 * time.Hour)},
+	)
+
+	tests := []struct {
+		from, to string
+		mult     float64
+		err      error
+	}{
+		{from: "USD", to: "INR", mult: 83},
+		{from: "INR", to: "USD", mult: 1.0 / 83},
+		{from: "USD", to: "EUR", mult: 1 / 1.25}, // the inverse pair is newer
+		{from: "EUR", to: "USD", mult: 1.25},
+		{from: "GBP", to: "INR", err: ErrNoRate}, // too old
+		{from: "AED", to: "INR", err: ErrNoRate},
+	}
+	for _, tt := range tests {
+		_, mult, err := Current(ctx, database, tt.from, tt.to, now, 24*time.Hour)
+		if !errors.Is(err, tt.err) || err == nil && mult != tt.mult {
+			t.Errorf("Current(%s, %s) = %v, %v; want %v, %v", tt.from, tt.to, mult, err, tt.mult, tt.err)
+		}
+	}
+}
+
+// insertPayment stores a payment a quote can be redeemed by.
+func insertPayment(t *testing.T, tx *sql.Tx, userID int, orderID string) int64 {
+	t.Helper()
+	var id int64
+	err := tx.QueryRowContext(context.Background(), `INSERT INTO payments
+			(user_id, amount, currency, from_account, to_account, razorpay_order_id, status, created_at)
+		VALUES ($1, 100, 'USD', 'ACC-FROM', 'ACC-TO', $2, 'created', NOW())
+		RETURNING id`, userID, orderID).Scan(&id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func TestNewQuote(t *testing.T) {
+	database := dbtest.Open(t)
+	ctx := context.Background()
+	now := time.Now()
+	saveRates(t, database, Rate{Base: "USD", Quote: "JPY", Rate: 151.237, EffectiveAt: now.Add(-time.Hour)})
+
+	q, err := NewQuote(ctx, database, 1, usd, jpy, 10.5, now, 15*time.Minute, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 1587.9885 yen, rounded to JPY's whole units
+	if q.ConvertedAmount != 1588 || q.Rate != 151.237 || (q.ExpiresAt.Sub(now)-15*time.Minute).Abs() > time.Millisecond {
+		t.Errorf("quote converts to %v at %v until %s", q.ConvertedAmount, q.Rate, q.ExpiresAt)
+	}
+	if _, err := NewQuote(ctx, database, 1, usd, jpy, 0.001, now, time.Minute, 24*time.Hour); !errors.Is(err, ErrTooSmall) {
+		t.Errorf("quoting less than a yen: err = %v, want ErrTooSmall", err)
+	}
+	if _, err := NewQuote(ctx, database, 1, usd, inr, 10, now, time.Minute, 24*time.Hour); !errors.Is(err, ErrNoRate) {
+		t.Errorf("quoting without a rate: err = %v, want ErrNoRate", err)
+	}
+}
+
+func TestRedeem(t *testing.T) {
+	database := dbtest.Open(t)
+	ctx := context.Background()
+	now := time.Now()
+	saveRates(t, database, Rate{Base: "USD", Quote: "INR", Rate: 83, EffectiveAt: now.Add(-time.Hour)})
+	const user = 2
+	quote := func(ttl time.Duration) Quote {
+		q, err := NewQuote(ctx, database, user, usd, inr, 100, now, ttl, 24*time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return q
+	}
+	// redeem stores a payment for the quote and redeems it in one transaction.
+	redeem := func(userID int, id int64, orderID string) (Quote, error) {
+		tx, err := database.BeginTx(ctx, nil)
+		if err != nil {
+			return Quote{}, err
+		}
+		defer tx.Rollback()
+		redeemed, err := Redeem(ctx, tx, userID, id, insertPayment(t, tx, userID, orderID), now)
+		if err == nil {
+			err = tx.Commit()
+		}
+		return redeemed, err
+	}
+
+	q := quote(time.Minute)
+	if _, err := redeem(user+1, q.ID, "order_other"); !errors.Is(err, ErrQuoteNotFound) {
+		t.Errorf("another user's quote: err = %v, want ErrQuoteNotFound", err)
+	}
+	redeemed, err := redeem(user, q.ID, "order_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if redeemed.PaymentID == nil || redeemed.UsedAt == nil {
+		t.Errorf("redeemed quote %+v is not marked used", redeemed)
+	}
+	if _, err := redeem(user, q.ID, "order_2"); !errors.Is(err, ErrQuoteUsed) {
+		t.Errorf("second redemption: err = %v, want ErrQuoteUsed", err)
+	}
+
+	// A quote is priced for a while only
+	expired := quote(-time.Second)
+	if _, err := redeem(user, expired.ID, "order_3"); !errors.Is(err, ErrQuoteExpired) {
+		t.Errorf("expired quote: err = %v, want ErrQuoteExpired", err)
+	}
+	if got, err := GetQuote(ctx, database, user, expired.ID); err != nil || got.PaymentID != nil {
+		t.Errorf("expired quote %+v (%v) was redeemed", got, err)
+	}
+}
+
+func TestRedeemRace(t *testing.T) {
+	database := dbtest.Open(t)
+	ctx := context.Background()
+	now := time.Now()
+	saveRates(t, database, Rate{Base: "USD", Quote: "INR", Rate: 83, EffectiveAt: now.Add(-time.Hour)})
+	q, err := NewQuote(ctx, database, 1, usd, inr, 100, now, time.Minute, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const payments = 5
+	errs := make([]error, payments)
+	var wg sync.WaitGroup
+	for i := range payments {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tx, err := database.BeginTx(ctx, nil)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			defer tx.Rollback()
+			var paymentID int64
+			err = tx.QueryRowContext(ctx, `INSERT INTO payments
+					(user_id, amount, currency, from_account, to_account, razorpay_order_id, status, created_at)
+				VALUES (1, 100, 'USD', 'ACC-FROM', 'ACC-TO', $1, 'created', NOW())
+				RETURNING id`, "order_race_"+strconv.Itoa(i)).Scan(&paymentID)
+			if err == nil {
+				_, err = Redeem(ctx, tx, 1, q.ID, paymentID, now)
+			}
+			if err == nil {
+				err = tx.Commit()
+			}
+			errs[i] = err
+		}()
+	}
+	wg.Wait()
+
+	// Exactly one payment gets the quote; the rest see it used
+	redeemed := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			redeemed++
+		case !errors.Is(err, ErrQuoteUsed):
+			t.Errorf("racing redemption: %v", err)
+		}
+	}
+	if redeemed != 1 {
+		t.Errorf("%d payments redeemed the quote, want 1", redeemed)
+	}
+}
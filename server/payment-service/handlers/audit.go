@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/audit"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/db"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/models"
+)
+
+// recordAudit records an action already committed. The change stands if
+// recording fails, so the failure is only logged.
+func recordAudit(r *http.Request, e audit.Event) {
+	if _, err := audit.Record(r.Context(), db.DB, e); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to record audit entry", "action", e.Action, "error", err)
+	}
+}
+
+// ListAuditLog lists audit log entries
+// @Summary List audit log entries
+// @Description Lists entries from both services newest first. An action ending in a dot matches every action under it, e.g. admin. for all admin actions. Pass the last id of a page as before_id for the next.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param service query string false "auth or payment"
+// @Param action query string false "e.g. auth.login, or admin. for a prefix"
+// @Param outcome query string false "success or failure"
+// @Param actor_id query int false "Only actions by this user"
+// @Param resource_type query string false "e.g. payment, user, currency"
+// @Param resource_id query string false "Only this resource; use with resource_type"
+// @Param request_id query string false "Only entries from this request"
+// @Param from query string false "Start, inclusive: YYYY-MM-DD or RFC 3339"
+// @Param to query string false "End: YYYY-MM-DD includes that day, RFC 3339 is exclusive"
+// @Param before_id query int false "Only entries older than this one"
+// @Param limit query int false "Maximum results (default 50, max 100)"
+// @Success 200 {array} models.AuditEntryResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/admin/audit [get]
+func ListAuditLog(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := audit.Filter{
+		Action:       q.Get("action"),
+		ResourceType: q.Get("resource_type"),
+		ResourceID:   q.Get("resource_id"),
+		RequestID:    q.Get("request_id"),
+	}
+	switch service := q.Get("service"); service {
+	case "", "auth", "payment":
+		filter.Service = service
+	default:
+		writeError(w, r, http.StatusBadRequest, "Invalid request", "service must be auth or payment")
+		return
+	}
+	switch outcome := audit.Outcome(q.Get("outcome")); outcome {
+	case "", audit.OutcomeSuccess, audit.OutcomeFailure:
+		filter.Outcome = outcome
+	default:
+		writeError(w, r, http.StatusBadRequest, "Invalid request", "outcome must be success or failure")
+		return
+	}
+	if v := q.Get("actor_id"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(w, r, http.StatusBadRequest, "Invalid request", "actor_id must be a positive integer")
+			return
+		}
+		filter.ActorID = n
+	}
+	if s := q.Get("from"); s != "" {
+		t, _, err := parseStatementTime(s)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "Invalid request", "from must be YYYY-MM-DD or an RFC 3339 time")
+			return
+		}
+		filter.Since = t
+	}
+	if s := q.Get("to"); s != "" {
+		t, dateOnly, err := parseStatementTime(s)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "Invalid request", "to must be YYYY-MM-DD or an RFC 3339 time")
+			return
+		}
+		if dateOnly {
+			t = t.AddDate(0, 0, 1)
+		}
+		filter.Until = t
+	}
+	if v := q.Get("before_id"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n <= 0 {
+			writeError(w, r, http.StatusBadRequest, "Invalid request", "before_id must be a positive integer")
+			return
+		}
+		filter.BeforeID = n
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "Invalid request", "limit must be an integer")
+			return
+		}
+		filter.Limit = n
+	}
+
+	entries, err := audit.List(r.Context(), db.DB, filter)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to list audit log", "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Could not list audit log")
+		return
+	}
+	resp := make([]models.AuditEntryResponse, 0, len(entries))
+	for _, e := range entries {
+		resp = append(resp, auditEntryResponse(e))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// VerifyAuditLog checks the audit log's hash chain
+// @Summary Verify the audit log
+// @Description Recomputes every chained entry's hash from the start of the chain and reports the first entry that was altered or whose predecessor was altered, removed or reordered. Entries are chained a few seconds after they are written; those still waiting are counted as pending. Reads the whole log, so it can take a while.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.AuditVerifyResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/admin/audit/verify [get]
+func VerifyAuditLog(w http.ResponseWriter, r *http.Request) {
+	v, err := audit.Verify(r.Context(), db.DB)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to verify audit log", "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Could not verify audit log")
+		return
+	}
+	if !v.Intact() {
+		slog.ErrorContext(r.Context(), "Audit log chain broken", "entry_id", v.BrokenAt, "problem", v.Problem)
+	}
+	writeJSON(w, http.StatusOK, models.AuditVerifyResponse{
+		Intact:   v.Intact(),
+		Checked:  v.Checked,
+		BrokenAt: v.BrokenAt,
+		Problem:  v.Problem,
+		LastHash: v.LastHash,
+		Pending:  v.Pending,
+	})
+}
+
+func auditEntryResponse(e audit.Entry) models.AuditEntryResponse {
+	resp := models.AuditEntryResponse{
+		ID:           e.ID,
+		CreatedAt:    e.CreatedAt,
+		Service:      e.Service,
+		Action:       e.Action,
+		Outcome:      string(e.Outcome),
+		ActorType:    e.ActorType,
+		ActorID:      e.ActorID,
+		ActorEmail:   e.ActorEmail,
+		IP:           e.IP,
+		UserAgent:    e.UserAgent,
+		RequestID:    e.RequestID,
+		ResourceType: e.ResourceType,
+		ResourceID:   e.ResourceID,
+		Before:       e.Before,
+		After:        e.After,
+		Detail:       e.Detail,
+		PrevHash:     e.PrevHash,
+		Hash:         e.Hash,
+	}
+	if e.Seq != 0 {
+		resp.Seq = &e.Seq
+	}
+	return resp
+}
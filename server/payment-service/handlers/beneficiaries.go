@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/beneficiaries"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/db"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/models"
+)
+
+// CreateBeneficiary saves a payee
+// @Summary Save beneficiary
+// @Description Saves a bank account (account number and IFSC) or UPI VPA that /pay can reference by beneficiary_id.
+// @Tags beneficiaries
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param beneficiary body models.BeneficiaryRequest true "Beneficiary"
+// @Success 201 {object} models.BeneficiaryResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/beneficiaries [post]
+func CreateBeneficiary(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.BeneficiaryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request", "Failed to parse request body")
+		return
+	}
+
+	b := beneficiaries.Beneficiary{
+		UserID:            uid,
+		Nickname:          req.Nickname,
+		Type:              beneficiaries.Type(req.Type),
+		AccountNumber:     req.AccountNumber,
+		IFSC:              req.IFSC,
+		AccountHolderName: req.AccountHolderName,
+		VPA:               req.VPA,
+	}
+	if err := beneficiaries.Normalize(&b); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid beneficiary", err.Error())
+		return
+	}
+
+	b, err := beneficiaries.Create(r.Context(), db.DB, b)
+	if errors.Is(err, beneficiaries.ErrDuplicateNickname) {
+		writeError(w, r, http.StatusConflict, "Duplicate nickname", err.Error())
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to create beneficiary", "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Could not save beneficiary")
+		return
+	}
+
+	slog.InfoContext(r.Context(), "Beneficiary created", "beneficiary_id", b.ID, "type", b.Type)
+	writeJSON(w, http.StatusCreated, beneficiaryResponse(b))
+}
+
+// ListBeneficiaries lists the caller's beneficiaries
+// @Summary List beneficiaries
+// @Tags beneficiaries
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.BeneficiaryResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/beneficiaries [get]
+func ListBeneficiaries(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+
+	list, err := beneficiaries.List(r.Context(), db.DB, uid)
+	if !beneficiaryFound(w, r, err) {
+		return
+	}
+	resp := make([]models.BeneficiaryResponse, 0, len(list))
+	for _, b := range list {
+		resp = append(resp, beneficiaryResponse(b))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// GetBeneficiary returns one of the caller's beneficiaries
+// @Summary Get beneficiary
+// @Tags beneficiaries
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Beneficiary ID"
+// @Success 200 {object} models.BeneficiaryResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/beneficiaries/{id} [get]
+func GetBeneficiary(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	b, err := beneficiaries.Get(r.Context(), db.DB, uid, id)
+	if !beneficiaryFound(w, r, err) {
+		return
+	}
+	writeJSON(w, http.StatusOK, beneficiaryResponse(b))
+}
+
+// DeleteBeneficiary removes one of the caller's beneficiaries
+// @Summary Delete beneficiary
+// @Description Removes the beneficiary from the list. Payments already made to it are unaffected.
+// @Tags beneficiaries
+// @Security BearerAuth
+// @Param id path int true "Beneficiary ID"
+// @Success 204
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/beneficiaries/{id} [delete]
+func DeleteBeneficiary(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	err := beneficiaries.Delete(r.Context(), db.DB, uid, id)
+	if !beneficiaryFound(w, r, err) {
+		return
+	}
+	slog.InfoContext(r.Context(), "Beneficiary deleted", "beneficiary_id", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func beneficiaryFound(w http.ResponseWriter, r *http.Request, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, beneficiaries.ErrNotFound):
+		writeError(w, r, http.StatusNotFound, "Not found", "Beneficiary not found")
+	default:
+		slog.ErrorContext(r.Context(), "Beneficiary request failed", "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Internal error", "Could not complete beneficiary request")
+	}
+	return false
+}
+
+func beneficiaryResponse(b beneficiaries.Beneficiary) models.BeneficiaryResponse {
+	resp := models.BeneficiaryResponse{
+		ID:                b.ID,
+		Nickname:          b.Nickname,
+		Type:              string(b.Type),
+		IFSC:              b.IFSC,
+		AccountHolderName: b.AccountHolderName,
+		VPA:               b.VPA,
+		CreatedAt:         b.CreatedAt,
+	}
+	if b.AccountNumber != "" {
+		resp.AccountNumber = models.MaskAccount(b.AccountNumber)
+	}
+	return resp
+}
@@ -0,0 +1,342 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/audit"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/blocklist"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/db"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/models"
+)
+
+// maxBlocklistImportBytes bounds a CSV import's body.
+const maxBlocklistImportBytes = 5 << 20
+
+// blocklistCache is the in-memory blocklist /pay is screened against, set
+// at startup.
+var blocklistCache *blocklist.Cache
+
+// SetBlocklist sets the cache reloaded after each change made through the
+// admin endpoints and served to other services.
+func SetBlocklist(c *blocklist.Cache) {
+	blocklistCache = c
+}
+
+// reloadBlocklist brings this instance's cache up to date after a change.
+// Should it fail, the refresher picks the change up on its next poll.
+func reloadBlocklist(r *http.Request) {
+	if err := blocklistCache.Reload(r.Context()); err != nil {
+		slog.WarnContext(r.Context(), "Failed to reload blocklist after change", "error", err)
+	}
+}
+
+// ListBlocklist lists blocklist entries
+// @Summary List blocklist entries
+// @Description Lists entries newest first. Live entries include those past their expiry, which no longer block; removed=true lists removed entries instead.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param kind query string false "account, vpa, email or ip"
+// @Param q query string false "Only values containing this"
+// @Param removed query bool false "List removed entries"
+// @Param limit query int false "Maximum results (default 50, max 100)"
+// @Success 200 {array} models.BlocklistEntryResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/admin/blocklist [get]
+func ListBlocklist(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := blocklist.Filter{Search: q.Get("q")}
+	if kind := blocklist.Kind(q.Get("kind")); kind != "" {
+		if !validBlocklistKind(kind) {
+			writeError(w, r, http.StatusBadRequest, "Invalid request", "kind must be account, vpa, email or ip")
+			return
+		}
+		filter.Kind = kind
+	}
+	if v := q.Get("removed"); v != "" {
+		removed, err := strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "Invalid request", "removed must be true or false")
+			return
+		}
+		filter.Removed = removed
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "Invalid request", "limit must be an integer")
+			return
+		}
+		filter.Limit = n
+	}
+
+	entries, err := blocklist.List(r.Context(), db.DB, filter)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to list blocklist", "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Could not list blocklist")
+		return
+	}
+	resp := make([]models.BlocklistEntryResponse, 0, len(entries))
+	for _, e := range entries {
+		resp = append(resp, blocklistEntryResponse(e))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// CreateBlocklistEntry blocklists a value
+// @Summary Blocklist a value
+// @Description Blocks an account number or VPA as payer or payee of /pay, an email (or @domain) from /pay, registration and login, or an IP address or network from all three. Takes effect on this instance at once and on others within BLOCKLIST_REFRESH_INTERVAL.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param entry body models.BlocklistEntryRequest true "Entry"
+// @Success 201 {object} models.BlocklistEntryResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/admin/blocklist [post]
+func CreateBlocklistEntry(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.BlocklistEntryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request", "Failed to parse request body")
+		return
+	}
+	entry := blocklist.Entry{
+		Kind:      blocklist.Kind(req.Kind),
+		Value:     req.Value,
+		Reason:    req.Reason,
+		ExpiresAt: req.ExpiresAt,
+		CreatedBy: uid,
+	}
+	if err := blocklist.Normalize(&entry); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid entry", err.Error())
+		return
+	}
+
+	added, err := blocklist.Add(r.Context(), db.DB, entry)
+	if errors.Is(err, blocklist.ErrDuplicate) {
+		writeError(w, r, http.StatusConflict, "Already blocklisted", fmt.Sprintf("%s %s is already on the blocklist", entry.Kind, entry.Value))
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to add blocklist entry", "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Could not save entry")
+		return
+	}
+	reloadBlocklist(r)
+
+	slog.InfoContext(r.Context(), "Value blocklisted", "entry_id", added.ID, "kind", added.Kind, "expires_at", added.ExpiresAt)
+	resp := blocklistEntryResponse(added)
+	recordAudit(r, audit.Event{
+		Action:       "admin.blocklist.added",
+		ResourceType: "blocklist_entry",
+		ResourceID:   audit.ID(added.ID),
+		After:        resp,
+	})
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+// GetBlocklistEntry returns a blocklist entry
+// @Summary Get a blocklist entry
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Entry ID"
+// @Success 200 {object} models.BlocklistEntryResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/admin/blocklist/{id} [get]
+func GetBlocklistEntry(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	entry, err := blocklist.Get(r.Context(), db.DB, id)
+	if errors.Is(err, blocklist.ErrNotFound) {
+		writeError(w, r, http.StatusNotFound, "Not found", "Blocklist entry not found")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to load blocklist entry", "entry_id", id, "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to load entry")
+		return
+	}
+	writeJSON(w, http.StatusOK, blocklistEntryResponse(entry))
+}
+
+// DeleteBlocklistEntry removes a blocklist entry
+// @Summary Remove a blocklist entry
+// @Description Stops the entry blocking; it is kept, with who removed it and when, and listed with removed=true.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Entry ID"
+// @Success 200 {object} models.BlocklistEntryResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/admin/blocklist/{id} [delete]
+func DeleteBlocklistEntry(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	removed, err := blocklist.Remove(r.Context(), db.DB, id, uid)
+	if errors.Is(err, blocklist.ErrNotFound) {
+		writeError(w, r, http.StatusNotFound, "Not found", "No live blocklist entry with this ID")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to remove blocklist entry", "entry_id", id, "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Could not remove entry")
+		return
+	}
+	reloadBlocklist(r)
+
+	slog.InfoContext(r.Context(), "Blocklist entry removed", "entry_id", removed.ID, "kind", removed.Kind)
+	before := removed
+	before.RemovedAt, before.RemovedBy = nil, nil
+	resp := blocklistEntryResponse(removed)
+	recordAudit(r, audit.Event{
+		Action:       "admin.blocklist.removed",
+		ResourceType: "blocklist_entry",
+		ResourceID:   audit.ID(removed.ID),
+		Before:       blocklistEntryResponse(before),
+		After:        resp,
+	})
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// ImportBlocklist adds entries from CSV
+// @Summary Import blocklist entries
+// @Description Takes a CSV body with the header kind,value,reason,expires_at; expires_at may be empty, an RFC 3339 time or a date. The whole file is rejected if any row is invalid. A value already listed is replaced with the row's reason and expiry, and the importing admin as its creator.
+// @Tags admin
+// @Accept text/csv
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.BlocklistImportResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/admin/blocklist/import [post]
+func ImportBlocklist(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+
+	entries, err := blocklist.ParseCSV(http.MaxBytesReader(w, r.Body, maxBlocklistImportBytes))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeError(w, r, http.StatusRequestEntityTooLarge, "File too large", fmt.Sprintf("The CSV must be at most %d bytes", maxBlocklistImportBytes))
+		return
+	}
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid CSV", err.Error())
+		return
+	}
+	if len(entries) == 0 {
+		writeError(w, r, http.StatusBadRequest, "Invalid CSV", "The file has no entries")
+		return
+	}
+
+	added, replaced, err := blocklist.Import(r.Context(), db.DB, entries, uid)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to import blocklist", "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Could not import entries")
+		return
+	}
+	reloadBlocklist(r)
+
+	slog.InfoContext(r.Context(), "Blocklist imported", "added", added, "replaced", replaced)
+	recordAudit(r, audit.Event{
+		Action:       "admin.blocklist.imported",
+		ResourceType: "blocklist",
+		After:        models.BlocklistImportResponse{Added: added, Replaced: replaced},
+		Detail:       fmt.Sprintf("%d rows imported", len(entries)),
+	})
+	writeJSON(w, http.StatusOK, models.BlocklistImportResponse{Added: added, Replaced: replaced})
+}
+
+// GetBlocklistSnapshot serves the live blocklist to other services
+// @Summary Live blocklist for other services
+// @Description Every live entry at the cached revision, for auth-service to screen registrations and logins. The revision is the ETag; a matching If-None-Match gets 304. Requires a service token; not routed by the gateway.
+// @Tags internal
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.BlocklistSnapshotResponse
+// @Success 304
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /api/v1/internal/blocklist [get]
+func GetBlocklistSnapshot(w http.ResponseWriter, r *http.Request) {
+	revision, entries := blocklistCache.Snapshot()
+	etag := `"` + strconv.FormatInt(revision, 10) + `"`
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	resp := models.BlocklistSnapshotResponse{Revision: revision, Entries: make([]models.BlocklistSnapshotEntry, 0, len(entries))}
+	for _, e := range entries {
+		resp.Entries = append(resp.Entries, models.BlocklistSnapshotEntry{
+			Kind:      string(e.Kind),
+			Value:     e.Value,
+			Reason:    e.Reason,
+			ExpiresAt: e.ExpiresAt,
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func validBlocklistKind(kind blocklist.Kind) bool {
+	for _, k := range blocklist.Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func blocklistEntryResponse(e blocklist.Entry) models.BlocklistEntryResponse {
+	return models.BlocklistEntryResponse{
+		ID:        e.ID,
+		Kind:      string(e.Kind),
+		Value:     e.Value,
+		Reason:    e.Reason,
+		ExpiresAt: e.ExpiresAt,
+		CreatedBy: e.CreatedBy,
+		CreatedAt: e.CreatedAt,
+		RemovedAt: e.RemovedAt,
+		RemovedBy: e.RemovedBy,
+	}
+}
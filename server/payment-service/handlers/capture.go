@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/currencies"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/db"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/metrics"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/models"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/payments"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/razorpay"
+)
+
+// CapturePayment captures an authorized manual-capture payment
+// @Summary Capture payment
+// @Description Captures all or part of an authorized payment created with capture_mode=manual. Razorpay refunds any uncaptured remainder.
+// @Tags payments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Payment ID"
+// @Param capture body models.CaptureRequest false "Amount to capture"
+// @Success 200 {object} models.PaymentResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 410 {object} models.ErrorResponse
+// @Failure 502 {object} models.ErrorResponse
+// @Router /api/v1/payments/{id}/capture [post]
+func CapturePayment(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.CaptureRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, r, http.StatusBadRequest, "Invalid request", "Failed to parse request body")
+		return
+	}
+
+	tx, err := db.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to begin transaction", "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to start transaction")
+		return
+	}
+	defer tx.Rollback()
+
+	payment, ok := lockOwnedPayment(w, r, tx, uid, id)
+	if !ok {
+		return
+	}
+	if payment.Expired(time.Now()) {
+		writeError(w, r, http.StatusGone, "Payment expired", "The order expired before it was paid")
+		return
+	}
+	if payment.Status == string(models.PaymentStatusCaptured) {
+		writeJSON(w, http.StatusOK, paymentResponse(payment)) // already captured, e.g. a retried request
+		return
+	}
+	if payment.CaptureMode != string(models.CaptureManual) || payment.Status != string(models.PaymentStatusAuthorized) || payment.RazorpayPaymentID == nil {
+		writeError(w, r, http.StatusConflict, "Invalid state",
+			fmt.Sprintf("Only authorized manual-capture payments can be captured; payment is %s", payment.Status))
+		return
+	}
+
+	cur, err := currencies.Get(r.Context(), tx, payment.Currency)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to load currency", "payment_id", payment.ID, "currency", payment.Currency, "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to load currency")
+		return
+	}
+
+	amount := payment.Amount
+	if req.Amount != nil {
+		amount = *req.Amount
+		if amount <= 0 || !cur.Precise(amount) || cur.ToMinor(amount) > cur.ToMinor(payment.Amount) {
+			writeError(w, r, http.StatusBadRequest, "Invalid amount",
+				fmt.Sprintf("amount must be positive and at most %.2f", payment.Amount))
+			return
+		}
+	}
+
+	// The row stays locked during the gateway call so a concurrent capture,
+	// void or webhook waits for the outcome
+	if _, err := razorpay.CapturePayment(r.Context(), *payment.RazorpayPaymentID, cur.ToMinor(amount), payment.Currency); err != nil {
+		slog.ErrorContext(r.Context(), "Razorpay capture failed", "payment_id", payment.ID, "error", err)
+		writeError(w, r, http.StatusBadGateway, "Capture failed", "Razorpay could not capture the payment")
+		return
+	}
+
+	if _, err := payments.Capture(r.Context(), tx, &payment, amount); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to record capture", "payment_id", payment.ID, "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Captured at Razorpay but failed to record it; it will be reconciled")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		slog.ErrorContext(r.Context(), "Transaction commit failed", "payment_id", payment.ID, "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Transaction error", "Captured at Razorpay but failed to record it; it will be reconciled")
+		return
+	}
+
+	slog.InfoContext(r.Context(), "Payment captured", "payment_id", payment.ID, "amount", amount)
+	writeJSON(w, http.StatusOK, paymentResponse(payment))
+}
+
+// VoidPayment voids an authorized manual-capture payment
+// @Summary Void payment
+// @Description Abandons an authorized payment created with capture_mode=manual. The void is recorded here only: Razorpay has no call to release an authorization and refunds it at the end of its auto-refund window. Until then the response has release_pending; authorization_released_at is set when Razorpay reports the refund.
+// @Tags payments
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Payment ID"
+// @Success 200 {object} models.PaymentResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /api/v1/payments/{id}/void [post]
+func VoidPayment(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	tx, err := db.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to begin transaction", "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to start transaction")
+		return
+	}
+	defer tx.Rollback()
+
+	payment, ok := lockOwnedPayment(w, r, tx, uid, id)
+	if !ok {
+		return
+	}
+	if payment.CaptureMode != string(models.CaptureManual) {
+		writeError(w, r, http.StatusConflict, "Invalid state", "Only manual-capture payments can be voided")
+		return
+	}
+
+	changed, err := payments.Transition(r.Context(), tx, &payment, models.PaymentStatusVoided, "")
+	var transitionErr *payments.TransitionError
+	if errors.As(err, &transitionErr) {
+		writeError(w, r, http.StatusConflict, "Invalid state", transitionErr.Error())
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to void payment", "payment_id", payment.ID, "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to void payment")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		slog.ErrorContext(r.Context(), "Transaction commit failed", "payment_id", payment.ID, "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Transaction error", "Failed to void payment")
+		return
+	}
+
+	if changed {
+		metrics.PaymentVoided("requested")
+		slog.InfoContext(r.Context(), "Payment voided; Razorpay has yet to release the authorization", "payment_id", payment.ID)
+	}
+	writeJSON(w, http.StatusOK, paymentResponse(payment))
+}
+
+// lockOwnedPayment locks payment id for update, writing a 404 unless it
+// belongs to uid.
+func lockOwnedPayment(w http.ResponseWriter, r *http.Request, tx *sql.Tx, uid int, id int64) (models.Payment, bool) {
+	payment, err := payments.LockByID(r.Context(), tx, int(id))
+	if errors.Is(err, payments.ErrNotFound) || (err == nil && payment.UserID != uid) {
+		writeError(w, r, http.StatusNotFound, "Not found", "Payment not found")
+		return models.Payment{}, false
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to load payment", "payment_id", id, "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to load payment")
+		return models.Payment{}, false
+	}
+	return payment, true
+}
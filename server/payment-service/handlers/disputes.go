@@ -0,0 +1,502 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/db"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/disputes"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/internal/config"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/metrics"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/models"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/razorpay"
+	"github.com/gorilla/mux"
+)
+
+// ListDisputes lists the caller's disputes
+// @Summary List disputes
+// @Description Disputes raised against the caller's payments, newest first.
+// @Tags disputes
+// @Produce json
+// @Security BearerAuth
+// @Param phase query string false "created, under_review, won, lost or closed"
+// @Param payment_id query int false "Only disputes of this payment"
+// @Param limit query int false "Maximum results (default 50, max 100)"
+// @Success 200 {array} models.DisputeResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/disputes [get]
+func ListDisputes(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	filter, ok := disputeFilter(w, r)
+	if !ok {
+		return
+	}
+	filter.UserID = uid
+	writeDisputes(w, r, filter)
+}
+
+// GetDispute returns one of the caller's disputes with its evidence
+// @Summary Get a dispute
+// @Tags disputes
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Dispute ID"
+// @Success 200 {object} models.DisputeDetailResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/disputes/{id} [get]
+func GetDispute(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	writeDispute(w, r, uid)
+}
+
+// AddDisputeEvidence submits evidence against a dispute
+// @Summary Add dispute evidence
+// @Description Adds a note as JSON, or a file as multipart/form-data with a "file" part and optional "category" and "note" fields. Files are limited to DISPUTE_EVIDENCE_MAX_BYTES and a dispute to 20 items. Evidence is accepted until the dispute is resolved or its respond_by time passes.
+// @Tags disputes
+// @Accept json,mpfd
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Dispute ID"
+// @Param evidence body models.DisputeEvidenceRequest false "A note"
+// @Param file formData file false "A document"
+// @Success 201 {object} models.DisputeEvidenceResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/disputes/{id}/evidence [post]
+func AddDisputeEvidence(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	evidence := disputes.Evidence{UploadedBy: uid}
+	var content []byte
+	maxBytes := config.Get().DisputeEvidenceMaxBytes
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		// Room for the form fields and part headers around the file
+		r.Body = http.MaxBytesReader(w, r.Body, int64(maxBytes)+64<<10)
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeError(w, r, http.StatusRequestEntityTooLarge, "File too large", fmt.Sprintf("Evidence files must be at most %d bytes", maxBytes))
+				return
+			}
+			writeError(w, r, http.StatusBadRequest, "Invalid request", "Failed to parse multipart form")
+			return
+		}
+		defer r.MultipartForm.RemoveAll()
+
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "Invalid request", `A "file" part is required`)
+			return
+		}
+		defer file.Close()
+		if header.Size > int64(maxBytes) {
+			writeError(w, r, http.StatusRequestEntityTooLarge, "File too large", fmt.Sprintf("Evidence files must be at most %d bytes", maxBytes))
+			return
+		}
+		if content, err = io.ReadAll(file); err != nil {
+			writeError(w, r, http.StatusBadRequest, "Invalid request", "Failed to read file")
+			return
+		}
+		evidence.Category = r.FormValue("category")
+		evidence.Note = r.FormValue("note")
+		evidence.Filename = header.Filename
+		evidence.ContentType = header.Header.Get("Content-Type")
+		if evidence.ContentType == "" {
+			evidence.ContentType = http.DetectContentType(content)
+		}
+	} else {
+		var req models.DisputeEvidenceRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, r, http.StatusBadRequest, "Invalid request", "Failed to parse request body")
+			return
+		}
+		evidence.Category = req.Category
+		evidence.Note = req.Note
+	}
+	if err := disputes.ValidateEvidence(&evidence, content); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid evidence", err.Error())
+		return
+	}
+
+	tx, err := db.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to begin transaction", "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to start transaction")
+		return
+	}
+	defer tx.Rollback()
+
+	evidence, err = disputes.AddEvidence(r.Context(), tx, uid, id, evidence, content)
+	switch {
+	case errors.Is(err, disputes.ErrNotFound):
+		writeError(w, r, http.StatusNotFound, "Not found", "Dispute not found")
+		return
+	case errors.Is(err, disputes.ErrClosed):
+		writeError(w, r, http.StatusConflict, "Dispute resolved", "The dispute has been resolved and takes no more evidence")
+		return
+	case errors.Is(err, disputes.ErrPastDeadline):
+		writeError(w, r, http.StatusConflict, "Deadline passed", "Evidence for this dispute was due by its respond_by time")
+		return
+	case errors.Is(err, disputes.ErrTooMuchEvidence):
+		writeError(w, r, http.StatusConflict, "Too much evidence", fmt.Sprintf("A dispute can hold at most %d evidence items", disputes.MaxEvidence))
+		return
+	case err != nil:
+		slog.ErrorContext(r.Context(), "Failed to add dispute evidence", "dispute_id", id, "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Could not add evidence")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		slog.ErrorContext(r.Context(), "Transaction commit failed", "dispute_id", id, "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Transaction error", "Could not add evidence")
+		return
+	}
+
+	slog.InfoContext(r.Context(), "Dispute evidence added",
+		"dispute_id", id,
+		"evidence_id", evidence.ID,
+		"kind", evidence.Kind,
+		"size_bytes", evidence.Size,
+	)
+	writeJSON(w, http.StatusCreated, evidenceResponse(evidence))
+}
+
+// GetDisputeEvidenceFile downloads a file submitted against a dispute
+// @Summary Download dispute evidence
+// @Tags disputes
+// @Produce octet-stream
+// @Security BearerAuth
+// @Param id path int true "Dispute ID"
+// @Param evidence_id path int true "Evidence ID"
+// @Success 200 {file} binary
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/disputes/{id}/evidence/{evidence_id}/file [get]
+func GetDisputeEvidenceFile(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	writeEvidenceFile(w, r, uid)
+}
+
+// AdminListDisputes lists every user's disputes
+// @Summary List disputes
+// @Description Every user's disputes, newest first. due_before lists only open disputes whose evidence is due before then, soonest first.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param user_id query int false "Only this user's disputes"
+// @Param phase query string false "created, under_review, won, lost or closed"
+// @Param payment_id query int false "Only disputes of this payment"
+// @Param due_before query string false "YYYY-MM-DD includes that day, RFC 3339 is exclusive"
+// @Param limit query int false "Maximum results (default 50, max 100)"
+// @Success 200 {array} models.DisputeResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/admin/disputes [get]
+func AdminListDisputes(w http.ResponseWriter, r *http.Request) {
+	filter, ok := disputeFilter(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	if v := q.Get("user_id"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(w, r, http.StatusBadRequest, "Invalid request", "user_id must be a positive integer")
+			return
+		}
+		filter.UserID = n
+	}
+	if s := q.Get("due_before"); s != "" {
+		t, dateOnly, err := parseStatementTime(s)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "Invalid request", "due_before must be YYYY-MM-DD or an RFC 3339 time")
+			return
+		}
+		if dateOnly {
+			t = t.AddDate(0, 0, 1)
+		}
+		filter.DueBefore = t
+	}
+	writeDisputes(w, r, filter)
+}
+
+// AdminGetDispute returns any dispute with its evidence
+// @Summary Get a dispute
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Dispute ID"
+// @Success 200 {object} models.DisputeDetailResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/admin/disputes/{id} [get]
+func AdminGetDispute(w http.ResponseWriter, r *http.Request) {
+	writeDispute(w, r, 0)
+}
+
+// AdminGetDisputeEvidenceFile downloads a file submitted against any dispute
+// @Summary Download dispute evidence
+// @Tags admin
+// @Produce octet-stream
+// @Security BearerAuth
+// @Param id path int true "Dispute ID"
+// @Param evidence_id path int true "Evidence ID"
+// @Success 200 {file} binary
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/admin/disputes/{id}/evidence/{evidence_id}/file [get]
+func AdminGetDisputeEvidenceFile(w http.ResponseWriter, r *http.Request) {
+	writeEvidenceFile(w, r, 0)
+}
+
+// disputeFilter reads the filters shared by the user and admin listings,
+// writing a 400 if one is invalid.
+func disputeFilter(w http.ResponseWriter, r *http.Request) (disputes.Filter, bool) {
+	q := r.URL.Query()
+	var filter disputes.Filter
+	switch phase := disputes.Phase(q.Get("phase")); phase {
+	case "", disputes.PhaseCreated, disputes.PhaseUnderReview, disputes.PhaseWon, disputes.PhaseLost, disputes.PhaseClosed:
+		filter.Phase = phase
+	default:
+		writeError(w, r, http.StatusBadRequest, "Invalid request", "phase must be created, under_review, won, lost or closed")
+		return disputes.Filter{}, false
+	}
+	if v := q.Get("payment_id"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(w, r, http.StatusBadRequest, "Invalid request", "payment_id must be a positive integer")
+			return disputes.Filter{}, false
+		}
+		filter.PaymentID = n
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "Invalid request", "limit must be an integer")
+			return disputes.Filter{}, false
+		}
+		filter.Limit = n
+	}
+	return filter, true
+}
+
+func writeDisputes(w http.ResponseWriter, r *http.Request, filter disputes.Filter) {
+	list, err := disputes.List(r.Context(), db.DB, filter)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to list disputes", "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Could not list disputes")
+		return
+	}
+	now := time.Now()
+	resp := make([]models.DisputeResponse, 0, len(list))
+	for _, d := range list {
+		resp = append(resp, disputeResponse(d, now))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// writeDispute writes dispute {id} with its evidence; a userID of 0 reads
+// any user's dispute.
+func writeDispute(w http.ResponseWriter, r *http.Request, userID int) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	d, err := disputes.Get(r.Context(), db.DB, userID, id)
+	if errors.Is(err, disputes.ErrNotFound) {
+		writeError(w, r, http.StatusNotFound, "Not found", "Dispute not found")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to load dispute", "dispute_id", id, "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to load dispute")
+		return
+	}
+	evidence, err := disputes.ListEvidence(r.Context(), db.DB, d.ID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to list dispute evidence", "dispute_id", id, "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to load evidence")
+		return
+	}
+	resp := models.DisputeDetailResponse{
+		DisputeResponse: disputeResponse(d, time.Now()),
+		Evidence:        make([]models.DisputeEvidenceResponse, 0, len(evidence)),
+	}
+	for _, e := range evidence {
+		resp.Evidence = append(resp.Evidence, evidenceResponse(e))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// writeEvidenceFile writes the file evidence {evidence_id} of dispute {id};
+// a userID of 0 reads any user's dispute.
+func writeEvidenceFile(w http.ResponseWriter, r *http.Request, userID int) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	evidenceID, err := strconv.ParseInt(mux.Vars(r)["evidence_id"], 10, 64)
+	if err != nil || evidenceID <= 0 {
+		writeError(w, r, http.StatusBadRequest, "Invalid request", "Invalid evidence ID in path")
+		return
+	}
+	if _, err := disputes.Get(r.Context(), db.DB, userID, id); err != nil {
+		if errors.Is(err, disputes.ErrNotFound) {
+			writeError(w, r, http.StatusNotFound, "Not found", "Dispute not found")
+			return
+		}
+		slog.ErrorContext(r.Context(), "Failed to load dispute", "dispute_id", id, "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to load dispute")
+		return
+	}
+	e, content, err := disputes.EvidenceFile(r.Context(), db.DB, id, evidenceID)
+	if errors.Is(err, disputes.ErrEvidenceNotFound) {
+		writeError(w, r, http.StatusNotFound, "Not found", "Evidence file not found")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to load evidence file", "dispute_id", id, "evidence_id", evidenceID, "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to load evidence file")
+		return
+	}
+
+	// Served as a download, never rendered, whatever type was uploaded
+	w.Header().Set("Content-Type", e.ContentType)
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": e.Filename})
+	if disposition == "" {
+		disposition = "attachment"
+	}
+	w.Header().Set("Content-Disposition", disposition)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(content)
+}
+
+// applyDisputeWebhook applies a verified payment.dispute.* event from
+// Razorpay to the local dispute and its payment.
+func applyDisputeWebhook(w http.ResponseWriter, r *http.Request, event razorpay.WebhookEvent) {
+	tx, err := db.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to begin transaction", "error", err)
+		metrics.GatewayWebhook(event.Event, "error")
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to start transaction")
+		return
+	}
+	defer tx.Rollback()
+
+	d, changed, err := disputes.ApplyWebhook(r.Context(), tx, event)
+	if errors.Is(err, disputes.ErrNotFound) || errors.Is(err, disputes.ErrUnknownStatus) {
+		// Payments created outside this service share the account
+		slog.WarnContext(r.Context(), "Ignoring Razorpay dispute webhook", "event", event.Event, "reason", err)
+		metrics.GatewayWebhook(event.Event, "ignored")
+		writeWebhookAck(w, "ignored")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to apply dispute webhook", "event", event.Event, "error", err)
+		metrics.GatewayWebhook(event.Event, "error")
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to update dispute")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		slog.ErrorContext(r.Context(), "Transaction commit failed", "dispute_id", d.ID, "error", err)
+		metrics.GatewayWebhook(event.Event, "error")
+		writeError(w, r, http.StatusInternalServerError, "Transaction error", "Failed to update dispute")
+		return
+	}
+
+	if !changed {
+		metrics.GatewayWebhook(event.Event, "duplicate")
+		writeWebhookAck(w, "duplicate")
+		return
+	}
+	slog.InfoContext(r.Context(), "Dispute updated from Razorpay webhook",
+		"event", event.Event,
+		"dispute_id", d.ID,
+		"payment_id", d.PaymentID,
+		"phase", d.Phase,
+	)
+	metrics.DisputePhase(string(d.Phase))
+	metrics.GatewayWebhook(event.Event, "applied")
+	writeWebhookAck(w, "applied")
+}
+
+func disputeResponse(d disputes.Dispute, now time.Time) models.DisputeResponse {
+	return models.DisputeResponse{
+		ID:                d.ID,
+		RazorpayDisputeID: d.RazorpayDisputeID,
+		PaymentID:         d.PaymentID,
+		UserID:            d.UserID,
+		Amount:            d.Amount,
+		Currency:          d.Currency,
+		AmountDeducted:    d.AmountDeducted,
+		ReasonCode:        d.ReasonCode,
+		ReasonDescription: d.ReasonDescription,
+		GatewayPhase:      d.GatewayPhase,
+		Phase:             string(d.Phase),
+		RespondBy:         d.RespondBy,
+		AcceptsEvidence:   d.AcceptsEvidence(now),
+		ResolvedAt:        d.ResolvedAt,
+		CreatedAt:         d.CreatedAt,
+		UpdatedAt:         d.UpdatedAt,
+	}
+}
+
+func evidenceResponse(e disputes.Evidence) models.DisputeEvidenceResponse {
+	return models.DisputeEvidenceResponse{
+		ID:          e.ID,
+		DisputeID:   e.DisputeID,
+		Kind:        string(e.Kind),
+		Category:    e.Category,
+		Note:        e.Note,
+		Filename:    e.Filename,
+		ContentType: e.ContentType,
+		SizeBytes:   e.Size,
+		SHA256:      e.SHA256,
+		UploadedBy:  e.UploadedBy,
+		CreatedAt:   e.CreatedAt,
+	}
+}
@@ -0,0 +1,389 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/audit"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/currencies"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/db"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/fx"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/internal/config"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/invoices"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/middleware"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/models"
+	"github.com/gorilla/mux"
+)
+
+// maxRatesPerRequest bounds POST /admin/fx/rates; larger sets belong in the
+// rates file.
+const maxRatesPerRequest = 500
+
+// ListCurrencies lists the currency registry
+// @Summary List currencies
+// @Description Lists the currencies /pay accepts. Admins may pass all=true to include disabled ones.
+// @Tags fx
+// @Produce json
+// @Security BearerAuth
+// @Param all query bool false "Include disabled currencies (admin only)"
+// @Success 200 {array} models.CurrencyResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/currencies [get]
+func ListCurrencies(w http.ResponseWriter, r *http.Request) {
+	all := r.URL.Query().Get("all") == "true" && middleware.IsAdmin(r.Context())
+	list, err := currencies.List(r.Context(), db.DB, !all)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to list currencies", "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to list currencies")
+		return
+	}
+
+	resp := make([]models.CurrencyResponse, 0, len(list))
+	for _, c := range list {
+		resp = append(resp, currencyResponse(c))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// PutCurrency adds or updates a currency
+// @Summary Add or update currency
+// @Description Adds the currency to the registry or replaces its name, exponent and enabled flag. Disabling a currency stops new payments in it; existing payments are unaffected. Change the exponent only before the currency is used; it cannot change once invoices are in the currency.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param code path string true "ISO 4217 code"
+// @Param currency body models.CurrencyRequest true "Currency"
+// @Success 200 {object} models.CurrencyResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/admin/currencies/{code} [put]
+func PutCurrency(w http.ResponseWriter, r *http.Request) {
+	var req models.CurrencyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request", "Failed to parse request body")
+		return
+	}
+	if req.Exponent == nil || req.Enabled == nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid currency", "exponent and enabled are required")
+		return
+	}
+
+	c := currencies.Currency{
+		Code:     mux.Vars(r)["code"],
+		Name:     req.Name,
+		Exponent: *req.Exponent,
+		Enabled:  *req.Enabled,
+	}
+	if err := currencies.Normalize(&c); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid currency", err.Error())
+		return
+	}
+
+	var before any
+	prev, err := currencies.Get(r.Context(), db.DB, c.Code)
+	if err == nil {
+		before = currencyResponse(prev)
+	} else if !errors.Is(err, currencies.ErrNotFound) {
+		slog.ErrorContext(r.Context(), "Failed to load currency", "code", c.Code, "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Could not save currency")
+		return
+	}
+	if err == nil && prev.Exponent != c.Exponent {
+		used, err := invoices.InCurrency(r.Context(), db.DB, c.Code)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Failed to check currency use", "code", c.Code, "error", err)
+			writeError(w, r, http.StatusInternalServerError, "Database error", "Could not save currency")
+			return
+		}
+		if used {
+			writeError(w, r, http.StatusConflict, "Currency in use",
+				"exponent cannot change while invoices are in "+c.Code+"; their amounts are stored in its minor unit")
+			return
+		}
+	}
+
+	saved, err := currencies.Put(r.Context(), db.DB, c)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to save currency", "code", c.Code, "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Could not save currency")
+		return
+	}
+
+	slog.InfoContext(r.Context(), "Currency saved", "code", saved.Code, "exponent", saved.Exponent, "enabled", saved.Enabled)
+	resp := currencyResponse(saved)
+	recordAudit(r, audit.Event{
+		Action:       "admin.currency.saved",
+		ResourceType: "currency",
+		ResourceID:   saved.Code,
+		Before:       before,
+		After:        resp,
+	})
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// CreateFXRates adds exchange rates
+// @Summary Add exchange rates
+// @Description Stores rates as of their effective_at (default now). A rate already stored for the same pair and effective_at is skipped. Rates are never edited: add a newer one instead.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param rates body models.FXRatesRequest true "Rates"
+// @Success 201 {object} models.FXRatesResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/admin/fx/rates [post]
+func CreateFXRates(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.FXRatesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request", "Failed to parse request body")
+		return
+	}
+	if len(req.Rates) == 0 || len(req.Rates) > maxRatesPerRequest {
+		writeError(w, r, http.StatusBadRequest, "Invalid rates", fmt.Sprintf("rates must hold 1 to %d rates", maxRatesPerRequest))
+		return
+	}
+
+	now := time.Now().UTC()
+	rates := make([]fx.Rate, 0, len(req.Rates))
+	for i, rr := range req.Rates {
+		rate := fx.Rate{Base: rr.Base, Quote: rr.Quote, Rate: rr.Rate, EffectiveAt: now, Source: fx.SourceAdmin, CreatedBy: &uid}
+		if rr.EffectiveAt != nil {
+			rate.EffectiveAt = *rr.EffectiveAt
+		}
+		if err := fx.Normalize(&rate); err != nil {
+			writeError(w, r, http.StatusBadRequest, "Invalid rates", fmt.Sprintf("rates[%d]: %s", i, err))
+			return
+		}
+		rates = append(rates, rate)
+	}
+
+	inserted, err := fx.SaveRates(r.Context(), db.DB, rates)
+	if errors.Is(err, fx.ErrUnknownCurrency) {
+		writeError(w, r, http.StatusBadRequest, "Invalid rates", err.Error())
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to save exchange rates", "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Could not save rates")
+		return
+	}
+
+	slog.InfoContext(r.Context(), "Exchange rates added", "count", len(rates), "inserted", inserted)
+	recordAudit(r, audit.Event{
+		Action:       "admin.fx_rates.added",
+		ResourceType: "fx_rates",
+		After:        req,
+		Detail:       fmt.Sprintf("%d of %d rates inserted", inserted, len(rates)),
+	})
+	writeJSON(w, http.StatusCreated, models.FXRatesResponse{Inserted: inserted})
+}
+
+// ListFXRates lists the current exchange rates
+// @Summary List exchange rates
+// @Description Lists the rate in effect now for every pair with a rate. Pairs without a direct rate are quoted from the inverse pair.
+// @Tags fx
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.FXRateResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/fx/rates [get]
+func ListFXRates(w http.ResponseWriter, r *http.Request) {
+	rates, err := fx.LatestRates(r.Context(), db.DB, time.Now())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to list exchange rates", "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to list rates")
+		return
+	}
+
+	resp := make([]models.FXRateResponse, 0, len(rates))
+	for _, rate := range rates {
+		resp = append(resp, models.FXRateResponse{
+			ID:          rate.ID,
+			Base:        rate.Base,
+			Quote:       rate.Quote,
+			Rate:        rate.Rate,
+			EffectiveAt: rate.EffectiveAt,
+			Source:      string(rate.Source),
+			CreatedBy:   rate.CreatedBy,
+			CreatedAt:   rate.CreatedAt,
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// CreateFXQuote prices a conversion and locks the rate
+// @Summary Create FX quote
+// @Description Converts amount of from into to at the current rate, rounded to to's minor unit, and locks it until expires_at. Pass the quote's id as quote_id to POST /pay to charge amount in from and credit converted_amount in to. Each quote pays for one payment.
+// @Tags fx
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param quote body models.FXQuoteRequest true "Conversion"
+// @Success 201 {object} models.FXQuoteResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/fx/quotes [post]
+func CreateFXQuote(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.FXQuoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request", "Failed to parse request body")
+		return
+	}
+	from, ok := supportedCurrency(w, r, req.From, req.Amount)
+	if !ok {
+		return
+	}
+	to, ok := supportedCurrency(w, r, req.To, 0)
+	if !ok {
+		return
+	}
+	if from.Code == to.Code {
+		writeError(w, r, http.StatusBadRequest, "Invalid quote", "from and to must differ")
+		return
+	}
+	if req.Amount <= 0 {
+		writeError(w, r, http.StatusBadRequest, "Invalid amount", "Amount must be positive")
+		return
+	}
+
+	cfg := config.Get()
+	quote, err := fx.NewQuote(r.Context(), db.DB, uid, from, to, req.Amount, time.Now().UTC(), cfg.FXQuoteTTL, cfg.FXRateMaxAge)
+	switch {
+	case errors.Is(err, fx.ErrNoRate):
+		slog.WarnContext(r.Context(), "No exchange rate for quote", "from", from.Code, "to", to.Code, "error", err)
+		writeError(w, r, http.StatusUnprocessableEntity, "No rate", fmt.Sprintf("No current exchange rate for %s to %s", from.Code, to.Code))
+		return
+	case errors.Is(err, fx.ErrTooSmall):
+		writeError(w, r, http.StatusBadRequest, "Invalid amount", err.Error())
+		return
+	case err != nil:
+		slog.ErrorContext(r.Context(), "Failed to create quote", "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Could not create quote")
+		return
+	}
+
+	slog.InfoContext(r.Context(), "FX quote created", "quote_id", quote.ID, "from", quote.From, "to", quote.To, "rate", quote.Rate)
+	writeJSON(w, http.StatusCreated, quoteResponse(quote))
+}
+
+// GetFXQuote returns one of the caller's quotes
+// @Summary Get FX quote
+// @Tags fx
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Quote ID"
+// @Success 200 {object} models.FXQuoteResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/fx/quotes/{id} [get]
+func GetFXQuote(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	quote, err := fx.GetQuote(r.Context(), db.DB, uid, id)
+	if errors.Is(err, fx.ErrQuoteNotFound) {
+		writeError(w, r, http.StatusNotFound, "Not found", "Quote not found")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to load quote", "quote_id", id, "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to load quote")
+		return
+	}
+	writeJSON(w, http.StatusOK, quoteResponse(quote))
+}
+
+// supportedCurrency looks up code in the registry and checks amount has no
+// more decimals than it has minor units. It writes a 400 and reports false
+// if the currency is unknown or disabled or the amount too precise.
+func supportedCurrency(w http.ResponseWriter, r *http.Request, code string, amount float64) (currencies.Currency, bool) {
+	normalized, err := currencies.NormalizeCode(code)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid currency", err.Error())
+		return currencies.Currency{}, false
+	}
+	c, err := currencies.Supported(r.Context(), db.DB, normalized)
+	if errors.Is(err, currencies.ErrUnsupported) {
+		writeError(w, r, http.StatusBadRequest, "Invalid currency", fmt.Sprintf("%s is not a supported currency", normalized))
+		return currencies.Currency{}, false
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to load currency", "currency", normalized, "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to load currency")
+		return currencies.Currency{}, false
+	}
+	if !c.Precise(amount) {
+		writeError(w, r, http.StatusBadRequest, "Invalid amount",
+			fmt.Sprintf("%s amounts have at most %d decimal places", c.Code, c.Exponent))
+		return currencies.Currency{}, false
+	}
+	return c, true
+}
+
+func currencyResponse(c currencies.Currency) models.CurrencyResponse {
+	return models.CurrencyResponse{
+		Code:      c.Code,
+		Name:      c.Name,
+		Exponent:  c.Exponent,
+		Enabled:   c.Enabled,
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+	}
+}
+
+func quoteResponse(q fx.Quote) models.FXQuoteResponse {
+	return models.FXQuoteResponse{
+		ID:              q.ID,
+		From:            q.From,
+		To:              q.To,
+		Rate:            q.Rate,
+		Amount:          q.Amount,
+		ConvertedAmount: q.ConvertedAmount,
+		ExpiresAt:       q.ExpiresAt,
+		PaymentID:       q.PaymentID,
+		UsedAt:          q.UsedAt,
+		CreatedAt:       q.CreatedAt,
+	}
+}
+
+func conversionResponse(q fx.Quote) *models.ConversionResponse {
+	return &models.ConversionResponse{
+		QuoteID:          q.ID,
+		Rate:             q.Rate,
+		CreditedAmount:   q.ConvertedAmount,
+		CreditedCurrency: q.To,
+	}
+}
@@ -0,0 +1,475 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/currencies"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/db"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/invoices"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/models"
+)
+
+// CreateInvoice creates a draft invoice
+// @Summary Create invoice
+// @Description Creates a draft invoice. Line amounts and GST are computed in minor units: CGST and SGST when the place of supply is the supplier's state, IGST otherwise.
+// @Tags invoices
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param invoice body models.InvoiceRequest true "Invoice"
+// @Success 201 {object} models.InvoiceResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/invoices [post]
+func CreateInvoice(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	reg, ok := invoiceCurrencies(w, r)
+	if !ok {
+		return
+	}
+	inv, ok := decodeInvoice(w, r, reg)
+	if !ok {
+		return
+	}
+	inv.UserID = uid
+
+	inv, err := invoices.Create(r.Context(), db.DB, inv)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to create invoice", "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Could not save invoice")
+		return
+	}
+
+	slog.InfoContext(r.Context(), "Invoice created", "invoice_id", inv.ID, "total_minor", inv.TotalMinor)
+	writeJSON(w, http.StatusCreated, invoiceResponse(inv, reg))
+}
+
+// ListInvoices lists the caller's invoices
+// @Summary List invoices
+// @Description Lists invoices newest first, without their line items.
+// @Tags invoices
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.InvoiceResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/invoices [get]
+func ListInvoices(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+
+	reg, ok := invoiceCurrencies(w, r)
+	if !ok {
+		return
+	}
+
+	list, err := invoices.List(r.Context(), db.DB, uid)
+	if !invoiceFound(w, r, err) {
+		return
+	}
+	resp := make([]models.InvoiceResponse, 0, len(list))
+	for _, inv := range list {
+		resp = append(resp, invoiceResponse(inv, reg))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// GetInvoice returns one of the caller's invoices
+// @Summary Get invoice
+// @Tags invoices
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Invoice ID"
+// @Success 200 {object} models.InvoiceResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/invoices/{id} [get]
+func GetInvoice(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	reg, ok := invoiceCurrencies(w, r)
+	if !ok {
+		return
+	}
+
+	inv, err := invoices.Get(r.Context(), db.DB, uid, id)
+	if !invoiceFound(w, r, err) {
+		return
+	}
+	writeJSON(w, http.StatusOK, invoiceResponse(inv, reg))
+}
+
+// UpdateInvoice replaces a draft invoice
+// @Summary Update draft invoice
+// @Description Replaces the contents of a draft, items included. Issued invoices cannot be changed.
+// @Tags invoices
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Invoice ID"
+// @Param invoice body models.InvoiceRequest true "Invoice"
+// @Success 200 {object} models.InvoiceResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/invoices/{id} [patch]
+func UpdateInvoice(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	reg, ok := invoiceCurrencies(w, r)
+	if !ok {
+		return
+	}
+	inv, ok := decodeInvoice(w, r, reg)
+	if !ok {
+		return
+	}
+
+	inv, err := invoices.Update(r.Context(), db.DB, uid, id, inv)
+	if !invoiceFound(w, r, err) {
+		return
+	}
+	writeJSON(w, http.StatusOK, invoiceResponse(inv, reg))
+}
+
+// IssueInvoice issues a draft invoice
+// @Summary Issue invoice
+// @Description Assigns the caller's next sequential invoice number and makes the draft final.
+// @Tags invoices
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Invoice ID"
+// @Param issue body models.IssueInvoiceRequest false "Due date"
+// @Success 200 {object} models.InvoiceResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/invoices/{id}/issue [post]
+func IssueInvoice(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	reg, ok := invoiceCurrencies(w, r)
+	if !ok {
+		return
+	}
+
+	// The body is optional
+	var req models.IssueInvoiceRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, r, http.StatusBadRequest, "Invalid request", "Failed to parse request body")
+			return
+		}
+	}
+	now := time.Now().UTC()
+	if req.DueAt != nil && req.DueAt.Before(now) {
+		writeError(w, r, http.StatusBadRequest, "Invalid due_at", "due_at must not be in the past")
+		return
+	}
+
+	inv, err := invoices.Issue(r.Context(), db.DB, uid, id, now, req.DueAt)
+	if !invoiceFound(w, r, err) {
+		return
+	}
+	slog.InfoContext(r.Context(), "Invoice issued", "invoice_id", inv.ID, "number", inv.DisplayNumber())
+	writeJSON(w, http.StatusOK, invoiceResponse(inv, reg))
+}
+
+// VoidInvoice voids an invoice
+// @Summary Void invoice
+// @Description Voids a draft or issued invoice; it keeps its number. Paid invoices cannot be voided.
+// @Tags invoices
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Invoice ID"
+// @Success 200 {object} models.InvoiceResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/invoices/{id}/void [post]
+func VoidInvoice(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	reg, ok := invoiceCurrencies(w, r)
+	if !ok {
+		return
+	}
+
+	inv, err := invoices.Void(r.Context(), db.DB, uid, id)
+	if !invoiceFound(w, r, err) {
+		return
+	}
+	slog.InfoContext(r.Context(), "Invoice voided", "invoice_id", inv.ID)
+	writeJSON(w, http.StatusOK, invoiceResponse(inv, reg))
+}
+
+// LinkInvoicePayment settles an invoice with a payment
+// @Summary Link invoice payment
+// @Description Links one of the caller's payments for the invoice's total to an issued invoice. The invoice becomes paid when the payment is captured, immediately if it already is.
+// @Tags invoices
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Invoice ID"
+// @Param payment body models.LinkInvoicePaymentRequest true "Payment"
+// @Success 200 {object} models.InvoiceResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/invoices/{id}/payment [post]
+func LinkInvoicePayment(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	reg, ok := invoiceCurrencies(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.LinkInvoicePaymentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.PaymentID <= 0 {
+		writeError(w, r, http.StatusBadRequest, "Invalid request", "payment_id is required")
+		return
+	}
+
+	inv, err := invoices.LinkPayment(r.Context(), db.DB, uid, id, req.PaymentID)
+	if !invoiceFound(w, r, err) {
+		return
+	}
+	slog.InfoContext(r.Context(), "Invoice payment linked", "invoice_id", inv.ID, "payment_id", req.PaymentID, "status", inv.Status)
+	writeJSON(w, http.StatusOK, invoiceResponse(inv, reg))
+}
+
+// GetInvoicePDF renders an invoice as a PDF
+// @Summary Download invoice PDF
+// @Tags invoices
+// @Produce application/pdf
+// @Security BearerAuth
+// @Param id path int true "Invoice ID"
+// @Success 200 {file} file
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/invoices/{id}.pdf [get]
+func GetInvoicePDF(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	reg, ok := invoiceCurrencies(w, r)
+	if !ok {
+		return
+	}
+
+	inv, err := invoices.Get(r.Context(), db.DB, uid, id)
+	if !invoiceFound(w, r, err) {
+		return
+	}
+
+	// Render fully before writing so a failure can still be reported
+	var buf bytes.Buffer
+	if err := invoices.Render(&buf, inv, reg.Lookup(inv.Currency)); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to render invoice", "invoice_id", inv.ID, "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Internal error", "Could not render invoice")
+		return
+	}
+
+	filename := fmt.Sprintf("invoice-draft-%d.pdf", inv.ID)
+	if inv.Number != nil {
+		filename = inv.DisplayNumber() + ".pdf"
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", `inline; filename="`+filename+`"`)
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// invoiceCurrencies loads the currency registry, which sets the minor unit
+// of invoice amounts, writing a 500 if it cannot.
+func invoiceCurrencies(w http.ResponseWriter, r *http.Request) (currencies.Registry, bool) {
+	reg, err := currencies.Load(r.Context(), db.DB)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to load currencies", "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Could not load currencies")
+		return nil, false
+	}
+	return reg, true
+}
+
+// decodeInvoice reads and validates an InvoiceRequest, writing a 400 if it
+// is invalid.
+func decodeInvoice(w http.ResponseWriter, r *http.Request, reg currencies.Registry) (invoices.Invoice, bool) {
+	var req models.InvoiceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request", "Failed to parse request body")
+		return invoices.Invoice{}, false
+	}
+	if req.Currency == "" {
+		req.Currency = "INR"
+	}
+
+	inv := invoices.Invoice{
+		Currency:        req.Currency,
+		SupplierName:    req.SupplierName,
+		SupplierGSTIN:   req.SupplierGSTIN,
+		SupplierAddress: req.SupplierAddress,
+		SupplierState:   req.SupplierState,
+		PlaceOfSupply:   req.PlaceOfSupply,
+		CustomerName:    req.CustomerName,
+		CustomerEmail:   req.CustomerEmail,
+		CustomerGSTIN:   req.CustomerGSTIN,
+		CustomerAddress: req.CustomerAddress,
+		Notes:           req.Notes,
+	}
+	cur := reg.Lookup(strings.TrimSpace(req.Currency))
+	for i, it := range req.Items {
+		if !cur.Precise(it.UnitPrice) {
+			writeError(w, r, http.StatusBadRequest, "Invalid invoice",
+				fmt.Sprintf("item %d: unit_price has more decimals than %s allows", i+1, cur.Code))
+			return invoices.Invoice{}, false
+		}
+		inv.Items = append(inv.Items, invoices.Item{
+			Description:    it.Description,
+			HSNSAC:         it.HSNSAC,
+			Quantity:       it.Quantity,
+			UnitPriceMinor: cur.ToMinor(it.UnitPrice),
+			TaxRate:        it.TaxRate,
+		})
+	}
+	if err := invoices.Validate(&inv, cur); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid invoice", err.Error())
+		return invoices.Invoice{}, false
+	}
+	return inv, true
+}
+
+// invoiceFound writes the error response for a failed invoice lookup or
+// operation and reports whether err was nil.
+func invoiceFound(w http.ResponseWriter, r *http.Request, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, invoices.ErrNotFound):
+		writeError(w, r, http.StatusNotFound, "Not found", "Invoice not found")
+	case errors.Is(err, invoices.ErrInvalidState):
+		writeError(w, r, http.StatusConflict, "Invalid state", err.Error())
+	case errors.Is(err, invoices.ErrPaymentMismatch):
+		writeError(w, r, http.StatusBadRequest, "Invalid payment", err.Error())
+	default:
+		slog.ErrorContext(r.Context(), "Invoice request failed", "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Internal error", "Could not complete invoice request")
+	}
+	return false
+}
+
+func invoiceResponse(inv invoices.Invoice, reg currencies.Registry) models.InvoiceResponse {
+	cur := reg.Lookup(inv.Currency)
+	resp := models.InvoiceResponse{
+		ID:              inv.ID,
+		Number:          inv.DisplayNumber(),
+		Status:          string(inv.Status),
+		Currency:        inv.Currency,
+		SupplierName:    inv.SupplierName,
+		SupplierGSTIN:   inv.SupplierGSTIN,
+		SupplierAddress: inv.SupplierAddress,
+		SupplierState:   inv.SupplierState,
+		PlaceOfSupply:   inv.PlaceOfSupply,
+		CustomerName:    inv.CustomerName,
+		CustomerEmail:   inv.CustomerEmail,
+		CustomerGSTIN:   inv.CustomerGSTIN,
+		CustomerAddress: inv.CustomerAddress,
+		Notes:           inv.Notes,
+		Subtotal:        cur.FromMinor(inv.SubtotalMinor),
+		CGST:            cur.FromMinor(inv.CGSTMinor),
+		SGST:            cur.FromMinor(inv.SGSTMinor),
+		IGST:            cur.FromMinor(inv.IGSTMinor),
+		Total:           cur.FromMinor(inv.TotalMinor),
+		TotalMinor:      inv.TotalMinor,
+		DueAt:           inv.DueAt,
+		IssuedAt:        inv.IssuedAt,
+		PaidAt:          inv.PaidAt,
+		VoidedAt:        inv.VoidedAt,
+		PaymentID:       inv.PaymentID,
+		CreatedAt:       inv.CreatedAt,
+		UpdatedAt:       inv.UpdatedAt,
+	}
+	for _, it := range inv.Items {
+		resp.Items = append(resp.Items, models.InvoiceItemResponse{
+			Description: it.Description,
+			HSNSAC:      it.HSNSAC,
+			Quantity:    it.Quantity,
+			UnitPrice:   cur.FromMinor(it.UnitPriceMinor),
+			TaxRate:     it.TaxRate,
+			Amount:      cur.FromMinor(it.AmountMinor),
+			CGST:        cur.FromMinor(it.CGSTMinor),
+			SGST:        cur.FromMinor(it.SGSTMinor),
+			IGST:        cur.FromMinor(it.IGSTMinor),
+		})
+	}
+	return resp
+}
@@ -0,0 +1,320 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/audit"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/db"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/limits"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/middleware"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/models"
+)
+
+// GetMyLimits returns the caller's limits and usage
+// @Summary Get my transaction limits
+// @Description Returns the limits that apply to the caller and what they have sent in each window. Days and months are calendar periods in UTC; the hourly count is a rolling hour.
+// @Tags limits
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.MyLimitsResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/limits [get]
+func GetMyLimits(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+
+	set, err := limits.Effective(r.Context(), db.DB, uid)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to load limits", "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to load limits")
+		return
+	}
+
+	// Usage of no currency still counts the payments in the last hour
+	now := time.Now()
+	hour, err := limits.CurrentUsage(r.Context(), db.DB, uid, "", now)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to load limit usage", "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to load usage")
+		return
+	}
+	resp := models.MyLimitsResponse{
+		Limits:           limitSetResponse(set),
+		PaymentsThisHour: hour.HourlyCount,
+		Usage:            make([]models.LimitUsage, 0, len(set.Currencies)),
+	}
+	for _, c := range set.Currencies {
+		u, err := limits.CurrentUsage(r.Context(), db.DB, uid, c.Currency, now)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Failed to load limit usage", "currency", c.Currency, "error", err)
+			writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to load usage")
+			return
+		}
+		resp.Usage = append(resp.Usage, models.LimitUsage{Currency: c.Currency, Today: u.Today, ThisMonth: u.ThisMonth})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// GetDefaultLimits returns the default limits
+// @Summary Get default limits
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.LimitSet
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/admin/limits/default [get]
+func GetDefaultLimits(w http.ResponseWriter, r *http.Request) {
+	set, err := limits.Get(r.Context(), db.DB, limits.DefaultUser)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to load default limits", "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to load limits")
+		return
+	}
+	writeJSON(w, http.StatusOK, limitSetResponse(set))
+}
+
+// PutDefaultLimits replaces the default limits
+// @Summary Replace default limits
+// @Description Replaces the limits every user gets unless overridden. A null hourly_count and unlisted currencies have no limit.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param limits body models.LimitSet true "Default limits"
+// @Success 200 {object} models.LimitSet
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/admin/limits/default [put]
+func PutDefaultLimits(w http.ResponseWriter, r *http.Request) {
+	putLimits(w, r, limits.DefaultUser)
+}
+
+// GetUserLimits returns a user's overrides and effective limits
+// @Summary Get a user's limits
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} models.UserLimitsResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/admin/limits/users/{id} [get]
+func GetUserLimits(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	writeUserLimits(w, r, int(id))
+}
+
+// PutUserLimits replaces a user's overrides
+// @Summary Replace a user's limit overrides
+// @Description Replaces the user's overrides. A listed currency replaces the default for that currency, with null meaning no limit; a null hourly_count and unlisted currencies keep the defaults.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param limits body models.LimitSet true "Overrides"
+// @Success 200 {object} models.UserLimitsResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/admin/limits/users/{id} [put]
+func PutUserLimits(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	putLimits(w, r, int(id))
+}
+
+// DeleteUserLimits removes a user's overrides
+// @Summary Remove a user's limit overrides
+// @Tags admin
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 204
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/admin/limits/users/{id} [delete]
+func DeleteUserLimits(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	before, err := limits.Get(r.Context(), db.DB, int(id))
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to load limit overrides", "user_id", id, "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to delete overrides")
+		return
+	}
+	found, err := limits.Delete(r.Context(), db.DB, int(id))
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to delete limit overrides", "user_id", id, "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to delete overrides")
+		return
+	}
+	if !found {
+		writeError(w, r, http.StatusNotFound, "Not found", "The user has no overrides")
+		return
+	}
+	slog.InfoContext(r.Context(), "Limit overrides removed", "user_id", id)
+	recordAudit(r, audit.Event{
+		Action:       "admin.limits.deleted",
+		ResourceType: "limits",
+		ResourceID:   audit.ID(id),
+		Before:       limitSetResponse(before),
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// putLimits replaces what is stored for target with the request body.
+func putLimits(w http.ResponseWriter, r *http.Request, target int) {
+	admin, ok := userID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.LimitSet
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request", "Failed to parse request body")
+		return
+	}
+	set := limits.Set{HourlyCount: req.HourlyCount}
+	for _, c := range req.Currencies {
+		set.Currencies = append(set.Currencies, limits.Currency{
+			Currency:       c.Currency,
+			PerTransaction: c.PerTransaction,
+			Daily:          c.Daily,
+			Monthly:        c.Monthly,
+		})
+	}
+	if err := limits.Normalize(&set); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid limits", err.Error())
+		return
+	}
+
+	before, err := limits.Get(r.Context(), db.DB, target)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to load limits", "user_id", target, "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Could not save limits")
+		return
+	}
+
+	err = limits.Put(r.Context(), db.DB, target, set, admin)
+	if errors.Is(err, limits.ErrUnknownCurrency) {
+		writeError(w, r, http.StatusBadRequest, "Invalid limits", err.Error())
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to save limits", "user_id", target, "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Could not save limits")
+		return
+	}
+
+	slog.InfoContext(r.Context(), "Limits changed", "user_id", target, "admin_id", admin)
+	after, err := limits.Get(r.Context(), db.DB, target)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to load limits", "user_id", target, "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to load limits")
+		return
+	}
+	recordAudit(r, audit.Event{
+		Action:       "admin.limits.updated",
+		ResourceType: "limits",
+		ResourceID:   strconv.Itoa(target),
+		Before:       limitSetResponse(before),
+		After:        limitSetResponse(after),
+	})
+	if target == limits.DefaultUser {
+		writeJSON(w, http.StatusOK, limitSetResponse(after))
+		return
+	}
+	writeUserLimits(w, r, target)
+}
+
+func writeUserLimits(w http.ResponseWriter, r *http.Request, target int) {
+	overrides, err := limits.Get(r.Context(), db.DB, target)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to load limit overrides", "user_id", target, "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to load limits")
+		return
+	}
+	effective, err := limits.Effective(r.Context(), db.DB, target)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to load limits", "user_id", target, "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to load limits")
+		return
+	}
+	writeJSON(w, http.StatusOK, models.UserLimitsResponse{
+		UserID:    target,
+		Overrides: limitSetResponse(overrides),
+		Effective: limitSetResponse(effective),
+	})
+}
+
+// writeLimitError writes the error for a payment refused by a limit: 429
+// for the hourly count and 422 for amounts, with Retry-After when the
+// window resets.
+func writeLimitError(w http.ResponseWriter, r *http.Request, v *limits.Violation) {
+	code := http.StatusUnprocessableEntity
+	if v.Kind == limits.KindHourlyCount {
+		code = http.StatusTooManyRequests
+	}
+	if v.ResetsAt != nil {
+		secs := int(time.Until(*v.ResetsAt).Seconds()) + 1
+		w.Header().Set("Retry-After", strconv.Itoa(max(secs, 1)))
+	}
+	slog.WarnContext(r.Context(), "Payment refused by limit", "limit", v.Kind, "currency", v.Currency, "max", v.Max, "used", v.Used)
+	writeJSON(w, code, models.ErrorResponse{
+		Error:   "Limit exceeded",
+		Message: "Payment exceeds your limits: " + v.Error(),
+		Limit: &models.LimitViolation{
+			Limit:     string(v.Kind),
+			Currency:  v.Currency,
+			Max:       v.Max,
+			Used:      v.Used,
+			Attempted: v.Attempted,
+			ResetsAt:  v.ResetsAt,
+		},
+		RequestID: middleware.GetRequestID(r.Context()),
+	})
+}
+
+func limitSetResponse(s limits.Set) models.LimitSet {
+	resp := models.LimitSet{
+		HourlyCount: s.HourlyCount,
+		Currencies:  make([]models.CurrencyLimit, 0, len(s.Currencies)),
+		UpdatedBy:   s.UpdatedBy,
+		UpdatedAt:   s.UpdatedAt,
+	}
+	for _, c := range s.Currencies {
+		resp.Currencies = append(resp.Currencies, models.CurrencyLimit{
+			Currency:       c.Currency,
+			PerTransaction: c.PerTransaction,
+			Daily:          c.Daily,
+			Monthly:        c.Monthly,
+		})
+	}
+	return resp
+}
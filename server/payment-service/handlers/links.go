@@ -0,0 +1,417 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/db"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/internal/config"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/links"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/middleware"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/models"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/payments"
+)
+
+// CreatePaymentLink creates a payment link
+// @Summary Create payment link
+// @Description Creates a shareable link through which anyone can pay the caller a fixed amount, or an amount of their choosing within bounds, until it expires, is cancelled or reaches max_uses captured payments.
+// @Tags links
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param link body models.PaymentLinkRequest true "Payment link"
+// @Success 201 {object} models.PaymentLinkResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/links [post]
+func CreatePaymentLink(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.PaymentLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request", "Failed to parse request body")
+		return
+	}
+	if req.Currency == "" {
+		req.Currency = "INR"
+	}
+
+	l := links.Link{
+		UserID:      uid,
+		Amount:      req.Amount,
+		MinAmount:   req.MinAmount,
+		MaxAmount:   req.MaxAmount,
+		Currency:    strings.ToUpper(req.Currency),
+		ToAccount:   req.ToAccount,
+		Description: req.Description,
+		ExpiresAt:   req.ExpiresAt,
+		MaxUses:     req.MaxUses,
+	}
+	if err := links.Validate(&l, time.Now(), config.Get().PaymentLinkMaxTTL); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid payment link", err.Error())
+		return
+	}
+	cur, ok := supportedCurrency(w, r, l.Currency, 0)
+	if !ok {
+		return
+	}
+	for _, amount := range []*float64{l.Amount, l.MinAmount, l.MaxAmount} {
+		if amount != nil && !cur.Precise(*amount) {
+			writeError(w, r, http.StatusBadRequest, "Invalid payment link",
+				fmt.Sprintf("%s amounts have at most %d decimal places", cur.Code, cur.Exponent))
+			return
+		}
+	}
+
+	l, err := links.Create(r.Context(), db.DB, l)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to create payment link", "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Could not save payment link")
+		return
+	}
+
+	slog.InfoContext(r.Context(), "Payment link created", "link_id", l.ID, "expires_at", l.ExpiresAt, "max_uses", l.MaxUses)
+	writeJSON(w, http.StatusCreated, linkResponse(l))
+}
+
+// ListPaymentLinks lists the caller's payment links
+// @Summary List payment links
+// @Tags links
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.PaymentLinkResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/links [get]
+func ListPaymentLinks(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+
+	list, err := links.List(r.Context(), db.DB, uid)
+	if !linkFound(w, r, err) {
+		return
+	}
+	resp := make([]models.PaymentLinkResponse, 0, len(list))
+	for _, l := range list {
+		resp = append(resp, linkResponse(l))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// GetPaymentLink returns one of the caller's payment links
+// @Summary Get payment link
+// @Tags links
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Payment link ID"
+// @Success 200 {object} models.PaymentLinkResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/links/{id} [get]
+func GetPaymentLink(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	l, err := links.Get(r.Context(), db.DB, uid, id)
+	if !linkFound(w, r, err) {
+		return
+	}
+	writeJSON(w, http.StatusOK, linkResponse(l))
+}
+
+// CancelPaymentLink stops a payment link accepting payments
+// @Summary Cancel payment link
+// @Description Cancels an active link. Orders already created through it can still be paid.
+// @Tags links
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Payment link ID"
+// @Success 200 {object} models.PaymentLinkResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/links/{id}/cancel [post]
+func CancelPaymentLink(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	l, err := links.Cancel(r.Context(), db.DB, uid, id)
+	if !linkFound(w, r, err) {
+		return
+	}
+	slog.InfoContext(r.Context(), "Payment link cancelled", "link_id", l.ID)
+	writeJSON(w, http.StatusOK, linkResponse(l))
+}
+
+// ListPaymentLinkPayments lists the orders created through a payment link
+// @Summary List payment link payments
+// @Description Every order created through the link with its payment status and what the payer entered, newest first.
+// @Tags links
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Payment link ID"
+// @Param limit query int false "At most this many (default 50, max 100)"
+// @Success 200 {array} models.PaymentLinkPaymentResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/links/{id}/payments [get]
+func ListPaymentLinkPayments(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	list, err := links.ListPayments(r.Context(), db.DB, uid, id, limit)
+	if !linkFound(w, r, err) {
+		return
+	}
+	resp := make([]models.PaymentLinkPaymentResponse, 0, len(list))
+	for _, lp := range list {
+		resp = append(resp, models.PaymentLinkPaymentResponse{
+			PaymentID:         lp.Payment.ID,
+			Amount:            lp.Payment.Amount,
+			Currency:          lp.Payment.Currency,
+			Status:            lp.Payment.Status,
+			RazorpayOrderID:   lp.Payment.RazorpayOrderID,
+			RazorpayPaymentID: lp.Payment.RazorpayPaymentID,
+			PayerName:         lp.PayerName,
+			PayerEmail:        lp.PayerEmail,
+			PayerContact:      lp.PayerContact,
+			CreatedAt:         lp.CreatedAt,
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// GetPublicPaymentLink describes a payment link to a payer
+// @Summary View payment link
+// @Description Public, unauthenticated view of a payment link for the page a payer opens.
+// @Tags links
+// @Produce json
+// @Param code path string true "Link code"
+// @Success 200 {object} models.PublicPaymentLinkResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /l/{code} [get]
+func GetPublicPaymentLink(w http.ResponseWriter, r *http.Request) {
+	l, err := links.GetByCode(r.Context(), db.DB, mux.Vars(r)["code"])
+	if !linkFound(w, r, err) {
+		return
+	}
+	writeJSON(w, http.StatusOK, models.PublicPaymentLinkResponse{
+		Code:        l.Code,
+		Amount:      l.Amount,
+		MinAmount:   l.MinAmount,
+		MaxAmount:   l.MaxAmount,
+		Currency:    l.Currency,
+		Description: l.Description,
+		ExpiresAt:   l.ExpiresAt,
+		Status:      string(l.Status),
+	})
+}
+
+// PayPaymentLink starts a payment through a link
+// @Summary Pay payment link
+// @Description Public, unauthenticated. Creates the Razorpay order for a payment to the link's owner and returns what Checkout needs. The payment is confirmed by Razorpay's webhook.
+// @Tags links
+// @Accept json
+// @Produce json
+// @Param code path string true "Link code"
+// @Param payment body models.PaymentLinkPayRequest true "Amount and payer details"
+// @Success 201 {object} models.PaymentLinkCheckoutResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 410 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /l/{code}/pay [post]
+func PayPaymentLink(w http.ResponseWriter, r *http.Request) {
+	var req models.PaymentLinkPayRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request", "Failed to parse request body")
+		return
+	}
+	payer, err := linkPayer(req)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid payer details", err.Error())
+		return
+	}
+
+	code := mux.Vars(r)["code"]
+	l, err := links.GetByCode(r.Context(), db.DB, code)
+	if err == nil {
+		err = l.Open()
+	}
+	if !linkFound(w, r, err) {
+		return
+	}
+	amount, err := l.CheckAmount(req.Amount)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid amount", err.Error())
+		return
+	}
+	if _, ok := supportedCurrency(w, r, l.Currency, amount); !ok {
+		return
+	}
+
+	// The order must not outlive the link
+	cfg := config.Get()
+	ttl := cfg.PaymentTTLFor(l.Currency)
+	if l.ExpiresAt != nil {
+		ttl = min(ttl, time.Until(*l.ExpiresAt))
+	}
+
+	payment, err := payments.Initiate(r.Context(), db.DB, payments.Order{
+		UserID:      l.UserID,
+		Direction:   models.DirectionIncoming,
+		Amount:      amount,
+		Currency:    l.Currency,
+		FromAccount: "link:" + l.Code,
+		ToAccount:   l.ToAccount,
+		Description: l.Description,
+		TTL:         ttl,
+		Receipt:     fmt.Sprintf("order_l%d_%d", l.ID, time.Now().UnixNano()),
+		RequestID:   middleware.GetRequestID(r.Context()),
+		Notes:       map[string]interface{}{"payment_link": l.Code},
+		Record: func(ctx context.Context, tx *sql.Tx, p models.Payment) error {
+			return links.Reserve(ctx, tx, l.Code, p, payer)
+		},
+	})
+	if errors.Is(err, payments.ErrBlocklisted) {
+		slog.WarnContext(r.Context(), "Payment link to a blocklisted account refused", "link_id", l.ID, "error", err)
+		writeError(w, r, http.StatusForbidden, "Payment declined", "This link cannot accept payments")
+		return
+	}
+	if errors.Is(err, payments.ErrGateway) {
+		slog.ErrorContext(r.Context(), "Razorpay order creation failed", "link_id", l.ID, "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Payment failed", "Could not create payment order")
+		return
+	}
+	if !linkFound(w, r, err) {
+		return
+	}
+
+	slog.InfoContext(r.Context(), "Payment link order created", "link_id", l.ID, "payment_id", payment.ID)
+	writeJSON(w, http.StatusCreated, models.PaymentLinkCheckoutResponse{
+		RazorpayOrderID: payment.RazorpayOrderID,
+		RazorpayKeyID:   cfg.RazorpayKeyID,
+		Amount:          payment.Amount,
+		Currency:        payment.Currency,
+		Description:     payment.Description,
+		ExpiresAt:       payment.ExpiresAt,
+	})
+}
+
+// linkPayer trims and checks the optional payer details in req.
+func linkPayer(req models.PaymentLinkPayRequest) (links.Payer, error) {
+	trim := func(s *string, max int, name string) (*string, error) {
+		if s == nil {
+			return nil, nil
+		}
+		v := strings.TrimSpace(*s)
+		if v == "" {
+			return nil, nil
+		}
+		if len(v) > max {
+			return nil, fmt.Errorf("%s must be at most %d characters", name, max)
+		}
+		return &v, nil
+	}
+
+	var p links.Payer
+	var err error
+	if p.Name, err = trim(req.Name, 128, "name"); err != nil {
+		return p, err
+	}
+	if p.Email, err = trim(req.Email, 255, "email"); err != nil {
+		return p, err
+	}
+	if p.Email != nil && !strings.Contains(*p.Email, "@") {
+		return p, errors.New("email is not a valid address")
+	}
+	if p.Contact, err = trim(req.Contact, 32, "contact"); err != nil {
+		return p, err
+	}
+	return p, nil
+}
+
+// linkFound writes the error response for a failed link lookup or
+// operation and reports whether err was nil.
+func linkFound(w http.ResponseWriter, r *http.Request, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, links.ErrNotFound):
+		writeError(w, r, http.StatusNotFound, "Not found", "Payment link not found")
+	case errors.Is(err, links.ErrInvalidState), errors.Is(err, links.ErrFullyReserved):
+		writeError(w, r, http.StatusConflict, "Invalid state", err.Error())
+	case errors.Is(err, links.ErrClosed):
+		writeError(w, r, http.StatusGone, "Link closed", err.Error())
+	default:
+		slog.ErrorContext(r.Context(), "Payment link request failed", "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Internal error", "Could not complete payment link request")
+	}
+	return false
+}
+
+func linkResponse(l links.Link) models.PaymentLinkResponse {
+	return models.PaymentLinkResponse{
+		ID:          l.ID,
+		Code:        l.Code,
+		URL:         strings.TrimRight(config.Get().PaymentLinkBaseURL, "/") + "/l/" + l.Code,
+		Amount:      l.Amount,
+		MinAmount:   l.MinAmount,
+		MaxAmount:   l.MaxAmount,
+		Currency:    l.Currency,
+		ToAccount:   l.ToAccount,
+		Description: l.Description,
+		ExpiresAt:   l.ExpiresAt,
+		MaxUses:     l.MaxUses,
+		Uses:        l.Uses,
+		Status:      string(l.Status),
+		PaidAt:      l.PaidAt,
+		CancelledAt: l.CancelledAt,
+		CreatedAt:   l.CreatedAt,
+		UpdatedAt:   l.UpdatedAt,
+	}
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/models"
+)
+
+func TestLinkPayer(t *testing.T) {
+	s := func(v string) *string { return &v }
+	tests := []struct {
+		name    string
+		req     models.PaymentLinkPayRequest
+		want    [3]string // name, email, contact; "" for nil
+		wantErr string
+	}{
+		{name: "anonymous", req: models.PaymentLinkPayRequest{}},
+		{name: "trimmed", req: models.PaymentLinkPayRequest{Name: s(" Asha "), Email: s(" asha@example.com"), Contact: s("+91 98765 43210 ")},
+			want: [3]string{"Asha", "asha@example.com", "+91 98765 43210"}},
+		{name: "blank is absent", req: models.PaymentLinkPayRequest{Name: s("   "), Email: s("")}},
+		{name: "long name", req: models.PaymentLinkPayRequest{Name: s(strings.Repeat("a", 129))}, wantErr: "name must be at most 128 characters"},
+		{name: "bad email", req: models.PaymentLinkPayRequest{Email: s("asha.example.com")}, wantErr: "email is not a valid address"},
+		{name: "long contact", req: models.PaymentLinkPayRequest{Contact: s(strings.Repeat("9", 33))}, wantErr: "contact must be at most 32 characters"},
+	}
+	for _, tt := range tests {
+		p, err := linkPayer(tt.req)
+		if tt.wantErr != "" {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		got := [3]string{}
+		for i, v := range []*string{p.Name, p.Email, p.Contact} {
+			if v != nil {
+				got[i] = *v
+			}
+		}
+		if got != tt.want {
+			t.Errorf("%s: payer %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
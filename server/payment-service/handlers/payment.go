@@ -1,49 +1,46 @@
 package handlers
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"strings"
 	"time"
 
+	"github.com/RaginiSharma01/gopay-lite/payment-service/beneficiaries"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/currencies"
 	"github.com/RaginiSharma01/gopay-lite/payment-service/db"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/fx"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/internal/config"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/limits"
 	"github.com/RaginiSharma01/gopay-lite/payment-service/middleware"
 	"github.com/RaginiSharma01/gopay-lite/payment-service/models"
-	razorpay "github.com/razorpay/razorpay-go"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/payments"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/razorpay"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/risk"
 )
 
-var razorpayClient *razorpay.Client
-
-// Payment represents the payment model
-type Payment struct {
-	ID              int       `json:"id"`
-	UserID          int       `json:"user_id"`
-	Amount          float64   `json:"amount"`
-	Currency        string    `json:"currency"`
-	FromAccount     string    `json:"from_account"`
-	ToAccount       string    `json:"to_account"`
-	RazorpayOrderID string    `json:"razorpay_order_id"`
-	Status          string    `json:"status"`
-	CreatedAt       time.Time `json:"created_at"`
-}
-
-// InitRazorpayClient initializes the Razorpay client
-func InitRazorpayClient(key, secret string) {
-	razorpayClient = razorpay.NewClient(key, secret)
-}
-
 // HandlePayment handles the payment request
 // @Summary Process payment
-// @Description Process a payment transaction
+// @Description Process a payment transaction. Payments over the caller's limits (GET /limits) are refused with the limit that was hit and when it resets. With quote_id, the payment is charged the quote's amount and currency and records the converted amount credited to the payee. Risk screening may decline the payment (403), as it always does when the payer, payee, email or client address is blocklisted, or hold it for review (202, status pending_review, no Razorpay order until an admin approves it).
 // @Tags payments
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param payment body models.PaymentRequest true "Payment details"
 // @Success 201 {object} models.PaymentResponse
+// @Success 202 {object} models.PaymentResponse
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 410 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /api/v1/pay [post]
 func HandlePayment(w http.ResponseWriter, r *http.Request) {
@@ -52,148 +49,380 @@ func HandlePayment(w http.ResponseWriter, r *http.Request) {
 	// Decode request
 	var req models.PaymentRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		log.Printf("Error decoding request: %v", err)
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(models.ErrorResponse{
-			Error:   "Invalid request",
-			Message: "Failed to parse request body",
-		})
+		slog.WarnContext(r.Context(), "Error decoding payment request", "error", err)
+		writeError(w, r, http.StatusBadRequest, "Invalid request", "Failed to parse request body")
 		return
 	}
-	log.Printf("Payment request received: %+v", req)
+	slog.InfoContext(r.Context(), "Payment request received", "request", req)
 
-	// Validate request
-	if req.Amount <= 0 {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(models.ErrorResponse{
-			Error:   "Invalid amount",
-			Message: "Amount must be positive",
-		})
+	// Get user from context
+	userID, ok := r.Context().Value(middleware.UserIDContextKey).(int)
+
+	if !ok {
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized", "Invalid user context")
+		return
+	}
+
+	var quote *fx.Quote
+	if req.QuoteID != nil {
+		if quote, ok = applyQuote(w, r, userID, &req); !ok {
+			return
+		}
+	}
+
+	ttl, ok := checkPaymentRequest(w, r, &req)
+	if !ok {
 		return
 	}
 
-	if req.FromAccount == "" || req.ToAccount == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(models.ErrorResponse{
-			Error:   "Missing accounts",
-			Message: "Both from_account and to_account must be specified",
-		})
+	order := payments.Order{
+		UserID:        userID,
+		Amount:        req.Amount,
+		Currency:      req.Currency,
+		FromAccount:   req.FromAccount,
+		ToAccount:     req.ToAccount,
+		BeneficiaryID: req.BeneficiaryID,
+		CaptureMode:   req.CaptureMode,
+		TTL:           ttl,
+		RequestID:     middleware.GetRequestID(r.Context()),
+		CheckLimits:   true,
+	}
+	if quote != nil {
+		order.Notes = map[string]interface{}{
+			"fx_quote_id":       quote.ID,
+			"credited_amount":   quote.ConvertedAmount,
+			"credited_currency": quote.To,
+		}
+	}
+
+	// Screening runs once the payee is known; its assessment is linked to
+	// the payment it lets through or holds
+	var assessment *risk.Assessment
+	if riskEngine != nil {
+		order.Screen = func(ctx context.Context, toAccount string) (bool, error) {
+			a, err := screenPayment(r, userID, req, toAccount)
+			if err != nil {
+				return false, fmt.Errorf("risk screening: %w", err)
+			}
+			assessment = &a
+			if a.Outcome == risk.OutcomeBlock {
+				return false, risk.ErrBlocked
+			}
+			return a.Outcome == risk.OutcomeReview, nil
+		}
+	}
+	order.Record = func(ctx context.Context, tx *sql.Tx, p models.Payment) error {
+		if assessment != nil {
+			if err := risk.Attach(ctx, tx, assessment.ID, int64(p.ID)); err != nil {
+				return err
+			}
+		}
+		if quote != nil {
+			// The quote is redeemed with the payment, so a quote raced
+			// away by another payment discards this one
+			redeemed, err := fx.Redeem(ctx, tx, userID, quote.ID, int64(p.ID), time.Now().UTC())
+			if err != nil {
+				return fmt.Errorf("redeem quote %d: %w", quote.ID, err)
+			}
+			*quote = redeemed
+		}
+		return nil
+	}
+
+	payment, err := payments.Initiate(r.Context(), db.DB, order)
+	var violation *limits.Violation
+	switch {
+	case errors.As(err, &violation):
+		writeLimitError(w, r, violation)
+		return
+	case errors.Is(err, risk.ErrBlocked):
+		slog.WarnContext(r.Context(), "Payment blocked by risk screening",
+			"assessment_id", assessment.ID,
+			"score", assessment.Score,
+		)
+		writeError(w, r, http.StatusForbidden, "Payment declined", "The payment was declined by risk screening")
+		return
+	case errors.Is(err, payments.ErrBlocklisted):
+		slog.WarnContext(r.Context(), "Payment to or from a blocklisted account refused", "error", err)
+		writeError(w, r, http.StatusForbidden, "Payment declined", "The payment was declined by risk screening")
+		return
+	case errors.Is(err, beneficiaries.ErrNotFound):
+		writeError(w, r, http.StatusBadRequest, "Unknown beneficiary", "beneficiary_id does not match a saved beneficiary")
 		return
+	case errors.Is(err, fx.ErrQuoteUsed):
+		writeError(w, r, http.StatusConflict, "Quote used", "The quote has already been used by another payment")
+		return
+	case errors.Is(err, fx.ErrQuoteExpired):
+		writeError(w, r, http.StatusGone, "Quote expired", "The quote expired; request a new one")
+		return
+	case errors.Is(err, currencies.ErrUnsupported):
+		writeError(w, r, http.StatusBadRequest, "Invalid currency", fmt.Sprintf("%s is not a supported currency", req.Currency))
+		return
+	case errors.Is(err, payments.ErrGateway):
+		slog.ErrorContext(r.Context(), "Razorpay order creation failed", "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Payment failed", "Could not create payment order")
+		return
+	case err != nil:
+		slog.ErrorContext(r.Context(), "Failed to save payment record", "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Payment processing failed", "Could not save payment record")
+		return
+	}
+
+	// Return success response
+	resp := paymentResponse(payment)
+	if quote != nil {
+		resp.Conversion = conversionResponse(*quote)
+	}
+	code := http.StatusCreated
+	if payment.Status == string(models.PaymentStatusPendingReview) {
+		slog.WarnContext(r.Context(), "Payment held by risk screening",
+			"payment_id", payment.ID,
+			"assessment_id", assessment.ID,
+			"score", assessment.Score,
+		)
+		code = http.StatusAccepted
+	}
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// screenPayment runs risk screening on userID's payment req to toAccount.
+// Without the account's age from auth-service the new-account rule is
+// skipped rather than failing the payment.
+func screenPayment(r *http.Request, userID int, req models.PaymentRequest, toAccount string) (risk.Assessment, error) {
+	p := risk.Payment{
+		UserID:      userID,
+		Amount:      req.Amount,
+		Currency:    req.Currency,
+		FromAccount: req.FromAccount,
+		ToAccount:   toAccount,
+		Email:       middleware.GetEmail(r.Context()),
+		IP:          middleware.ClientIP(r),
+		RequestID:   middleware.GetRequestID(r.Context()),
+	}
+	if u, err := profiles.Me(r.Context(), r.Header.Get("Authorization")); err != nil {
+		slog.WarnContext(r.Context(), "Screening payment without account age", "error", err)
+	} else {
+		p.AccountCreatedAt = u.CreatedAt
+	}
+	return riskEngine.Assess(r.Context(), p, time.Now())
+}
+
+// applyQuote loads userID's quote req.QuoteID and fills req's amount and
+// currency from it. It writes an error and reports false if the quote is
+// unknown, spent or expired, or req disagrees with it.
+func applyQuote(w http.ResponseWriter, r *http.Request, userID int, req *models.PaymentRequest) (*fx.Quote, bool) {
+	quote, err := fx.GetQuote(r.Context(), db.DB, userID, *req.QuoteID)
+	if errors.Is(err, fx.ErrQuoteNotFound) {
+		writeError(w, r, http.StatusBadRequest, "Unknown quote", "quote_id does not match one of your quotes")
+		return nil, false
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to load quote", "quote_id", *req.QuoteID, "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to load quote")
+		return nil, false
+	}
+
+	switch err := quote.Usable(time.Now()); {
+	case errors.Is(err, fx.ErrQuoteUsed):
+		writeError(w, r, http.StatusConflict, "Quote used", "The quote has already been used by another payment")
+		return nil, false
+	case errors.Is(err, fx.ErrQuoteExpired):
+		writeError(w, r, http.StatusGone, "Quote expired", "The quote expired; request a new one")
+		return nil, false
+	}
+
+	if req.Amount == 0 {
+		req.Amount = quote.Amount
+	}
+	if req.Currency == "" {
+		req.Currency = quote.From
+	}
+	cur, err := currencies.Get(r.Context(), db.DB, quote.From)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to load quote currency", "quote_id", quote.ID, "currency", quote.From, "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to load quote")
+		return nil, false
+	}
+	if cur.ToMinor(req.Amount) != cur.ToMinor(quote.Amount) || !strings.EqualFold(req.Currency, quote.From) {
+		writeError(w, r, http.StatusBadRequest, "Quote mismatch",
+			fmt.Sprintf("The quote is for %s %s; omit amount and currency or match it", cur.Decimal(cur.ToMinor(quote.Amount)), quote.From))
+		return nil, false
+	}
+	return &quote, true
+}
+
+// checkPaymentRequest validates req and fills in its defaults, returning how
+// long the order stays payable. It writes a 400 and reports false if req is
+// invalid.
+func checkPaymentRequest(w http.ResponseWriter, r *http.Request, req *models.PaymentRequest) (time.Duration, bool) {
+	if req.Amount <= 0 {
+		writeError(w, r, http.StatusBadRequest, "Invalid amount", "Amount must be positive")
+		return 0, false
+	}
+
+	if req.BeneficiaryID != nil && req.ToAccount != "" {
+		writeError(w, r, http.StatusBadRequest, "Ambiguous payee", "Specify either to_account or beneficiary_id, not both")
+		return 0, false
+	}
+	if req.FromAccount == "" || (req.ToAccount == "" && req.BeneficiaryID == nil) {
+		writeError(w, r, http.StatusBadRequest, "Missing accounts", "Both from_account and to_account (or beneficiary_id) must be specified")
+		return 0, false
+	}
+	if len(req.FromAccount) > models.MaxAccountLength || len(req.ToAccount) > models.MaxAccountLength {
+		writeError(w, r, http.StatusBadRequest, "Invalid account",
+			fmt.Sprintf("from_account and to_account must be at most %d characters", models.MaxAccountLength))
+		return 0, false
 	}
 
 	// Set default currency if not provided
 	if req.Currency == "" {
 		req.Currency = "INR"
 	}
+	cur, ok := supportedCurrency(w, r, req.Currency, req.Amount)
+	if !ok {
+		return 0, false
+	}
+	req.Currency = cur.Code
 
-	// Get user from context
-	userID, ok := r.Context().Value(middleware.UserIDContextKey).(int)
+	switch models.CaptureMode(req.CaptureMode) {
+	case "":
+		req.CaptureMode = string(models.CaptureAuto)
+	case models.CaptureAuto, models.CaptureManual:
+	default:
+		writeError(w, r, http.StatusBadRequest, "Invalid capture_mode", "capture_mode must be auto or manual")
+		return 0, false
+	}
+
+	cfg := config.Get()
+	ttl := cfg.PaymentTTLFor(req.Currency)
+	if req.ExpiresIn != "" {
+		d, err := time.ParseDuration(req.ExpiresIn)
+		if err != nil || d < time.Minute || d > cfg.PaymentMaxTTL {
+			writeError(w, r, http.StatusBadRequest, "Invalid expires_in",
+				fmt.Sprintf("expires_in must be a duration between 1m and %s", cfg.PaymentMaxTTL))
+			return 0, false
+		}
+		ttl = d
+	}
+	return ttl, true
+}
 
+// VerifyPayment confirms a Checkout payment
+// @Summary Verify checkout payment
+// @Description Verifies the signature Razorpay Checkout returned and marks the payment pending until Razorpay confirms the capture. Expired orders are refused.
+// @Tags payments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param verification body models.VerifyPaymentRequest true "Checkout response"
+// @Success 200 {object} models.PaymentResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 410 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/pay/verify [post]
+func VerifyPayment(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
 	if !ok {
-		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(models.ErrorResponse{
-			Error:   "Unauthorized",
-			Message: "Invalid user context",
-		})
 		return
 	}
 
-	// Begin database transaction
+	var req models.VerifyPaymentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request", "Failed to parse request body")
+		return
+	}
+	if req.RazorpayOrderID == "" || req.RazorpayPaymentID == "" || req.RazorpaySignature == "" {
+		writeError(w, r, http.StatusBadRequest, "Invalid request",
+			"razorpay_order_id, razorpay_payment_id and razorpay_signature are required")
+		return
+	}
+	if !razorpay.VerifyPaymentSignature(req.RazorpayOrderID, req.RazorpayPaymentID, req.RazorpaySignature, config.Get().RazorpayKeySecret) {
+		slog.WarnContext(r.Context(), "Invalid checkout signature", "order_id", req.RazorpayOrderID)
+		writeError(w, r, http.StatusBadRequest, "Invalid signature", "Payment signature verification failed")
+		return
+	}
+
 	tx, err := db.DB.BeginTx(r.Context(), nil)
 	if err != nil {
-		log.Printf("Failed to begin transaction: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(models.ErrorResponse{
-			Error:   "Database error",
-			Message: "Failed to start transaction",
-		})
+		slog.ErrorContext(r.Context(), "Failed to begin transaction", "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to start transaction")
 		return
 	}
 	defer tx.Rollback()
 
-	// Create Razorpay order
-	orderData := map[string]interface{}{
-		"amount":   int(req.Amount * 100), // Convert to paise
-		"currency": req.Currency,
-		"receipt":  fmt.Sprintf("order_%d_%d", userID, time.Now().Unix()),
-		"notes": map[string]interface{}{
-			"from_account": req.FromAccount,
-			"to_account":   req.ToAccount,
-		},
+	payment, err := payments.LockByOrderID(r.Context(), tx, req.RazorpayOrderID)
+	if errors.Is(err, payments.ErrNotFound) || (err == nil && payment.UserID != uid) {
+		writeError(w, r, http.StatusNotFound, "Not found", "Payment not found")
+		return
 	}
-
-	order, err := razorpayClient.Order.Create(orderData, nil)
 	if err != nil {
-		log.Printf("Razorpay order creation failed: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(models.ErrorResponse{
-			Error:   "Payment failed",
-			Message: "Could not create payment order",
-		})
-		return
-	}
-
-	// Prepare payment record
-	payment := Payment{
-		UserID:          userID,
-		Amount:          req.Amount,
-		Currency:        req.Currency,
-		FromAccount:     req.FromAccount,
-		ToAccount:       req.ToAccount,
-		RazorpayOrderID: order["id"].(string),
-		Status:          "created",
-		CreatedAt:       time.Now().UTC(),
-	}
-
-	// Store payment in database
-	query := `INSERT INTO payments 
-		(user_id, amount, currency, from_account, to_account, razorpay_order_id, status, created_at) 
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) 
-		RETURNING id`
-
-	err = tx.QueryRowContext(r.Context(),
-		query,
-		payment.UserID,
-		payment.Amount,
-		payment.Currency,
-		payment.FromAccount,
-		payment.ToAccount,
-		payment.RazorpayOrderID,
-		payment.Status,
-		payment.CreatedAt,
-	).Scan(&payment.ID)
+		slog.ErrorContext(r.Context(), "Failed to load payment", "order_id", req.RazorpayOrderID, "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to load payment")
+		return
+	}
 
-	if err != nil {
-		log.Printf("Database error: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(models.ErrorResponse{
-			Error:   "Payment processing failed",
-			Message: "Could not save payment record",
-		})
+	if payment.Expired(time.Now()) {
+		// Expire it now rather than waiting for the sweeper so the refusal
+		// and the payment.expired event agree
+		if _, err := payments.Transition(r.Context(), tx, &payment, models.PaymentStatusExpired, ""); err != nil {
+			slog.ErrorContext(r.Context(), "Failed to expire payment", "payment_id", payment.ID, "error", err)
+		} else if err := tx.Commit(); err != nil {
+			slog.ErrorContext(r.Context(), "Transaction commit failed", "payment_id", payment.ID, "error", err)
+		}
+		slog.WarnContext(r.Context(), "Verification refused for expired payment",
+			"payment_id", payment.ID,
+			"razorpay_payment_id", req.RazorpayPaymentID,
+		)
+		writeError(w, r, http.StatusGone, "Payment expired", "The order expired before it was paid; create a new payment")
 		return
 	}
 
-	// Commit transaction
+	_, err = payments.Transition(r.Context(), tx, &payment, models.PaymentStatusPending, req.RazorpayPaymentID)
+	var transitionErr *payments.TransitionError
+	if errors.As(err, &transitionErr) {
+		// Already captured by the webhook, or failed: report where it is
+		if payment.RazorpayPaymentID != nil && *payment.RazorpayPaymentID == req.RazorpayPaymentID {
+			writeJSON(w, http.StatusOK, paymentResponse(payment))
+			return
+		}
+		writeError(w, r, http.StatusConflict, "Invalid state", transitionErr.Error())
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to update payment", "payment_id", payment.ID, "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to update payment")
+		return
+	}
 	if err := tx.Commit(); err != nil {
-		log.Printf("Transaction commit failed: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(models.ErrorResponse{
-			Error:   "Transaction error",
-			Message: "Failed to complete payment",
-		})
+		slog.ErrorContext(r.Context(), "Transaction commit failed", "payment_id", payment.ID, "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Transaction error", "Failed to update payment")
 		return
 	}
 
-	// Return success response
-	response := models.PaymentResponse{
-		ID:              payment.ID,
-		RazorpayOrderID: payment.RazorpayOrderID,
-		Status:          payment.Status,
-		Amount:          payment.Amount,
-		Currency:        payment.Currency,
-		CreatedAt:       payment.CreatedAt,
-	}
+	slog.InfoContext(r.Context(), "Checkout payment verified", "payment_id", payment.ID, "status", payment.Status)
+	writeJSON(w, http.StatusOK, paymentResponse(payment))
+}
 
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(response)
+func paymentResponse(p models.Payment) models.PaymentResponse {
+	return models.PaymentResponse{
+		ID:                      p.ID,
+		RazorpayOrderID:         p.RazorpayOrderID,
+		Status:                  p.Status,
+		Amount:                  p.Amount,
+		Currency:                p.Currency,
+		CreatedAt:               p.CreatedAt,
+		UpdatedAt:               p.UpdatedAt,
+		ExpiresAt:               p.ExpiresAt,
+		CaptureMode:             p.CaptureMode,
+		Direction:               p.Direction,
+		CapturedAmount:          p.CapturedAmount,
+		BeneficiaryID:           p.BeneficiaryID,
+		ChargedBackAmount:       p.ChargedBackAmount,
+		ReleasePending:          p.ReleasePending(),
+		AuthorizationReleasedAt: p.AuthorizationReleasedAt,
+	}
 }
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/currencies"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/fx"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/models"
+)
+
+func TestApplyQuote(t *testing.T) {
+	database := useDB(t)
+	ctx := context.Background()
+	now := time.Now()
+	rate := fx.Rate{Base: "USD", Quote: "INR", Rate: 83, EffectiveAt: now.Add(-time.Hour), Source: fx.SourceFile}
+	if _, err := fx.SaveRates(ctx, database, []fx.Rate{rate}); err != nil {
+		t.Fatal(err)
+	}
+	usd := currencies.Currency{Code: "USD", Exponent: 2}
+	inr := currencies.Currency{Code: "INR", Exponent: 2}
+	const user = 6
+	newQuote := func(ttl time.Duration) int64 {
+		q, err := fx.NewQuote(ctx, database, user, usd, inr, 12.5, now, ttl, 24*time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return q.ID
+	}
+	fresh, expired, used := newQuote(time.Hour), newQuote(-time.Second), newQuote(time.Hour)
+	var paymentID int64
+	err := database.QueryRowContext(ctx, `INSERT INTO payments
+			(user_id, amount, currency, from_account, to_account, razorpay_order_id, status, created_at)
+		VALUES ($1, 12.5, 'USD', 'ACC-FROM', 'ACC-TO', 'order_quoted', 'created', NOW())
+		RETURNING id`, user).Scan(&paymentID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.ExecContext(ctx, `UPDATE fx_quotes SET payment_id = $2, used_at = NOW() WHERE id = $1`, used, paymentID); err != nil {
+		t.Fatal(err)
+	}
+	unknown := used + 100
+
+	tests := []struct {
+		name     string
+		userID   int
+		req      models.PaymentRequest
+		code     int
+		amount   float64
+		currency string
+	}{
+		{name: "fills amount and currency", userID: user, req: models.PaymentRequest{QuoteID: &fresh}, amount: 12.5, currency: "USD"},
+		{name: "matching amount", userID: user, req: models.PaymentRequest{QuoteID: &fresh, Amount: 12.50, Currency: "usd"}, amount: 12.5, currency: "usd"},
+		{name: "different amount", userID: user, req: models.PaymentRequest{QuoteID: &fresh, Amount: 12.51}, code: http.StatusBadRequest},
+		{name: "different currency", userID: user, req: models.PaymentRequest{QuoteID: &fresh, Currency: "INR"}, code: http.StatusBadRequest},
+		{name: "another user's quote", userID: user + 1, req: models.PaymentRequest{QuoteID: &fresh}, code: http.StatusBadRequest},
+		{name: "unknown quote", userID: user, req: models.PaymentRequest{QuoteID: &unknown}, code: http.StatusBadRequest},
+		{name: "expired", userID: user, req: models.PaymentRequest{QuoteID: &expired}, code: http.StatusGone},
+		{name: "already used", userID: user, req: models.PaymentRequest{QuoteID: &used}, code: http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := tt.req
+			quote, ok := applyQuote(rec, httptest.NewRequest(http.MethodPost, "/api/v1/payments", nil), tt.userID, &req)
+			if tt.code != 0 {
+				if ok || rec.Code != tt.code {
+					t.Errorf("ok %t with status %d, want %d", ok, rec.Code, tt.code)
+				}
+				return
+			}
+			if !ok || quote == nil || quote.ID != fresh {
+				t.Fatalf("quote refused with %d: %s", rec.Code, rec.Body)
+			}
+			if req.Amount != tt.amount || req.Currency != tt.currency {
+				t.Errorf("request is %v %s, want %v %s", req.Amount, req.Currency, tt.amount, tt.currency)
+			}
+		})
+	}
+}
+
+func TestCheckPaymentRequestRejects(t *testing.T) {
+	long := strings.Repeat("9", models.MaxAccountLength+1)
+	beneficiary := int64(3)
+	tests := []struct {
+		name string
+		req  models.PaymentRequest
+	}{
+		{"no amount", models.PaymentRequest{FromAccount: "ACC-FROM", ToAccount: "ACC-TO"}},
+		{"negative amount", models.PaymentRequest{Amount: -1, FromAccount: "ACC-FROM", ToAccount: "ACC-TO"}},
+		{"payee twice", models.PaymentRequest{Amount: 10, FromAccount: "ACC-FROM", ToAccount: "ACC-TO", BeneficiaryID: &beneficiary}},
+		{"no payee", models.PaymentRequest{Amount: 10, FromAccount: "ACC-FROM"}},
+		{"no payer", models.PaymentRequest{Amount: 10, ToAccount: "ACC-TO"}},
+		{"long from_account", models.PaymentRequest{Amount: 10, FromAccount: long, ToAccount: "ACC-TO"}},
+		{"long to_account", models.PaymentRequest{Amount: 10, FromAccount: "ACC-FROM", ToAccount: long}},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		req := tt.req
+		if _, ok := checkPaymentRequest(rec, httptest.NewRequest(http.MethodPost, "/api/v1/payments", nil), &req); ok || rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: ok %t with status %d, want 400", tt.name, ok, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/audit"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/db"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/internal/config"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/metrics"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/models"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/payments"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/razorpay"
+)
+
+// maxWebhookBody bounds inbound webhook payloads.
+const maxWebhookBody = 1 << 20
+
+// HandleRazorpayWebhook applies Razorpay payment notifications
+// @Summary Razorpay webhook receiver
+// @Description Verifies X-Razorpay-Signature and applies payment.captured, payment.failed and refund.processed notifications to the matching payment (a full refund of a voided authorization records its release), subscription.* notifications to the matching subscription, and payment.dispute.* notifications to the disputed payment
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param X-Razorpay-Signature header string true "HMAC-SHA256 of the body with the webhook secret"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /webhooks/razorpay [post]
+func HandleRazorpayWebhook(w http.ResponseWriter, r *http.Request) {
+	secret := config.Get().RazorpayWebhookSecret
+	if secret == "" {
+		slog.ErrorContext(r.Context(), "Razorpay webhook received but RAZORPAY_WEBHOOK_SECRET is not set")
+		writeError(w, r, http.StatusServiceUnavailable, "Not configured", "Webhook secret not configured")
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request", "Failed to read request body")
+		return
+	}
+
+	if !razorpay.VerifyWebhookSignature(body, r.Header.Get("X-Razorpay-Signature"), secret) {
+		slog.WarnContext(r.Context(), "Rejected Razorpay webhook with invalid signature")
+		metrics.GatewayWebhook("unknown", "rejected")
+		writeError(w, r, http.StatusUnauthorized, "Invalid signature", "Webhook signature verification failed")
+		return
+	}
+	r = r.WithContext(audit.WithActor(r.Context(), audit.Actor{Type: audit.ActorRazorpay}))
+
+	var event razorpay.WebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		metrics.GatewayWebhook("unknown", "rejected")
+		writeError(w, r, http.StatusBadRequest, "Invalid request", "Failed to parse webhook body")
+		return
+	}
+
+	if strings.HasPrefix(event.Event, "subscription.") {
+		applySubscriptionWebhook(w, r, event)
+		return
+	}
+	if strings.HasPrefix(event.Event, "payment.dispute.") {
+		applyDisputeWebhook(w, r, event)
+		return
+	}
+
+	target, orderID, razorpayPaymentID, ok := webhookTransition(event)
+	if !ok {
+		slog.DebugContext(r.Context(), "Ignoring Razorpay webhook", "event", event.Event)
+		metrics.GatewayWebhook(event.Event, "ignored")
+		writeWebhookAck(w, "ignored")
+		return
+	}
+
+	tx, err := db.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to begin transaction", "error", err)
+		metrics.GatewayWebhook(event.Event, "error")
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to start transaction")
+		return
+	}
+	defer tx.Rollback()
+
+	var payment models.Payment
+	if orderID != "" {
+		payment, err = payments.LockByOrderID(r.Context(), tx, orderID)
+	} else {
+		payment, err = payments.LockByRazorpayPaymentID(r.Context(), tx, razorpayPaymentID)
+	}
+	if errors.Is(err, payments.ErrNotFound) {
+		// Orders not created by this service share the Razorpay account;
+		// acknowledge so Razorpay does not keep retrying them.
+		slog.WarnContext(r.Context(), "Razorpay webhook for unknown payment",
+			"event", event.Event,
+			"order_id", orderID,
+			"razorpay_payment_id", razorpayPaymentID,
+		)
+		metrics.GatewayWebhook(event.Event, "ignored")
+		writeWebhookAck(w, "ignored")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to load payment for webhook", "event", event.Event, "error", err)
+		metrics.GatewayWebhook(event.Event, "error")
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to load payment")
+		return
+	}
+
+	if target == models.PaymentStatusAuthorized && payment.CaptureMode != string(models.CaptureManual) {
+		// Auto-capture payments are authorized on the way to captured;
+		// only the capture is recorded
+		metrics.GatewayWebhook(event.Event, "ignored")
+		writeWebhookAck(w, "ignored")
+		return
+	}
+
+	if target == models.PaymentStatusRefunded && payment.Status == string(models.PaymentStatusVoided) {
+		applyAuthorizationRelease(w, r, tx, event, payment)
+		return
+	}
+
+	previous := payment.Status
+	changed, err := payments.Transition(r.Context(), tx, &payment, target, razorpayPaymentID)
+	var transitionErr *payments.TransitionError
+	if errors.As(err, &transitionErr) && previous == string(models.PaymentStatusExpired) && target == models.PaymentStatusCaptured {
+		// The customer paid an order we had already abandoned. The payment
+		// stays expired; the money has to be returned by hand.
+		slog.ErrorContext(r.Context(), "Razorpay captured an expired payment; refund required",
+			"payment_id", payment.ID,
+			"razorpay_payment_id", razorpayPaymentID,
+		)
+		metrics.GatewayWebhook(event.Event, "expired")
+		writeWebhookAck(w, "ignored")
+		return
+	}
+	if errors.As(err, &transitionErr) {
+		// Razorpay does not guarantee delivery order; a stale notification
+		// must not move a payment backwards.
+		slog.WarnContext(r.Context(), "Ignoring out-of-order Razorpay webhook",
+			"event", event.Event,
+			"payment_id", payment.ID,
+			"status", previous,
+		)
+		metrics.GatewayWebhook(event.Event, "ignored")
+		writeWebhookAck(w, "ignored")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to apply Razorpay webhook", "event", event.Event, "payment_id", payment.ID, "error", err)
+		metrics.GatewayWebhook(event.Event, "error")
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to update payment")
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.ErrorContext(r.Context(), "Transaction commit failed", "payment_id", payment.ID, "error", err)
+		metrics.GatewayWebhook(event.Event, "error")
+		writeError(w, r, http.StatusInternalServerError, "Transaction error", "Failed to update payment")
+		return
+	}
+
+	if !changed {
+		metrics.GatewayWebhook(event.Event, "duplicate")
+		writeWebhookAck(w, "duplicate")
+		return
+	}
+
+	slog.InfoContext(r.Context(), "Payment status updated from Razorpay webhook",
+		"event", event.Event,
+		"payment_id", payment.ID,
+		"from", previous,
+		"to", payment.Status,
+	)
+	metrics.GatewayWebhook(event.Event, "applied")
+	writeWebhookAck(w, "applied")
+}
+
+// applyAuthorizationRelease records that Razorpay refunded an authorization
+// we had voided, which is how it returns held funds to the payer. payment
+// is locked in tx.
+func applyAuthorizationRelease(w http.ResponseWriter, r *http.Request, tx *sql.Tx, event razorpay.WebhookEvent, payment models.Payment) {
+	changed, err := payments.ReleaseAuthorization(r.Context(), tx, &payment)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to record authorization release", "event", event.Event, "payment_id", payment.ID, "error", err)
+		metrics.GatewayWebhook(event.Event, "error")
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to update payment")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		slog.ErrorContext(r.Context(), "Transaction commit failed", "payment_id", payment.ID, "error", err)
+		metrics.GatewayWebhook(event.Event, "error")
+		writeError(w, r, http.StatusInternalServerError, "Transaction error", "Failed to update payment")
+		return
+	}
+
+	if !changed {
+		metrics.GatewayWebhook(event.Event, "duplicate")
+		writeWebhookAck(w, "duplicate")
+		return
+	}
+	slog.InfoContext(r.Context(), "Voided authorization released by Razorpay", "payment_id", payment.ID)
+	metrics.GatewayWebhook(event.Event, "applied")
+	writeWebhookAck(w, "applied")
+}
+
+// webhookTransition maps a Razorpay event to the status it moves a payment
+// to, and how to find that payment.
+func webhookTransition(event razorpay.WebhookEvent) (target models.PaymentStatus, orderID, razorpayPaymentID string, ok bool) {
+	var entity razorpay.PaymentEntity
+	if event.Payload.Payment != nil {
+		entity = event.Payload.Payment.Entity
+	}
+
+	switch event.Event {
+	case "payment.authorized":
+		return models.PaymentStatusAuthorized, entity.OrderID, entity.ID, entity.ID != ""
+	case "payment.captured":
+		return models.PaymentStatusCaptured, entity.OrderID, entity.ID, entity.ID != ""
+	case "payment.failed":
+		return models.PaymentStatusFailed, entity.OrderID, entity.ID, entity.ID != ""
+	case "refund.processed":
+		// Partial refunds leave the payment captured
+		if entity.RefundStatus != "full" {
+			return "", "", "", false
+		}
+		paymentID := entity.ID
+		if paymentID == "" && event.Payload.Refund != nil {
+			paymentID = event.Payload.Refund.Entity.PaymentID
+		}
+		return models.PaymentStatusRefunded, entity.OrderID, paymentID, paymentID != ""
+	}
+	return "", "", "", false
+}
+
+func writeWebhookAck(w http.ResponseWriter, result string) {
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": result})
+}
@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/db"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/internal/config"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/internal/dbtest"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/models"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/payments"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/razorpay"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/subscriptions"
+)
+
+const webhookSecret = "whsec_test"
+
+// useWebhookSecret loads a configuration with the webhook secret set. The
+// rest of it is left invalid, which the webhook handler does not mind.
+func useWebhookSecret(t *testing.T, secret string) {
+	t.Helper()
+	t.Setenv("RAZORPAY_WEBHOOK_SECRET", secret)
+	config.Load()
+}
+
+// useDB points the handlers at a test database.
+func useDB(t *testing.T) *sql.DB {
+	t.Helper()
+	database := dbtest.Open(t)
+	previous := db.DB
+	db.DB = database
+	t.Cleanup(func() { db.DB = previous })
+	return database
+}
+
+func sign(body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// deliver posts body to the webhook handler as Razorpay would.
+func deliver(t *testing.T, body []byte, signature string) (int, string) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/razorpay", strings.NewReader(string(body)))
+	req.Header.Set("X-Razorpay-Signature", signature)
+	rec := httptest.NewRecorder()
+	HandleRazorpayWebhook(rec, req)
+	var ack struct{ Status string }
+	json.NewDecoder(rec.Body).Decode(&ack)
+	return rec.Code, ack.Status
+}
+
+func paymentEvent(t *testing.T, event string, entity razorpay.PaymentEntity) []byte {
+	t.Helper()
+	body, err := json.Marshal(map[string]any{
+		"event":   event,
+		"payload": map[string]any{"payment": map[string]any{"entity": entity}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func TestWebhookSignature(t *testing.T) {
+	body := paymentEvent(t, "payment.downtime.started", razorpay.PaymentEntity{})
+
+	useWebhookSecret(t, "")
+	if code, _ := deliver(t, body, sign(body, webhookSecret)); code != http.StatusServiceUnavailable {
+		t.Errorf("without a secret: status %d, want 503", code)
+	}
+
+	useWebhookSecret(t, webhookSecret)
+	for name, signature := range map[string]string{
+		"missing":         "",
+		"wrong secret":    sign(body, "whsec_other"),
+		"other body":      sign([]byte(`{}`), webhookSecret),
+		"not hexadecimal": "not-a-signature",
+	} {
+		if code, _ := deliver(t, body, signature); code != http.StatusUnauthorized {
+			t.Errorf("%s signature: status %d, want 401", name, code)
+		}
+	}
+	// Events the service does not act on are still acknowledged
+	if code, status := deliver(t, body, sign(body, webhookSecret)); code != http.StatusOK || status != "ignored" {
+		t.Errorf("valid signature: %d %q, want 200 ignored", code, status)
+	}
+}
+
+func TestWebhookTransition(t *testing.T) {
+	captured := razorpay.PaymentEntity{ID: "pay_1", OrderID: "order_1", Status: "captured"}
+	partial := razorpay.PaymentEntity{ID: "pay_1", OrderID: "order_1", RefundStatus: "partial"}
+	full := razorpay.PaymentEntity{ID: "pay_1", OrderID: "order_1", RefundStatus: "full"}
+	tests := []struct {
+		event  string
+		entity razorpay.PaymentEntity
+		target models.PaymentStatus
+	}{
+		{"payment.authorized", captured, models.PaymentStatusAuthorized},
+		{"payment.captured", captured, models.PaymentStatusCaptured},
+		{"payment.failed", captured, models.PaymentStatusFailed},
+		{"refund.processed", full, models.PaymentStatusRefunded},
+		{"refund.processed", partial, ""},
+		{"payment.captured", razorpay.PaymentEntity{OrderID: "order_1"}, ""},
+		{"order.paid", captured, ""},
+		// A subscription's charge updates the subscription, not a payment
+		{"subscription.charged", captured, ""},
+	}
+	for _, tt := range tests {
+		var event razorpay.WebhookEvent
+		if err := json.Unmarshal(paymentEvent(t, tt.event, tt.entity), &event); err != nil {
+			t.Fatal(err)
+		}
+		target, orderID, paymentID, ok := webhookTransition(event)
+		if ok != (tt.target != "") || ok && target != tt.target {
+			t.Errorf("%s (refund %q): %q ok %t, want %q", tt.event, tt.entity.RefundStatus, target, ok, tt.target)
+			continue
+		}
+		if ok && (orderID != "order_1" || paymentID != "pay_1") {
+			t.Errorf("%s finds order %q payment %q", tt.event, orderID, paymentID)
+		}
+	}
+}
+
+func TestWebhookReleasesVoidedAuthorization(t *testing.T) {
+	database := useDB(t)
+	useWebhookSecret(t, webhookSecret)
+	ctx := context.Background()
+
+	var id int
+	err := database.QueryRowContext(ctx, `INSERT INTO payments
+			(user_id, amount, currency, from_account, to_account, razorpay_order_id, razorpay_payment_id,
+			 status, created_at, capture_mode, authorized_at)
+		VALUES (9, 500, 'INR', 'ACC-FROM', 'ACC-TO', 'order_void', 'pay_void', 'voided', NOW(), 'manual', NOW())
+		RETURNING id`).Scan(&id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Razorpay refunds an authorization it was never asked to capture
+	body := paymentEvent(t, "refund.processed", razorpay.PaymentEntity{
+		ID: "pay_void", OrderID: "order_void", Amount: 50000, Status: "refunded", AmountRefunded: 50000, RefundStatus: "full",
+	})
+	if code, status := deliver(t, body, sign(body, webhookSecret)); code != http.StatusOK || status != "applied" {
+		t.Fatalf("refund of a voided payment: %d %q, want 200 applied", code, status)
+	}
+	p, err := payments.Get(ctx, database, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Status != string(models.PaymentStatusVoided) || p.AuthorizationReleasedAt == nil {
+		t.Errorf("payment is %s, released %v; want voided and released", p.Status, p.AuthorizationReleasedAt)
+	}
+	if resp := paymentResponse(p); resp.ReleasePending || resp.AuthorizationReleasedAt == nil {
+		t.Errorf("response %+v still shows the release pending", resp)
+	}
+
+	if code, status := deliver(t, body, sign(body, webhookSecret)); code != http.StatusOK || status != "duplicate" {
+		t.Errorf("redelivery: %d %q, want 200 duplicate", code, status)
+	}
+}
+
+func TestWebhookSubscription(t *testing.T) {
+	database := useDB(t)
+	useWebhookSecret(t, webhookSecret)
+	ctx := context.Background()
+
+	plan, err := subscriptions.CreatePlan(ctx, database, subscriptions.Plan{
+		RazorpayPlanID: "plan_hook", Name: "Pro", Amount: 499, Currency: "INR", Period: subscriptions.PeriodMonthly, Interval: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub, err := subscriptions.CreateSubscription(ctx, database, 9, plan, razorpay.SubscriptionEntity{ID: "sub_hook", Status: "created", TotalCount: 12})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event := func(id string, sentAt time.Time) []byte {
+		body, err := json.Marshal(map[string]any{
+			"event":      "subscription.charged",
+			"created_at": sentAt.Unix(),
+			"payload": map[string]any{
+				"subscription": map[string]any{"entity": razorpay.SubscriptionEntity{ID: id, Status: "active", PaidCount: 1, TotalCount: 12}},
+				"payment":      map[string]any{"entity": razorpay.PaymentEntity{ID: "pay_" + id, Amount: 49900, Currency: "INR", Status: "captured"}},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return body
+	}
+
+	now := time.Now()
+	tests := []struct {
+		name string
+		body []byte
+		want string
+	}{
+		{"charged", event("sub_hook", now), "applied"},
+		{"older redelivery", event("sub_hook", now.Add(-time.Minute)), "duplicate"},
+		{"created elsewhere", event("sub_other", now), "ignored"},
+	}
+	for _, tt := range tests {
+		if code, status := deliver(t, tt.body, sign(tt.body, webhookSecret)); code != http.StatusOK || status != tt.want {
+			t.Errorf("%s: %d %q, want 200 %s", tt.name, code, status, tt.want)
+		}
+	}
+
+	got, err := subscriptions.GetSubscription(ctx, database, 9, sub.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != subscriptions.StatusActive || got.PaidCount != 1 {
+		t.Errorf("subscription %s with %d paid cycles, want active with 1", got.Status, got.PaidCount)
+	}
+	// No payment is touched by a subscription's charge
+	var n int
+	if err := database.QueryRowContext(ctx, `SELECT COUNT(*) FROM payments`).Scan(&n); err != nil || n != 0 {
+		t.Errorf("%d payments (%v), want none", n, err)
+	}
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/currencies"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/db"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/links"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/models"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/payments"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/receipts"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/users"
+)
+
+var (
+	// receiptTemplates renders receipts; profiles supplies payer details,
+	// and account ages for risk screening.
+	receiptTemplates *receipts.Templates
+	profiles         *users.Client
+)
+
+// SetReceipts sets the templates and auth-service client used for receipts.
+func SetReceipts(t *receipts.Templates, c *users.Client) {
+	receiptTemplates, profiles = t, c
+}
+
+// GetPaymentReceipt renders the receipt for a paid payment
+// @Summary Download payment receipt
+// @Description Renders the receipt for a captured (or since refunded) payment as PDF or HTML, chosen by the Accept header; PDF when both are acceptable. Times are in the user's timezone from auth-service.
+// @Tags payments
+// @Produce application/pdf
+// @Produce text/html
+// @Security BearerAuth
+// @Param id path int true "Payment ID"
+// @Success 200 {file} file
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 406 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 502 {object} models.ErrorResponse
+// @Router /api/v1/payments/{id}/receipt [get]
+func GetPaymentReceipt(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Vary", "Accept")
+	format, ok := receipts.Negotiate(r.Header.Get("Accept"))
+	if !ok {
+		writeError(w, r, http.StatusNotAcceptable, "Not acceptable", "Receipts are available as application/pdf or text/html")
+		return
+	}
+
+	payment, err := payments.Get(r.Context(), db.DB, int(id))
+	if errors.Is(err, payments.ErrNotFound) || (err == nil && payment.UserID != uid) {
+		writeError(w, r, http.StatusNotFound, "Not found", "Payment not found")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to load payment", "payment_id", id, "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to load payment")
+		return
+	}
+	if !receipts.Available(models.PaymentStatus(payment.Status)) {
+		writeError(w, r, http.StatusConflict, "Invalid state",
+			fmt.Sprintf("No receipt for a payment in status %s; it must be captured first", payment.Status))
+		return
+	}
+
+	reg, err := currencies.Load(r.Context(), db.DB)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to load currencies", "payment_id", id, "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to load payment")
+		return
+	}
+
+	owner, err := profiles.Me(r.Context(), r.Header.Get("Authorization"))
+	if errors.Is(err, users.ErrUnauthorized) {
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized", "User not found")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to load user profile", "payment_id", id, "error", err)
+		writeError(w, r, http.StatusBadGateway, "Upstream error", "Could not load payer details")
+		return
+	}
+
+	// A payment received through a link was made by whoever paid the link
+	var linkPayer receipts.Payer
+	if payment.Incoming() {
+		p, err := links.PayerOf(r.Context(), db.DB, payment.ID)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Failed to load link payer", "payment_id", id, "error", err)
+			writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to load payment")
+			return
+		}
+		if p.Name != nil {
+			linkPayer.Name = *p.Name
+		}
+		if p.Email != nil {
+			linkPayer.Email = *p.Email
+		}
+	}
+
+	// Render fully before writing so a failure can still be reported
+	var buf bytes.Buffer
+	if err := receiptTemplates.Render(&buf, format, receipts.New(payment, reg.Lookup(payment.Currency), owner, linkPayer, time.Now())); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to render receipt", "payment_id", id, "format", format, "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Internal error", "Could not render receipt")
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	if format == receipts.FormatPDF {
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="receipt-%d.pdf"`, payment.ID))
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/audit"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/db"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/models"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/reconcile"
+)
+
+// reconciler runs reconciliations triggered through the admin API.
+var reconciler *reconcile.Reconciler
+
+// SetReconciler sets the reconciler used by the admin handlers.
+func SetReconciler(rec *reconcile.Reconciler) {
+	reconciler = rec
+}
+
+// TriggerReconciliation starts a reconciliation run
+// @Summary Start reconciliation run
+// @Description Starts a reconciliation against Razorpay in the background. Requires the admin role.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 202 {object} models.ReconciliationTriggerResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/admin/reconciliation/runs [post]
+func TriggerReconciliation(w http.ResponseWriter, r *http.Request) {
+	id, err := reconciler.Trigger(reconcile.TriggerManual)
+	if errors.Is(err, reconcile.ErrBusy) {
+		writeError(w, r, http.StatusConflict, "Conflict", "A reconciliation run is already in progress")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to start reconciliation", "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Internal error", "Could not start reconciliation")
+		return
+	}
+
+	slog.InfoContext(r.Context(), "Reconciliation started", "run_id", id)
+	recordAudit(r, audit.Event{
+		Action:       "admin.reconciliation.triggered",
+		ResourceType: "reconciliation_run",
+		ResourceID:   audit.ID(id),
+	})
+	writeJSON(w, http.StatusAccepted, models.ReconciliationTriggerResponse{
+		RunID:  id,
+		Status: string(reconcile.RunRunning),
+	})
+}
+
+// ListReconciliationRuns lists recent reconciliation runs
+// @Summary List reconciliation runs
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param limit query int false "Maximum results (default 20, max 100)"
+// @Success 200 {array} models.ReconciliationRun
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/admin/reconciliation/runs [get]
+func ListReconciliationRuns(w http.ResponseWriter, r *http.Request) {
+	var limit int
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "Invalid request", "limit must be an integer")
+			return
+		}
+		limit = n
+	}
+
+	runs, err := reconcile.ListRuns(r.Context(), db.DB, limit)
+	if !reconciliationFound(w, r, err) {
+		return
+	}
+	resp := make([]models.ReconciliationRun, 0, len(runs))
+	for _, run := range runs {
+		resp = append(resp, runResponse(run))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// GetReconciliationReport returns a run with its discrepancies
+// @Summary Get reconciliation report
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Run ID"
+// @Success 200 {object} models.ReconciliationReport
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/admin/reconciliation/runs/{id} [get]
+func GetReconciliationReport(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	rep, err := reconcile.GetReport(r.Context(), db.DB, id)
+	if !reconciliationFound(w, r, err) {
+		return
+	}
+	writeJSON(w, http.StatusOK, ReportResponse(rep))
+}
+
+// GetLatestReconciliationReport returns the most recent finished run
+// @Summary Get latest reconciliation report
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.ReconciliationReport
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/admin/reconciliation/runs/latest [get]
+func GetLatestReconciliationReport(w http.ResponseWriter, r *http.Request) {
+	rep, err := reconcile.LatestReport(r.Context(), db.DB)
+	if !reconciliationFound(w, r, err) {
+		return
+	}
+	writeJSON(w, http.StatusOK, ReportResponse(rep))
+}
+
+func reconciliationFound(w http.ResponseWriter, r *http.Request, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, reconcile.ErrNotFound):
+		writeError(w, r, http.StatusNotFound, "Not found", "Reconciliation run not found")
+	default:
+		slog.ErrorContext(r.Context(), "Reconciliation request failed", "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Internal error", "Could not load reconciliation runs")
+	}
+	return false
+}
+
+func runResponse(run reconcile.Run) models.ReconciliationRun {
+	return models.ReconciliationRun{
+		ID:            run.ID,
+		Trigger:       string(run.Trigger),
+		Status:        string(run.Status),
+		WindowStart:   run.WindowStart,
+		WindowEnd:     run.WindowEnd,
+		Scanned:       run.Scanned,
+		Corrected:     run.Corrected,
+		Discrepancies: run.Discrepancies,
+		Errors:        run.Errors,
+		Error:         run.Error,
+		StartedAt:     run.StartedAt,
+		FinishedAt:    run.FinishedAt,
+	}
+}
+
+// ReportResponse converts a report to its API form. The reconcile CLI
+// prints the same JSON.
+func ReportResponse(rep reconcile.Report) models.ReconciliationReport {
+	resp := models.ReconciliationReport{
+		ReconciliationRun: runResponse(rep.Run),
+		Items:             make([]models.ReconciliationDiscrepancy, 0, len(rep.Items)),
+	}
+	for _, d := range rep.Items {
+		resp.Items = append(resp.Items, models.ReconciliationDiscrepancy{
+			Kind:            string(d.Kind),
+			PaymentID:       d.PaymentID,
+			RazorpayOrderID: d.RazorpayOrderID,
+			LocalStatus:     d.LocalStatus,
+			GatewayStatus:   d.GatewayStatus,
+			LocalAmount:     d.LocalAmount,
+			GatewayAmount:   d.GatewayAmount,
+			Currency:        d.Currency,
+			Details:         d.Details,
+			CreatedAt:       d.CreatedAt,
+		})
+	}
+	return resp
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/middleware"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/models"
+	"github.com/gorilla/mux"
+)
+
+// writeError writes a models.ErrorResponse tagged with the request ID.
+func writeError(w http.ResponseWriter, r *http.Request, code int, errType, message string) {
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(models.ErrorResponse{
+		Error:     errType,
+		Message:   message,
+		RequestID: middleware.GetRequestID(r.Context()),
+	})
+}
+
+// writeJSON writes v as the response body with status code.
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+// userID returns the authenticated user set by middleware.JWTAuth, writing
+// a 401 if it is missing.
+func userID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, ok := r.Context().Value(middleware.UserIDContextKey).(int)
+	if !ok {
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized", "Invalid user context")
+	}
+	return id, ok
+}
+
+// pathID parses the {id} route variable, writing a 400 if it is invalid.
+func pathID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil || id <= 0 {
+		writeError(w, r, http.StatusBadRequest, "Invalid request", "Invalid ID in path")
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,370 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/audit"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/db"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/internal/config"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/middleware"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/models"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/payments"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/risk"
+)
+
+// riskEngine screens /pay; nil screens nothing.
+var riskEngine *risk.Engine
+
+// SetRisk sets the engine that screens payments made through /pay.
+func SetRisk(e *risk.Engine) {
+	riskEngine = e
+}
+
+// ListRiskReviews lists payments held for review
+// @Summary List payments held for review
+// @Description Lists payments risk screening held in pending_review, oldest first, with the rules that fired.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param limit query int false "Maximum results (default 50, max 100)"
+// @Success 200 {array} models.RiskAssessmentResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/admin/risk/reviews [get]
+func ListRiskReviews(w http.ResponseWriter, r *http.Request) {
+	filter := risk.Filter{Held: true}
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "Invalid request", "limit must be an integer")
+			return
+		}
+		filter.Limit = n
+	}
+	writeAssessments(w, r, filter)
+}
+
+// ListRiskAssessments lists screenings and their decisions
+// @Summary List risk assessments
+// @Description Audit of risk screening: every screened payment, newest first, with the rules that fired, the outcome and any admin decision. Blocked attempts have no payment_id.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param user_id query int false "Only this user's payments"
+// @Param outcome query string false "allow, review or block"
+// @Param from query string false "Start, inclusive: YYYY-MM-DD or RFC 3339"
+// @Param to query string false "End: YYYY-MM-DD includes that day, RFC 3339 is exclusive"
+// @Param limit query int false "Maximum results (default 50, max 100)"
+// @Success 200 {array} models.RiskAssessmentResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/admin/risk/assessments [get]
+func ListRiskAssessments(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var filter risk.Filter
+	if v := q.Get("user_id"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(w, r, http.StatusBadRequest, "Invalid request", "user_id must be a positive integer")
+			return
+		}
+		filter.UserID = n
+	}
+	switch outcome := risk.Outcome(q.Get("outcome")); outcome {
+	case "", risk.OutcomeAllow, risk.OutcomeReview, risk.OutcomeBlock:
+		filter.Outcome = outcome
+	default:
+		writeError(w, r, http.StatusBadRequest, "Invalid request", "outcome must be allow, review or block")
+		return
+	}
+	if s := q.Get("from"); s != "" {
+		t, _, err := parseStatementTime(s)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "Invalid request", "from must be YYYY-MM-DD or an RFC 3339 time")
+			return
+		}
+		filter.Since = t
+	}
+	if s := q.Get("to"); s != "" {
+		t, dateOnly, err := parseStatementTime(s)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "Invalid request", "to must be YYYY-MM-DD or an RFC 3339 time")
+			return
+		}
+		if dateOnly {
+			t = t.AddDate(0, 0, 1)
+		}
+		filter.Until = t
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "Invalid request", "limit must be an integer")
+			return
+		}
+		filter.Limit = n
+	}
+	writeAssessments(w, r, filter)
+}
+
+func writeAssessments(w http.ResponseWriter, r *http.Request, filter risk.Filter) {
+	assessments, err := risk.List(r.Context(), db.DB, filter)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to list risk assessments", "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Could not list assessments")
+		return
+	}
+	resp := make([]models.RiskAssessmentResponse, 0, len(assessments))
+	for _, a := range assessments {
+		resp = append(resp, assessmentResponse(a))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// GetRiskReview returns a screened payment with its assessment
+// @Summary Get a payment's risk review
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Payment ID"
+// @Success 200 {object} models.RiskReviewResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/admin/risk/reviews/{id} [get]
+func GetRiskReview(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	payment, err := payments.Get(r.Context(), db.DB, int(id))
+	if errors.Is(err, payments.ErrNotFound) {
+		writeError(w, r, http.StatusNotFound, "Not found", "Payment not found")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to load payment", "payment_id", id, "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to load payment")
+		return
+	}
+	a, err := risk.ForPayment(r.Context(), db.DB, id)
+	if errors.Is(err, risk.ErrNotFound) {
+		writeError(w, r, http.StatusNotFound, "Not found", "The payment was not screened")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to load risk assessment", "payment_id", id, "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to load assessment")
+		return
+	}
+	writeJSON(w, http.StatusOK, models.RiskReviewResponse{Payment: paymentResponse(payment), Assessment: assessmentResponse(a)})
+}
+
+// ApproveRiskReview releases a held payment
+// @Summary Approve a held payment
+// @Description Creates the Razorpay order for a payment held in pending_review and moves it to created; it is payable for the currency's PAYMENT_TTL from now.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Payment ID"
+// @Param decision body models.RiskDecisionRequest false "Reason"
+// @Success 200 {object} models.RiskReviewResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 502 {object} models.ErrorResponse
+// @Router /api/v1/admin/risk/reviews/{id}/approve [post]
+func ApproveRiskReview(w http.ResponseWriter, r *http.Request) {
+	decideRiskReview(w, r, risk.VerdictApprove)
+}
+
+// RejectRiskReview fails a held payment
+// @Summary Reject a held payment
+// @Description Moves a payment held in pending_review to failed. No Razorpay order is ever created for it.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Payment ID"
+// @Param decision body models.RiskDecisionRequest true "Reason"
+// @Success 200 {object} models.RiskReviewResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/admin/risk/reviews/{id}/reject [post]
+func RejectRiskReview(w http.ResponseWriter, r *http.Request) {
+	decideRiskReview(w, r, risk.VerdictReject)
+}
+
+// decideRiskReview records the admin's verdict on a held payment and
+// releases or fails it in the same transaction.
+func decideRiskReview(w http.ResponseWriter, r *http.Request, verdict risk.Verdict) {
+	admin, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.RiskDecisionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, r, http.StatusBadRequest, "Invalid request", "Failed to parse request body")
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if verdict == risk.VerdictReject && req.Reason == "" {
+		writeError(w, r, http.StatusBadRequest, "Invalid request", "reason is required to reject a payment")
+		return
+	}
+
+	tx, err := db.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to begin transaction", "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to start transaction")
+		return
+	}
+	defer tx.Rollback()
+
+	payment, err := payments.LockByID(r.Context(), tx, int(id))
+	if errors.Is(err, payments.ErrNotFound) {
+		writeError(w, r, http.StatusNotFound, "Not found", "Payment not found")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to load payment", "payment_id", id, "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to load payment")
+		return
+	}
+	a, err := risk.ForPayment(r.Context(), tx, id)
+	if errors.Is(err, risk.ErrNotFound) {
+		writeError(w, r, http.StatusNotFound, "Not found", "The payment was not screened")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to load risk assessment", "payment_id", id, "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to load assessment")
+		return
+	}
+	if payment.Status != string(models.PaymentStatusPendingReview) {
+		writeError(w, r, http.StatusConflict, "Invalid state",
+			fmt.Sprintf("Only payments held for review can be decided; payment is %s", payment.Status))
+		return
+	}
+
+	decision, err := risk.Decide(r.Context(), tx, a.ID, verdict, req.Reason, admin)
+	if errors.Is(err, risk.ErrDecided) {
+		writeError(w, r, http.StatusConflict, "Invalid state", "The payment has already been reviewed")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to record risk decision", "payment_id", id, "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to record decision")
+		return
+	}
+
+	// The decision is audited in the transaction that applies it
+	record := func(ctx context.Context, tx *sql.Tx) error {
+		_, err := audit.Append(ctx, tx, audit.Event{
+			Action:       "admin.risk_review.decided",
+			ResourceType: "payment",
+			ResourceID:   audit.ID(id),
+			Before:       map[string]any{"status": models.PaymentStatusPendingReview},
+			After:        map[string]any{"status": payment.Status, "decision": verdict},
+			Detail:       req.Reason,
+		})
+		if err != nil {
+			return fmt.Errorf("audit risk decision: %w", err)
+		}
+		return nil
+	}
+	if verdict == risk.VerdictApprove {
+		// Release commits, and stores the order against a failed payment if
+		// that fails once the order exists
+		ttl := config.Get().PaymentTTLFor(payment.Currency)
+		err = payments.Release(r.Context(), db.DB, tx, &payment, ttl, middleware.GetRequestID(r.Context()), record)
+	} else {
+		err = func() error {
+			if _, err := payments.Transition(r.Context(), tx, &payment, models.PaymentStatusFailed, ""); err != nil {
+				return err
+			}
+			if err := record(r.Context(), tx); err != nil {
+				return err
+			}
+			return tx.Commit()
+		}()
+	}
+	if errors.Is(err, payments.ErrGateway) {
+		slog.ErrorContext(r.Context(), "Razorpay order creation failed", "payment_id", id, "error", err)
+		writeError(w, r, http.StatusBadGateway, "Approval failed", "Could not create the payment order; the payment is still held")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to decide held payment", "payment_id", id, "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to record decision")
+		return
+	}
+
+	a.Decision = &decision
+	slog.InfoContext(r.Context(), "Held payment reviewed",
+		"payment_id", payment.ID,
+		"decision", verdict,
+		"admin_id", admin,
+		"status", payment.Status,
+	)
+	writeJSON(w, http.StatusOK, models.RiskReviewResponse{Payment: paymentResponse(payment), Assessment: assessmentResponse(a)})
+}
+
+func assessmentResponse(a risk.Assessment) models.RiskAssessmentResponse {
+	resp := models.RiskAssessmentResponse{
+		ID:        a.ID,
+		PaymentID: a.PaymentID,
+		UserID:    a.UserID,
+		Amount:    a.Amount,
+		Currency:  a.Currency,
+		ToAccount: a.ToAccount,
+		IP:        a.IP,
+		Country:   a.Country,
+		Score:     a.Score,
+		Outcome:   string(a.Outcome),
+		Rules:     make([]models.RiskHit, 0, len(a.Hits)),
+		RequestID: a.RequestID,
+		CreatedAt: a.CreatedAt,
+	}
+	for _, h := range a.Hits {
+		resp.Rules = append(resp.Rules, models.RiskHit{Rule: string(h.Rule), Score: h.Score, Detail: h.Detail})
+	}
+	if d := a.Decision; d != nil {
+		resp.Decision = &models.RiskDecisionResponse{
+			Decision:  string(d.Verdict),
+			Reason:    d.Reason,
+			DecidedBy: d.DecidedBy,
+			CreatedAt: d.CreatedAt,
+		}
+	}
+	return resp
+}
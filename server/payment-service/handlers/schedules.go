@@ -0,0 +1,416 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/beneficiaries"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/db"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/models"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/schedules"
+)
+
+// CreateSchedule schedules a payment
+// @Summary Schedule payment
+// @Description Schedules a payment for a future date, or repeats it daily, weekly or monthly until end_at or max_occurrences. Each occurrence creates a payment as /pay does.
+// @Tags schedules
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param schedule body models.ScheduleRequest true "Schedule"
+// @Success 201 {object} models.ScheduleResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/schedules [post]
+func CreateSchedule(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.ScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request", "Failed to parse request body")
+		return
+	}
+
+	payment := models.PaymentRequest{
+		Amount:        req.Amount,
+		FromAccount:   req.FromAccount,
+		ToAccount:     req.ToAccount,
+		BeneficiaryID: req.BeneficiaryID,
+		Currency:      req.Currency,
+		ExpiresIn:     req.ExpiresIn,
+		CaptureMode:   req.CaptureMode,
+	}
+	ttl, ok := checkPaymentRequest(w, r, &payment)
+	if !ok {
+		return
+	}
+	if req.ExpiresIn == "" {
+		ttl = 0 // use the currency's TTL when each occurrence runs
+	}
+
+	if req.BeneficiaryID != nil {
+		_, err := beneficiaries.Get(r.Context(), db.DB, uid, *req.BeneficiaryID)
+		if errors.Is(err, beneficiaries.ErrNotFound) {
+			writeError(w, r, http.StatusBadRequest, "Unknown beneficiary", "beneficiary_id does not match a saved beneficiary")
+			return
+		}
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Failed to load beneficiary", "beneficiary_id", *req.BeneficiaryID, "error", err)
+			writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to load beneficiary")
+			return
+		}
+	}
+
+	s := schedules.Schedule{
+		UserID:         uid,
+		Amount:         payment.Amount,
+		Currency:       payment.Currency,
+		FromAccount:    payment.FromAccount,
+		ToAccount:      payment.ToAccount,
+		BeneficiaryID:  payment.BeneficiaryID,
+		CaptureMode:    payment.CaptureMode,
+		TTL:            ttl,
+		Description:    req.Description,
+		Frequency:      schedules.Frequency(req.Frequency),
+		StartAt:        req.StartAt,
+		EndAt:          req.EndAt,
+		MaxOccurrences: req.MaxOccurrences,
+	}
+	if err := schedules.Validate(&s, time.Now()); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid schedule", err.Error())
+		return
+	}
+
+	s, err := schedules.Create(r.Context(), db.DB, s)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to create schedule", "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Could not save schedule")
+		return
+	}
+
+	slog.InfoContext(r.Context(), "Schedule created", "schedule_id", s.ID, "frequency", s.Frequency, "next_run_at", s.NextRunAt)
+	writeJSON(w, http.StatusCreated, scheduleResponse(s))
+}
+
+// ListSchedules lists the caller's schedules
+// @Summary List schedules
+// @Tags schedules
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.ScheduleResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/schedules [get]
+func ListSchedules(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+
+	list, err := schedules.List(r.Context(), db.DB, uid)
+	if !scheduleFound(w, r, err) {
+		return
+	}
+	resp := make([]models.ScheduleResponse, 0, len(list))
+	for _, s := range list {
+		resp = append(resp, scheduleResponse(s))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// GetSchedule returns one of the caller's schedules
+// @Summary Get schedule
+// @Tags schedules
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Schedule ID"
+// @Success 200 {object} models.ScheduleResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/schedules/{id} [get]
+func GetSchedule(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	s, err := schedules.Get(r.Context(), db.DB, uid, id)
+	if !scheduleFound(w, r, err) {
+		return
+	}
+	writeJSON(w, http.StatusOK, scheduleResponse(s))
+}
+
+// UpdateSchedule changes one of the caller's schedules
+// @Summary Update schedule
+// @Description Changes the amount, description or end of an active or paused schedule. Occurrences already created are unaffected.
+// @Tags schedules
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Schedule ID"
+// @Param schedule body models.UpdateScheduleRequest true "Changes"
+// @Success 200 {object} models.ScheduleResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/schedules/{id} [patch]
+func UpdateSchedule(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request", "Failed to parse request body")
+		return
+	}
+	if req.Amount != nil && *req.Amount <= 0 {
+		writeError(w, r, http.StatusBadRequest, "Invalid amount", "Amount must be positive")
+		return
+	}
+	if req.MaxOccurrences != nil && *req.MaxOccurrences <= 0 {
+		writeError(w, r, http.StatusBadRequest, "Invalid schedule", "max_occurrences must be positive")
+		return
+	}
+
+	s, err := schedules.Update(r.Context(), db.DB, uid, id, schedules.Changes{
+		Amount:         req.Amount,
+		Description:    req.Description,
+		EndAt:          req.EndAt,
+		MaxOccurrences: req.MaxOccurrences,
+	})
+	if !scheduleFound(w, r, err) {
+		return
+	}
+	slog.InfoContext(r.Context(), "Schedule updated", "schedule_id", s.ID, "status", s.Status)
+	writeJSON(w, http.StatusOK, scheduleResponse(s))
+}
+
+// CancelSchedule cancels one of the caller's schedules
+// @Summary Cancel schedule
+// @Description Stops the schedule for good. Payments already created are unaffected; occurrences waiting for a retry are skipped.
+// @Tags schedules
+// @Security BearerAuth
+// @Param id path int true "Schedule ID"
+// @Success 204
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/schedules/{id} [delete]
+func CancelSchedule(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	_, err := schedules.Cancel(r.Context(), db.DB, uid, id)
+	if !scheduleFound(w, r, err) {
+		return
+	}
+	slog.InfoContext(r.Context(), "Schedule cancelled", "schedule_id", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PauseSchedule pauses one of the caller's schedules
+// @Summary Pause schedule
+// @Tags schedules
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Schedule ID"
+// @Success 200 {object} models.ScheduleResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /api/v1/schedules/{id}/pause [post]
+func PauseSchedule(w http.ResponseWriter, r *http.Request) {
+	changeSchedule(w, r, "paused", func(uid int, id int64) (schedules.Schedule, error) {
+		return schedules.Pause(r.Context(), db.DB, uid, id)
+	})
+}
+
+// ResumeSchedule resumes one of the caller's paused schedules
+// @Summary Resume schedule
+// @Description Reactivates a paused schedule. Occurrences that fell due while it was paused are recorded as skipped.
+// @Tags schedules
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Schedule ID"
+// @Success 200 {object} models.ScheduleResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /api/v1/schedules/{id}/resume [post]
+func ResumeSchedule(w http.ResponseWriter, r *http.Request) {
+	changeSchedule(w, r, "resumed", func(uid int, id int64) (schedules.Schedule, error) {
+		return schedules.Resume(r.Context(), db.DB, uid, id, time.Now())
+	})
+}
+
+// SkipNextOccurrence skips the next occurrence of one of the caller's
+// schedules
+// @Summary Skip next occurrence
+// @Description Skips the next occurrence. It still counts towards max_occurrences.
+// @Tags schedules
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Schedule ID"
+// @Success 200 {object} models.ScheduleResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /api/v1/schedules/{id}/skip-next [post]
+func SkipNextOccurrence(w http.ResponseWriter, r *http.Request) {
+	changeSchedule(w, r, "skipped next occurrence", func(uid int, id int64) (schedules.Schedule, error) {
+		return schedules.SkipNext(r.Context(), db.DB, uid, id)
+	})
+}
+
+// ListScheduleExecutions lists the occurrences of one of the caller's
+// schedules
+// @Summary List schedule executions
+// @Description Lists occurrences latest first, with their attempts, last error and the payment each created.
+// @Tags schedules
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Schedule ID"
+// @Param limit query int false "Maximum results (default 50, max 100)"
+// @Success 200 {array} models.ScheduleExecutionResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/schedules/{id}/executions [get]
+func ListScheduleExecutions(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "Invalid request", "limit must be an integer")
+			return
+		}
+		limit = n
+	}
+
+	list, err := schedules.ListExecutions(r.Context(), db.DB, uid, id, limit)
+	if !scheduleFound(w, r, err) {
+		return
+	}
+	resp := make([]models.ScheduleExecutionResponse, 0, len(list))
+	for _, e := range list {
+		item := models.ScheduleExecutionResponse{
+			ID:           e.ID,
+			Occurrence:   e.Occurrence,
+			ScheduledFor: e.ScheduledFor,
+			Status:       string(e.Status),
+			Attempts:     e.Attempts,
+			LastError:    e.LastError,
+			PaymentID:    e.PaymentID,
+			UpdatedAt:    e.UpdatedAt,
+		}
+		if e.Status == schedules.ExecutionPending {
+			item.NextAttemptAt = &e.NextAttemptAt
+		}
+		resp = append(resp, item)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// changeSchedule runs a state change on one of the caller's schedules and
+// writes the result.
+func changeSchedule(w http.ResponseWriter, r *http.Request, action string, change func(uid int, id int64) (schedules.Schedule, error)) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	s, err := change(uid, id)
+	if !scheduleFound(w, r, err) {
+		return
+	}
+	slog.InfoContext(r.Context(), "Schedule "+action, "schedule_id", s.ID, "next_run_at", s.NextRunAt)
+	writeJSON(w, http.StatusOK, scheduleResponse(s))
+}
+
+func scheduleFound(w http.ResponseWriter, r *http.Request, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, schedules.ErrNotFound):
+		writeError(w, r, http.StatusNotFound, "Not found", "Schedule not found")
+	case errors.Is(err, schedules.ErrInvalidState):
+		writeError(w, r, http.StatusConflict, "Invalid state", err.Error())
+	default:
+		slog.ErrorContext(r.Context(), "Schedule request failed", "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Internal error", "Could not complete schedule request")
+	}
+	return false
+}
+
+func scheduleResponse(s schedules.Schedule) models.ScheduleResponse {
+	resp := models.ScheduleResponse{
+		ID:             s.ID,
+		Amount:         s.Amount,
+		Currency:       s.Currency,
+		FromAccount:    s.FromAccount,
+		ToAccount:      s.ToAccount,
+		BeneficiaryID:  s.BeneficiaryID,
+		CaptureMode:    s.CaptureMode,
+		Description:    s.Description,
+		Frequency:      string(s.Frequency),
+		StartAt:        s.StartAt,
+		EndAt:          s.EndAt,
+		MaxOccurrences: s.MaxOccurrences,
+		Occurrences:    s.Occurrences,
+		NextRunAt:      s.NextRunAt,
+		Status:         string(s.Status),
+		CreatedAt:      s.CreatedAt,
+		UpdatedAt:      s.UpdatedAt,
+	}
+	if s.TTL > 0 {
+		resp.ExpiresIn = s.TTL.String()
+	}
+	return resp
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/db"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/statements"
+)
+
+// ExportStatement streams the caller's statement for a period
+// @Summary Export account statement
+// @Description Streams the captured payments sent (debits) and received through payment links (credits), and the refunds and chargebacks reversing them, posted in [from, to), one section per currency, with opening and closing balances. Balances are the running net of the user's own entries from zero, since the service holds no funds.
+// @Description format=csv (default) has these columns, in this order; new columns are only ever appended:
+// @Description type, posted_at, entry_id, payment_id, currency, amount, balance, status, description, from_account, to_account, razorpay_order_id, razorpay_payment_id.
+// @Description type is opening_balance, payment, received, refund, chargeback or closing_balance; balance rows fill only type, posted_at, currency and balance. Amounts are plain decimals, negative for payments sent and positive for payments received; refunds and chargebacks have the opposite sign of their payment.
+// @Description format=jsonl writes one models.StatementLine per line in the same order. format=ofx writes an OFX 2.2 bank statement per currency with the closing balance as LEDGERBAL.
+// @Tags statements
+// @Produce text/csv
+// @Produce application/x-ofx
+// @Produce application/x-ndjson
+// @Security BearerAuth
+// @Param from query string false "Start, inclusive: YYYY-MM-DD or RFC 3339 (default: start of this month, UTC)"
+// @Param to query string false "End: YYYY-MM-DD includes that day, RFC 3339 is exclusive (default: now)"
+// @Param format query string false "csv, ofx or jsonl" default(csv)
+// @Success 200 {array} models.StatementLine
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/statements [get]
+func ExportStatement(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	format, ok := statements.ParseFormat(q.Get("format"))
+	if !ok {
+		writeError(w, r, http.StatusBadRequest, "Invalid request", "format must be csv, ofx or jsonl")
+		return
+	}
+
+	now := time.Now().UTC()
+	period := statements.Period{
+		From: time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC),
+		To:   now,
+	}
+	if s := q.Get("from"); s != "" {
+		t, _, err := parseStatementTime(s)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "Invalid request", "from must be YYYY-MM-DD or an RFC 3339 time")
+			return
+		}
+		period.From = t
+	}
+	if s := q.Get("to"); s != "" {
+		t, dateOnly, err := parseStatementTime(s)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "Invalid request", "to must be YYYY-MM-DD or an RFC 3339 time")
+			return
+		}
+		if dateOnly {
+			t = t.AddDate(0, 0, 1)
+		}
+		period.To = t
+	}
+	if !period.From.Before(period.To) {
+		writeError(w, r, http.StatusBadRequest, "Invalid request", "from must be before to")
+		return
+	}
+
+	// The export may take longer than the server's WriteTimeout: the
+	// deadline starts now and moves with every write
+	out := flushWriter{w: w, rc: http.NewResponseController(w)}
+	out.extend()
+
+	st, err := statements.Query(r.Context(), db.DB, uid, period)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to query statement", "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to load statement")
+		return
+	}
+	defer st.Close()
+
+	filename := fmt.Sprintf("statement-%s-%s.%s",
+		period.From.Format("20060102"), period.To.Add(-time.Nanosecond).Format("20060102"), format)
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.WriteHeader(http.StatusOK)
+
+	// The status is sent, so a failure from here on can only cut the
+	// download short
+	if err := st.Export(statements.NewWriter(format, out, uid, period, now)); err != nil {
+		slog.ErrorContext(r.Context(), "Statement export aborted", "format", format, "error", err)
+	}
+}
+
+// parseStatementTime accepts a date (midnight UTC) or an RFC 3339 time.
+func parseStatementTime(s string) (t time.Time, dateOnly bool, err error) {
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, true, nil
+	}
+	t, err = time.Parse(time.RFC3339, s)
+	return t, false, err
+}
+
+// statementWriteTimeout is how long a statement download may go without a
+// write before the connection is closed.
+const statementWriteTimeout = 30 * time.Second
+
+// flushWriter sends every write to the client straight away, so a long
+// export streams rather than collecting in the server's buffers, and gives
+// each write statementWriteTimeout to complete.
+type flushWriter struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+// extend moves the write deadline to statementWriteTimeout from now.
+func (f flushWriter) extend() {
+	// Writers that cannot set a deadline keep the server's
+	f.rc.SetWriteDeadline(time.Now().Add(statementWriteTimeout))
+}
+
+func (f flushWriter) Write(p []byte) (int, error) {
+	f.extend()
+	n, err := f.w.Write(p)
+	if err == nil {
+		f.rc.Flush()
+	}
+	return n, err
+}
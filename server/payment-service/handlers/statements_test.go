@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFlushWriterOutlastsWriteTimeout(t *testing.T) {
+	// Rows keep coming for longer than the server's write timeout
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		out := flushWriter{w: w, rc: http.NewResponseController(w)}
+		out.extend()
+		for range 6 {
+			time.Sleep(50 * time.Millisecond)
+			out.Write([]byte("row\n"))
+		}
+	}))
+	server.Config.WriteTimeout = 100 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil || strings.Count(string(body), "row\n") != 6 {
+		t.Errorf("read %q (%v), want all 6 rows", body, err)
+	}
+}
@@ -0,0 +1,566 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/audit"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/db"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/metrics"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/models"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/razorpay"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/subscriptions"
+)
+
+// CreatePlan creates a recurring plan
+// @Summary Create plan
+// @Description Creates the plan at Razorpay and makes it available to subscribe to. Plans cannot be edited; create a new one and deactivate the old one to change the price.
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param plan body models.PlanRequest true "Plan"
+// @Success 201 {object} models.PlanResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 502 {object} models.ErrorResponse
+// @Router /api/v1/admin/plans [post]
+func CreatePlan(w http.ResponseWriter, r *http.Request) {
+	var req models.PlanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request", "Failed to parse request body")
+		return
+	}
+
+	plan := subscriptions.Plan{
+		Name:        req.Name,
+		Description: req.Description,
+		Amount:      req.Amount,
+		Currency:    req.Currency,
+		Period:      subscriptions.Period(req.Period),
+		Interval:    req.Interval,
+		TrialDays:   req.TrialDays,
+	}
+	if err := subscriptions.ValidatePlan(&plan); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid plan", err.Error())
+		return
+	}
+	cur, ok := supportedCurrency(w, r, plan.Currency, plan.Amount)
+	if !ok {
+		return
+	}
+
+	description := ""
+	if plan.Description != nil {
+		description = *plan.Description
+	}
+	entity, err := razorpay.CreatePlan(r.Context(), string(plan.Period), plan.Interval, plan.Name, description,
+		cur.ToMinor(plan.Amount), plan.Currency, map[string]interface{}{"trial_days": plan.TrialDays})
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Razorpay plan creation failed", "error", err)
+		writeError(w, r, http.StatusBadGateway, "Plan creation failed", "Razorpay could not create the plan")
+		return
+	}
+	plan.RazorpayPlanID = entity.ID
+
+	plan, err = subscriptions.CreatePlan(r.Context(), db.DB, plan)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to save plan", "razorpay_plan_id", entity.ID, "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Could not save plan")
+		return
+	}
+
+	slog.InfoContext(r.Context(), "Plan created", "plan_id", plan.ID, "razorpay_plan_id", plan.RazorpayPlanID)
+	resp := planResponse(plan)
+	recordAudit(r, audit.Event{
+		Action:       "admin.plan.created",
+		ResourceType: "plan",
+		ResourceID:   audit.ID(plan.ID),
+		After:        resp,
+	})
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+// UpdatePlan opens or closes a plan to new subscriptions
+// @Summary Activate or deactivate plan
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Plan ID"
+// @Param plan body models.PlanUpdateRequest true "Plan status"
+// @Success 200 {object} models.PlanResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/v1/admin/plans/{id} [patch]
+func UpdatePlan(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	var req models.PlanUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request", "Failed to parse request body")
+		return
+	}
+
+	before, err := subscriptions.GetPlan(r.Context(), db.DB, id)
+	if !subscriptionFound(w, r, err, "Plan") {
+		return
+	}
+	plan, err := subscriptions.SetPlanActive(r.Context(), db.DB, id, req.Active)
+	if !subscriptionFound(w, r, err, "Plan") {
+		return
+	}
+	slog.InfoContext(r.Context(), "Plan updated", "plan_id", plan.ID, "active", plan.Active)
+	resp := planResponse(plan)
+	recordAudit(r, audit.Event{
+		Action:       "admin.plan.updated",
+		ResourceType: "plan",
+		ResourceID:   audit.ID(plan.ID),
+		Before:       planResponse(before),
+		After:        resp,
+	})
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// ListPlans lists the plans open to new subscriptions
+// @Summary List plans
+// @Tags subscriptions
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.PlanResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/plans [get]
+func ListPlans(w http.ResponseWriter, r *http.Request) {
+	list, err := subscriptions.ListPlans(r.Context(), db.DB, true)
+	if !subscriptionFound(w, r, err, "Plan") {
+		return
+	}
+	resp := make([]models.PlanResponse, 0, len(list))
+	for _, p := range list {
+		resp = append(resp, planResponse(p))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// GetPlan returns a plan
+// @Summary Get plan
+// @Tags subscriptions
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Plan ID"
+// @Success 200 {object} models.PlanResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/v1/plans/{id} [get]
+func GetPlan(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	plan, err := subscriptions.GetPlan(r.Context(), db.DB, id)
+	if !subscriptionFound(w, r, err, "Plan") {
+		return
+	}
+	writeJSON(w, http.StatusOK, planResponse(plan))
+}
+
+// CreateSubscription subscribes the caller to a plan
+// @Summary Subscribe
+// @Description Creates a Razorpay subscription. The customer authorizes the recurring payment at short_url; plans with a trial are first charged when it ends.
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param subscription body models.SubscriptionRequest true "Subscription"
+// @Success 201 {object} models.SubscriptionResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 502 {object} models.ErrorResponse
+// @Router /api/v1/subscriptions [post]
+func CreateSubscription(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.SubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request", "Failed to parse request body")
+		return
+	}
+	if req.TotalCount <= 0 {
+		writeError(w, r, http.StatusBadRequest, "Invalid subscription", "total_count must be positive")
+		return
+	}
+	if req.Quantity == 0 {
+		req.Quantity = 1
+	}
+	if req.Quantity < 0 {
+		writeError(w, r, http.StatusBadRequest, "Invalid subscription", "quantity must be positive")
+		return
+	}
+
+	plan, err := subscriptions.GetPlan(r.Context(), db.DB, req.PlanID)
+	if errors.Is(err, subscriptions.ErrNotFound) || (err == nil && !plan.Active) {
+		writeError(w, r, http.StatusBadRequest, "Unknown plan", "plan_id does not match an active plan")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to load plan", "plan_id", req.PlanID, "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to load plan")
+		return
+	}
+
+	entity, err := razorpay.CreateSubscription(r.Context(), plan.RazorpayPlanID, req.TotalCount, req.Quantity,
+		plan.TrialStart(time.Now()), map[string]interface{}{
+			"user_id": uid,
+			"plan_id": plan.ID,
+		})
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Razorpay subscription creation failed", "plan_id", plan.ID, "error", err)
+		writeError(w, r, http.StatusBadGateway, "Subscription failed", "Razorpay could not create the subscription")
+		return
+	}
+
+	sub, err := subscriptions.CreateSubscription(r.Context(), db.DB, uid, plan, entity)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to save subscription", "razorpay_subscription_id", entity.ID, "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Could not save subscription")
+		return
+	}
+
+	slog.InfoContext(r.Context(), "Subscription created", "subscription_id", sub.ID, "plan_id", plan.ID)
+	writeJSON(w, http.StatusCreated, subscriptionResponse(sub))
+}
+
+// ListSubscriptions lists the caller's subscriptions
+// @Summary List subscriptions
+// @Tags subscriptions
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.SubscriptionResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/subscriptions [get]
+func ListSubscriptions(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	list, err := subscriptions.ListSubscriptions(r.Context(), db.DB, uid)
+	if !subscriptionFound(w, r, err, "Subscription") {
+		return
+	}
+	resp := make([]models.SubscriptionResponse, 0, len(list))
+	for _, s := range list {
+		resp = append(resp, subscriptionResponse(s))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// GetSubscription returns one of the caller's subscriptions
+// @Summary Get subscription
+// @Tags subscriptions
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Subscription ID"
+// @Success 200 {object} models.SubscriptionResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/v1/subscriptions/{id} [get]
+func GetSubscription(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	sub, err := subscriptions.GetSubscription(r.Context(), db.DB, uid, id)
+	if !subscriptionFound(w, r, err, "Subscription") {
+		return
+	}
+	writeJSON(w, http.StatusOK, subscriptionResponse(sub))
+}
+
+// ListSubscriptionInvoices lists the paid cycles of one of the caller's
+// subscriptions
+// @Summary List subscription invoices
+// @Tags subscriptions
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Subscription ID"
+// @Success 200 {array} models.SubscriptionInvoiceResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/v1/subscriptions/{id}/invoices [get]
+func ListSubscriptionInvoices(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	list, err := subscriptions.ListInvoices(r.Context(), db.DB, uid, id)
+	if !subscriptionFound(w, r, err, "Subscription") {
+		return
+	}
+	resp := make([]models.SubscriptionInvoiceResponse, 0, len(list))
+	for _, i := range list {
+		resp = append(resp, models.SubscriptionInvoiceResponse{
+			ID:                i.ID,
+			Cycle:             i.Cycle,
+			RazorpayInvoiceID: i.RazorpayInvoiceID,
+			RazorpayPaymentID: i.RazorpayPaymentID,
+			Amount:            i.Amount,
+			Currency:          i.Currency,
+			PeriodStart:       i.PeriodStart,
+			PeriodEnd:         i.PeriodEnd,
+			CreatedAt:         i.CreatedAt,
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// PauseSubscription pauses one of the caller's active subscriptions
+// @Summary Pause subscription
+// @Description Pauses billing now. Razorpay only pauses active subscriptions.
+// @Tags subscriptions
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Subscription ID"
+// @Success 200 {object} models.SubscriptionResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 502 {object} models.ErrorResponse
+// @Router /api/v1/subscriptions/{id}/pause [post]
+func PauseSubscription(w http.ResponseWriter, r *http.Request) {
+	changeSubscription(w, r, "pause",
+		func(sub subscriptions.Subscription) error {
+			if sub.Status != subscriptions.StatusActive {
+				return fmt.Errorf("only active subscriptions can be paused; subscription is %s", sub.Status)
+			}
+			return nil
+		},
+		func(sub subscriptions.Subscription) (razorpay.SubscriptionEntity, bool, error) {
+			entity, err := razorpay.PauseSubscription(r.Context(), sub.RazorpaySubscriptionID)
+			return entity, false, err
+		})
+}
+
+// ResumeSubscription resumes one of the caller's paused subscriptions
+// @Summary Resume subscription
+// @Tags subscriptions
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Subscription ID"
+// @Success 200 {object} models.SubscriptionResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 502 {object} models.ErrorResponse
+// @Router /api/v1/subscriptions/{id}/resume [post]
+func ResumeSubscription(w http.ResponseWriter, r *http.Request) {
+	changeSubscription(w, r, "resume",
+		func(sub subscriptions.Subscription) error {
+			if sub.Status != subscriptions.StatusPaused {
+				return fmt.Errorf("only paused subscriptions can be resumed; subscription is %s", sub.Status)
+			}
+			return nil
+		},
+		func(sub subscriptions.Subscription) (razorpay.SubscriptionEntity, bool, error) {
+			entity, err := razorpay.ResumeSubscription(r.Context(), sub.RazorpaySubscriptionID)
+			return entity, false, err
+		})
+}
+
+// CancelSubscription cancels one of the caller's subscriptions
+// @Summary Cancel subscription
+// @Description Active subscriptions are cancelled at the end of the current billing cycle; subscriptions not yet billing are cancelled now.
+// @Tags subscriptions
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Subscription ID"
+// @Success 200 {object} models.SubscriptionResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 502 {object} models.ErrorResponse
+// @Router /api/v1/subscriptions/{id}/cancel [post]
+func CancelSubscription(w http.ResponseWriter, r *http.Request) {
+	changeSubscription(w, r, "cancel",
+		func(sub subscriptions.Subscription) error {
+			if sub.Status.Ended() {
+				return fmt.Errorf("subscription is already %s", sub.Status)
+			}
+			if sub.CancelAtPeriodEnd {
+				return errors.New("subscription is already cancelled at period end")
+			}
+			return nil
+		},
+		func(sub subscriptions.Subscription) (razorpay.SubscriptionEntity, bool, error) {
+			atPeriodEnd := sub.Status == subscriptions.StatusActive
+			entity, err := razorpay.CancelSubscription(r.Context(), sub.RazorpaySubscriptionID, atPeriodEnd)
+			return entity, atPeriodEnd, err
+		})
+}
+
+// changeSubscription loads one of the caller's subscriptions, writes a 409
+// unless check allows the change, runs the Razorpay lifecycle call and
+// stores the state Razorpay returns.
+func changeSubscription(w http.ResponseWriter, r *http.Request, action string,
+	check func(subscriptions.Subscription) error,
+	call func(subscriptions.Subscription) (entity razorpay.SubscriptionEntity, cancelAtPeriodEnd bool, err error)) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	sub, err := subscriptions.GetSubscription(r.Context(), db.DB, uid, id)
+	if !subscriptionFound(w, r, err, "Subscription") {
+		return
+	}
+	if err := check(sub); err != nil {
+		writeError(w, r, http.StatusConflict, "Invalid state", err.Error())
+		return
+	}
+
+	entity, cancelAtPeriodEnd, err := call(sub)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Razorpay subscription call failed", "action", action, "subscription_id", sub.ID, "error", err)
+		writeError(w, r, http.StatusBadGateway, "Subscription update failed", fmt.Sprintf("Razorpay could not %s the subscription", action))
+		return
+	}
+
+	sub, err = subscriptions.Sync(r.Context(), db.DB, sub.ID, entity, cancelAtPeriodEnd)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to store subscription state", "subscription_id", id, "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Updated at Razorpay but failed to record it; the next webhook will")
+		return
+	}
+	slog.InfoContext(r.Context(), "Subscription updated", "action", action, "subscription_id", sub.ID, "status", sub.Status)
+	writeJSON(w, http.StatusOK, subscriptionResponse(sub))
+}
+
+// applySubscriptionWebhook applies a verified subscription.* event from
+// Razorpay to the local subscription.
+func applySubscriptionWebhook(w http.ResponseWriter, r *http.Request, event razorpay.WebhookEvent) {
+	tx, err := db.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to begin transaction", "error", err)
+		metrics.GatewayWebhook(event.Event, "error")
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to start transaction")
+		return
+	}
+	defer tx.Rollback()
+
+	sub, changed, err := subscriptions.ApplyWebhook(r.Context(), tx, event)
+	if errors.Is(err, subscriptions.ErrNotFound) {
+		// Subscriptions created outside this service share the account
+		slog.WarnContext(r.Context(), "Razorpay webhook for unknown subscription", "event", event.Event)
+		metrics.GatewayWebhook(event.Event, "ignored")
+		writeWebhookAck(w, "ignored")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to apply subscription webhook", "event", event.Event, "error", err)
+		metrics.GatewayWebhook(event.Event, "error")
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Failed to update subscription")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		slog.ErrorContext(r.Context(), "Transaction commit failed", "subscription_id", sub.ID, "error", err)
+		metrics.GatewayWebhook(event.Event, "error")
+		writeError(w, r, http.StatusInternalServerError, "Transaction error", "Failed to update subscription")
+		return
+	}
+
+	if !changed {
+		metrics.GatewayWebhook(event.Event, "duplicate")
+		writeWebhookAck(w, "duplicate")
+		return
+	}
+	slog.InfoContext(r.Context(), "Subscription updated from Razorpay webhook",
+		"event", event.Event,
+		"subscription_id", sub.ID,
+		"status", sub.Status,
+		"paid_count", sub.PaidCount,
+	)
+	metrics.GatewayWebhook(event.Event, "applied")
+	writeWebhookAck(w, "applied")
+}
+
+func subscriptionFound(w http.ResponseWriter, r *http.Request, err error, what string) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, subscriptions.ErrNotFound):
+		writeError(w, r, http.StatusNotFound, "Not found", what+" not found")
+	default:
+		slog.ErrorContext(r.Context(), what+" request failed", "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Internal error", "Could not complete "+strings.ToLower(what)+" request")
+	}
+	return false
+}
+
+func planResponse(p subscriptions.Plan) models.PlanResponse {
+	return models.PlanResponse{
+		ID:             p.ID,
+		RazorpayPlanID: p.RazorpayPlanID,
+		Name:           p.Name,
+		Description:    p.Description,
+		Amount:         p.Amount,
+		Currency:       p.Currency,
+		Period:         string(p.Period),
+		Interval:       p.Interval,
+		TrialDays:      p.TrialDays,
+		Active:         p.Active,
+		CreatedAt:      p.CreatedAt,
+	}
+}
+
+func subscriptionResponse(s subscriptions.Subscription) models.SubscriptionResponse {
+	return models.SubscriptionResponse{
+		ID:                     s.ID,
+		PlanID:                 s.PlanID,
+		RazorpaySubscriptionID: s.RazorpaySubscriptionID,
+		Status:                 string(s.Status),
+		Quantity:               s.Quantity,
+		TotalCount:             s.TotalCount,
+		PaidCount:              s.PaidCount,
+		ShortURL:               s.ShortURL,
+		TrialEndsAt:            s.TrialEndsAt,
+		CurrentStart:           s.CurrentStart,
+		CurrentEnd:             s.CurrentEnd,
+		ChargeAt:               s.ChargeAt,
+		EndedAt:                s.EndedAt,
+		CancelAtPeriodEnd:      s.CancelAtPeriodEnd,
+		CreatedAt:              s.CreatedAt,
+		UpdatedAt:              s.UpdatedAt,
+	}
+}
@@ -0,0 +1,493 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/db"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/events"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/internal/config"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/models"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/webhooks"
+)
+
+// webhookDispatcher sends test events and manual retries.
+var webhookDispatcher *webhooks.Dispatcher
+
+// SetWebhookDispatcher sets the dispatcher used by the webhook handlers.
+func SetWebhookDispatcher(d *webhooks.Dispatcher) {
+	webhookDispatcher = d
+}
+
+// CreateWebhookEndpoint registers a webhook endpoint
+// @Summary Register webhook endpoint
+// @Description Registers a URL to receive signed payment event notifications. The signing secret is only returned here and on rotation.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param endpoint body models.WebhookEndpointRequest true "Endpoint"
+// @Success 201 {object} models.WebhookEndpointResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/webhooks/endpoints [post]
+func CreateWebhookEndpoint(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.WebhookEndpointRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request", "Failed to parse request body")
+		return
+	}
+	if req.URL == nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request", "url is required")
+		return
+	}
+	if !validateEndpointRequest(w, r, req) {
+		return
+	}
+
+	var description string
+	if req.Description != nil {
+		description = *req.Description
+	}
+	var eventTypes []string
+	if req.EventTypes != nil {
+		eventTypes = *req.EventTypes
+	}
+
+	ep, err := webhooks.CreateEndpoint(r.Context(), db.DB, uid, *req.URL, description, eventTypes)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to create webhook endpoint", "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Could not create webhook endpoint")
+		return
+	}
+
+	slog.InfoContext(r.Context(), "Webhook endpoint created", "endpoint_id", ep.ID)
+	writeJSON(w, http.StatusCreated, endpointResponse(ep, true))
+}
+
+// ListWebhookEndpoints lists the caller's webhook endpoints
+// @Summary List webhook endpoints
+// @Tags webhooks
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.WebhookEndpointResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/webhooks/endpoints [get]
+func ListWebhookEndpoints(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+
+	endpoints, err := webhooks.ListEndpoints(r.Context(), db.DB, uid)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to list webhook endpoints", "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Could not list webhook endpoints")
+		return
+	}
+
+	resp := make([]models.WebhookEndpointResponse, 0, len(endpoints))
+	for _, ep := range endpoints {
+		resp = append(resp, endpointResponse(ep, false))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// GetWebhookEndpoint returns one webhook endpoint
+// @Summary Get webhook endpoint
+// @Tags webhooks
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Endpoint ID"
+// @Success 200 {object} models.WebhookEndpointResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/v1/webhooks/endpoints/{id} [get]
+func GetWebhookEndpoint(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	ep, err := webhooks.GetEndpoint(r.Context(), db.DB, uid, id)
+	if !webhookFound(w, r, err) {
+		return
+	}
+	writeJSON(w, http.StatusOK, endpointResponse(ep, false))
+}
+
+// UpdateWebhookEndpoint changes a webhook endpoint
+// @Summary Update webhook endpoint
+// @Description Changes the URL, description, event filter or active flag; omitted fields are unchanged
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Endpoint ID"
+// @Param endpoint body models.WebhookEndpointRequest true "Fields to change"
+// @Success 200 {object} models.WebhookEndpointResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/v1/webhooks/endpoints/{id} [patch]
+func UpdateWebhookEndpoint(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.WebhookEndpointRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid request", "Failed to parse request body")
+		return
+	}
+	if !validateEndpointRequest(w, r, req) {
+		return
+	}
+
+	ep, err := webhooks.UpdateEndpoint(r.Context(), db.DB, uid, id, webhooks.EndpointUpdate{
+		URL:         req.URL,
+		Description: req.Description,
+		EventTypes:  req.EventTypes,
+		Active:      req.Active,
+	})
+	if !webhookFound(w, r, err) {
+		return
+	}
+	writeJSON(w, http.StatusOK, endpointResponse(ep, false))
+}
+
+// DeleteWebhookEndpoint removes a webhook endpoint
+// @Summary Delete webhook endpoint
+// @Description Removes the endpoint together with its delivery history
+// @Tags webhooks
+// @Security BearerAuth
+// @Param id path int true "Endpoint ID"
+// @Success 204
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/v1/webhooks/endpoints/{id} [delete]
+func DeleteWebhookEndpoint(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	if !webhookFound(w, r, webhooks.DeleteEndpoint(r.Context(), db.DB, uid, id)) {
+		return
+	}
+	slog.InfoContext(r.Context(), "Webhook endpoint deleted", "endpoint_id", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RotateWebhookSecret issues a new signing secret
+// @Summary Rotate webhook signing secret
+// @Description Issues a new secret. During the grace period (default WEBHOOK_SECRET_ROTATION_GRACE) deliveries carry signatures from both the new and the previous secret.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Endpoint ID"
+// @Param rotation body models.RotateSecretRequest false "Grace period"
+// @Success 200 {object} models.WebhookEndpointResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/v1/webhooks/endpoints/{id}/rotate-secret [post]
+func RotateWebhookSecret(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	grace := config.Get().WebhookSecretRotationGrace
+	var req models.RotateSecretRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, r, http.StatusBadRequest, "Invalid request", "Failed to parse request body")
+			return
+		}
+	}
+	if req.GracePeriod != "" {
+		d, err := time.ParseDuration(req.GracePeriod)
+		if err != nil || d < 0 || d > 7*24*time.Hour {
+			writeError(w, r, http.StatusBadRequest, "Invalid grace period", "grace_period must be a duration between 0s and 168h")
+			return
+		}
+		grace = d
+	}
+
+	ep, err := webhooks.RotateSecret(r.Context(), db.DB, uid, id, grace)
+	if !webhookFound(w, r, err) {
+		return
+	}
+	slog.InfoContext(r.Context(), "Webhook secret rotated", "endpoint_id", ep.ID, "grace_period", grace)
+	writeJSON(w, http.StatusOK, endpointResponse(ep, true))
+}
+
+// TestWebhookEndpoint sends a test event
+// @Summary Send test webhook
+// @Description Sends a signed webhook.test event to the endpoint right away and returns the resulting delivery
+// @Tags webhooks
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Endpoint ID"
+// @Success 200 {object} models.WebhookDeliveryResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/webhooks/endpoints/{id}/test [post]
+func TestWebhookEndpoint(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	del, err := webhookDispatcher.SendTest(r.Context(), uid, id)
+	if !webhookFound(w, r, err) {
+		return
+	}
+	writeJSON(w, http.StatusOK, deliveryResponse(del, nil))
+}
+
+// ListWebhookDeliveries lists recent deliveries
+// @Summary List webhook deliveries
+// @Tags webhooks
+// @Produce json
+// @Security BearerAuth
+// @Param endpoint_id query int false "Only deliveries to this endpoint"
+// @Param status query string false "pending, succeeded or failed"
+// @Param limit query int false "Maximum results (default 50, max 100)"
+// @Success 200 {array} models.WebhookDeliveryResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/webhooks/deliveries [get]
+func ListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	var filter webhooks.DeliveryFilter
+	if v := q.Get("endpoint_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "Invalid request", "endpoint_id must be an integer")
+			return
+		}
+		filter.EndpointID = id
+	}
+	switch status := webhooks.DeliveryStatus(q.Get("status")); status {
+	case "", webhooks.DeliveryPending, webhooks.DeliverySucceeded, webhooks.DeliveryFailed:
+		filter.Status = status
+	default:
+		writeError(w, r, http.StatusBadRequest, "Invalid request", "status must be pending, succeeded or failed")
+		return
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "Invalid request", "limit must be an integer")
+			return
+		}
+		filter.Limit = n
+	}
+
+	deliveries, err := webhooks.ListDeliveries(r.Context(), db.DB, uid, filter)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to list webhook deliveries", "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Database error", "Could not list deliveries")
+		return
+	}
+
+	resp := make([]models.WebhookDeliveryResponse, 0, len(deliveries))
+	for _, del := range deliveries {
+		item := deliveryResponse(del, nil)
+		item.Payload = nil
+		resp = append(resp, item)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// GetWebhookDelivery returns a delivery with its attempts
+// @Summary Get webhook delivery
+// @Tags webhooks
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Delivery ID"
+// @Success 200 {object} models.WebhookDeliveryResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/v1/webhooks/deliveries/{id} [get]
+func GetWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	del, attempts, err := webhooks.GetDelivery(r.Context(), db.DB, uid, id)
+	if !webhookFound(w, r, err) {
+		return
+	}
+	writeJSON(w, http.StatusOK, deliveryResponse(del, attempts))
+}
+
+// RetryWebhookDelivery re-sends a delivery
+// @Summary Retry webhook delivery
+// @Description Immediately re-sends the delivery with a fresh timestamp and signature, whatever its status, and returns the result
+// @Tags webhooks
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Delivery ID"
+// @Success 200 {object} models.WebhookDeliveryResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/webhooks/deliveries/{id}/retry [post]
+func RetryWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	uid, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	del, err := webhookDispatcher.Retry(r.Context(), uid, id)
+	if !webhookFound(w, r, err) {
+		return
+	}
+	slog.InfoContext(r.Context(), "Webhook delivery retried", "delivery_id", del.ID, "status", del.Status)
+	writeJSON(w, http.StatusOK, deliveryResponse(del, nil))
+}
+
+// validateEndpointRequest checks the fields present in req.
+func validateEndpointRequest(w http.ResponseWriter, r *http.Request, req models.WebhookEndpointRequest) bool {
+	if req.URL != nil {
+		u, err := url.Parse(*req.URL)
+		switch {
+		case err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http"):
+			writeError(w, r, http.StatusBadRequest, "Invalid URL", "url must be an absolute http(s) URL")
+			return false
+		case u.Scheme != "https" && config.Get().IsProduction():
+			writeError(w, r, http.StatusBadRequest, "Invalid URL", "url must use https")
+			return false
+		}
+	}
+	if req.EventTypes != nil {
+		for _, t := range *req.EventTypes {
+			if !events.IsKnown(events.Type(t)) {
+				writeError(w, r, http.StatusBadRequest, "Invalid event type", "Unknown event type "+strconv.Quote(t))
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// webhookFound writes the error response for err, if any, and reports
+// whether the handler should continue.
+func webhookFound(w http.ResponseWriter, r *http.Request, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, webhooks.ErrNotFound):
+		writeError(w, r, http.StatusNotFound, "Not found", "Webhook endpoint or delivery not found")
+	default:
+		slog.ErrorContext(r.Context(), "Webhook request failed", "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Internal error", "Could not complete webhook request")
+	}
+	return false
+}
+
+func endpointResponse(ep webhooks.Endpoint, withSecret bool) models.WebhookEndpointResponse {
+	resp := models.WebhookEndpointResponse{
+		ID:          ep.ID,
+		URL:         ep.URL,
+		Description: ep.Description,
+		EventTypes:  ep.EventTypes,
+		Active:      ep.Active,
+		CreatedAt:   ep.CreatedAt,
+		UpdatedAt:   ep.UpdatedAt,
+	}
+	if resp.EventTypes == nil {
+		resp.EventTypes = []string{}
+	}
+	if withSecret {
+		resp.Secret = ep.Secret
+	}
+	if ep.PreviousSecretExpiresAt != nil && ep.PreviousSecretExpiresAt.After(time.Now()) {
+		resp.PreviousSecretExpiresAt = ep.PreviousSecretExpiresAt
+	}
+	return resp
+}
+
+func deliveryResponse(del webhooks.Delivery, attempts []webhooks.Attempt) models.WebhookDeliveryResponse {
+	resp := models.WebhookDeliveryResponse{
+		ID:            del.ID,
+		EndpointID:    del.EndpointID,
+		EventID:       del.EventID,
+		EventType:     del.EventType,
+		Status:        string(del.Status),
+		Attempts:      del.Attempts,
+		LastAttemptAt: del.LastAttemptAt,
+		ResponseCode:  del.ResponseCode,
+		LatencyMS:     del.LatencyMS,
+		LastError:     del.LastError,
+		CreatedAt:     del.CreatedAt,
+		Payload:       del.Payload,
+	}
+	if del.Status == webhooks.DeliveryPending {
+		resp.NextAttemptAt = &del.NextAttemptAt
+	}
+	for _, a := range attempts {
+		resp.AttemptLog = append(resp.AttemptLog, models.WebhookAttempt{
+			Attempt:      a.Attempt,
+			ResponseCode: a.ResponseCode,
+			ResponseBody: a.ResponseBody,
+			LatencyMS:    a.LatencyMS,
+			Error:        a.Error,
+			CreatedAt:    a.CreatedAt,
+		})
+	}
+	return resp
+}
@@ -0,0 +1,13 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+)
+
+// DBCheck pings the database.
+func DBCheck(db *sql.DB) CheckFunc {
+	return func(ctx context.Context) error {
+		return db.PingContext(ctx)
+	}
+}
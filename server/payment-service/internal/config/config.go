@@ -0,0 +1,455 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"net/netip"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// Config is the complete payment-service configuration.
+type Config struct {
+	Env  string `env:"APP_ENV" default:"development"`
+	Port string `env:"PORT" default:"8084"`
+
+	// ReadinessTimeout bounds each /readyz dependency check;
+	// ShutdownDrainDelay is how long /readyz fails before the server stops.
+	ReadinessTimeout   time.Duration `env:"READINESS_TIMEOUT" default:"2s"`
+	ShutdownDrainDelay time.Duration `env:"SHUTDOWN_DRAIN_DELAY" default:"5s"`
+
+	// DatabaseURL takes precedence; otherwise the DSN is built from DB_*.
+	DatabaseURL string `env:"DATABASE_URL" secret:"true"`
+	DBHost      string `env:"DB_HOST" default:"localhost"`
+	DBPort      string `env:"DB_PORT" default:"5432"`
+	DBUser      string `env:"DB_USER"`
+	DBPassword  string `env:"DB_PASSWORD" secret:"true"`
+	DBName      string `env:"DB_NAME"`
+	DBSSLMode   string `env:"DB_SSLMODE" default:"disable"`
+	AutoMigrate bool   `env:"AUTO_MIGRATE" default:"false"`
+
+	JWTSecret string `env:"JWT_SECRET" secret:"true"`
+
+	RazorpayKeyID     string `env:"RAZORPAY_KEY_ID,RAZORPAY_KEY"`
+	RazorpayKeySecret string `env:"RAZORPAY_KEY_SECRET,RAZORPAY_SECRET" secret:"true"`
+
+	// RazorpayWebhookSecret verifies X-Razorpay-Signature on
+	// POST /webhooks/razorpay; the endpoint rejects everything when unset.
+	RazorpayWebhookSecret string `env:"RAZORPAY_WEBHOOK_SECRET" secret:"true"`
+
+	// OutboxPublisher selects where the outbox relay sends domain events
+	// besides merchant webhooks: stdout, webhook, nats or none.
+	OutboxPublisher     string        `env:"OUTBOX_PUBLISHER" default:"stdout"`
+	OutboxWebhookURL    string        `env:"OUTBOX_WEBHOOK_URL"`
+	OutboxWebhookSecret string        `env:"OUTBOX_WEBHOOK_SECRET" secret:"true"`
+	NATSURL             string        `env:"NATS_URL" default:"nats://localhost:4222"`
+	NATSSubjectPrefix   string        `env:"NATS_SUBJECT_PREFIX" default:"gopay"`
+	OutboxPollInterval  time.Duration `env:"OUTBOX_POLL_INTERVAL" default:"1s"`
+	OutboxBatchSize     int           `env:"OUTBOX_BATCH_SIZE" default:"100"`
+	OutboxMaxBackoff    time.Duration `env:"OUTBOX_MAX_BACKOFF" default:"10m"`
+	OutboxRetention     time.Duration `env:"OUTBOX_RETENTION" default:"168h"`
+
+	// Merchant webhook deliveries. WebhookAllowPrivateNetworks lets
+	// endpoints resolve to loopback/private addresses (local development).
+	WebhookPollInterval         time.Duration `env:"WEBHOOK_POLL_INTERVAL" default:"2s"`
+	WebhookTimeout              time.Duration `env:"WEBHOOK_TIMEOUT" default:"10s"`
+	WebhookConcurrency          int           `env:"WEBHOOK_CONCURRENCY" default:"8"`
+	WebhookMaxAttempts          int           `env:"WEBHOOK_MAX_ATTEMPTS" default:"12"`
+	WebhookMaxBackoff           time.Duration `env:"WEBHOOK_MAX_BACKOFF" default:"6h"`
+	WebhookSecretRotationGrace  time.Duration `env:"WEBHOOK_SECRET_ROTATION_GRACE" default:"24h"`
+	WebhookAllowPrivateNetworks bool          `env:"WEBHOOK_ALLOW_PRIVATE_NETWORKS" default:"false"`
+
+	// Unpaid orders expire after PaymentTTL unless the currency has its own
+	// entry in PaymentTTLByCurrency ("USD=1h,EUR=1h") or the request asks
+	// for a TTL of at most PaymentMaxTTL.
+	PaymentTTL           time.Duration `env:"PAYMENT_TTL" default:"30m"`
+	PaymentTTLByCurrency []string      `env:"PAYMENT_TTL_BY_CURRENCY"`
+	PaymentMaxTTL        time.Duration `env:"PAYMENT_MAX_TTL" default:"24h"`
+	ExpirySweepInterval  time.Duration `env:"EXPIRY_SWEEP_INTERVAL" default:"1m"`
+
+	// AuthorizationTTL voids manual-capture authorizations nobody captured;
+	// keep it within Razorpay's auto-refund window (5 days by default).
+	AuthorizationTTL time.Duration `env:"AUTHORIZATION_TTL" default:"120h"`
+
+	// Reconciliation against Razorpay. RECONCILE_INTERVAL=0 disables the
+	// scheduled runs; admin and CLI runs still work.
+	ReconcileInterval  time.Duration `env:"RECONCILE_INTERVAL" default:"15m"`
+	ReconcileMinAge    time.Duration `env:"RECONCILE_MIN_AGE" default:"15m"`
+	ReconcileLookback  time.Duration `env:"RECONCILE_LOOKBACK" default:"72h"`
+	ReconcileBatchSize int           `env:"RECONCILE_BATCH_SIZE" default:"500"`
+
+	// Scheduled payments. A failed occurrence is retried up to
+	// ScheduleMaxRetries times with backoff capped at ScheduleMaxBackoff.
+	SchedulePollInterval time.Duration `env:"SCHEDULE_POLL_INTERVAL" default:"30s"`
+	ScheduleMaxRetries   int           `env:"SCHEDULE_MAX_RETRIES" default:"3"`
+	ScheduleMaxBackoff   time.Duration `env:"SCHEDULE_MAX_BACKOFF" default:"1h"`
+
+	// Payment links are shared as PaymentLinkBaseURL/l/{code}, normally
+	// the API gateway's public address.
+	PaymentLinkBaseURL string        `env:"PAYMENT_LINK_BASE_URL" default:"http://localhost:8080"`
+	PaymentLinkMaxTTL  time.Duration `env:"PAYMENT_LINK_MAX_TTL" default:"2160h"`
+
+	// Receipts show the payer's profile from auth-service, fetched with the
+	// caller's token. ReceiptTemplateDir may hold receipt.html and/or
+	// receipt.pdf.tmpl to replace the built-in templates.
+	AuthServiceURL     string        `env:"AUTH_SERVICE_URL" default:"http://localhost:8083"`
+	AuthServiceTimeout time.Duration `env:"AUTH_SERVICE_TIMEOUT" default:"5s"`
+	ReceiptTemplateDir string        `env:"RECEIPT_TEMPLATE_DIR"`
+
+	// FXRatesFile, if set, is a CSV of exchange rates imported at startup.
+	// Quotes lock a rate for FXQuoteTTL and are refused when the newest
+	// rate for the pair is older than FXRateMaxAge.
+	FXRatesFile  string        `env:"FX_RATES_FILE"`
+	FXQuoteTTL   time.Duration `env:"FX_QUOTE_TTL" default:"5m"`
+	FXRateMaxAge time.Duration `env:"FX_RATE_MAX_AGE" default:"24h"`
+
+	// Risk screening of /pay: payments scoring RiskReviewScore are held
+	// for review and RiskBlockScore refused. RiskHighValue lists, per
+	// currency, the amount from which a new account's payment is high
+	// value. RiskIPRangesFile, if set, is a CSV of network,country
+	// imported at startup for the geography rule.
+	RiskReviewScore       int           `env:"RISK_REVIEW_SCORE" default:"50"`
+	RiskBlockScore        int           `env:"RISK_BLOCK_SCORE" default:"100"`
+	RiskNewAccountAge     time.Duration `env:"RISK_NEW_ACCOUNT_AGE" default:"168h"`
+	RiskHighValue         []string      `env:"RISK_HIGH_VALUE" default:"INR=50000"`
+	RiskBeneficiaryWindow time.Duration `env:"RISK_BENEFICIARY_WINDOW" default:"1h"`
+	RiskMaxBeneficiaries  int           `env:"RISK_MAX_BENEFICIARIES" default:"5"`
+	RiskNearLimitRatio    float64       `env:"RISK_NEAR_LIMIT_RATIO" default:"0.9"`
+	RiskIPRangesFile      string        `env:"RISK_IP_RANGES_FILE"`
+
+	// Each instance keeps the blocklist in memory, reloading it when
+	// changed through its own admin endpoints and otherwise when a poll
+	// every BlocklistRefreshInterval finds it changed.
+	BlocklistRefreshInterval time.Duration `env:"BLOCKLIST_REFRESH_INTERVAL" default:"10s"`
+
+	// Audit entries from both services are linked into the hash chain
+	// every AuditChainInterval after they commit.
+	AuditChainInterval time.Duration `env:"AUDIT_CHAIN_INTERVAL" default:"5s"`
+
+	// DisputeEvidenceMaxBytes bounds each evidence file uploaded against a
+	// dispute; files are stored in the database.
+	DisputeEvidenceMaxBytes int `env:"DISPUTE_EVIDENCE_MAX_BYTES" default:"5242880"`
+
+	// Requests from TrustedProxies, such as the API gateway, may name the
+	// client in X-Forwarded-For; from anywhere else the header is ignored.
+	// Entries are CIDRs or single addresses.
+	TrustedProxies []string `env:"TRUSTED_PROXIES" default:"127.0.0.1/32,::1/128"`
+
+	CORSAllowedOrigins []string `env:"CORS_ALLOWED_ORIGINS" default:"http://localhost:3000"`
+
+	LogLevel        string   `env:"LOG_LEVEL" default:"info"`
+	LogFormat       string   `env:"LOG_FORMAT" default:"json"`
+	LogRedactFields []string `env:"LOG_REDACT_FIELDS"`
+	TracesExporter  string   `env:"OTEL_TRACES_EXPORTER" default:"none"`
+
+	sources        map[string]string
+	paymentTTLs    map[string]time.Duration
+	riskHighValues map[string]float64
+	trustedProxies []netip.Prefix
+}
+
+var current = &Config{}
+
+// LoadEnv loads ../.env outside Docker. It runs before the logger is
+// configured, so failures are returned rather than logged.
+func LoadEnv() error {
+	if os.Getenv("RUNNING_IN_DOCKER") == "true" {
+		return nil
+	}
+	return godotenv.Load("../.env")
+}
+
+// Load reads the configuration from the environment, *_FILE secrets and the
+// optional CONFIG_FILE, then validates it. The returned Config is populated
+// even when validation fails so it can still be printed; every problem is
+// reported in the returned error.
+func Load() (*Config, error) {
+	cfg := &Config{}
+	l := newLoader()
+	l.fill(cfg)
+	cfg.sources = l.sources
+
+	v := &validator{errs: l.errs}
+	cfg.validate(v)
+
+	current = cfg
+	return cfg, v.err()
+}
+
+func (c *Config) validate(v *validator) {
+	switch c.Env {
+	case "development", "staging", "production":
+	default:
+		v.errorf("APP_ENV must be development, staging or production, got %q", c.Env)
+	}
+
+	if c.ReadinessTimeout <= 0 {
+		v.errorf("READINESS_TIMEOUT must be positive")
+	}
+	if c.ShutdownDrainDelay < 0 {
+		v.errorf("SHUTDOWN_DRAIN_DELAY must not be negative")
+	}
+
+	if c.DatabaseURL == "" {
+		v.require("DB_USER (or DATABASE_URL)", c.DBUser)
+		v.require("DB_NAME (or DATABASE_URL)", c.DBName)
+	} else if _, err := url.Parse(c.DatabaseURL); err != nil {
+		v.errorf("DATABASE_URL is not a valid URL")
+	}
+
+	v.require("RAZORPAY_KEY_ID", c.RazorpayKeyID)
+	v.require("RAZORPAY_KEY_SECRET", c.RazorpayKeySecret)
+
+	switch c.OutboxPublisher {
+	case "stdout", "nats", "none":
+	case "webhook":
+		if u, err := url.Parse(c.OutboxWebhookURL); err != nil || u.Scheme == "" || u.Host == "" {
+			v.errorf("OUTBOX_WEBHOOK_URL must be an absolute URL when OUTBOX_PUBLISHER=webhook")
+		}
+	default:
+		v.errorf("OUTBOX_PUBLISHER must be stdout, webhook, nats or none, got %q", c.OutboxPublisher)
+	}
+	if c.OutboxPollInterval <= 0 {
+		v.errorf("OUTBOX_POLL_INTERVAL must be positive")
+	}
+	if c.OutboxBatchSize <= 0 {
+		v.errorf("OUTBOX_BATCH_SIZE must be positive")
+	}
+	if c.WebhookTimeout <= 0 {
+		v.errorf("WEBHOOK_TIMEOUT must be positive")
+	}
+	if c.WebhookMaxAttempts <= 0 {
+		v.errorf("WEBHOOK_MAX_ATTEMPTS must be positive")
+	}
+	if c.PaymentTTL <= 0 {
+		v.errorf("PAYMENT_TTL must be positive")
+	}
+	if c.PaymentMaxTTL < c.PaymentTTL {
+		v.errorf("PAYMENT_MAX_TTL must be at least PAYMENT_TTL")
+	}
+	c.paymentTTLs = make(map[string]time.Duration, len(c.PaymentTTLByCurrency))
+	for _, entry := range c.PaymentTTLByCurrency {
+		currency, value, ok := strings.Cut(entry, "=")
+		ttl, err := time.ParseDuration(strings.TrimSpace(value))
+		currency = strings.ToUpper(strings.TrimSpace(currency))
+		if !ok || err != nil || ttl <= 0 || len(currency) != 3 {
+			v.errorf("PAYMENT_TTL_BY_CURRENCY entry %q must look like INR=30m", entry)
+			continue
+		}
+		if ttl > c.PaymentMaxTTL {
+			v.errorf("PAYMENT_TTL_BY_CURRENCY entry %q exceeds PAYMENT_MAX_TTL", entry)
+		}
+		c.paymentTTLs[currency] = ttl
+	}
+	if c.ExpirySweepInterval <= 0 {
+		v.errorf("EXPIRY_SWEEP_INTERVAL must be positive")
+	}
+	if c.AuthorizationTTL <= 0 {
+		v.errorf("AUTHORIZATION_TTL must be positive")
+	}
+	if c.ReconcileInterval < 0 {
+		v.errorf("RECONCILE_INTERVAL must not be negative")
+	}
+	if c.ReconcileMinAge <= 0 {
+		v.errorf("RECONCILE_MIN_AGE must be positive")
+	}
+	if c.ReconcileLookback <= c.ReconcileMinAge {
+		v.errorf("RECONCILE_LOOKBACK must be longer than RECONCILE_MIN_AGE")
+	}
+	if c.ReconcileBatchSize <= 0 {
+		v.errorf("RECONCILE_BATCH_SIZE must be positive")
+	}
+	if c.SchedulePollInterval <= 0 {
+		v.errorf("SCHEDULE_POLL_INTERVAL must be positive")
+	}
+	if c.ScheduleMaxRetries < 0 {
+		v.errorf("SCHEDULE_MAX_RETRIES must not be negative")
+	}
+	if c.ScheduleMaxBackoff < time.Minute {
+		v.errorf("SCHEDULE_MAX_BACKOFF must be at least 1m")
+	}
+	if u, err := url.Parse(c.PaymentLinkBaseURL); err != nil || u.Scheme == "" || u.Host == "" {
+		v.errorf("PAYMENT_LINK_BASE_URL must be an absolute URL")
+	}
+	if c.PaymentLinkMaxTTL < time.Hour {
+		v.errorf("PAYMENT_LINK_MAX_TTL must be at least 1h")
+	}
+	if u, err := url.Parse(c.AuthServiceURL); err != nil || u.Scheme == "" || u.Host == "" {
+		v.errorf("AUTH_SERVICE_URL must be an absolute URL")
+	}
+	if c.AuthServiceTimeout <= 0 {
+		v.errorf("AUTH_SERVICE_TIMEOUT must be positive")
+	}
+	if c.ReceiptTemplateDir != "" {
+		if info, err := os.Stat(c.ReceiptTemplateDir); err != nil || !info.IsDir() {
+			v.errorf("RECEIPT_TEMPLATE_DIR must be an existing directory")
+		}
+	}
+	if c.FXRatesFile != "" {
+		if info, err := os.Stat(c.FXRatesFile); err != nil || info.IsDir() {
+			v.errorf("FX_RATES_FILE must be an existing file")
+		}
+	}
+	if c.FXQuoteTTL < 10*time.Second || c.FXQuoteTTL > time.Hour {
+		v.errorf("FX_QUOTE_TTL must be between 10s and 1h")
+	}
+	if c.FXRateMaxAge <= 0 {
+		v.errorf("FX_RATE_MAX_AGE must be positive")
+	}
+	if c.RiskReviewScore <= 0 {
+		v.errorf("RISK_REVIEW_SCORE must be positive")
+	}
+	if c.RiskBlockScore <= c.RiskReviewScore {
+		v.errorf("RISK_BLOCK_SCORE must be greater than RISK_REVIEW_SCORE")
+	}
+	if c.RiskNewAccountAge <= 0 {
+		v.errorf("RISK_NEW_ACCOUNT_AGE must be positive")
+	}
+	c.riskHighValues = make(map[string]float64, len(c.RiskHighValue))
+	for _, entry := range c.RiskHighValue {
+		currency, value, ok := strings.Cut(entry, "=")
+		amount, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		currency = strings.ToUpper(strings.TrimSpace(currency))
+		if !ok || err != nil || !(amount > 0) || len(currency) != 3 {
+			v.errorf("RISK_HIGH_VALUE entry %q must look like INR=50000", entry)
+			continue
+		}
+		c.riskHighValues[currency] = amount
+	}
+	if c.RiskBeneficiaryWindow <= 0 {
+		v.errorf("RISK_BENEFICIARY_WINDOW must be positive")
+	}
+	if c.RiskMaxBeneficiaries <= 0 {
+		v.errorf("RISK_MAX_BENEFICIARIES must be positive")
+	}
+	if !(c.RiskNearLimitRatio > 0 && c.RiskNearLimitRatio < 1) {
+		v.errorf("RISK_NEAR_LIMIT_RATIO must be between 0 and 1")
+	}
+	if c.RiskIPRangesFile != "" {
+		if info, err := os.Stat(c.RiskIPRangesFile); err != nil || info.IsDir() {
+			v.errorf("RISK_IP_RANGES_FILE must be an existing file")
+		}
+	}
+	if c.BlocklistRefreshInterval < time.Second {
+		v.errorf("BLOCKLIST_REFRESH_INTERVAL must be at least 1s")
+	}
+	c.trustedProxies = make([]netip.Prefix, 0, len(c.TrustedProxies))
+	for _, entry := range c.TrustedProxies {
+		prefix, err := parsePrefix(strings.TrimSpace(entry))
+		if err != nil {
+			v.errorf("TRUSTED_PROXIES entry %q must be a CIDR or an IP address", entry)
+			continue
+		}
+		c.trustedProxies = append(c.trustedProxies, prefix)
+	}
+	if c.AuditChainInterval <= 0 {
+		v.errorf("AUDIT_CHAIN_INTERVAL must be positive")
+	}
+	if c.DisputeEvidenceMaxBytes <= 0 {
+		v.errorf("DISPUTE_EVIDENCE_MAX_BYTES must be positive")
+	}
+
+	if c.IsProduction() {
+		v.strongSecret("JWT_SECRET", c.JWTSecret)
+		v.require("RAZORPAY_WEBHOOK_SECRET", c.RazorpayWebhookSecret)
+		if c.DatabaseURL == "" {
+			v.require("DB_PASSWORD", c.DBPassword)
+		}
+		if c.WebhookAllowPrivateNetworks {
+			v.errorf("WEBHOOK_ALLOW_PRIVATE_NETWORKS must be false in production")
+		}
+		if strings.HasPrefix(c.RazorpayKeyID, "rzp_test_") {
+			v.errorf("RAZORPAY_KEY_ID is a test key; use a live key in production")
+		}
+		for _, origin := range c.CORSAllowedOrigins {
+			if origin == "*" {
+				v.errorf("CORS_ALLOWED_ORIGINS must not contain * in production")
+			}
+		}
+	} else {
+		v.require("JWT_SECRET", c.JWTSecret)
+	}
+}
+
+// IsProduction reports whether APP_ENV is production.
+func (c *Config) IsProduction() bool {
+	return c.Env == "production"
+}
+
+// PaymentTTLFor returns how long an unpaid order in currency stays payable.
+func (c *Config) PaymentTTLFor(currency string) time.Duration {
+	if ttl, ok := c.paymentTTLs[strings.ToUpper(currency)]; ok {
+		return ttl
+	}
+	return c.PaymentTTL
+}
+
+// RiskHighValues returns the parsed RISK_HIGH_VALUE thresholds by currency.
+func (c *Config) RiskHighValues() map[string]float64 {
+	return c.riskHighValues
+}
+
+// TrustedProxyPrefixes returns the parsed TRUSTED_PROXIES.
+func (c *Config) TrustedProxyPrefixes() []netip.Prefix {
+	return c.trustedProxies
+}
+
+// parsePrefix parses a CIDR, or an address as a prefix of just itself.
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		return prefix.Masked(), err
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()), nil
+}
+
+// DSN returns the Postgres connection string.
+func (c *Config) DSN() string {
+	if c.DatabaseURL != "" {
+		return c.DatabaseURL
+	}
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.DBUser, c.DBPassword),
+		Host:     c.DBHost + ":" + c.DBPort,
+		Path:     "/" + c.DBName,
+		RawQuery: "sslmode=" + c.DBSSLMode,
+	}
+	return u.String()
+}
+
+// Print writes the effective configuration with secrets masked.
+func (c *Config) Print(w io.Writer) error {
+	return printConfig(w, c, c.sources)
+}
+
+// Deprecated lists the variables that were resolved through a deprecated
+// alias (e.g. RAZORPAY_KEY instead of RAZORPAY_KEY_ID).
+func (c *Config) Deprecated() []string {
+	var names []string
+	for name, source := range c.sources {
+		if strings.Contains(source, "deprecated") {
+			names = append(names, fmt.Sprintf("%s via %s", name, source))
+		}
+	}
+	return names
+}
+
+// Get returns the configuration most recently returned by Load.
+func Get() *Config {
+	return current
+}
+
+// GetJWTSecret returns the JWT signing secret from the loaded configuration.
+func GetJWTSecret() string {
+	return current.JWTSecret
+}
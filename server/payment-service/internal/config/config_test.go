@@ -0,0 +1,162 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// loadWith runs Load with only env set among the variables Config reads.
+func loadWith(t *testing.T, env map[string]string) (*Config, error) {
+	t.Helper()
+	t.Setenv("CONFIG_FILE", "")
+	ct := reflect.TypeOf(Config{})
+	for i := 0; i < ct.NumField(); i++ {
+		for _, name := range strings.Split(ct.Field(i).Tag.Get("env"), ",") {
+			if name != "" {
+				t.Setenv(name, "")
+				t.Setenv(name+"_FILE", "")
+			}
+		}
+	}
+	for k, v := range env {
+		t.Setenv(k, v)
+	}
+	prev := current
+	t.Cleanup(func() { current = prev })
+	return Load()
+}
+
+// with returns base plus extra.
+func with(base map[string]string, extra ...string) map[string]string {
+	env := make(map[string]string, len(base)+len(extra)/2)
+	for k, v := range base {
+		env[k] = v
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		env[extra[i]] = extra[i+1]
+	}
+	return env
+}
+
+var (
+	devEnv = map[string]string{
+		"DB_USER":             "gopay",
+		"DB_NAME":             "gopay",
+		"JWT_SECRET":          "dev",
+		"RAZORPAY_KEY_ID":     "rzp_test_key",
+		"RAZORPAY_KEY_SECRET": "rzp_secret",
+	}
+	prodEnv = with(devEnv,
+		"APP_ENV", "production",
+		"JWT_SECRET", strings.Repeat("s", 40),
+		"DB_PASSWORD", "db-password",
+		"RAZORPAY_KEY_ID", "rzp_live_key",
+		"RAZORPAY_WEBHOOK_SECRET", "whsec",
+		"CORS_ALLOWED_ORIGINS", "https://app.example.com",
+	)
+)
+
+func TestLoadValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr []string
+	}{
+		{name: "development", env: devEnv},
+		{name: "production", env: prodEnv},
+		{name: "database url instead of parts", env: with(devEnv, "DB_USER", "", "DB_NAME", "", "DATABASE_URL", "postgres://u:p@db/gopay")},
+		{
+			name:    "nothing set",
+			env:     nil,
+			wantErr: []string{"DB_USER (or DATABASE_URL) is required", "DB_NAME (or DATABASE_URL) is required", "RAZORPAY_KEY_ID is required", "RAZORPAY_KEY_SECRET is required", "JWT_SECRET is required"},
+		},
+		{name: "unknown environment", env: with(devEnv, "APP_ENV", "prod"), wantErr: []string{`APP_ENV must be development, staging or production, got "prod"`}},
+		{name: "legacy razorpay names", env: with(devEnv, "RAZORPAY_KEY_ID", "", "RAZORPAY_KEY_SECRET", "", "RAZORPAY_KEY", "rzp_test_old", "RAZORPAY_SECRET", "old")},
+		{name: "bad duration", env: with(devEnv, "PAYMENT_TTL", "soon"), wantErr: []string{`PAYMENT_TTL: invalid duration "soon"`, "PAYMENT_TTL must be positive"}},
+		{name: "max ttl below ttl", env: with(devEnv, "PAYMENT_TTL", "2h", "PAYMENT_MAX_TTL", "1h"), wantErr: []string{"PAYMENT_MAX_TTL must be at least PAYMENT_TTL"}},
+		{name: "currency ttl malformed", env: with(devEnv, "PAYMENT_TTL_BY_CURRENCY", "USD:1h"), wantErr: []string{`entry "USD:1h" must look like INR=30m`}},
+		{name: "currency ttl over max", env: with(devEnv, "PAYMENT_TTL_BY_CURRENCY", "USD=48h"), wantErr: []string{`entry "USD=48h" exceeds PAYMENT_MAX_TTL`}},
+		{name: "webhook publisher without url", env: with(devEnv, "OUTBOX_PUBLISHER", "webhook"), wantErr: []string{"OUTBOX_WEBHOOK_URL must be an absolute URL"}},
+		{name: "unknown publisher", env: with(devEnv, "OUTBOX_PUBLISHER", "kafka"), wantErr: []string{`OUTBOX_PUBLISHER must be stdout, webhook, nats or none, got "kafka"`}},
+		{name: "reconcile lookback too short", env: with(devEnv, "RECONCILE_LOOKBACK", "10m"), wantErr: []string{"RECONCILE_LOOKBACK must be longer than RECONCILE_MIN_AGE"}},
+		{name: "block score under review score", env: with(devEnv, "RISK_BLOCK_SCORE", "40"), wantErr: []string{"RISK_BLOCK_SCORE must be greater than RISK_REVIEW_SCORE"}},
+		{name: "high value malformed", env: with(devEnv, "RISK_HIGH_VALUE", "INR=lots"), wantErr: []string{`RISK_HIGH_VALUE entry "INR=lots"`}},
+		{name: "near limit ratio", env: with(devEnv, "RISK_NEAR_LIMIT_RATIO", "1"), wantErr: []string{"RISK_NEAR_LIMIT_RATIO must be between 0 and 1"}},
+		{name: "fx quote ttl", env: with(devEnv, "FX_QUOTE_TTL", "2h"), wantErr: []string{"FX_QUOTE_TTL must be between 10s and 1h"}},
+		{name: "missing rates file", env: with(devEnv, "FX_RATES_FILE", "/nonexistent/rates.csv"), wantErr: []string{"FX_RATES_FILE must be an existing file"}},
+		{name: "trusted proxy", env: with(devEnv, "TRUSTED_PROXIES", "10.0.0.0/8,gateway"), wantErr: []string{`TRUSTED_PROXIES entry "gateway"`}},
+		{name: "link base url", env: with(devEnv, "PAYMENT_LINK_BASE_URL", "gopay.example.com"), wantErr: []string{"PAYMENT_LINK_BASE_URL must be an absolute URL"}},
+		{name: "production placeholder secret", env: with(prodEnv, "JWT_SECRET", "changeme"), wantErr: []string{"JWT_SECRET uses a well-known placeholder value"}},
+		{name: "production short secret", env: with(prodEnv, "JWT_SECRET", "not-quite-long-enough"), wantErr: []string{"JWT_SECRET must be at least 32 characters in production"}},
+		{name: "production without secrets", env: with(prodEnv, "JWT_SECRET", "", "DB_PASSWORD", "", "RAZORPAY_WEBHOOK_SECRET", ""), wantErr: []string{"JWT_SECRET is required in production", "DB_PASSWORD is required", "RAZORPAY_WEBHOOK_SECRET is required"}},
+		{name: "production test key", env: with(prodEnv, "RAZORPAY_KEY_ID", "rzp_test_key"), wantErr: []string{"RAZORPAY_KEY_ID is a test key"}},
+		{name: "production private webhooks", env: with(prodEnv, "WEBHOOK_ALLOW_PRIVATE_NETWORKS", "true"), wantErr: []string{"WEBHOOK_ALLOW_PRIVATE_NETWORKS must be false in production"}},
+		{name: "production any origin", env: with(prodEnv, "CORS_ALLOWED_ORIGINS", "https://app.example.com,*"), wantErr: []string{"CORS_ALLOWED_ORIGINS must not contain * in production"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadWith(t, tt.env)
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("no error, want %q", tt.wantErr)
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error lacks %q:\n%v", want, err)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadParsesLists(t *testing.T) {
+	cfg, err := loadWith(t, with(devEnv,
+		"PAYMENT_TTL_BY_CURRENCY", "usd=1h, EUR=45m",
+		"RISK_HIGH_VALUE", "INR=50000,usd=600",
+		"TRUSTED_PROXIES", "10.0.0.0/8, 192.168.1.7",
+		"RAZORPAY_KEY", "rzp_test_old",
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for currency, want := range map[string]time.Duration{"USD": time.Hour, "eur": 45 * time.Minute, "INR": 30 * time.Minute} {
+		if got := cfg.PaymentTTLFor(currency); got != want {
+			t.Errorf("PaymentTTLFor(%s) = %v, want %v", currency, got, want)
+		}
+	}
+	if got := cfg.RiskHighValues(); !reflect.DeepEqual(got, map[string]float64{"INR": 50000, "USD": 600}) {
+		t.Errorf("RiskHighValues() = %v", got)
+	}
+	var proxies []string
+	for _, p := range cfg.TrustedProxyPrefixes() {
+		proxies = append(proxies, p.String())
+	}
+	if !reflect.DeepEqual(proxies, []string{"10.0.0.0/8", "192.168.1.7/32"}) {
+		t.Errorf("TrustedProxyPrefixes() = %v", proxies)
+	}
+	// RAZORPAY_KEY_ID is set, so the alias is ignored and not reported
+	if got := cfg.Deprecated(); len(got) != 0 {
+		t.Errorf("Deprecated() = %v, want none", got)
+	}
+	if Get() != cfg {
+		t.Error("Get does not return the loaded configuration")
+	}
+}
+
+func TestDeprecated(t *testing.T) {
+	cfg, err := loadWith(t, with(devEnv, "RAZORPAY_KEY_ID", "", "RAZORPAY_KEY", "rzp_test_old"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"RAZORPAY_KEY_ID via env RAZORPAY_KEY (deprecated, use RAZORPAY_KEY_ID)"}
+	if got := cfg.Deprecated(); !reflect.DeepEqual(got, want) || cfg.RazorpayKeyID != "rzp_test_old" {
+		t.Errorf("Deprecated() = %v with key %q, want %v", got, cfg.RazorpayKeyID, want)
+	}
+}
@@ -0,0 +1,258 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// Fields of a config struct are described with tags:
+//
+//	env:"NAME,LEGACY_NAME"  variable name, followed by deprecated aliases
+//	default:"value"         used when no source provides a value
+//	secret:"true"           masked by Print
+//
+// Each name is resolved, in order, from the environment, from the file named
+// by NAME_FILE (for Docker/Kubernetes secrets) and from the optional config
+// file named by CONFIG_FILE.
+
+// loader resolves struct fields and remembers where each value came from.
+type loader struct {
+	file    map[string]string
+	sources map[string]string
+	errs    []error
+}
+
+func newLoader() *loader {
+	l := &loader{file: map[string]string{}, sources: map[string]string{}}
+
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		values, err := readConfigFile(path)
+		if err != nil {
+			l.errs = append(l.errs, fmt.Errorf("CONFIG_FILE: %w", err))
+		} else {
+			l.file = values
+		}
+	}
+	return l
+}
+
+// readConfigFile reads a JSON object or a KEY=VALUE (.env style) file.
+func readConfigFile(path string) (map[string]string, error) {
+	if !strings.EqualFold(filepath.Ext(path), ".json") {
+		return godotenv.Read(path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(raw))
+	for k, v := range raw {
+		switch v := v.(type) {
+		case []any:
+			parts := make([]string, len(v))
+			for i, p := range v {
+				parts[i] = fmt.Sprint(p)
+			}
+			values[k] = strings.Join(parts, ",")
+		default:
+			values[k] = fmt.Sprint(v)
+		}
+	}
+	return values, nil
+}
+
+func (l *loader) lookup(names []string) (string, string, bool) {
+	for i, name := range names {
+		deprecated := ""
+		if i > 0 {
+			deprecated = " (deprecated, use " + names[0] + ")"
+		}
+
+		if v := os.Getenv(name); v != "" {
+			return v, "env " + name + deprecated, true
+		}
+		if path := os.Getenv(name + "_FILE"); path != "" {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				l.errs = append(l.errs, fmt.Errorf("%s_FILE: %w", name, err))
+				continue
+			}
+			return strings.TrimSpace(string(data)), "file " + path + deprecated, true
+		}
+		if v, ok := l.file[name]; ok {
+			return v, "config file" + deprecated, true
+		}
+	}
+	return "", "", false
+}
+
+// fill populates the tagged fields of the struct pointed to by dst.
+func (l *loader) fill(dst any) {
+	v := reflect.ValueOf(dst).Elem()
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("env")
+		if tag == "" {
+			continue
+		}
+		names := strings.Split(tag, ",")
+
+		raw, source, ok := l.lookup(names)
+		if !ok {
+			raw, source = sf.Tag.Get("default"), "default"
+		}
+		l.sources[names[0]] = source
+
+		if err := setField(v.Field(i), raw); err != nil {
+			l.errs = append(l.errs, fmt.Errorf("%s: %w", names[0], err))
+		}
+	}
+}
+
+func setField(f reflect.Value, raw string) error {
+	if raw == "" {
+		return nil
+	}
+
+	switch f.Interface().(type) {
+	case string:
+		f.SetString(raw)
+	case bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		f.SetBool(b)
+	case int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		f.SetInt(int64(n))
+	case float64:
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		f.SetFloat(n)
+	case time.Duration:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", raw)
+		}
+		f.SetInt(int64(d))
+	case []string:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		f.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported config field type %s", f.Type())
+	}
+	return nil
+}
+
+// printConfig writes every tagged field as NAME=value with its source,
+// masking fields tagged secret:"true".
+func printConfig(w io.Writer, cfg any, sources map[string]string) error {
+	v := reflect.ValueOf(cfg).Elem()
+	t := v.Type()
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tVALUE\tSOURCE")
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("env")
+		if tag == "" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+
+		value := formatValue(v.Field(i))
+		if sf.Tag.Get("secret") == "true" {
+			value = maskSecret(value)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", name, value, sources[name])
+	}
+	return tw.Flush()
+}
+
+func formatValue(f reflect.Value) string {
+	switch v := f.Interface().(type) {
+	case []string:
+		return strings.Join(v, ",")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func maskSecret(s string) string {
+	if s == "" {
+		return "(unset)"
+	}
+	return "******** (" + strconv.Itoa(len(s)) + " chars)"
+}
+
+// validator accumulates validation errors so they can be reported at once.
+type validator struct {
+	errs []error
+}
+
+func (v *validator) require(name, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.errorf("%s is required", name)
+	}
+}
+
+func (v *validator) errorf(format string, args ...any) {
+	v.errs = append(v.errs, fmt.Errorf(format, args...))
+}
+
+func (v *validator) err() error {
+	return errors.Join(v.errs...)
+}
+
+// weakSecrets are placeholder values that must never reach production.
+var weakSecrets = map[string]bool{
+	"secret":                      true,
+	"changeme":                    true,
+	"change-me":                   true,
+	"your-secret-key":             true,
+	"dev-secret-please-change-me": true,
+}
+
+// minSecretLength is the shortest JWT secret accepted in production.
+const minSecretLength = 32
+
+func (v *validator) strongSecret(name, value string) {
+	switch {
+	case value == "":
+		v.errorf("%s is required in production", name)
+	case weakSecrets[strings.ToLower(value)]:
+		v.errorf("%s uses a well-known placeholder value", name)
+	case len(value) < minSecretLength:
+		v.errorf("%s must be at least %d characters in production", name, minSecretLength)
+	}
+}
@@ -0,0 +1,31 @@
+// Package rzptest points the shared Razorpay client at a fake API so tests
+// can see and answer the calls the service makes.
+package rzptest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	sdk "github.com/razorpay/razorpay-go"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/razorpay"
+)
+
+// Serve starts h as the Razorpay API and points razorpay.Client at it until
+// t ends.
+func Serve(t testing.TB, h http.Handler) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(h)
+	prev := razorpay.Client
+	if err := razorpay.Init("rzp_test_key", "rzp_test_secret"); err != nil {
+		t.Fatal(err)
+	}
+	// The SDK keeps its request settings in a package variable
+	sdk.Request.BaseURL = server.URL
+	t.Cleanup(func() {
+		razorpay.Client = prev
+		server.Close()
+	})
+	return server
+}
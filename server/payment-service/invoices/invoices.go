@@ -0,0 +1,536 @@
+// Package invoices stores the GST invoices users raise for their customers:
+// line items with computed taxes, per-user sequential numbering on issue,
+// and settlement through a linked payment.
+package invoices
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/currencies"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/payments"
+)
+
+// ErrNotFound is returned when an invoice does not exist or belongs to
+// another user.
+var ErrNotFound = errors.New("invoice not found")
+
+// ErrInvalidState is returned when an operation does not apply to an
+// invoice's current status.
+var ErrInvalidState = errors.New("operation not allowed")
+
+// ErrPaymentMismatch is returned when linking a payment that cannot settle
+// the invoice.
+var ErrPaymentMismatch = errors.New("payment does not match invoice")
+
+// Status is an invoice's lifecycle state.
+type Status string
+
+const (
+	StatusDraft  Status = "draft"
+	StatusIssued Status = "issued"
+	StatusPaid   Status = "paid"
+	StatusVoid   Status = "void"
+)
+
+// MaxItems is the most line items an invoice may have.
+const MaxItems = 100
+
+// Item is one line of an invoice. Amounts are in the minor unit of the
+// invoice currency.
+type Item struct {
+	Description string
+	// HSNSAC is the HSN code for goods or the SAC code for services.
+	HSNSAC         string
+	Quantity       float64
+	UnitPriceMinor int64
+	// TaxRate is the GST rate in percent; intra-state supplies split it
+	// equally into CGST and SGST.
+	TaxRate float64
+
+	// Computed by Compute
+	AmountMinor int64
+	CGSTMinor   int64
+	SGSTMinor   int64
+	IGSTMinor   int64
+}
+
+// TaxMinor is the item's total GST.
+func (it Item) TaxMinor() int64 {
+	return it.CGSTMinor + it.SGSTMinor + it.IGSTMinor
+}
+
+// Invoice is a bill to a customer.
+type Invoice struct {
+	ID     int64
+	UserID int
+	// Number is assigned when the invoice is issued.
+	Number   *int
+	Status   Status
+	Currency string
+
+	SupplierName    string
+	SupplierGSTIN   string
+	SupplierAddress string
+	// SupplierState and PlaceOfSupply are two-digit GST state codes.
+	SupplierState string
+	PlaceOfSupply string
+
+	CustomerName    string
+	CustomerEmail   string
+	CustomerGSTIN   string
+	CustomerAddress string
+
+	Notes *string
+	Items []Item
+
+	// Computed by Compute
+	SubtotalMinor int64
+	CGSTMinor     int64
+	SGSTMinor     int64
+	IGSTMinor     int64
+	TotalMinor    int64
+
+	DueAt     *time.Time
+	IssuedAt  *time.Time
+	PaidAt    *time.Time
+	VoidedAt  *time.Time
+	PaymentID *int64
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// DisplayNumber is the invoice number as printed, e.g. INV-000042, or ""
+// for a draft.
+func (inv Invoice) DisplayNumber() string {
+	if inv.Number == nil {
+		return ""
+	}
+	return fmt.Sprintf("INV-%06d", *inv.Number)
+}
+
+// Intrastate reports whether the supply is within the supplier's state, in
+// which case GST is charged as CGST and SGST rather than IGST.
+func (inv Invoice) Intrastate() bool {
+	return inv.PlaceOfSupply == inv.SupplierState
+}
+
+var (
+	currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+	// State code, PAN, entity number, Z and a check character.
+	gstinPattern = regexp.MustCompile(`^[0-9]{2}[A-Z]{5}[0-9]{4}[A-Z][1-9A-Z]Z[0-9A-Z]$`)
+	statePattern = regexp.MustCompile(`^[0-9]{2}$`)
+	hsnPattern   = regexp.MustCompile(`^[0-9]{4,8}$`)
+)
+
+// Validate trims and checks a draft's fields, then computes its totals. The
+// supplier's state defaults to the one in its GSTIN and the place of supply
+// to the supplier's state. cur is inv's currency, whose minor unit the item
+// prices are in.
+func Validate(inv *Invoice, cur currencies.Currency) error {
+	inv.Currency = strings.ToUpper(strings.TrimSpace(inv.Currency))
+	if !currencyPattern.MatchString(inv.Currency) {
+		return errors.New("currency must be a three-letter ISO code")
+	}
+
+	inv.SupplierName = strings.TrimSpace(inv.SupplierName)
+	inv.CustomerName = strings.TrimSpace(inv.CustomerName)
+	if inv.SupplierName == "" || len(inv.SupplierName) > 128 {
+		return errors.New("supplier_name is required and must be at most 128 characters")
+	}
+	if inv.CustomerName == "" || len(inv.CustomerName) > 128 {
+		return errors.New("customer_name is required and must be at most 128 characters")
+	}
+	inv.CustomerEmail = strings.TrimSpace(inv.CustomerEmail)
+	if inv.CustomerEmail != "" && (len(inv.CustomerEmail) > 255 || !strings.Contains(inv.CustomerEmail, "@")) {
+		return errors.New("customer_email is not a valid address")
+	}
+	inv.SupplierAddress = strings.TrimSpace(inv.SupplierAddress)
+	inv.CustomerAddress = strings.TrimSpace(inv.CustomerAddress)
+
+	inv.SupplierGSTIN = strings.ToUpper(strings.TrimSpace(inv.SupplierGSTIN))
+	inv.CustomerGSTIN = strings.ToUpper(strings.TrimSpace(inv.CustomerGSTIN))
+	for name, gstin := range map[string]string{"supplier_gstin": inv.SupplierGSTIN, "customer_gstin": inv.CustomerGSTIN} {
+		if gstin != "" && !gstinPattern.MatchString(gstin) {
+			return fmt.Errorf("%s must look like 29ABCDE1234F1Z5", name)
+		}
+	}
+
+	inv.SupplierState = strings.TrimSpace(inv.SupplierState)
+	inv.PlaceOfSupply = strings.TrimSpace(inv.PlaceOfSupply)
+	if inv.SupplierState == "" && inv.SupplierGSTIN != "" {
+		inv.SupplierState = inv.SupplierGSTIN[:2]
+	}
+	if inv.PlaceOfSupply == "" {
+		inv.PlaceOfSupply = inv.SupplierState
+	}
+	if !statePattern.MatchString(inv.SupplierState) || !statePattern.MatchString(inv.PlaceOfSupply) {
+		return errors.New("supplier_state (or supplier_gstin) and place_of_supply must be two-digit GST state codes")
+	}
+
+	if len(inv.Items) == 0 || len(inv.Items) > MaxItems {
+		return fmt.Errorf("an invoice needs 1 to %d items", MaxItems)
+	}
+	for i := range inv.Items {
+		it := &inv.Items[i]
+		it.Description = strings.TrimSpace(it.Description)
+		it.HSNSAC = strings.TrimSpace(it.HSNSAC)
+		switch {
+		case it.Description == "" || len(it.Description) > 255:
+			return fmt.Errorf("item %d: description is required and must be at most 255 characters", i+1)
+		case it.HSNSAC != "" && !hsnPattern.MatchString(it.HSNSAC):
+			return fmt.Errorf("item %d: hsn_sac must be 4 to 8 digits", i+1)
+		case it.Quantity <= 0 || it.Quantity > 1e6:
+			return fmt.Errorf("item %d: quantity must be positive and at most 1000000", i+1)
+		case it.UnitPriceMinor < 0 || it.UnitPriceMinor > cur.ToMinor(1e9):
+			return fmt.Errorf("item %d: unit_price must not be negative or exceed 1000000000", i+1)
+		case it.TaxRate < 0 || it.TaxRate > 100:
+			return fmt.Errorf("item %d: tax_rate must be between 0 and 100", i+1)
+		}
+		// Stored as NUMERIC(12, 3) and NUMERIC(5, 2)
+		it.Quantity = math.Round(it.Quantity*1000) / 1000
+		it.TaxRate = math.Round(it.TaxRate*100) / 100
+	}
+
+	Compute(inv)
+	for i, it := range inv.Items {
+		if it.AmountMinor > 1e15 {
+			return fmt.Errorf("item %d: amount is too large", i+1)
+		}
+	}
+	if inv.TotalMinor <= 0 {
+		return errors.New("invoice total must be positive")
+	}
+	return nil
+}
+
+// Compute sets each item's amount and taxes and the invoice's totals. Each
+// amount is rounded half up to a minor unit per line; CGST and SGST are
+// each computed at half the rate so they are always equal.
+func Compute(inv *Invoice) {
+	inv.SubtotalMinor, inv.CGSTMinor, inv.SGSTMinor, inv.IGSTMinor = 0, 0, 0, 0
+	intrastate := inv.Intrastate()
+	for i := range inv.Items {
+		it := &inv.Items[i]
+		milliQty := int64(math.Round(it.Quantity * 1000))
+		basisPoints := int64(math.Round(it.TaxRate * 100))
+
+		it.AmountMinor = mulDivRound(it.UnitPriceMinor, milliQty, 1000)
+		it.CGSTMinor, it.SGSTMinor, it.IGSTMinor = 0, 0, 0
+		if intrastate {
+			it.CGSTMinor = mulDivRound(it.AmountMinor, basisPoints, 20000)
+			it.SGSTMinor = it.CGSTMinor
+		} else {
+			it.IGSTMinor = mulDivRound(it.AmountMinor, basisPoints, 10000)
+		}
+
+		inv.SubtotalMinor += it.AmountMinor
+		inv.CGSTMinor += it.CGSTMinor
+		inv.SGSTMinor += it.SGSTMinor
+		inv.IGSTMinor += it.IGSTMinor
+	}
+	inv.TotalMinor = inv.SubtotalMinor + inv.CGSTMinor + inv.SGSTMinor + inv.IGSTMinor
+}
+
+// mulDivRound returns a*num/den rounded half up for non-negative values,
+// without overflowing where a*num would.
+func mulDivRound(a, num, den int64) int64 {
+	return a/den*num + (a%den*num+den/2)/den
+}
+
+// queryer is satisfied by *sql.DB and *sql.Tx.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+const columns = `id, user_id, number, status, currency, supplier_name, COALESCE(supplier_gstin, ''),
+	COALESCE(supplier_address, ''), supplier_state, place_of_supply, customer_name, COALESCE(customer_email, ''),
+	COALESCE(customer_gstin, ''), COALESCE(customer_address, ''), notes, subtotal_minor, cgst_minor,
+	sgst_minor, igst_minor, total_minor, due_at, issued_at, paid_at, voided_at, payment_id, created_at, updated_at`
+
+func scan(row interface{ Scan(...any) error }) (Invoice, error) {
+	var inv Invoice
+	err := row.Scan(&inv.ID, &inv.UserID, &inv.Number, &inv.Status, &inv.Currency, &inv.SupplierName,
+		&inv.SupplierGSTIN, &inv.SupplierAddress, &inv.SupplierState, &inv.PlaceOfSupply, &inv.CustomerName,
+		&inv.CustomerEmail, &inv.CustomerGSTIN, &inv.CustomerAddress, &inv.Notes, &inv.SubtotalMinor,
+		&inv.CGSTMinor, &inv.SGSTMinor, &inv.IGSTMinor, &inv.TotalMinor, &inv.DueAt, &inv.IssuedAt,
+		&inv.PaidAt, &inv.VoidedAt, &inv.PaymentID, &inv.CreatedAt, &inv.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Invoice{}, ErrNotFound
+	}
+	return inv, err
+}
+
+// Create stores a draft, which must have passed Validate, for inv.UserID.
+func Create(ctx context.Context, db *sql.DB, inv Invoice) (Invoice, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return Invoice{}, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `INSERT INTO invoices
+			(user_id, status, currency, supplier_name, supplier_gstin, supplier_address, supplier_state,
+			 place_of_supply, customer_name, customer_email, customer_gstin, customer_address, notes,
+			 subtotal_minor, cgst_minor, sgst_minor, igst_minor, total_minor)
+		VALUES ($1, 'draft', $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6, $7, $8, NULLIF($9, ''),
+			NULLIF($10, ''), NULLIF($11, ''), $12, $13, $14, $15, $16, $17)
+		RETURNING id`,
+		inv.UserID, inv.Currency, inv.SupplierName, inv.SupplierGSTIN, inv.SupplierAddress, inv.SupplierState,
+		inv.PlaceOfSupply, inv.CustomerName, inv.CustomerEmail, inv.CustomerGSTIN, inv.CustomerAddress,
+		inv.Notes, inv.SubtotalMinor, inv.CGSTMinor, inv.SGSTMinor, inv.IGSTMinor, inv.TotalMinor).Scan(&inv.ID)
+	if err != nil {
+		return Invoice{}, err
+	}
+	if err := saveItems(ctx, tx, inv); err != nil {
+		return Invoice{}, err
+	}
+	if inv, err = Get(ctx, tx, inv.UserID, inv.ID); err != nil {
+		return Invoice{}, err
+	}
+	return inv, tx.Commit()
+}
+
+// List returns userID's invoices without their items, newest first.
+func List(ctx context.Context, q queryer, userID int) ([]Invoice, error) {
+	rows, err := q.QueryContext(ctx, `SELECT `+columns+` FROM invoices
+		WHERE user_id = $1
+		ORDER BY id DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Invoice
+	for rows.Next() {
+		inv, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, inv)
+	}
+	return list, rows.Err()
+}
+
+// Get returns one of userID's invoices with its items.
+func Get(ctx context.Context, q queryer, userID int, id int64) (Invoice, error) {
+	inv, err := scan(q.QueryRowContext(ctx, `SELECT `+columns+` FROM invoices
+		WHERE id = $1 AND user_id = $2`, id, userID))
+	if err != nil {
+		return Invoice{}, err
+	}
+	inv.Items, err = listItems(ctx, q, inv.ID)
+	return inv, err
+}
+
+// InCurrency reports whether any invoice is in currency code. Their amounts
+// are in its minor unit, so its exponent must not change while one is.
+func InCurrency(ctx context.Context, q queryer, code string) (bool, error) {
+	var exists bool
+	err := q.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM invoices WHERE currency = $1)`, code).Scan(&exists)
+	return exists, err
+}
+
+func listItems(ctx context.Context, q queryer, invoiceID int64) ([]Item, error) {
+	rows, err := q.QueryContext(ctx, `SELECT description, COALESCE(hsn_sac, ''), quantity, unit_price_minor,
+			tax_rate, amount_minor, cgst_minor, sgst_minor, igst_minor
+		FROM invoice_items
+		WHERE invoice_id = $1
+		ORDER BY position`, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []Item
+	for rows.Next() {
+		var it Item
+		if err := rows.Scan(&it.Description, &it.HSNSAC, &it.Quantity, &it.UnitPriceMinor, &it.TaxRate,
+			&it.AmountMinor, &it.CGSTMinor, &it.SGSTMinor, &it.IGSTMinor); err != nil {
+			return nil, err
+		}
+		items = append(items, it)
+	}
+	return items, rows.Err()
+}
+
+// Update replaces the contents of one of userID's drafts with inv, which
+// must have passed Validate.
+func Update(ctx context.Context, db *sql.DB, userID int, id int64, inv Invoice) (Invoice, error) {
+	return modify(ctx, db, userID, id, func(tx *sql.Tx, cur *Invoice) error {
+		if cur.Status != StatusDraft {
+			return fmt.Errorf("%w: only drafts can be changed; invoice is %s", ErrInvalidState, cur.Status)
+		}
+		inv.ID = cur.ID
+		return saveDraft(ctx, tx, inv)
+	})
+}
+
+// Issue assigns one of userID's drafts the user's next invoice number and
+// makes it final. dueAt, if set, must not be before now.
+func Issue(ctx context.Context, db *sql.DB, userID int, id int64, now time.Time, dueAt *time.Time) (Invoice, error) {
+	return modify(ctx, db, userID, id, func(tx *sql.Tx, inv *Invoice) error {
+		if inv.Status != StatusDraft {
+			return fmt.Errorf("%w: only drafts can be issued; invoice is %s", ErrInvalidState, inv.Status)
+		}
+		// The counter row lock serialises concurrent issues for a user, so
+		// numbers have no gaps or duplicates
+		var number int
+		err := tx.QueryRowContext(ctx, `INSERT INTO invoice_counters (user_id, last_number) VALUES ($1, 1)
+			ON CONFLICT (user_id) DO UPDATE SET last_number = invoice_counters.last_number + 1
+			RETURNING last_number`, userID).Scan(&number)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `UPDATE invoices
+			SET status = 'issued', number = $2, issued_at = $3, due_at = $4
+			WHERE id = $1`, inv.ID, number, now, dueAt)
+		return err
+	})
+}
+
+// Void cancels one of userID's draft or issued invoices. Paid invoices
+// cannot be voided; refund the payment instead.
+func Void(ctx context.Context, db *sql.DB, userID int, id int64) (Invoice, error) {
+	return modify(ctx, db, userID, id, func(tx *sql.Tx, inv *Invoice) error {
+		switch inv.Status {
+		case StatusVoid:
+			return nil
+		case StatusDraft, StatusIssued:
+		default:
+			return fmt.Errorf("%w: invoice is %s", ErrInvalidState, inv.Status)
+		}
+		_, err := tx.ExecContext(ctx, `UPDATE invoices SET status = 'void', voided_at = NOW()
+			WHERE id = $1`, inv.ID)
+		return err
+	})
+}
+
+// LinkPayment attaches one of userID's payments to one of their issued
+// invoices. The payment must be for the invoice's total and currency; the
+// invoice is paid now if the payment is already captured, otherwise when it
+// is. Linking another payment replaces one that was never captured.
+func LinkPayment(ctx context.Context, db *sql.DB, userID int, id int64, paymentID int64) (Invoice, error) {
+	return modify(ctx, db, userID, id, func(tx *sql.Tx, inv *Invoice) error {
+		if inv.Status != StatusIssued {
+			return fmt.Errorf("%w: only issued invoices take payments; invoice is %s", ErrInvalidState, inv.Status)
+		}
+		p, err := payments.LockByID(ctx, tx, int(paymentID))
+		if errors.Is(err, payments.ErrNotFound) || (err == nil && p.UserID != userID) {
+			return fmt.Errorf("%w: payment %d not found", ErrPaymentMismatch, paymentID)
+		}
+		if err != nil {
+			return err
+		}
+		reg, err := currencies.Load(ctx, tx)
+		if err != nil {
+			return err
+		}
+		paid, due := reg.Lookup(p.Currency), reg.Lookup(inv.Currency)
+		if p.Currency != inv.Currency || due.ToMinor(p.Amount) != inv.TotalMinor {
+			return fmt.Errorf("%w: payment is %s %s, invoice total is %s %s", ErrPaymentMismatch,
+				paid.Decimal(paid.ToMinor(p.Amount)), p.Currency, due.Decimal(inv.TotalMinor), inv.Currency)
+		}
+
+		_, err = tx.ExecContext(ctx, `UPDATE invoices SET payment_id = $2 WHERE id = $1`, inv.ID, paymentID)
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return fmt.Errorf("%w: payment %d already settles another invoice", ErrPaymentMismatch, paymentID)
+		}
+		if err != nil {
+			return err
+		}
+		return Settle(ctx, tx, int(paymentID))
+	})
+}
+
+// Settle marks the issued invoice linked to paymentID paid if the payment
+// has been captured. It does nothing otherwise, so it may be repeated.
+func Settle(ctx context.Context, q queryer, paymentID int) error {
+	_, err := q.ExecContext(ctx, `UPDATE invoices SET status = 'paid', paid_at = NOW()
+		WHERE payment_id = $1 AND status = 'issued'
+			AND EXISTS (SELECT 1 FROM payments WHERE id = $1 AND status IN ('captured', 'completed'))`, paymentID)
+	return err
+}
+
+// modify locks one of userID's invoices, applies fn and reloads it in one
+// transaction.
+func modify(ctx context.Context, db *sql.DB, userID int, id int64, fn func(*sql.Tx, *Invoice) error) (Invoice, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return Invoice{}, err
+	}
+	defer tx.Rollback()
+
+	inv, err := scan(tx.QueryRowContext(ctx, `SELECT `+columns+` FROM invoices
+		WHERE id = $1 AND user_id = $2
+		FOR UPDATE`, id, userID))
+	if err != nil {
+		return Invoice{}, err
+	}
+	if err := fn(tx, &inv); err != nil {
+		return Invoice{}, err
+	}
+	if inv, err = Get(ctx, tx, userID, id); err != nil {
+		return Invoice{}, err
+	}
+	return inv, tx.Commit()
+}
+
+// saveDraft writes a draft's fields and replaces its items.
+func saveDraft(ctx context.Context, tx *sql.Tx, inv Invoice) error {
+	_, err := tx.ExecContext(ctx, `UPDATE invoices SET
+			currency = $2,
+			supplier_name = $3,
+			supplier_gstin = NULLIF($4, ''),
+			supplier_address = NULLIF($5, ''),
+			supplier_state = $6,
+			place_of_supply = $7,
+			customer_name = $8,
+			customer_email = NULLIF($9, ''),
+			customer_gstin = NULLIF($10, ''),
+			customer_address = NULLIF($11, ''),
+			notes = $12,
+			subtotal_minor = $13,
+			cgst_minor = $14,
+			sgst_minor = $15,
+			igst_minor = $16,
+			total_minor = $17
+		WHERE id = $1`,
+		inv.ID, inv.Currency, inv.SupplierName, inv.SupplierGSTIN, inv.SupplierAddress, inv.SupplierState,
+		inv.PlaceOfSupply, inv.CustomerName, inv.CustomerEmail, inv.CustomerGSTIN, inv.CustomerAddress,
+		inv.Notes, inv.SubtotalMinor, inv.CGSTMinor, inv.SGSTMinor, inv.IGSTMinor, inv.TotalMinor)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM invoice_items WHERE invoice_id = $1`, inv.ID); err != nil {
+		return err
+	}
+	return saveItems(ctx, tx, inv)
+}
+
+// saveItems inserts inv's items in order.
+func saveItems(ctx context.Context, tx *sql.Tx, inv Invoice) error {
+	for i, it := range inv.Items {
+		_, err := tx.ExecContext(ctx, `INSERT INTO invoice_items
+				(invoice_id, position, description, hsn_sac, quantity, unit_price_minor, tax_rate,
+				 amount_minor, cgst_minor, sgst_minor, igst_minor)
+			VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7, $8, $9, $10, $11)`,
+			inv.ID, i+1, it.Description, it.HSNSAC, it.Quantity, it.UnitPriceMinor, it.TaxRate,
+			it.AmountMinor, it.CGSTMinor, it.SGSTMinor, it.IGSTMinor)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,276 @@
+package invoices
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/currencies"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/internal/dbtest"
+)
+
+var (
+	inr = currencies.Currency{Code: "INR", Exponent: 2}
+	jpy = currencies.Currency{Code: "JPY", Exponent: 0}
+)
+
+func draft(currency, placeOfSupply string, items ...Item) Invoice {
+	return Invoice{
+		Currency:      currency,
+		SupplierName:  "Acme Traders",
+		SupplierGSTIN: "29ABCDE1234F1Z5",
+		PlaceOfSupply: placeOfSupply,
+		CustomerName:  "Globex",
+		Items:         items,
+	}
+}
+
+func TestValidateComputesGST(t *testing.T) {
+	tests := []struct {
+		name          string
+		cur           currencies.Currency
+		placeOfSupply string
+		items         []Item
+		subtotal      int64
+		cgst, igst    int64
+	}{
+		{
+			name:          "intra-state splits the rate",
+			cur:           inr,
+			placeOfSupply: "29",
+			items: []Item{
+				{Description: "Consulting", Quantity: 1.5, UnitPriceMinor: 100000, TaxRate: 18},
+				{Description: "Travel", Quantity: 1, UnitPriceMinor: 333, TaxRate: 5},
+			},
+			subtotal: 150333,
+			cgst:     13500 + 8, // 8.325 rounds down
+		},
+		{
+			name:          "inter-state charges IGST",
+			cur:           inr,
+			placeOfSupply: "27",
+			items:         []Item{{Description: "Consulting", Quantity: 2, UnitPriceMinor: 12345, TaxRate: 18}},
+			subtotal:      24690,
+			igst:          4444, // 4444.2
+		},
+		{
+			name:          "whole yen",
+			cur:           jpy,
+			placeOfSupply: "27",
+			items:         []Item{{Description: "Licence", Quantity: 3, UnitPriceMinor: 1999, TaxRate: 18}},
+			subtotal:      5997,
+			igst:          1079, // 1079.46
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv := draft(tt.cur.Code, tt.placeOfSupply, tt.items...)
+			if err := Validate(&inv, tt.cur); err != nil {
+				t.Fatal(err)
+			}
+			if inv.SubtotalMinor != tt.subtotal || inv.CGSTMinor != tt.cgst || inv.SGSTMinor != tt.cgst || inv.IGSTMinor != tt.igst {
+				t.Errorf("subtotal %d, CGST %d, SGST %d, IGST %d; want %d, %d, %d, %d",
+					inv.SubtotalMinor, inv.CGSTMinor, inv.SGSTMinor, inv.IGSTMinor, tt.subtotal, tt.cgst, tt.cgst, tt.igst)
+			}
+			if want := tt.subtotal + 2*tt.cgst + tt.igst; inv.TotalMinor != want {
+				t.Errorf("total %d, want %d", inv.TotalMinor, want)
+			}
+		})
+	}
+}
+
+func TestValidateUnitPriceLimit(t *testing.T) {
+	// The limit is 1000000000 in the major unit of any currency
+	for _, tt := range []struct {
+		cur   currencies.Currency
+		price int64
+		ok    bool
+	}{
+		{inr, 1e11, true},
+		{inr, 1e11 + 1, false},
+		{jpy, 1e9, true},
+		{jpy, 1e9 + 1, false},
+	} {
+		inv := draft(tt.cur.Code, "29", Item{Description: "Item", Quantity: 1, UnitPriceMinor: tt.price})
+		err := Validate(&inv, tt.cur)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s unit price %d: err = %v, want ok %t", tt.cur.Code, tt.price, err, tt.ok)
+		}
+		if err != nil && !strings.Contains(err.Error(), "unit_price") {
+			t.Errorf("%s unit price %d: unexpected error %v", tt.cur.Code, tt.price, err)
+		}
+	}
+}
+
+func TestDisplayNumber(t *testing.T) {
+	var inv Invoice
+	if got := inv.DisplayNumber(); got != "" {
+		t.Errorf("draft DisplayNumber = %q, want empty", got)
+	}
+	for n, want := range map[int]string{1: "INV-000001", 42: "INV-000042", 1234567: "INV-1234567"} {
+		inv.Number = &n
+		if got := inv.DisplayNumber(); got != want {
+			t.Errorf("DisplayNumber(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+// createDraft stores a valid one-item INR draft for userID.
+func createDraft(t *testing.T, database *sql.DB, userID int) Invoice {
+	t.Helper()
+	inv := draft("INR", "29", Item{Description: "Consulting", Quantity: 1, UnitPriceMinor: 10000, TaxRate: 18})
+	inv.UserID = userID
+	if err := Validate(&inv, inr); err != nil {
+		t.Fatal(err)
+	}
+	created, err := Create(context.Background(), database, inv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return created
+}
+
+func issue(t *testing.T, database *sql.DB, inv Invoice) int {
+	t.Helper()
+	issued, err := Issue(context.Background(), database, inv.UserID, inv.ID, time.Now(), nil)
+	if err != nil {
+		t.Fatalf("issue invoice %d: %v", inv.ID, err)
+	}
+	if issued.Status != StatusIssued || issued.Number == nil || issued.IssuedAt == nil {
+		t.Fatalf("issued invoice %+v has no number", issued)
+	}
+	return *issued.Number
+}
+
+func TestIssueNumbering(t *testing.T) {
+	database := dbtest.Open(t)
+	ctx := context.Background()
+
+	first, abandoned, second := createDraft(t, database, 1), createDraft(t, database, 1), createDraft(t, database, 1)
+	if first.Number != nil {
+		t.Fatalf("draft has number %d", *first.Number)
+	}
+	// Drafts that are voided or never issued take no number
+	if _, err := Void(ctx, database, 1, abandoned.ID); err != nil {
+		t.Fatal(err)
+	}
+	if n := issue(t, database, first); n != 1 {
+		t.Errorf("first invoice is number %d, want 1", n)
+	}
+	createDraft(t, database, 1)
+	if n := issue(t, database, second); n != 2 {
+		t.Errorf("second invoice is number %d, want 2", n)
+	}
+
+	// Numbering is per user
+	if n := issue(t, database, createDraft(t, database, 2)); n != 1 {
+		t.Errorf("another user's first invoice is number %d, want 1", n)
+	}
+
+	// An invoice is numbered once
+	if _, err := Issue(ctx, database, 1, first.ID, time.Now(), nil); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("issuing twice: err = %v, want ErrInvalidState", err)
+	}
+	if _, err := Issue(ctx, database, 2, second.ID, time.Now(), nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("issuing another user's draft: err = %v, want ErrNotFound", err)
+	}
+	again, err := Get(ctx, database, 1, first.ID)
+	if err != nil || again.Number == nil || *again.Number != 1 {
+		t.Errorf("first invoice renumbered: %+v, %v", again.Number, err)
+	}
+}
+
+func TestIssueConcurrently(t *testing.T) {
+	database := dbtest.Open(t)
+	const user = 3
+	var drafts []Invoice
+	for range 10 {
+		drafts = append(drafts, createDraft(t, database, user))
+	}
+
+	numbers := make([]int, len(drafts))
+	var wg sync.WaitGroup
+	for i, inv := range drafts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			issued, err := Issue(context.Background(), database, user, inv.ID, time.Now(), nil)
+			if err != nil || issued.Number == nil {
+				t.Errorf("issue invoice %d: %v", inv.ID, err)
+				return
+			}
+			numbers[i] = *issued.Number
+		}()
+	}
+	wg.Wait()
+	if t.Failed() {
+		return
+	}
+
+	// No gaps and no duplicates
+	slices.Sort(numbers)
+	for i, n := range numbers {
+		if n != i+1 {
+			t.Fatalf("numbers issued %v, want 1 to %d", numbers, len(drafts))
+		}
+	}
+}
+
+func TestLinkPayment(t *testing.T) {
+	database := dbtest.Open(t)
+	ctx := context.Background()
+	const user = 4
+	inv := createDraft(t, database, user)
+	issue(t, database, inv)
+
+	orders := 0
+	pay := func(amount float64, status string) int64 {
+		orders++
+		var id int64
+		err := database.QueryRowContext(ctx, `INSERT INTO payments
+				(user_id, amount, currency, from_account, to_account, razorpay_order_id, status, created_at)
+			VALUES ($1, $2, 'INR', 'ACC-FROM', 'ACC-TO', $3, $4, NOW())
+			RETURNING id`, user, amount, "order_inv_"+strconv.Itoa(orders), status).Scan(&id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+
+	// The total is 100.00 plus 18% GST
+	if _, err := LinkPayment(ctx, database, user, inv.ID, pay(100, "captured")); !errors.Is(err, ErrPaymentMismatch) {
+		t.Errorf("linking a payment short of the total: err = %v, want ErrPaymentMismatch", err)
+	}
+	created := pay(118, "created")
+	linked, err := LinkPayment(ctx, database, user, inv.ID, created)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if linked.Status != StatusIssued || linked.PaymentID == nil || *linked.PaymentID != created {
+		t.Errorf("invoice %s linked to %v, want issued and linked to %d", linked.Status, linked.PaymentID, created)
+	}
+
+	// Once captured, the payment settles the invoice
+	if _, err := database.ExecContext(ctx, `UPDATE payments SET status = 'captured' WHERE id = $1`, created); err != nil {
+		t.Fatal(err)
+	}
+	if err := Settle(ctx, database, int(created)); err != nil {
+		t.Fatal(err)
+	}
+	paid, err := Get(ctx, database, user, inv.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if paid.Status != StatusPaid || paid.PaidAt == nil {
+		t.Errorf("invoice is %s, want paid", paid.Status)
+	}
+	if _, err := Void(ctx, database, user, inv.ID); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("voiding a paid invoice: err = %v, want ErrInvalidState", err)
+	}
+}
@@ -0,0 +1,210 @@
+package invoices
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/currencies"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/pdf"
+)
+
+// Page layout in points.
+const (
+	marginLeft   = 40.0
+	marginRight  = pdf.PageWidth - 40
+	marginBottom = pdf.PageHeight - 60
+)
+
+// Table columns: left edges for text, right edges for numbers.
+const (
+	colNumber      = marginLeft
+	colDescription = 62.0
+	colHSN         = 250.0
+	colQuantity    = 335.0
+	colUnitPrice   = 400.0
+	colTaxable     = 465.0
+	colTaxRate     = 500.0
+	colTax         = marginRight
+)
+
+// Render writes inv, whose currency is cur, as a PDF. Drafts and void
+// invoices are marked as such.
+func Render(w io.Writer, inv Invoice, cur currencies.Currency) error {
+	title := "Invoice " + inv.DisplayNumber()
+	if inv.Number == nil {
+		title = "Draft invoice"
+	}
+	doc := pdf.New(title)
+	r := &renderer{doc: doc, y: 60}
+
+	// Heading and invoice details
+	heading := "TAX INVOICE"
+	switch inv.Status {
+	case StatusDraft:
+		heading = "DRAFT INVOICE"
+	case StatusVoid:
+		heading = "TAX INVOICE (VOID)"
+	}
+	doc.Text(marginLeft, r.y, pdf.Bold, 18, heading)
+	details := [][2]string{
+		{"Invoice no.", orDash(inv.DisplayNumber())},
+		{"Issued", formatDate(inv.IssuedAt)},
+		{"Due", formatDate(inv.DueAt)},
+		{"Status", strings.ToUpper(string(inv.Status))},
+	}
+	if inv.PaidAt != nil {
+		details = append(details, [2]string{"Paid", formatDate(inv.PaidAt)})
+	}
+	dy := r.y - 12
+	for _, d := range details {
+		doc.TextRight(470, dy, pdf.Bold, 9, d[0])
+		doc.TextRight(marginRight, dy, pdf.Regular, 9, d[1])
+		dy += 13
+	}
+	r.y = max(r.y+30, dy+10)
+
+	// Parties
+	top := r.y
+	left := r.party("From", inv.SupplierName, inv.SupplierAddress, inv.SupplierGSTIN, inv.SupplierState, marginLeft, top)
+	right := r.party("Bill to", inv.CustomerName, inv.CustomerAddress, inv.CustomerGSTIN, "", 310, top)
+	r.y = max(left, right) + 8
+	supply := "Place of supply: state " + inv.PlaceOfSupply + " (inter-state: IGST)"
+	if inv.Intrastate() {
+		supply = "Place of supply: state " + inv.PlaceOfSupply + " (intra-state: CGST + SGST)"
+	}
+	doc.Text(marginLeft, r.y, pdf.Regular, 9, supply)
+	r.y += 22
+
+	// Line items
+	r.tableHeader(inv.Currency)
+	for i, it := range inv.Items {
+		lines := pdf.Wrap(pdf.Regular, 9, it.Description, colHSN-colDescription-8)
+		r.ensure(float64(len(lines))*11+6, inv.Currency)
+		doc.Text(colNumber, r.y, pdf.Regular, 9, strconv.Itoa(i+1))
+		doc.Text(colHSN, r.y, pdf.Regular, 9, it.HSNSAC)
+		doc.TextRight(colQuantity, r.y, pdf.Regular, 9, strconv.FormatFloat(it.Quantity, 'f', -1, 64))
+		doc.TextRight(colUnitPrice, r.y, pdf.Regular, 9, cur.Format(it.UnitPriceMinor))
+		doc.TextRight(colTaxable, r.y, pdf.Regular, 9, cur.Format(it.AmountMinor))
+		doc.TextRight(colTaxRate, r.y, pdf.Regular, 9, strconv.FormatFloat(it.TaxRate, 'f', -1, 64)+"%")
+		doc.TextRight(colTax, r.y, pdf.Regular, 9, cur.Format(it.TaxMinor()))
+		for _, line := range lines {
+			doc.Text(colDescription, r.y, pdf.Regular, 9, line)
+			r.y += 11
+		}
+		r.y += 4
+	}
+	doc.Line(marginLeft, r.y-6, marginRight, r.y-6, 0.5)
+
+	// Totals
+	totals := [][2]string{{"Taxable value", cur.Format(inv.SubtotalMinor)}}
+	if inv.Intrastate() {
+		totals = append(totals,
+			[2]string{"CGST", cur.Format(inv.CGSTMinor)},
+			[2]string{"SGST", cur.Format(inv.SGSTMinor)})
+	} else {
+		totals = append(totals, [2]string{"IGST", cur.Format(inv.IGSTMinor)})
+	}
+	r.ensure(float64(len(totals)+1)*14+10, inv.Currency)
+	r.y += 8
+	for _, t := range totals {
+		doc.TextRight(colTaxRate, r.y, pdf.Regular, 9, t[0])
+		doc.TextRight(colTax, r.y, pdf.Regular, 9, t[1])
+		r.y += 14
+	}
+	doc.Line(380, r.y-9, marginRight, r.y-9, 0.5)
+	r.y += 4
+	doc.TextRight(colTaxRate, r.y, pdf.Bold, 11, "Total ("+inv.Currency+")")
+	doc.TextRight(colTax, r.y, pdf.Bold, 11, cur.Format(inv.TotalMinor))
+	r.y += 28
+
+	// Notes
+	if inv.Notes != nil && strings.TrimSpace(*inv.Notes) != "" {
+		lines := pdf.Wrap(pdf.Regular, 9, *inv.Notes, marginRight-marginLeft)
+		r.ensure(float64(len(lines))*11+14, "")
+		doc.Text(marginLeft, r.y, pdf.Bold, 9, "Notes")
+		r.y += 12
+		for _, line := range lines {
+			r.ensure(11, "")
+			doc.Text(marginLeft, r.y, pdf.Regular, 9, line)
+			r.y += 11
+		}
+	}
+
+	doc.Text(marginLeft, pdf.PageHeight-30, pdf.Regular, 7, "This is a computer-generated invoice.")
+	return doc.Write(w)
+}
+
+// renderer tracks the vertical position on the current page.
+type renderer struct {
+	doc *pdf.Document
+	y   float64
+}
+
+// ensure starts a new page if height does not fit on this one, repeating
+// the table header when currency is set.
+func (r *renderer) ensure(height float64, currency string) {
+	if r.y+height <= marginBottom {
+		return
+	}
+	r.doc.AddPage()
+	r.y = 60
+	if currency != "" {
+		r.tableHeader(currency)
+	}
+}
+
+func (r *renderer) tableHeader(currency string) {
+	d := r.doc
+	d.Line(marginLeft, r.y-11, marginRight, r.y-11, 0.5)
+	d.Text(colNumber, r.y, pdf.Bold, 8, "#")
+	d.Text(colDescription, r.y, pdf.Bold, 8, "Description")
+	d.Text(colHSN, r.y, pdf.Bold, 8, "HSN/SAC")
+	d.TextRight(colQuantity, r.y, pdf.Bold, 8, "Qty")
+	d.TextRight(colUnitPrice, r.y, pdf.Bold, 8, "Unit price")
+	d.TextRight(colTaxable, r.y, pdf.Bold, 8, "Taxable ("+currency+")")
+	d.TextRight(colTaxRate, r.y, pdf.Bold, 8, "GST")
+	d.TextRight(colTax, r.y, pdf.Bold, 8, "Tax ("+currency+")")
+	d.Line(marginLeft, r.y+5, marginRight, r.y+5, 0.5)
+	r.y += 18
+}
+
+// party draws an address block at x, y and returns where it ends.
+func (r *renderer) party(label, name, address, gstin, state string, x, y float64) float64 {
+	d := r.doc
+	d.Text(x, y, pdf.Bold, 8, strings.ToUpper(label))
+	y += 14
+	d.Text(x, y, pdf.Bold, 10, name)
+	y += 13
+	for _, line := range pdf.Wrap(pdf.Regular, 9, address, 240) {
+		if line == "" {
+			continue
+		}
+		d.Text(x, y, pdf.Regular, 9, line)
+		y += 11
+	}
+	if gstin != "" {
+		d.Text(x, y, pdf.Regular, 9, "GSTIN: "+gstin)
+		y += 11
+	}
+	if state != "" {
+		d.Text(x, y, pdf.Regular, 9, "State code: "+state)
+		y += 11
+	}
+	return y
+}
+
+func formatDate(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.UTC().Format("02 Jan 2006")
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,33 @@
+package invoices
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/events"
+)
+
+// Tracker is an outbox publisher that marks invoices paid as their linked
+// payments are captured.
+type Tracker struct {
+	db *sql.DB
+}
+
+// NewTracker creates a tracker updating invoices in db.
+func NewTracker(db *sql.DB) *Tracker {
+	return &Tracker{db: db}
+}
+
+// Publish settles the invoice linked to the payment in a payment.captured
+// event. Other events are ignored.
+func (t *Tracker) Publish(ctx context.Context, e events.Event) error {
+	if e.Type != events.PaymentCaptured {
+		return nil
+	}
+	var data events.PaymentData
+	if err := json.Unmarshal(e.Data, &data); err != nil || data.PaymentID == 0 {
+		return nil
+	}
+	return Settle(ctx, t.db, data.PaymentID)
+}
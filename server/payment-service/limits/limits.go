@@ -0,0 +1,392 @@
+// Package limits caps what a user may send: a maximum per transaction and
+// daily and monthly totals per currency, and a number of payments per hour.
+//
+// The defaults every user gets are stored under DefaultUser. Admins can
+// override them per user: an override for a currency replaces the default
+// for that currency as a whole, and an hourly count override replaces the
+// default count. Days and months are calendar periods in UTC; the hourly
+// count is a rolling hour.
+package limits
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/currencies"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/models"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/tracing"
+	"github.com/lib/pq"
+)
+
+// DefaultUser is the user ID the defaults are stored under.
+const DefaultUser = 0
+
+// lockSpace is the first key of the pg_advisory_xact_lock taken per user
+// while a payment is checked, so concurrent payments are counted in turn.
+const lockSpace int32 = 0x6c696d74
+
+// ErrUnknownCurrency is returned when limits name a currency missing from
+// the registry.
+var ErrUnknownCurrency = errors.New("unknown currency")
+
+// Kind names a limit.
+type Kind string
+
+const (
+	KindPerTransaction Kind = "per_transaction"
+	KindDaily          Kind = "daily"
+	KindMonthly        Kind = "monthly"
+	KindHourlyCount    Kind = "hourly_count"
+)
+
+// Currency holds the amount limits for one currency; nil means no limit.
+type Currency struct {
+	Currency       string
+	PerTransaction *float64
+	Daily          *float64
+	Monthly        *float64
+}
+
+// Set is the limits stored for a user, or the defaults, or the two merged.
+type Set struct {
+	// HourlyCount caps payments in any rolling hour; nil means no limit,
+	// or for an override, that the default applies.
+	HourlyCount *int
+	Currencies  []Currency // ordered by currency
+
+	UpdatedBy *int
+	UpdatedAt *time.Time // nil when nothing is stored
+}
+
+// For returns the limits for currency; ok is false if it has none.
+func (s Set) For(currency string) (c Currency, ok bool) {
+	for _, c := range s.Currencies {
+		if c.Currency == currency {
+			return c, true
+		}
+	}
+	return Currency{Currency: currency}, false
+}
+
+// Normalize upper-cases and sorts s's currencies and checks the values,
+// returning a description of the first problem found.
+func Normalize(s *Set) error {
+	if s.HourlyCount != nil && *s.HourlyCount <= 0 {
+		return errors.New("hourly_count must be positive")
+	}
+	seen := make(map[string]bool, len(s.Currencies))
+	for i := range s.Currencies {
+		c := &s.Currencies[i]
+		code, err := currencies.NormalizeCode(c.Currency)
+		if err != nil {
+			return err
+		}
+		if seen[code] {
+			return fmt.Errorf("%s is listed twice", code)
+		}
+		seen[code] = true
+		c.Currency = code
+
+		for _, v := range []struct {
+			name  string
+			value *float64
+		}{{"per_transaction", c.PerTransaction}, {"daily", c.Daily}, {"monthly", c.Monthly}} {
+			if v.value != nil && !(*v.value > 0) {
+				return fmt.Errorf("%s %s must be positive", code, v.name)
+			}
+		}
+		if c.Daily != nil && c.Monthly != nil && *c.Daily > *c.Monthly {
+			return fmt.Errorf("%s daily must not exceed monthly", code)
+		}
+	}
+	sort.Slice(s.Currencies, func(i, j int) bool { return s.Currencies[i].Currency < s.Currencies[j].Currency })
+	return nil
+}
+
+// queryer is satisfied by *sql.DB and *sql.Tx.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// Get returns the limits stored for userID: the overrides for a user, or
+// the defaults for DefaultUser.
+func Get(ctx context.Context, q queryer, userID int) (Set, error) {
+	return load(ctx, q, `user_id = $1`, userID)
+}
+
+// Effective returns the limits that apply to userID: the defaults with the
+// user's overrides applied.
+func Effective(ctx context.Context, q queryer, userID int) (Set, error) {
+	return load(ctx, q, `user_id IN (0, $1)`, userID)
+}
+
+// load reads the rows matching where, preferring userID's row over the
+// default's for each currency and for the hourly count.
+func load(ctx context.Context, q queryer, where string, userID int) (Set, error) {
+	var s Set
+	var count sql.NullInt64
+	var by sql.NullInt64
+	var at sql.NullTime
+	err := q.QueryRowContext(ctx, `SELECT hourly_count, updated_by, updated_at FROM velocity_limits
+		WHERE `+where+`
+		ORDER BY user_id DESC
+		LIMIT 1`, userID).Scan(&count, &by, &at)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return Set{}, err
+	}
+	if count.Valid {
+		n := int(count.Int64)
+		s.HourlyCount = &n
+	}
+	s.touch(by, at)
+
+	rows, err := q.QueryContext(ctx, `SELECT DISTINCT ON (currency) currency, per_transaction, daily, monthly, updated_by, updated_at
+		FROM currency_limits
+		WHERE `+where+`
+		ORDER BY currency, user_id DESC`, userID)
+	if err != nil {
+		return Set{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c Currency
+		var perTx, daily, monthly sql.NullFloat64
+		if err := rows.Scan(&c.Currency, &perTx, &daily, &monthly, &by, &at); err != nil {
+			return Set{}, err
+		}
+		c.PerTransaction, c.Daily, c.Monthly = floatPtr(perTx), floatPtr(daily), floatPtr(monthly)
+		s.Currencies = append(s.Currencies, c)
+		s.touch(by, at)
+	}
+	return s, rows.Err()
+}
+
+// touch records the most recent change seen while loading s.
+func (s *Set) touch(by sql.NullInt64, at sql.NullTime) {
+	if !at.Valid || (s.UpdatedAt != nil && !at.Time.After(*s.UpdatedAt)) {
+		return
+	}
+	t := at.Time
+	s.UpdatedAt = &t
+	s.UpdatedBy = nil
+	if by.Valid {
+		id := int(by.Int64)
+		s.UpdatedBy = &id
+	}
+}
+
+func floatPtr(v sql.NullFloat64) *float64 {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Float64
+}
+
+// Put replaces everything stored for userID with s, which must have passed
+// Normalize. by is the admin making the change.
+func Put(ctx context.Context, db *sql.DB, userID int, s Set, by int) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := deleteAll(ctx, tx, userID); err != nil {
+		return err
+	}
+	// The defaults always have a velocity row, even without a limit, so
+	// a user without overrides never falls through to nothing
+	if s.HourlyCount != nil || userID == DefaultUser {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO velocity_limits (user_id, hourly_count, updated_by)
+			VALUES ($1, $2, $3)`, userID, s.HourlyCount, by); err != nil {
+			return err
+		}
+	}
+	for _, c := range s.Currencies {
+		_, err := tx.ExecContext(ctx, `INSERT INTO currency_limits (user_id, currency, per_transaction, daily, monthly, updated_by)
+			VALUES ($1, $2, $3, $4, $5, $6)`, userID, c.Currency, c.PerTransaction, c.Daily, c.Monthly, by)
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+			return fmt.Errorf("%w: %s", ErrUnknownCurrency, c.Currency)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Delete removes userID's overrides, reporting whether there were any.
+func Delete(ctx context.Context, db *sql.DB, userID int) (bool, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var n int64
+	if err := tx.QueryRowContext(ctx, `SELECT
+		(SELECT COUNT(*) FROM velocity_limits WHERE user_id = $1) +
+		(SELECT COUNT(*) FROM currency_limits WHERE user_id = $1)`, userID).Scan(&n); err != nil {
+		return false, err
+	}
+	if err := deleteAll(ctx, tx, userID); err != nil {
+		return false, err
+	}
+	return n > 0, tx.Commit()
+}
+
+func deleteAll(ctx context.Context, tx *sql.Tx, userID int) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM velocity_limits WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `DELETE FROM currency_limits WHERE user_id = $1`, userID)
+	return err
+}
+
+// Usage is what a user has sent in the windows the limits apply to. Failed,
+// expired and voided payments do not count; open orders do, so a user
+// cannot exceed a cap by starting many checkouts at once. Payments received
+// through the user's links are not theirs to limit and never count.
+type Usage struct {
+	Currency     string
+	Today        float64
+	ThisMonth    float64
+	HourlyCount  int       // payments in any currency in the last hour
+	OldestInHour time.Time // zero when HourlyCount is 0
+}
+
+// usageQuery sums userID's payments in currency since the start of the
+// day ($3) and month ($4), and counts all of them in the last hour ($5).
+// Payment $6 is left out.
+const usageQuery = `SELECT
+		COALESCE(SUM(COALESCE(captured_amount, amount)) FILTER (WHERE currency = $2 AND created_at >= $3), 0),
+		COALESCE(SUM(COALESCE(captured_amount, amount)) FILTER (WHERE currency = $2 AND created_at >= $4), 0),
+		COUNT(*) FILTER (WHERE created_at > $5),
+		MIN(created_at) FILTER (WHERE created_at > $5)
+	FROM payments
+	WHERE user_id = $1 AND id <> $6
+	  AND created_at >= LEAST($4::TIMESTAMPTZ, $5::TIMESTAMPTZ)
+	  AND status NOT IN ('failed', 'expired', 'voided')
+	  AND direction = 'outgoing'`
+
+// CurrentUsage returns userID's usage of currency at now.
+func CurrentUsage(ctx context.Context, q queryer, userID int, currency string, now time.Time) (Usage, error) {
+	return usage(ctx, q, userID, currency, now, 0)
+}
+
+func usage(ctx context.Context, q queryer, userID int, currency string, now time.Time, exclude int) (Usage, error) {
+	day, month := periodStarts(now)
+	u := Usage{Currency: currency}
+	var oldest sql.NullTime
+	spanCtx, span := tracing.StartDBSpan(ctx, "SELECT", "payments", usageQuery)
+	err := q.QueryRowContext(spanCtx, usageQuery, userID, currency, day, month, now.Add(-time.Hour), exclude).
+		Scan(&u.Today, &u.ThisMonth, &u.HourlyCount, &oldest)
+	tracing.End(span, err)
+	if oldest.Valid {
+		u.OldestInHour = oldest.Time
+	}
+	return u, err
+}
+
+// periodStarts returns the start of now's day and month in UTC.
+func periodStarts(now time.Time) (day, month time.Time) {
+	now = now.UTC()
+	day = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	month = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return day, month
+}
+
+// Violation is the limit a payment would exceed.
+type Violation struct {
+	Kind     Kind
+	Currency string // empty for KindHourlyCount
+	Max      float64
+	// Used is the usage before the payment: the amount sent or, for
+	// KindHourlyCount, the number of payments
+	Used      float64
+	Attempted float64
+	// ResetsAt is when enough of the window has passed for the payment to
+	// fit again; nil for KindPerTransaction, which never resets.
+	ResetsAt *time.Time
+}
+
+func (v *Violation) Error() string {
+	switch v.Kind {
+	case KindHourlyCount:
+		return fmt.Sprintf("at most %d payments per hour", int(v.Max))
+	case KindPerTransaction:
+		return fmt.Sprintf("at most %.2f %s per payment", v.Max, v.Currency)
+	}
+	return fmt.Sprintf("%s limit of %.2f %s reached: %.2f already sent", v.Kind, v.Max, v.Currency, v.Used)
+}
+
+// Check returns a *Violation if userID sending amount in currency at now
+// would exceed one of their limits. Checks run in the order per
+// transaction, hourly count, daily, monthly.
+func Check(ctx context.Context, q queryer, userID int, currency string, amount float64, now time.Time) error {
+	return check(ctx, q, userID, currency, amount, now, 0)
+}
+
+// Enforce checks p against its user's limits in tx, before or after p is
+// stored there; a stored p is not counted twice. It holds a per-user lock
+// until tx ends, so of two concurrent payments the second is checked with
+// the first counted.
+func Enforce(ctx context.Context, tx *sql.Tx, p models.Payment, now time.Time) error {
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1, $2)`, lockSpace, p.UserID); err != nil {
+		return fmt.Errorf("lock limits: %w", err)
+	}
+	return check(ctx, tx, p.UserID, p.Currency, p.Amount, now, p.ID)
+}
+
+func check(ctx context.Context, q queryer, userID int, currency string, amount float64, now time.Time, exclude int) error {
+	set, err := Effective(ctx, q, userID)
+	if err != nil {
+		return fmt.Errorf("load limits: %w", err)
+	}
+	lim, _ := set.For(currency)
+	if lim.PerTransaction != nil && exceeds(amount, *lim.PerTransaction) {
+		return &Violation{Kind: KindPerTransaction, Currency: currency, Max: *lim.PerTransaction, Attempted: amount}
+	}
+	if set.HourlyCount == nil && lim.Daily == nil && lim.Monthly == nil {
+		return nil
+	}
+
+	u, err := usage(ctx, q, userID, currency, now, exclude)
+	if err != nil {
+		return fmt.Errorf("load usage: %w", err)
+	}
+	return evaluate(set.HourlyCount, lim, u, amount, now)
+}
+
+// evaluate compares u plus a payment of amount with the window limits.
+func evaluate(hourlyCount *int, lim Currency, u Usage, amount float64, now time.Time) error {
+	day, month := periodStarts(now)
+	if hourlyCount != nil && u.HourlyCount >= *hourlyCount {
+		resets := u.OldestInHour.Add(time.Hour).UTC()
+		return &Violation{Kind: KindHourlyCount, Max: float64(*hourlyCount), Used: float64(u.HourlyCount), Attempted: 1, ResetsAt: &resets}
+	}
+	if lim.Daily != nil && exceeds(u.Today+amount, *lim.Daily) {
+		resets := day.AddDate(0, 0, 1)
+		return &Violation{Kind: KindDaily, Currency: lim.Currency, Max: *lim.Daily, Used: u.Today, Attempted: amount, ResetsAt: &resets}
+	}
+	if lim.Monthly != nil && exceeds(u.ThisMonth+amount, *lim.Monthly) {
+		resets := month.AddDate(0, 1, 0)
+		return &Violation{Kind: KindMonthly, Currency: lim.Currency, Max: *lim.Monthly, Used: u.ThisMonth, Attempted: amount, ResetsAt: &resets}
+	}
+	return nil
+}
+
+// exceeds compares amounts to the cent, so float error cannot push a
+// payment that exactly meets a limit over it.
+func exceeds(amount, limit float64) bool {
+	return math.Round(amount*100) > math.Round(limit*100)
+}
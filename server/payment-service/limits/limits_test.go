@@ -0,0 +1,219 @@
+package limits
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/internal/dbtest"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/models"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+func TestPeriodStarts(t *testing.T) {
+	// 02:00 in India on 1 April is still 31 March in UTC
+	now := time.Date(2026, 4, 1, 2, 0, 0, 0, time.FixedZone("IST", 5*3600+1800))
+	day, month := periodStarts(now)
+	if want := time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC); !day.Equal(want) {
+		t.Errorf("day starts %s, want %s", day, want)
+	}
+	if want := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC); !month.Equal(want) {
+		t.Errorf("month starts %s, want %s", month, want)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	now := time.Date(2026, 3, 31, 23, 30, 0, 0, time.UTC)
+	oldest := now.Add(-40 * time.Minute)
+	lim := Currency{Currency: "INR", Daily: ptr(1000.0), Monthly: ptr(5000.0)}
+	tests := []struct {
+		name   string
+		hourly *int
+		used   Usage
+		amount float64
+		kind   Kind // empty when allowed
+		resets time.Time
+	}{
+		{name: "under every limit", used: Usage{Today: 100, ThisMonth: 100}, amount: 100},
+		{name: "exactly the daily limit", used: Usage{Today: 700, ThisMonth: 700}, amount: 300},
+		{name: "float error at the limit", used: Usage{Today: 999.7, ThisMonth: 999.7}, amount: 0.3},
+		{
+			name: "a paisa over the daily limit", used: Usage{Today: 700.01, ThisMonth: 700.01}, amount: 300,
+			kind: KindDaily, resets: time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "over the monthly limit", used: Usage{Today: 0, ThisMonth: 4900}, amount: 200,
+			kind: KindMonthly, resets: time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "daily is reported before monthly", used: Usage{Today: 900, ThisMonth: 4900}, amount: 200,
+			kind: KindDaily, resets: time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{name: "one payment left this hour", hourly: ptr(3), used: Usage{HourlyCount: 2, OldestInHour: oldest}, amount: 1},
+		{
+			name: "hourly count reached", hourly: ptr(3), used: Usage{HourlyCount: 3, OldestInHour: oldest}, amount: 1,
+			kind: KindHourlyCount, resets: oldest.Add(time.Hour),
+		},
+		{
+			name: "hourly count is checked first", hourly: ptr(3), used: Usage{Today: 1000, HourlyCount: 3, OldestInHour: oldest}, amount: 1,
+			kind: KindHourlyCount, resets: oldest.Add(time.Hour),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := evaluate(tt.hourly, lim, tt.used, tt.amount, now)
+			if tt.kind == "" {
+				if err != nil {
+					t.Fatalf("refused: %v", err)
+				}
+				return
+			}
+			var v *Violation
+			if !errors.As(err, &v) {
+				t.Fatalf("err = %v, want a %s violation", err, tt.kind)
+			}
+			if v.Kind != tt.kind || v.ResetsAt == nil || !v.ResetsAt.Equal(tt.resets) {
+				t.Errorf("violation %s resetting %v, want %s resetting %s", v.Kind, v.ResetsAt, tt.kind, tt.resets)
+			}
+		})
+	}
+}
+
+var orderSeq atomic.Int64
+
+// insertPayment stores a payment as the payment service would have.
+func insertPayment(t *testing.T, q queryer, userID int, amount float64, currency, status string, createdAt time.Time, captured *float64) int {
+	t.Helper()
+	var id int
+	err := q.QueryRowContext(context.Background(), `INSERT INTO payments
+			(user_id, amount, currency, from_account, to_account, razorpay_order_id, status, created_at, captured_amount)
+		VALUES ($1, $2, $3, 'ACC-FROM', 'ACC-TO', $4, $5, $6, $7)
+		RETURNING id`,
+		userID, amount, currency, fmt.Sprintf("order_test_%d", orderSeq.Add(1)), status, createdAt, captured).Scan(&id)
+	if err != nil {
+		t.Fatalf("insert %s payment: %v", status, err)
+	}
+	return id
+}
+
+func TestUsage(t *testing.T) {
+	database := dbtest.Open(t)
+	ctx := context.Background()
+	now := time.Date(2026, 3, 15, 10, 30, 0, 0, time.UTC)
+	const user = 7
+
+	insertPayment(t, database, user, 100, "INR", "completed", now.Add(-10*time.Minute), nil)
+	insertPayment(t, database, user, 50, "INR", "pending_review", now.Add(-20*time.Minute), nil)
+	// A partial capture counts what was taken
+	insertPayment(t, database, user, 80, "INR", "captured", now.Add(-2*time.Hour), ptr(30.0))
+	insertPayment(t, database, user, 5, "INR", "created", time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC), nil)
+	// Yesterday and earlier this month
+	insertPayment(t, database, user, 200, "INR", "completed", time.Date(2026, 3, 14, 23, 59, 59, 0, time.UTC), nil)
+	insertPayment(t, database, user, 400, "INR", "refunded", time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), nil)
+	// Outside the month, and in another currency
+	insertPayment(t, database, user, 1000, "INR", "completed", time.Date(2026, 2, 28, 23, 59, 59, 0, time.UTC), nil)
+	insertPayment(t, database, user, 9, "USD", "completed", now.Add(-5*time.Minute), nil)
+	// Money that never moved, and another user
+	for _, status := range []string{"failed", "expired", "voided"} {
+		insertPayment(t, database, user, 10000, "INR", status, now.Add(-time.Minute), nil)
+	}
+	insertPayment(t, database, user+1, 10000, "INR", "completed", now.Add(-time.Minute), nil)
+	// Orders strangers opened on the user's payment links, paid or not
+	for _, status := range []string{"created", "completed"} {
+		id := insertPayment(t, database, user, 10000, "INR", status, now.Add(-time.Minute), nil)
+		if _, err := database.ExecContext(ctx, `UPDATE payments SET direction = 'incoming' WHERE id = $1`, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	u, err := CurrentUsage(ctx, database, user, "INR", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Today != 185 || u.ThisMonth != 785 {
+		t.Errorf("today %.2f, this month %.2f; want 185.00 and 785.00", u.Today, u.ThisMonth)
+	}
+	if u.HourlyCount != 3 || !u.OldestInHour.Equal(now.Add(-20*time.Minute)) {
+		t.Errorf("%d in the last hour, oldest %s; want 3, oldest %s", u.HourlyCount, u.OldestInHour, now.Add(-20*time.Minute))
+	}
+}
+
+func TestEnforce(t *testing.T) {
+	database := dbtest.Open(t)
+	ctx := context.Background()
+	const user = 11
+	if err := Put(ctx, database, user, Set{Currencies: []Currency{{Currency: "INR", Daily: ptr(1000.0)}}}, 1); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+
+	// A payment already stored in the transaction is not counted twice
+	tx, err := database.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := models.Payment{UserID: user, Amount: 1000, Currency: "INR"}
+	p.ID = insertPayment(t, tx, user, p.Amount, p.Currency, "created", now, nil)
+	if err := Enforce(ctx, tx, p, now); err != nil {
+		t.Fatalf("payment of exactly the daily limit refused: %v", err)
+	}
+	tx.Rollback()
+
+	// Concurrent payments each fit alone, but only three fit together
+	var wg sync.WaitGroup
+	var allowed, refused atomic.Int32
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := pay(ctx, database, models.Payment{UserID: user, Amount: 300, Currency: "INR"}, now)
+			var v *Violation
+			switch {
+			case err == nil:
+				allowed.Add(1)
+			case errors.As(err, &v) && v.Kind == KindDaily:
+				refused.Add(1)
+			default:
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if allowed.Load() != 3 || refused.Load() != 7 {
+		t.Errorf("%d allowed and %d refused, want 3 and 7", allowed.Load(), refused.Load())
+	}
+
+	u, err := CurrentUsage(ctx, database, user, "INR", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Today != 900 {
+		t.Errorf("sent %.2f today, want 900.00", u.Today)
+	}
+}
+
+// pay enforces limits on p and stores it the way payments.Initiate does.
+func pay(ctx context.Context, database *sql.DB, p models.Payment, now time.Time) error {
+	tx, err := database.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := Enforce(ctx, tx, p, now); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO payments
+			(user_id, amount, currency, from_account, to_account, razorpay_order_id, status, created_at)
+		VALUES ($1, $2, $3, 'ACC-FROM', 'ACC-TO', $4, 'created', $5)`,
+		p.UserID, p.Amount, p.Currency, fmt.Sprintf("order_test_%d", orderSeq.Add(1)), now)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,389 @@
+// Package links stores payment links: shareable /l/{code} URLs through which
+// anyone can pay the link's owner, and the payments made through them.
+package links
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/models"
+)
+
+// ErrNotFound is returned when a link does not exist or belongs to another
+// user.
+var ErrNotFound = errors.New("payment link not found")
+
+// ErrInvalidState is returned when an operation does not apply to a link's
+// current status.
+var ErrInvalidState = errors.New("operation not allowed")
+
+// ErrClosed is returned when paying a link that is paid, expired or
+// cancelled.
+var ErrClosed = errors.New("payment link no longer accepts payments")
+
+// ErrFullyReserved is returned when paying a link whose remaining uses are
+// all held by orders still awaiting payment.
+var ErrFullyReserved = errors.New("payment link has no uses left")
+
+// Status is a link's lifecycle state.
+type Status string
+
+const (
+	StatusActive    Status = "active"
+	StatusPaid      Status = "paid" // max_uses payments captured
+	StatusExpired   Status = "expired"
+	StatusCancelled Status = "cancelled"
+)
+
+// Link is a request for money.
+type Link struct {
+	ID     int64
+	UserID int
+	Code   string
+
+	// Amount is set for a fixed-amount link; otherwise the payer chooses
+	// an amount between MinAmount and MaxAmount.
+	Amount    *float64
+	MinAmount *float64
+	MaxAmount *float64
+
+	Currency    string
+	ToAccount   string
+	Description *string
+	ExpiresAt   *time.Time
+	MaxUses     *int
+
+	// Uses counts captured payments; Reserved adds orders still awaiting
+	// payment.
+	Uses     int
+	Reserved int
+	Status   Status
+
+	PaidAt      *time.Time
+	CancelledAt *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// Payment is an order created through a link.
+type Payment struct {
+	ID           int64
+	LinkID       int64
+	Payment      models.Payment
+	PayerName    *string
+	PayerEmail   *string
+	PayerContact *string
+	CreatedAt    time.Time
+}
+
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// Validate checks a new link and sets its initial status.
+func Validate(l *Link, now time.Time, maxTTL time.Duration) error {
+	switch {
+	case l.Amount != nil && (l.MinAmount != nil || l.MaxAmount != nil):
+		return errors.New("specify either amount or min_amount and max_amount, not both")
+	case l.Amount != nil:
+		if *l.Amount <= 0 {
+			return errors.New("amount must be positive")
+		}
+	case l.MinAmount == nil || l.MaxAmount == nil:
+		return errors.New("amount, or min_amount and max_amount for a payer-chosen amount, is required")
+	case *l.MinAmount <= 0 || *l.MaxAmount < *l.MinAmount:
+		return errors.New("min_amount must be positive and max_amount at least min_amount")
+	}
+
+	if !currencyPattern.MatchString(l.Currency) {
+		return errors.New("currency must be a three-letter ISO code")
+	}
+	l.ToAccount = strings.TrimSpace(l.ToAccount)
+	if l.ToAccount == "" || len(l.ToAccount) > 128 {
+		return errors.New("to_account is required and must be at most 128 characters")
+	}
+	if l.ExpiresAt != nil {
+		if !l.ExpiresAt.After(now) {
+			return errors.New("expires_at must be in the future")
+		}
+		if l.ExpiresAt.After(now.Add(maxTTL)) {
+			return fmt.Errorf("expires_at must be within %s", maxTTL)
+		}
+		utc := l.ExpiresAt.UTC()
+		l.ExpiresAt = &utc
+	}
+	if l.MaxUses != nil && *l.MaxUses <= 0 {
+		return errors.New("max_uses must be positive")
+	}
+	l.Status = StatusActive
+	return nil
+}
+
+// CheckAmount returns the amount a payer pays through l. chosen is the
+// payer's amount, required only when l has no fixed amount.
+func (l Link) CheckAmount(chosen *float64) (float64, error) {
+	if l.Amount != nil {
+		if chosen != nil && *chosen != *l.Amount {
+			return 0, fmt.Errorf("this link is for exactly %.2f %s", *l.Amount, l.Currency)
+		}
+		return *l.Amount, nil
+	}
+	if chosen == nil || *chosen < *l.MinAmount || *chosen > *l.MaxAmount {
+		return 0, fmt.Errorf("amount must be between %.2f and %.2f %s", *l.MinAmount, *l.MaxAmount, l.Currency)
+	}
+	return *chosen, nil
+}
+
+// Open reports whether l still accepts payments, returning ErrClosed or
+// ErrFullyReserved if not.
+func (l Link) Open() error {
+	if l.Status != StatusActive {
+		return fmt.Errorf("%w: link is %s", ErrClosed, l.Status)
+	}
+	if l.MaxUses != nil && l.Reserved >= *l.MaxUses {
+		return ErrFullyReserved
+	}
+	return nil
+}
+
+// queryer is satisfied by *sql.DB and *sql.Tx.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// Payment statuses that count as a use of a link, and those that hold one
+// until the order is paid or abandoned.
+const (
+	paidStatuses     = `'captured', 'completed', 'refunded', 'charged_back'`
+	reservedStatuses = `'created', 'pending', 'authorized', ` + paidStatuses
+)
+
+// columns reads a link aliased as l. An active link past its expiry reads
+// as expired.
+const columns = `l.id, l.user_id, l.code, l.amount, l.min_amount, l.max_amount, l.currency, l.to_account,
+	l.description, l.expires_at, l.max_uses,
+	(SELECT COUNT(*) FROM payment_link_payments lp JOIN payments p ON p.id = lp.payment_id
+		WHERE lp.link_id = l.id AND p.status IN (` + paidStatuses + `)),
+	(SELECT COUNT(*) FROM payment_link_payments lp JOIN payments p ON p.id = lp.payment_id
+		WHERE lp.link_id = l.id AND p.status IN (` + reservedStatuses + `)),
+	CASE WHEN l.status = 'active' AND l.expires_at <= NOW() THEN 'expired' ELSE l.status END,
+	l.paid_at, l.cancelled_at, l.created_at, l.updated_at`
+
+func scan(row interface{ Scan(...any) error }) (Link, error) {
+	var l Link
+	err := row.Scan(&l.ID, &l.UserID, &l.Code, &l.Amount, &l.MinAmount, &l.MaxAmount, &l.Currency, &l.ToAccount,
+		&l.Description, &l.ExpiresAt, &l.MaxUses, &l.Uses, &l.Reserved, &l.Status,
+		&l.PaidAt, &l.CancelledAt, &l.CreatedAt, &l.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Link{}, ErrNotFound
+	}
+	return l, err
+}
+
+// codeAlphabet avoids characters that are easily confused when a link is
+// read out or retyped.
+const codeAlphabet = "23456789abcdefghjkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ"
+
+// codeLength gives 55^10 possible codes, so links cannot be guessed.
+const codeLength = 10
+
+func newCode() (string, error) {
+	b := make([]byte, codeLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		// 256 is not a multiple of 55; the bias this leaves is negligible
+		b[i] = codeAlphabet[int(b[i])%len(codeAlphabet)]
+	}
+	return string(b), nil
+}
+
+// Create stores l, which must have passed Validate, for l.UserID under a new
+// random code.
+func Create(ctx context.Context, q queryer, l Link) (Link, error) {
+	for attempt := 0; ; attempt++ {
+		code, err := newCode()
+		if err != nil {
+			return Link{}, err
+		}
+		var id int64
+		err = q.QueryRowContext(ctx, `INSERT INTO payment_links
+				(user_id, code, amount, min_amount, max_amount, currency, to_account, description, expires_at, max_uses, status)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			RETURNING id`,
+			l.UserID, code, l.Amount, l.MinAmount, l.MaxAmount, l.Currency, l.ToAccount, l.Description,
+			l.ExpiresAt, l.MaxUses, string(l.Status)).Scan(&id)
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" && attempt < 3 {
+			continue // code collision
+		}
+		if err != nil {
+			return Link{}, err
+		}
+		return Get(ctx, q, l.UserID, id)
+	}
+}
+
+// List returns userID's links, newest first.
+func List(ctx context.Context, q queryer, userID int) ([]Link, error) {
+	rows, err := q.QueryContext(ctx, `SELECT `+columns+` FROM payment_links l
+		WHERE l.user_id = $1
+		ORDER BY l.id DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Link
+	for rows.Next() {
+		l, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, l)
+	}
+	return list, rows.Err()
+}
+
+// Get returns one of userID's links.
+func Get(ctx context.Context, q queryer, userID int, id int64) (Link, error) {
+	return scan(q.QueryRowContext(ctx, `SELECT `+columns+` FROM payment_links l
+		WHERE l.id = $1 AND l.user_id = $2`, id, userID))
+}
+
+// GetByCode returns the link with code, whoever owns it.
+func GetByCode(ctx context.Context, q queryer, code string) (Link, error) {
+	return scan(q.QueryRowContext(ctx, `SELECT `+columns+` FROM payment_links l
+		WHERE l.code = $1`, code))
+}
+
+// Cancel stops one of userID's active links from accepting payments. Orders
+// already created through it can still be paid.
+func Cancel(ctx context.Context, db *sql.DB, userID int, id int64) (Link, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return Link{}, err
+	}
+	defer tx.Rollback()
+
+	l, err := scan(tx.QueryRowContext(ctx, `SELECT `+columns+` FROM payment_links l
+		WHERE l.id = $1 AND l.user_id = $2
+		FOR UPDATE`, id, userID))
+	if err != nil {
+		return Link{}, err
+	}
+	switch l.Status {
+	case StatusCancelled:
+		return l, nil
+	case StatusActive:
+	default:
+		return Link{}, fmt.Errorf("%w: link is %s", ErrInvalidState, l.Status)
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE payment_links SET status = 'cancelled', cancelled_at = NOW()
+		WHERE id = $1`, id); err != nil {
+		return Link{}, err
+	}
+	if l, err = Get(ctx, tx, userID, id); err != nil {
+		return Link{}, err
+	}
+	return l, tx.Commit()
+}
+
+// Payer is what a payer may tell us about themselves; every field is
+// optional.
+type Payer struct {
+	Name    *string
+	Email   *string
+	Contact *string
+}
+
+// Reserve locks the link with code in tx, checks it still accepts payments
+// and records p as made through it. It is meant for payments.Order.Record so
+// that two payers cannot both take a link's last use.
+func Reserve(ctx context.Context, tx *sql.Tx, code string, p models.Payment, payer Payer) error {
+	l, err := scan(tx.QueryRowContext(ctx, `SELECT `+columns+` FROM payment_links l
+		WHERE l.code = $1
+		FOR UPDATE`, code))
+	if err != nil {
+		return err
+	}
+	if err := l.Open(); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO payment_link_payments
+			(link_id, payment_id, payer_name, payer_email, payer_contact)
+		VALUES ($1, $2, $3, $4, $5)`,
+		l.ID, p.ID, payer.Name, payer.Email, payer.Contact)
+	return err
+}
+
+// PayerOf returns what the payer of paymentID told us when paying through a
+// link, or ErrNotFound if the payment was not made through one.
+func PayerOf(ctx context.Context, q queryer, paymentID int) (Payer, error) {
+	var p Payer
+	err := q.QueryRowContext(ctx, `SELECT payer_name, payer_email, payer_contact
+		FROM payment_link_payments WHERE payment_id = $1`, paymentID).Scan(&p.Name, &p.Email, &p.Contact)
+	if errors.Is(err, sql.ErrNoRows) {
+		return p, ErrNotFound
+	}
+	return p, err
+}
+
+// ListPayments returns up to limit of the orders created through one of
+// userID's links, newest first.
+func ListPayments(ctx context.Context, q queryer, userID int, linkID int64, limit int) ([]Payment, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	if _, err := Get(ctx, q, userID, linkID); err != nil {
+		return nil, err
+	}
+	rows, err := q.QueryContext(ctx, `SELECT lp.id, lp.link_id, lp.payer_name, lp.payer_email, lp.payer_contact,
+			lp.created_at, `+paymentColumns+`
+		FROM payment_link_payments lp JOIN payments p ON p.id = lp.payment_id
+		WHERE lp.link_id = $1
+		ORDER BY lp.id DESC
+		LIMIT $2`, linkID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Payment
+	for rows.Next() {
+		var lp Payment
+		err := rows.Scan(&lp.ID, &lp.LinkID, &lp.PayerName, &lp.PayerEmail, &lp.PayerContact, &lp.CreatedAt,
+			&lp.Payment.ID, &lp.Payment.Amount, &lp.Payment.Currency, &lp.Payment.Status,
+			&lp.Payment.RazorpayOrderID, &lp.Payment.RazorpayPaymentID)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, lp)
+	}
+	return list, rows.Err()
+}
+
+// paymentColumns are the payment fields a link's owner sees per order.
+const paymentColumns = `p.id, p.amount, p.currency, p.status, p.razorpay_order_id, p.razorpay_payment_id`
+
+// Settle marks the link paymentID was made through as paid once it has
+// max_uses captured payments. It does nothing for other payments, links
+// without max_uses or links that were cancelled, so it may be repeated.
+func Settle(ctx context.Context, q queryer, paymentID int) error {
+	_, err := q.ExecContext(ctx, `UPDATE payment_links l SET status = 'paid', paid_at = NOW()
+		WHERE l.id = (SELECT link_id FROM payment_link_payments WHERE payment_id = $1)
+			AND l.status = 'active' AND l.max_uses IS NOT NULL
+			AND (SELECT COUNT(*) FROM payment_link_payments lp JOIN payments p ON p.id = lp.payment_id
+				WHERE lp.link_id = l.id AND p.status IN (`+paidStatuses+`)) >= l.max_uses`, paymentID)
+	return err
+}
@@ -0,0 +1,123 @@
+package links
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func amount(v float64) *float64 { return &v }
+func uses(n int) *int           { return &n }
+
+func TestValidate(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	soon, past, late := now.Add(time.Hour), now.Add(-time.Minute), now.Add(48*time.Hour)
+	tests := []struct {
+		name    string
+		link    Link
+		wantErr string
+	}{
+		{name: "fixed", link: Link{Amount: amount(250), Currency: "INR", ToAccount: " 50100987654321 "}},
+		{name: "payer chooses", link: Link{MinAmount: amount(10), MaxAmount: amount(10), Currency: "INR", ToAccount: "x@upi", ExpiresAt: &soon, MaxUses: uses(1)}},
+		{name: "both kinds", link: Link{Amount: amount(5), MaxAmount: amount(10), Currency: "INR", ToAccount: "x@upi"}, wantErr: "specify either amount or min_amount and max_amount, not both"},
+		{name: "no amount", link: Link{MinAmount: amount(10), Currency: "INR", ToAccount: "x@upi"}, wantErr: "amount, or min_amount and max_amount for a payer-chosen amount, is required"},
+		{name: "zero amount", link: Link{Amount: amount(0), Currency: "INR", ToAccount: "x@upi"}, wantErr: "amount must be positive"},
+		{name: "inverted range", link: Link{MinAmount: amount(10), MaxAmount: amount(5), Currency: "INR", ToAccount: "x@upi"}, wantErr: "min_amount must be positive and max_amount at least min_amount"},
+		{name: "lower-case currency", link: Link{Amount: amount(5), Currency: "inr", ToAccount: "x@upi"}, wantErr: "currency must be a three-letter ISO code"},
+		{name: "no payee", link: Link{Amount: amount(5), Currency: "INR", ToAccount: "  "}, wantErr: "to_account is required and must be at most 128 characters"},
+		{name: "long payee", link: Link{Amount: amount(5), Currency: "INR", ToAccount: strings.Repeat("9", 129)}, wantErr: "to_account is required and must be at most 128 characters"},
+		{name: "expired", link: Link{Amount: amount(5), Currency: "INR", ToAccount: "x@upi", ExpiresAt: &past}, wantErr: "expires_at must be in the future"},
+		{name: "too far out", link: Link{Amount: amount(5), Currency: "INR", ToAccount: "x@upi", ExpiresAt: &late}, wantErr: "expires_at must be within 24h0m0s"},
+		{name: "no uses", link: Link{Amount: amount(5), Currency: "INR", ToAccount: "x@upi", MaxUses: uses(0)}, wantErr: "max_uses must be positive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := tt.link
+			err := Validate(&l, now, 24*time.Hour)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if l.Status != StatusActive || l.ToAccount != strings.TrimSpace(tt.link.ToAccount) {
+				t.Errorf("validated %s to %q, want active to the trimmed account", l.Status, l.ToAccount)
+			}
+		})
+	}
+}
+
+func TestCheckAmount(t *testing.T) {
+	fixed := Link{Amount: amount(250), Currency: "INR"}
+	ranged := Link{MinAmount: amount(100), MaxAmount: amount(500), Currency: "INR"}
+	tests := []struct {
+		name    string
+		link    Link
+		chosen  *float64
+		want    float64
+		wantErr string
+	}{
+		{"fixed", fixed, nil, 250, ""},
+		{"fixed restated", fixed, amount(250), 250, ""},
+		{"fixed changed", fixed, amount(200), 0, "this link is for exactly 250.00 INR"},
+		{"lowest", ranged, amount(100), 100, ""},
+		{"highest", ranged, amount(500), 500, ""},
+		{"below", ranged, amount(99.99), 0, "amount must be between 100.00 and 500.00 INR"},
+		{"above", ranged, amount(500.01), 0, "amount must be between 100.00 and 500.00 INR"},
+		{"not chosen", ranged, nil, 0, "amount must be between 100.00 and 500.00 INR"},
+	}
+	for _, tt := range tests {
+		got, err := tt.link.CheckAmount(tt.chosen)
+		if tt.wantErr != "" {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: %v, %v; want %v", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestOpen(t *testing.T) {
+	tests := []struct {
+		name string
+		link Link
+		want error
+	}{
+		{"active", Link{Status: StatusActive}, nil},
+		{"uses left", Link{Status: StatusActive, MaxUses: uses(2), Uses: 1, Reserved: 1}, nil},
+		// Orders awaiting payment hold their use until they expire
+		{"reserved", Link{Status: StatusActive, MaxUses: uses(2), Uses: 1, Reserved: 2}, ErrFullyReserved},
+		{"paid", Link{Status: StatusPaid}, ErrClosed},
+		{"expired", Link{Status: StatusExpired}, ErrClosed},
+		{"cancelled", Link{Status: StatusCancelled}, ErrClosed},
+	}
+	for _, tt := range tests {
+		if err := tt.link.Open(); !errors.Is(err, tt.want) || (tt.want == nil) != (err == nil) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestNewCode(t *testing.T) {
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		code, err := newCode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(code) != codeLength || strings.Trim(code, codeAlphabet) != "" {
+			t.Fatalf("code %q is not %d characters from the alphabet", code, codeLength)
+		}
+		if seen[code] {
+			t.Fatalf("code %q repeated", code)
+		}
+		seen[code] = true
+	}
+}
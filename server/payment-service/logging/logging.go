@@ -0,0 +1,100 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Init installs the service-wide structured logger as the slog default and
+// routes the standard library log package through it.
+func Init(service string, opts Options) *slog.Logger {
+	logger := New(os.Stdout, service, opts)
+	slog.SetDefault(logger)
+	return logger
+}
+
+// Options controls the logger built by New.
+type Options struct {
+	// Level is debug, info (default), warn or error
+	Level string
+	// Format is json (default) or text
+	Format string
+	// RedactFields are extra attribute keys to redact
+	RedactFields []string
+}
+
+// New builds a logger writing to w. Every record is passed through the
+// redaction layer and enriched with the request-scoped fields stored in its
+// context.
+func New(w io.Writer, service string, opts Options) *slog.Logger {
+	handlerOpts := &slog.HandlerOptions{
+		Level:       parseLevel(opts.Level),
+		ReplaceAttr: newRedactor(opts.RedactFields).replaceAttr,
+	}
+
+	var handler slog.Handler
+	if strings.EqualFold(opts.Format, "text") {
+		handler = slog.NewTextHandler(w, handlerOpts)
+	} else {
+		handler = slog.NewJSONHandler(w, handlerOpts)
+	}
+
+	return slog.New(contextHandler{handler}).With("service", service)
+}
+
+type ctxKey struct{}
+
+// WithAttrs returns a copy of ctx carrying attrs, which are added to every
+// record logged with that context (e.g. request_id, user_id).
+func WithAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	existing, _ := ctx.Value(ctxKey{}).([]slog.Attr)
+	merged := make([]slog.Attr, 0, len(existing)+len(attrs))
+	merged = append(merged, existing...)
+	merged = append(merged, attrs...)
+	return context.WithValue(ctx, ctxKey{}, merged)
+}
+
+// contextHandler adds request-scoped attributes and the active trace/span IDs
+// to each record before handing it to the wrapped handler.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if attrs, ok := ctx.Value(ctxKey{}).([]slog.Attr); ok {
+		r.AddAttrs(attrs...)
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(
+			slog.String("trace_id", sc.TraceID().String()),
+			slog.String("span_id", sc.SpanID().String()),
+		)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+func parseLevel(s string) slog.Level {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
@@ -2,20 +2,40 @@ package main
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
+	_ "time/tzdata" // receipts use the user's timezone in minimal images
 
 	"github.com/gorilla/mux"
-	"github.com/joho/godotenv"
 
+	"github.com/RaginiSharma01/gopay-lite/payment-service/audit"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/blocklist"
 	"github.com/RaginiSharma01/gopay-lite/payment-service/db"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/fx"
 	"github.com/RaginiSharma01/gopay-lite/payment-service/handlers"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/health"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/internal/config"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/invoices"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/links"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/logging"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/metrics"
 	"github.com/RaginiSharma01/gopay-lite/payment-service/middleware"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/outbox"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/payments"
 	"github.com/RaginiSharma01/gopay-lite/payment-service/razorpay"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/receipts"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/reconcile"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/risk"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/schedules"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/tracing"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/users"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/webhooks"
 
 	_ "github.com/RaginiSharma01/gopay-lite/payment-service/docs" // Swagger generated docs
 	httpSwagger "github.com/swaggo/http-swagger"
@@ -32,29 +52,255 @@ import (
 
 func main() {
 	// Load environment variables
-	if os.Getenv("RUNNING_IN_DOCKER") != "true" {
-		err := godotenv.Load("../.env")
-		if err != nil {
-			log.Println("Warning: .env file not found or failed to load")
+	envErr := config.LoadEnv()
+	cfg, cfgErr := config.Load()
+
+	// `payment-service config print` shows the effective configuration
+	if len(os.Args) > 2 && os.Args[1] == "config" && os.Args[2] == "print" {
+		cfg.Print(os.Stdout)
+		if cfgErr != nil {
+			fmt.Fprintf(os.Stderr, "\ninvalid configuration:\n%v\n", cfgErr)
+			os.Exit(1)
 		}
+		return
+	}
+
+	// Structured logging
+	logging.Init("payment-service", logging.Options{
+		Level:        cfg.LogLevel,
+		Format:       cfg.LogFormat,
+		RedactFields: cfg.LogRedactFields,
+	})
+	if envErr != nil {
+		slog.Warn(".env file not found or failed to load", "error", envErr)
+	}
+	if cfgErr != nil {
+		slog.Error("Invalid configuration", "error", cfgErr)
+		os.Exit(1)
+	}
+	for _, name := range cfg.Deprecated() {
+		slog.Warn("Deprecated configuration variable", "variable", name)
 	}
 
 	// Initialize database
-	if err := db.Init(); err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
+	if err := db.Init(cfg.DSN()); err != nil {
+		slog.Error("Failed to initialize database", "error", err)
+		os.Exit(1)
 	}
 	defer db.Close()
 
+	// `payment-service migrate ...` manages the schema and exits
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(context.Background(), db.DB, os.Args[2:]); err != nil {
+			slog.Error("Migration failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if cfg.AutoMigrate {
+		if err := autoMigrate(context.Background(), db.DB); err != nil {
+			slog.Error("Auto-migration failed", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	// `payment-service fx ...` imports or lists exchange rates and exits
+	if len(os.Args) > 1 && os.Args[1] == "fx" {
+		if err := runFX(context.Background(), db.DB, os.Args[2:]); err != nil {
+			slog.Error("FX command failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if cfg.FXRatesFile != "" {
+		inserted, err := fx.ImportFile(context.Background(), db.DB, cfg.FXRatesFile)
+		if err != nil {
+			slog.Error("Failed to import exchange rates", "file", cfg.FXRatesFile, "error", err)
+			os.Exit(1)
+		}
+		slog.Info("Exchange rates imported", "file", cfg.FXRatesFile, "inserted", inserted)
+	}
+	if len(os.Args) > 1 && os.Args[1] == "risk" {
+		if err := runRisk(context.Background(), db.DB, os.Args[2:]); err != nil {
+			slog.Error("Risk command failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if cfg.RiskIPRangesFile != "" {
+		n, err := risk.ImportIPRanges(context.Background(), db.DB, cfg.RiskIPRangesFile)
+		if err != nil {
+			slog.Error("Failed to import IP ranges", "file", cfg.RiskIPRangesFile, "error", err)
+			os.Exit(1)
+		}
+		slog.Info("IP ranges imported", "file", cfg.RiskIPRangesFile, "ranges", n)
+	}
+	if len(os.Args) > 1 && os.Args[1] == "blocklist" {
+		if err := runBlocklist(context.Background(), db.DB, os.Args[2:]); err != nil {
+			slog.Error("Blocklist command failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "audit" {
+		if err := runAudit(context.Background(), db.DB, os.Args[2:]); err != nil {
+			slog.Error("Audit command failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	metrics.RegisterDB(db.DB)
+
 	// Initialize Razorpay client
-	razorpay.Init()
-	razorpayKey := os.Getenv("RAZORPAY_KEY")
-	razorpaySecret := os.Getenv("RAZORPAY_SECRET")
+	if err := razorpay.Init(cfg.RazorpayKeyID, cfg.RazorpayKeySecret); err != nil {
+		slog.Error("Failed to initialize Razorpay client", "error", err)
+		os.Exit(1)
+	}
+
+	// `payment-service reconcile ...` runs or reports a reconciliation and exits
+	if len(os.Args) > 1 && os.Args[1] == "reconcile" {
+		if err := runReconcile(context.Background(), db.DB, cfg, os.Args[2:]); err != nil {
+			slog.Error("Reconciliation failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Initialize tracing
+	shutdownTracing, err := tracing.Init(context.Background(), cfg.TracesExporter)
+	if err != nil {
+		slog.Error("Failed to initialize tracing", "error", err)
+		os.Exit(1)
+	}
+
+	// Background workers stop when workerCtx is cancelled on shutdown
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+
+	// Merchant webhooks: the dispatcher queues deliveries for each event
+	// the relay publishes and sends them with retries
+	dispatcher := webhooks.NewDispatcher(db.DB, webhooks.Options{
+		PollInterval:         cfg.WebhookPollInterval,
+		Timeout:              cfg.WebhookTimeout,
+		Concurrency:          cfg.WebhookConcurrency,
+		MaxAttempts:          cfg.WebhookMaxAttempts,
+		MaxBackoff:           cfg.WebhookMaxBackoff,
+		AllowPrivateNetworks: cfg.WebhookAllowPrivateNetworks,
+	})
+	handlers.SetWebhookDispatcher(dispatcher)
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		dispatcher.Run(workerCtx)
+	}()
+
+	// Outbox relay publishes domain events recorded with state changes
+	publisher, closePublisher, err := newOutboxPublisher(cfg)
+	if err != nil {
+		slog.Error("Failed to initialize outbox publisher", "error", err)
+		os.Exit(1)
+	}
+	// The trackers mark payment links and invoices paid as their payments
+	// are captured
+	publishers := outbox.MultiPublisher{dispatcher, links.NewTracker(db.DB), invoices.NewTracker(db.DB)}
+	if publisher != nil {
+		publishers = append(publishers, publisher)
+	}
+	relay := outbox.NewRelay(db.DB, publishers, outbox.Options{
+		PollInterval: cfg.OutboxPollInterval,
+		BatchSize:    cfg.OutboxBatchSize,
+		MaxBackoff:   cfg.OutboxMaxBackoff,
+		Retention:    cfg.OutboxRetention,
+	})
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		relay.Run(workerCtx)
+	}()
 
-	if razorpayKey == "" || razorpaySecret == "" {
-		log.Fatal("Razorpay credentials not set in environment")
+	// The blocklist is loaded before the scheduler starts so no payment
+	// slips through before it is, then kept current by polling for changes
+	blocklistCache := blocklist.NewCache(db.DB, cfg.BlocklistRefreshInterval)
+	if err := blocklistCache.Reload(context.Background()); err != nil {
+		slog.Error("Failed to load blocklist", "error", err)
+		os.Exit(1)
 	}
+	handlers.SetBlocklist(blocklistCache)
+	payments.SetBlocklist(blocklistCache)
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		blocklistCache.Run(workerCtx)
+	}()
+
+	// Unpaid orders are abandoned once they pass expires_at, and
+	// uncaptured authorizations once they pass AUTHORIZATION_TTL
+	sweeper := payments.NewSweeper(db.DB, payments.SweeperOptions{
+		Interval:         cfg.ExpirySweepInterval,
+		AuthorizationTTL: cfg.AuthorizationTTL,
+	})
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		sweeper.Run(workerCtx)
+	}()
+
+	// Scheduled payments run through the same path as /pay when due
+	scheduler := schedules.NewScheduler(db.DB, schedules.Options{
+		PollInterval: cfg.SchedulePollInterval,
+		MaxRetries:   cfg.ScheduleMaxRetries,
+		MaxBackoff:   cfg.ScheduleMaxBackoff,
+		TTL:          cfg.PaymentTTLFor,
+	})
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		scheduler.Run(workerCtx)
+	}()
+
+	// Reconciliation corrects payments whose Razorpay webhook was missed
+	reconciler := reconcile.New(db.DB, reconcile.Options{
+		Interval:  cfg.ReconcileInterval,
+		MinAge:    cfg.ReconcileMinAge,
+		Lookback:  cfg.ReconcileLookback,
+		BatchSize: cfg.ReconcileBatchSize,
+	})
+	handlers.SetReconciler(reconciler)
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		reconciler.Run(workerCtx)
+	}()
 
-	handlers.InitRazorpayClient(razorpayKey, razorpaySecret)
+	// Audit entries from both services are hash-chained once committed
+	chainer := audit.NewChainer(db.DB, audit.ChainerOptions{Interval: cfg.AuditChainInterval})
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		chainer.Run(workerCtx)
+	}()
+
+	// Receipt templates are parsed up front so a broken override fails
+	// startup rather than the first download
+	receiptTemplates, err := receipts.Load(cfg.ReceiptTemplateDir)
+	if err != nil {
+		slog.Error("Failed to load receipt templates", "dir", cfg.ReceiptTemplateDir, "error", err)
+		os.Exit(1)
+	}
+	handlers.SetReceipts(receiptTemplates, users.NewClient(cfg.AuthServiceURL, cfg.AuthServiceTimeout))
+
+	// Payments through /pay are screened before their order is created
+	handlers.SetRisk(risk.New(db.DB, risk.Options{
+		ReviewScore:       cfg.RiskReviewScore,
+		BlockScore:        cfg.RiskBlockScore,
+		NewAccountAge:     cfg.RiskNewAccountAge,
+		HighValue:         cfg.RiskHighValues(),
+		BeneficiaryWindow: cfg.RiskBeneficiaryWindow,
+		MaxBeneficiaries:  cfg.RiskMaxBeneficiaries,
+		NearLimitRatio:    cfg.RiskNearLimitRatio,
+		Blocklist:         blocklistCache,
+	}))
 
 	// Create router
 	r := mux.NewRouter()
@@ -66,27 +312,159 @@ func main() {
 	}).Methods("GET")
 
 	// Middlewares
+	r.Use(middleware.RequestID)
+	r.Use(middleware.ClientInfo)
+	r.Use(tracing.Middleware)
 	r.Use(loggingMiddleware)
-	r.Use(corsMiddleware) 
+	r.Use(corsMiddleware(cfg.CORSAllowedOrigins))
 	r.Use(middleware.ContentTypeJSON)
+	r.Use(metrics.Middleware)
 
 	// Swagger docs
 	r.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
 
-	// Health check
-	r.HandleFunc("/health", healthCheck).Methods("GET")
+	// Probes: /livez never touches dependencies, /readyz checks the DB and
+	// that the gateway credentials were loaded
+	checker := health.New(cfg.ReadinessTimeout)
+	checker.Register("database", health.DBCheck(db.DB))
+	checker.Register("razorpay_credentials", razorpay.CheckCredentials)
+
+	r.HandleFunc("/livez", checker.LiveHandler).Methods("GET")
+	r.HandleFunc("/readyz", checker.ReadyHandler).Methods("GET")
+	r.HandleFunc("/health", checker.LiveHandler).Methods("GET") // kept for existing probes
+
+	// Prometheus metrics
+	r.Handle("/metrics", metrics.Handler()).Methods("GET")
+
+	// Razorpay notifications are authenticated by their signature
+	r.HandleFunc("/webhooks/razorpay", handlers.HandleRazorpayWebhook).Methods("POST")
+
+	// Payment links are public: anyone with the code can view and pay
+	r.HandleFunc("/l/{code:[0-9A-Za-z]+}", handlers.GetPublicPaymentLink).Methods("GET")
+	r.HandleFunc("/l/{code:[0-9A-Za-z]+}/pay", handlers.PayPaymentLink).Methods("POST")
 
 	// Protected routes
 	api := r.PathPrefix("/api/v1").Subrouter()
 	api.Use(middleware.JWTAuth)
 	api.HandleFunc("/pay", handlers.HandlePayment).Methods("POST")
+	api.HandleFunc("/pay/verify", handlers.VerifyPayment).Methods("POST")
+	api.HandleFunc("/payments/{id:[0-9]+}/capture", handlers.CapturePayment).Methods("POST")
+	api.HandleFunc("/payments/{id:[0-9]+}/void", handlers.VoidPayment).Methods("POST")
+	api.HandleFunc("/payments/{id:[0-9]+}/receipt", handlers.GetPaymentReceipt).Methods("GET")
+	api.HandleFunc("/statements", handlers.ExportStatement).Methods("GET")
+	api.HandleFunc("/limits", handlers.GetMyLimits).Methods("GET")
+
+	// Currencies and FX quotes for cross-currency payments
+	api.HandleFunc("/currencies", handlers.ListCurrencies).Methods("GET")
+	api.HandleFunc("/fx/rates", handlers.ListFXRates).Methods("GET")
+	api.HandleFunc("/fx/quotes", handlers.CreateFXQuote).Methods("POST")
+	api.HandleFunc("/fx/quotes/{id:[0-9]+}", handlers.GetFXQuote).Methods("GET")
+
+	// Saved payees for /pay
+	api.HandleFunc("/beneficiaries", handlers.CreateBeneficiary).Methods("POST")
+	api.HandleFunc("/beneficiaries", handlers.ListBeneficiaries).Methods("GET")
+	api.HandleFunc("/beneficiaries/{id:[0-9]+}", handlers.GetBeneficiary).Methods("GET")
+	api.HandleFunc("/beneficiaries/{id:[0-9]+}", handlers.DeleteBeneficiary).Methods("DELETE")
+
+	// Scheduled and recurring payments
+	api.HandleFunc("/schedules", handlers.CreateSchedule).Methods("POST")
+	api.HandleFunc("/schedules", handlers.ListSchedules).Methods("GET")
+	api.HandleFunc("/schedules/{id:[0-9]+}", handlers.GetSchedule).Methods("GET")
+	api.HandleFunc("/schedules/{id:[0-9]+}", handlers.UpdateSchedule).Methods("PATCH")
+	api.HandleFunc("/schedules/{id:[0-9]+}", handlers.CancelSchedule).Methods("DELETE")
+	api.HandleFunc("/schedules/{id:[0-9]+}/pause", handlers.PauseSchedule).Methods("POST")
+	api.HandleFunc("/schedules/{id:[0-9]+}/resume", handlers.ResumeSchedule).Methods("POST")
+	api.HandleFunc("/schedules/{id:[0-9]+}/skip-next", handlers.SkipNextOccurrence).Methods("POST")
+	api.HandleFunc("/schedules/{id:[0-9]+}/executions", handlers.ListScheduleExecutions).Methods("GET")
+
+	// Recurring plans billed through Razorpay subscriptions
+	api.HandleFunc("/plans", handlers.ListPlans).Methods("GET")
+	api.HandleFunc("/plans/{id:[0-9]+}", handlers.GetPlan).Methods("GET")
+	api.HandleFunc("/subscriptions", handlers.CreateSubscription).Methods("POST")
+	api.HandleFunc("/subscriptions", handlers.ListSubscriptions).Methods("GET")
+	api.HandleFunc("/subscriptions/{id:[0-9]+}", handlers.GetSubscription).Methods("GET")
+	api.HandleFunc("/subscriptions/{id:[0-9]+}/pause", handlers.PauseSubscription).Methods("POST")
+	api.HandleFunc("/subscriptions/{id:[0-9]+}/resume", handlers.ResumeSubscription).Methods("POST")
+	api.HandleFunc("/subscriptions/{id:[0-9]+}/cancel", handlers.CancelSubscription).Methods("POST")
+	api.HandleFunc("/subscriptions/{id:[0-9]+}/invoices", handlers.ListSubscriptionInvoices).Methods("GET")
+
+	// Payment links requesting money from others
+	api.HandleFunc("/links", handlers.CreatePaymentLink).Methods("POST")
+	api.HandleFunc("/links", handlers.ListPaymentLinks).Methods("GET")
+	api.HandleFunc("/links/{id:[0-9]+}", handlers.GetPaymentLink).Methods("GET")
+	api.HandleFunc("/links/{id:[0-9]+}/cancel", handlers.CancelPaymentLink).Methods("POST")
+	api.HandleFunc("/links/{id:[0-9]+}/payments", handlers.ListPaymentLinkPayments).Methods("GET")
+
+	// Invoices with GST line items
+	api.HandleFunc("/invoices", handlers.CreateInvoice).Methods("POST")
+	api.HandleFunc("/invoices", handlers.ListInvoices).Methods("GET")
+	api.HandleFunc("/invoices/{id:[0-9]+}", handlers.GetInvoice).Methods("GET")
+	api.HandleFunc("/invoices/{id:[0-9]+}.pdf", handlers.GetInvoicePDF).Methods("GET")
+	api.HandleFunc("/invoices/{id:[0-9]+}", handlers.UpdateInvoice).Methods("PATCH")
+	api.HandleFunc("/invoices/{id:[0-9]+}/issue", handlers.IssueInvoice).Methods("POST")
+	api.HandleFunc("/invoices/{id:[0-9]+}/void", handlers.VoidInvoice).Methods("POST")
+	api.HandleFunc("/invoices/{id:[0-9]+}/payment", handlers.LinkInvoicePayment).Methods("POST")
+
+	// Merchant webhook endpoints and their deliveries
+	api.HandleFunc("/webhooks/endpoints", handlers.CreateWebhookEndpoint).Methods("POST")
+	api.HandleFunc("/webhooks/endpoints", handlers.ListWebhookEndpoints).Methods("GET")
+	api.HandleFunc("/webhooks/endpoints/{id:[0-9]+}", handlers.GetWebhookEndpoint).Methods("GET")
+	api.HandleFunc("/webhooks/endpoints/{id:[0-9]+}", handlers.UpdateWebhookEndpoint).Methods("PATCH")
+	api.HandleFunc("/webhooks/endpoints/{id:[0-9]+}", handlers.DeleteWebhookEndpoint).Methods("DELETE")
+	api.HandleFunc("/webhooks/endpoints/{id:[0-9]+}/rotate-secret", handlers.RotateWebhookSecret).Methods("POST")
+	api.HandleFunc("/webhooks/endpoints/{id:[0-9]+}/test", handlers.TestWebhookEndpoint).Methods("POST")
+	api.HandleFunc("/webhooks/deliveries", handlers.ListWebhookDeliveries).Methods("GET")
+	api.HandleFunc("/webhooks/deliveries/{id:[0-9]+}", handlers.GetWebhookDelivery).Methods("GET")
+	api.HandleFunc("/webhooks/deliveries/{id:[0-9]+}/retry", handlers.RetryWebhookDelivery).Methods("POST")
+
+	// Disputes of the caller's payments
+	api.HandleFunc("/disputes", handlers.ListDisputes).Methods("GET")
+	api.HandleFunc("/disputes/{id:[0-9]+}", handlers.GetDispute).Methods("GET")
+	api.HandleFunc("/disputes/{id:[0-9]+}/evidence", handlers.AddDisputeEvidence).Methods("POST")
+	api.HandleFunc("/disputes/{id:[0-9]+}/evidence/{evidence_id:[0-9]+}/file", handlers.GetDisputeEvidenceFile).Methods("GET")
+
+	// Operator routes need the admin role claim
+	admin := api.PathPrefix("/admin").Subrouter()
+	admin.Use(middleware.RequireAdmin)
+	admin.HandleFunc("/reconciliation/runs", handlers.TriggerReconciliation).Methods("POST")
+	admin.HandleFunc("/reconciliation/runs", handlers.ListReconciliationRuns).Methods("GET")
+	admin.HandleFunc("/reconciliation/runs/latest", handlers.GetLatestReconciliationReport).Methods("GET")
+	admin.HandleFunc("/reconciliation/runs/{id:[0-9]+}", handlers.GetReconciliationReport).Methods("GET")
+	admin.HandleFunc("/plans", handlers.CreatePlan).Methods("POST")
+	admin.HandleFunc("/plans/{id:[0-9]+}", handlers.UpdatePlan).Methods("PATCH")
+	admin.HandleFunc("/currencies/{code:[A-Za-z]{3}}", handlers.PutCurrency).Methods("PUT")
+	admin.HandleFunc("/fx/rates", handlers.CreateFXRates).Methods("POST")
+	admin.HandleFunc("/limits/default", handlers.GetDefaultLimits).Methods("GET")
+	admin.HandleFunc("/limits/default", handlers.PutDefaultLimits).Methods("PUT")
+	admin.HandleFunc("/limits/users/{id:[0-9]+}", handlers.GetUserLimits).Methods("GET")
+	admin.HandleFunc("/limits/users/{id:[0-9]+}", handlers.PutUserLimits).Methods("PUT")
+	admin.HandleFunc("/limits/users/{id:[0-9]+}", handlers.DeleteUserLimits).Methods("DELETE")
+	admin.HandleFunc("/risk/reviews", handlers.ListRiskReviews).Methods("GET")
+	admin.HandleFunc("/risk/reviews/{id:[0-9]+}", handlers.GetRiskReview).Methods("GET")
+	admin.HandleFunc("/risk/reviews/{id:[0-9]+}/approve", handlers.ApproveRiskReview).Methods("POST")
+	admin.HandleFunc("/risk/reviews/{id:[0-9]+}/reject", handlers.RejectRiskReview).Methods("POST")
+	admin.HandleFunc("/risk/assessments", handlers.ListRiskAssessments).Methods("GET")
+	admin.HandleFunc("/blocklist", handlers.ListBlocklist).Methods("GET")
+	admin.HandleFunc("/blocklist", handlers.CreateBlocklistEntry).Methods("POST")
+	admin.HandleFunc("/blocklist/import", handlers.ImportBlocklist).Methods("POST")
+	admin.HandleFunc("/blocklist/{id:[0-9]+}", handlers.GetBlocklistEntry).Methods("GET")
+	admin.HandleFunc("/blocklist/{id:[0-9]+}", handlers.DeleteBlocklistEntry).Methods("DELETE")
+	admin.HandleFunc("/audit", handlers.ListAuditLog).Methods("GET")
+	admin.HandleFunc("/audit/verify", handlers.VerifyAuditLog).Methods("GET")
+	admin.HandleFunc("/disputes", handlers.AdminListDisputes).Methods("GET")
+	admin.HandleFunc("/disputes/{id:[0-9]+}", handlers.AdminGetDispute).Methods("GET")
+	admin.HandleFunc("/disputes/{id:[0-9]+}/evidence/{evidence_id:[0-9]+}/file", handlers.AdminGetDisputeEvidenceFile).Methods("GET")
+
+	// Service-to-service routes; the gateway does not expose them
+	internal := api.PathPrefix("/internal").Subrouter()
+	internal.Use(middleware.RequireService)
+	internal.HandleFunc("/blocklist", handlers.GetBlocklistSnapshot).Methods("GET")
 
 	// Server setup
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8084"
-	}
+	port := cfg.Port
 
+	// Statement exports move their own write deadline past WriteTimeout
+	// as they stream
 	srv := &http.Server{
 		Addr:         ":" + port,
 		Handler:      r,
@@ -100,54 +478,83 @@ func main() {
 	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 
 	go func() {
-		log.Printf("Payment Service running on http://localhost:%s", port)
-		log.Printf("Swagger UI available at http://localhost:%s/swagger/index.html", port)
+		slog.Info("Payment Service running",
+			"port", port,
+			"swagger", "http://localhost:"+port+"/swagger/index.html",
+		)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server error: %v", err)
+			slog.Error("Server error", "error", err)
+			os.Exit(1)
 		}
 	}()
 
 	<-done
-	log.Println("Shutting down Payment Service...")
+	slog.Info("Shutting down Payment Service...", "drain_delay", cfg.ShutdownDrainDelay)
+
+	// Fail readiness first so load balancers stop routing new traffic
+	checker.SetDraining()
+	time.Sleep(cfg.ShutdownDrainDelay)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatalf("Shutdown error: %v", err)
+		slog.Error("Shutdown error", "error", err)
+		os.Exit(1)
 	}
 
-	log.Println("Stopped gracefully")
-}
+	// Let in-flight worker batches finish before closing their outputs
+	stopWorkers()
+	workers.Wait()
+	if err := closePublisher(); err != nil {
+		slog.Warn("Outbox publisher close error", "error", err)
+	}
 
-// Health check endpoint
-func healthCheck(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("OK"))
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Warn("Tracing shutdown error", "error", err)
+	}
+
+	slog.Info("Stopped gracefully")
 }
 
 // Logging middleware
 func loggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
-		log.Printf("Started %s %s", r.Method, r.URL.Path)
+		slog.DebugContext(r.Context(), "Request started", "method", r.Method, "path", r.URL.Path)
 
 		next.ServeHTTP(w, r)
 
-		log.Printf("Completed %s %s in %v", r.Method, r.URL.Path, time.Since(start))
+		slog.InfoContext(r.Context(), "Request completed",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"duration", time.Since(start),
+		)
 	})
 }
 
 // CORS middleware for allowing frontend requests
-func corsMiddleware(next http.Handler) http.Handler {
+func corsMiddleware(allowedOrigins []string) mux.MiddlewareFunc {
+	allowed := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		allowed[origin] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return corsHandler(next, allowed)
+	}
+}
+
+func corsHandler(next http.Handler, allowed map[string]bool) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		origin := r.Header.Get("Origin")
-		if origin == "http://localhost:3000" {
+		if allowed[origin] {
 			w.Header().Set("Access-Control-Allow-Origin", origin)
 		}
 
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, X-Request-ID")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID")
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
 
 		if r.Method == http.MethodOptions {
@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	outboxPublished = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "outbox_events_published_total",
+		Help:      "Outbox events delivered to the publisher, by event type.",
+	}, []string{"type"})
+
+	outboxFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "outbox_publish_failures_total",
+		Help:      "Failed outbox publish attempts (retried later), by event type.",
+	}, []string{"type"})
+
+	outboxLag = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "outbox_publish_lag_seconds",
+		Help:      "Time from an event being recorded to it being published.",
+		Buckets:   []float64{.1, .5, 1, 2.5, 5, 15, 60, 300, 900, 3600},
+	})
+)
+
+// OutboxPublished counts a delivered event recorded at createdAt.
+func OutboxPublished(eventType string, createdAt time.Time) {
+	outboxPublished.WithLabelValues(eventType).Inc()
+	outboxLag.Observe(time.Since(createdAt).Seconds())
+}
+
+// OutboxPublishFailed counts a failed delivery attempt.
+func OutboxPublishFailed(eventType string) {
+	outboxFailures.WithLabelValues(eventType).Inc()
+}
+
+var (
+	webhookDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "webhook_deliveries_total",
+		Help:      "Merchant webhook delivery attempts, by outcome.",
+	}, []string{"outcome"})
+
+	webhookLatency = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "webhook_delivery_duration_seconds",
+		Help:      "Latency of merchant webhook delivery attempts.",
+		Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10},
+	})
+)
+
+// WebhookDelivery records one merchant webhook attempt.
+func WebhookDelivery(outcome string, latency time.Duration) {
+	webhookDeliveries.WithLabelValues(outcome).Inc()
+	webhookLatency.Observe(latency.Seconds())
+}
@@ -0,0 +1,95 @@
+package metrics
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	paymentsCreated = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "payments_created_total",
+		Help:      "Payment creation attempts, by resulting status and currency.",
+	}, []string{"status", "currency"})
+
+	gatewayDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "gateway_request_duration_seconds",
+		Help:      "Latency of calls to the payment gateway (Razorpay), by operation and outcome.",
+		Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"operation", "outcome"})
+)
+
+// PaymentCreated counts a payment creation attempt ending in status.
+func PaymentCreated(status, currency string) {
+	paymentsCreated.WithLabelValues(status, currency).Inc()
+}
+
+// ObserveGatewayCall records the latency of a gateway call started at start.
+func ObserveGatewayCall(operation string, start time.Time, err error) {
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+	}
+	gatewayDuration.WithLabelValues(operation, outcome).Observe(time.Since(start).Seconds())
+}
+
+// RegisterDB exports connection pool stats from sql.DB.Stats().
+func RegisterDB(db *sql.DB) {
+	prometheus.MustRegister(collectors.NewDBStatsCollector(db, "payment"))
+}
+
+var gatewayWebhooks = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "gateway_webhooks_total",
+	Help:      "Inbound Razorpay webhooks, by event and outcome.",
+}, []string{"event", "outcome"})
+
+// GatewayWebhook counts an inbound Razorpay webhook and how it was handled
+// (applied, duplicate, ignored, expired, rejected, error).
+func GatewayWebhook(event, outcome string) {
+	gatewayWebhooks.WithLabelValues(event, outcome).Inc()
+}
+
+var paymentsExpired = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "payments_expired_total",
+	Help:      "Unpaid payments moved to expired, by currency.",
+}, []string{"currency"})
+
+// PaymentExpired counts a payment the expiry sweeper expired.
+func PaymentExpired(currency string) {
+	paymentsExpired.WithLabelValues(currency).Inc()
+}
+
+var paymentsVoided = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "payments_voided_total",
+	Help:      "Manual-capture authorizations voided, by reason (requested, expired).",
+}, []string{"reason"})
+
+// PaymentVoided counts a voided authorization.
+func PaymentVoided(reason string) {
+	paymentsVoided.WithLabelValues(reason).Inc()
+}
+
+var disputePhases = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "dispute_phases_total",
+	Help:      "Disputes entering each phase (created, under_review, won, lost, closed).",
+}, []string{"phase"})
+
+// DisputePhase counts a dispute recorded in or moved to phase.
+func DisputePhase(phase string) {
+	disputePhases.WithLabelValues(phase).Inc()
+}
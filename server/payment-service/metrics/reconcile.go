@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	reconcileRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "reconciliation_runs_total",
+		Help:      "Reconciliation runs against Razorpay, by outcome.",
+	}, []string{"status"})
+
+	reconcileDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "reconciliation_run_duration_seconds",
+		Help:      "Duration of reconciliation runs.",
+		Buckets:   []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800},
+	})
+
+	reconcileDiscrepancies = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "reconciliation_discrepancies_total",
+		Help:      "Reconciliation findings, by kind; status_corrected counts applied fixes.",
+	}, []string{"kind"})
+)
+
+// ReconcileRun records a finished reconciliation run.
+func ReconcileRun(status string, duration time.Duration) {
+	reconcileRuns.WithLabelValues(status).Inc()
+	reconcileDuration.Observe(duration.Seconds())
+}
+
+// ReconcileDiscrepancy counts one reconciliation finding.
+func ReconcileDiscrepancy(kind string) {
+	reconcileDiscrepancies.WithLabelValues(kind).Inc()
+}
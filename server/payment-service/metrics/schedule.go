@@ -0,0 +1,18 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var scheduleExecutions = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "schedule_executions_total",
+	Help:      "Scheduled payment attempts, by outcome; pending counts failures that will be retried.",
+}, []string{"status"})
+
+// ScheduleExecution counts one attempt at a scheduled payment.
+func ScheduleExecution(status string) {
+	scheduleExecutions.WithLabelValues(status).Inc()
+}
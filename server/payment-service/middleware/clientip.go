@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/internal/config"
+)
+
+// ClientIP returns the address the request came from: the peer address,
+// unless the peer is one of TRUSTED_PROXIES such as the API gateway. Then
+// X-Forwarded-For is read from the right, past any hops that are trusted
+// proxies too, and the first address a trusted proxy did not add is the
+// client; entries further left could have been written by anyone. The
+// result is invalid if the peer address does not parse.
+func ClientIP(r *http.Request) netip.Addr {
+	return clientIP(r, config.Get().TrustedProxyPrefixes())
+}
+
+func clientIP(r *http.Request, trusted []netip.Prefix) netip.Addr {
+	addr, ok := parseAddr(r.RemoteAddr)
+	if !ok || !contains(trusted, addr) {
+		return addr
+	}
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, ok := parseAddr(strings.TrimSpace(hops[i]))
+		if !ok {
+			// Nothing left of an unreadable hop can be placed
+			break
+		}
+		addr = hop
+		if !contains(trusted, hop) {
+			break
+		}
+	}
+	return addr
+}
+
+func contains(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, p := range prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+const clientKey contextKey = "client"
+
+// Client is who made a request, as recorded in the audit log.
+type Client struct {
+	IP        netip.Addr
+	UserAgent string
+}
+
+// ClientInfo stores the request's client address and user agent in its
+// context.
+func ClientInfo(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := Client{IP: ClientIP(r), UserAgent: r.UserAgent()}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientKey, c)))
+	})
+}
+
+// GetClient returns the client ClientInfo stored in ctx; ok is false
+// outside a request.
+func GetClient(ctx context.Context) (c Client, ok bool) {
+	c, ok = ctx.Value(clientKey).(Client)
+	return c, ok
+}
+
+// parseAddr parses an address with or without a port.
+func parseAddr(s string) (netip.Addr, bool) {
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
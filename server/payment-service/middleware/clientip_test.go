@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	gateway := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/24"), netip.MustParsePrefix("::1/128")}
+	tests := []struct {
+		name    string
+		peer    string
+		xff     []string
+		trusted []netip.Prefix
+		want    string
+	}{
+		{name: "direct", peer: "203.0.113.7:5123", want: "203.0.113.7"},
+		{name: "spoofed from outside", peer: "203.0.113.7:5123", xff: []string{"198.51.100.1"}, trusted: gateway, want: "203.0.113.7"},
+		{name: "no proxies trusted", peer: "10.0.0.2:5123", xff: []string{"198.51.100.1"}, want: "10.0.0.2"},
+		{name: "through the gateway", peer: "10.0.0.2:5123", xff: []string{"198.51.100.1"}, trusted: gateway, want: "198.51.100.1"},
+		{name: "gateway with port and appended hop", peer: "10.0.0.2:5123", xff: []string{"198.51.100.1:40000, 198.51.100.1"}, trusted: gateway, want: "198.51.100.1"},
+		{name: "client prepends a lie", peer: "10.0.0.2:5123", xff: []string{"1.2.3.4, 198.51.100.1"}, trusted: gateway, want: "198.51.100.1"},
+		{name: "lie in an earlier header", peer: "10.0.0.2:5123", xff: []string{"1.2.3.4", "198.51.100.1"}, trusted: gateway, want: "198.51.100.1"},
+		{name: "chain of trusted proxies", peer: "10.0.0.2:5123", xff: []string{"198.51.100.1, 10.0.0.9"}, trusted: gateway, want: "198.51.100.1"},
+		{name: "only trusted hops", peer: "10.0.0.2:5123", xff: []string{"10.0.0.5, 10.0.0.9"}, trusted: gateway, want: "10.0.0.5"},
+		{name: "unreadable hop", peer: "10.0.0.2:5123", xff: []string{"1.2.3.4, junk, 10.0.0.9"}, trusted: gateway, want: "10.0.0.9"},
+		{name: "trusted without header", peer: "10.0.0.2:5123", trusted: gateway, want: "10.0.0.2"},
+		{name: "IPv6 loopback proxy", peer: "[::1]:5123", xff: []string{"2001:db8::1"}, trusted: gateway, want: "2001:db8::1"},
+		{name: "IPv4-mapped peer", peer: "[::ffff:10.0.0.2]:5123", xff: []string{"198.51.100.1"}, trusted: gateway, want: "198.51.100.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.peer
+			for _, v := range tt.xff {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+			if got := clientIP(r, tt.trusted); got.String() != tt.want {
+				t.Errorf("clientIP = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "not an address"
+	if got := clientIP(r, gateway); got.IsValid() {
+		t.Errorf("clientIP of an unreadable peer = %s, want invalid", got)
+	}
+}
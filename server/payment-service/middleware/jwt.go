@@ -2,11 +2,14 @@ package middleware
 
 import (
 	"context"
-	"log"
+	"encoding/json"
+	"log/slog"
 	"net/http"
-	"os"
 	"strings"
 
+	"github.com/RaginiSharma01/gopay-lite/payment-service/internal/config"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/logging"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/models"
 	"github.com/golang-jwt/jwt/v5"
 )
 
@@ -15,6 +18,14 @@ type contextKey string
 const (
 	EmailKey  contextKey = "email"
 	UserIDKey contextKey = "userID"
+	RoleKey   contextKey = "role"
+)
+
+// Roles auth-service issues in the JWT "role" claim: RoleAdmin to
+// operators, RoleService to itself when calling this service.
+const (
+	RoleAdmin   = "admin"
+	RoleService = "service"
 )
 
 // Exported for access in handlers
@@ -27,24 +38,24 @@ func JWTAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
-			log.Println("Missing Authorization header")
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			slog.WarnContext(r.Context(), "Missing Authorization header")
+			writeError(w, r, http.StatusUnauthorized, "Unauthorized", "Authorization header required")
 			return
 		}
 
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 		if tokenString == "" {
-			http.Error(w, "Bearer token required", http.StatusUnauthorized)
+			writeError(w, r, http.StatusUnauthorized, "Unauthorized", "Bearer token required")
 			return
 		}
 
 		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			return []byte(os.Getenv("JWT_SECRET")), nil
+			return []byte(config.GetJWTSecret()), nil
 		}, jwt.WithValidMethods([]string{"HS256"}))
 
 		if err != nil || !token.Valid {
-			log.Printf("Token parsing error: %v", err)
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
+			slog.WarnContext(r.Context(), "Token parsing error", "error", err)
+			writeError(w, r, http.StatusUnauthorized, "Unauthorized", "Invalid token")
 			return
 		}
 
@@ -52,21 +63,74 @@ func JWTAuth(next http.Handler) http.Handler {
 			email, _ := claims["email"].(string)
 			uidFloat, ok := claims["user_id"].(float64)
 			if !ok {
-				http.Error(w, "Invalid token claims: user_id missing", http.StatusUnauthorized)
+				writeError(w, r, http.StatusUnauthorized, "Unauthorized", "Invalid token claims: user_id missing")
 				return
 			}
 			userID := int(uidFloat)
+			role, _ := claims["role"].(string)
 
 			ctx := context.WithValue(r.Context(), EmailKey, email)
 			ctx = context.WithValue(ctx, UserIDKey, userID)
+			ctx = context.WithValue(ctx, RoleKey, role)
+			ctx = logging.WithAttrs(ctx, slog.Int("user_id", userID))
 			next.ServeHTTP(w, r.WithContext(ctx))
 			return
 		}
 
-		http.Error(w, "Failed to parse claims", http.StatusUnauthorized)
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized", "Failed to parse claims")
+	})
+}
+
+// RequireAdmin rejects requests whose token does not carry the admin role.
+// It must run after JWTAuth.
+func RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !IsAdmin(r.Context()) {
+			slog.WarnContext(r.Context(), "Admin route denied", "path", r.URL.Path)
+			writeError(w, r, http.StatusForbidden, "Forbidden", "Admin role required")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireService rejects requests whose token was not issued to another
+// service. It must run after JWTAuth.
+func RequireService(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if role, _ := r.Context().Value(RoleKey).(string); role != RoleService {
+			slog.WarnContext(r.Context(), "Internal route denied", "path", r.URL.Path)
+			writeError(w, r, http.StatusForbidden, "Forbidden", "Only other services may call this route")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// writeError writes a models.ErrorResponse tagged with the request ID, as
+// the handlers do.
+func writeError(w http.ResponseWriter, r *http.Request, code int, errType, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(models.ErrorResponse{
+		Error:     errType,
+		Message:   message,
+		RequestID: GetRequestID(r.Context()),
 	})
 }
 
+// GetEmail returns the email claim JWTAuth found in the token.
+func GetEmail(ctx context.Context) string {
+	email, _ := ctx.Value(EmailKey).(string)
+	return email
+}
+
+// IsAdmin reports whether JWTAuth found the admin role in the token.
+func IsAdmin(ctx context.Context) bool {
+	role, _ := ctx.Value(RoleKey).(string)
+	return role == RoleAdmin
+}
+
 // ContentTypeJSON sets response content type to application/json
 func ContentTypeJSON(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
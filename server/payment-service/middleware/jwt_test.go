@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/models"
+)
+
+func TestAuthFailuresAreJSON(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	tests := []struct {
+		name    string
+		handler http.Handler
+		header  string
+		role    string
+		code    int
+		message string
+	}{
+		{"no header", JWTAuth(ok), "", "", http.StatusUnauthorized, "Authorization header required"},
+		{"empty bearer", JWTAuth(ok), "Bearer ", "", http.StatusUnauthorized, "Bearer token required"},
+		{"user on admin route", RequireAdmin(ok), "", "", http.StatusForbidden, "Admin role required"},
+		{"admin on internal route", RequireService(ok), "", RoleAdmin, http.StatusForbidden, "Only other services may call this route"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/payments", nil)
+		r.Header.Set(RequestIDHeader, "req-123")
+		if tt.header != "" {
+			r.Header.Set("Authorization", tt.header)
+		}
+		if tt.role != "" {
+			r = r.WithContext(context.WithValue(r.Context(), RoleKey, tt.role))
+		}
+		w := httptest.NewRecorder()
+		RequestID(tt.handler).ServeHTTP(w, r)
+
+		var got models.ErrorResponse
+		if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+			t.Errorf("%s: body is not JSON: %v", tt.name, err)
+			continue
+		}
+		if w.Code != tt.code || w.Header().Get("Content-Type") != "application/json" ||
+			got.Message != tt.message || got.RequestID != "req-123" {
+			t.Errorf("%s: %d %s %+v, want %d %q for req-123", tt.name, w.Code, w.Header().Get("Content-Type"), got, tt.code, tt.message)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditEntryResponse is an audit log entry
+// @swagger:model AuditEntryResponse
+type AuditEntryResponse struct {
+	// example: 5120
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// auth or payment
+	// example: payment
+	Service string `json:"service"`
+
+	// example: payment.status_changed
+	Action string `json:"action"`
+
+	// success or failure
+	// example: success
+	Outcome string `json:"outcome"`
+
+	// user, admin, service, razorpay, anonymous or system
+	// example: razorpay
+	ActorType  string `json:"actor_type"`
+	ActorID    *int   `json:"actor_id,omitempty"`
+	ActorEmail string `json:"actor_email,omitempty"`
+	IP         string `json:"ip,omitempty"`
+	UserAgent  string `json:"user_agent,omitempty"`
+	RequestID  string `json:"request_id,omitempty"`
+
+	// example: payment
+	ResourceType string `json:"resource_type,omitempty"`
+	// example: 42
+	ResourceID string `json:"resource_id,omitempty"`
+
+	Before json.RawMessage `json:"before,omitempty" swaggertype:"object"`
+	After  json.RawMessage `json:"after,omitempty" swaggertype:"object"`
+	Detail string          `json:"detail,omitempty"`
+
+	// Place in the hash chain. Entries are chained a few seconds after
+	// they are written; seq, prev_hash and hash are absent until then.
+	// example: 5087
+	Seq *int64 `json:"seq,omitempty"`
+
+	// Hash of the previous entry, which this entry's hash covers
+	PrevHash string `json:"prev_hash,omitempty"`
+	Hash     string `json:"hash,omitempty"`
+}
+
+// AuditVerifyResponse is the result of checking the audit log's hash chain
+// @swagger:model AuditVerifyResponse
+type AuditVerifyResponse struct {
+	// example: true
+	Intact bool `json:"intact"`
+
+	// Entries read, up to and including the first broken one
+	// example: 5120
+	Checked int64 `json:"checked"`
+
+	// First entry altered or not following the one before it
+	BrokenAt int64  `json:"broken_at,omitempty"`
+	Problem  string `json:"problem,omitempty"`
+
+	// Hash of the last entry checked
+	LastHash string `json:"last_hash"`
+
+	// Entries written but not yet chained, and so not checked
+	// example: 3
+	Pending int64 `json:"pending"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// BeneficiaryRequest saves a payee
+// @swagger:model BeneficiaryRequest
+type BeneficiaryRequest struct {
+	// Name shown to the user; unique per user
+	// required: true
+	// example: Landlord
+	Nickname string `json:"nickname"`
+
+	// bank_account or vpa
+	// required: true
+	// example: bank_account
+	Type string `json:"type"`
+
+	// 9 to 18 digits (bank_account)
+	// example: 50100123456789
+	AccountNumber string `json:"account_number,omitempty"`
+
+	// Branch IFSC (bank_account)
+	// example: HDFC0001234
+	IFSC string `json:"ifsc,omitempty"`
+
+	// example: Asha Verma
+	AccountHolderName string `json:"account_holder_name,omitempty"`
+
+	// UPI address (vpa)
+	// maxLength: 128
+	// example: asha@okhdfcbank
+	VPA string `json:"vpa,omitempty"`
+}
+
+// BeneficiaryResponse describes a saved payee. Account numbers are masked.
+// @swagger:model BeneficiaryResponse
+type BeneficiaryResponse struct {
+	ID       int64  `json:"id"`
+	Nickname string `json:"nickname"`
+	Type     string `json:"type"`
+
+	// example: **********6789
+	AccountNumber     string    `json:"account_number,omitempty"`
+	IFSC              string    `json:"ifsc,omitempty"`
+	AccountHolderName string    `json:"account_holder_name,omitempty"`
+	VPA               string    `json:"vpa,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+}
@@ -0,0 +1,77 @@
+package models
+
+import "time"
+
+// BlocklistEntryRequest blocklists a value
+// @swagger:model BlocklistEntryRequest
+type BlocklistEntryRequest struct {
+	// account, vpa, email or ip
+	// example: vpa
+	Kind string `json:"kind"`
+
+	// An account number, VPA, email (or @domain for a whole domain), or an
+	// IP address or CIDR network
+	// example: mule.account@okbank
+	Value string `json:"value"`
+
+	// example: Reported in fraud case FR-2291
+	Reason string `json:"reason"`
+
+	// Omit to block until removed
+	// example: 2027-01-01T00:00:00Z
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// BlocklistEntryResponse is a blocklisted value
+// @swagger:model BlocklistEntryResponse
+type BlocklistEntryResponse struct {
+	// example: 12
+	ID int64 `json:"id"`
+
+	// example: vpa
+	Kind string `json:"kind"`
+
+	// Normalised: upper-case account, lower-case VPA or email, masked network
+	// example: mule.account@okbank
+	Value  string `json:"value"`
+	Reason string `json:"reason"`
+
+	// Absent for an entry that never expires
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	CreatedBy int        `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+
+	// Set once the entry has been removed
+	RemovedAt *time.Time `json:"removed_at,omitempty"`
+	RemovedBy *int       `json:"removed_by,omitempty"`
+}
+
+// BlocklistImportResponse summarises a CSV import
+// @swagger:model BlocklistImportResponse
+type BlocklistImportResponse struct {
+	// Entries newly listed
+	// example: 40
+	Added int `json:"added"`
+
+	// Entries already listed whose reason, expiry and creator were replaced
+	// example: 2
+	Replaced int `json:"replaced"`
+}
+
+// BlocklistSnapshotEntry is a live entry as other services cache it
+// @swagger:model BlocklistSnapshotEntry
+type BlocklistSnapshotEntry struct {
+	Kind      string     `json:"kind"`
+	Value     string     `json:"value"`
+	Reason    string     `json:"reason"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// BlocklistSnapshotResponse is every live entry at a revision of the list
+// @swagger:model BlocklistSnapshotResponse
+type BlocklistSnapshotResponse struct {
+	// Increases with every change to the list
+	// example: 318
+	Revision int64                    `json:"revision"`
+	Entries  []BlocklistSnapshotEntry `json:"entries"`
+}
@@ -0,0 +1,98 @@
+package models
+
+import "time"
+
+// DisputeResponse is a cardholder's dispute of a payment
+// @swagger:model DisputeResponse
+type DisputeResponse struct {
+	// example: 9
+	ID int64 `json:"id"`
+
+	// example: disp_Esz7KAitoYM7PJ
+	RazorpayDisputeID string `json:"razorpay_dispute_id"`
+
+	// example: 42
+	PaymentID int `json:"payment_id"`
+	UserID    int `json:"user_id"`
+
+	// Amount disputed
+	// example: 1000
+	Amount float64 `json:"amount"`
+
+	// example: INR
+	Currency string `json:"currency"`
+
+	// Held back from settlements while the dispute is open
+	// example: 1000
+	AmountDeducted float64 `json:"amount_deducted"`
+
+	// example: chargeback_fraud
+	ReasonCode        string `json:"reason_code"`
+	ReasonDescription string `json:"reason_description"`
+
+	// Razorpay's escalation stage: retrieval, chargeback, pre_arbitration,
+	// arbitration or fraud
+	// example: chargeback
+	GatewayPhase string `json:"gateway_phase"`
+
+	// created, under_review, won, lost or closed
+	// example: created
+	Phase string `json:"phase"`
+
+	// Evidence is due by then; absent when no deadline was set
+	RespondBy *time.Time `json:"respond_by,omitempty"`
+
+	// Whether evidence can still be added
+	// example: true
+	AcceptsEvidence bool       `json:"accepts_evidence"`
+	ResolvedAt      *time.Time `json:"resolved_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// DisputeEvidenceRequest adds a note to a dispute
+// @swagger:model DisputeEvidenceRequest
+type DisputeEvidenceRequest struct {
+	// explanation_letter, proof_of_delivery, proof_of_service,
+	// customer_communication, billing_proof, refund_confirmation,
+	// cancellation_proof or others (default)
+	// example: proof_of_delivery
+	Category string `json:"category"`
+
+	// example: Delivered on 3 March; the courier's signed receipt is attached.
+	Note string `json:"note"`
+}
+
+// DisputeEvidenceResponse is a note or file submitted against a dispute
+// @swagger:model DisputeEvidenceResponse
+type DisputeEvidenceResponse struct {
+	// example: 31
+	ID        int64 `json:"id"`
+	DisputeID int64 `json:"dispute_id"`
+
+	// text or file
+	// example: file
+	Kind string `json:"kind"`
+
+	// example: proof_of_delivery
+	Category string `json:"category"`
+	Note     string `json:"note,omitempty"`
+
+	// File evidence only
+	// example: delivery-receipt.pdf
+	Filename    string `json:"filename,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	SizeBytes   int    `json:"size_bytes,omitempty"`
+
+	// Hex SHA-256 of the file
+	SHA256     string    `json:"sha256,omitempty"`
+	UploadedBy int       `json:"uploaded_by"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// DisputeDetailResponse is a dispute with its evidence
+// @swagger:model DisputeDetailResponse
+type DisputeDetailResponse struct {
+	DisputeResponse
+	Evidence []DisputeEvidenceResponse `json:"evidence"`
+}
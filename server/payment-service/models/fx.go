@@ -0,0 +1,164 @@
+package models
+
+import "time"
+
+// CurrencyRequest adds or updates a registry entry
+// @swagger:model CurrencyRequest
+type CurrencyRequest struct {
+	// required: true
+	// example: Japanese Yen
+	Name string `json:"name"`
+
+	// Minor-unit digits Razorpay expects, 0 to 2
+	// required: true
+	// example: 0
+	Exponent *int `json:"exponent"`
+
+	// Whether /pay accepts the currency
+	// required: true
+	// example: true
+	Enabled *bool `json:"enabled"`
+}
+
+// CurrencyResponse is a registry entry
+// @swagger:model CurrencyResponse
+type CurrencyResponse struct {
+	// example: INR
+	Code string `json:"code"`
+
+	// example: Indian Rupee
+	Name string `json:"name"`
+
+	// example: 2
+	Exponent  int       `json:"exponent"`
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// FXRateRequest is one exchange rate: 1 base = rate quote
+// @swagger:model FXRateRequest
+type FXRateRequest struct {
+	// required: true
+	// example: USD
+	Base string `json:"base"`
+
+	// required: true
+	// example: INR
+	Quote string `json:"quote"`
+
+	// required: true
+	// example: 83.125
+	Rate float64 `json:"rate"`
+
+	// When the rate takes effect; defaults to now
+	// example: 2026-10-18T09:00:00Z
+	EffectiveAt *time.Time `json:"effective_at,omitempty"`
+}
+
+// FXRatesRequest adds exchange rates
+// @swagger:model FXRatesRequest
+type FXRatesRequest struct {
+	// required: true
+	Rates []FXRateRequest `json:"rates"`
+}
+
+// FXRateResponse is a stored exchange rate
+// @swagger:model FXRateResponse
+type FXRateResponse struct {
+	ID int64 `json:"id"`
+
+	// example: USD
+	Base string `json:"base"`
+
+	// example: INR
+	Quote string `json:"quote"`
+
+	// example: 83.125
+	Rate        float64   `json:"rate"`
+	EffectiveAt time.Time `json:"effective_at"`
+
+	// file or admin
+	// example: admin
+	Source    string    `json:"source"`
+	CreatedBy *int      `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// FXRatesResponse reports an import of exchange rates
+// @swagger:model FXRatesResponse
+type FXRatesResponse struct {
+	// Rates stored; rates already stored for the pair and time are skipped
+	// example: 2
+	Inserted int `json:"inserted"`
+}
+
+// FXQuoteRequest prices a conversion
+// @swagger:model FXQuoteRequest
+type FXQuoteRequest struct {
+	// Currency debited
+	// required: true
+	// example: USD
+	From string `json:"from"`
+
+	// Currency credited
+	// required: true
+	// example: INR
+	To string `json:"to"`
+
+	// Amount in from
+	// required: true
+	// example: 100
+	Amount float64 `json:"amount"`
+}
+
+// FXQuoteResponse is a rate locked for a conversion until expires_at
+// @swagger:model FXQuoteResponse
+type FXQuoteResponse struct {
+	// Pass as quote_id to POST /pay
+	// example: 12
+	ID int64 `json:"id"`
+
+	// example: USD
+	From string `json:"from"`
+
+	// example: INR
+	To string `json:"to"`
+
+	// 1 from = rate to
+	// example: 83.125
+	Rate float64 `json:"rate"`
+
+	// Debited, in from
+	// example: 100
+	Amount float64 `json:"amount"`
+
+	// Credited, in to
+	// example: 8312.5
+	ConvertedAmount float64 `json:"converted_amount"`
+
+	ExpiresAt time.Time `json:"expires_at"`
+
+	// Set once a payment has used the quote
+	PaymentID *int64     `json:"payment_id,omitempty"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// ConversionResponse records the rate a cross-currency payment used
+// @swagger:model ConversionResponse
+type ConversionResponse struct {
+	// example: 12
+	QuoteID int64 `json:"quote_id"`
+
+	// 1 payment currency = rate credited currency
+	// example: 83.125
+	Rate float64 `json:"rate"`
+
+	// What the payee is credited
+	// example: 8312.5
+	CreditedAmount float64 `json:"credited_amount"`
+
+	// example: INR
+	CreditedCurrency string `json:"credited_currency"`
+}
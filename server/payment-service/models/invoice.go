@@ -0,0 +1,154 @@
+package models
+
+import "time"
+
+// InvoiceItemRequest is one line of an invoice
+// @swagger:model InvoiceItemRequest
+type InvoiceItemRequest struct {
+	// required: true
+	// example: Website design
+	Description string `json:"description"`
+
+	// HSN code for goods or SAC code for services
+	// example: 998314
+	HSNSAC string `json:"hsn_sac,omitempty"`
+
+	// Up to three decimal places
+	// required: true
+	// example: 2
+	Quantity float64 `json:"quantity"`
+
+	// Price per unit before tax
+	// required: true
+	// example: 15000
+	UnitPrice float64 `json:"unit_price"`
+
+	// GST rate in percent
+	// example: 18
+	TaxRate float64 `json:"tax_rate"`
+}
+
+// InvoiceRequest creates a draft invoice or replaces a draft's contents
+// @swagger:model InvoiceRequest
+type InvoiceRequest struct {
+	// default: "INR"
+	// example: INR
+	Currency string `json:"currency,omitempty"`
+
+	// required: true
+	// example: Acme Studio
+	SupplierName string `json:"supplier_name"`
+
+	// example: 29ABCDE1234F1Z5
+	SupplierGSTIN string `json:"supplier_gstin,omitempty"`
+
+	// example: 12 MG Road, Bengaluru
+	SupplierAddress string `json:"supplier_address,omitempty"`
+
+	// Two-digit GST state code; defaults to the one in supplier_gstin
+	// example: 29
+	SupplierState string `json:"supplier_state,omitempty"`
+
+	// State code of the place of supply; defaults to supplier_state.
+	// The same state is charged CGST and SGST, any other IGST.
+	// example: 27
+	PlaceOfSupply string `json:"place_of_supply,omitempty"`
+
+	// required: true
+	// example: Globex Pvt Ltd
+	CustomerName string `json:"customer_name"`
+
+	// example: accounts@globex.example
+	CustomerEmail string `json:"customer_email,omitempty"`
+
+	// example: 27AAACG1234A1Z2
+	CustomerGSTIN string `json:"customer_gstin,omitempty"`
+
+	// example: 4 Marine Drive, Mumbai
+	CustomerAddress string `json:"customer_address,omitempty"`
+
+	// example: Payment within 15 days
+	Notes *string `json:"notes,omitempty"`
+
+	// required: true
+	Items []InvoiceItemRequest `json:"items"`
+}
+
+// IssueInvoiceRequest issues a draft
+// @swagger:model IssueInvoiceRequest
+type IssueInvoiceRequest struct {
+	// example: 2026-11-15T00:00:00Z
+	DueAt *time.Time `json:"due_at,omitempty"`
+}
+
+// LinkInvoicePaymentRequest settles an invoice with a payment
+// @swagger:model LinkInvoicePaymentRequest
+type LinkInvoicePaymentRequest struct {
+	// A payment for the invoice's total in its currency
+	// required: true
+	// example: 42
+	PaymentID int64 `json:"payment_id"`
+}
+
+// InvoiceItemResponse is one line of an invoice with its computed amounts
+// @swagger:model InvoiceItemResponse
+type InvoiceItemResponse struct {
+	Description string  `json:"description"`
+	HSNSAC      string  `json:"hsn_sac,omitempty"`
+	Quantity    float64 `json:"quantity"`
+	UnitPrice   float64 `json:"unit_price"`
+	TaxRate     float64 `json:"tax_rate"`
+
+	// Taxable value of the line
+	Amount float64 `json:"amount"`
+	CGST   float64 `json:"cgst"`
+	SGST   float64 `json:"sgst"`
+	IGST   float64 `json:"igst"`
+}
+
+// InvoiceResponse describes an invoice. Amounts are in major units, with
+// totals also in minor units as they are computed.
+// @swagger:model InvoiceResponse
+type InvoiceResponse struct {
+	ID int64 `json:"id"`
+
+	// Assigned on issue
+	// example: INV-000042
+	Number string `json:"number,omitempty"`
+
+	// draft, issued, paid or void
+	// example: issued
+	Status   string `json:"status"`
+	Currency string `json:"currency"`
+
+	SupplierName    string `json:"supplier_name"`
+	SupplierGSTIN   string `json:"supplier_gstin,omitempty"`
+	SupplierAddress string `json:"supplier_address,omitempty"`
+	SupplierState   string `json:"supplier_state"`
+	PlaceOfSupply   string `json:"place_of_supply"`
+
+	CustomerName    string `json:"customer_name"`
+	CustomerEmail   string `json:"customer_email,omitempty"`
+	CustomerGSTIN   string `json:"customer_gstin,omitempty"`
+	CustomerAddress string `json:"customer_address,omitempty"`
+
+	Notes *string `json:"notes,omitempty"`
+
+	// Omitted when listing invoices
+	Items []InvoiceItemResponse `json:"items,omitempty"`
+
+	Subtotal   float64 `json:"subtotal"`
+	CGST       float64 `json:"cgst"`
+	SGST       float64 `json:"sgst"`
+	IGST       float64 `json:"igst"`
+	Total      float64 `json:"total"`
+	TotalMinor int64   `json:"total_minor"`
+
+	DueAt     *time.Time `json:"due_at,omitempty"`
+	IssuedAt  *time.Time `json:"issued_at,omitempty"`
+	PaidAt    *time.Time `json:"paid_at,omitempty"`
+	VoidedAt  *time.Time `json:"voided_at,omitempty"`
+	PaymentID *int64     `json:"payment_id,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,102 @@
+package models
+
+import "time"
+
+// CurrencyLimit is the amount limits for one currency; null means no limit
+// @swagger:model CurrencyLimit
+type CurrencyLimit struct {
+	// example: INR
+	Currency string `json:"currency"`
+
+	// Largest single payment
+	// example: 100000
+	PerTransaction *float64 `json:"per_transaction"`
+
+	// Total per calendar day (UTC)
+	// example: 200000
+	Daily *float64 `json:"daily"`
+
+	// Total per calendar month (UTC)
+	// example: 1000000
+	Monthly *float64 `json:"monthly"`
+}
+
+// LimitSet is a set of transaction limits. For the defaults, a null
+// hourly_count means no limit and an unlisted currency has no limits. For
+// a user's overrides, a null hourly_count and unlisted currencies keep the
+// defaults, while a listed currency replaces its default entirely.
+// @swagger:model LimitSet
+type LimitSet struct {
+	// Payments in any rolling hour, across currencies
+	// example: 30
+	HourlyCount *int `json:"hourly_count"`
+
+	Currencies []CurrencyLimit `json:"currencies"`
+
+	// Admin who last changed the set
+	UpdatedBy *int       `json:"updated_by,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// UserLimitsResponse shows a user's overrides and the limits they get
+// @swagger:model UserLimitsResponse
+type UserLimitsResponse struct {
+	// example: 42
+	UserID    int      `json:"user_id"`
+	Overrides LimitSet `json:"overrides"`
+	Effective LimitSet `json:"effective"`
+}
+
+// LimitUsage is what the caller has sent in one currency so far
+// @swagger:model LimitUsage
+type LimitUsage struct {
+	// example: INR
+	Currency string `json:"currency"`
+
+	// example: 1500
+	Today float64 `json:"today"`
+
+	// example: 42000
+	ThisMonth float64 `json:"this_month"`
+}
+
+// MyLimitsResponse shows the caller's limits and usage
+// @swagger:model MyLimitsResponse
+type MyLimitsResponse struct {
+	Limits LimitSet `json:"limits"`
+
+	// Payments in the last hour
+	// example: 3
+	PaymentsThisHour int `json:"payments_this_hour"`
+
+	// Usage of every currency that has limits
+	Usage []LimitUsage `json:"usage"`
+}
+
+// LimitViolation says which limit a payment would exceed
+// @swagger:model LimitViolation
+type LimitViolation struct {
+	// per_transaction, daily, monthly or hourly_count
+	// example: daily
+	Limit string `json:"limit"`
+
+	// Empty for hourly_count
+	// example: INR
+	Currency string `json:"currency,omitempty"`
+
+	// The limit: an amount, or a number of payments for hourly_count
+	// example: 200000
+	Max float64 `json:"max"`
+
+	// Already used in the window
+	// example: 195000
+	Used float64 `json:"used"`
+
+	// What the payment would add
+	// example: 10000
+	Attempted float64 `json:"attempted"`
+
+	// When the payment would fit again; absent for per_transaction
+	// example: 2026-10-19T00:00:00Z
+	ResetsAt *time.Time `json:"resets_at,omitempty"`
+}
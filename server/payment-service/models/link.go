@@ -0,0 +1,137 @@
+package models
+
+import "time"
+
+// PaymentLinkRequest creates a payment link. Set amount for a fixed amount,
+// or min_amount and max_amount to let the payer choose.
+// @swagger:model PaymentLinkRequest
+type PaymentLinkRequest struct {
+	// example: 1200
+	Amount *float64 `json:"amount,omitempty"`
+
+	// example: 100
+	MinAmount *float64 `json:"min_amount,omitempty"`
+
+	// example: 5000
+	MaxAmount *float64 `json:"max_amount,omitempty"`
+
+	// default: "INR"
+	// example: INR
+	Currency string `json:"currency,omitempty"`
+
+	// Where the money goes
+	// required: true
+	// example: acc_987654321
+	ToAccount string `json:"to_account"`
+
+	// Shown to payers
+	// example: Dinner on Friday
+	Description *string `json:"description,omitempty"`
+
+	// The link stops accepting payments after this time
+	// example: 2026-11-30T23:59:59Z
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// Captured payments after which the link is paid; omit for no limit
+	// example: 1
+	MaxUses *int `json:"max_uses,omitempty"`
+}
+
+// PaymentLinkResponse describes a payment link to its owner
+// @swagger:model PaymentLinkResponse
+type PaymentLinkResponse struct {
+	ID int64 `json:"id"`
+
+	// example: 7mKq2xRb9T
+	Code string `json:"code"`
+
+	// Shareable URL
+	// example: http://localhost:8080/l/7mKq2xRb9T
+	URL string `json:"url"`
+
+	Amount      *float64   `json:"amount,omitempty"`
+	MinAmount   *float64   `json:"min_amount,omitempty"`
+	MaxAmount   *float64   `json:"max_amount,omitempty"`
+	Currency    string     `json:"currency"`
+	ToAccount   string     `json:"to_account"`
+	Description *string    `json:"description,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	MaxUses     *int       `json:"max_uses,omitempty"`
+
+	// Captured payments
+	Uses int `json:"uses"`
+
+	// active, paid, expired or cancelled
+	// example: active
+	Status      string     `json:"status"`
+	PaidAt      *time.Time `json:"paid_at,omitempty"`
+	CancelledAt *time.Time `json:"cancelled_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// PublicPaymentLinkResponse is what anyone with a link's code may see
+// @swagger:model PublicPaymentLinkResponse
+type PublicPaymentLinkResponse struct {
+	// example: 7mKq2xRb9T
+	Code        string     `json:"code"`
+	Amount      *float64   `json:"amount,omitempty"`
+	MinAmount   *float64   `json:"min_amount,omitempty"`
+	MaxAmount   *float64   `json:"max_amount,omitempty"`
+	Currency    string     `json:"currency"`
+	Description *string    `json:"description,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+
+	// active, paid, expired or cancelled; only active links can be paid
+	// example: active
+	Status string `json:"status"`
+}
+
+// PaymentLinkPayRequest starts a payment through a link
+// @swagger:model PaymentLinkPayRequest
+type PaymentLinkPayRequest struct {
+	// Required when the link lets the payer choose the amount
+	// example: 750
+	Amount *float64 `json:"amount,omitempty"`
+
+	// example: Asha Rao
+	Name *string `json:"name,omitempty"`
+
+	// example: asha@example.com
+	Email *string `json:"email,omitempty"`
+
+	// example: +919876543210
+	Contact *string `json:"contact,omitempty"`
+}
+
+// PaymentLinkCheckoutResponse carries what Razorpay Checkout needs to
+// collect a link payment
+// @swagger:model PaymentLinkCheckoutResponse
+type PaymentLinkCheckoutResponse struct {
+	// example: order_IluGWxBm9U8zJ8
+	RazorpayOrderID string `json:"razorpay_order_id"`
+
+	// Publishable Razorpay key for Checkout
+	// example: rzp_test_1DP5mmOlF5G5ag
+	RazorpayKeyID string     `json:"razorpay_key_id"`
+	Amount        float64    `json:"amount"`
+	Currency      string     `json:"currency"`
+	Description   *string    `json:"description,omitempty"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+}
+
+// PaymentLinkPaymentResponse describes one order created through a link
+// @swagger:model PaymentLinkPaymentResponse
+type PaymentLinkPaymentResponse struct {
+	PaymentID         int     `json:"payment_id"`
+	Amount            float64 `json:"amount"`
+	Currency          string  `json:"currency"`
+	Status            string  `json:"status"`
+	RazorpayOrderID   string  `json:"razorpay_order_id"`
+	RazorpayPaymentID *string `json:"razorpay_payment_id,omitempty"`
+	PayerName         *string `json:"payer_name,omitempty"`
+	PayerEmail        *string `json:"payer_email,omitempty"`
+	PayerContact      *string `json:"payer_contact,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -1,9 +1,15 @@
 package models
 
 import (
+	"log/slog"
+	"strings"
 	"time"
 )
 
+// MaxAccountLength is the longest from_account or to_account the payments
+// table stores.
+const MaxAccountLength = 128
+
 // PaymentRequest represents the incoming payment request payload
 // @swagger:model PaymentRequest
 type PaymentRequest struct {
@@ -14,18 +20,59 @@ type PaymentRequest struct {
 
 	// Source account ID
 	// required: true
+	// maxLength: 128
 	// example: acc_123456789
 	FromAccount string `json:"from_account" validate:"required"`
 
-	// Destination account ID
-	// required: true
+	// Destination account ID; omit when beneficiary_id is set
+	// maxLength: 128
 	// example: acc_987654321
-	ToAccount string `json:"to_account" validate:"required"`
+	ToAccount string `json:"to_account,omitempty"`
+
+	// Saved payee to pay instead of to_account
+	// example: 7
+	BeneficiaryID *int64 `json:"beneficiary_id,omitempty"`
 
 	// Currency code (ISO 4217)
 	// default: "INR"
 	// example: INR
 	Currency string `json:"currency,omitempty" validate:"omitempty,len=3"`
+
+	// How long the order stays payable (Go duration); defaults to the
+	// currency's configured TTL
+	// example: 30m
+	ExpiresIn string `json:"expires_in,omitempty"`
+
+	// auto captures on payment; manual stops at authorized until
+	// POST /payments/{id}/capture
+	// default: "auto"
+	// example: manual
+	CaptureMode string `json:"capture_mode,omitempty"`
+
+	// FX quote to pay with: amount and currency default to the quote's and
+	// must match it if given, and the payee is credited the converted amount
+	// example: 12
+	QuoteID *int64 `json:"quote_id,omitempty"`
+}
+
+// LogValue masks the account identifiers when a request is logged.
+func (p PaymentRequest) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Float64("amount", p.Amount),
+		slog.String("currency", p.Currency),
+		slog.String("from_account", MaskAccount(p.FromAccount)),
+		slog.String("to_account", MaskAccount(p.ToAccount)),
+		slog.Any("beneficiary_id", p.BeneficiaryID),
+		slog.Any("quote_id", p.QuoteID),
+	)
+}
+
+// MaskAccount masks all but the last four characters of an account number.
+func MaskAccount(s string) string {
+	if len(s) <= 4 {
+		return strings.Repeat("*", len(s))
+	}
+	return strings.Repeat("*", len(s)-4) + s[len(s)-4:]
 }
 
 // PaymentResponse represents the API response for a successful payment
@@ -35,7 +82,7 @@ type PaymentResponse struct {
 	// example: 42
 	ID int `json:"id"`
 
-	// Razorpay order ID
+	// Razorpay order ID; absent while the payment is held for review
 	// example: order_123456789
 	RazorpayOrderID string `json:"razorpay_order_id,omitempty"`
 
@@ -58,21 +105,125 @@ type PaymentResponse struct {
 	// Timestamp of last update
 	// example: 2023-05-15T14:30:45Z
 	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+
+	// The order can no longer be paid after this time
+	// example: 2023-05-15T15:00:45Z
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// auto or manual
+	// example: auto
+	CaptureMode string `json:"capture_mode,omitempty"`
+
+	// outgoing, or incoming for a payment received through a payment link
+	// example: outgoing
+	Direction string `json:"direction,omitempty"`
+
+	// Amount captured when less than the full amount was captured
+	// example: 80.00
+	CapturedAmount *float64 `json:"captured_amount,omitempty"`
+
+	// Amount returned to the payer after a lost dispute
+	// example: 100.50
+	ChargedBackAmount *float64 `json:"charged_back_amount,omitempty"`
+
+	// Saved payee the payment was made to
+	// example: 7
+	BeneficiaryID *int64 `json:"beneficiary_id,omitempty"`
+
+	// A voided authorization whose funds Razorpay has not yet returned to
+	// the payer; it does so at the end of its auto-refund window
+	// example: true
+	ReleasePending bool `json:"release_pending,omitempty"`
+
+	// When Razorpay returned a voided authorization's funds
+	// example: 2023-05-20T14:30:45Z
+	AuthorizationReleasedAt *time.Time `json:"authorization_released_at,omitempty"`
+
+	// The FX quote a cross-currency payment used
+	Conversion *ConversionResponse `json:"conversion,omitempty"`
+}
+
+// CaptureRequest captures an authorized payment
+// @swagger:model CaptureRequest
+type CaptureRequest struct {
+	// Amount to capture; omit for the full amount. Razorpay refunds the
+	// uncaptured remainder.
+	// example: 80.00
+	Amount *float64 `json:"amount,omitempty"`
+}
+
+// VerifyPaymentRequest carries the fields Razorpay Checkout returns to the
+// client after a successful payment
+// @swagger:model VerifyPaymentRequest
+type VerifyPaymentRequest struct {
+	// required: true
+	// example: order_123456789
+	RazorpayOrderID string `json:"razorpay_order_id"`
+
+	// required: true
+	// example: pay_29QQoUBi66xm2f
+	RazorpayPaymentID string `json:"razorpay_payment_id"`
+
+	// HMAC-SHA256 of "<order_id>|<payment_id>" with the key secret
+	// required: true
+	RazorpaySignature string `json:"razorpay_signature"`
 }
 
 // Payment represents the payment record in database
 type Payment struct {
-	ID              int        `json:"id" db:"id"`
-	UserID          int        `json:"user_id" db:"user_id"`
-	Amount          float64    `json:"amount" db:"amount"`
-	Currency        string     `json:"currency" db:"currency"`
-	FromAccount     string     `json:"from_account" db:"from_account"`
-	ToAccount       string     `json:"to_account" db:"to_account"`
-	RazorpayOrderID string     `json:"razorpay_order_id" db:"razorpay_order_id"`
-	Status          string     `json:"status" db:"status"`
-	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
-	UpdatedAt       *time.Time `json:"updated_at,omitempty" db:"updated_at"`
-	Description     *string    `json:"description,omitempty" db:"description"`
+	ID                int        `json:"id" db:"id"`
+	UserID            int        `json:"user_id" db:"user_id"`
+	Amount            float64    `json:"amount" db:"amount"`
+	Currency          string     `json:"currency" db:"currency"`
+	FromAccount       string     `json:"from_account" db:"from_account"`
+	ToAccount         string     `json:"to_account" db:"to_account"`
+	RazorpayOrderID   string     `json:"razorpay_order_id" db:"razorpay_order_id"`
+	RazorpayPaymentID *string    `json:"razorpay_payment_id,omitempty" db:"razorpay_payment_id"`
+	Status            string     `json:"status" db:"status"`
+	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt         *time.Time `json:"updated_at,omitempty" db:"updated_at"`
+	Description       *string    `json:"description,omitempty" db:"description"`
+	ExpiresAt         *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	CaptureMode       string     `json:"capture_mode" db:"capture_mode"`
+	AuthorizedAt      *time.Time `json:"authorized_at,omitempty" db:"authorized_at"`
+	// CapturedAmount is set by partial captures; nil means the full amount.
+	CapturedAmount *float64 `json:"captured_amount,omitempty" db:"captured_amount"`
+	BeneficiaryID  *int64   `json:"beneficiary_id,omitempty" db:"beneficiary_id"`
+	// CapturedAt and RefundedAt record when money moved, for statements.
+	CapturedAt *time.Time `json:"captured_at,omitempty" db:"captured_at"`
+	RefundedAt *time.Time `json:"refunded_at,omitempty" db:"refunded_at"`
+	// Set when a lost dispute returned ChargedBackAmount to the payer.
+	ChargedBackAt     *time.Time `json:"charged_back_at,omitempty" db:"charged_back_at"`
+	ChargedBackAmount *float64   `json:"charged_back_amount,omitempty" db:"charged_back_amount"`
+	// Set when Razorpay reports it returned a voided authorization's held
+	// funds; voiding itself is local.
+	AuthorizationReleasedAt *time.Time `json:"authorization_released_at,omitempty" db:"authorization_released_at"`
+	// Direction is incoming for a payment made to UserID through one of
+	// their payment links.
+	Direction string `json:"direction" db:"direction"`
+}
+
+// Incoming reports whether p is money its user received rather than sent.
+func (p Payment) Incoming() bool {
+	return Direction(p.Direction) == DirectionIncoming
+}
+
+// Expired reports whether p is unpaid and past its expiry at now, whether or
+// not the sweeper has marked it yet.
+func (p Payment) Expired(now time.Time) bool {
+	switch PaymentStatus(p.Status) {
+	case PaymentStatusExpired:
+		return true
+	case PaymentStatusCreated:
+		return p.ExpiresAt != nil && !now.Before(*p.ExpiresAt)
+	}
+	return false
+}
+
+// ReleasePending reports whether p is a voided authorization whose funds
+// Razorpay has not yet returned to the payer.
+func (p Payment) ReleasePending() bool {
+	return PaymentStatus(p.Status) == PaymentStatusVoided && p.AuthorizationReleasedAt == nil
 }
 
 // ErrorResponse represents standard API error response
@@ -88,6 +239,13 @@ type ErrorResponse struct {
 
 	// Optional field-specific errors
 	Errors map[string]string `json:"errors,omitempty"`
+
+	// The transaction limit a refused payment would exceed
+	Limit *LimitViolation `json:"limit,omitempty"`
+
+	// ID of the request that failed, for correlating with service logs
+	// example: 3f2b8c1de4a94f0c9b7a6e5d4c3b2a19
+	RequestID string `json:"request_id,omitempty"`
 }
 
 // PaymentStatus represents possible payment states
@@ -96,7 +254,33 @@ type PaymentStatus string
 const (
 	PaymentStatusCreated   PaymentStatus = "created"
 	PaymentStatusPending   PaymentStatus = "pending"
+	PaymentStatusCaptured  PaymentStatus = "captured"
 	PaymentStatusCompleted PaymentStatus = "completed"
 	PaymentStatusFailed    PaymentStatus = "failed"
 	PaymentStatusRefunded  PaymentStatus = "refunded"
+	PaymentStatusExpired   PaymentStatus = "expired"
+	// Manual capture: authorized payments wait for capture or void
+	PaymentStatusAuthorized PaymentStatus = "authorized"
+	PaymentStatusVoided     PaymentStatus = "voided"
+	// Held by risk screening until an admin approves or rejects it
+	PaymentStatusPendingReview PaymentStatus = "pending_review"
+	// The payer won a dispute and the money was returned to them
+	PaymentStatusChargedBack PaymentStatus = "charged_back"
+)
+
+// CaptureMode is how a payment's authorization is captured.
+type CaptureMode string
+
+const (
+	CaptureAuto   CaptureMode = "auto"
+	CaptureManual CaptureMode = "manual"
+)
+
+// Direction is which way a payment moved money for its user.
+type Direction string
+
+const (
+	DirectionOutgoing Direction = "outgoing"
+	// Paid to the user through one of their payment links
+	DirectionIncoming Direction = "incoming"
 )
@@ -0,0 +1,83 @@
+package models
+
+import "time"
+
+// ReconciliationRun summarises one reconciliation pass against Razorpay
+// @swagger:model ReconciliationRun
+type ReconciliationRun struct {
+	ID int64 `json:"id"`
+
+	// scheduled, manual or cli
+	// example: scheduled
+	Trigger string `json:"trigger"`
+
+	// running, completed or failed
+	// example: completed
+	Status string `json:"status"`
+
+	// Payments created in [window_start, window_end) were checked
+	WindowStart time.Time `json:"window_start"`
+	WindowEnd   time.Time `json:"window_end"`
+
+	// Non-terminal payments compared with their Razorpay order
+	// example: 120
+	Scanned int `json:"scanned"`
+
+	// Payments moved to the status Razorpay reports
+	// example: 3
+	Corrected int `json:"corrected"`
+
+	// Findings that need a human (local_only, gateway_only, amount_mismatch)
+	// example: 1
+	Discrepancies int `json:"discrepancies"`
+
+	// Payments Razorpay could not be asked about; they are retried next run
+	// example: 0
+	Errors int `json:"errors"`
+
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// ReconciliationDiscrepancy is one finding of a run. Amounts are in major
+// currency units.
+// @swagger:model ReconciliationDiscrepancy
+type ReconciliationDiscrepancy struct {
+	// local_only, gateway_only, amount_mismatch or status_corrected
+	// example: status_corrected
+	Kind string `json:"kind"`
+
+	PaymentID       *int64 `json:"payment_id,omitempty"`
+	RazorpayOrderID string `json:"razorpay_order_id,omitempty"`
+
+	// example: created
+	LocalStatus string `json:"local_status,omitempty"`
+
+	// example: captured
+	GatewayStatus string   `json:"gateway_status,omitempty"`
+	LocalAmount   *float64 `json:"local_amount,omitempty"`
+	GatewayAmount *float64 `json:"gateway_amount,omitempty"`
+	Currency      string   `json:"currency,omitempty"`
+
+	// example: moved from created to captured (razorpay payment pay_29QQoUBi66xm2f)
+	Details   string    `json:"details,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ReconciliationReport is a run with its findings
+// @swagger:model ReconciliationReport
+type ReconciliationReport struct {
+	ReconciliationRun
+	Items []ReconciliationDiscrepancy `json:"items"`
+}
+
+// ReconciliationTriggerResponse identifies a run started on demand
+// @swagger:model ReconciliationTriggerResponse
+type ReconciliationTriggerResponse struct {
+	// example: 17
+	RunID int64 `json:"run_id"`
+
+	// example: running
+	Status string `json:"status"`
+}
@@ -0,0 +1,83 @@
+package models
+
+import "time"
+
+// RiskHit is a screening rule that fired
+// @swagger:model RiskHit
+type RiskHit struct {
+	// example: new_account_high_value
+	Rule string `json:"rule"`
+
+	// example: 50
+	Score int `json:"score"`
+
+	// example: account registered 26h0m0s ago sending 60000.00 INR; high value from 50000.00
+	Detail string `json:"detail"`
+}
+
+// RiskDecisionRequest approves or rejects a held payment
+// @swagger:model RiskDecisionRequest
+type RiskDecisionRequest struct {
+	// Why; required to reject
+	// example: Confirmed with the customer by phone
+	Reason string `json:"reason"`
+}
+
+// RiskDecisionResponse is an admin's verdict on a held payment
+// @swagger:model RiskDecisionResponse
+type RiskDecisionResponse struct {
+	// approve or reject
+	// example: approve
+	Decision  string    `json:"decision"`
+	Reason    string    `json:"reason,omitempty"`
+	DecidedBy int       `json:"decided_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RiskAssessmentResponse is the screening of a payment
+// @swagger:model RiskAssessmentResponse
+type RiskAssessmentResponse struct {
+	// example: 31
+	ID int64 `json:"id"`
+
+	// Absent for a blocked attempt
+	// example: 42
+	PaymentID *int64 `json:"payment_id,omitempty"`
+
+	UserID int `json:"user_id"`
+
+	// example: 60000
+	Amount float64 `json:"amount"`
+
+	// example: INR
+	Currency  string `json:"currency"`
+	ToAccount string `json:"to_account"`
+
+	// example: 203.0.113.7
+	IP string `json:"ip,omitempty"`
+
+	// Country of the IP, if it is in a known range
+	// example: IN
+	Country string `json:"country,omitempty"`
+
+	// Sum of the scores of the rules that fired
+	// example: 50
+	Score int `json:"score"`
+
+	// allow, review or block
+	// example: review
+	Outcome   string    `json:"outcome"`
+	Rules     []RiskHit `json:"rules"`
+	RequestID string    `json:"request_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// The admin's verdict on a held payment, once made
+	Decision *RiskDecisionResponse `json:"decision,omitempty"`
+}
+
+// RiskReviewResponse is a payment held by risk screening
+// @swagger:model RiskReviewResponse
+type RiskReviewResponse struct {
+	Payment    PaymentResponse        `json:"payment"`
+	Assessment RiskAssessmentResponse `json:"assessment"`
+}
@@ -0,0 +1,133 @@
+package models
+
+import "time"
+
+// ScheduleRequest schedules a payment for later, once or repeatedly. The
+// payment fields are validated as for /pay.
+// @swagger:model ScheduleRequest
+type ScheduleRequest struct {
+	// required: true
+	// example: 15000
+	Amount float64 `json:"amount"`
+
+	// required: true
+	// example: acc_123456789
+	FromAccount string `json:"from_account"`
+
+	// Omit when beneficiary_id is set
+	// example: acc_987654321
+	ToAccount string `json:"to_account,omitempty"`
+
+	// example: 7
+	BeneficiaryID *int64 `json:"beneficiary_id,omitempty"`
+
+	// default: "INR"
+	// example: INR
+	Currency string `json:"currency,omitempty"`
+
+	// How long each occurrence's order stays payable (Go duration)
+	// example: 2h
+	ExpiresIn string `json:"expires_in,omitempty"`
+
+	// default: "auto"
+	// example: auto
+	CaptureMode string `json:"capture_mode,omitempty"`
+
+	// example: Rent
+	Description *string `json:"description,omitempty"`
+
+	// once, daily, weekly or monthly
+	// required: true
+	// example: monthly
+	Frequency string `json:"frequency"`
+
+	// First occurrence
+	// required: true
+	// example: 2026-11-01T09:00:00Z
+	StartAt time.Time `json:"start_at"`
+
+	// No occurrence is scheduled after this time
+	// example: 2027-10-31T23:59:59Z
+	EndAt *time.Time `json:"end_at,omitempty"`
+
+	// Stop after this many occurrences, skipped ones included
+	// example: 12
+	MaxOccurrences *int `json:"max_occurrences,omitempty"`
+}
+
+// UpdateScheduleRequest changes an active or paused schedule; omitted
+// fields are left as they are.
+// @swagger:model UpdateScheduleRequest
+type UpdateScheduleRequest struct {
+	// example: 16000
+	Amount *float64 `json:"amount,omitempty"`
+
+	// example: Rent incl. maintenance
+	Description *string `json:"description,omitempty"`
+
+	// example: 2027-03-31T23:59:59Z
+	EndAt *time.Time `json:"end_at,omitempty"`
+
+	// example: 6
+	MaxOccurrences *int `json:"max_occurrences,omitempty"`
+}
+
+// ScheduleResponse describes a scheduled payment.
+// @swagger:model ScheduleResponse
+type ScheduleResponse struct {
+	ID            int64   `json:"id"`
+	Amount        float64 `json:"amount"`
+	Currency      string  `json:"currency"`
+	FromAccount   string  `json:"from_account"`
+	ToAccount     string  `json:"to_account,omitempty"`
+	BeneficiaryID *int64  `json:"beneficiary_id,omitempty"`
+	CaptureMode   string  `json:"capture_mode"`
+	// example: 2h0m0s
+	ExpiresIn   string  `json:"expires_in,omitempty"`
+	Description *string `json:"description,omitempty"`
+
+	// example: monthly
+	Frequency      string     `json:"frequency"`
+	StartAt        time.Time  `json:"start_at"`
+	EndAt          *time.Time `json:"end_at,omitempty"`
+	MaxOccurrences *int       `json:"max_occurrences,omitempty"`
+
+	// Occurrences scheduled or skipped so far
+	// example: 3
+	Occurrences int `json:"occurrences"`
+
+	// When the next occurrence is due; absent once the schedule completes
+	// example: 2027-02-01T09:00:00Z
+	NextRunAt *time.Time `json:"next_run_at,omitempty"`
+
+	// active, paused, completed or cancelled
+	// example: active
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ScheduleExecutionResponse describes one occurrence of a schedule.
+// @swagger:model ScheduleExecutionResponse
+type ScheduleExecutionResponse struct {
+	ID int64 `json:"id"`
+
+	// 1 for the first occurrence
+	// example: 3
+	Occurrence   int       `json:"occurrence"`
+	ScheduledFor time.Time `json:"scheduled_for"`
+
+	// pending, succeeded, failed or skipped
+	// example: succeeded
+	Status   string `json:"status"`
+	Attempts int    `json:"attempts"`
+
+	// When a pending occurrence is next attempted
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	LastError     *string    `json:"last_error,omitempty"`
+
+	// Payment created for the occurrence
+	// example: 42
+	PaymentID *int64    `json:"payment_id,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
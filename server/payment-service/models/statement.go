@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// StatementLine is one line of a JSON Lines statement. Each currency is a
+// section: an opening_balance line, its payment, refund and chargeback
+// lines in posting order, then a closing_balance line. Balance lines carry
+// only type, posted_at, currency and balance.
+// @swagger:model StatementLine
+type StatementLine struct {
+	// opening_balance, payment, received, refund, chargeback or
+	// closing_balance
+	// example: payment
+	Type string `json:"type"`
+
+	// When the money moved; the period start or end for balance lines
+	// example: 2026-10-05T09:30:00Z
+	PostedAt time.Time `json:"posted_at"`
+
+	// Unique per user: <type>-<payment_id>
+	// example: payment-42
+	EntryID string `json:"entry_id,omitempty"`
+
+	// example: 42
+	PaymentID int `json:"payment_id,omitempty"`
+
+	// example: INR
+	Currency string `json:"currency"`
+
+	// Negative for payments sent, positive for payments received; refunds
+	// and chargebacks have the opposite sign of their payment
+	// example: -1500.50
+	Amount      *float64 `json:"amount,omitempty"`
+	AmountMinor *int64   `json:"amount_minor,omitempty"`
+
+	// Running net of the user's entries in this currency after the line
+	// example: -4200.00
+	Balance      float64 `json:"balance"`
+	BalanceMinor int64   `json:"balance_minor"`
+
+	// Current payment status
+	// example: captured
+	Status      string `json:"status,omitempty"`
+	Description string `json:"description,omitempty"`
+
+	// Masked to the last four characters
+	// example: *********6789
+	FromAccount string `json:"from_account,omitempty"`
+	ToAccount   string `json:"to_account,omitempty"`
+
+	RazorpayOrderID   string `json:"razorpay_order_id,omitempty"`
+	RazorpayPaymentID string `json:"razorpay_payment_id,omitempty"`
+}
@@ -0,0 +1,135 @@
+package models
+
+import "time"
+
+// PlanRequest creates a recurring plan
+// @swagger:model PlanRequest
+type PlanRequest struct {
+	// required: true
+	// example: Pro monthly
+	Name string `json:"name"`
+
+	// example: Everything in Pro, billed monthly
+	Description *string `json:"description,omitempty"`
+
+	// Price per billing cycle
+	// required: true
+	// example: 499
+	Amount float64 `json:"amount"`
+
+	// default: "INR"
+	// example: INR
+	Currency string `json:"currency,omitempty"`
+
+	// daily, weekly, monthly or yearly
+	// required: true
+	// example: monthly
+	Period string `json:"period"`
+
+	// Bill every interval periods; daily plans need at least 7
+	// default: 1
+	// example: 1
+	Interval int `json:"interval,omitempty"`
+
+	// Days before the first charge
+	// example: 14
+	TrialDays int `json:"trial_days,omitempty"`
+}
+
+// PlanUpdateRequest opens or closes a plan to new subscriptions
+// @swagger:model PlanUpdateRequest
+type PlanUpdateRequest struct {
+	// required: true
+	// example: false
+	Active bool `json:"active"`
+}
+
+// PlanResponse describes a plan
+// @swagger:model PlanResponse
+type PlanResponse struct {
+	ID int64 `json:"id"`
+
+	// example: plan_00000000000001
+	RazorpayPlanID string    `json:"razorpay_plan_id"`
+	Name           string    `json:"name"`
+	Description    *string   `json:"description,omitempty"`
+	Amount         float64   `json:"amount"`
+	Currency       string    `json:"currency"`
+	Period         string    `json:"period"`
+	Interval       int       `json:"interval"`
+	TrialDays      int       `json:"trial_days"`
+	Active         bool      `json:"active"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// SubscriptionRequest subscribes the caller to a plan
+// @swagger:model SubscriptionRequest
+type SubscriptionRequest struct {
+	// required: true
+	// example: 3
+	PlanID int64 `json:"plan_id"`
+
+	// Billing cycles before the subscription completes
+	// required: true
+	// example: 12
+	TotalCount int `json:"total_count"`
+
+	// default: 1
+	// example: 1
+	Quantity int `json:"quantity,omitempty"`
+}
+
+// SubscriptionResponse describes a subscription
+// @swagger:model SubscriptionResponse
+type SubscriptionResponse struct {
+	ID     int64 `json:"id"`
+	PlanID int64 `json:"plan_id"`
+
+	// example: sub_00000000000001
+	RazorpaySubscriptionID string `json:"razorpay_subscription_id"`
+
+	// created, authenticated, active, pending, halted, paused, cancelled,
+	// completed or expired
+	// example: active
+	Status     string `json:"status"`
+	Quantity   int    `json:"quantity"`
+	TotalCount int    `json:"total_count"`
+	PaidCount  int    `json:"paid_count"`
+
+	// Where the customer authorizes the recurring payment
+	// example: https://rzp.io/i/abc123
+	ShortURL string `json:"short_url,omitempty"`
+
+	TrialEndsAt  *time.Time `json:"trial_ends_at,omitempty"`
+	CurrentStart *time.Time `json:"current_start,omitempty"`
+	CurrentEnd   *time.Time `json:"current_end,omitempty"`
+
+	// Next charge
+	ChargeAt *time.Time `json:"charge_at,omitempty"`
+	EndedAt  *time.Time `json:"ended_at,omitempty"`
+
+	// Cancelled at the end of the current billing cycle
+	CancelAtPeriodEnd bool      `json:"cancel_at_period_end"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// SubscriptionInvoiceResponse describes one paid billing cycle
+// @swagger:model SubscriptionInvoiceResponse
+type SubscriptionInvoiceResponse struct {
+	ID int64 `json:"id"`
+
+	// example: 3
+	Cycle int `json:"cycle"`
+
+	// example: inv_00000000000001
+	RazorpayInvoiceID *string `json:"razorpay_invoice_id,omitempty"`
+
+	// example: pay_00000000000001
+	RazorpayPaymentID string     `json:"razorpay_payment_id"`
+	Amount            float64    `json:"amount"`
+	Currency          string     `json:"currency"`
+	PeriodStart       *time.Time `json:"period_start,omitempty"`
+	PeriodEnd         *time.Time `json:"period_end,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// WebhookEndpointRequest registers or updates a webhook endpoint
+// @swagger:model WebhookEndpointRequest
+type WebhookEndpointRequest struct {
+	// HTTPS URL that receives POSTed events
+	// example: https://merchant.example.com/gopay/webhooks
+	URL *string `json:"url,omitempty"`
+
+	// Free-form label
+	// example: Order fulfilment
+	Description *string `json:"description,omitempty"`
+
+	// Event types to receive; empty or omitted means all
+	// example: ["payment.captured","payment.refunded"]
+	EventTypes *[]string `json:"event_types,omitempty"`
+
+	// Set false to pause deliveries (update only)
+	Active *bool `json:"active,omitempty"`
+}
+
+// WebhookEndpointResponse describes a webhook endpoint. Secret is only
+// returned when the endpoint is created or its secret rotated.
+// @swagger:model WebhookEndpointResponse
+type WebhookEndpointResponse struct {
+	ID          int64     `json:"id"`
+	URL         string    `json:"url"`
+	Description string    `json:"description,omitempty"`
+	EventTypes  []string  `json:"event_types"`
+	Active      bool      `json:"active"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	// Signing secret for X-GoPay-Signature
+	// example: whsec_3f2b8c1de4a94f0c9b7a6e5d4c3b2a19...
+	Secret string `json:"secret,omitempty"`
+
+	// Until this time deliveries are also signed with the previous secret
+	PreviousSecretExpiresAt *time.Time `json:"previous_secret_expires_at,omitempty"`
+}
+
+// RotateSecretRequest rotates an endpoint's signing secret
+// @swagger:model RotateSecretRequest
+type RotateSecretRequest struct {
+	// How long the old secret keeps signing deliveries (Go duration)
+	// example: 24h
+	GracePeriod string `json:"grace_period,omitempty"`
+}
+
+// WebhookDeliveryResponse describes one event delivered to an endpoint
+// @swagger:model WebhookDeliveryResponse
+type WebhookDeliveryResponse struct {
+	ID         int64  `json:"id"`
+	EndpointID int64  `json:"endpoint_id"`
+	EventID    string `json:"event_id"`
+	EventType  string `json:"event_type"`
+
+	// pending, succeeded or failed (retries exhausted)
+	// example: succeeded
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	LastAttemptAt *time.Time `json:"last_attempt_at,omitempty"`
+
+	// HTTP status of the last attempt
+	// example: 200
+	ResponseCode *int `json:"response_code,omitempty"`
+
+	// Duration of the last attempt in milliseconds
+	// example: 84
+	LatencyMS *int      `json:"latency_ms,omitempty"`
+	LastError *string   `json:"last_error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// Exact JSON body sent to the endpoint
+	Payload json.RawMessage `json:"payload,omitempty" swaggertype:"object"`
+
+	// Every attempt, oldest first (single delivery only)
+	AttemptLog []WebhookAttempt `json:"attempt_log,omitempty"`
+}
+
+// WebhookAttempt is one HTTP request made for a delivery
+// @swagger:model WebhookAttempt
+type WebhookAttempt struct {
+	Attempt      int       `json:"attempt"`
+	ResponseCode *int      `json:"response_code,omitempty"`
+	ResponseBody string    `json:"response_body,omitempty"`
+	LatencyMS    int       `json:"latency_ms"`
+	Error        string    `json:"error,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
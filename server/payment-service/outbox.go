@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/internal/config"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/outbox"
+)
+
+// newOutboxPublisher builds the publisher selected by OUTBOX_PUBLISHER. It
+// returns nil for none, leaving merchant webhooks as the only consumer. The
+// returned close function releases the publisher's connections.
+func newOutboxPublisher(cfg *config.Config) (outbox.Publisher, func() error, error) {
+	noop := func() error { return nil }
+
+	switch cfg.OutboxPublisher {
+	case "stdout":
+		return outbox.NewStdoutPublisher(os.Stdout), noop, nil
+	case "webhook":
+		return outbox.NewWebhookPublisher(cfg.OutboxWebhookURL, cfg.OutboxWebhookSecret), noop, nil
+	case "nats":
+		p, err := outbox.NewNATSPublisher(cfg.NATSURL, cfg.NATSSubjectPrefix)
+		if err != nil {
+			return nil, nil, err
+		}
+		return p, p.Close, nil
+	case "none":
+		return nil, noop, nil
+	}
+	return nil, nil, fmt.Errorf("unknown outbox publisher %q", cfg.OutboxPublisher)
+}
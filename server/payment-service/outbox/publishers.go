@@ -0,0 +1,112 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/events"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// StdoutPublisher writes each event as a JSON line. It is meant for local
+// development.
+type StdoutPublisher struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewStdoutPublisher publishes to w.
+func NewStdoutPublisher(w io.Writer) *StdoutPublisher {
+	return &StdoutPublisher{w: w}
+}
+
+func (p *StdoutPublisher) Publish(ctx context.Context, e events.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return json.NewEncoder(p.w).Encode(e)
+}
+
+// WebhookPublisher POSTs each event as JSON to a fixed URL. Any non-2xx
+// response is a failure and is retried.
+type WebhookPublisher struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+// NewWebhookPublisher publishes to url. If secret is set, every request
+// carries X-GoPay-Signature: sha256=<hex HMAC-SHA256 of timestamp.body>.
+// Requests are bounded by the relay's per-event publish timeout.
+func NewWebhookPublisher(url, secret string) *WebhookPublisher {
+	return &WebhookPublisher{
+		url:    url,
+		secret: secret,
+		client: &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)},
+	}
+}
+
+func (p *WebhookPublisher) Publish(ctx context.Context, e events.Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GoPay-Event-ID", e.EventID)
+	req.Header.Set("X-GoPay-Event-Type", string(e.Type))
+	req.Header.Set("X-GoPay-Timestamp", timestamp)
+	if p.secret != "" {
+		req.Header.Set("X-GoPay-Signature", "sha256="+Sign(p.secret, timestamp, body))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded %s", resp.Status)
+	}
+	return nil
+}
+
+// Sign returns the hex HMAC-SHA256 of "timestamp.body" under secret.
+// Including the timestamp lets receivers reject replayed deliveries.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// MultiPublisher publishes every event to each of its publishers in turn.
+// If any of them fails the whole event is retried, so each publisher must
+// tolerate redeliveries.
+type MultiPublisher []Publisher
+
+func (m MultiPublisher) Publish(ctx context.Context, e events.Event) error {
+	for _, p := range m {
+		if err := p.Publish(ctx, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
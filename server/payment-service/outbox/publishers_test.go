@@ -0,0 +1,88 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/events"
+)
+
+func TestWebhookPublisher(t *testing.T) {
+	tests := []struct {
+		name    string
+		secret  string
+		status  int
+		wantErr bool
+	}{
+		{"signed", "whsec", http.StatusNoContent, false},
+		{"unsigned", "", http.StatusOK, false},
+		{"rejected", "whsec", http.StatusServiceUnavailable, true},
+		{"not modified", "whsec", http.StatusNotModified, true},
+	}
+	e := events.Event{
+		EventID:       "evt_1",
+		Type:          events.PaymentCaptured,
+		AggregateType: events.AggregatePayment,
+		AggregateID:   "42",
+		Data:          json.RawMessage(`{"id":42}`),
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *http.Request
+			var body []byte
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r
+				body, _ = io.ReadAll(r.Body)
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			err := NewWebhookPublisher(srv.URL, tt.secret).Publish(context.Background(), e)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if got.Header.Get("X-GoPay-Event-ID") != "evt_1" || got.Header.Get("X-GoPay-Event-Type") != "payment.captured" {
+				t.Errorf("headers %v", got.Header)
+			}
+			var sent events.Event
+			if err := json.Unmarshal(body, &sent); err != nil || sent.EventID != "evt_1" || string(sent.Data) != `{"id":42}` {
+				t.Errorf("body %s (%v)", body, err)
+			}
+
+			signature := got.Header.Get("X-GoPay-Signature")
+			if tt.secret == "" {
+				if signature != "" {
+					t.Errorf("unsigned delivery carries signature %q", signature)
+				}
+				return
+			}
+			want := "sha256=" + Sign(tt.secret, got.Header.Get("X-GoPay-Timestamp"), body)
+			if signature != want {
+				t.Errorf("signature %q, want %q", signature, want)
+			}
+			if Sign("other", got.Header.Get("X-GoPay-Timestamp"), body) == strings.TrimPrefix(signature, "sha256=") {
+				t.Error("signature does not depend on the secret")
+			}
+		})
+	}
+}
+
+func TestMultiPublisherStopsAtFailure(t *testing.T) {
+	first, last := &recorder{}, &recorder{}
+	failing := &recorder{fail: map[string]bool{"42 payment.created": true}}
+	m := MultiPublisher{first, failing, last}
+
+	err := m.Publish(context.Background(), events.Event{Type: events.PaymentCreated, AggregateID: "42"})
+	if err == nil {
+		t.Fatal("failure not returned")
+	}
+	// The whole event is retried, so later publishers wait for the retry
+	if len(first.take()) != 1 || len(last.take()) != 0 {
+		t.Error("publishers after the failing one were called")
+	}
+}
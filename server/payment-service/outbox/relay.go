@@ -0,0 +1,221 @@
+// Package outbox relays events recorded in the outbox_events table to a
+// Publisher.
+//
+// Delivery is at-least-once: an event is marked published only after the
+// publisher accepted it, so a crash in between causes a redelivery and
+// consumers must deduplicate by event ID. Events of the same aggregate are
+// published in the order they were recorded; a failing event holds back the
+// later events of its aggregate until it succeeds.
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/events"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/metrics"
+)
+
+// Publisher delivers an event to downstream consumers. A nil error means the
+// event was durably accepted.
+type Publisher interface {
+	Publish(ctx context.Context, e events.Event) error
+}
+
+// Options tunes a Relay. Zero values take the defaults noted per field.
+type Options struct {
+	PollInterval   time.Duration // 1s
+	BatchSize      int           // 100
+	PublishTimeout time.Duration // 10s per event
+	MinBackoff     time.Duration // 1s after the first failure
+	MaxBackoff     time.Duration // 10m
+	Retention      time.Duration // published events are deleted after this; 0 keeps them
+}
+
+// Relay polls the outbox and publishes pending events.
+type Relay struct {
+	db        *sql.DB
+	publisher Publisher
+	opts      Options
+}
+
+// NewRelay creates a relay publishing events from db to publisher.
+func NewRelay(db *sql.DB, publisher Publisher, opts Options) *Relay {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = time.Second
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	if opts.PublishTimeout <= 0 {
+		opts.PublishTimeout = 10 * time.Second
+	}
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = time.Second
+	}
+	if opts.MaxBackoff < opts.MinBackoff {
+		opts.MaxBackoff = 10 * time.Minute
+	}
+	return &Relay{db: db, publisher: publisher, opts: opts}
+}
+
+// Run publishes pending events until ctx is cancelled.
+func (r *Relay) Run(ctx context.Context) {
+	slog.Info("Outbox relay started", "poll_interval", r.opts.PollInterval, "batch_size", r.opts.BatchSize)
+
+	ticker := time.NewTicker(r.opts.PollInterval)
+	defer ticker.Stop()
+	lastPrune := time.Time{}
+
+	for {
+		// Keep draining while batches come back full
+		for {
+			n, err := r.PublishBatch(ctx)
+			if err != nil && ctx.Err() == nil {
+				slog.Error("Outbox relay batch failed", "error", err)
+			}
+			if err != nil || n < r.opts.BatchSize {
+				break
+			}
+		}
+
+		if r.opts.Retention > 0 && time.Since(lastPrune) > time.Hour {
+			if err := r.prune(ctx); err != nil && ctx.Err() == nil {
+				slog.Warn("Outbox prune failed", "error", err)
+			}
+			lastPrune = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			slog.Info("Outbox relay stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// pendingQuery selects due events that are the oldest unpublished event of
+// their aggregate. Rows locked by another relay replica are skipped, and the
+// NOT EXISTS keeps that replica's in-flight event from being overtaken.
+// Events of one payment are recorded while its row is locked, so id order is
+// commit order within an aggregate.
+const pendingQuery = `SELECT e.id, e.event_id, e.event_type, e.aggregate_type, e.aggregate_id,
+		e.payload, e.created_at, e.attempts
+	FROM outbox_events e
+	WHERE e.published_at IS NULL
+	  AND e.next_attempt_at <= NOW()
+	  AND NOT EXISTS (
+		SELECT 1 FROM outbox_events prev
+		WHERE prev.aggregate_type = e.aggregate_type
+		  AND prev.aggregate_id = e.aggregate_id
+		  AND prev.published_at IS NULL
+		  AND prev.id < e.id
+	  )
+	ORDER BY e.id
+	LIMIT $1
+	FOR UPDATE SKIP LOCKED`
+
+// PublishBatch publishes one batch of due events and returns how many were
+// attempted.
+func (r *Relay) PublishBatch(ctx context.Context) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, pendingQuery, r.opts.BatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("select pending events: %w", err)
+	}
+	var batch []events.Event
+	for rows.Next() {
+		var e events.Event
+		var payload []byte
+		if err := rows.Scan(&e.ID, &e.EventID, &e.Type, &e.AggregateType, &e.AggregateID,
+			&payload, &e.CreatedAt, &e.Attempts); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		e.Data = payload
+		batch = append(batch, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, e := range batch {
+		if err := r.publish(ctx, e); err != nil {
+			if ctx.Err() != nil {
+				return 0, ctx.Err()
+			}
+			metrics.OutboxPublishFailed(string(e.Type))
+			delay := Backoff(e.Attempts, r.opts.MinBackoff, r.opts.MaxBackoff)
+			slog.Warn("Outbox publish failed",
+				"event_id", e.EventID,
+				"type", e.Type,
+				"aggregate_id", e.AggregateID,
+				"attempt", e.Attempts+1,
+				"retry_in", delay,
+				"error", err,
+			)
+			if _, err := tx.ExecContext(ctx, `UPDATE outbox_events
+				SET attempts = attempts + 1, last_error = $2,
+				    next_attempt_at = NOW() + make_interval(secs => $3)
+				WHERE id = $1`, e.ID, err.Error(), delay.Seconds()); err != nil {
+				return 0, err
+			}
+			continue
+		}
+
+		metrics.OutboxPublished(string(e.Type), e.CreatedAt)
+		if _, err := tx.ExecContext(ctx, `UPDATE outbox_events
+			SET published_at = NOW(), attempts = attempts + 1, last_error = NULL
+			WHERE id = $1`, e.ID); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(batch), nil
+}
+
+func (r *Relay) publish(ctx context.Context, e events.Event) error {
+	ctx, cancel := context.WithTimeout(ctx, r.opts.PublishTimeout)
+	defer cancel()
+	return r.publisher.Publish(ctx, e)
+}
+
+// Backoff is the delay before retrying something that has already failed
+// attempts+1 times: exponential from min, capped at max, with jitter so
+// retries of many failures spread out.
+func Backoff(attempts int, min, max time.Duration) time.Duration {
+	d := max
+	if attempts < 30 {
+		if exp := min << attempts; exp > 0 && exp < d {
+			d = exp
+		}
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+func (r *Relay) prune(ctx context.Context) error {
+	res, err := r.db.ExecContext(ctx,
+		"DELETE FROM outbox_events WHERE published_at < NOW() - make_interval(secs => $1)",
+		r.opts.Retention.Seconds())
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		slog.Info("Pruned published outbox events", "count", n)
+	}
+	return nil
+}
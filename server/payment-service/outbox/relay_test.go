@@ -0,0 +1,174 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/events"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/internal/dbtest"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		min, max time.Duration
+		want     time.Duration // before jitter, which takes off up to half
+	}{
+		{0, time.Second, time.Minute, time.Second},
+		{1, time.Second, time.Minute, 2 * time.Second},
+		{5, time.Second, time.Minute, 32 * time.Second},
+		{6, time.Second, time.Minute, time.Minute},
+		{40, time.Second, time.Minute, time.Minute},
+		// min << attempts overflows long before 30 attempts here
+		{29, time.Hour, 6 * time.Hour, 6 * time.Hour},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 50; i++ {
+			if got := Backoff(tt.attempts, tt.min, tt.max); got < tt.want/2 || got > tt.want {
+				t.Errorf("Backoff(%d, %v, %v) = %v, want within [%v, %v]", tt.attempts, tt.min, tt.max, got, tt.want/2, tt.want)
+				break
+			}
+		}
+	}
+}
+
+// recorder is a Publisher that remembers what it accepted and refuses the
+// events in fail.
+type recorder struct {
+	mu        sync.Mutex
+	published []string
+	fail      map[string]bool
+}
+
+func (r *recorder) Publish(ctx context.Context, e events.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.fail[e.AggregateID+" "+string(e.Type)] {
+		return errors.New("broker unavailable")
+	}
+	r.published = append(r.published, e.AggregateID+" "+string(e.Type))
+	return nil
+}
+
+// take returns and forgets what was published so far.
+func (r *recorder) take() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	got := r.published
+	r.published = nil
+	return got
+}
+
+func record(t *testing.T, database *sql.DB, aggregateID string, typ events.Type) {
+	t.Helper()
+	ctx := context.Background()
+	tx, err := database.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if _, err := events.Record(ctx, tx, typ, events.AggregatePayment, aggregateID, map[string]string{"id": aggregateID}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestPublishBatchOrderAndRetry(t *testing.T) {
+	database := dbtest.Open(t)
+	ctx := context.Background()
+
+	record(t, database, "1", events.PaymentCreated)
+	record(t, database, "2", events.PaymentCreated)
+	record(t, database, "1", events.PaymentCaptured)
+	record(t, database, "2", events.PaymentCaptured)
+
+	pub := &recorder{fail: map[string]bool{"1 payment.created": true}}
+	relay := NewRelay(database, pub, Options{MinBackoff: time.Hour, MaxBackoff: time.Hour})
+
+	// Payment 1's failing event holds back its capture; payment 2 is
+	// unaffected and its events go out in order over two batches
+	for _, want := range [][]string{{"2 payment.created"}, {"2 payment.captured"}, nil} {
+		if _, err := relay.PublishBatch(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if got := pub.take(); !equal(got, want) {
+			t.Fatalf("published %v, want %v", got, want)
+		}
+	}
+
+	var attempts int
+	var lastError sql.NullString
+	var retryIn float64
+	err := database.QueryRowContext(ctx, `SELECT attempts, last_error,
+			EXTRACT(EPOCH FROM next_attempt_at - NOW())
+		FROM outbox_events WHERE aggregate_id = '1' AND event_type = 'payment.created'`).Scan(&attempts, &lastError, &retryIn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 1 || lastError.String != "broker unavailable" {
+		t.Errorf("failed event: %d attempts, last error %q; want 1, broker unavailable", attempts, lastError.String)
+	}
+	// Backoff after one failure is between half and all of MinBackoff
+	if retryIn < 1700 || retryIn > 3600 {
+		t.Errorf("failed event retries in %.0fs, want about an hour", retryIn)
+	}
+
+	// Once due and accepted, payment 1's events follow in order
+	pub.fail = nil
+	if _, err := database.ExecContext(ctx, `UPDATE outbox_events SET next_attempt_at = NOW() WHERE published_at IS NULL`); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range [][]string{{"1 payment.created"}, {"1 payment.captured"}, nil} {
+		if _, err := relay.PublishBatch(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if got := pub.take(); !equal(got, want) {
+			t.Fatalf("published %v, want %v", got, want)
+		}
+	}
+	var pending int
+	if err := database.QueryRowContext(ctx, `SELECT COUNT(*) FROM outbox_events WHERE published_at IS NULL`).Scan(&pending); err != nil || pending != 0 {
+		t.Errorf("%d events still pending (%v)", pending, err)
+	}
+}
+
+func TestPublishBatchSize(t *testing.T) {
+	database := dbtest.Open(t)
+	ctx := context.Background()
+	for _, id := range []string{"1", "2", "3"} {
+		record(t, database, id, events.PaymentCreated)
+	}
+
+	pub := &recorder{}
+	relay := NewRelay(database, pub, Options{BatchSize: 2})
+	for _, want := range []int{2, 1, 0} {
+		n, err := relay.PublishBatch(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != want {
+			t.Errorf("batch of %d events, want %d", n, want)
+		}
+	}
+	if got := pub.take(); !equal(got, []string{"1 payment.created", "2 payment.created", "3 payment.created"}) {
+		t.Errorf("published %v, want events in id order", got)
+	}
+}
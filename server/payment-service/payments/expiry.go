@@ -0,0 +1,155 @@
+package payments
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/metrics"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/models"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/tracing"
+)
+
+// SweeperOptions tunes a Sweeper. Zero values take the defaults noted per
+// field.
+type SweeperOptions struct {
+	Interval  time.Duration // 1m
+	BatchSize int           // 100 payments per transaction
+	// AuthorizationTTL is how long a manual-capture authorization is held
+	// before it is voided; 0 never voids.
+	AuthorizationTTL time.Duration
+}
+
+// Sweeper moves unpaid payments past their expires_at to expired, and voids
+// authorizations nobody captured within AuthorizationTTL.
+type Sweeper struct {
+	db   *sql.DB
+	opts SweeperOptions
+}
+
+// NewSweeper creates a sweeper for the payments in db.
+func NewSweeper(db *sql.DB, opts SweeperOptions) *Sweeper {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Minute
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	return &Sweeper{db: db, opts: opts}
+}
+
+// Run sweeps until ctx is cancelled.
+func (s *Sweeper) Run(ctx context.Context) {
+	slog.Info("Payment sweeper started", "interval", s.opts.Interval, "authorization_ttl", s.opts.AuthorizationTTL)
+
+	ticker := time.NewTicker(s.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.drain(ctx, "expire", s.Expire)
+		if s.opts.AuthorizationTTL > 0 {
+			s.drain(ctx, "void", s.VoidStale)
+		}
+
+		select {
+		case <-ctx.Done():
+			slog.Info("Payment sweeper stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// drain repeats sweep while batches come back full.
+func (s *Sweeper) drain(ctx context.Context, name string, sweep func(context.Context) (int, error)) {
+	for {
+		n, err := sweep(ctx)
+		if err != nil && ctx.Err() == nil {
+			slog.Error("Payment sweep failed", "sweep", name, "error", err)
+		}
+		if err != nil || n < s.opts.BatchSize {
+			return
+		}
+	}
+}
+
+// Rows locked by a webhook, a capture or another replica are left for the
+// next sweep.
+const (
+	expiredQuery = "SELECT " + Columns + ` FROM payments
+	WHERE status = 'created' AND expires_at <= NOW()
+	ORDER BY expires_at
+	LIMIT $1
+	FOR UPDATE SKIP LOCKED`
+
+	staleAuthorizationQuery = "SELECT " + Columns + ` FROM payments
+	WHERE status = 'authorized' AND authorized_at <= NOW() - make_interval(secs => $2)
+	ORDER BY authorized_at
+	LIMIT $1
+	FOR UPDATE SKIP LOCKED`
+)
+
+// Expire expires one batch of payments and returns how many it expired.
+func (s *Sweeper) Expire(ctx context.Context) (int, error) {
+	due, err := s.sweep(ctx, models.PaymentStatusExpired, expiredQuery, s.opts.BatchSize)
+	for _, p := range due {
+		metrics.PaymentExpired(p.Currency)
+		slog.InfoContext(ctx, "Payment expired", "payment_id", p.ID, "order_id", p.RazorpayOrderID)
+	}
+	return len(due), err
+}
+
+// VoidStale voids one batch of authorizations older than AuthorizationTTL
+// and returns how many it voided. The void is local: Razorpay has no call
+// to release an authorization, so the payments stay release-pending until
+// its refund of them arrives (see ReleaseAuthorization).
+func (s *Sweeper) VoidStale(ctx context.Context) (int, error) {
+	due, err := s.sweep(ctx, models.PaymentStatusVoided, staleAuthorizationQuery,
+		s.opts.BatchSize, s.opts.AuthorizationTTL.Seconds())
+	for _, p := range due {
+		metrics.PaymentVoided("expired")
+		slog.InfoContext(ctx, "Stale authorization voided; Razorpay has yet to release it", "payment_id", p.ID, "authorized_at", p.AuthorizedAt)
+	}
+	return len(due), err
+}
+
+// sweep moves the payments query selects to status to in one transaction.
+func (s *Sweeper) sweep(ctx context.Context, to models.PaymentStatus, query string, args ...any) ([]models.Payment, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	spanCtx, span := tracing.StartDBSpan(ctx, "SELECT", "payments", query)
+	rows, err := tx.QueryContext(spanCtx, query, args...)
+	tracing.End(span, err)
+	if err != nil {
+		return nil, fmt.Errorf("select payments to mark %s: %w", to, err)
+	}
+	var due []models.Payment
+	for rows.Next() {
+		p, err := Scan(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		due = append(due, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range due {
+		if _, err := Transition(ctx, tx, &due[i], to, ""); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return due, nil
+}
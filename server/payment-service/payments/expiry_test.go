@@ -0,0 +1,128 @@
+package payments
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/internal/dbtest"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/models"
+)
+
+// insertAuthorized stores a manual-capture payment authorized at
+// authorizedAt.
+func insertAuthorized(t *testing.T, database *sql.DB, orderID string, authorizedAt time.Time) models.Payment {
+	t.Helper()
+	ctx := context.Background()
+	var id int
+	err := database.QueryRowContext(ctx, `INSERT INTO payments
+			(user_id, amount, currency, from_account, to_account, razorpay_order_id, razorpay_payment_id,
+			 status, created_at, capture_mode, authorized_at)
+		VALUES (5, 500, 'INR', 'ACC-FROM', 'ACC-TO', $1, $2, 'authorized', $3, 'manual', $3)
+		RETURNING id`, orderID, "pay_"+orderID, authorizedAt).Scan(&id)
+	if err != nil {
+		t.Fatalf("insert authorized payment: %v", err)
+	}
+	p, err := Get(ctx, database, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestVoidStaleLeavesReleasePending(t *testing.T) {
+	database := dbtest.Open(t)
+	ctx := context.Background()
+	stale := insertAuthorized(t, database, "order_stale", time.Now().Add(-6*24*time.Hour))
+	fresh := insertAuthorized(t, database, "order_fresh", time.Now().Add(-time.Hour))
+
+	s := NewSweeper(database, SweeperOptions{AuthorizationTTL: 120 * time.Hour})
+	n, err := s.VoidStale(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatalf("voided %d, want 1", n)
+	}
+
+	p, err := Get(ctx, database, stale.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Status != string(models.PaymentStatusVoided) || !p.ReleasePending() {
+		t.Errorf("stale authorization is %s, release pending %t; want voided and pending", p.Status, p.ReleasePending())
+	}
+	if p, _ := Get(ctx, database, fresh.ID); p.Status != string(models.PaymentStatusAuthorized) {
+		t.Errorf("fresh authorization is %s, want authorized", p.Status)
+	}
+}
+
+func TestReleaseAuthorization(t *testing.T) {
+	database := dbtest.Open(t)
+	ctx := context.Background()
+	p := insertAuthorized(t, database, "order_release", time.Now())
+
+	release := func(p *models.Payment) (bool, error) {
+		tx, err := database.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tx.Rollback()
+		changed, err := ReleaseAuthorization(ctx, tx, p)
+		if err == nil {
+			err = tx.Commit()
+		}
+		return changed, err
+	}
+
+	// Only a voided authorization can be released
+	var transitionErr *TransitionError
+	if _, err := release(&p); !errors.As(err, &transitionErr) {
+		t.Fatalf("releasing an authorized payment: err = %v, want a TransitionError", err)
+	}
+
+	tx, err := database.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Transition(ctx, tx, &p, models.PaymentStatusVoided, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	if changed, err := release(&p); err != nil || !changed {
+		t.Fatalf("release: changed %t, err %v", changed, err)
+	}
+	if p.AuthorizationReleasedAt == nil || p.ReleasePending() {
+		t.Errorf("released payment %+v still pending", p)
+	}
+
+	// A redelivered refund finds it released already
+	again, err := Get(ctx, database, p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.AuthorizationReleasedAt == nil || !again.AuthorizationReleasedAt.Equal(*p.AuthorizationReleasedAt) {
+		t.Errorf("stored release %v, want %v", again.AuthorizationReleasedAt, p.AuthorizationReleasedAt)
+	}
+	if changed, err := release(&again); err != nil || changed {
+		t.Errorf("second release: changed %t, err %v; want a no-op", changed, err)
+	}
+
+	var events, audits int
+	err = database.QueryRowContext(ctx, `SELECT
+			(SELECT COUNT(*) FROM outbox_events WHERE event_type = 'payment.authorization_released' AND aggregate_id = $1),
+			(SELECT COUNT(*) FROM audit_log WHERE action = 'payment.authorization_released' AND resource_id = $1)`,
+		strconv.Itoa(again.ID)).Scan(&events, &audits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if events != 1 || audits != 1 {
+		t.Errorf("%d events and %d audit entries, want one of each", events, audits)
+	}
+}
@@ -0,0 +1,282 @@
+package payments
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/beneficiaries"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/blocklist"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/currencies"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/limits"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/metrics"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/middleware"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/models"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/razorpay"
+)
+
+// ErrGateway wraps a failure to create the Razorpay order.
+var ErrGateway = errors.New("could not create payment order")
+
+// ErrBlocklisted is returned when the payee or source account of a payment
+// is on the blocklist.
+var ErrBlocklisted = errors.New("account is blocklisted")
+
+// blocked is the list Initiate checks every payment against.
+var blocked *blocklist.Cache
+
+// SetBlocklist sets the list Initiate refuses payments to and from. The
+// check applies to every caller, whether or not it also passes a Screen.
+func SetBlocklist(c *blocklist.Cache) {
+	blocked = c
+}
+
+// Order describes a payment to initiate. Callers validate the amount,
+// accounts and capture mode first.
+type Order struct {
+	UserID      int
+	Amount      float64
+	Currency    string
+	FromAccount string
+	// ToAccount is ignored when BeneficiaryID is set; the beneficiary's
+	// payee is copied in instead.
+	ToAccount     string
+	BeneficiaryID *int64
+	CaptureMode   string
+	Description   *string
+	// Direction defaults to outgoing: UserID is sending the money.
+	Direction models.Direction
+	TTL       time.Duration
+
+	// Receipt defaults to order_<user>_<unix time>. It must keep the order_
+	// prefix so reconciliation recognises the order as ours.
+	Receipt   string
+	RequestID string
+	// Notes are added to the order's Razorpay notes.
+	Notes map[string]interface{}
+
+	// CheckLimits applies the user's transaction limits: once up front, and
+	// again under a per-user lock taken before the order is created and held
+	// until the payment is stored, so concurrent payments cannot both
+	// squeeze under a cap. Errors then wrap a *limits.Violation.
+	CheckLimits bool
+
+	// Screen, if set, runs once the payee is known and before the order is
+	// created. An error refuses the payment; hold stores it in
+	// pending_review without a Razorpay order until Release creates one.
+	Screen func(ctx context.Context, toAccount string) (hold bool, err error)
+
+	// Record, if set, runs in the transaction that stores the payment so
+	// the caller can link to it atomically; an error fails the payment.
+	Record func(ctx context.Context, tx *sql.Tx, p models.Payment) error
+}
+
+// Initiate creates the Razorpay order for o and stores the payment with its
+// payment.created event, or stores it held with payment.held if o.Screen
+// asks for review. Errors wrap currencies.ErrUnsupported when the currency
+// is unknown or disabled, *limits.Violation when the payment is over a
+// limit, beneficiaries.ErrNotFound when the beneficiary is unknown,
+// ErrBlocklisted when either account is blocklisted and ErrGateway when
+// Razorpay refused the order; errors from o.Screen are returned as they
+// are. If storing the payment fails once its order exists, the payment is
+// stored as failed instead so the order is accounted for.
+func Initiate(ctx context.Context, db *sql.DB, o Order) (models.Payment, error) {
+	cur, err := currencies.Supported(ctx, db, o.Currency)
+	if err != nil {
+		return models.Payment{}, err
+	}
+	if o.CheckLimits {
+		if err := limits.Check(ctx, db, o.UserID, cur.Code, o.Amount, time.Now()); err != nil {
+			return models.Payment{}, err
+		}
+	}
+
+	// A saved payee replaces the free-form to_account; the payment keeps a
+	// copy so deleting the beneficiary later does not change history
+	notes := map[string]interface{}{
+		"from_account": o.FromAccount,
+		"request_id":   o.RequestID,
+	}
+	for k, v := range o.Notes {
+		notes[k] = v
+	}
+	if o.BeneficiaryID != nil {
+		b, err := beneficiaries.Get(ctx, db, o.UserID, *o.BeneficiaryID)
+		if err != nil {
+			return models.Payment{}, fmt.Errorf("load beneficiary %d: %w", *o.BeneficiaryID, err)
+		}
+		o.ToAccount = b.Payee()
+		notes["beneficiary_id"] = b.ID
+		if b.IFSC != "" {
+			notes["ifsc"] = b.IFSC
+		}
+	}
+	notes["to_account"] = o.ToAccount
+
+	if o.CaptureMode == "" {
+		o.CaptureMode = string(models.CaptureAuto)
+	}
+	payment := models.Payment{
+		UserID:        o.UserID,
+		Amount:        o.Amount,
+		Currency:      o.Currency,
+		FromAccount:   o.FromAccount,
+		ToAccount:     o.ToAccount,
+		Status:        string(models.PaymentStatusCreated),
+		Description:   o.Description,
+		CaptureMode:   o.CaptureMode,
+		BeneficiaryID: o.BeneficiaryID,
+		Direction:     string(o.Direction),
+	}
+
+	hold := false
+	if o.Screen != nil {
+		if hold, err = o.Screen(ctx, o.ToAccount); err != nil {
+			return models.Payment{}, err
+		}
+	}
+	// Screening may have stored why it refused such a payment; either way
+	// no order is created for a blocklisted account
+	if err := checkBlocklist(o.FromAccount, o.ToAccount); err != nil {
+		return models.Payment{}, err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		metrics.PaymentCreated(string(models.PaymentStatusFailed), o.Currency)
+		return models.Payment{}, err
+	}
+	defer tx.Rollback()
+
+	// The lock is held through the gateway call, so a payment over a limit
+	// never gets an order and the user's next payment waits for this one
+	if o.CheckLimits {
+		if err := limits.Enforce(ctx, tx, payment, time.Now()); err != nil {
+			metrics.PaymentCreated(string(models.PaymentStatusFailed), o.Currency)
+			return models.Payment{}, err
+		}
+	}
+
+	if hold {
+		payment.Status = string(models.PaymentStatusPendingReview)
+	} else {
+		if o.Receipt == "" {
+			o.Receipt = fmt.Sprintf("order_%d_%d", o.UserID, time.Now().Unix())
+		}
+		order, err := createOrder(ctx, cur, payment, o.Receipt, notes, o.RequestID)
+		if err != nil {
+			metrics.PaymentCreated(string(models.PaymentStatusFailed), o.Currency)
+			return models.Payment{}, err
+		}
+		expiresAt := time.Now().UTC().Add(o.TTL)
+		payment.RazorpayOrderID = order.ID
+		payment.ExpiresAt = &expiresAt
+	}
+
+	// Store the payment and its event in the same transaction
+	payment.CreatedAt = time.Now().UTC()
+	fail := func(err error) (models.Payment, error) {
+		metrics.PaymentCreated(string(models.PaymentStatusFailed), o.Currency)
+		tx.Rollback()
+		if payment.RazorpayOrderID != "" {
+			abandon(ctx, db, payment, err)
+		}
+		return models.Payment{}, err
+	}
+	if err := Create(ctx, tx, &payment); err != nil {
+		return fail(fmt.Errorf("save payment for order %q: %w", payment.RazorpayOrderID, err))
+	}
+	if o.Record != nil {
+		if err := o.Record(ctx, tx, payment); err != nil {
+			return fail(err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fail(fmt.Errorf("commit payment for order %q: %w", payment.RazorpayOrderID, err))
+	}
+
+	metrics.PaymentCreated(payment.Status, payment.Currency)
+	msg := "Payment created"
+	if hold {
+		msg = "Payment held for review"
+	}
+	slog.InfoContext(ctx, msg,
+		"payment_id", payment.ID,
+		"order_id", payment.RazorpayOrderID,
+		"amount", payment.Amount,
+		"currency", payment.Currency,
+	)
+	return payment, nil
+}
+
+// checkBlocklist returns an error wrapping ErrBlocklisted if either account
+// is on the blocklist set with SetBlocklist.
+func checkBlocklist(fromAccount, toAccount string) error {
+	if blocked == nil {
+		return nil
+	}
+	if entry, ok := blocked.Payee(toAccount); ok {
+		return fmt.Errorf("%w: payee %s (entry %d)", ErrBlocklisted, models.MaskAccount(toAccount), entry.ID)
+	}
+	if entry, ok := blocked.Payee(fromAccount); ok {
+		return fmt.Errorf("%w: source account %s (entry %d)", ErrBlocklisted, models.MaskAccount(fromAccount), entry.ID)
+	}
+	return nil
+}
+
+// abandon stores p, whose Razorpay order was created but which could not be
+// stored because of cause, as a failed payment. Razorpay cannot cancel an
+// order, so the row is what tells reconciliation and webhooks the order is
+// ours and dead.
+func abandon(ctx context.Context, db *sql.DB, p models.Payment, cause error) {
+	// The request may have been cancelled, which is why storing failed
+	ctx = context.WithoutCancel(ctx)
+	p.Status = string(models.PaymentStatusFailed)
+	p.ExpiresAt = nil
+
+	err := func() error {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		if err := Create(ctx, tx, &p); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}()
+	if err != nil {
+		slog.ErrorContext(ctx, "Razorpay order left without a payment",
+			"order_id", p.RazorpayOrderID, "cause", cause, "error", err)
+		return
+	}
+	slog.WarnContext(ctx, "Payment failed after its order was created",
+		"payment_id", p.ID, "order_id", p.RazorpayOrderID, "error", cause)
+}
+
+// createOrder creates the Razorpay order for p. Errors wrap ErrGateway.
+func createOrder(ctx context.Context, cur currencies.Currency, p models.Payment, receipt string, notes map[string]interface{}, requestID string) (razorpay.Order, error) {
+	order, err := razorpay.CreateOrder(ctx, map[string]interface{}{
+		"amount":   cur.ToMinor(p.Amount), // e.g. paise for INR
+		"currency": p.Currency,
+		"receipt":  receipt,
+		// 0 leaves payments authorized until POST /payments/{id}/capture
+		"payment_capture": captureFlag(p.CaptureMode),
+		"notes":           notes,
+	}, map[string]string{middleware.RequestIDHeader: requestID})
+	if err != nil {
+		return razorpay.Order{}, fmt.Errorf("%w: %w", ErrGateway, err)
+	}
+	return order, nil
+}
+
+// captureFlag is Razorpay's payment_capture value for a capture mode.
+func captureFlag(mode string) int {
+	if models.CaptureMode(mode) == models.CaptureManual {
+		return 0
+	}
+	return 1
+}
@@ -0,0 +1,180 @@
+package payments
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/internal/dbtest"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/internal/rzptest"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/limits"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/models"
+)
+
+// fakeOrders answers order creation like Razorpay, counting the orders.
+func fakeOrders(t *testing.T) *atomic.Int64 {
+	var created atomic.Int64
+	rzptest.Serve(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/orders" {
+			http.NotFound(w, r)
+			return
+		}
+		var req map[string]any
+		json.NewDecoder(r.Body).Decode(&req)
+		json.NewEncoder(w).Encode(map[string]any{
+			"id":       fmt.Sprintf("order_fake%d", created.Add(1)),
+			"amount":   req["amount"],
+			"currency": req["currency"],
+			"receipt":  req["receipt"],
+			"status":   "created",
+		})
+	}))
+	return &created
+}
+
+func order(userID int, amount float64) Order {
+	return Order{
+		UserID:      userID,
+		Amount:      amount,
+		Currency:    "INR",
+		FromAccount: "50100123456789",
+		ToAccount:   "50100987654321",
+		TTL:         15 * time.Minute,
+		CheckLimits: true,
+	}
+}
+
+func TestInitiate(t *testing.T) {
+	database := dbtest.Open(t)
+	created := fakeOrders(t)
+	ctx := context.Background()
+
+	p, err := Initiate(ctx, database, order(1, 250))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Status != string(models.PaymentStatusCreated) || p.RazorpayOrderID != "order_fake1" || p.ExpiresAt == nil {
+		t.Fatalf("payment = %+v, want created with order_fake1", p)
+	}
+	stored, err := Get(ctx, database, p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.RazorpayOrderID != p.RazorpayOrderID || stored.Amount != 250 {
+		t.Errorf("stored %+v", stored)
+	}
+	if created.Load() != 1 {
+		t.Errorf("%d orders created, want 1", created.Load())
+	}
+}
+
+func TestInitiateOverLimitCreatesNoOrder(t *testing.T) {
+	database := dbtest.Open(t)
+	created := fakeOrders(t)
+	ctx := context.Background()
+	daily := 100.0
+	if err := limits.Put(ctx, database, 2, limits.Set{Currencies: []limits.Currency{{Currency: "INR", Daily: &daily}}}, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := Initiate(ctx, database, order(2, 150))
+	var v *limits.Violation
+	if !errors.As(err, &v) || v.Kind != limits.KindDaily {
+		t.Fatalf("err = %v, want a daily limit violation", err)
+	}
+	if created.Load() != 0 {
+		t.Errorf("%d orders created for a refused payment", created.Load())
+	}
+}
+
+func TestInitiateFailureKeepsOrder(t *testing.T) {
+	database := dbtest.Open(t)
+	fakeOrders(t)
+	ctx := context.Background()
+
+	errLinked := errors.New("quote already used")
+	o := order(3, 75)
+	o.Record = func(ctx context.Context, tx *sql.Tx, p models.Payment) error {
+		if p.RazorpayOrderID == "" {
+			t.Error("Record ran before the order was created")
+		}
+		return errLinked
+	}
+	if _, err := Initiate(ctx, database, o); !errors.Is(err, errLinked) {
+		t.Fatalf("err = %v, want the Record error", err)
+	}
+
+	// The order is stored against a failed payment, not left unknown
+	var status string
+	var expiresAt sql.NullTime
+	err := database.QueryRowContext(ctx, `SELECT status, expires_at FROM payments
+		WHERE razorpay_order_id = 'order_fake1' AND user_id = 3`).Scan(&status, &expiresAt)
+	if err != nil {
+		t.Fatalf("order_fake1 has no payment: %v", err)
+	}
+	if status != string(models.PaymentStatusFailed) || expiresAt.Valid {
+		t.Errorf("payment for order_fake1 is %s (expires %v), want failed", status, expiresAt)
+	}
+	var n int
+	if err := database.QueryRowContext(ctx, `SELECT COUNT(*) FROM payments WHERE user_id = 3`).Scan(&n); err != nil || n != 1 {
+		t.Errorf("%d payments stored, want 1 (%v)", n, err)
+	}
+}
+
+func TestReleaseFailureKeepsOrder(t *testing.T) {
+	database := dbtest.Open(t)
+	created := fakeOrders(t)
+	ctx := context.Background()
+
+	o := order(4, 90)
+	o.Screen = func(context.Context, string) (bool, error) { return true, nil }
+	held, err := Initiate(ctx, database, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Load() != 0 {
+		t.Fatalf("%d orders created for a held payment", created.Load())
+	}
+
+	tx, err := database.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	p, err := LockByID(ctx, tx, held.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	errAudit := errors.New("audit log unavailable")
+	err = Release(ctx, database, tx, &p, 15*time.Minute, "", func(context.Context, *sql.Tx) error {
+		return errAudit
+	})
+	if !errors.Is(err, errAudit) {
+		t.Fatalf("err = %v, want the record error", err)
+	}
+
+	// The payment is still held and can be approved again; the order it
+	// was given belongs to a failed payment
+	stored, err := Get(ctx, database, held.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != string(models.PaymentStatusPendingReview) || stored.RazorpayOrderID != "" {
+		t.Errorf("held payment is %s with order %q, want still held", stored.Status, stored.RazorpayOrderID)
+	}
+	var status string
+	err = database.QueryRowContext(ctx, `SELECT status FROM payments
+		WHERE razorpay_order_id = 'order_fake1' AND user_id = 4`).Scan(&status)
+	if err != nil {
+		t.Fatalf("order_fake1 has no payment: %v", err)
+	}
+	if status != string(models.PaymentStatusFailed) {
+		t.Errorf("payment for order_fake1 is %s, want failed", status)
+	}
+}
@@ -0,0 +1,402 @@
+// Package payments owns writes to the payments table. Every state change
+// records its domain event in the outbox and an audit log entry within the
+// caller's transaction.
+package payments
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/audit"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/events"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/models"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/tracing"
+)
+
+// ErrNotFound is returned when no payment matches a lookup.
+var ErrNotFound = errors.New("payment not found")
+
+// TransitionError reports a status change the payment lifecycle forbids.
+type TransitionError struct {
+	From, To string
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("payment cannot move from %s to %s", e.From, e.To)
+}
+
+// transitions lists the statuses each status may move to.
+var transitions = map[models.PaymentStatus][]models.PaymentStatus{
+	// Checkout verification moves a created payment to pending until
+	// Razorpay confirms the capture; unpaid orders expire
+	models.PaymentStatusCreated: {models.PaymentStatusPending, models.PaymentStatusAuthorized, models.PaymentStatusCaptured, models.PaymentStatusFailed, models.PaymentStatusExpired},
+	models.PaymentStatusPending: {models.PaymentStatusAuthorized, models.PaymentStatusCaptured, models.PaymentStatusFailed},
+	// Manual capture mode stops here until captured or voided
+	models.PaymentStatusAuthorized: {models.PaymentStatusCaptured, models.PaymentStatusFailed, models.PaymentStatusVoided},
+	// A customer may retry the same order after a failed attempt
+	models.PaymentStatusFailed:    {models.PaymentStatusCaptured},
+	models.PaymentStatusCaptured:  {models.PaymentStatusRefunded, models.PaymentStatusChargedBack},
+	models.PaymentStatusCompleted: {models.PaymentStatusRefunded, models.PaymentStatusChargedBack},
+	// Held by risk screening: approval creates the order, rejection fails it
+	models.PaymentStatusPendingReview: {models.PaymentStatusCreated, models.PaymentStatusFailed},
+}
+
+// eventTypes is the event recorded when a payment enters a status.
+var eventTypes = map[models.PaymentStatus]events.Type{
+	models.PaymentStatusCreated:       events.PaymentCreated,
+	models.PaymentStatusCaptured:      events.PaymentCaptured,
+	models.PaymentStatusFailed:        events.PaymentFailed,
+	models.PaymentStatusRefunded:      events.PaymentRefunded,
+	models.PaymentStatusExpired:       events.PaymentExpired,
+	models.PaymentStatusAuthorized:    events.PaymentAuthorized,
+	models.PaymentStatusVoided:        events.PaymentVoided,
+	models.PaymentStatusPendingReview: events.PaymentHeld,
+	models.PaymentStatusChargedBack:   events.PaymentChargedBack,
+}
+
+// Columns is the select list understood by Scan.
+const Columns = `id, user_id, amount, currency, from_account, to_account,
+	razorpay_order_id, razorpay_payment_id, status, created_at, updated_at, description, expires_at,
+	capture_mode, authorized_at, captured_amount, beneficiary_id, captured_at, refunded_at,
+	charged_back_at, charged_back_amount, authorization_released_at, direction`
+
+// CanTransition reports whether a payment in status from may move to to.
+func CanTransition(from, to models.PaymentStatus) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Create inserts p, filling in its ID and timestamps, and records
+// payment.created, or payment.held for a payment held for review. The audit
+// entry is payment.created either way, with the payment as its after value.
+func Create(ctx context.Context, tx *sql.Tx, p *models.Payment) error {
+	const query = `INSERT INTO payments
+		(user_id, amount, currency, from_account, to_account, razorpay_order_id, status, created_at, description, expires_at, capture_mode, beneficiary_id, direction)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING id, updated_at`
+
+	if p.CaptureMode == "" {
+		p.CaptureMode = string(models.CaptureAuto)
+	}
+	if p.Direction == "" {
+		p.Direction = string(models.DirectionOutgoing)
+	}
+
+	var updatedAt sql.NullTime
+	spanCtx, span := tracing.StartDBSpan(ctx, "INSERT", "payments", query)
+	err := tx.QueryRowContext(spanCtx, query,
+		p.UserID,
+		p.Amount,
+		p.Currency,
+		p.FromAccount,
+		p.ToAccount,
+		sql.NullString{String: p.RazorpayOrderID, Valid: p.RazorpayOrderID != ""},
+		p.Status,
+		p.CreatedAt,
+		p.Description,
+		p.ExpiresAt,
+		p.CaptureMode,
+		p.BeneficiaryID,
+		p.Direction,
+	).Scan(&p.ID, &updatedAt)
+	tracing.End(span, err)
+	if err != nil {
+		return fmt.Errorf("insert payment: %w", err)
+	}
+	if updatedAt.Valid {
+		p.UpdatedAt = &updatedAt.Time
+	}
+
+	if _, err := events.RecordPayment(ctx, tx, eventTypes[models.PaymentStatus(p.Status)], *p, ""); err != nil {
+		return err
+	}
+	_, err = audit.Append(ctx, tx, audit.Event{
+		Action:       "payment.created",
+		ResourceType: "payment",
+		ResourceID:   strconv.Itoa(p.ID),
+		After:        *p,
+	})
+	return err
+}
+
+// Get loads a payment without locking it.
+func Get(ctx context.Context, db *sql.DB, id int) (models.Payment, error) {
+	query := "SELECT " + Columns + " FROM payments WHERE id = $1"
+
+	spanCtx, span := tracing.StartDBSpan(ctx, "SELECT", "payments", query)
+	p, err := Scan(db.QueryRowContext(spanCtx, query, id))
+	tracing.End(span, err)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Payment{}, ErrNotFound
+	}
+	return p, err
+}
+
+// LockByID loads a payment and locks its row until tx ends.
+func LockByID(ctx context.Context, tx *sql.Tx, id int) (models.Payment, error) {
+	return lockOne(ctx, tx, "id = $1", id)
+}
+
+// LockByOrderID loads the payment for a Razorpay order and locks its row.
+func LockByOrderID(ctx context.Context, tx *sql.Tx, orderID string) (models.Payment, error) {
+	return lockOne(ctx, tx, "razorpay_order_id = $1", orderID)
+}
+
+// LockByRazorpayPaymentID loads the payment for a Razorpay payment ID and
+// locks its row.
+func LockByRazorpayPaymentID(ctx context.Context, tx *sql.Tx, razorpayPaymentID string) (models.Payment, error) {
+	return lockOne(ctx, tx, "razorpay_payment_id = $1", razorpayPaymentID)
+}
+
+func lockOne(ctx context.Context, tx *sql.Tx, where string, arg any) (models.Payment, error) {
+	query := "SELECT " + Columns + " FROM payments WHERE " + where + " FOR UPDATE"
+
+	spanCtx, span := tracing.StartDBSpan(ctx, "SELECT", "payments", query)
+	p, err := Scan(tx.QueryRowContext(spanCtx, query, arg))
+	tracing.End(span, err)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Payment{}, ErrNotFound
+	}
+	return p, err
+}
+
+// Transition moves p to status to and records the matching event. Moving to
+// the current status is a no-op so redelivered gateway notifications are
+// harmless; changed reports whether anything was written. razorpayPaymentID,
+// if set, is stored on the payment.
+func Transition(ctx context.Context, tx *sql.Tx, p *models.Payment, to models.PaymentStatus, razorpayPaymentID string) (changed bool, err error) {
+	from := models.PaymentStatus(p.Status)
+	if from == to {
+		return false, nil
+	}
+	if !CanTransition(from, to) {
+		return false, &TransitionError{From: string(from), To: string(to)}
+	}
+
+	const query = `UPDATE payments
+		SET status = $2, razorpay_payment_id = COALESCE(NULLIF($3, ''), razorpay_payment_id),
+			authorized_at = CASE WHEN $2 = 'authorized' THEN NOW() ELSE authorized_at END,
+			captured_at = CASE WHEN $2 = 'captured' THEN NOW() ELSE captured_at END,
+			refunded_at = CASE WHEN $2 = 'refunded' THEN NOW() ELSE refunded_at END,
+			charged_back_at = CASE WHEN $2 = 'charged_back' THEN NOW() ELSE charged_back_at END
+		WHERE id = $1
+		RETURNING razorpay_payment_id, updated_at, authorized_at, captured_at, refunded_at, charged_back_at`
+
+	var paymentID sql.NullString
+	var updatedAt, authorizedAt, capturedAt, refundedAt, chargedBackAt sql.NullTime
+	spanCtx, span := tracing.StartDBSpan(ctx, "UPDATE", "payments", query)
+	err = tx.QueryRowContext(spanCtx, query, p.ID, string(to), razorpayPaymentID).
+		Scan(&paymentID, &updatedAt, &authorizedAt, &capturedAt, &refundedAt, &chargedBackAt)
+	tracing.End(span, err)
+	if err != nil {
+		return false, fmt.Errorf("update payment %d: %w", p.ID, err)
+	}
+
+	p.Status = string(to)
+	if paymentID.Valid {
+		p.RazorpayPaymentID = &paymentID.String
+	}
+	if updatedAt.Valid {
+		p.UpdatedAt = &updatedAt.Time
+	}
+	if authorizedAt.Valid {
+		p.AuthorizedAt = &authorizedAt.Time
+	}
+	if capturedAt.Valid {
+		p.CapturedAt = &capturedAt.Time
+	}
+	if refundedAt.Valid {
+		p.RefundedAt = &refundedAt.Time
+	}
+	if chargedBackAt.Valid {
+		p.ChargedBackAt = &chargedBackAt.Time
+	}
+
+	if typ, ok := eventTypes[to]; ok {
+		if _, err := events.RecordPayment(ctx, tx, typ, *p, string(from)); err != nil {
+			return false, err
+		}
+	}
+	action := "payment.status_changed"
+	switch to {
+	case models.PaymentStatusRefunded:
+		action = "payment.refunded"
+	case models.PaymentStatusChargedBack:
+		action = "payment.charged_back"
+	}
+	_, err = audit.Append(ctx, tx, audit.Event{
+		Action:       action,
+		ResourceType: "payment",
+		ResourceID:   strconv.Itoa(p.ID),
+		Before:       map[string]any{"status": from},
+		After: map[string]any{
+			"status":              p.Status,
+			"razorpay_payment_id": p.RazorpayPaymentID,
+			"captured_amount":     p.CapturedAmount,
+			"charged_back_amount": p.ChargedBackAmount,
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Capture moves an authorized payment to captured after amount was captured
+// at Razorpay. A partial amount is stored so the event and later refunds
+// see what was actually taken.
+func Capture(ctx context.Context, tx *sql.Tx, p *models.Payment, amount float64) (changed bool, err error) {
+	if amount < p.Amount {
+		const query = "UPDATE payments SET captured_amount = $2 WHERE id = $1"
+		spanCtx, span := tracing.StartDBSpan(ctx, "UPDATE", "payments", query)
+		_, err := tx.ExecContext(spanCtx, query, p.ID, amount)
+		tracing.End(span, err)
+		if err != nil {
+			return false, fmt.Errorf("update payment %d: %w", p.ID, err)
+		}
+		p.CapturedAmount = &amount
+	}
+	return Transition(ctx, tx, p, models.PaymentStatusCaptured, "")
+}
+
+// ChargeBack moves a captured payment to charged_back after a dispute over
+// amount was lost, storing the amount returned to the payer for statements.
+// Payments in any other status return a *TransitionError.
+func ChargeBack(ctx context.Context, tx *sql.Tx, p *models.Payment, amount float64) (changed bool, err error) {
+	from := models.PaymentStatus(p.Status)
+	if from == models.PaymentStatusChargedBack {
+		return false, nil
+	}
+	if !CanTransition(from, models.PaymentStatusChargedBack) {
+		return false, &TransitionError{From: p.Status, To: string(models.PaymentStatusChargedBack)}
+	}
+	const query = "UPDATE payments SET charged_back_amount = $2 WHERE id = $1"
+	spanCtx, span := tracing.StartDBSpan(ctx, "UPDATE", "payments", query)
+	_, err = tx.ExecContext(spanCtx, query, p.ID, amount)
+	tracing.End(span, err)
+	if err != nil {
+		return false, fmt.Errorf("update payment %d: %w", p.ID, err)
+	}
+	p.ChargedBackAmount = &amount
+	return Transition(ctx, tx, p, models.PaymentStatusChargedBack, "")
+}
+
+// ReleaseAuthorization records that Razorpay returned a voided
+// authorization's held funds to the payer. It is a no-op for a payment
+// already released, and any status but voided returns a *TransitionError.
+func ReleaseAuthorization(ctx context.Context, tx *sql.Tx, p *models.Payment) (changed bool, err error) {
+	if p.Status != string(models.PaymentStatusVoided) {
+		return false, &TransitionError{From: p.Status, To: "released"}
+	}
+	if p.AuthorizationReleasedAt != nil {
+		return false, nil
+	}
+	const query = "UPDATE payments SET authorization_released_at = NOW() WHERE id = $1 RETURNING authorization_released_at, updated_at"
+	var releasedAt, updatedAt time.Time
+	spanCtx, span := tracing.StartDBSpan(ctx, "UPDATE", "payments", query)
+	err = tx.QueryRowContext(spanCtx, query, p.ID).Scan(&releasedAt, &updatedAt)
+	tracing.End(span, err)
+	if err != nil {
+		return false, fmt.Errorf("update payment %d: %w", p.ID, err)
+	}
+	p.AuthorizationReleasedAt = &releasedAt
+	p.UpdatedAt = &updatedAt
+
+	if _, err := events.RecordPayment(ctx, tx, events.PaymentAuthorizationReleased, *p, p.Status); err != nil {
+		return false, err
+	}
+	_, err = audit.Append(ctx, tx, audit.Event{
+		Action:       "payment.authorization_released",
+		ResourceType: "payment",
+		ResourceID:   strconv.Itoa(p.ID),
+		Before:       map[string]any{"status": p.Status, "authorization_released_at": nil},
+		After:        map[string]any{"status": p.Status, "authorization_released_at": p.AuthorizationReleasedAt},
+	})
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Scan reads a row selected with Columns.
+func Scan(row interface{ Scan(...any) error }) (models.Payment, error) {
+	var p models.Payment
+	var orderID, paymentID, description sql.NullString
+	var updatedAt, expiresAt, authorizedAt, capturedAt, refundedAt, chargedBackAt, releasedAt sql.NullTime
+	var capturedAmount, chargedBackAmount sql.NullFloat64
+	var beneficiaryID sql.NullInt64
+	err := row.Scan(
+		&p.ID,
+		&p.UserID,
+		&p.Amount,
+		&p.Currency,
+		&p.FromAccount,
+		&p.ToAccount,
+		&orderID,
+		&paymentID,
+		&p.Status,
+		&p.CreatedAt,
+		&updatedAt,
+		&description,
+		&expiresAt,
+		&p.CaptureMode,
+		&authorizedAt,
+		&capturedAmount,
+		&beneficiaryID,
+		&capturedAt,
+		&refundedAt,
+		&chargedBackAt,
+		&chargedBackAmount,
+		&releasedAt,
+		&p.Direction,
+	)
+	if err != nil {
+		return models.Payment{}, err
+	}
+	p.RazorpayOrderID = orderID.String // NULL while held for review
+	if paymentID.Valid {
+		p.RazorpayPaymentID = &paymentID.String
+	}
+	if updatedAt.Valid {
+		p.UpdatedAt = &updatedAt.Time
+	}
+	if description.Valid {
+		p.Description = &description.String
+	}
+	if expiresAt.Valid {
+		p.ExpiresAt = &expiresAt.Time
+	}
+	if authorizedAt.Valid {
+		p.AuthorizedAt = &authorizedAt.Time
+	}
+	if capturedAmount.Valid {
+		p.CapturedAmount = &capturedAmount.Float64
+	}
+	if beneficiaryID.Valid {
+		p.BeneficiaryID = &beneficiaryID.Int64
+	}
+	if capturedAt.Valid {
+		p.CapturedAt = &capturedAt.Time
+	}
+	if refundedAt.Valid {
+		p.RefundedAt = &refundedAt.Time
+	}
+	if chargedBackAt.Valid {
+		p.ChargedBackAt = &chargedBackAt.Time
+	}
+	if chargedBackAmount.Valid {
+		p.ChargedBackAmount = &chargedBackAmount.Float64
+	}
+	if releasedAt.Valid {
+		p.AuthorizationReleasedAt = &releasedAt.Time
+	}
+	return p, nil
+}
@@ -0,0 +1,94 @@
+package payments
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/currencies"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/fx"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/models"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/tracing"
+)
+
+// Release creates the Razorpay order for p, held in pending_review and
+// locked in tx, moves it to created with payment.created and commits tx.
+// The order is payable for ttl from now and carries the FX quote p
+// redeemed, if any. record, if set, runs in tx before the commit so the
+// caller can log the decision with it. Errors wrap ErrGateway when Razorpay
+// refused the order, in which case p is still held. If anything fails once
+// the order exists, tx is rolled back and the order is stored against a
+// failed payment through db, as Initiate does, so p stays held and the
+// order is accounted for.
+func Release(ctx context.Context, db *sql.DB, tx *sql.Tx, p *models.Payment, ttl time.Duration, requestID string, record func(ctx context.Context, tx *sql.Tx) error) error {
+	if models.PaymentStatus(p.Status) != models.PaymentStatusPendingReview {
+		return &TransitionError{From: p.Status, To: string(models.PaymentStatusCreated)}
+	}
+	cur, err := currencies.Get(ctx, tx, p.Currency)
+	if err != nil {
+		return fmt.Errorf("load currency %s: %w", p.Currency, err)
+	}
+
+	notes := map[string]interface{}{
+		"from_account": p.FromAccount,
+		"to_account":   p.ToAccount,
+		"payment_id":   p.ID,
+		"request_id":   requestID,
+	}
+	if p.BeneficiaryID != nil {
+		notes["beneficiary_id"] = *p.BeneficiaryID
+	}
+	quote, err := fx.ForPayment(ctx, tx, int64(p.ID))
+	switch {
+	case err == nil:
+		notes["fx_quote_id"] = quote.ID
+		notes["credited_amount"] = quote.ConvertedAmount
+		notes["credited_currency"] = quote.To
+	case !errors.Is(err, fx.ErrQuoteNotFound):
+		return fmt.Errorf("load quote for payment %d: %w", p.ID, err)
+	}
+
+	// The row stays locked during the gateway call so approving twice
+	// cannot create two orders
+	receipt := fmt.Sprintf("order_%d_%d", p.UserID, time.Now().Unix())
+	order, err := createOrder(ctx, cur, *p, receipt, notes, requestID)
+	if err != nil {
+		return err
+	}
+
+	held := *p
+	fail := func(err error) error {
+		tx.Rollback()
+		*p = held
+		failed := held
+		failed.RazorpayOrderID = order.ID
+		abandon(ctx, db, failed, err)
+		return err
+	}
+
+	expiresAt := time.Now().UTC().Add(ttl)
+	const query = "UPDATE payments SET razorpay_order_id = $2, expires_at = $3 WHERE id = $1"
+	spanCtx, span := tracing.StartDBSpan(ctx, "UPDATE", "payments", query)
+	_, err = tx.ExecContext(spanCtx, query, p.ID, order.ID, expiresAt)
+	tracing.End(span, err)
+	if err != nil {
+		return fail(fmt.Errorf("update payment %d: %w", p.ID, err))
+	}
+	p.RazorpayOrderID = order.ID
+	p.ExpiresAt = &expiresAt
+
+	if _, err := Transition(ctx, tx, p, models.PaymentStatusCreated, ""); err != nil {
+		return fail(err)
+	}
+	if record != nil {
+		if err := record(ctx, tx); err != nil {
+			return fail(err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fail(fmt.Errorf("commit payment %d for order %q: %w", p.ID, order.ID, err))
+	}
+	return nil
+}
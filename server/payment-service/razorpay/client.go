@@ -1,18 +1,17 @@
 package razorpay
 
 import (
+	"context"
 	"errors"
-	"os"
 
 	"github.com/razorpay/razorpay-go"
 )
 
+// Client is the shared Razorpay API client, set by Init.
 var Client *razorpay.Client
 
-func Init() error {
-	keyID := os.Getenv("RAZORPAY_KEY_ID")
-	keySecret := os.Getenv("RAZORPAY_KEY_SECRET")
-
+// Init creates the Razorpay client from the configured key pair.
+func Init(keyID, keySecret string) error {
 	if keyID == "" || keySecret == "" {
 		return errors.New("Razorpay keys not configured")
 	}
@@ -20,3 +19,11 @@ func Init() error {
 	Client = razorpay.NewClient(keyID, keySecret)
 	return nil
 }
+
+// CheckCredentials is a readiness check reporting whether Init succeeded.
+func CheckCredentials(ctx context.Context) error {
+	if Client == nil {
+		return errors.New("Razorpay credentials not loaded")
+	}
+	return nil
+}
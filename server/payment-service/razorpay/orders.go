@@ -0,0 +1,121 @@
+package razorpay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/metrics"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/tracing"
+	rzperrors "github.com/razorpay/razorpay-go/errors"
+)
+
+// ErrNotFound is returned when Razorpay does not know the requested ID.
+var ErrNotFound = errors.New("not found at Razorpay")
+
+// Order is a Razorpay order. Amounts are in the smallest currency unit.
+type Order struct {
+	ID         string         `json:"id"`
+	Amount     int64          `json:"amount"`
+	AmountPaid int64          `json:"amount_paid"`
+	Currency   string         `json:"currency"`
+	Receipt    string         `json:"receipt"`
+	Status     string         `json:"status"` // created, attempted or paid
+	Notes      map[string]any `json:"notes"`
+	CreatedAt  int64          `json:"created_at"`
+}
+
+// CreateOrder creates an order from data, sending headers with the request.
+func CreateOrder(ctx context.Context, data map[string]interface{}, headers map[string]string) (Order, error) {
+	var order Order
+	err := call(ctx, "order.create", &order, func() (map[string]interface{}, error) {
+		return Client.Order.Create(data, headers)
+	})
+	return order, err
+}
+
+// FetchOrder returns the order with the given ID.
+func FetchOrder(ctx context.Context, orderID string) (Order, error) {
+	var order Order
+	err := call(ctx, "order.fetch", &order, func() (map[string]interface{}, error) {
+		return Client.Order.Fetch(orderID, nil, nil)
+	})
+	return order, err
+}
+
+// OrderPayments returns every payment attempt made against an order.
+func OrderPayments(ctx context.Context, orderID string) ([]PaymentEntity, error) {
+	var list struct {
+		Items []PaymentEntity `json:"items"`
+	}
+	err := call(ctx, "order.payments", &list, func() (map[string]interface{}, error) {
+		return Client.Order.Payments(orderID, nil, nil)
+	})
+	return list.Items, err
+}
+
+// ListOrders returns the orders created in [from, to), following
+// pagination.
+func ListOrders(ctx context.Context, from, to time.Time) ([]Order, error) {
+	const pageSize = 100
+	var orders []Order
+	for skip := 0; ; skip += pageSize {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		var page struct {
+			Items []Order `json:"items"`
+		}
+		err := call(ctx, "order.list", &page, func() (map[string]interface{}, error) {
+			return Client.Order.All(map[string]interface{}{
+				"from":  from.Unix(),
+				"to":    to.Unix() - 1,
+				"count": pageSize,
+				"skip":  skip,
+			}, nil)
+		})
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, page.Items...)
+		if len(page.Items) < pageSize {
+			return orders, nil
+		}
+	}
+}
+
+// call runs one SDK request inside a gateway span, records its latency and
+// decodes the untyped response into out. The SDK takes no context, so ctx
+// only parents the span.
+func call(ctx context.Context, operation string, out any, fn func() (map[string]interface{}, error)) error {
+	if Client == nil {
+		return errors.New("Razorpay client not initialized")
+	}
+
+	start := time.Now()
+	_, span := tracing.StartGatewaySpan(ctx, operation)
+	resp, err := fn()
+	err = mapError(err)
+	tracing.End(span, err)
+	metrics.ObserveGatewayCall(operation, start, err)
+	if err != nil {
+		return err
+	}
+
+	raw, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, out)
+}
+
+func mapError(err error) error {
+	var badRequest *rzperrors.BadRequestError
+	if errors.As(err, &badRequest) && strings.Contains(strings.ToLower(badRequest.Message), "does not exist") {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,64 @@
+package razorpay_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/internal/rzptest"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/razorpay"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/tracing"
+)
+
+func TestCallRecordsGatewaySpan(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	shutdown := tracing.InitWithExporter(exporter)
+	defer shutdown(context.Background())
+
+	rzptest.Serve(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/orders/order_1" {
+			json.NewEncoder(w).Encode(map[string]any{"id": "order_1", "amount": 10000, "currency": "INR", "status": "paid"})
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{
+			"code": "BAD_REQUEST_ERROR", "description": "The id provided does not exist",
+		}})
+	}))
+
+	ctx, parent := tracing.Tracer().Start(context.Background(), "GET /api/v1/pay/{id}")
+	order, err := razorpay.FetchOrder(ctx, "order_1")
+	if err != nil || order.ID != "order_1" || order.Status != "paid" {
+		t.Fatalf("FetchOrder = %+v, %v", order, err)
+	}
+	if _, err := razorpay.FetchOrder(ctx, "order_missing"); !errors.Is(err, razorpay.ErrNotFound) {
+		t.Fatalf("FetchOrder(order_missing) err = %v, want ErrNotFound", err)
+	}
+	parent.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("%d spans recorded, want 3", len(spans))
+	}
+	for i, span := range spans[:2] {
+		if span.Name != "razorpay order.fetch" {
+			t.Errorf("span %d is %q, want razorpay order.fetch", i, span.Name)
+		}
+		// The SDK takes no context, so the span is all that ties the call
+		// to the request
+		if span.Parent.SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("span %d is not a child of the request span", i)
+		}
+	}
+	if spans[0].Status.Code != codes.Unset {
+		t.Errorf("successful call has status %v", spans[0].Status)
+	}
+	if spans[1].Status.Code != codes.Error {
+		t.Errorf("failed call has status %v, want error", spans[1].Status)
+	}
+}
@@ -0,0 +1,15 @@
+package razorpay
+
+import "context"
+
+// CapturePayment captures amount (in the smallest currency unit) of an
+// authorized payment. Razorpay refunds any uncaptured remainder.
+func CapturePayment(ctx context.Context, paymentID string, amount int64, currency string) (PaymentEntity, error) {
+	var payment PaymentEntity
+	err := call(ctx, "payment.capture", &payment, func() (map[string]interface{}, error) {
+		return Client.Payment.Capture(paymentID, int(amount), map[string]interface{}{
+			"currency": currency,
+		}, nil)
+	})
+	return payment, err
+}
@@ -0,0 +1,109 @@
+package razorpay
+
+import "context"
+
+// PlanEntity is a Razorpay plan. Plans cannot be changed once created.
+type PlanEntity struct {
+	ID       string `json:"id"`
+	Period   string `json:"period"` // daily, weekly, monthly or yearly
+	Interval int    `json:"interval"`
+	Item     struct {
+		Name        string `json:"name"`
+		Amount      int64  `json:"amount"`
+		Currency    string `json:"currency"`
+		Description string `json:"description"`
+	} `json:"item"`
+	CreatedAt int64 `json:"created_at"`
+}
+
+// SubscriptionEntity is a Razorpay subscription. Times are Unix seconds and
+// absent until they apply.
+type SubscriptionEntity struct {
+	ID           string         `json:"id"`
+	PlanID       string         `json:"plan_id"`
+	Status       string         `json:"status"`
+	Quantity     int            `json:"quantity"`
+	TotalCount   int            `json:"total_count"`
+	PaidCount    int            `json:"paid_count"`
+	CurrentStart *int64         `json:"current_start"`
+	CurrentEnd   *int64         `json:"current_end"`
+	ChargeAt     *int64         `json:"charge_at"`
+	StartAt      *int64         `json:"start_at"`
+	EndedAt      *int64         `json:"ended_at"`
+	ShortURL     string         `json:"short_url"`
+	Notes        map[string]any `json:"notes"`
+	CreatedAt    int64          `json:"created_at"`
+}
+
+// CreatePlan creates a plan charging amount (in the smallest currency unit)
+// every interval periods.
+func CreatePlan(ctx context.Context, period string, interval int, name, description string, amount int64, currency string, notes map[string]interface{}) (PlanEntity, error) {
+	var plan PlanEntity
+	err := call(ctx, "plan.create", &plan, func() (map[string]interface{}, error) {
+		return Client.Plan.Create(map[string]interface{}{
+			"period":   period,
+			"interval": interval,
+			"item": map[string]interface{}{
+				"name":        name,
+				"amount":      amount,
+				"currency":    currency,
+				"description": description,
+			},
+			"notes": notes,
+		}, nil)
+	})
+	return plan, err
+}
+
+// CreateSubscription subscribes to planID for totalCount billing cycles.
+// A non-zero startAt (Unix seconds) delays the first charge, which is how
+// trial periods are expressed.
+func CreateSubscription(ctx context.Context, planID string, totalCount, quantity int, startAt int64, notes map[string]interface{}) (SubscriptionEntity, error) {
+	data := map[string]interface{}{
+		"plan_id":         planID,
+		"total_count":     totalCount,
+		"quantity":        quantity,
+		"customer_notify": 1,
+		"notes":           notes,
+	}
+	if startAt > 0 {
+		data["start_at"] = startAt
+	}
+	var sub SubscriptionEntity
+	err := call(ctx, "subscription.create", &sub, func() (map[string]interface{}, error) {
+		return Client.Subscription.Create(data, nil)
+	})
+	return sub, err
+}
+
+// PauseSubscription pauses an active subscription now.
+func PauseSubscription(ctx context.Context, subscriptionID string) (SubscriptionEntity, error) {
+	var sub SubscriptionEntity
+	err := call(ctx, "subscription.pause", &sub, func() (map[string]interface{}, error) {
+		return Client.Subscription.Pause(subscriptionID, map[string]interface{}{"pause_at": "now"}, nil)
+	})
+	return sub, err
+}
+
+// ResumeSubscription resumes a paused subscription now.
+func ResumeSubscription(ctx context.Context, subscriptionID string) (SubscriptionEntity, error) {
+	var sub SubscriptionEntity
+	err := call(ctx, "subscription.resume", &sub, func() (map[string]interface{}, error) {
+		return Client.Subscription.Resume(subscriptionID, map[string]interface{}{"resume_at": "now"}, nil)
+	})
+	return sub, err
+}
+
+// CancelSubscription cancels a subscription, at the end of the current
+// billing cycle when atCycleEnd is set.
+func CancelSubscription(ctx context.Context, subscriptionID string, atCycleEnd bool) (SubscriptionEntity, error) {
+	flag := 0
+	if atCycleEnd {
+		flag = 1
+	}
+	var sub SubscriptionEntity
+	err := call(ctx, "subscription.cancel", &sub, func() (map[string]interface{}, error) {
+		return Client.Subscription.Cancel(subscriptionID, map[string]interface{}{"cancel_at_cycle_end": flag}, nil)
+	})
+	return sub, err
+}
@@ -0,0 +1,91 @@
+package razorpay
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// WebhookEvent is the envelope Razorpay POSTs to webhook endpoints. Only
+// the fields the service acts on are decoded.
+type WebhookEvent struct {
+	Event     string `json:"event"`
+	AccountID string `json:"account_id"`
+	CreatedAt int64  `json:"created_at"`
+	Payload   struct {
+		Payment *struct {
+			Entity PaymentEntity `json:"entity"`
+		} `json:"payment,omitempty"`
+		Refund *struct {
+			Entity RefundEntity `json:"entity"`
+		} `json:"refund,omitempty"`
+		Subscription *struct {
+			Entity SubscriptionEntity `json:"entity"`
+		} `json:"subscription,omitempty"`
+		Dispute *struct {
+			Entity DisputeEntity `json:"entity"`
+		} `json:"dispute,omitempty"`
+	} `json:"payload"`
+}
+
+// PaymentEntity is a Razorpay payment. Amounts are in the smallest currency
+// unit (paise for INR).
+type PaymentEntity struct {
+	ID               string `json:"id"`
+	OrderID          string `json:"order_id"`
+	InvoiceID        string `json:"invoice_id"` // subscription charges only
+	Amount           int64  `json:"amount"`
+	Currency         string `json:"currency"`
+	Status           string `json:"status"`
+	AmountRefunded   int64  `json:"amount_refunded"`
+	RefundStatus     string `json:"refund_status"`
+	ErrorCode        string `json:"error_code"`
+	ErrorDescription string `json:"error_description"`
+	CreatedAt        int64  `json:"created_at"`
+}
+
+// RefundEntity is a Razorpay refund.
+type RefundEntity struct {
+	ID        string `json:"id"`
+	PaymentID string `json:"payment_id"`
+	Amount    int64  `json:"amount"`
+	Status    string `json:"status"`
+}
+
+// DisputeEntity is a cardholder's dispute of a payment. Each phase Razorpay
+// escalates a dispute to (chargeback, pre_arbitration, arbitration) is a
+// new dispute.
+type DisputeEntity struct {
+	ID                string `json:"id"`
+	PaymentID         string `json:"payment_id"`
+	Amount            int64  `json:"amount"`
+	Currency          string `json:"currency"`
+	AmountDeducted    int64  `json:"amount_deducted"`
+	ReasonCode        string `json:"reason_code"`
+	ReasonDescription string `json:"reason_description"`
+	// RespondBy is the Unix time evidence is due by.
+	RespondBy int64 `json:"respond_by"`
+	// Status is open, under_review, won, lost or closed.
+	Status    string `json:"status"`
+	Phase     string `json:"phase"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+// VerifyWebhookSignature checks the X-Razorpay-Signature header: the hex
+// HMAC-SHA256 of the raw request body keyed with the webhook secret.
+func VerifyWebhookSignature(body []byte, signature, secret string) bool {
+	if secret == "" || signature == "" {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	expected := hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// VerifyPaymentSignature checks the razorpay_signature Checkout returns to
+// the client: the hex HMAC-SHA256 of "<order_id>|<payment_id>" keyed with
+// the API key secret.
+func VerifyPaymentSignature(orderID, paymentID, signature, keySecret string) bool {
+	return VerifyWebhookSignature([]byte(orderID+"|"+paymentID), signature, keySecret)
+}
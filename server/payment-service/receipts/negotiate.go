@@ -0,0 +1,63 @@
+package receipts
+
+import (
+	"strconv"
+	"strings"
+)
+
+// formats lists what Negotiate offers, most preferred first. PDF wins ties
+// because a receipt is usually downloaded.
+var formats = []Format{FormatPDF, FormatHTML}
+
+// Negotiate picks the format an Accept header prefers, honouring q-values
+// and the most specific matching media range. An empty header accepts
+// anything; ok is false when nothing offered is acceptable.
+func Negotiate(accept string) (f Format, ok bool) {
+	if strings.TrimSpace(accept) == "" {
+		return formats[0], true
+	}
+
+	best := 0.0
+	for _, offer := range formats {
+		if q := quality(accept, string(offer)); q > best {
+			f, best = offer, q
+		}
+	}
+	return f, best > 0
+}
+
+// quality returns the q-value accept gives mediaType, taken from the most
+// specific media range that matches it.
+func quality(accept, mediaType string) float64 {
+	typ, _, _ := strings.Cut(mediaType, "/")
+	q, specificity := 0.0, -1
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		rng := strings.ToLower(strings.TrimSpace(params[0]))
+
+		s := -1
+		switch rng {
+		case mediaType:
+			s = 2
+		case typ + "/*":
+			s = 1
+		case "*/*":
+			s = 0
+		}
+		if s <= specificity {
+			continue
+		}
+
+		rq := 1.0
+		for _, p := range params[1:] {
+			k, v, _ := strings.Cut(strings.TrimSpace(p), "=")
+			if strings.EqualFold(k, "q") {
+				if parsed, err := strconv.ParseFloat(v, 64); err == nil && parsed >= 0 && parsed <= 1 {
+					rq = parsed
+				}
+			}
+		}
+		q, specificity = rq, s
+	}
+	return q
+}
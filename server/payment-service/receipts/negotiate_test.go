@@ -0,0 +1,32 @@
+package receipts
+
+import "testing"
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   Format
+		ok     bool
+	}{
+		{"", FormatPDF, true},
+		{"*/*", FormatPDF, true},
+		{"application/pdf", FormatPDF, true},
+		{"text/html", FormatHTML, true},
+		{"text/*", FormatHTML, true},
+		{"Text/HTML", FormatHTML, true},
+		{"text/html, application/pdf", FormatPDF, true},
+		{"text/html;q=0.9, application/pdf;q=0.8", FormatHTML, true},
+		{"application/pdf;q=0, */*", FormatHTML, true},
+		// The most specific range decides, whatever the order
+		{"text/html;q=0.1, text/*;q=1, application/pdf;q=0.5", FormatPDF, true},
+		{"application/json", "", false},
+		{"text/html;q=0, application/pdf;q=0", "", false},
+		{"application/pdf;q=high", FormatPDF, true},
+	}
+	for _, tt := range tests {
+		got, ok := Negotiate(tt.accept)
+		if ok != tt.ok || ok && got != tt.want {
+			t.Errorf("Negotiate(%q) = %q, %t; want %q, %t", tt.accept, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package receipts
+
+import (
+	"io"
+	"strings"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/pdf"
+)
+
+// Page layout in points.
+const (
+	marginLeft   = 50.0
+	marginRight  = pdf.PageWidth - 50
+	marginTop    = 70.0
+	marginBottom = pdf.PageHeight - 60
+	valueLeft    = 210.0
+)
+
+// renderPDF lays out the output of the PDF template, one element per line:
+//
+//	# text          title
+//	## text         section heading
+//	label | value   row with the value in a second column
+//	---             horizontal rule
+//	(empty line)    vertical space
+//	anything else   paragraph
+func renderPDF(w io.Writer, title, layout string) error {
+	doc := pdf.New(title)
+	y := marginTop
+	ensure := func(height float64) {
+		if y+height > marginBottom {
+			doc.AddPage()
+			y = marginTop
+		}
+	}
+
+	for _, line := range strings.Split(layout, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		switch {
+		case strings.TrimSpace(line) == "":
+			y += 8
+		case line == "---":
+			ensure(12)
+			doc.Line(marginLeft, y-4, marginRight, y-4, 0.5)
+			y += 8
+		case strings.HasPrefix(line, "## "):
+			ensure(30)
+			y += 6
+			doc.Text(marginLeft, y, pdf.Bold, 11, strings.TrimPrefix(line, "## "))
+			doc.Line(marginLeft, y+5, marginRight, y+5, 0.5)
+			y += 20
+		case strings.HasPrefix(line, "# "):
+			ensure(30)
+			doc.Text(marginLeft, y, pdf.Bold, 20, strings.TrimPrefix(line, "# "))
+			y += 28
+		case strings.Contains(line, " | "):
+			label, value, _ := strings.Cut(line, " | ")
+			lines := pdf.Wrap(pdf.Regular, 10, value, marginRight-valueLeft)
+			ensure(float64(len(lines)) * 14)
+			doc.Text(marginLeft, y, pdf.Bold, 10, strings.TrimSpace(label))
+			for _, l := range lines {
+				doc.Text(valueLeft, y, pdf.Regular, 10, l)
+				y += 14
+			}
+		default:
+			for _, l := range pdf.Wrap(pdf.Regular, 9, strings.TrimSpace(line), marginRight-marginLeft) {
+				ensure(12)
+				doc.Text(marginLeft, y, pdf.Regular, 9, l)
+				y += 12
+			}
+		}
+	}
+	return doc.Write(w)
+}
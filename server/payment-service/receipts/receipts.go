@@ -0,0 +1,209 @@
+// Package receipts renders payment receipts as HTML or PDF from templates.
+// Built-in templates are embedded; a directory holding receipt.html and/or
+// receipt.pdf.tmpl replaces them file by file.
+package receipts
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/currencies"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/models"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/users"
+)
+
+//go:embed templates/*
+var builtin embed.FS
+
+// Template file names, in the embedded set and in an override directory.
+const (
+	HTMLTemplate = "receipt.html"
+	PDFTemplate  = "receipt.pdf.tmpl"
+)
+
+// Format is a representation a receipt can be rendered in.
+type Format string
+
+const (
+	FormatPDF  Format = "application/pdf"
+	FormatHTML Format = "text/html"
+)
+
+// ContentType returns the Content-Type header for f.
+func (f Format) ContentType() string {
+	if f == FormatHTML {
+		return "text/html; charset=utf-8"
+	}
+	return string(f)
+}
+
+// Available reports whether a payment in status has a receipt: it must have
+// been paid, even if it was refunded or charged back since.
+func Available(status models.PaymentStatus) bool {
+	switch status {
+	case models.PaymentStatusCaptured, models.PaymentStatusCompleted, models.PaymentStatusRefunded,
+		models.PaymentStatusChargedBack:
+		return true
+	}
+	return false
+}
+
+// Payer identifies who made or received the payment.
+type Payer struct {
+	Name  string
+	Email string
+}
+
+// Receipt is the data the templates are executed with. Strings are single
+// lines and times are in the payer's time zone.
+type Receipt struct {
+	PaymentID   int
+	Status      string
+	Currency    string
+	Description string
+
+	// Amount is what was captured. AuthorizedAmount is set only when a
+	// partial capture took less than was authorized.
+	Amount           string
+	AuthorizedAmount string
+
+	// Account identifiers with all but the last four characters masked
+	FromAccount string
+	ToAccount   string
+
+	RazorpayOrderID   string
+	RazorpayPaymentID string
+
+	Payer Payer
+	// Payee is set for a payment received through a link: the receipt's
+	// user was paid by Payer, who gave only what the link asked for.
+	Payee    *Payer
+	Timezone string
+
+	CreatedAt    time.Time
+	AuthorizedAt *time.Time
+	UpdatedAt    *time.Time
+	GeneratedAt  time.Time
+}
+
+// New builds the receipt for p, whose currency is cur, for owner, the user
+// p belongs to, as generated at now. owner paid an outgoing payment; an
+// incoming one was paid to owner by linkPayer.
+func New(p models.Payment, cur currencies.Currency, owner users.User, linkPayer Payer, now time.Time) Receipt {
+	loc := owner.Location()
+	in := func(t *time.Time) *time.Time {
+		if t == nil {
+			return nil
+		}
+		local := t.In(loc)
+		return &local
+	}
+
+	rc := Receipt{
+		PaymentID:       p.ID,
+		Status:          p.Status,
+		Currency:        p.Currency,
+		Amount:          cur.Format(cur.ToMinor(p.Amount)),
+		FromAccount:     models.MaskAccount(p.FromAccount),
+		ToAccount:       models.MaskAccount(p.ToAccount),
+		RazorpayOrderID: p.RazorpayOrderID,
+		Payer:           Payer{Name: oneLine(owner.Name), Email: owner.Email},
+		Timezone:        loc.String(),
+		CreatedAt:       p.CreatedAt.In(loc),
+		AuthorizedAt:    in(p.AuthorizedAt),
+		UpdatedAt:       in(p.UpdatedAt),
+		GeneratedAt:     now.In(loc),
+	}
+	if p.Incoming() {
+		payee := rc.Payer
+		rc.Payee = &payee
+		rc.Payer = Payer{Name: oneLine(linkPayer.Name), Email: linkPayer.Email}
+	}
+	if p.CapturedAmount != nil && *p.CapturedAmount != p.Amount {
+		rc.AuthorizedAmount = rc.Amount
+		rc.Amount = cur.Format(cur.ToMinor(*p.CapturedAmount))
+	}
+	if p.Description != nil {
+		rc.Description = oneLine(*p.Description)
+	}
+	if p.RazorpayPaymentID != nil {
+		rc.RazorpayPaymentID = *p.RazorpayPaymentID
+	}
+	return rc
+}
+
+// Templates holds the parsed receipt templates.
+type Templates struct {
+	html *htmltemplate.Template
+	pdf  *texttemplate.Template
+}
+
+// funcs are available to both templates.
+var funcs = map[string]any{
+	"datetime": func(t time.Time) string { return t.Format("02 Jan 2006, 15:04 MST") },
+	"date":     func(t time.Time) string { return t.Format("02 Jan 2006") },
+	"upper":    strings.ToUpper,
+}
+
+// Load parses the templates, taking each from dir when it has one and
+// from the built-in set otherwise. dir may be empty.
+func Load(dir string) (*Templates, error) {
+	htmlSrc, err := source(dir, HTMLTemplate)
+	if err != nil {
+		return nil, err
+	}
+	pdfSrc, err := source(dir, PDFTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Templates{}
+	if t.html, err = htmltemplate.New(HTMLTemplate).Funcs(funcs).Parse(htmlSrc); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", HTMLTemplate, err)
+	}
+	if t.pdf, err = texttemplate.New(PDFTemplate).Funcs(funcs).Parse(pdfSrc); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", PDFTemplate, err)
+	}
+	return t, nil
+}
+
+func source(dir, name string) (string, error) {
+	if dir != "" {
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if err == nil {
+			return string(b), nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+	}
+	b, err := builtin.ReadFile("templates/" + name)
+	return string(b), err
+}
+
+// Render writes rc to w in format f.
+func (t *Templates) Render(w io.Writer, f Format, rc Receipt) error {
+	if f == FormatHTML {
+		return t.html.Execute(w, rc)
+	}
+
+	var layout bytes.Buffer
+	if err := t.pdf.Execute(&layout, rc); err != nil {
+		return err
+	}
+	return renderPDF(w, fmt.Sprintf("Receipt for payment %d", rc.PaymentID), layout.String())
+}
+
+// oneLine collapses runs of whitespace, including newlines, to one space.
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
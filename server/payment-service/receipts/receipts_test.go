@@ -0,0 +1,185 @@
+package receipts
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/currencies"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/models"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/users"
+)
+
+var inr = currencies.Currency{Code: "INR", Exponent: 2}
+
+func payment() models.Payment {
+	desc := "Rent\nfor   June"
+	paymentID := "pay_123"
+	return models.Payment{
+		ID:                42,
+		UserID:            7,
+		Amount:            12500.5,
+		Currency:          "INR",
+		FromAccount:       "50100123456789",
+		ToAccount:         "tenant@okbank",
+		RazorpayOrderID:   "order_123",
+		RazorpayPaymentID: &paymentID,
+		Status:            string(models.PaymentStatusCaptured),
+		CreatedAt:         time.Date(2026, 6, 1, 18, 45, 0, 0, time.UTC),
+		Description:       &desc,
+		Direction:         string(models.DirectionOutgoing),
+	}
+}
+
+func TestNew(t *testing.T) {
+	owner := users.User{ID: 7, Name: "Ravi  Kumar", Email: "ravi@example.com", Timezone: "Asia/Kolkata"}
+	linkPayer := Payer{Name: "Asha\nRao", Email: "asha@example.com"}
+	now := time.Date(2026, 6, 2, 0, 0, 0, 0, time.UTC)
+
+	partial := payment()
+	captured := 10000.0
+	partial.CapturedAmount = &captured
+
+	incoming := payment()
+	incoming.Direction = string(models.DirectionIncoming)
+
+	tests := []struct {
+		name       string
+		p          models.Payment
+		owner      users.User
+		amount     string
+		authorized string
+		payer      Payer
+		payee      *Payer
+		created    string
+	}{
+		{"outgoing", payment(), owner, "12,500.50", "", Payer{"Ravi Kumar", "ravi@example.com"}, nil, "2026-06-02 00:15 IST"},
+		{"partial capture", partial, owner, "10,000.00", "12,500.50", Payer{"Ravi Kumar", "ravi@example.com"}, nil, "2026-06-02 00:15 IST"},
+		{"incoming", incoming, owner, "12,500.50", "", Payer{"Asha Rao", "asha@example.com"}, &Payer{"Ravi Kumar", "ravi@example.com"}, "2026-06-02 00:15 IST"},
+		{"unknown time zone", payment(), users.User{Name: "Ravi", Timezone: "Mars/Olympus"}, "12,500.50", "", Payer{Name: "Ravi"}, nil, "2026-06-01 18:45 UTC"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := New(tt.p, inr, tt.owner, linkPayer, now)
+			if rc.Amount != tt.amount || rc.AuthorizedAmount != tt.authorized {
+				t.Errorf("amount %q authorized %q, want %q and %q", rc.Amount, rc.AuthorizedAmount, tt.amount, tt.authorized)
+			}
+			if rc.Payer != tt.payer {
+				t.Errorf("payer %+v, want %+v", rc.Payer, tt.payer)
+			}
+			if (rc.Payee == nil) != (tt.payee == nil) || rc.Payee != nil && *rc.Payee != *tt.payee {
+				t.Errorf("payee %+v, want %+v", rc.Payee, tt.payee)
+			}
+			if got := rc.CreatedAt.Format("2006-01-02 15:04 MST"); got != tt.created {
+				t.Errorf("created %s, want %s", got, tt.created)
+			}
+			if rc.FromAccount != "**********6789" || rc.ToAccount != "*********bank" {
+				t.Errorf("accounts %q → %q are not masked", rc.FromAccount, rc.ToAccount)
+			}
+			if rc.Description != "Rent for June" || rc.RazorpayPaymentID != "pay_123" || rc.PaymentID != 42 {
+				t.Errorf("receipt %+v", rc)
+			}
+		})
+	}
+}
+
+func TestAvailable(t *testing.T) {
+	for status, want := range map[models.PaymentStatus]bool{
+		models.PaymentStatusCaptured:      true,
+		models.PaymentStatusCompleted:     true,
+		models.PaymentStatusRefunded:      true,
+		models.PaymentStatusChargedBack:   true,
+		models.PaymentStatusCreated:       false,
+		models.PaymentStatusAuthorized:    false,
+		models.PaymentStatusVoided:        false,
+		models.PaymentStatusFailed:        false,
+		models.PaymentStatusExpired:       false,
+		models.PaymentStatusPendingReview: false,
+	} {
+		if got := Available(status); got != want {
+			t.Errorf("Available(%s) = %t, want %t", status, got, want)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	templates, err := Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	owner := users.User{Name: "Ravi <b>Kumar</b>", Email: "ravi@example.com"}
+	now := time.Date(2026, 6, 2, 0, 0, 0, 0, time.UTC)
+
+	incoming := payment()
+	incoming.Direction = string(models.DirectionIncoming)
+	tests := []struct {
+		name    string
+		p       models.Payment
+		want    []string
+		notWant []string
+	}{
+		{"outgoing", payment(), []string{"Paid by", "Ravi &lt;b&gt;Kumar&lt;/b&gt;", "12,500.50", "Rent for June"}, []string{"Paid to", "<b>Kumar"}},
+		{"incoming", incoming, []string{"Paid by", "Asha Rao", "Paid to", "Ravi &lt;b&gt;Kumar&lt;/b&gt;"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := New(tt.p, inr, owner, Payer{Name: "Asha Rao"}, now)
+			var html bytes.Buffer
+			if err := templates.Render(&html, FormatHTML, rc); err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range tt.want {
+				if !strings.Contains(html.String(), s) {
+					t.Errorf("HTML lacks %q", s)
+				}
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(html.String(), s) {
+					t.Errorf("HTML contains %q", s)
+				}
+			}
+
+			var pdf bytes.Buffer
+			if err := templates.Render(&pdf, FormatPDF, rc); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.HasPrefix(pdf.Bytes(), []byte("%PDF-")) {
+				t.Errorf("PDF starts %q", pdf.Bytes()[:min(pdf.Len(), 16)])
+			}
+		})
+	}
+}
+
+func TestLoadOverride(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, HTMLTemplate), []byte(`Receipt {{.PaymentID}} for {{.Payer.Name}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	templates, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc := New(payment(), inr, users.User{Name: "Ravi"}, Payer{}, time.Now())
+	var html bytes.Buffer
+	if err := templates.Render(&html, FormatHTML, rc); err != nil {
+		t.Fatal(err)
+	}
+	if html.String() != "Receipt 42 for Ravi" {
+		t.Errorf("override rendered %q", html.String())
+	}
+	// The PDF template was not overridden and still renders
+	var pdf bytes.Buffer
+	if err := templates.Render(&pdf, FormatPDF, rc); err != nil || !bytes.HasPrefix(pdf.Bytes(), []byte("%PDF-")) {
+		t.Errorf("built-in PDF template: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, PDFTemplate), []byte(`{{.Missing`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(dir); err == nil || !strings.Contains(err.Error(), PDFTemplate) {
+		t.Errorf("broken override: err = %v, want a parse error naming %s", err, PDFTemplate)
+	}
+}
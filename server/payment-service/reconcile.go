@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/handlers"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/internal/config"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/reconcile"
+)
+
+const reconcileUsage = `usage: payment-service reconcile <command>
+
+commands:
+  run [flags]            reconcile payments against Razorpay now
+      -dry-run           report what would be corrected without changing or storing anything
+      -lookback <dur>    oldest payments to check (default RECONCILE_LOOKBACK)
+      -min-age <dur>     youngest payments to check (default RECONCILE_MIN_AGE)
+      -json              print the report as JSON
+  report [id|latest]     print a stored report (default latest)
+      -json              print the report as JSON`
+
+// runReconcile implements the `reconcile` subcommand.
+func runReconcile(ctx context.Context, database *sql.DB, cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New(reconcileUsage)
+	}
+
+	fs := flag.NewFlagSet("reconcile "+args[0], flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	asJSON := fs.Bool("json", false, "")
+
+	switch args[0] {
+	case "run":
+		dryRun := fs.Bool("dry-run", false, "")
+		lookback := fs.Duration("lookback", cfg.ReconcileLookback, "")
+		minAge := fs.Duration("min-age", cfg.ReconcileMinAge, "")
+		if err := fs.Parse(args[1:]); err != nil || fs.NArg() > 0 {
+			return errors.New(reconcileUsage)
+		}
+		if *minAge <= 0 || *lookback <= *minAge {
+			return errors.New("-lookback must be longer than -min-age, and -min-age positive")
+		}
+
+		rec := reconcile.New(database, reconcile.Options{
+			MinAge:    *minAge,
+			Lookback:  *lookback,
+			BatchSize: cfg.ReconcileBatchSize,
+			DryRun:    *dryRun,
+		})
+		rep, err := rec.RunOnce(ctx, reconcile.TriggerCLI)
+		if rep.StartedAt.IsZero() {
+			return err // the run never started, e.g. reconcile.ErrBusy
+		}
+		// A failed run still has a partial report worth printing
+		if printErr := printReport(rep, *asJSON); printErr != nil {
+			return errors.Join(err, printErr)
+		}
+		return err
+
+	case "report":
+		if err := fs.Parse(args[1:]); err != nil || fs.NArg() > 1 {
+			return errors.New(reconcileUsage)
+		}
+		var rep reconcile.Report
+		var err error
+		if which := fs.Arg(0); which == "" || which == "latest" {
+			rep, err = reconcile.LatestReport(ctx, database)
+		} else {
+			id, parseErr := strconv.ParseInt(which, 10, 64)
+			if parseErr != nil {
+				return fmt.Errorf("invalid run ID %q", which)
+			}
+			rep, err = reconcile.GetReport(ctx, database, id)
+		}
+		if err != nil {
+			return err
+		}
+		return printReport(rep, *asJSON)
+
+	default:
+		return errors.New(reconcileUsage)
+	}
+}
+
+func printReport(rep reconcile.Report, asJSON bool) error {
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(handlers.ReportResponse(rep))
+	}
+
+	run := "dry run"
+	if rep.ID != 0 {
+		run = "run " + strconv.FormatInt(rep.ID, 10)
+	}
+	fmt.Printf("Reconciliation %s (%s, %s): window %s to %s\n", run, rep.Trigger, rep.Status,
+		rep.WindowStart.Format("2006-01-02 15:04 MST"), rep.WindowEnd.Format("2006-01-02 15:04 MST"))
+	fmt.Printf("scanned %d, corrected %d, discrepancies %d, errors %d\n",
+		rep.Scanned, rep.Corrected, rep.Discrepancies, rep.Errors)
+	if rep.Error != "" {
+		fmt.Printf("error: %s\n", rep.Error)
+	}
+	if len(rep.Items) == 0 {
+		return nil
+	}
+
+	fmt.Println()
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "KIND\tPAYMENT\tORDER\tLOCAL\tGATEWAY\tDETAILS")
+	for _, d := range rep.Items {
+		payment := "-"
+		if d.PaymentID != nil {
+			payment = strconv.FormatInt(*d.PaymentID, 10)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			d.Kind, payment, orDash(d.RazorpayOrderID), orDash(d.LocalStatus), orDash(d.GatewayStatus), d.Details)
+	}
+	return tw.Flush()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,564 @@
+// Package reconcile compares payments with their Razorpay orders. It moves
+// payments whose webhook was missed to the status Razorpay reports and
+// records what it cannot fix as discrepancies for a human to review.
+package reconcile
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/currencies"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/metrics"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/models"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/payments"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/razorpay"
+	"github.com/lib/pq"
+)
+
+// lockID is the pg_advisory_lock key held for the duration of a run so only
+// one replica reconciles at a time.
+const lockID int64 = 0x6770_6179_0003
+
+// receiptPrefix starts the receipt of every order HandlePayment creates.
+// Other orders on the same Razorpay account are not reported as
+// gateway-only.
+const receiptPrefix = "order_"
+
+// ErrBusy is returned when another run, in this process or another
+// replica, is in progress.
+var ErrBusy = errors.New("a reconciliation run is already in progress")
+
+// Options tunes a Reconciler. Zero values take the defaults noted per field.
+type Options struct {
+	Interval  time.Duration // between scheduled runs; 0 disables scheduling
+	MinAge    time.Duration // 15m; younger payments may still get their webhook
+	Lookback  time.Duration // 72h; older payments are left alone, except voided authorizations awaiting release
+	BatchSize int           // 500 payments per query
+	DryRun    bool          // report corrections without applying or storing them
+}
+
+// Reconciler runs reconciliation passes on a schedule or on demand.
+type Reconciler struct {
+	db   *sql.DB
+	opts Options
+	busy atomic.Bool
+
+	mu      sync.Mutex
+	baseCtx context.Context // set by Run; parents triggered runs
+	runs    sync.WaitGroup
+}
+
+// New creates a reconciler for the payments in db.
+func New(db *sql.DB, opts Options) *Reconciler {
+	if opts.MinAge <= 0 {
+		opts.MinAge = 15 * time.Minute
+	}
+	if opts.Lookback <= opts.MinAge {
+		opts.Lookback = 72 * time.Hour
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 500
+	}
+	return &Reconciler{db: db, opts: opts}
+}
+
+// Run reconciles every Interval until ctx is cancelled, then waits for runs
+// started with Trigger to finish.
+func (r *Reconciler) Run(ctx context.Context) {
+	r.mu.Lock()
+	r.baseCtx = ctx
+	r.mu.Unlock()
+	defer r.runs.Wait()
+
+	if r.opts.Interval <= 0 {
+		slog.Info("Scheduled reconciliation disabled")
+		<-ctx.Done()
+		return
+	}
+
+	slog.Info("Reconciler started", "interval", r.opts.Interval, "min_age", r.opts.MinAge, "lookback", r.opts.Lookback)
+	ticker := time.NewTicker(r.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Reconciler stopped")
+			return
+		case <-ticker.C:
+		}
+
+		_, err := r.RunOnce(ctx, TriggerScheduled)
+		if errors.Is(err, ErrBusy) {
+			slog.Info("Skipping scheduled reconciliation; another run is in progress")
+		} else if err != nil && ctx.Err() == nil {
+			slog.Error("Reconciliation run failed", "error", err)
+		}
+	}
+}
+
+// RunOnce performs a run and returns its report.
+func (r *Reconciler) RunOnce(ctx context.Context, trigger Trigger) (Report, error) {
+	s, err := r.begin(ctx, trigger)
+	if err != nil {
+		return Report{}, err
+	}
+	return r.execute(ctx, s)
+}
+
+// Trigger starts a run in the background and returns its ID. The run
+// outlives the caller's request and is awaited when Run returns.
+func (r *Reconciler) Trigger(trigger Trigger) (int64, error) {
+	r.mu.Lock()
+	ctx := r.baseCtx
+	if ctx == nil || ctx.Err() != nil {
+		r.mu.Unlock()
+		return 0, errors.New("reconciler is not running")
+	}
+	r.runs.Add(1)
+	r.mu.Unlock()
+
+	s, err := r.begin(ctx, trigger)
+	if err != nil {
+		r.runs.Done()
+		return 0, err
+	}
+	go func() {
+		defer r.runs.Done()
+		if _, err := r.execute(ctx, s); err != nil && ctx.Err() == nil {
+			slog.Error("Reconciliation run failed", "run_id", s.report.ID, "error", err)
+		}
+	}()
+	return s.report.ID, nil
+}
+
+// session is a run that holds the reconciliation lock.
+type session struct {
+	conn   *sql.Conn
+	report Report
+}
+
+// begin takes the lock and records the run so its ID is known before any
+// work is done.
+func (r *Reconciler) begin(ctx context.Context, trigger Trigger) (*session, error) {
+	if !r.busy.CompareAndSwap(false, true) {
+		return nil, ErrBusy
+	}
+
+	// Session-level advisory locks belong to a connection, so the run keeps
+	// one for its whole duration.
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		r.busy.Store(false)
+		return nil, err
+	}
+	var locked bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", lockID).Scan(&locked); err != nil || !locked {
+		conn.Close()
+		r.busy.Store(false)
+		if err != nil {
+			return nil, fmt.Errorf("acquire reconciliation lock: %w", err)
+		}
+		return nil, ErrBusy
+	}
+
+	now := time.Now().UTC()
+	s := &session{conn: conn, report: Report{Run: Run{
+		Trigger:     trigger,
+		Status:      RunRunning,
+		DryRun:      r.opts.DryRun,
+		WindowStart: now.Add(-r.opts.Lookback),
+		WindowEnd:   now.Add(-r.opts.MinAge),
+		StartedAt:   now,
+	}}}
+	if !r.opts.DryRun {
+		if err := insertRun(ctx, conn, &s.report.Run); err != nil {
+			r.end(s)
+			return nil, fmt.Errorf("record run: %w", err)
+		}
+	}
+	return s, nil
+}
+
+func (r *Reconciler) end(s *session) {
+	s.conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockID)
+	s.conn.Close()
+	r.busy.Store(false)
+}
+
+func (r *Reconciler) execute(ctx context.Context, s *session) (Report, error) {
+	defer r.end(s)
+	start := time.Now()
+	rep := &s.report
+
+	err := r.reconcile(ctx, rep)
+	rep.Status = RunCompleted
+	if err != nil {
+		rep.Status = RunFailed
+		rep.Error = err.Error()
+	}
+
+	if r.opts.DryRun {
+		now := time.Now().UTC()
+		rep.FinishedAt = &now
+	} else {
+		// Record the outcome even when shutdown interrupted the run
+		saveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+		defer cancel()
+		if saveErr := saveReport(saveCtx, s.conn, rep); saveErr != nil {
+			err = errors.Join(err, fmt.Errorf("save report: %w", saveErr))
+		}
+	}
+
+	metrics.ReconcileRun(string(rep.Status), time.Since(start))
+	for _, d := range rep.Items {
+		metrics.ReconcileDiscrepancy(string(d.Kind))
+	}
+	slog.InfoContext(ctx, "Reconciliation run finished",
+		"run_id", rep.ID,
+		"trigger", rep.Trigger,
+		"status", rep.Status,
+		"dry_run", rep.DryRun,
+		"scanned", rep.Scanned,
+		"corrected", rep.Corrected,
+		"discrepancies", rep.Discrepancies,
+		"errors", rep.Errors,
+		"duration", time.Since(start),
+	)
+	return *rep, err
+}
+
+// reconcile checks every non-terminal payment in the run's window, and every
+// voided authorization Razorpay has not released yet, against Razorpay, then
+// looks for orders in the window that have no payment.
+func (r *Reconciler) reconcile(ctx context.Context, rep *Report) error {
+	registry, err := currencies.Load(ctx, r.db)
+	if err != nil {
+		return fmt.Errorf("load currencies: %w", err)
+	}
+
+	checked := make(map[string]bool)
+	afterID := 0
+	for {
+		batch, err := r.pendingBatch(ctx, rep.WindowStart, rep.WindowEnd, afterID)
+		if err != nil {
+			return fmt.Errorf("select pending payments: %w", err)
+		}
+		for _, p := range batch {
+			afterID = p.ID
+			checked[p.RazorpayOrderID] = true
+			rep.Scanned++
+			if err := r.checkPayment(ctx, rep, registry, p); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				// One unreachable order must not stop the rest of the run
+				rep.Errors++
+				slog.WarnContext(ctx, "Reconciliation check failed",
+					"payment_id", p.ID,
+					"order_id", p.RazorpayOrderID,
+					"error", err,
+				)
+			}
+		}
+		if len(batch) < r.opts.BatchSize {
+			break
+		}
+	}
+
+	return r.checkGatewayOrders(ctx, rep, registry, checked)
+}
+
+func (r *Reconciler) pendingBatch(ctx context.Context, from, to time.Time, afterID int) ([]models.Payment, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+payments.Columns+` FROM payments
+		WHERE (status IN ($1, $2, $3) AND created_at >= $4
+		       OR status = $8 AND authorization_released_at IS NULL)
+		  AND created_at < $5
+		  AND id > $6
+		ORDER BY id
+		LIMIT $7`,
+		string(models.PaymentStatusCreated), string(models.PaymentStatusPending), string(models.PaymentStatusAuthorized),
+		from, to, afterID, r.opts.BatchSize, string(models.PaymentStatusVoided))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var batch []models.Payment
+	for rows.Next() {
+		p, err := payments.Scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		batch = append(batch, p)
+	}
+	return batch, rows.Err()
+}
+
+// checkPayment compares one payment with its order and corrects its status
+// when Razorpay has a definite outcome.
+func (r *Reconciler) checkPayment(ctx context.Context, rep *Report, registry currencies.Registry, p models.Payment) error {
+	order, err := razorpay.FetchOrder(ctx, p.RazorpayOrderID)
+	if errors.Is(err, razorpay.ErrNotFound) {
+		rep.add(localDiscrepancy(KindLocalOnly, p, "order not found at Razorpay"))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if d, ok := amountMismatch(registry, p, order); ok {
+		rep.add(d)
+		return nil
+	}
+
+	attempts, err := razorpay.OrderPayments(ctx, order.ID)
+	if err != nil {
+		return err
+	}
+	if p.ReleasePending() {
+		if released, ok := releasedAttempt(attempts); ok {
+			return r.release(ctx, rep, p, released)
+		}
+		return nil // Razorpay still holds the funds
+	}
+	path, gatewayPayment, ok := gatewayOutcome(attempts, p.CaptureMode == string(models.CaptureManual))
+	if !ok || path[len(path)-1] == models.PaymentStatus(p.Status) {
+		return nil // not paid yet, still being processed, or already in step
+	}
+	return r.correct(ctx, rep, p, path, gatewayPayment)
+}
+
+// gatewayOutcome returns the statuses a payment moves through to match its
+// order's payment attempts, and the attempt that decided it. ok is false
+// while the outcome is open. Authorizations only count for manual capture;
+// auto-capture payments are captured moments later.
+func gatewayOutcome(attempts []razorpay.PaymentEntity, manual bool) (path []models.PaymentStatus, decisive razorpay.PaymentEntity, ok bool) {
+	failed := 0
+	var authorized *razorpay.PaymentEntity
+	for _, a := range attempts {
+		switch {
+		case a.RefundStatus == "full":
+			return []models.PaymentStatus{models.PaymentStatusCaptured, models.PaymentStatusRefunded}, a, true
+		case a.Status == "captured" || a.Status == "refunded":
+			// Partial refunds leave the payment captured
+			return []models.PaymentStatus{models.PaymentStatusCaptured}, a, true
+		case a.Status == "authorized" && manual:
+			authorized = &a
+		case a.Status == "failed":
+			failed++
+		}
+	}
+	if authorized != nil {
+		return []models.PaymentStatus{models.PaymentStatusAuthorized}, *authorized, true
+	}
+	if len(attempts) > 0 && failed == len(attempts) {
+		return []models.PaymentStatus{models.PaymentStatusFailed}, attempts[len(attempts)-1], true
+	}
+	return nil, razorpay.PaymentEntity{}, false
+}
+
+// releasedAttempt returns the attempt Razorpay refunded in full, which is
+// how it returns the funds of an authorization that was never captured.
+func releasedAttempt(attempts []razorpay.PaymentEntity) (razorpay.PaymentEntity, bool) {
+	for _, a := range attempts {
+		if a.Status == "refunded" || a.RefundStatus == "full" {
+			return a, true
+		}
+	}
+	return razorpay.PaymentEntity{}, false
+}
+
+// release records that Razorpay released voided payment p's authorization,
+// unless a webhook already did.
+func (r *Reconciler) release(ctx context.Context, rep *Report, p models.Payment, gatewayPayment razorpay.PaymentEntity) error {
+	d := localDiscrepancy(KindStatusCorrected, p, "")
+	d.GatewayStatus = gatewayPayment.Status
+
+	if r.opts.DryRun {
+		d.Details = fmt.Sprintf("dry run: would record the authorization as released (razorpay payment %s)", gatewayPayment.ID)
+		rep.add(d)
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	locked, err := payments.LockByID(ctx, tx, p.ID)
+	if err != nil {
+		return err
+	}
+	changed, err := payments.ReleaseAuthorization(ctx, tx, &locked)
+	if err != nil || !changed {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "Voided authorization release recorded by reconciliation",
+		"payment_id", p.ID,
+		"order_id", p.RazorpayOrderID,
+	)
+	rep.Corrected++
+	d.Details = fmt.Sprintf("authorization released (razorpay payment %s)", gatewayPayment.ID)
+	rep.add(d)
+	return nil
+}
+
+// correct applies path to p unless a webhook changed it since it was read.
+func (r *Reconciler) correct(ctx context.Context, rep *Report, p models.Payment, path []models.PaymentStatus, gatewayPayment razorpay.PaymentEntity) error {
+	target := path[len(path)-1]
+	d := localDiscrepancy(KindStatusCorrected, p, "")
+	d.GatewayStatus = gatewayPayment.Status
+
+	if r.opts.DryRun {
+		d.Details = fmt.Sprintf("dry run: would move to %s (razorpay payment %s)", target, gatewayPayment.ID)
+		rep.add(d)
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	locked, err := payments.LockByID(ctx, tx, p.ID)
+	if err != nil {
+		return err
+	}
+	if locked.Status != p.Status {
+		return nil // a webhook arrived meanwhile
+	}
+	for _, to := range path {
+		if _, err := payments.Transition(ctx, tx, &locked, to, gatewayPayment.ID); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "Payment status corrected by reconciliation",
+		"payment_id", p.ID,
+		"order_id", p.RazorpayOrderID,
+		"from", p.Status,
+		"to", locked.Status,
+	)
+	rep.Corrected++
+	d.Details = fmt.Sprintf("moved from %s to %s (razorpay payment %s)", p.Status, locked.Status, gatewayPayment.ID)
+	rep.add(d)
+	return nil
+}
+
+// checkGatewayOrders reports orders in the window with no payment row, and
+// amount mismatches on payments the first pass did not look at.
+func (r *Reconciler) checkGatewayOrders(ctx context.Context, rep *Report, registry currencies.Registry, checked map[string]bool) error {
+	orders, err := razorpay.ListOrders(ctx, rep.WindowStart, rep.WindowEnd)
+	if err != nil {
+		return fmt.Errorf("list Razorpay orders: %w", err)
+	}
+
+	var ids []string
+	for _, o := range orders {
+		if !checked[o.ID] && strings.HasPrefix(o.Receipt, receiptPrefix) {
+			ids = append(ids, o.ID)
+		}
+	}
+	local, err := r.paymentsByOrderID(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("select payments by order: %w", err)
+	}
+
+	for _, o := range orders {
+		if checked[o.ID] || !strings.HasPrefix(o.Receipt, receiptPrefix) {
+			continue
+		}
+		p, ok := local[o.ID]
+		if !ok {
+			amount := registry.Lookup(o.Currency).FromMinor(o.Amount)
+			rep.add(Discrepancy{
+				Kind:            KindGatewayOnly,
+				RazorpayOrderID: o.ID,
+				GatewayStatus:   o.Status,
+				GatewayAmount:   &amount,
+				Currency:        o.Currency,
+				Details:         "no payment recorded for order " + o.Receipt,
+			})
+			continue
+		}
+		if d, mismatch := amountMismatch(registry, p, o); mismatch {
+			rep.add(d)
+		}
+	}
+	return nil
+}
+
+func (r *Reconciler) paymentsByOrderID(ctx context.Context, orderIDs []string) (map[string]models.Payment, error) {
+	found := make(map[string]models.Payment, len(orderIDs))
+	if len(orderIDs) == 0 {
+		return found, nil
+	}
+
+	rows, err := r.db.QueryContext(ctx, `SELECT `+payments.Columns+` FROM payments
+		WHERE razorpay_order_id = ANY($1)`, pq.Array(orderIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		p, err := payments.Scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		found[p.RazorpayOrderID] = p
+	}
+	return found, rows.Err()
+}
+
+func amountMismatch(registry currencies.Registry, p models.Payment, o razorpay.Order) (Discrepancy, bool) {
+	if registry.Lookup(p.Currency).ToMinor(p.Amount) == o.Amount && strings.EqualFold(p.Currency, o.Currency) {
+		return Discrepancy{}, false
+	}
+	amount := registry.Lookup(o.Currency).FromMinor(o.Amount)
+	d := localDiscrepancy(KindAmountMismatch, p,
+		fmt.Sprintf("local %.2f %s, Razorpay %.2f %s", p.Amount, p.Currency, amount, o.Currency))
+	d.GatewayAmount = &amount
+	d.GatewayStatus = o.Status
+	return d, true
+}
+
+func localDiscrepancy(kind Kind, p models.Payment, details string) Discrepancy {
+	id := int64(p.ID)
+	amount := p.Amount
+	return Discrepancy{
+		Kind:            kind,
+		PaymentID:       &id,
+		RazorpayOrderID: p.RazorpayOrderID,
+		LocalStatus:     p.Status,
+		LocalAmount:     &amount,
+		Currency:        p.Currency,
+		Details:         details,
+	}
+}
+
+func (rep *Report) add(d Discrepancy) {
+	if d.CreatedAt.IsZero() {
+		d.CreatedAt = time.Now().UTC()
+	}
+	rep.Items = append(rep.Items, d)
+	if d.Kind != KindStatusCorrected {
+		rep.Discrepancies++
+	}
+}
@@ -0,0 +1,80 @@
+package reconcile
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/models"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/razorpay"
+)
+
+func attempt(id, status, refundStatus string) razorpay.PaymentEntity {
+	return razorpay.PaymentEntity{ID: id, Status: status, RefundStatus: refundStatus}
+}
+
+func TestGatewayOutcome(t *testing.T) {
+	tests := []struct {
+		name     string
+		attempts []razorpay.PaymentEntity
+		manual   bool
+		path     []models.PaymentStatus
+		decisive string
+	}{
+		{name: "no attempts"},
+		{name: "still processing", attempts: []razorpay.PaymentEntity{attempt("pay_1", "created", "")}},
+		{
+			name:     "captured after a failed attempt",
+			attempts: []razorpay.PaymentEntity{attempt("pay_1", "failed", ""), attempt("pay_2", "captured", "")},
+			path:     []models.PaymentStatus{models.PaymentStatusCaptured},
+			decisive: "pay_2",
+		},
+		{
+			name:     "fully refunded",
+			attempts: []razorpay.PaymentEntity{attempt("pay_1", "refunded", "full")},
+			path:     []models.PaymentStatus{models.PaymentStatusCaptured, models.PaymentStatusRefunded},
+			decisive: "pay_1",
+		},
+		{
+			name:     "partially refunded stays captured",
+			attempts: []razorpay.PaymentEntity{attempt("pay_1", "captured", "partial")},
+			path:     []models.PaymentStatus{models.PaymentStatusCaptured},
+			decisive: "pay_1",
+		},
+		{
+			name:     "every attempt failed",
+			attempts: []razorpay.PaymentEntity{attempt("pay_1", "failed", ""), attempt("pay_2", "failed", "")},
+			path:     []models.PaymentStatus{models.PaymentStatusFailed},
+			decisive: "pay_2",
+		},
+		{
+			name:     "authorized for manual capture",
+			attempts: []razorpay.PaymentEntity{attempt("pay_1", "failed", ""), attempt("pay_2", "authorized", "")},
+			manual:   true,
+			path:     []models.PaymentStatus{models.PaymentStatusAuthorized},
+			decisive: "pay_2",
+		},
+		{
+			name:     "auto capture is about to capture",
+			attempts: []razorpay.PaymentEntity{attempt("pay_1", "authorized", "")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, decisive, ok := gatewayOutcome(tt.attempts, tt.manual)
+			if ok != (tt.path != nil) || !slices.Equal(path, tt.path) || decisive.ID != tt.decisive {
+				t.Errorf("gatewayOutcome = %v via %q (ok %t), want %v via %q", path, decisive.ID, ok, tt.path, tt.decisive)
+			}
+		})
+	}
+}
+
+func TestReleasedAttempt(t *testing.T) {
+	// An authorization Razorpay refunded on its own is reported refunded
+	attempts := []razorpay.PaymentEntity{attempt("pay_1", "failed", ""), attempt("pay_2", "refunded", "full")}
+	if a, ok := releasedAttempt(attempts); !ok || a.ID != "pay_2" {
+		t.Errorf("releasedAttempt = %q (ok %t), want pay_2", a.ID, ok)
+	}
+	if _, ok := releasedAttempt([]razorpay.PaymentEntity{attempt("pay_1", "authorized", "")}); ok {
+		t.Error("a held authorization counted as released")
+	}
+}
@@ -0,0 +1,203 @@
+package reconcile
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNotFound is returned when a run does not exist.
+var ErrNotFound = errors.New("reconciliation run not found")
+
+// Trigger records what started a run.
+type Trigger string
+
+const (
+	TriggerScheduled Trigger = "scheduled"
+	TriggerManual    Trigger = "manual" // admin API
+	TriggerCLI       Trigger = "cli"
+)
+
+// RunStatus is the state of a run.
+type RunStatus string
+
+const (
+	RunRunning   RunStatus = "running"
+	RunCompleted RunStatus = "completed"
+	RunFailed    RunStatus = "failed"
+)
+
+// Kind classifies a discrepancy.
+type Kind string
+
+const (
+	// KindLocalOnly is a payment whose order Razorpay does not know.
+	KindLocalOnly Kind = "local_only"
+	// KindGatewayOnly is a Razorpay order created by this service with no
+	// payment row.
+	KindGatewayOnly Kind = "gateway_only"
+	// KindAmountMismatch is a payment whose amount or currency differs from
+	// its order. Such payments are never corrected automatically.
+	KindAmountMismatch Kind = "amount_mismatch"
+	// KindStatusCorrected is a payment the run moved to the gateway's
+	// status, or a voided authorization it found released.
+	KindStatusCorrected Kind = "status_corrected"
+)
+
+// Run summarises one reconciliation pass.
+type Run struct {
+	ID            int64
+	Trigger       Trigger
+	Status        RunStatus
+	DryRun        bool
+	WindowStart   time.Time
+	WindowEnd     time.Time
+	Scanned       int
+	Corrected     int
+	Discrepancies int
+	Errors        int
+	Error         string
+	StartedAt     time.Time
+	FinishedAt    *time.Time
+}
+
+// Discrepancy is one finding of a run. Amounts are in major units.
+type Discrepancy struct {
+	Kind            Kind
+	PaymentID       *int64
+	RazorpayOrderID string
+	LocalStatus     string
+	GatewayStatus   string
+	LocalAmount     *float64
+	GatewayAmount   *float64
+	Currency        string
+	Details         string
+	CreatedAt       time.Time
+}
+
+// Report is a run with its findings.
+type Report struct {
+	Run
+	Items []Discrepancy
+}
+
+func insertRun(ctx context.Context, conn *sql.Conn, run *Run) error {
+	return conn.QueryRowContext(ctx, `INSERT INTO reconciliation_runs (trigger, window_start, window_end)
+		VALUES ($1, $2, $3)
+		RETURNING id, status, started_at`,
+		string(run.Trigger), run.WindowStart, run.WindowEnd,
+	).Scan(&run.ID, &run.Status, &run.StartedAt)
+}
+
+// saveReport stores the outcome of a run and its findings.
+func saveReport(ctx context.Context, conn *sql.Conn, rep *Report) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, d := range rep.Items {
+		_, err := tx.ExecContext(ctx, `INSERT INTO reconciliation_discrepancies
+				(run_id, kind, payment_id, razorpay_order_id, local_status, gateway_status,
+				 local_amount, gateway_amount, currency, details)
+			VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''), $7, $8, NULLIF($9, ''), NULLIF($10, ''))`,
+			rep.ID, string(d.Kind), d.PaymentID, d.RazorpayOrderID, d.LocalStatus, d.GatewayStatus,
+			d.LocalAmount, d.GatewayAmount, d.Currency, d.Details)
+		if err != nil {
+			return fmt.Errorf("insert discrepancy: %w", err)
+		}
+	}
+
+	err = tx.QueryRowContext(ctx, `UPDATE reconciliation_runs SET
+			status = $2, scanned = $3, corrected = $4, discrepancies = $5, errors = $6,
+			error = NULLIF($7, ''), finished_at = NOW()
+		WHERE id = $1
+		RETURNING finished_at`,
+		rep.ID, string(rep.Status), rep.Scanned, rep.Corrected, rep.Discrepancies, rep.Errors, rep.Error,
+	).Scan(&rep.FinishedAt)
+	if err != nil {
+		return fmt.Errorf("update run: %w", err)
+	}
+	return tx.Commit()
+}
+
+const runColumns = `id, trigger, status, window_start, window_end, scanned, corrected,
+	discrepancies, errors, COALESCE(error, ''), started_at, finished_at`
+
+func scanRun(row interface{ Scan(...any) error }) (Run, error) {
+	var r Run
+	err := row.Scan(&r.ID, &r.Trigger, &r.Status, &r.WindowStart, &r.WindowEnd, &r.Scanned, &r.Corrected,
+		&r.Discrepancies, &r.Errors, &r.Error, &r.StartedAt, &r.FinishedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Run{}, ErrNotFound
+	}
+	return r, err
+}
+
+// ListRuns returns the most recent runs, newest first.
+func ListRuns(ctx context.Context, db *sql.DB, limit int) ([]Run, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	rows, err := db.QueryContext(ctx, `SELECT `+runColumns+` FROM reconciliation_runs
+		ORDER BY id DESC LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []Run
+	for rows.Next() {
+		r, err := scanRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, r)
+	}
+	return runs, rows.Err()
+}
+
+// GetReport returns run id with its discrepancies.
+func GetReport(ctx context.Context, db *sql.DB, id int64) (Report, error) {
+	run, err := scanRun(db.QueryRowContext(ctx, `SELECT `+runColumns+` FROM reconciliation_runs
+		WHERE id = $1`, id))
+	if err != nil {
+		return Report{}, err
+	}
+	return withItems(ctx, db, run)
+}
+
+// LatestReport returns the most recent finished run with its discrepancies.
+func LatestReport(ctx context.Context, db *sql.DB) (Report, error) {
+	run, err := scanRun(db.QueryRowContext(ctx, `SELECT `+runColumns+` FROM reconciliation_runs
+		WHERE status <> 'running' ORDER BY id DESC LIMIT 1`))
+	if err != nil {
+		return Report{}, err
+	}
+	return withItems(ctx, db, run)
+}
+
+func withItems(ctx context.Context, db *sql.DB, run Run) (Report, error) {
+	rows, err := db.QueryContext(ctx, `SELECT kind, payment_id, COALESCE(razorpay_order_id, ''),
+			COALESCE(local_status, ''), COALESCE(gateway_status, ''), local_amount, gateway_amount,
+			COALESCE(currency, ''), COALESCE(details, ''), created_at
+		FROM reconciliation_discrepancies WHERE run_id = $1 ORDER BY id`, run.ID)
+	if err != nil {
+		return Report{}, err
+	}
+	defer rows.Close()
+
+	rep := Report{Run: run}
+	for rows.Next() {
+		var d Discrepancy
+		if err := rows.Scan(&d.Kind, &d.PaymentID, &d.RazorpayOrderID, &d.LocalStatus, &d.GatewayStatus,
+			&d.LocalAmount, &d.GatewayAmount, &d.Currency, &d.Details, &d.CreatedAt); err != nil {
+			return Report{}, err
+		}
+		rep.Items = append(rep.Items, d)
+	}
+	return rep, rows.Err()
+}
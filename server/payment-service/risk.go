@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/netip"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/risk"
+)
+
+const riskUsage = `usage: payment-service risk <command>
+
+commands:
+  import-ip-ranges <file>  replace the IP ranges with a CSV file (network,country)
+  country <ip>             print the country the IP ranges place an address in`
+
+// runRisk implements the `risk` subcommand.
+func runRisk(ctx context.Context, database *sql.DB, args []string) error {
+	if len(args) != 2 {
+		return errors.New(riskUsage)
+	}
+
+	switch args[0] {
+	case "import-ip-ranges":
+		n, err := risk.ImportIPRanges(ctx, database, args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("imported %d IP ranges from %s\n", n, args[1])
+		return nil
+
+	case "country":
+		addr, err := netip.ParseAddr(args[1])
+		if err != nil {
+			return fmt.Errorf("%q is not an IP address", args[1])
+		}
+		country, err := risk.Country(ctx, database, addr.Unmap())
+		if err != nil {
+			return err
+		}
+		if country == "" {
+			country = "unknown"
+		}
+		fmt.Println(country)
+		return nil
+
+	default:
+		return errors.New(riskUsage)
+	}
+}
@@ -0,0 +1,145 @@
+package risk
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"strings"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/tracing"
+)
+
+// IPRange maps a network to the country its addresses are in.
+type IPRange struct {
+	Network netip.Prefix
+	Country string // ISO 3166-1 alpha-2
+}
+
+// rangeColumns is the header an IP ranges file must start with.
+var rangeColumns = []string{"network", "country"}
+
+// ParseIPRanges reads ranges in the IP ranges file format: a header row
+// "network,country", then one CIDR network and two-letter country code per
+// row. Blank lines and lines starting with # are ignored. Errors name the
+// offending line.
+func ParseIPRanges(r io.Reader) ([]IPRange, error) {
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	cr.FieldsPerRecord = len(rangeColumns)
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("missing header row")
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i, col := range rangeColumns {
+		if !strings.EqualFold(strings.TrimSpace(header[i]), col) {
+			return nil, fmt.Errorf("header must be %s", strings.Join(rangeColumns, ","))
+		}
+	}
+
+	var ranges []IPRange
+	seen := make(map[netip.Prefix]bool)
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return ranges, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+
+		network, err := netip.ParsePrefix(strings.TrimSpace(record[0]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: network must be in CIDR notation", line)
+		}
+		network = network.Masked()
+		if seen[network] {
+			return nil, fmt.Errorf("line %d: %s is listed twice", line, network)
+		}
+		seen[network] = true
+
+		country := strings.ToUpper(strings.TrimSpace(record[1]))
+		if !validCountry(country) {
+			return nil, fmt.Errorf("line %d: country must be a two-letter code", line)
+		}
+		ranges = append(ranges, IPRange{Network: network, Country: country})
+	}
+}
+
+func validCountry(code string) bool {
+	if len(code) != 2 {
+		return false
+	}
+	for _, c := range code {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// ReplaceIPRanges replaces every stored range with ranges.
+func ReplaceIPRanges(ctx context.Context, db *sql.DB, ranges []IPRange) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM ip_ranges`); err != nil {
+		return err
+	}
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO ip_ranges (network, country) VALUES ($1, $2)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, r := range ranges {
+		if _, err := stmt.ExecContext(ctx, r.Network.String(), r.Country); err != nil {
+			return fmt.Errorf("insert %s: %w", r.Network, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// ImportIPRanges parses the IP ranges file at path and replaces the stored
+// ranges with it, returning how many it holds.
+func ImportIPRanges(ctx context.Context, db *sql.DB, path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	ranges, err := ParseIPRanges(f)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", path, err)
+	}
+	return len(ranges), ReplaceIPRanges(ctx, db, ranges)
+}
+
+// Country returns the country of the most specific stored network
+// containing addr, or "" if none does.
+func Country(ctx context.Context, q queryer, addr netip.Addr) (string, error) {
+	const query = `SELECT country FROM ip_ranges WHERE network >>= $1::INET
+		ORDER BY masklen(network) DESC LIMIT 1`
+
+	var country string
+	spanCtx, span := tracing.StartDBSpan(ctx, "SELECT", "ip_ranges", query)
+	err := q.QueryRowContext(spanCtx, query, addr.String()).Scan(&country)
+	tracing.End(span, err)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return country, err
+}
@@ -0,0 +1,170 @@
+package risk
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/tracing"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrNotFound is returned when no assessment matches a lookup.
+	ErrNotFound = errors.New("risk assessment not found")
+	// ErrDecided is returned when a held payment already has a verdict.
+	ErrDecided = errors.New("payment already reviewed")
+)
+
+// Verdict is an admin's decision on a held payment.
+type Verdict string
+
+const (
+	VerdictApprove Verdict = "approve"
+	VerdictReject  Verdict = "reject"
+)
+
+// Decision records who decided a held payment's fate, and why.
+type Decision struct {
+	ID        int64
+	Verdict   Verdict
+	Reason    string
+	DecidedBy int
+	CreatedAt time.Time
+}
+
+// assessmentColumns is the select list understood by scanAssessment, over
+// risk_assessments a LEFT JOIN risk_decisions d.
+const assessmentColumns = `a.id, a.payment_id, a.user_id, a.amount, a.currency, a.to_account,
+	COALESCE(HOST(a.ip), ''), COALESCE(a.country, ''), a.score, a.outcome, a.rules,
+	COALESCE(a.request_id, ''), a.created_at,
+	d.id, d.decision, d.reason, d.decided_by, d.created_at`
+
+const assessmentFrom = ` FROM risk_assessments a LEFT JOIN risk_decisions d ON d.assessment_id = a.id`
+
+func scanAssessment(row interface{ Scan(...any) error }) (Assessment, error) {
+	var a Assessment
+	var paymentID sql.NullInt64
+	var rules []byte
+	var decisionID, decidedBy sql.NullInt64
+	var verdict, reason sql.NullString
+	var decidedAt sql.NullTime
+	err := row.Scan(&a.ID, &paymentID, &a.UserID, &a.Amount, &a.Currency, &a.ToAccount,
+		&a.IP, &a.Country, &a.Score, &a.Outcome, &rules,
+		&a.RequestID, &a.CreatedAt,
+		&decisionID, &verdict, &reason, &decidedBy, &decidedAt)
+	if err != nil {
+		return Assessment{}, err
+	}
+	if paymentID.Valid {
+		a.PaymentID = &paymentID.Int64
+	}
+	if err := json.Unmarshal(rules, &a.Hits); err != nil {
+		return Assessment{}, fmt.Errorf("decode rules of assessment %d: %w", a.ID, err)
+	}
+	if decisionID.Valid {
+		a.Decision = &Decision{
+			ID:        decisionID.Int64,
+			Verdict:   Verdict(verdict.String),
+			Reason:    reason.String,
+			DecidedBy: int(decidedBy.Int64),
+			CreatedAt: decidedAt.Time,
+		}
+	}
+	return a, nil
+}
+
+// ForPayment returns the assessment of paymentID with its decision, if any.
+func ForPayment(ctx context.Context, q queryer, paymentID int64) (Assessment, error) {
+	query := "SELECT " + assessmentColumns + assessmentFrom + " WHERE a.payment_id = $1"
+
+	spanCtx, span := tracing.StartDBSpan(ctx, "SELECT", "risk_assessments", query)
+	a, err := scanAssessment(q.QueryRowContext(spanCtx, query, paymentID))
+	tracing.End(span, err)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Assessment{}, ErrNotFound
+	}
+	return a, err
+}
+
+// Filter narrows List. Zero fields do not filter.
+type Filter struct {
+	UserID  int
+	Outcome Outcome
+	// Held lists only payments still waiting for review, oldest first;
+	// otherwise assessments are listed newest first.
+	Held  bool
+	Since time.Time
+	Until time.Time
+	Limit int // default 50, at most 100
+}
+
+// List returns the assessments matching f with their decisions.
+func List(ctx context.Context, q queryer, f Filter) ([]Assessment, error) {
+	if f.Limit <= 0 || f.Limit > 100 {
+		f.Limit = 50
+	}
+	order := "a.id DESC"
+	if f.Held {
+		order = "a.id"
+	}
+	var since, until sql.NullTime
+	if !f.Since.IsZero() {
+		since = sql.NullTime{Time: f.Since, Valid: true}
+	}
+	if !f.Until.IsZero() {
+		until = sql.NullTime{Time: f.Until, Valid: true}
+	}
+
+	query := "SELECT " + assessmentColumns + assessmentFrom + `
+		WHERE ($1 = 0 OR a.user_id = $1)
+		  AND ($2 = '' OR a.outcome = $2)
+		  AND (NOT $3 OR EXISTS (SELECT 1 FROM payments p WHERE p.id = a.payment_id AND p.status = 'pending_review'))
+		  AND ($4::TIMESTAMPTZ IS NULL OR a.created_at >= $4)
+		  AND ($5::TIMESTAMPTZ IS NULL OR a.created_at < $5)
+		ORDER BY ` + order + `
+		LIMIT $6`
+
+	spanCtx, span := tracing.StartDBSpan(ctx, "SELECT", "risk_assessments", query)
+	rows, err := q.QueryContext(spanCtx, query, f.UserID, string(f.Outcome), f.Held, since, until, f.Limit)
+	tracing.End(span, err)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var assessments []Assessment
+	for rows.Next() {
+		a, err := scanAssessment(rows)
+		if err != nil {
+			return nil, err
+		}
+		assessments = append(assessments, a)
+	}
+	return assessments, rows.Err()
+}
+
+// Decide records by's verdict on the payment assessment id held, in the
+// transaction that releases or fails the payment. It returns ErrDecided if
+// a verdict was already recorded.
+func Decide(ctx context.Context, tx *sql.Tx, id int64, v Verdict, reason string, by int) (Decision, error) {
+	const query = `INSERT INTO risk_decisions (assessment_id, decision, reason, decided_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`
+
+	d := Decision{Verdict: v, Reason: reason, DecidedBy: by}
+	spanCtx, span := tracing.StartDBSpan(ctx, "INSERT", "risk_decisions", query)
+	err := tx.QueryRowContext(spanCtx, query, id, string(v), reason, by).Scan(&d.ID, &d.CreatedAt)
+	tracing.End(span, err)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return Decision{}, ErrDecided
+	}
+	if err != nil {
+		return Decision{}, fmt.Errorf("insert risk decision: %w", err)
+	}
+	return d, nil
+}
@@ -0,0 +1,398 @@
+// Package risk screens payments before their Razorpay order is created.
+//
+// Each rule that fires adds its score to the payment's total, which decides
+// whether the payment is allowed, held in pending_review for an admin, or
+// blocked; a payment touching anything on the blocklist is blocked whatever
+// its score. Every screening is stored as an assessment, including blocked
+// attempts that never became a payment, and admins' verdicts on held
+// payments are stored as decisions, so each outcome can be explained later.
+package risk
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/blocklist"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/limits"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/tracing"
+)
+
+// ErrBlocked is returned for a payment screening refused.
+var ErrBlocked = errors.New("payment blocked by risk screening")
+
+// Outcome is what screening decided for a payment.
+type Outcome string
+
+const (
+	OutcomeAllow  Outcome = "allow"
+	OutcomeReview Outcome = "review"
+	OutcomeBlock  Outcome = "block"
+)
+
+// Rule names a screening rule.
+type Rule string
+
+const (
+	RuleNewAccountHighValue Rule = "new_account_high_value"
+	RuleManyBeneficiaries   Rule = "many_beneficiaries"
+	RuleNearLimit           Rule = "near_limit"
+	RuleGeoMismatch         Rule = "geo_mismatch"
+	RuleBlocklisted         Rule = "blocklisted_account" // named before it covered emails and IPs
+)
+
+// Scores is what each rule adds to a payment's score when it fires.
+var Scores = map[Rule]int{
+	RuleNewAccountHighValue: 50,
+	RuleManyBeneficiaries:   40,
+	RuleNearLimit:           20,
+	RuleGeoMismatch:         30,
+	RuleBlocklisted:         100,
+}
+
+// homeLookback is how far back the country a user usually pays from is
+// taken from.
+const homeLookback = 90 * 24 * time.Hour
+
+// Options tunes an Engine. Zero values take the defaults noted per field.
+type Options struct {
+	ReviewScore int // 50: held for review from this score
+	BlockScore  int // 100: blocked from this score
+
+	// A payment from an account younger than NewAccountAge (168h) is high
+	// value from HighValue[currency]; currencies not listed never are.
+	NewAccountAge time.Duration
+	HighValue     map[string]float64
+
+	// More than MaxBeneficiaries (5) distinct payees within
+	// BeneficiaryWindow (1h) is suspicious.
+	BeneficiaryWindow time.Duration
+	MaxBeneficiaries  int
+
+	// NearLimitRatio (0.9) is the share of a limit from which a payment
+	// that still fits under it counts as just under the limit.
+	NearLimitRatio float64
+
+	// Blocklist holds the accounts, VPAs, emails and networks a payment
+	// may not involve; nil blocklists nothing.
+	Blocklist *blocklist.Cache
+}
+
+// Engine screens payments against the rules.
+type Engine struct {
+	db   *sql.DB
+	opts Options
+}
+
+// New creates an engine screening payments in db.
+func New(db *sql.DB, opts Options) *Engine {
+	if opts.ReviewScore <= 0 {
+		opts.ReviewScore = 50
+	}
+	if opts.BlockScore <= 0 {
+		opts.BlockScore = 100
+	}
+	if opts.NewAccountAge <= 0 {
+		opts.NewAccountAge = 7 * 24 * time.Hour
+	}
+	if opts.BeneficiaryWindow <= 0 {
+		opts.BeneficiaryWindow = time.Hour
+	}
+	if opts.MaxBeneficiaries <= 0 {
+		opts.MaxBeneficiaries = 5
+	}
+	if opts.NearLimitRatio <= 0 || opts.NearLimitRatio >= 1 {
+		opts.NearLimitRatio = 0.9
+	}
+	return &Engine{db: db, opts: opts}
+}
+
+// Payment is what a payment is screened on.
+type Payment struct {
+	UserID      int
+	Amount      float64
+	Currency    string
+	FromAccount string
+	ToAccount   string
+	// Email is the payer's, from their token; empty is not checked
+	// against the blocklist.
+	Email string
+	// IP is the client address; invalid skips the geography rule.
+	IP netip.Addr
+	// AccountCreatedAt is when the user registered; zero skips the new
+	// account rule.
+	AccountCreatedAt time.Time
+	RequestID        string
+}
+
+// Hit is a rule that fired.
+type Hit struct {
+	Rule   Rule   `json:"rule"`
+	Score  int    `json:"score"`
+	Detail string `json:"detail"`
+}
+
+// Assessment is the stored result of screening a payment.
+type Assessment struct {
+	ID int64
+	// PaymentID is nil for a blocked attempt, or while the payment is
+	// being created.
+	PaymentID *int64
+	UserID    int
+	Amount    float64
+	Currency  string
+	ToAccount string
+	IP        string // empty when unknown
+	Country   string // empty when the IP is in no known range
+	Score     int
+	Outcome   Outcome
+	Hits      []Hit
+	RequestID string
+	CreatedAt time.Time
+
+	// Decision is the admin's verdict on a held payment, once made.
+	Decision *Decision
+}
+
+// queryer is satisfied by *sql.DB and *sql.Tx.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// Assess runs the rules on p at now and stores the assessment. Rules whose
+// input is missing are skipped rather than failing the payment.
+func (e *Engine) Assess(ctx context.Context, p Payment, now time.Time) (Assessment, error) {
+	a := Assessment{
+		UserID:    p.UserID,
+		Amount:    p.Amount,
+		Currency:  p.Currency,
+		ToAccount: p.ToAccount,
+		RequestID: p.RequestID,
+		Hits:      []Hit{},
+	}
+	if p.IP.IsValid() {
+		a.IP = p.IP.String()
+		country, err := Country(ctx, e.db, p.IP)
+		if err != nil {
+			return Assessment{}, fmt.Errorf("look up country: %w", err)
+		}
+		a.Country = country
+	}
+
+	checks := []struct {
+		rule Rule
+		run  func(context.Context, Payment, string, time.Time) (string, error)
+	}{
+		{RuleBlocklisted, e.blocklisted},
+		{RuleNewAccountHighValue, e.newAccountHighValue},
+		{RuleManyBeneficiaries, e.manyBeneficiaries},
+		{RuleNearLimit, e.nearLimit},
+		{RuleGeoMismatch, e.geoMismatch},
+	}
+	for _, c := range checks {
+		detail, err := c.run(ctx, p, a.Country, now)
+		if err != nil {
+			return Assessment{}, fmt.Errorf("rule %s: %w", c.rule, err)
+		}
+		if detail != "" {
+			a.Hits = append(a.Hits, Hit{Rule: c.rule, Score: Scores[c.rule], Detail: detail})
+			a.Score += Scores[c.rule]
+		}
+	}
+	a.Outcome = e.outcome(a.Score)
+	for _, h := range a.Hits {
+		if h.Rule == RuleBlocklisted {
+			a.Outcome = OutcomeBlock
+		}
+	}
+
+	if err := save(ctx, e.db, &a); err != nil {
+		return Assessment{}, err
+	}
+	return a, nil
+}
+
+// outcome maps a score to an outcome.
+func (e *Engine) outcome(score int) Outcome {
+	switch {
+	case score >= e.opts.BlockScore:
+		return OutcomeBlock
+	case score >= e.opts.ReviewScore:
+		return OutcomeReview
+	}
+	return OutcomeAllow
+}
+
+// Each rule is given the payment, the country of its IP and the time, and
+// returns why it fired, or "" if it did not.
+
+func (e *Engine) blocklisted(_ context.Context, p Payment, _ string, _ time.Time) (string, error) {
+	list := e.opts.Blocklist
+	if list == nil {
+		return "", nil
+	}
+	if entry, ok := list.Payee(p.ToAccount); ok {
+		return fmt.Sprintf("payee %s is blocklisted: %s", p.ToAccount, entry.Reason), nil
+	}
+	if entry, ok := list.Payee(p.FromAccount); ok {
+		return fmt.Sprintf("source account %s is blocklisted: %s", p.FromAccount, entry.Reason), nil
+	}
+	if entry, ok := list.Email(p.Email); ok {
+		return fmt.Sprintf("payer email %s is blocklisted: %s", p.Email, entry.Reason), nil
+	}
+	if entry, ok := list.IP(p.IP); ok {
+		return fmt.Sprintf("client address %s is in blocklisted network %s: %s", p.IP, entry.Value, entry.Reason), nil
+	}
+	return "", nil
+}
+
+func (e *Engine) newAccountHighValue(_ context.Context, p Payment, _ string, now time.Time) (string, error) {
+	if p.AccountCreatedAt.IsZero() {
+		return "", nil
+	}
+	age := now.Sub(p.AccountCreatedAt)
+	threshold, ok := e.opts.HighValue[p.Currency]
+	if age >= e.opts.NewAccountAge || !ok || p.Amount < threshold {
+		return "", nil
+	}
+	return fmt.Sprintf("account registered %s ago sending %.2f %s; high value from %.2f",
+		age.Truncate(time.Minute), p.Amount, p.Currency, threshold), nil
+}
+
+func (e *Engine) manyBeneficiaries(ctx context.Context, p Payment, _ string, now time.Time) (string, error) {
+	const query = `SELECT COUNT(DISTINCT to_account) FROM risk_assessments
+		WHERE user_id = $1 AND created_at > $2 AND to_account <> $3`
+
+	var others int
+	spanCtx, span := tracing.StartDBSpan(ctx, "SELECT", "risk_assessments", query)
+	err := e.db.QueryRowContext(spanCtx, query, p.UserID, now.Add(-e.opts.BeneficiaryWindow), p.ToAccount).Scan(&others)
+	tracing.End(span, err)
+	if err != nil {
+		return "", err
+	}
+	if others+1 <= e.opts.MaxBeneficiaries {
+		return "", nil
+	}
+	return fmt.Sprintf("%d distinct payees in the last %s", others+1, e.opts.BeneficiaryWindow), nil
+}
+
+func (e *Engine) nearLimit(ctx context.Context, p Payment, _ string, now time.Time) (string, error) {
+	set, err := limits.Effective(ctx, e.db, p.UserID)
+	if err != nil {
+		return "", err
+	}
+	lim, ok := set.For(p.Currency)
+	if !ok {
+		return "", nil
+	}
+	if lim.PerTransaction != nil && e.justUnder(p.Amount, *lim.PerTransaction) {
+		return fmt.Sprintf("%.2f %s is just under the per-payment limit of %.2f", p.Amount, p.Currency, *lim.PerTransaction), nil
+	}
+	if lim.Daily == nil && lim.Monthly == nil {
+		return "", nil
+	}
+
+	u, err := limits.CurrentUsage(ctx, e.db, p.UserID, p.Currency, now)
+	if err != nil {
+		return "", err
+	}
+	if lim.Daily != nil && e.justUnder(u.Today+p.Amount, *lim.Daily) {
+		return fmt.Sprintf("brings today's total to %.2f of the daily limit of %.2f %s", u.Today+p.Amount, *lim.Daily, p.Currency), nil
+	}
+	if lim.Monthly != nil && e.justUnder(u.ThisMonth+p.Amount, *lim.Monthly) {
+		return fmt.Sprintf("brings this month's total to %.2f of the monthly limit of %.2f %s", u.ThisMonth+p.Amount, *lim.Monthly, p.Currency), nil
+	}
+	return "", nil
+}
+
+// justUnder reports whether amount fits under limit but uses at least
+// NearLimitRatio of it.
+func (e *Engine) justUnder(amount, limit float64) bool {
+	return amount <= limit && amount >= limit*e.opts.NearLimitRatio
+}
+
+func (e *Engine) geoMismatch(ctx context.Context, p Payment, country string, now time.Time) (string, error) {
+	if country == "" {
+		return "", nil
+	}
+	home, err := homeCountry(ctx, e.db, p.UserID, now.Add(-homeLookback))
+	if err != nil || home == "" || home == country {
+		return "", err
+	}
+	return fmt.Sprintf("paying from %s; usually from %s", country, home), nil
+}
+
+// homeCountry returns the country userID has most often paid from since
+// since, counting only payments that went ahead; "" if there are none.
+func homeCountry(ctx context.Context, q queryer, userID int, since time.Time) (string, error) {
+	const query = `SELECT a.country FROM risk_assessments a
+		LEFT JOIN risk_decisions d ON d.assessment_id = a.id
+		WHERE a.user_id = $1 AND a.created_at > $2 AND a.country IS NOT NULL
+		  AND (a.outcome = 'allow' OR d.decision = 'approve')
+		GROUP BY a.country
+		ORDER BY COUNT(*) DESC, MAX(a.created_at) DESC
+		LIMIT 1`
+
+	var country string
+	spanCtx, span := tracing.StartDBSpan(ctx, "SELECT", "risk_assessments", query)
+	err := q.QueryRowContext(spanCtx, query, userID, since).Scan(&country)
+	tracing.End(span, err)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return country, err
+}
+
+// save inserts a, filling in its ID and creation time.
+func save(ctx context.Context, q queryer, a *Assessment) error {
+	const query = `INSERT INTO risk_assessments
+		(user_id, amount, currency, to_account, ip, country, score, outcome, rules, request_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, created_at`
+
+	rules, err := json.Marshal(a.Hits)
+	if err != nil {
+		return err
+	}
+	spanCtx, span := tracing.StartDBSpan(ctx, "INSERT", "risk_assessments", query)
+	err = q.QueryRowContext(spanCtx, query,
+		a.UserID,
+		a.Amount,
+		a.Currency,
+		a.ToAccount,
+		nullString(a.IP),
+		nullString(a.Country),
+		a.Score,
+		string(a.Outcome),
+		rules,
+		nullString(a.RequestID),
+	).Scan(&a.ID, &a.CreatedAt)
+	tracing.End(span, err)
+	if err != nil {
+		return fmt.Errorf("insert risk assessment: %w", err)
+	}
+	return nil
+}
+
+// Attach links assessment id to the payment it screened, in the
+// transaction that stores the payment.
+func Attach(ctx context.Context, tx *sql.Tx, id, paymentID int64) error {
+	const query = "UPDATE risk_assessments SET payment_id = $2 WHERE id = $1"
+	spanCtx, span := tracing.StartDBSpan(ctx, "UPDATE", "risk_assessments", query)
+	_, err := tx.ExecContext(spanCtx, query, id, paymentID)
+	tracing.End(span, err)
+	if err != nil {
+		return fmt.Errorf("attach risk assessment %d: %w", id, err)
+	}
+	return nil
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
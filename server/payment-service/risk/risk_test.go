@@ -0,0 +1,222 @@
+package risk
+
+import (
+	"context"
+	"net/netip"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/blocklist"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/internal/dbtest"
+)
+
+func TestOutcome(t *testing.T) {
+	e := New(nil, Options{})
+	for score, want := range map[int]Outcome{0: OutcomeAllow, 49: OutcomeAllow, 50: OutcomeReview, 99: OutcomeReview, 100: OutcomeBlock, 170: OutcomeBlock} {
+		if got := e.outcome(score); got != want {
+			t.Errorf("outcome(%d) = %s, want %s", score, got, want)
+		}
+	}
+	// A lone geography or near-limit hit does not hold a payment; the new
+	// account rule does
+	if e.outcome(Scores[RuleGeoMismatch]) != OutcomeAllow || e.outcome(Scores[RuleNearLimit]) != OutcomeAllow {
+		t.Error("a single weak rule holds the payment")
+	}
+	if e.outcome(Scores[RuleNewAccountHighValue]) != OutcomeReview {
+		t.Error("a new account sending a high value is not held")
+	}
+}
+
+func TestNewAccountHighValue(t *testing.T) {
+	e := New(nil, Options{HighValue: map[string]float64{"INR": 50000}})
+	now := time.Now()
+	tests := []struct {
+		name  string
+		p     Payment
+		fires bool
+	}{
+		{"new account at the threshold", Payment{Amount: 50000, Currency: "INR", AccountCreatedAt: now.Add(-time.Hour)}, true},
+		{"new account below it", Payment{Amount: 49999.99, Currency: "INR", AccountCreatedAt: now.Add(-time.Hour)}, false},
+		{"week-old account", Payment{Amount: 90000, Currency: "INR", AccountCreatedAt: now.Add(-7 * 24 * time.Hour)}, false},
+		{"currency without a threshold", Payment{Amount: 90000, Currency: "USD", AccountCreatedAt: now.Add(-time.Hour)}, false},
+		{"unknown account age", Payment{Amount: 90000, Currency: "INR"}, false},
+	}
+	for _, tt := range tests {
+		detail, err := e.newAccountHighValue(context.Background(), tt.p, "", now)
+		if err != nil || (detail != "") != tt.fires {
+			t.Errorf("%s: %q (%v), want fired %t", tt.name, detail, err, tt.fires)
+		}
+	}
+}
+
+func TestJustUnder(t *testing.T) {
+	e := New(nil, Options{})
+	for _, tt := range []struct {
+		amount, limit float64
+		want          bool
+	}{
+		{899.99, 1000, false},
+		{900, 1000, true},
+		{1000, 1000, true},
+		{1000.01, 1000, false}, // over the limit is the limits check's business
+	} {
+		if got := e.justUnder(tt.amount, tt.limit); got != tt.want {
+			t.Errorf("justUnder(%v, %v) = %t, want %t", tt.amount, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestParseIPRanges(t *testing.T) {
+	ranges, err := ParseIPRanges(strings.NewReader("network,country\n# comment\n\n10.1.2.3/8, in\n2001:db8::/32,US\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []IPRange{
+		{netip.MustParsePrefix("10.0.0.0/8"), "IN"},
+		{netip.MustParsePrefix("2001:db8::/32"), "US"},
+	}
+	if !slices.Equal(ranges, want) {
+		t.Errorf("ParseIPRanges = %v, want %v", ranges, want)
+	}
+
+	for input, problem := range map[string]string{
+		"":                                  "missing header",
+		"cidr,country\n":                    "header must be",
+		"network,country\n10.0.0.0,IN\n":    "line 2: network",
+		"network,country\n10.0.0.0/8,IND\n": "line 2: country",
+		"network,country\n10.0.0.0/8,IN\n10.0.0.0/8,US\n": "line 3: 10.0.0.0/8 is listed twice",
+	} {
+		if _, err := ParseIPRanges(strings.NewReader(input)); err == nil || !strings.Contains(err.Error(), problem) {
+			t.Errorf("ParseIPRanges(%q): err = %v, want %q", input, err, problem)
+		}
+	}
+}
+
+func rules(a Assessment) []Rule {
+	var list []Rule
+	for _, h := range a.Hits {
+		list = append(list, h.Rule)
+	}
+	return list
+}
+
+func TestAssess(t *testing.T) {
+	database := dbtest.Open(t)
+	ctx := context.Background()
+	if err := ReplaceIPRanges(ctx, database, []IPRange{
+		{netip.MustParsePrefix("49.36.0.0/14"), "IN"},
+		{netip.MustParsePrefix("81.2.69.0/24"), "GB"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	list := blocklist.NewCache(database, time.Minute)
+	entry := blocklist.Entry{Kind: blocklist.KindVPA, Value: "mule@okbank", Reason: "reported mule", CreatedBy: 1}
+	if err := blocklist.Normalize(&entry); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := blocklist.Add(ctx, database, entry); err != nil {
+		t.Fatal(err)
+	}
+	if err := list.Reload(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	e := New(database, Options{HighValue: map[string]float64{"INR": 50000}, Blocklist: list})
+	home := netip.MustParseAddr("49.36.200.1")
+	abroad := netip.MustParseAddr("81.2.69.160")
+	assess := func(p Payment) Assessment {
+		t.Helper()
+		a, err := e.Assess(ctx, p, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a
+	}
+
+	// The first payment has no history to compare against
+	a := assess(Payment{UserID: 1, Amount: 1000, Currency: "INR", ToAccount: "ACC-1", IP: abroad})
+	if a.Outcome != OutcomeAllow || len(a.Hits) != 0 || a.Country != "GB" || a.IP != "81.2.69.160" {
+		t.Errorf("first payment: %s with %v from %q (%s)", a.Outcome, rules(a), a.IP, a.Country)
+	}
+	for range 2 {
+		assess(Payment{UserID: 1, Amount: 1000, Currency: "INR", ToAccount: "ACC-1", IP: home})
+	}
+
+	tests := []struct {
+		name    string
+		p       Payment
+		rules   []Rule
+		outcome Outcome
+	}{
+		{
+			name:    "from home",
+			p:       Payment{UserID: 1, Amount: 1000, Currency: "INR", ToAccount: "ACC-1", IP: home},
+			outcome: OutcomeAllow,
+		},
+		{
+			name:    "from abroad",
+			p:       Payment{UserID: 1, Amount: 1000, Currency: "INR", ToAccount: "ACC-1", IP: abroad},
+			rules:   []Rule{RuleGeoMismatch},
+			outcome: OutcomeAllow,
+		},
+		{
+			name:    "just under the default per-payment limit",
+			p:       Payment{UserID: 1, Amount: 95000, Currency: "INR", ToAccount: "ACC-1", IP: home},
+			rules:   []Rule{RuleNearLimit},
+			outcome: OutcomeAllow,
+		},
+		{
+			name:    "new account sending a high value from abroad",
+			p:       Payment{UserID: 1, Amount: 60000, Currency: "INR", ToAccount: "ACC-1", IP: abroad, AccountCreatedAt: time.Now().Add(-time.Hour)},
+			rules:   []Rule{RuleNewAccountHighValue, RuleGeoMismatch},
+			outcome: OutcomeReview,
+		},
+		{
+			name:    "blocklisted payee",
+			p:       Payment{UserID: 2, Amount: 10, Currency: "INR", ToAccount: "Mule@OKBank"},
+			rules:   []Rule{RuleBlocklisted},
+			outcome: OutcomeBlock,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := assess(tt.p)
+			if !slices.Equal(rules(a), tt.rules) || a.Outcome != tt.outcome {
+				t.Errorf("%s with %v, want %s with %v", a.Outcome, rules(a), tt.outcome, tt.rules)
+			}
+			want := 0
+			for _, r := range tt.rules {
+				want += Scores[r]
+			}
+			if a.Score != want {
+				t.Errorf("score %d, want %d", a.Score, want)
+			}
+		})
+	}
+
+	// Paying a sixth payee within the hour
+	for i := range 5 {
+		a := assess(Payment{UserID: 3, Amount: 10, Currency: "INR", ToAccount: "ACC-" + string(rune('A'+i))})
+		if len(a.Hits) != 0 {
+			t.Fatalf("payee %d: %v", i+1, rules(a))
+		}
+	}
+	if a := assess(Payment{UserID: 3, Amount: 10, Currency: "INR", ToAccount: "ACC-A"}); len(a.Hits) != 0 {
+		t.Errorf("paying a known payee again: %v", rules(a))
+	}
+	if a := assess(Payment{UserID: 3, Amount: 10, Currency: "INR", ToAccount: "ACC-F"}); !slices.Equal(rules(a), []Rule{RuleManyBeneficiaries}) {
+		t.Errorf("sixth payee: %v, want many_beneficiaries", rules(a))
+	}
+
+	// Every screening is stored with why it ended as it did
+	stored, err := List(ctx, database, Filter{UserID: 2})
+	if err != nil || len(stored) != 1 {
+		t.Fatalf("%d assessments stored for user 2 (%v), want 1", len(stored), err)
+	}
+	if got := stored[0]; got.Outcome != OutcomeBlock || got.PaymentID != nil || len(got.Hits) != 1 || !strings.Contains(got.Hits[0].Detail, "reported mule") {
+		t.Errorf("stored %+v", got)
+	}
+}
@@ -0,0 +1,281 @@
+package schedules
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/beneficiaries"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/limits"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/metrics"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/models"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/outbox"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/payments"
+)
+
+// errAlreadyExecuted discards a payment whose occurrence was finished by
+// another attempt, or skipped, while it was being created.
+var errAlreadyExecuted = errors.New("occurrence is no longer pending")
+
+// Options tunes a Scheduler. Zero values take the defaults noted per field.
+type Options struct {
+	PollInterval time.Duration // 30s
+	BatchSize    int           // 50
+	MaxRetries   int           // 3 retries after the first failed attempt
+	MinBackoff   time.Duration // 1m after the first failure
+	MaxBackoff   time.Duration // 1h
+	// TTL returns how long an occurrence's order stays payable when its
+	// schedule does not say; nil means 30m.
+	TTL func(currency string) time.Duration
+}
+
+// Scheduler records occurrences as they fall due and executes them.
+type Scheduler struct {
+	db   *sql.DB
+	opts Options
+}
+
+// NewScheduler creates a scheduler for the schedules in db.
+func NewScheduler(db *sql.DB, opts Options) *Scheduler {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 30 * time.Second
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 50
+	}
+	if opts.MaxRetries < 0 {
+		opts.MaxRetries = 0
+	}
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = time.Minute
+	}
+	if opts.MaxBackoff < opts.MinBackoff {
+		opts.MaxBackoff = time.Hour
+	}
+	if opts.TTL == nil {
+		opts.TTL = func(string) time.Duration { return 30 * time.Minute }
+	}
+	return &Scheduler{db: db, opts: opts}
+}
+
+// Run schedules and executes occurrences until ctx is cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	slog.Info("Payment scheduler started", "poll_interval", s.opts.PollInterval, "max_retries", s.opts.MaxRetries)
+
+	ticker := time.NewTicker(s.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.drain(ctx, "plan", s.Plan)
+		s.drain(ctx, "execute", s.ExecuteDue)
+
+		select {
+		case <-ctx.Done():
+			slog.Info("Payment scheduler stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// drain repeats step while batches come back full.
+func (s *Scheduler) drain(ctx context.Context, name string, step func(context.Context) (int, error)) {
+	for {
+		n, err := step(ctx)
+		if err != nil && ctx.Err() == nil {
+			slog.Error("Scheduler batch failed", "step", name, "error", err)
+		}
+		if err != nil || n < s.opts.BatchSize {
+			return
+		}
+	}
+}
+
+// dueQuery selects active schedules whose next occurrence is due. Rows
+// locked by a user's change or another replica are left for the next poll.
+const dueQuery = `SELECT ` + columns + ` FROM schedules
+	WHERE status = 'active' AND next_run_at <= NOW()
+	ORDER BY next_run_at
+	LIMIT $1
+	FOR UPDATE SKIP LOCKED`
+
+// Plan records the next occurrence of one batch of due schedules as a
+// pending execution and moves each schedule on, returning the batch size.
+// A schedule that was down for several occurrences catches up one
+// occurrence per poll.
+func (s *Scheduler) Plan(ctx context.Context) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, dueQuery, s.opts.BatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("select due schedules: %w", err)
+	}
+	var due []Schedule
+	for rows.Next() {
+		sch, err := scan(rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		due = append(due, sch)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, sch := range due {
+		if err := insertExecution(ctx, tx, sch, ExecutionPending); err != nil {
+			return 0, fmt.Errorf("record occurrence of schedule %d: %w", sch.ID, err)
+		}
+		sch.advance()
+		if _, err := save(ctx, tx, sch); err != nil {
+			return 0, fmt.Errorf("advance schedule %d: %w", sch.ID, err)
+		}
+	}
+	return len(due), tx.Commit()
+}
+
+// claimQuery leases due executions by pushing next_attempt_at past the time
+// an attempt can take and counts the attempt up front, so an attempt that
+// dies mid-way is retried and still uses up the retry budget. Occurrences of
+// paused or cancelled schedules wait.
+const claimQuery = `UPDATE schedule_executions
+	SET next_attempt_at = NOW() + make_interval(secs => $2),
+		attempts = attempts + 1
+	WHERE id IN (
+		SELECT e.id FROM schedule_executions e
+		JOIN schedules s ON s.id = e.schedule_id
+		WHERE e.status = 'pending' AND e.next_attempt_at <= NOW()
+		  AND s.status IN ('active', 'completed')
+		ORDER BY e.next_attempt_at
+		LIMIT $1
+		FOR UPDATE OF e SKIP LOCKED
+	)
+	RETURNING ` + executionColumns
+
+// leaseDuration bounds one attempt: a Razorpay call and two small writes.
+const leaseDuration = 5 * time.Minute
+
+// ExecuteDue attempts one batch of due executions and returns its size.
+func (s *Scheduler) ExecuteDue(ctx context.Context) (int, error) {
+	rows, err := s.db.QueryContext(ctx, claimQuery, s.opts.BatchSize, leaseDuration.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("claim executions: %w", err)
+	}
+	var batch []Execution
+	for rows.Next() {
+		e, err := scanExecution(rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		batch = append(batch, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, e := range batch {
+		if err := s.execute(ctx, e); err != nil && ctx.Err() == nil {
+			slog.Error("Schedule execution bookkeeping failed", "execution_id", e.ID, "error", err)
+		}
+	}
+	return len(batch), nil
+}
+
+// execute creates the payment for e through the same path as /pay. The
+// execution is marked succeeded in the transaction that stores the
+// payment, so each occurrence yields at most one payment.
+func (s *Scheduler) execute(ctx context.Context, e Execution) error {
+	sch, err := scan(s.db.QueryRowContext(ctx, `SELECT `+columns+` FROM schedules WHERE id = $1`, e.ScheduleID))
+	if err != nil {
+		return err
+	}
+
+	ttl := sch.TTL
+	if ttl <= 0 {
+		ttl = s.opts.TTL(sch.Currency)
+	}
+	payment, err := payments.Initiate(ctx, s.db, payments.Order{
+		UserID:        sch.UserID,
+		Amount:        sch.Amount,
+		Currency:      sch.Currency,
+		FromAccount:   sch.FromAccount,
+		ToAccount:     sch.ToAccount,
+		BeneficiaryID: sch.BeneficiaryID,
+		CaptureMode:   sch.CaptureMode,
+		Description:   sch.Description,
+		TTL:           ttl,
+		CheckLimits:   true,
+		Receipt:       fmt.Sprintf("order_s%d_%d", sch.ID, e.Occurrence),
+		RequestID:     fmt.Sprintf("schedule-%d-%d", sch.ID, e.Occurrence),
+		Notes: map[string]interface{}{
+			"schedule_id": sch.ID,
+			"occurrence":  e.Occurrence,
+		},
+		Record: func(ctx context.Context, tx *sql.Tx, p models.Payment) error {
+			res, err := tx.ExecContext(ctx, `UPDATE schedule_executions
+				SET status = 'succeeded', payment_id = $2, last_error = NULL
+				WHERE id = $1 AND status = 'pending'`, e.ID, p.ID)
+			if err != nil {
+				return err
+			}
+			if n, _ := res.RowsAffected(); n == 0 {
+				return errAlreadyExecuted
+			}
+			return nil
+		},
+	})
+	switch {
+	case err == nil:
+		metrics.ScheduleExecution(string(ExecutionSucceeded))
+		slog.InfoContext(ctx, "Scheduled payment created",
+			"schedule_id", sch.ID, "occurrence", e.Occurrence, "payment_id", payment.ID)
+		return nil
+	case errors.Is(err, errAlreadyExecuted):
+		return nil
+	case ctx.Err() != nil:
+		// Shutting down; the lease expires and the occurrence is retried
+		return ctx.Err()
+	}
+
+	// A deleted beneficiary will not come back, an amount over the
+	// per-transaction limit will not shrink and a blocklisted payee stays
+	// listed until an admin acts, so retrying cannot help
+	status := ExecutionPending
+	var retryIn time.Duration
+	var violation *limits.Violation
+	permanent := errors.Is(err, beneficiaries.ErrNotFound) || errors.Is(err, payments.ErrBlocklisted) ||
+		(errors.As(err, &violation) && violation.Kind == limits.KindPerTransaction)
+	if permanent || e.Attempts > s.opts.MaxRetries {
+		status = ExecutionFailed
+	} else {
+		retryIn = outbox.Backoff(e.Attempts-1, s.opts.MinBackoff, s.opts.MaxBackoff)
+	}
+	metrics.ScheduleExecution(string(status))
+	slog.WarnContext(ctx, "Scheduled payment failed",
+		"schedule_id", sch.ID,
+		"occurrence", e.Occurrence,
+		"attempt", e.Attempts,
+		"status", status,
+		"retry_in", retryIn,
+		"error", err,
+	)
+
+	_, err = s.db.ExecContext(ctx, `UPDATE schedule_executions SET
+			status = $2,
+			next_attempt_at = NOW() + make_interval(secs => $3),
+			last_error = $4
+		WHERE id = $1 AND status = 'pending'`,
+		e.ID, string(status), retryIn.Seconds(), err.Error())
+	return err
+}
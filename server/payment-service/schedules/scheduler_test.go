@@ -0,0 +1,395 @@
+package schedules
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/blocklist"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/internal/dbtest"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/internal/rzptest"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/payments"
+)
+
+// fakeOrders answers order creation like Razorpay, counting the orders.
+func fakeOrders(t *testing.T) *atomic.Int64 {
+	return flakyOrders(t, 0)
+}
+
+// flakyOrders is fakeOrders with Razorpay failing the first failures
+// requests.
+func flakyOrders(t *testing.T, failures int64) *atomic.Int64 {
+	var created, calls atomic.Int64
+	rzptest.Serve(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/orders" {
+			http.NotFound(w, r)
+			return
+		}
+		if calls.Add(1) <= failures {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]any{
+				"error": map[string]any{"code": "SERVER_ERROR", "description": "The server encountered an error"},
+			})
+			return
+		}
+		var req map[string]any
+		json.NewDecoder(r.Body).Decode(&req)
+		json.NewEncoder(w).Encode(map[string]any{
+			"id":       fmt.Sprintf("order_sched%d", created.Add(1)),
+			"amount":   req["amount"],
+			"currency": req["currency"],
+			"receipt":  req["receipt"],
+			"status":   "created",
+		})
+	}))
+	return &created
+}
+
+// dueSchedule stores a one-off schedule of 100 INR from userID to toAccount
+// that is due now.
+func dueSchedule(t *testing.T, database *sql.DB, userID int, toAccount string) Schedule {
+	t.Helper()
+	now := time.Now()
+	s := Schedule{
+		UserID:      userID,
+		Amount:      100,
+		Currency:    "INR",
+		FromAccount: "50100123456789",
+		ToAccount:   toAccount,
+		CaptureMode: "auto",
+		Frequency:   FrequencyOnce,
+		StartAt:     now,
+	}
+	if err := Validate(&s, now); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Create(context.Background(), database, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// executions returns the executions of s, newest first.
+func executions(t *testing.T, database *sql.DB, s Schedule) []Execution {
+	t.Helper()
+	list, err := ListExecutions(context.Background(), database, s.UserID, s.ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return list
+}
+
+func TestBlocklistedPayee(t *testing.T) {
+	database := dbtest.Open(t)
+	created := fakeOrders(t)
+	ctx := context.Background()
+
+	entry := blocklist.Entry{Kind: blocklist.KindVPA, Value: "mule@okbank", Reason: "reported mule", CreatedBy: 1}
+	if err := blocklist.Normalize(&entry); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := blocklist.Add(ctx, database, entry); err != nil {
+		t.Fatal(err)
+	}
+	list := blocklist.NewCache(database, time.Minute)
+	if err := list.Reload(ctx); err != nil {
+		t.Fatal(err)
+	}
+	payments.SetBlocklist(list)
+	t.Cleanup(func() { payments.SetBlocklist(nil) })
+
+	// The schedule was set up before the payee was listed
+	s := dueSchedule(t, database, 9, "Mule@OKBank")
+	scheduler := NewScheduler(database, Options{MaxRetries: 3})
+	if _, err := scheduler.Plan(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := scheduler.ExecuteDue(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if created.Load() != 0 {
+		t.Errorf("%d orders created for a blocklisted payee", created.Load())
+	}
+	got := executions(t, database, s)
+	if len(got) != 1 {
+		t.Fatalf("%d executions, want 1", len(got))
+	}
+	// Retrying cannot help, so the occurrence fails on its first attempt
+	e := got[0]
+	if e.Status != ExecutionFailed || e.PaymentID != nil || e.LastError == nil || !strings.Contains(*e.LastError, "blocklisted") {
+		t.Errorf("execution %s with payment %v: %v; want failed as blocklisted", e.Status, e.PaymentID, e.LastError)
+	}
+}
+
+// due makes every pending execution due now.
+func due(t *testing.T, database *sql.DB) {
+	t.Helper()
+	if _, err := database.ExecContext(context.Background(),
+		`UPDATE schedule_executions SET next_attempt_at = NOW() WHERE status = 'pending'`); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	zero, two := 0, 2
+	before := now.Add(-time.Hour)
+	tests := []struct {
+		name    string
+		s       Schedule
+		wantErr string
+		next    *time.Time
+	}{
+		{name: "once", s: Schedule{Frequency: FrequencyOnce, StartAt: now.Add(time.Hour)}, next: ptr(now.Add(time.Hour))},
+		{name: "clock skew", s: Schedule{Frequency: FrequencyDaily, StartAt: now.Add(-30 * time.Second)}, next: ptr(now.Add(-30 * time.Second))},
+		{name: "unknown frequency", s: Schedule{Frequency: "hourly", StartAt: now}, wantErr: "frequency must be once, daily, weekly or monthly"},
+		{name: "no start", s: Schedule{Frequency: FrequencyDaily}, wantErr: "start_at is required"},
+		{name: "past start", s: Schedule{Frequency: FrequencyDaily, StartAt: before}, wantErr: "start_at must not be in the past"},
+		{name: "end before start", s: Schedule{Frequency: FrequencyDaily, StartAt: now, EndAt: &before}, wantErr: "end_at must not be before start_at"},
+		{name: "no occurrences", s: Schedule{Frequency: FrequencyDaily, StartAt: now, MaxOccurrences: &zero}, wantErr: "max_occurrences must be positive"},
+		{name: "bounded", s: Schedule{Frequency: FrequencyWeekly, StartAt: now, MaxOccurrences: &two}, next: ptr(now)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := tt.s
+			err := Validate(&s, now)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s.Status != StatusActive || s.NextRunAt == nil || !s.NextRunAt.Equal(*tt.next) {
+				t.Errorf("%s, next run %v; want active, next run %v", s.Status, s.NextRunAt, tt.next)
+			}
+		})
+	}
+}
+
+func ptr(t time.Time) *time.Time { return &t }
+
+func TestOccurrenceAt(t *testing.T) {
+	at := func(s string) time.Time {
+		v, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	tests := []struct {
+		frequency Frequency
+		start     string
+		n         int
+		want      string
+	}{
+		{FrequencyOnce, "2026-01-31T10:00:00Z", 3, "2026-01-31T10:00:00Z"},
+		{FrequencyDaily, "2026-01-31T10:00:00Z", 1, "2026-02-01T10:00:00Z"},
+		{FrequencyWeekly, "2026-01-31T10:00:00Z", 2, "2026-02-14T10:00:00Z"},
+		{FrequencyMonthly, "2026-01-15T10:00:00Z", 1, "2026-02-15T10:00:00Z"},
+		// The 31st falls on the last day of shorter months and comes back
+		{FrequencyMonthly, "2026-01-31T10:00:00Z", 1, "2026-02-28T10:00:00Z"},
+		{FrequencyMonthly, "2026-01-31T10:00:00Z", 2, "2026-03-31T10:00:00Z"},
+		{FrequencyMonthly, "2026-01-31T10:00:00Z", 3, "2026-04-30T10:00:00Z"},
+		{FrequencyMonthly, "2027-12-29T10:00:00Z", 2, "2028-02-29T10:00:00Z"},
+		{FrequencyMonthly, "2026-11-30T10:00:00Z", 14, "2028-01-30T10:00:00Z"},
+		// Counted in UTC, whatever zone the start was given in
+		{FrequencyDaily, "2026-03-28T23:30:00+01:00", 1, "2026-03-29T22:30:00Z"},
+	}
+	for _, tt := range tests {
+		s := Schedule{Frequency: tt.frequency, StartAt: at(tt.start)}
+		if got := s.OccurrenceAt(tt.n); !got.Equal(at(tt.want)) {
+			t.Errorf("%s from %s, occurrence %d = %s, want %s", tt.frequency, tt.start, tt.n, got.Format(time.RFC3339), tt.want)
+		}
+	}
+}
+
+func TestAdvanceCompletes(t *testing.T) {
+	start := time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC)
+	three := 3
+	end := start.AddDate(0, 0, 14)
+	tests := []struct {
+		name string
+		s    Schedule
+		runs int // occurrences scheduled before it completes
+	}{
+		{"once", Schedule{Frequency: FrequencyOnce, StartAt: start}, 1},
+		{"max occurrences", Schedule{Frequency: FrequencyDaily, StartAt: start, MaxOccurrences: &three}, 3},
+		{"end inclusive", Schedule{Frequency: FrequencyWeekly, StartAt: start, EndAt: &end}, 3},
+	}
+	for _, tt := range tests {
+		s := tt.s
+		if err := Validate(&s, start); err != nil {
+			t.Fatal(err)
+		}
+		runs := 0
+		for s.Status == StatusActive && runs < 10 {
+			s.advance()
+			runs++
+		}
+		if runs != tt.runs || s.Status != StatusCompleted || s.NextRunAt != nil {
+			t.Errorf("%s: %s after %d occurrences (next %v), want completed after %d", tt.name, s.Status, runs, s.NextRunAt, tt.runs)
+		}
+	}
+}
+
+func TestOccurrenceRunsOnce(t *testing.T) {
+	database := dbtest.Open(t)
+	created := fakeOrders(t)
+	ctx := context.Background()
+
+	s := dueSchedule(t, database, 11, "50100987654321")
+	scheduler := NewScheduler(database, Options{})
+	for i := 0; i < 2; i++ {
+		if _, err := scheduler.Plan(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Two replicas polling at once claim the occurrence once between them
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := NewScheduler(database, Options{}).ExecuteDue(ctx); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	// Even past its lease, a finished occurrence is not retried
+	due(t, database)
+	if _, err := scheduler.ExecuteDue(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if created.Load() != 1 {
+		t.Errorf("%d orders created, want 1", created.Load())
+	}
+	got := executions(t, database, s)
+	if len(got) != 1 || got[0].Status != ExecutionSucceeded || got[0].PaymentID == nil || got[0].Attempts != 1 {
+		t.Fatalf("executions %+v, want one succeeded on its first attempt", got)
+	}
+	stored, err := Get(ctx, database, s.UserID, s.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != StatusCompleted || stored.Occurrences != 1 {
+		t.Errorf("schedule %s after %d occurrences, want completed after 1", stored.Status, stored.Occurrences)
+	}
+}
+
+func TestPlanCatchesUpOnePerPoll(t *testing.T) {
+	database := dbtest.Open(t)
+	ctx := context.Background()
+
+	now := time.Now()
+	s := Schedule{UserID: 12, Amount: 50, Currency: "INR", FromAccount: "50100123456789", ToAccount: "50100987654321",
+		CaptureMode: "auto", Frequency: FrequencyDaily, StartAt: now}
+	if err := Validate(&s, now); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Create(ctx, database, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The scheduler was down for the last two days
+	if _, err := database.ExecContext(ctx, `UPDATE schedules
+		SET start_at = start_at - INTERVAL '2 days', next_run_at = start_at - INTERVAL '2 days'
+		WHERE id = $1`, s.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	scheduler := NewScheduler(database, Options{})
+	for _, want := range []int{1, 1, 1, 0} {
+		n, err := scheduler.Plan(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != want {
+			t.Fatalf("planned %d, want %d", n, want)
+		}
+	}
+	got := executions(t, database, s)
+	if len(got) != 3 {
+		t.Fatalf("%d executions, want 3", len(got))
+	}
+	for i, e := range got {
+		if want := 3 - i; e.Occurrence != want || e.Status != ExecutionPending {
+			t.Errorf("execution %d: occurrence %d %s, want occurrence %d pending", i, e.Occurrence, e.Status, want)
+		}
+	}
+}
+
+func TestExecutionRetries(t *testing.T) {
+	tests := []struct {
+		name       string
+		failures   int64
+		maxRetries int
+		want       ExecutionStatus
+		attempts   int
+	}{
+		{"recovers", 1, 2, ExecutionSucceeded, 2},
+		{"recovers on last retry", 2, 2, ExecutionSucceeded, 3},
+		{"gives up", 5, 2, ExecutionFailed, 3},
+		{"no retries", 1, 0, ExecutionFailed, 1},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database := dbtest.Open(t)
+			created := flakyOrders(t, tt.failures)
+			ctx := context.Background()
+
+			s := dueSchedule(t, database, 20+i, "50100987654321")
+			scheduler := NewScheduler(database, Options{MaxRetries: tt.maxRetries})
+			if _, err := scheduler.Plan(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			var e Execution
+			for attempt := 1; attempt <= tt.maxRetries+2; attempt++ {
+				if _, err := scheduler.ExecuteDue(ctx); err != nil {
+					t.Fatal(err)
+				}
+				e = executions(t, database, s)[0]
+				if e.Status != ExecutionPending {
+					break
+				}
+				// A failed attempt is retried after a backoff, not at once
+				if !e.NextAttemptAt.After(time.Now().Add(20*time.Second)) || e.LastError == nil {
+					t.Fatalf("attempt %d: retry at %v with error %v, want a backoff", attempt, e.NextAttemptAt, e.LastError)
+				}
+				if n, err := scheduler.ExecuteDue(ctx); err != nil || n != 0 {
+					t.Fatalf("executed %d before the backoff elapsed (%v)", n, err)
+				}
+				due(t, database)
+			}
+
+			if e.Status != tt.want || e.Attempts != tt.attempts {
+				t.Errorf("%s after %d attempts, want %s after %d", e.Status, e.Attempts, tt.want, tt.attempts)
+			}
+			wantOrders := int64(0)
+			if tt.want == ExecutionSucceeded {
+				wantOrders = 1
+				if e.PaymentID == nil || e.LastError != nil {
+					t.Errorf("succeeded with payment %v, error %v", e.PaymentID, e.LastError)
+				}
+			}
+			if created.Load() != wantOrders {
+				t.Errorf("%d orders created, want %d", created.Load(), wantOrders)
+			}
+		})
+	}
+}
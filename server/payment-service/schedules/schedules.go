@@ -0,0 +1,425 @@
+// Package schedules stores scheduled and recurring payments and runs each
+// occurrence through payments.Initiate when it falls due.
+package schedules
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNotFound is returned when a schedule does not exist or belongs to
+// another user.
+var ErrNotFound = errors.New("schedule not found")
+
+// ErrInvalidState is returned when an operation does not apply to a
+// schedule's current status.
+var ErrInvalidState = errors.New("operation not allowed")
+
+// Frequency is how often a schedule repeats.
+type Frequency string
+
+const (
+	FrequencyOnce    Frequency = "once"
+	FrequencyDaily   Frequency = "daily"
+	FrequencyWeekly  Frequency = "weekly"
+	FrequencyMonthly Frequency = "monthly"
+)
+
+// Status is a schedule's lifecycle state.
+type Status string
+
+const (
+	StatusActive    Status = "active"
+	StatusPaused    Status = "paused"
+	StatusCompleted Status = "completed" // no occurrences left to schedule
+	StatusCancelled Status = "cancelled"
+)
+
+// ExecutionStatus is the outcome of one occurrence.
+type ExecutionStatus string
+
+const (
+	ExecutionPending   ExecutionStatus = "pending"
+	ExecutionSucceeded ExecutionStatus = "succeeded"
+	ExecutionFailed    ExecutionStatus = "failed"
+	ExecutionSkipped   ExecutionStatus = "skipped"
+)
+
+// Schedule is a payment to make once or repeatedly.
+type Schedule struct {
+	ID            int64
+	UserID        int
+	Amount        float64
+	Currency      string
+	FromAccount   string
+	ToAccount     string
+	BeneficiaryID *int64
+	CaptureMode   string
+	// TTL is how long each occurrence's order stays payable; 0 uses the
+	// currency's configured TTL.
+	TTL         time.Duration
+	Description *string
+
+	Frequency      Frequency
+	StartAt        time.Time
+	EndAt          *time.Time
+	MaxOccurrences *int
+
+	// Occurrences counts the occurrences already scheduled or skipped.
+	// NextRunAt is when occurrence Occurrences+1 is due; nil once the
+	// schedule has completed.
+	Occurrences int
+	NextRunAt   *time.Time
+	Status      Status
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Execution is one occurrence of a schedule.
+type Execution struct {
+	ID            int64
+	ScheduleID    int64
+	Occurrence    int
+	ScheduledFor  time.Time
+	Status        ExecutionStatus
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     *string
+	PaymentID     *int64
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// Validate checks s's recurrence fields for a new schedule and sets its
+// first run.
+func Validate(s *Schedule, now time.Time) error {
+	switch s.Frequency {
+	case FrequencyOnce, FrequencyDaily, FrequencyWeekly, FrequencyMonthly:
+	default:
+		return fmt.Errorf("frequency must be %s, %s, %s or %s", FrequencyOnce, FrequencyDaily, FrequencyWeekly, FrequencyMonthly)
+	}
+	if s.StartAt.IsZero() {
+		return errors.New("start_at is required")
+	}
+	// Allow for clock skew between the client and us
+	if s.StartAt.Before(now.Add(-time.Minute)) {
+		return errors.New("start_at must not be in the past")
+	}
+	if s.EndAt != nil && s.EndAt.Before(s.StartAt) {
+		return errors.New("end_at must not be before start_at")
+	}
+	if s.MaxOccurrences != nil && *s.MaxOccurrences <= 0 {
+		return errors.New("max_occurrences must be positive")
+	}
+	s.StartAt = s.StartAt.UTC()
+	s.Status = StatusActive
+	s.Occurrences = 0
+	s.scheduleNext()
+	return nil
+}
+
+// OccurrenceAt returns when the occurrence with zero-based index n is due.
+// Occurrences are counted from StartAt in UTC so they do not drift; monthly
+// schedules started on the 29th to 31st fall on the last day of shorter
+// months.
+func (s Schedule) OccurrenceAt(n int) time.Time {
+	start := s.StartAt.UTC()
+	switch s.Frequency {
+	case FrequencyDaily:
+		return start.AddDate(0, 0, n)
+	case FrequencyWeekly:
+		return start.AddDate(0, 0, 7*n)
+	case FrequencyMonthly:
+		first := time.Date(start.Year(), start.Month()+time.Month(n), 1,
+			start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), time.UTC)
+		lastDay := first.AddDate(0, 1, -1).Day()
+		return first.AddDate(0, 0, min(start.Day(), lastDay)-1)
+	default:
+		return start
+	}
+}
+
+// scheduleNext sets NextRunAt to the next unscheduled occurrence, completing
+// s when the series has ended.
+func (s *Schedule) scheduleNext() {
+	next := s.OccurrenceAt(s.Occurrences)
+	if (s.Frequency == FrequencyOnce && s.Occurrences > 0) ||
+		(s.MaxOccurrences != nil && s.Occurrences >= *s.MaxOccurrences) ||
+		(s.EndAt != nil && next.After(*s.EndAt)) {
+		s.NextRunAt = nil
+		s.Status = StatusCompleted
+		return
+	}
+	s.NextRunAt = &next
+}
+
+// advance moves s past its next occurrence.
+func (s *Schedule) advance() {
+	s.Occurrences++
+	s.scheduleNext()
+}
+
+// queryer is satisfied by *sql.DB and *sql.Tx.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+const columns = `id, user_id, amount, currency, from_account, COALESCE(to_account, ''), beneficiary_id,
+	capture_mode, COALESCE(ttl_seconds, 0), description, frequency, start_at, end_at, max_occurrences,
+	occurrences, next_run_at, status, created_at, updated_at`
+
+func scan(row interface{ Scan(...any) error }) (Schedule, error) {
+	var (
+		s          Schedule
+		ttlSeconds int64
+	)
+	err := row.Scan(&s.ID, &s.UserID, &s.Amount, &s.Currency, &s.FromAccount, &s.ToAccount, &s.BeneficiaryID,
+		&s.CaptureMode, &ttlSeconds, &s.Description, &s.Frequency, &s.StartAt, &s.EndAt, &s.MaxOccurrences,
+		&s.Occurrences, &s.NextRunAt, &s.Status, &s.CreatedAt, &s.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Schedule{}, ErrNotFound
+	}
+	s.TTL = time.Duration(ttlSeconds) * time.Second
+	return s, err
+}
+
+const executionColumns = `id, schedule_id, occurrence, scheduled_for, status, attempts, next_attempt_at,
+	last_error, payment_id, created_at, updated_at`
+
+func scanExecution(row interface{ Scan(...any) error }) (Execution, error) {
+	var e Execution
+	err := row.Scan(&e.ID, &e.ScheduleID, &e.Occurrence, &e.ScheduledFor, &e.Status, &e.Attempts,
+		&e.NextAttemptAt, &e.LastError, &e.PaymentID, &e.CreatedAt, &e.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Execution{}, ErrNotFound
+	}
+	return e, err
+}
+
+// Create stores s, which must have passed Validate, for s.UserID.
+func Create(ctx context.Context, q queryer, s Schedule) (Schedule, error) {
+	var ttlSeconds *int64
+	if s.TTL > 0 {
+		secs := int64(s.TTL / time.Second)
+		ttlSeconds = &secs
+	}
+	return scan(q.QueryRowContext(ctx, `INSERT INTO schedules
+			(user_id, amount, currency, from_account, to_account, beneficiary_id, capture_mode, ttl_seconds,
+			 description, frequency, start_at, end_at, max_occurrences, occurrences, next_run_at, status)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+		RETURNING `+columns,
+		s.UserID, s.Amount, s.Currency, s.FromAccount, s.ToAccount, s.BeneficiaryID, s.CaptureMode, ttlSeconds,
+		s.Description, string(s.Frequency), s.StartAt, s.EndAt, s.MaxOccurrences, s.Occurrences, s.NextRunAt, string(s.Status)))
+}
+
+// List returns userID's schedules, newest first.
+func List(ctx context.Context, q queryer, userID int) ([]Schedule, error) {
+	rows, err := q.QueryContext(ctx, `SELECT `+columns+` FROM schedules
+		WHERE user_id = $1
+		ORDER BY id DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Schedule
+	for rows.Next() {
+		s, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, s)
+	}
+	return list, rows.Err()
+}
+
+// Get returns one of userID's schedules.
+func Get(ctx context.Context, q queryer, userID int, id int64) (Schedule, error) {
+	return scan(q.QueryRowContext(ctx, `SELECT `+columns+` FROM schedules
+		WHERE id = $1 AND user_id = $2`, id, userID))
+}
+
+// ListExecutions returns up to limit of the occurrences of one of userID's
+// schedules, latest first.
+func ListExecutions(ctx context.Context, q queryer, userID int, scheduleID int64, limit int) ([]Execution, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	if _, err := Get(ctx, q, userID, scheduleID); err != nil {
+		return nil, err
+	}
+	rows, err := q.QueryContext(ctx, `SELECT `+executionColumns+` FROM schedule_executions
+		WHERE schedule_id = $1
+		ORDER BY occurrence DESC
+		LIMIT $2`, scheduleID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Execution
+	for rows.Next() {
+		e, err := scanExecution(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, e)
+	}
+	return list, rows.Err()
+}
+
+// Changes are the fields Update may modify; nil leaves a field as it is.
+type Changes struct {
+	Amount         *float64
+	Description    *string
+	EndAt          *time.Time
+	MaxOccurrences *int
+}
+
+// Update applies c to one of userID's active or paused schedules. Lowering
+// the end date or occurrence count below what has already been scheduled
+// completes the schedule.
+func Update(ctx context.Context, db *sql.DB, userID int, id int64, c Changes) (Schedule, error) {
+	return modify(ctx, db, userID, id, func(tx *sql.Tx, s *Schedule) error {
+		if s.Status != StatusActive && s.Status != StatusPaused {
+			return fmt.Errorf("%w: schedule is %s", ErrInvalidState, s.Status)
+		}
+		if c.Amount != nil {
+			s.Amount = *c.Amount
+		}
+		if c.Description != nil {
+			s.Description = c.Description
+		}
+		if c.EndAt != nil {
+			s.EndAt = c.EndAt
+		}
+		if c.MaxOccurrences != nil {
+			s.MaxOccurrences = c.MaxOccurrences
+		}
+		status := s.Status
+		s.scheduleNext()
+		if s.Status != StatusCompleted {
+			s.Status = status
+		}
+		return nil
+	})
+}
+
+// Pause stops one of userID's active schedules from running until it is
+// resumed. An occurrence already being retried waits too.
+func Pause(ctx context.Context, db *sql.DB, userID int, id int64) (Schedule, error) {
+	return modify(ctx, db, userID, id, func(tx *sql.Tx, s *Schedule) error {
+		if s.Status != StatusActive {
+			return fmt.Errorf("%w: only active schedules can be paused; schedule is %s", ErrInvalidState, s.Status)
+		}
+		s.Status = StatusPaused
+		return nil
+	})
+}
+
+// Resume reactivates one of userID's paused schedules. Occurrences that fell
+// due while it was paused are recorded as skipped rather than run late.
+func Resume(ctx context.Context, db *sql.DB, userID int, id int64, now time.Time) (Schedule, error) {
+	return modify(ctx, db, userID, id, func(tx *sql.Tx, s *Schedule) error {
+		if s.Status != StatusPaused {
+			return fmt.Errorf("%w: only paused schedules can be resumed; schedule is %s", ErrInvalidState, s.Status)
+		}
+		s.Status = StatusActive
+		for s.NextRunAt != nil && s.NextRunAt.Before(now) {
+			if err := skip(ctx, tx, s); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SkipNext skips the next occurrence of one of userID's active or paused
+// schedules. A skipped occurrence still counts towards max_occurrences.
+func SkipNext(ctx context.Context, db *sql.DB, userID int, id int64) (Schedule, error) {
+	return modify(ctx, db, userID, id, func(tx *sql.Tx, s *Schedule) error {
+		if (s.Status != StatusActive && s.Status != StatusPaused) || s.NextRunAt == nil {
+			return fmt.Errorf("%w: schedule is %s and has no upcoming occurrence", ErrInvalidState, s.Status)
+		}
+		return skip(ctx, tx, s)
+	})
+}
+
+// Cancel stops one of userID's schedules for good. Occurrences still
+// waiting for a retry are skipped; payments already made are unaffected.
+func Cancel(ctx context.Context, db *sql.DB, userID int, id int64) (Schedule, error) {
+	return modify(ctx, db, userID, id, func(tx *sql.Tx, s *Schedule) error {
+		if s.Status == StatusCancelled {
+			return nil
+		}
+		s.Status = StatusCancelled
+		s.NextRunAt = nil
+		_, err := tx.ExecContext(ctx, `UPDATE schedule_executions SET status = 'skipped'
+			WHERE schedule_id = $1 AND status = 'pending'`, s.ID)
+		return err
+	})
+}
+
+// modify locks one of userID's schedules, applies fn and saves the result in
+// one transaction.
+func modify(ctx context.Context, db *sql.DB, userID int, id int64, fn func(*sql.Tx, *Schedule) error) (Schedule, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return Schedule{}, err
+	}
+	defer tx.Rollback()
+
+	s, err := scan(tx.QueryRowContext(ctx, `SELECT `+columns+` FROM schedules
+		WHERE id = $1 AND user_id = $2
+		FOR UPDATE`, id, userID))
+	if err != nil {
+		return Schedule{}, err
+	}
+	if err := fn(tx, &s); err != nil {
+		return Schedule{}, err
+	}
+	if s, err = save(ctx, tx, s); err != nil {
+		return Schedule{}, err
+	}
+	return s, tx.Commit()
+}
+
+// save writes the fields of s that change after creation.
+func save(ctx context.Context, q queryer, s Schedule) (Schedule, error) {
+	return scan(q.QueryRowContext(ctx, `UPDATE schedules SET
+			amount = $2,
+			description = $3,
+			end_at = $4,
+			max_occurrences = $5,
+			occurrences = $6,
+			next_run_at = $7,
+			status = $8
+		WHERE id = $1
+		RETURNING `+columns,
+		s.ID, s.Amount, s.Description, s.EndAt, s.MaxOccurrences, s.Occurrences, s.NextRunAt, string(s.Status)))
+}
+
+// skip records s's next occurrence as skipped and moves past it.
+func skip(ctx context.Context, q queryer, s *Schedule) error {
+	if err := insertExecution(ctx, q, *s, ExecutionSkipped); err != nil {
+		return err
+	}
+	s.advance()
+	return nil
+}
+
+// insertExecution records s's next occurrence. The occurrence key absorbs a
+// replay of an occurrence that was already recorded.
+func insertExecution(ctx context.Context, q queryer, s Schedule, status ExecutionStatus) error {
+	_, err := q.ExecContext(ctx, `INSERT INTO schedule_executions (schedule_id, occurrence, scheduled_for, status)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (schedule_id, occurrence) DO NOTHING`,
+		s.ID, s.Occurrences+1, *s.NextRunAt, string(status))
+	return err
+}
@@ -0,0 +1,91 @@
+package statements
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/currencies"
+)
+
+// CSVColumns is the header row of a CSV statement. Columns are only ever
+// appended, so imports can rely on their positions.
+var CSVColumns = []string{
+	"type", "posted_at", "entry_id", "payment_id", "currency", "amount", "balance",
+	"status", "description", "from_account", "to_account", "razorpay_order_id", "razorpay_payment_id",
+}
+
+type csvWriter struct {
+	w      *csv.Writer
+	period Period
+	header bool
+}
+
+func newCSVWriter(w io.Writer, p Period) *csvWriter {
+	return &csvWriter{w: csv.NewWriter(w), period: p}
+}
+
+func (c *csvWriter) write(record []string) error {
+	if !c.header {
+		c.header = true
+		if err := c.w.Write(CSVColumns); err != nil {
+			return err
+		}
+	}
+	return c.w.Write(record)
+}
+
+// balance writes an opening or closing balance row, which leaves the entry
+// columns empty.
+func (c *csvWriter) balance(typ string, at time.Time, currency currencies.Currency, minor int64) error {
+	return c.write([]string{typ, at.UTC().Format(time.RFC3339), "", "", currency.Code, "", currency.Decimal(minor),
+		"", "", "", "", "", ""})
+}
+
+func (c *csvWriter) Begin(s Section) error {
+	return c.balance("opening_balance", c.period.From, s.Currency, s.OpeningMinor)
+}
+
+func (c *csvWriter) Entry(e Entry) error {
+	return c.write([]string{
+		string(e.Kind),
+		e.PostedAt.Format(time.RFC3339),
+		e.ID(),
+		strconv.Itoa(e.PaymentID),
+		e.Currency.Code,
+		e.Currency.Decimal(e.AmountMinor),
+		e.Currency.Decimal(e.BalanceMinor),
+		e.Status,
+		cell(e.Description),
+		cell(e.FromAccount),
+		cell(e.ToAccount),
+		cell(e.RazorpayOrderID),
+		cell(e.RazorpayPaymentID),
+	})
+}
+
+func (c *csvWriter) End(s Section) error {
+	return c.balance("closing_balance", c.period.To, s.Currency, s.ClosingMinor)
+}
+
+func (c *csvWriter) Close() error {
+	if !c.header {
+		// An empty statement still has its header row
+		if err := c.w.Write(CSVColumns); err != nil {
+			return err
+		}
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// cell keeps free text from being evaluated as a formula when the file is
+// opened in a spreadsheet.
+func cell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
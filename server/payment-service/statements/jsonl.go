@@ -0,0 +1,65 @@
+package statements
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/models"
+)
+
+type jsonlWriter struct {
+	buf    *bufio.Writer
+	enc    *json.Encoder
+	period Period
+}
+
+func newJSONLWriter(w io.Writer, p Period) *jsonlWriter {
+	buf := bufio.NewWriter(w)
+	return &jsonlWriter{buf: buf, enc: json.NewEncoder(buf), period: p}
+}
+
+func (j *jsonlWriter) Begin(s Section) error {
+	return j.enc.Encode(models.StatementLine{
+		Type:         "opening_balance",
+		PostedAt:     j.period.From.UTC(),
+		Currency:     s.Currency.Code,
+		Balance:      s.Currency.FromMinor(s.OpeningMinor),
+		BalanceMinor: s.OpeningMinor,
+	})
+}
+
+func (j *jsonlWriter) Entry(e Entry) error {
+	amount := e.Currency.FromMinor(e.AmountMinor)
+	return j.enc.Encode(models.StatementLine{
+		Type:              string(e.Kind),
+		PostedAt:          e.PostedAt,
+		EntryID:           e.ID(),
+		PaymentID:         e.PaymentID,
+		Currency:          e.Currency.Code,
+		Amount:            &amount,
+		AmountMinor:       &e.AmountMinor,
+		Balance:           e.Currency.FromMinor(e.BalanceMinor),
+		BalanceMinor:      e.BalanceMinor,
+		Status:            e.Status,
+		Description:       e.Description,
+		FromAccount:       e.FromAccount,
+		ToAccount:         e.ToAccount,
+		RazorpayOrderID:   e.RazorpayOrderID,
+		RazorpayPaymentID: e.RazorpayPaymentID,
+	})
+}
+
+func (j *jsonlWriter) End(s Section) error {
+	return j.enc.Encode(models.StatementLine{
+		Type:         "closing_balance",
+		PostedAt:     j.period.To.UTC(),
+		Currency:     s.Currency.Code,
+		Balance:      s.Currency.FromMinor(s.ClosingMinor),
+		BalanceMinor: s.ClosingMinor,
+	})
+}
+
+func (j *jsonlWriter) Close() error {
+	return j.buf.Flush()
+}
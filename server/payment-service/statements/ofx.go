@@ -0,0 +1,97 @@
+package statements
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ofxWriter writes OFX 2.2 bank statements, one statement response per
+// currency. OFX has no opening balance, so only the closing balance is
+// written, as LEDGERBAL.
+type ofxWriter struct {
+	buf     *bufio.Writer
+	account string
+	period  Period
+	now     time.Time
+	trnuid  int
+}
+
+func newOFXWriter(w io.Writer, userID int, p Period, now time.Time) *ofxWriter {
+	o := &ofxWriter{buf: bufio.NewWriter(w), account: fmt.Sprintf("gopay-%d", userID), period: p, now: now}
+	fmt.Fprintf(o.buf, `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+<OFX>
+<SIGNONMSGSRSV1><SONRS><STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS><DTSERVER>%s</DTSERVER><LANGUAGE>ENG</LANGUAGE></SONRS></SIGNONMSGSRSV1>
+<BANKMSGSRSV1>
+`, ofxTime(now))
+	return o
+}
+
+func (o *ofxWriter) Begin(s Section) error {
+	o.trnuid++
+	_, err := fmt.Fprintf(o.buf, `<STMTTRNRS><TRNUID>%d</TRNUID><STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>
+<STMTRS><CURDEF>%s</CURDEF>
+<BANKACCTFROM><BANKID>GOPAY</BANKID><ACCTID>%s</ACCTID><ACCTTYPE>CHECKING</ACCTTYPE></BANKACCTFROM>
+<BANKTRANLIST><DTSTART>%s</DTSTART><DTEND>%s</DTEND>
+`, o.trnuid, ofxText(s.Currency.Code), o.account, ofxTime(o.period.From), ofxTime(o.period.To))
+	return err
+}
+
+func (o *ofxWriter) Entry(e Entry) error {
+	name := fmt.Sprintf("Payment %d", e.PaymentID)
+	switch e.Kind {
+	case KindReceived:
+		name = fmt.Sprintf("Payment %d received", e.PaymentID)
+	case KindRefund:
+		name = fmt.Sprintf("Refund of payment %d", e.PaymentID)
+	case KindChargeback:
+		name = fmt.Sprintf("Chargeback of payment %d", e.PaymentID)
+	}
+	trntype := "DEBIT"
+	if e.AmountMinor > 0 {
+		trntype = "CREDIT"
+	}
+	memo := e.Description
+	if memo == "" {
+		memo = "To " + e.ToAccount
+	}
+	_, err := fmt.Fprintf(o.buf, "<STMTTRN><TRNTYPE>%s</TRNTYPE><DTPOSTED>%s</DTPOSTED><TRNAMT>%s</TRNAMT>"+
+		"<FITID>%s</FITID><NAME>%s</NAME><MEMO>%s</MEMO></STMTTRN>\n",
+		trntype, ofxTime(e.PostedAt), e.Currency.Decimal(e.AmountMinor), e.ID(), ofxText(name), ofxText(truncate(memo, 255)))
+	return err
+}
+
+func (o *ofxWriter) End(s Section) error {
+	_, err := fmt.Fprintf(o.buf, "</BANKTRANLIST>\n<LEDGERBAL><BALAMT>%s</BALAMT><DTASOF>%s</DTASOF></LEDGERBAL>\n</STMTRS>\n</STMTTRNRS>\n",
+		s.Currency.Decimal(s.ClosingMinor), ofxTime(o.period.To))
+	return err
+}
+
+func (o *ofxWriter) Close() error {
+	o.buf.WriteString("</BANKMSGSRSV1>\n</OFX>\n")
+	return o.buf.Flush()
+}
+
+// ofxTime formats t as an OFX date-time in UTC.
+func ofxTime(t time.Time) string {
+	return t.UTC().Format("20060102150405") + ".000[0:GMT]"
+}
+
+func ofxText(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// truncate shortens s to at most n runes, the limit OFX places on the field.
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
@@ -0,0 +1,315 @@
+// Package statements exports the money a user moved in a period: captured
+// payments they sent as debits, payments received through their links as
+// credits, and refunds and chargebacks reversing either. The export
+// has one section per currency: an opening balance, the entries in posting
+// order, then a closing balance.
+//
+// The service holds no funds, so there is no account balance to report.
+// Balances are instead the running net of the user's own entries, starting
+// from zero at their first payment. That keeps every statement
+// self-consistent, with closing = opening + the sum of the entries.
+package statements
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/currencies"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/models"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/tracing"
+)
+
+// Format is an export file format.
+type Format string
+
+const (
+	FormatCSV   Format = "csv"
+	FormatOFX   Format = "ofx"
+	FormatJSONL Format = "jsonl"
+)
+
+// ParseFormat returns the format named s; an empty s means CSV.
+func ParseFormat(s string) (Format, bool) {
+	switch f := Format(strings.ToLower(s)); f {
+	case "":
+		return FormatCSV, true
+	case FormatCSV, FormatOFX, FormatJSONL:
+		return f, true
+	}
+	return "", false
+}
+
+// ContentType returns the Content-Type header for f.
+func (f Format) ContentType() string {
+	switch f {
+	case FormatOFX:
+		return "application/x-ofx"
+	case FormatJSONL:
+		return "application/x-ndjson"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Kind is the type of a statement entry.
+type Kind string
+
+const (
+	KindPayment Kind = "payment"
+	// A payment made to the user through one of their links
+	KindReceived Kind = "received"
+	KindRefund   Kind = "refund"
+	// A lost dispute returned money to the payer
+	KindChargeback Kind = "chargeback"
+)
+
+// Period is the half-open interval [From, To) entries are posted in.
+type Period struct {
+	From, To time.Time
+}
+
+// Entry is one movement of money. Payments are negative and received
+// payments positive; refunds and chargebacks reverse the payment they
+// belong to.
+type Entry struct {
+	Kind         Kind
+	PaymentID    int
+	PostedAt     time.Time
+	Currency     currencies.Currency
+	AmountMinor  int64
+	BalanceMinor int64 // after this entry
+
+	Status      string
+	Description string
+
+	// Masked to the last four characters
+	FromAccount string
+	ToAccount   string
+
+	RazorpayOrderID   string
+	RazorpayPaymentID string
+}
+
+// ID identifies the entry uniquely within a user's statements.
+func (e Entry) ID() string {
+	return fmt.Sprintf("%s-%d", e.Kind, e.PaymentID)
+}
+
+// Section is one currency of a statement.
+type Section struct {
+	Currency     currencies.Currency
+	OpeningMinor int64
+	ClosingMinor int64 // set once the section ends
+}
+
+// Writer writes a statement in some format. Export calls Begin and End
+// around the entries of each section, then Close once.
+type Writer interface {
+	Begin(s Section) error
+	Entry(e Entry) error
+	End(s Section) error
+	Close() error
+}
+
+// scale is the number of minor units in a payment's major unit, two digits
+// for currencies missing from the registry as in currencies.Lookup.
+const scale = `POWER(10::NUMERIC, COALESCE((SELECT exponent FROM currencies c WHERE c.code = payments.currency), 2))`
+
+// sign is -1 for the payments a user sent and 1 for those they received.
+const sign = `CASE WHEN direction = 'incoming' THEN 1 ELSE -1 END`
+
+// entries is the union of payments, refunds and chargebacks, with amounts
+// in minor units. A refund returns the whole captured amount, a chargeback
+// the amount disputed.
+const entries = `
+	SELECT CASE WHEN direction = 'incoming' THEN 'received' ELSE 'payment' END AS kind,
+		id, captured_at AS posted_at, currency,
+		` + sign + ` * ROUND(COALESCE(captured_amount, amount) * ` + scale + `)::BIGINT AS amount_minor,
+		status, description, from_account, to_account, razorpay_order_id, razorpay_payment_id
+	FROM payments WHERE user_id = $1 AND captured_at IS NOT NULL
+	UNION ALL
+	SELECT 'refund', id, refunded_at, currency,
+		-` + sign + ` * ROUND(COALESCE(captured_amount, amount) * ` + scale + `)::BIGINT,
+		status, description, from_account, to_account, razorpay_order_id, razorpay_payment_id
+	FROM payments WHERE user_id = $1 AND refunded_at IS NOT NULL
+	UNION ALL
+	SELECT 'chargeback', id, charged_back_at, currency,
+		-` + sign + ` * ROUND(charged_back_amount * ` + scale + `)::BIGINT,
+		status, description, from_account, to_account, razorpay_order_id, razorpay_payment_id
+	FROM payments WHERE user_id = $1 AND charged_back_at IS NOT NULL`
+
+// Statement is an open query over a user's entries. It holds a database
+// connection and transaction until closed.
+type Statement struct {
+	registry currencies.Registry
+	openings []Section
+	tx       *sql.Tx
+	rows     *sql.Rows
+}
+
+// Query runs the statement queries for userID over p. Entries are read as
+// they are exported, so the statement never sits in memory. Both queries
+// read one snapshot, so a payment captured or refunded meanwhile cannot
+// land in the opening balances but not the entries, or the other way round.
+func Query(ctx context.Context, db *sql.DB, userID int, p Period) (*Statement, error) {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	st, err := query(ctx, tx, userID, p)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return st, nil
+}
+
+func query(ctx context.Context, tx *sql.Tx, userID int, p Period) (*Statement, error) {
+	openingQuery := `SELECT currency, SUM(amount_minor) FROM (` + entries + `) e
+		WHERE posted_at < $2 GROUP BY currency ORDER BY currency`
+	spanCtx, span := tracing.StartDBSpan(ctx, "SELECT", "payments", openingQuery)
+	rows, err := tx.QueryContext(spanCtx, openingQuery, userID, p.From)
+	tracing.End(span, err)
+	if err != nil {
+		return nil, fmt.Errorf("query opening balances: %w", err)
+	}
+	defer rows.Close()
+
+	st := &Statement{tx: tx}
+	var codes []string
+	for rows.Next() {
+		var code string
+		s := Section{}
+		if err := rows.Scan(&code, &s.OpeningMinor); err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		st.openings = append(st.openings, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// A transaction runs one query at a time
+	rows.Close()
+
+	if st.registry, err = currencies.Load(ctx, tx); err != nil {
+		return nil, fmt.Errorf("load currencies: %w", err)
+	}
+	for i, code := range codes {
+		st.openings[i].Currency = st.registry.Lookup(code)
+	}
+
+	entryQuery := `SELECT kind, id, posted_at, currency, amount_minor, status,
+			COALESCE(description, ''), from_account, to_account, razorpay_order_id, COALESCE(razorpay_payment_id, '')
+		FROM (` + entries + `) e
+		WHERE posted_at >= $2 AND posted_at < $3
+		ORDER BY currency, posted_at, id, kind`
+	spanCtx, span = tracing.StartDBSpan(ctx, "SELECT", "payments", entryQuery)
+	st.rows, err = tx.QueryContext(spanCtx, entryQuery, userID, p.From, p.To)
+	tracing.End(span, err)
+	if err != nil {
+		return nil, fmt.Errorf("query entries: %w", err)
+	}
+	return st, nil
+}
+
+// Close releases the statement's connection. The transaction only read, so
+// it is rolled back.
+func (st *Statement) Close() error {
+	err := st.rows.Close()
+	if rbErr := st.tx.Rollback(); err == nil && !errors.Is(rbErr, sql.ErrTxDone) {
+		err = rbErr
+	}
+	return err
+}
+
+// Export writes the statement to w and closes w. Every currency with an
+// opening balance or an entry in the period gets a section.
+func (st *Statement) Export(w Writer) error {
+	var (
+		current *Section
+		balance int64
+	)
+	// begin ends the current section and starts the one for currency,
+	// first writing sections for any currencies before it that only have
+	// an opening balance.
+	begin := func(currency string) error {
+		if current != nil {
+			current.ClosingMinor = balance
+			if err := w.End(*current); err != nil {
+				return err
+			}
+			current = nil
+		}
+		for len(st.openings) > 0 && (currency == "" || st.openings[0].Currency.Code < currency) {
+			s := st.openings[0]
+			st.openings = st.openings[1:]
+			s.ClosingMinor = s.OpeningMinor
+			if err := w.Begin(s); err != nil {
+				return err
+			}
+			if err := w.End(s); err != nil {
+				return err
+			}
+		}
+		if currency == "" {
+			return nil
+		}
+
+		s := Section{Currency: st.registry.Lookup(currency)}
+		if len(st.openings) > 0 && st.openings[0].Currency.Code == currency {
+			s = st.openings[0]
+			st.openings = st.openings[1:]
+		}
+		current, balance = &s, s.OpeningMinor
+		return w.Begin(s)
+	}
+
+	for st.rows.Next() {
+		var e Entry
+		var code string
+		if err := st.rows.Scan(&e.Kind, &e.PaymentID, &e.PostedAt, &code, &e.AmountMinor, &e.Status,
+			&e.Description, &e.FromAccount, &e.ToAccount, &e.RazorpayOrderID, &e.RazorpayPaymentID); err != nil {
+			return err
+		}
+		e.Currency = st.registry.Lookup(code)
+		if current == nil || code != current.Currency.Code {
+			if err := begin(code); err != nil {
+				return err
+			}
+		}
+
+		balance += e.AmountMinor
+		e.BalanceMinor = balance
+		e.PostedAt = e.PostedAt.UTC()
+		e.Description = strings.Join(strings.Fields(e.Description), " ")
+		e.FromAccount = models.MaskAccount(e.FromAccount)
+		e.ToAccount = models.MaskAccount(e.ToAccount)
+		if err := w.Entry(e); err != nil {
+			return err
+		}
+	}
+	if err := st.rows.Err(); err != nil {
+		return err
+	}
+	if err := begin(""); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// NewWriter returns a Writer for userID's statement over p in format f,
+// generated at now.
+func NewWriter(f Format, w io.Writer, userID int, p Period, now time.Time) Writer {
+	switch f {
+	case FormatOFX:
+		return newOFXWriter(w, userID, p, now)
+	case FormatJSONL:
+		return newJSONLWriter(w, p)
+	}
+	return newCSVWriter(w, p)
+}
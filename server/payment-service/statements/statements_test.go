@@ -0,0 +1,121 @@
+package statements
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/currencies"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/internal/dbtest"
+)
+
+func TestOFXEntryType(t *testing.T) {
+	inr := currencies.Currency{Code: "INR", Exponent: 2}
+	tests := []struct {
+		e    Entry
+		want string
+	}{
+		{Entry{Kind: KindPayment, PaymentID: 1, Currency: inr, AmountMinor: -50000}, "<TRNTYPE>DEBIT</TRNTYPE>"},
+		{Entry{Kind: KindReceived, PaymentID: 2, Currency: inr, AmountMinor: 50000}, "<TRNTYPE>CREDIT</TRNTYPE>"},
+		{Entry{Kind: KindRefund, PaymentID: 1, Currency: inr, AmountMinor: 50000}, "<TRNTYPE>CREDIT</TRNTYPE>"},
+		// Refunding money the user received takes it back
+		{Entry{Kind: KindRefund, PaymentID: 2, Currency: inr, AmountMinor: -50000}, "<TRNTYPE>DEBIT</TRNTYPE>"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		o := newOFXWriter(&buf, 1, Period{}, time.Now())
+		if err := o.Entry(tt.e); err != nil {
+			t.Fatal(err)
+		}
+		o.buf.Flush()
+		if !strings.Contains(buf.String(), tt.want) {
+			t.Errorf("%s of %d: %s, want %s", tt.e.Kind, tt.e.AmountMinor, buf.String(), tt.want)
+		}
+	}
+}
+
+// ledger records the entries of an exported statement.
+type ledger struct {
+	entries []Entry
+	closing int64
+}
+
+func (l *ledger) Begin(Section) error { return nil }
+func (l *ledger) Entry(e Entry) error {
+	l.entries = append(l.entries, e)
+	return nil
+}
+func (l *ledger) End(s Section) error {
+	l.closing = s.ClosingMinor
+	return nil
+}
+func (l *ledger) Close() error { return nil }
+
+// insertCaptured stores a payment of amount INR for user 5, captured at
+// capturedAt and refunded a minute later if refunded is set.
+func insertCaptured(t *testing.T, database *sql.DB, direction string, amount float64, capturedAt time.Time, refunded bool) int {
+	t.Helper()
+	var refundedAt *time.Time
+	status := "captured"
+	if refunded {
+		at := capturedAt.Add(time.Minute)
+		refundedAt, status = &at, "refunded"
+	}
+	var id int
+	err := database.QueryRowContext(context.Background(), `INSERT INTO payments
+			(user_id, amount, currency, from_account, to_account, razorpay_order_id, status, created_at,
+			 captured_at, refunded_at, direction)
+		VALUES (5, $1, 'INR', 'ACC-FROM-1234', 'ACC-TO-5678', $2, $3, $4, $4, $5, $6)
+		RETURNING id`, amount, "order_"+direction+capturedAt.Format("150405"), status, capturedAt, refundedAt, direction).Scan(&id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func TestIncomingPayments(t *testing.T) {
+	database := dbtest.Open(t)
+	ctx := context.Background()
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	sent := insertCaptured(t, database, "outgoing", 300, start, false)
+	received := insertCaptured(t, database, "incoming", 500, start.Add(10*time.Minute), false)
+	returned := insertCaptured(t, database, "incoming", 200, start.Add(20*time.Minute), true)
+
+	st, err := Query(ctx, database, 5, Period{From: start.Add(-time.Minute), To: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	var l ledger
+	if err := st.Export(&l); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		kind    Kind
+		id      int
+		amount  int64
+		balance int64
+	}{
+		{KindPayment, sent, -30000, -30000},
+		{KindReceived, received, 50000, 20000},
+		{KindReceived, returned, 20000, 40000},
+		{KindRefund, returned, -20000, 20000},
+	}
+	if len(l.entries) != len(want) {
+		t.Fatalf("statement has %d entries, want %d", len(l.entries), len(want))
+	}
+	for i, w := range want {
+		if e := l.entries[i]; e.Kind != w.kind || e.PaymentID != w.id || e.AmountMinor != w.amount || e.BalanceMinor != w.balance {
+			t.Errorf("entry %d: %s of %d for %d, balance %d; want %s of %d for %d, balance %d",
+				i, e.Kind, e.AmountMinor, e.PaymentID, e.BalanceMinor, w.kind, w.amount, w.id, w.balance)
+		}
+	}
+	if l.closing != 20000 {
+		t.Errorf("closing %d, want 20000", l.closing)
+	}
+}
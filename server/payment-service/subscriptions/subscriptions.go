@@ -0,0 +1,409 @@
+// Package subscriptions stores recurring plans and the Razorpay
+// subscriptions users hold on them. Razorpay owns the billing; local state
+// follows its subscription.* webhooks.
+package subscriptions
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/currencies"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/razorpay"
+)
+
+// ErrNotFound is returned when a plan or subscription does not exist or
+// belongs to another user.
+var ErrNotFound = errors.New("not found")
+
+// Period is the unit a plan bills in.
+type Period string
+
+const (
+	PeriodDaily   Period = "daily"
+	PeriodWeekly  Period = "weekly"
+	PeriodMonthly Period = "monthly"
+	PeriodYearly  Period = "yearly"
+)
+
+// Status is a subscription's Razorpay status.
+type Status string
+
+const (
+	StatusCreated       Status = "created" // waiting for the customer to authorize the mandate
+	StatusAuthenticated Status = "authenticated"
+	StatusActive        Status = "active"
+	StatusPending       Status = "pending" // a charge failed and is being retried
+	StatusHalted        Status = "halted"  // retries exhausted
+	StatusPaused        Status = "paused"
+	StatusCancelled     Status = "cancelled"
+	StatusCompleted     Status = "completed"
+	StatusExpired       Status = "expired"
+)
+
+// Ended reports whether s is final.
+func (s Status) Ended() bool {
+	return s == StatusCancelled || s == StatusCompleted || s == StatusExpired
+}
+
+// Plan is a recurring price users can subscribe to.
+type Plan struct {
+	ID             int64
+	RazorpayPlanID string
+	Name           string
+	Description    *string
+	Amount         float64
+	Currency       string
+	Period         Period
+	Interval       int // billed every Interval periods
+	TrialDays      int
+	Active         bool
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// Subscription is a user's subscription to a plan.
+type Subscription struct {
+	ID                     int64
+	UserID                 int
+	PlanID                 int64
+	RazorpaySubscriptionID string
+	Status                 Status
+	Quantity               int
+	TotalCount             int
+	PaidCount              int
+	// ShortURL is where the customer authorizes the recurring mandate.
+	ShortURL          string
+	TrialEndsAt       *time.Time
+	CurrentStart      *time.Time
+	CurrentEnd        *time.Time
+	ChargeAt          *time.Time
+	EndedAt           *time.Time
+	CancelAtPeriodEnd bool
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+// Invoice records one paid billing cycle.
+type Invoice struct {
+	ID                int64
+	SubscriptionID    int64
+	Cycle             int
+	RazorpayInvoiceID *string
+	RazorpayPaymentID string
+	Amount            float64
+	Currency          string
+	PeriodStart       *time.Time
+	PeriodEnd         *time.Time
+	CreatedAt         time.Time
+}
+
+// ValidatePlan trims and checks p's fields, returning a description of the
+// first problem found.
+func ValidatePlan(p *Plan) error {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" || len(p.Name) > 128 {
+		return errors.New("name is required and must be at most 128 characters")
+	}
+	if p.Amount <= 0 {
+		return errors.New("amount must be positive")
+	}
+	if p.Currency == "" {
+		p.Currency = "INR"
+	}
+	p.Currency = strings.ToUpper(p.Currency)
+	if len(p.Currency) != 3 {
+		return errors.New("currency must be a 3-letter ISO 4217 code")
+	}
+	if p.Interval == 0 {
+		p.Interval = 1
+	}
+	switch p.Period {
+	case PeriodDaily:
+		// Razorpay rejects daily plans billed more often than weekly
+		if p.Interval < 7 {
+			return errors.New("daily plans need an interval of at least 7")
+		}
+	case PeriodWeekly, PeriodMonthly, PeriodYearly:
+		if p.Interval < 1 {
+			return errors.New("interval must be positive")
+		}
+	default:
+		return fmt.Errorf("period must be %s, %s, %s or %s", PeriodDaily, PeriodWeekly, PeriodMonthly, PeriodYearly)
+	}
+	if p.TrialDays < 0 || p.TrialDays > 365 {
+		return errors.New("trial_days must be between 0 and 365")
+	}
+	return nil
+}
+
+// queryer is satisfied by *sql.DB and *sql.Tx.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+const planColumns = `id, razorpay_plan_id, name, description, amount, currency, period, interval_count,
+	trial_days, active, created_at, updated_at`
+
+func scanPlan(row interface{ Scan(...any) error }) (Plan, error) {
+	var p Plan
+	err := row.Scan(&p.ID, &p.RazorpayPlanID, &p.Name, &p.Description, &p.Amount, &p.Currency, &p.Period,
+		&p.Interval, &p.TrialDays, &p.Active, &p.CreatedAt, &p.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Plan{}, ErrNotFound
+	}
+	return p, err
+}
+
+const subscriptionColumns = `id, user_id, plan_id, razorpay_subscription_id, status, quantity, total_count,
+	paid_count, COALESCE(short_url, ''), trial_ends_at, current_start, current_end, charge_at, ended_at,
+	cancel_at_period_end, created_at, updated_at`
+
+func scanSubscription(row interface{ Scan(...any) error }) (Subscription, error) {
+	var s Subscription
+	err := row.Scan(&s.ID, &s.UserID, &s.PlanID, &s.RazorpaySubscriptionID, &s.Status, &s.Quantity,
+		&s.TotalCount, &s.PaidCount, &s.ShortURL, &s.TrialEndsAt, &s.CurrentStart, &s.CurrentEnd,
+		&s.ChargeAt, &s.EndedAt, &s.CancelAtPeriodEnd, &s.CreatedAt, &s.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Subscription{}, ErrNotFound
+	}
+	return s, err
+}
+
+const invoiceColumns = `id, subscription_id, cycle, razorpay_invoice_id, razorpay_payment_id, amount,
+	currency, period_start, period_end, created_at`
+
+func scanInvoice(row interface{ Scan(...any) error }) (Invoice, error) {
+	var i Invoice
+	err := row.Scan(&i.ID, &i.SubscriptionID, &i.Cycle, &i.RazorpayInvoiceID, &i.RazorpayPaymentID,
+		&i.Amount, &i.Currency, &i.PeriodStart, &i.PeriodEnd, &i.CreatedAt)
+	return i, err
+}
+
+// CreatePlan stores p, which must have passed ValidatePlan and been created
+// at Razorpay.
+func CreatePlan(ctx context.Context, q queryer, p Plan) (Plan, error) {
+	return scanPlan(q.QueryRowContext(ctx, `INSERT INTO plans
+			(razorpay_plan_id, name, description, amount, currency, period, interval_count, trial_days)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING `+planColumns,
+		p.RazorpayPlanID, p.Name, p.Description, p.Amount, p.Currency, string(p.Period), p.Interval, p.TrialDays))
+}
+
+// ListPlans returns the plans, optionally only those open to new
+// subscriptions, newest first.
+func ListPlans(ctx context.Context, q queryer, activeOnly bool) ([]Plan, error) {
+	rows, err := q.QueryContext(ctx, `SELECT `+planColumns+` FROM plans
+		WHERE active OR NOT $1
+		ORDER BY id DESC`, activeOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Plan
+	for rows.Next() {
+		p, err := scanPlan(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, p)
+	}
+	return list, rows.Err()
+}
+
+// GetPlan returns a plan.
+func GetPlan(ctx context.Context, q queryer, id int64) (Plan, error) {
+	return scanPlan(q.QueryRowContext(ctx, `SELECT `+planColumns+` FROM plans WHERE id = $1`, id))
+}
+
+// SetPlanActive opens or closes a plan to new subscriptions. Existing
+// subscriptions are unaffected.
+func SetPlanActive(ctx context.Context, q queryer, id int64, active bool) (Plan, error) {
+	return scanPlan(q.QueryRowContext(ctx, `UPDATE plans SET active = $2
+		WHERE id = $1
+		RETURNING `+planColumns, id, active))
+}
+
+// CreateSubscription stores the Razorpay subscription sub that userID took
+// out on plan.
+func CreateSubscription(ctx context.Context, q queryer, userID int, plan Plan, sub razorpay.SubscriptionEntity) (Subscription, error) {
+	var trialEndsAt *time.Time
+	if plan.TrialDays > 0 {
+		trialEndsAt = unixTime(sub.StartAt)
+	}
+	return scanSubscription(q.QueryRowContext(ctx, `INSERT INTO subscriptions
+			(user_id, plan_id, razorpay_subscription_id, status, quantity, total_count, paid_count,
+			 short_url, trial_ends_at, charge_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), $9, $10)
+		RETURNING `+subscriptionColumns,
+		userID, plan.ID, sub.ID, sub.Status, max(sub.Quantity, 1), sub.TotalCount, sub.PaidCount,
+		sub.ShortURL, trialEndsAt, unixTime(sub.ChargeAt)))
+}
+
+// ListSubscriptions returns userID's subscriptions, newest first.
+func ListSubscriptions(ctx context.Context, q queryer, userID int) ([]Subscription, error) {
+	rows, err := q.QueryContext(ctx, `SELECT `+subscriptionColumns+` FROM subscriptions
+		WHERE user_id = $1
+		ORDER BY id DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Subscription
+	for rows.Next() {
+		s, err := scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, s)
+	}
+	return list, rows.Err()
+}
+
+// GetSubscription returns one of userID's subscriptions.
+func GetSubscription(ctx context.Context, q queryer, userID int, id int64) (Subscription, error) {
+	return scanSubscription(q.QueryRowContext(ctx, `SELECT `+subscriptionColumns+` FROM subscriptions
+		WHERE id = $1 AND user_id = $2`, id, userID))
+}
+
+// ListInvoices returns the paid cycles of one of userID's subscriptions,
+// latest first.
+func ListInvoices(ctx context.Context, q queryer, userID int, subscriptionID int64) ([]Invoice, error) {
+	if _, err := GetSubscription(ctx, q, userID, subscriptionID); err != nil {
+		return nil, err
+	}
+	rows, err := q.QueryContext(ctx, `SELECT `+invoiceColumns+` FROM subscription_invoices
+		WHERE subscription_id = $1
+		ORDER BY cycle DESC, id DESC`, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Invoice
+	for rows.Next() {
+		i, err := scanInvoice(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, i)
+	}
+	return list, rows.Err()
+}
+
+// Sync copies the state Razorpay returned from a pause, resume or cancel
+// call onto subscription id. cancelAtPeriodEnd is stored when set.
+func Sync(ctx context.Context, q queryer, id int64, sub razorpay.SubscriptionEntity, cancelAtPeriodEnd bool) (Subscription, error) {
+	return scanSubscription(q.QueryRowContext(ctx, `UPDATE subscriptions SET
+			status = $2,
+			paid_count = $3,
+			current_start = COALESCE($4, current_start),
+			current_end = COALESCE($5, current_end),
+			charge_at = $6,
+			ended_at = $7,
+			cancel_at_period_end = cancel_at_period_end OR $8
+		WHERE id = $1
+		RETURNING `+subscriptionColumns,
+		id, sub.Status, sub.PaidCount, unixTime(sub.CurrentStart), unixTime(sub.CurrentEnd),
+		unixTime(sub.ChargeAt), unixTime(sub.EndedAt), cancelAtPeriodEnd))
+}
+
+// ApplyWebhook updates the subscription named in a subscription.* event and,
+// for subscription.charged, records the cycle's invoice. It returns
+// ErrNotFound for subscriptions not created by this service. changed is
+// false when the event was older than the last one applied or already
+// recorded.
+func ApplyWebhook(ctx context.Context, tx *sql.Tx, event razorpay.WebhookEvent) (s Subscription, changed bool, err error) {
+	if event.Payload.Subscription == nil {
+		return Subscription{}, false, ErrNotFound
+	}
+	entity := event.Payload.Subscription.Entity
+	eventAt := time.Unix(event.CreatedAt, 0)
+
+	var (
+		id          int64
+		lastApplied sql.NullTime
+	)
+	err = tx.QueryRowContext(ctx, `SELECT id, gateway_updated_at FROM subscriptions
+		WHERE razorpay_subscription_id = $1
+		FOR UPDATE`, entity.ID).Scan(&id, &lastApplied)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Subscription{}, false, ErrNotFound
+	}
+	if err != nil {
+		return Subscription{}, false, err
+	}
+
+	// Razorpay does not guarantee delivery order; an older event must not
+	// overwrite newer state, though its invoice still counts
+	if !lastApplied.Valid || !eventAt.Before(lastApplied.Time) {
+		s, err = scanSubscription(tx.QueryRowContext(ctx, `UPDATE subscriptions SET
+				status = $2,
+				paid_count = $3,
+				current_start = $4,
+				current_end = $5,
+				charge_at = $6,
+				ended_at = $7,
+				gateway_updated_at = $8
+			WHERE id = $1
+			RETURNING `+subscriptionColumns,
+			id, entity.Status, entity.PaidCount, unixTime(entity.CurrentStart), unixTime(entity.CurrentEnd),
+			unixTime(entity.ChargeAt), unixTime(entity.EndedAt), eventAt))
+		changed = true
+	} else {
+		s, err = scanSubscription(tx.QueryRowContext(ctx, `SELECT `+subscriptionColumns+` FROM subscriptions
+			WHERE id = $1`, id))
+	}
+	if err != nil {
+		return Subscription{}, false, fmt.Errorf("update subscription %d: %w", id, err)
+	}
+
+	if event.Event == "subscription.charged" && event.Payload.Payment != nil {
+		payment := event.Payload.Payment.Entity
+		var invoiceID *string
+		if payment.InvoiceID != "" {
+			invoiceID = &payment.InvoiceID
+		}
+		reg, err := currencies.Load(ctx, tx)
+		if err != nil {
+			return Subscription{}, false, err
+		}
+		res, err := tx.ExecContext(ctx, `INSERT INTO subscription_invoices
+				(subscription_id, cycle, razorpay_invoice_id, razorpay_payment_id, amount, currency, period_start, period_end)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			ON CONFLICT (razorpay_payment_id) DO NOTHING`,
+			s.ID, entity.PaidCount, invoiceID, payment.ID, reg.Lookup(payment.Currency).FromMinor(payment.Amount), payment.Currency,
+			unixTime(entity.CurrentStart), unixTime(entity.CurrentEnd))
+		if err != nil {
+			return Subscription{}, false, fmt.Errorf("record invoice for subscription %d: %w", s.ID, err)
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			changed = true
+		}
+	}
+	return s, changed, nil
+}
+
+// TrialStart returns the Unix time a new subscription to p starts billing,
+// or 0 to bill immediately.
+func (p Plan) TrialStart(now time.Time) int64 {
+	if p.TrialDays == 0 {
+		return 0
+	}
+	return now.AddDate(0, 0, p.TrialDays).Unix()
+}
+
+func unixTime(sec *int64) *time.Time {
+	if sec == nil || *sec == 0 {
+		return nil
+	}
+	t := time.Unix(*sec, 0).UTC()
+	return &t
+}
@@ -0,0 +1,183 @@
+package subscriptions
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/internal/dbtest"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/razorpay"
+)
+
+func TestValidatePlan(t *testing.T) {
+	tests := []struct {
+		name    string
+		plan    Plan
+		wantErr string
+		want    Plan // fields ValidatePlan fills in
+	}{
+		{name: "defaults", plan: Plan{Name: " Pro ", Amount: 499, Period: PeriodMonthly}, want: Plan{Name: "Pro", Amount: 499, Currency: "INR", Period: PeriodMonthly, Interval: 1}},
+		{name: "currency upper-cased", plan: Plan{Name: "Pro", Amount: 5, Currency: "usd", Period: PeriodYearly, Interval: 2, TrialDays: 14}, want: Plan{Name: "Pro", Amount: 5, Currency: "USD", Period: PeriodYearly, Interval: 2, TrialDays: 14}},
+		{name: "weekly in days", plan: Plan{Name: "Box", Amount: 99, Period: PeriodDaily, Interval: 7}, want: Plan{Name: "Box", Amount: 99, Currency: "INR", Period: PeriodDaily, Interval: 7}},
+		{name: "no name", plan: Plan{Name: "  ", Amount: 1, Period: PeriodMonthly}, wantErr: "name is required and must be at most 128 characters"},
+		{name: "free", plan: Plan{Name: "Free", Period: PeriodMonthly}, wantErr: "amount must be positive"},
+		{name: "bad currency", plan: Plan{Name: "Pro", Amount: 1, Currency: "RUPEE", Period: PeriodMonthly}, wantErr: "currency must be a 3-letter ISO 4217 code"},
+		{name: "daily too often", plan: Plan{Name: "Pro", Amount: 1, Period: PeriodDaily}, wantErr: "daily plans need an interval of at least 7"},
+		{name: "negative interval", plan: Plan{Name: "Pro", Amount: 1, Period: PeriodWeekly, Interval: -1}, wantErr: "interval must be positive"},
+		{name: "unknown period", plan: Plan{Name: "Pro", Amount: 1, Period: "hourly"}, wantErr: "period must be daily, weekly, monthly or yearly"},
+		{name: "long trial", plan: Plan{Name: "Pro", Amount: 1, Period: PeriodMonthly, TrialDays: 366}, wantErr: "trial_days must be between 0 and 365"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tt.plan
+			err := ValidatePlan(&p)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p != tt.want {
+				t.Errorf("validated %+v, want %+v", p, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrialStart(t *testing.T) {
+	now := time.Date(2026, 2, 20, 12, 0, 0, 0, time.UTC)
+	if got := (Plan{}).TrialStart(now); got != 0 {
+		t.Errorf("no trial starts billing at %d, want 0 (immediately)", got)
+	}
+	if got, want := (Plan{TrialDays: 14}).TrialStart(now), now.AddDate(0, 0, 14).Unix(); got != want {
+		t.Errorf("14-day trial starts billing at %d, want %d", got, want)
+	}
+}
+
+// subscriptionEvent builds a subscription.* webhook sent at sentAt for
+// Razorpay subscription sub_test. payment, if set, is the cycle's charge.
+func subscriptionEvent(t *testing.T, event string, sentAt time.Time, entity razorpay.SubscriptionEntity, payment *razorpay.PaymentEntity) razorpay.WebhookEvent {
+	t.Helper()
+	entity.ID = "sub_test"
+	payload := map[string]any{"subscription": map[string]any{"entity": entity}}
+	if payment != nil {
+		payload["payment"] = map[string]any{"entity": payment}
+	}
+	body, err := json.Marshal(map[string]any{"event": event, "created_at": sentAt.Unix(), "payload": payload})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var e razorpay.WebhookEvent
+	if err := json.Unmarshal(body, &e); err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+// apply runs ApplyWebhook in its own transaction.
+func apply(t *testing.T, database *sql.DB, event razorpay.WebhookEvent) (Subscription, bool, error) {
+	t.Helper()
+	ctx := context.Background()
+	tx, err := database.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	s, changed, err := ApplyWebhook(ctx, tx, event)
+	if err != nil {
+		return s, changed, err
+	}
+	return s, changed, tx.Commit()
+}
+
+func unix(t time.Time) *int64 {
+	sec := t.Unix()
+	return &sec
+}
+
+func TestApplyWebhook(t *testing.T) {
+	database := dbtest.Open(t)
+	ctx := context.Background()
+
+	plan, err := CreatePlan(ctx, database, Plan{RazorpayPlanID: "plan_test", Name: "Pro", Amount: 499, Currency: "INR", Period: PeriodMonthly, Interval: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub, err := CreateSubscription(ctx, database, 7, plan, razorpay.SubscriptionEntity{ID: "sub_test", Status: "created", TotalCount: 12})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+	cycle := razorpay.SubscriptionEntity{Status: "active", PaidCount: 1, TotalCount: 12,
+		CurrentStart: unix(start), CurrentEnd: unix(start.AddDate(0, 1, 0)), ChargeAt: unix(start.AddDate(0, 1, 0))}
+	charge := &razorpay.PaymentEntity{ID: "pay_cycle1", InvoiceID: "inv_cycle1", Amount: 49900, Currency: "INR", Status: "captured"}
+	paused := razorpay.SubscriptionEntity{Status: "paused", PaidCount: 1, TotalCount: 12}
+
+	steps := []struct {
+		name    string
+		event   razorpay.WebhookEvent
+		changed bool
+		status  Status
+	}{
+		{"activated", subscriptionEvent(t, "subscription.activated", start, cycle, nil), true, StatusActive},
+		{"charged", subscriptionEvent(t, "subscription.charged", start.Add(time.Minute), cycle, charge), true, StatusActive},
+		// Reapplying the same state is harmless; the invoice is not
+		// recorded twice
+		{"charge redelivered", subscriptionEvent(t, "subscription.charged", start.Add(time.Minute), cycle, charge), true, StatusActive},
+		{"paused", subscriptionEvent(t, "subscription.paused", start.Add(2*time.Minute), paused, nil), true, StatusPaused},
+		// Delivered late: the state it carries is older than the pause
+		{"late activation", subscriptionEvent(t, "subscription.activated", start.Add(30*time.Second), cycle, nil), false, StatusPaused},
+		{"late charge redelivered", subscriptionEvent(t, "subscription.charged", start.Add(time.Minute), cycle, charge), false, StatusPaused},
+	}
+	for _, step := range steps {
+		s, changed, err := apply(t, database, step.event)
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if changed != step.changed || s.Status != step.status || s.ID != sub.ID {
+			t.Errorf("%s: subscription %d %s, changed %t; want %d %s, changed %t",
+				step.name, s.ID, s.Status, changed, sub.ID, step.status, step.changed)
+		}
+	}
+
+	invoices, err := ListInvoices(ctx, database, 7, sub.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(invoices) != 1 {
+		t.Fatalf("%d invoices, want 1", len(invoices))
+	}
+	inv := invoices[0]
+	if inv.Cycle != 1 || inv.Amount != 499 || inv.RazorpayPaymentID != "pay_cycle1" || inv.RazorpayInvoiceID == nil ||
+		*inv.RazorpayInvoiceID != "inv_cycle1" || inv.PeriodStart == nil || !inv.PeriodStart.Equal(start) {
+		t.Errorf("invoice %+v", inv)
+	}
+
+	got, err := GetSubscription(ctx, database, 7, sub.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != StatusPaused || got.PaidCount != 1 {
+		t.Errorf("stored %s with %d paid cycles, want paused with 1", got.Status, got.PaidCount)
+	}
+}
+
+func TestApplyWebhookUnknown(t *testing.T) {
+	database := dbtest.Open(t)
+
+	event := subscriptionEvent(t, "subscription.activated", time.Now(), razorpay.SubscriptionEntity{Status: "active"}, nil)
+	if _, _, err := apply(t, database, event); !errors.Is(err, ErrNotFound) {
+		t.Errorf("subscription created elsewhere: err = %v, want ErrNotFound", err)
+	}
+	event.Payload.Subscription = nil
+	if _, _, err := apply(t, database, event); !errors.Is(err, ErrNotFound) {
+		t.Errorf("event without a subscription: err = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,109 @@
+// Package tracing sets up OpenTelemetry for payment-service: the tracer
+// provider, the server span per request, and client spans for SQL and
+// Razorpay calls.
+//
+// auth-service and api-gateway carry their own copies of tracing.go. The
+// three services are separate Go modules, and their Dockerfiles use the
+// service's own directory as the build context, so no service can import
+// another's packages; auth-service's module path is also plain gopay-lite.
+// The copies differ only in serviceName, so a change here usually belongs
+// in all three. span.go holds the span helpers a service actually uses.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const serviceName = "payment-service"
+
+// Init configures the global tracer provider for the given exporter kind:
+//   - "otlp":  OTLP over HTTP, honouring the standard OTEL_EXPORTER_OTLP_* variables
+//   - "stdout": pretty-printed spans on stdout
+//   - "none" (default): spans are propagated but not exported
+//
+// The returned function flushes and stops the provider.
+func Init(ctx context.Context, exporter string) (func(context.Context) error, error) {
+	switch kind := strings.ToLower(exporter); kind {
+	case "otlp":
+		exporter, err := otlptracehttp.New(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("create otlp exporter: %w", err)
+		}
+		return install(sdktrace.WithBatcher(exporter)), nil
+	case "stdout":
+		exporter, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+		if err != nil {
+			return nil, fmt.Errorf("create stdout exporter: %w", err)
+		}
+		return install(sdktrace.WithSyncer(exporter)), nil
+	case "", "none":
+		return install(), nil
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", kind)
+	}
+}
+
+// InitWithExporter installs a tracer provider that sends spans synchronously
+// to exporter. Tests pass a tracetest.InMemoryExporter to assert on
+// recorded spans.
+func InitWithExporter(exporter sdktrace.SpanExporter) func(context.Context) error {
+	return install(sdktrace.WithSyncer(exporter))
+}
+
+func install(opts ...sdktrace.TracerProviderOption) func(context.Context) error {
+	opts = append(opts, sdktrace.WithResource(
+		resource.NewSchemaless(semconv.ServiceName(serviceName)),
+	))
+
+	provider := sdktrace.NewTracerProvider(opts...)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	return provider.Shutdown
+}
+
+// Tracer returns the service tracer.
+func Tracer() trace.Tracer {
+	return otel.Tracer(serviceName)
+}
+
+// Middleware starts a server span per request, named after the matched mux
+// route and continuing any incoming W3C traceparent. The request ID echoed by
+// the RequestID middleware is attached to the span.
+func Middleware(next http.Handler) http.Handler {
+	withRequestID := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id := w.Header().Get("X-Request-ID"); id != "" {
+			trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("http.request_id", id))
+		}
+		next.ServeHTTP(w, r)
+	})
+
+	return otelhttp.NewHandler(withRequestID, "",
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			if route := mux.CurrentRoute(r); route != nil {
+				if tmpl, err := route.GetPathTemplate(); err == nil {
+					return r.Method + " " + tmpl
+				}
+			}
+			return r.Method
+		}),
+	)
+}
@@ -0,0 +1,138 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// record installs an in-memory exporter for the rest of t.
+func record(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	shutdown := InitWithExporter(exporter)
+	t.Cleanup(func() { shutdown(context.Background()) })
+	return exporter
+}
+
+func attr(span tracetest.SpanStub, key attribute.Key) string {
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			return kv.Value.Emit()
+		}
+	}
+	return ""
+}
+
+const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestMiddleware(t *testing.T) {
+	exporter := record(t)
+
+	r := mux.NewRouter()
+	// Stands in for middleware.RequestID, which runs first
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Request-ID", "req-123")
+			next.ServeHTTP(w, r)
+		})
+	})
+	r.Use(Middleware)
+	r.HandleFunc("/api/v1/payments/{id}", func(w http.ResponseWriter, r *http.Request) {
+		// Spans started by handlers are children of the server span
+		_, span := StartDBSpan(r.Context(), "SELECT", "payments", "SELECT 1")
+		span.End()
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/payments/42", nil)
+	req.Header.Set("traceparent", traceparent)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("%d spans recorded, want 2", len(spans))
+	}
+	db, server := spans[0], spans[1]
+	if server.Name != "GET /api/v1/payments/{id}" || server.SpanKind != trace.SpanKindServer {
+		t.Errorf("server span %q (%s), want GET /api/v1/payments/{id} (server)", server.Name, server.SpanKind)
+	}
+	if got := server.SpanContext.TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("server span is in trace %s, not the caller's", got)
+	}
+	if !server.Parent.IsRemote() || server.Parent.SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("server span parent %s, want the caller's span", server.Parent.SpanID())
+	}
+	if got := attr(server, "http.request_id"); got != "req-123" {
+		t.Errorf("http.request_id = %q, want req-123", got)
+	}
+	if db.Parent.SpanID() != server.SpanContext.SpanID() {
+		t.Error("handler span is not a child of the server span")
+	}
+}
+
+func TestMiddlewareWithoutRoute(t *testing.T) {
+	exporter := record(t)
+
+	Middleware(http.NotFoundHandler()).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/nowhere", nil))
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 || spans[0].Name != "POST" {
+		t.Fatalf("spans %v, want one named POST", spans)
+	}
+	// A request without traceparent starts a new trace
+	if spans[0].Parent.IsValid() {
+		t.Error("server span has a parent")
+	}
+}
+
+func TestDBSpan(t *testing.T) {
+	exporter := record(t)
+	ctx, parent := Tracer().Start(context.Background(), "parent")
+
+	_, span := StartDBSpan(ctx, "UPDATE", "payments", "UPDATE payments SET status = $2 WHERE id = $1")
+	End(span, errors.New("deadlock detected"))
+	parent.End()
+
+	got := exporter.GetSpans()[0]
+	if got.Name != "UPDATE payments" || got.SpanKind != trace.SpanKindClient {
+		t.Errorf("span %q (%s), want UPDATE payments (client)", got.Name, got.SpanKind)
+	}
+	if got.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Error("DB span is not a child of the caller's span")
+	}
+	for key, want := range map[attribute.Key]string{
+		"db.system":          "postgresql",
+		"db.operation.name":  "UPDATE",
+		"db.collection.name": "payments",
+		"db.query.text":      "UPDATE payments SET status = $2 WHERE id = $1",
+	} {
+		if v := attr(got, key); v != want {
+			t.Errorf("%s = %q, want %q", key, v, want)
+		}
+	}
+	if got.Status.Code != codes.Error || got.Status.Description != "deadlock detected" || len(got.Events) != 1 {
+		t.Errorf("status %v with %d events, want the error recorded", got.Status, len(got.Events))
+	}
+}
+
+func TestGatewaySpan(t *testing.T) {
+	exporter := record(t)
+	_, span := StartGatewaySpan(context.Background(), "order.fetch")
+	End(span, nil)
+
+	got := exporter.GetSpans()[0]
+	if got.Name != "razorpay order.fetch" || attr(got, "peer.service") != "razorpay" || attr(got, "gateway.operation") != "order.fetch" {
+		t.Errorf("gateway span %q with %v", got.Name, got.Attributes)
+	}
+	if got.Status.Code != codes.Unset {
+		t.Errorf("status %v, want unset", got.Status)
+	}
+}
@@ -0,0 +1,99 @@
+// Package users reads user profiles from auth-service. The service keeps no
+// copy of them; callers fetch the profile on behalf of the user whose
+// bearer token they hold.
+package users
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/middleware"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// ErrUnauthorized is returned when auth-service rejects the token or no
+// longer knows the user.
+var ErrUnauthorized = errors.New("user not recognised by auth-service")
+
+// User is the profile auth-service returns from /api/v1/me.
+type User struct {
+	ID       int    `json:"user_id"`
+	Email    string `json:"email"`
+	Name     string `json:"name"`
+	Timezone string `json:"timezone"`
+	// CreatedAt is when the account was registered; zero from an
+	// auth-service that predates it.
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Location returns the user's time zone, falling back to UTC when it is
+// unset or unknown to this binary.
+func (u User) Location() *time.Location {
+	if u.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(u.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// Client calls auth-service.
+type Client struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewClient talks to the auth-service at baseURL, giving up on each
+// request after timeout.
+func NewClient(baseURL string, timeout time.Duration) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client: &http.Client{
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
+			Timeout:   timeout,
+		},
+	}
+}
+
+// Me returns the profile of the user authorization (the caller's
+// Authorization header) belongs to.
+func (c *Client) Me(ctx context.Context, authorization string) (User, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/api/v1/me", nil)
+	if err != nil {
+		return User{}, err
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Accept", "application/json")
+	if id := middleware.GetRequestID(ctx); id != "" {
+		req.Header.Set(middleware.RequestIDHeader, id)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return User{}, fmt.Errorf("auth-service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusNotFound:
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+		return User{}, ErrUnauthorized
+	case resp.StatusCode != http.StatusOK:
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+		return User{}, fmt.Errorf("auth-service responded %s", resp.Status)
+	}
+
+	var u User
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&u); err != nil {
+		return User{}, fmt.Errorf("decode auth-service profile: %w", err)
+	}
+	return u, nil
+}
@@ -0,0 +1,61 @@
+package users
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/tracing"
+)
+
+func TestMePropagatesTrace(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	shutdown := tracing.InitWithExporter(exporter)
+	defer shutdown(context.Background())
+
+	var received trace.SpanContext
+	auth := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := propagation.TraceContext{}.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		received = trace.SpanContextFromContext(ctx)
+		if r.Header.Get("Authorization") != "Bearer good" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(User{ID: 7, Email: "asha@example.com", Timezone: "Asia/Kolkata"})
+	}))
+	defer auth.Close()
+	client := NewClient(auth.URL+"/", time.Second)
+
+	ctx, parent := tracing.Tracer().Start(context.Background(), "GET /api/v1/statements")
+	u, err := client.Me(ctx, "Bearer good")
+	if err != nil || u.ID != 7 || u.Location().String() != "Asia/Kolkata" {
+		t.Fatalf("Me = %+v, %v", u, err)
+	}
+	if _, err := client.Me(ctx, "Bearer bad"); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("rejected token: err = %v, want ErrUnauthorized", err)
+	}
+	parent.End()
+
+	// auth-service continues the trace from the client span of each call;
+	// received is from the second
+	spans := exporter.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("%d spans recorded, want 3", len(spans))
+	}
+	call := spans[1]
+	if call.SpanKind != trace.SpanKindClient || call.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("call span %q (%s) is not a client child of the request", call.Name, call.SpanKind)
+	}
+	if received.TraceID() != parent.SpanContext().TraceID() || received.SpanID() != call.SpanContext.SpanID() {
+		t.Errorf("auth-service received %s/%s, want %s/%s",
+			received.TraceID(), received.SpanID(), parent.SpanContext().TraceID(), call.SpanContext.SpanID())
+	}
+}
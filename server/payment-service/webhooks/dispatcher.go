@@ -0,0 +1,386 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/events"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/metrics"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/outbox"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// maxResponseBody is how much of an endpoint's response is kept per attempt.
+const maxResponseBody = 1 << 10
+
+// Options tunes a Dispatcher. Zero values take the defaults noted per field.
+type Options struct {
+	PollInterval time.Duration // 2s
+	BatchSize    int           // 50
+	Concurrency  int           // 8 requests in flight
+	Timeout      time.Duration // 10s per request
+	MaxAttempts  int           // 12 before a delivery is marked failed
+	MinBackoff   time.Duration // 30s after the first failure
+	MaxBackoff   time.Duration // 6h
+
+	// AllowPrivateNetworks permits endpoints that resolve to loopback,
+	// private or link-local addresses. Keep it off in production so
+	// merchants cannot make the service call internal systems.
+	AllowPrivateNetworks bool
+}
+
+// Dispatcher fans events out to subscribed endpoints and delivers them.
+type Dispatcher struct {
+	db     *sql.DB
+	client *http.Client
+	opts   Options
+}
+
+// NewDispatcher creates a dispatcher storing deliveries in db.
+func NewDispatcher(db *sql.DB, opts Options) *Dispatcher {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 2 * time.Second
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 50
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 8
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 12
+	}
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = 30 * time.Second
+	}
+	if opts.MaxBackoff < opts.MinBackoff {
+		opts.MaxBackoff = 6 * time.Hour
+	}
+
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	if !opts.AllowPrivateNetworks {
+		dialer.Control = denyPrivateAddresses
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.Proxy = nil
+
+	return &Dispatcher{
+		db: db,
+		client: &http.Client{
+			Timeout:   opts.Timeout,
+			Transport: otelhttp.NewTransport(transport),
+			// A redirect could point at an internal address; receivers
+			// must answer at the registered URL
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		opts: opts,
+	}
+}
+
+// Publish implements outbox.Publisher: it queues e for every active endpoint
+// of the user named in the event data that subscribes to its type. Events
+// without a user_id are not merchant-visible and are skipped.
+func (d *Dispatcher) Publish(ctx context.Context, e events.Event) error {
+	var owner struct {
+		UserID int `json:"user_id"`
+	}
+	if err := json.Unmarshal(e.Data, &owner); err != nil || owner.UserID == 0 {
+		return nil
+	}
+
+	endpoints, err := ListEndpoints(ctx, d.db, owner.UserID)
+	if err != nil {
+		return err
+	}
+	for _, ep := range endpoints {
+		if !ep.Active || !ep.Subscribes(e.Type) {
+			continue
+		}
+		if _, _, err := Enqueue(ctx, d.db, ep.ID, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run delivers due deliveries until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	slog.Info("Webhook dispatcher started", "poll_interval", d.opts.PollInterval, "concurrency", d.opts.Concurrency)
+
+	ticker := time.NewTicker(d.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			n, err := d.DeliverDue(ctx)
+			if err != nil && ctx.Err() == nil {
+				slog.Error("Webhook dispatch batch failed", "error", err)
+			}
+			if err != nil || n < d.opts.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			slog.Info("Webhook dispatcher stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// claimQuery leases due deliveries by pushing next_attempt_at past the
+// request timeout, so other replicas skip them while they are in flight and
+// pick them up again if this process dies mid-attempt.
+const claimQuery = `UPDATE webhook_deliveries
+	SET next_attempt_at = NOW() + make_interval(secs => $2)
+	WHERE id IN (
+		SELECT id FROM webhook_deliveries
+		WHERE status = 'pending' AND next_attempt_at <= NOW()
+		ORDER BY next_attempt_at
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING ` + deliveryColumns
+
+// DeliverDue attempts one batch of due deliveries and returns its size.
+func (d *Dispatcher) DeliverDue(ctx context.Context) (int, error) {
+	lease := 2*d.opts.Timeout + 30*time.Second
+	rows, err := d.db.QueryContext(ctx, claimQuery, d.opts.BatchSize, lease.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("claim deliveries: %w", err)
+	}
+	var batch []Delivery
+	for rows.Next() {
+		del, err := scanDelivery(rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		batch = append(batch, del)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	sem := make(chan struct{}, d.opts.Concurrency)
+	var wg sync.WaitGroup
+	for _, del := range batch {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(del Delivery) {
+			defer func() { <-sem; wg.Done() }()
+			if _, err := d.attempt(ctx, del, false); err != nil && ctx.Err() == nil {
+				slog.Error("Webhook delivery bookkeeping failed", "delivery_id", del.ID, "error", err)
+			}
+		}(del)
+	}
+	wg.Wait()
+	return len(batch), nil
+}
+
+// Retry immediately re-sends one of userID's deliveries, whatever its
+// status, and returns it updated. A success marks it succeeded; a failure
+// is recorded without changing a finished delivery's status.
+func (d *Dispatcher) Retry(ctx context.Context, userID int, deliveryID int64) (Delivery, error) {
+	del, _, err := GetDelivery(ctx, d.db, userID, deliveryID)
+	if err != nil {
+		return Delivery{}, err
+	}
+	return d.attempt(ctx, del, true)
+}
+
+// SendTest queues a webhook.test event for one of userID's endpoints and
+// sends it immediately. Failed test deliveries are retried like any other.
+func (d *Dispatcher) SendTest(ctx context.Context, userID int, endpointID int64) (Delivery, error) {
+	ep, err := GetEndpoint(ctx, d.db, userID, endpointID)
+	if err != nil {
+		return Delivery{}, err
+	}
+
+	data, _ := json.Marshal(map[string]any{
+		"user_id":     userID,
+		"endpoint_id": ep.ID,
+		"message":     "This is a test event from GoPay.",
+	})
+	e := events.Event{
+		EventID:       events.NewEventID(),
+		Type:          events.WebhookTest,
+		AggregateType: "webhook_endpoint",
+		AggregateID:   strconv.FormatInt(ep.ID, 10),
+		Data:          data,
+		CreatedAt:     time.Now().UTC(),
+	}
+	del, _, err := Enqueue(ctx, d.db, ep.ID, e)
+	if err != nil {
+		return Delivery{}, err
+	}
+	return d.attempt(ctx, del, false)
+}
+
+type result struct {
+	code    *int
+	body    string
+	latency time.Duration
+	err     error
+}
+
+// attempt sends del once and records the outcome. manual attempts do not
+// consume the automatic retry budget of a finished delivery.
+func (d *Dispatcher) attempt(ctx context.Context, del Delivery, manual bool) (Delivery, error) {
+	ep, err := scanEndpoint(d.db.QueryRowContext(ctx,
+		`SELECT `+endpointColumns+` FROM webhook_endpoints WHERE id = $1`, del.EndpointID))
+	if err != nil {
+		return Delivery{}, err
+	}
+
+	res := d.send(ctx, ep, del)
+	if ctx.Err() != nil {
+		// Shutting down; the lease expires and the delivery is retried
+		return del, ctx.Err()
+	}
+
+	status := DeliveryPending
+	var retryIn time.Duration
+	switch {
+	case res.err == nil:
+		status = DeliverySucceeded
+	case manual && del.Status != DeliveryPending:
+		status = del.Status
+	case del.Attempts+1 >= d.opts.MaxAttempts:
+		status = DeliveryFailed
+	default:
+		retryIn = outbox.Backoff(del.Attempts, d.opts.MinBackoff, d.opts.MaxBackoff)
+	}
+
+	outcome := "success"
+	if res.err != nil {
+		outcome = "error"
+		slog.Warn("Webhook delivery failed",
+			"delivery_id", del.ID,
+			"endpoint_id", ep.ID,
+			"event_type", del.EventType,
+			"attempt", del.Attempts+1,
+			"status", status,
+			"retry_in", retryIn,
+			"error", res.err,
+		)
+	}
+	metrics.WebhookDelivery(outcome, res.latency)
+
+	return d.record(ctx, del, res, status, retryIn)
+}
+
+func (d *Dispatcher) send(ctx context.Context, ep Endpoint, del Delivery) result {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ep.URL, bytes.NewReader(del.Payload))
+	if err != nil {
+		return result{err: err}
+	}
+
+	now := time.Now()
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	var signatures []string
+	for _, secret := range ep.signingSecrets(now) {
+		signatures = append(signatures, "sha256="+outbox.Sign(secret, timestamp, del.Payload))
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "GoPay-Webhooks/1.0")
+	req.Header.Set("X-GoPay-Event-ID", del.EventID)
+	req.Header.Set("X-GoPay-Event-Type", del.EventType)
+	req.Header.Set("X-GoPay-Delivery-ID", strconv.FormatInt(del.ID, 10))
+	req.Header.Set("X-GoPay-Timestamp", timestamp)
+	req.Header.Set("X-GoPay-Signature", strings.Join(signatures, ","))
+
+	start := time.Now()
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return result{latency: time.Since(start), err: err}
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	res := result{code: &resp.StatusCode, body: string(body), latency: time.Since(start)}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		res.err = fmt.Errorf("endpoint responded %s", resp.Status)
+	}
+	return res
+}
+
+func (d *Dispatcher) record(ctx context.Context, del Delivery, res result, status DeliveryStatus, retryIn time.Duration) (Delivery, error) {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Delivery{}, err
+	}
+	defer tx.Rollback()
+
+	latencyMS := int(res.latency.Milliseconds())
+	var errMsg *string
+	if res.err != nil {
+		msg := res.err.Error()
+		errMsg = &msg
+	}
+
+	updated, err := scanDelivery(tx.QueryRowContext(ctx, `UPDATE webhook_deliveries SET
+			attempts = attempts + 1,
+			status = $2,
+			next_attempt_at = NOW() + make_interval(secs => $3),
+			last_attempt_at = NOW(),
+			response_code = $4,
+			latency_ms = $5,
+			last_error = $6
+		WHERE id = $1
+		RETURNING `+deliveryColumns,
+		del.ID, string(status), retryIn.Seconds(), res.code, latencyMS, errMsg))
+	if err != nil {
+		return Delivery{}, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `INSERT INTO webhook_delivery_attempts
+			(delivery_id, attempt, response_code, response_body, latency_ms, error)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6)`,
+		del.ID, updated.Attempts, res.code, res.body, latencyMS, errMsg); err != nil {
+		return Delivery{}, err
+	}
+
+	return updated, tx.Commit()
+}
+
+var errPrivateAddress = errors.New("webhook endpoint resolves to a private address")
+
+// denyPrivateAddresses is a net.Dialer Control hook that refuses to connect
+// to non-public addresses. It runs after DNS resolution, so it also covers
+// hostnames that point inside the network.
+func denyPrivateAddresses(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsUnspecified() || ip.IsMulticast() {
+		return errPrivateAddress
+	}
+	return nil
+}
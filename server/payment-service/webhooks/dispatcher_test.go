@@ -0,0 +1,239 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/events"
+	"github.com/RaginiSharma01/gopay-lite/payment-service/internal/dbtest"
+)
+
+// receiver records the requests a merchant endpoint gets.
+type receiver struct {
+	mu       sync.Mutex
+	status   int
+	requests []captured
+}
+
+type captured struct {
+	header http.Header
+	body   []byte
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rc.mu.Lock()
+	rc.requests = append(rc.requests, captured{r.Header.Clone(), body})
+	status := rc.status
+	rc.mu.Unlock()
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	io.WriteString(w, "received")
+}
+
+// verify checks a delivery's signature header the way the README tells
+// merchants to, returning how many of its signatures secret produced.
+func verify(t *testing.T, req captured, secret string) int {
+	t.Helper()
+	timestamp := req.header.Get("X-GoPay-Timestamp")
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || time.Since(time.Unix(ts, 0)).Abs() > time.Minute {
+		t.Errorf("X-GoPay-Timestamp %q is not the current Unix time", timestamp)
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + string(req.body)))
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	n := 0
+	for _, sig := range strings.Split(req.header.Get("X-GoPay-Signature"), ",") {
+		if hmac.Equal([]byte(sig), []byte(want)) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestSendSignsDelivery(t *testing.T) {
+	rc := &receiver{}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+	d := NewDispatcher(nil, Options{AllowPrivateNetworks: true})
+
+	del := Delivery{ID: 12, EventID: "evt_1", EventType: "payment.captured", Payload: json.RawMessage(`{"id":"evt_1"}`)}
+	later := time.Now().Add(time.Hour)
+	earlier := time.Now().Add(-time.Minute)
+	tests := []struct {
+		name       string
+		ep         Endpoint
+		signatures int
+	}{
+		{"one secret", Endpoint{Secret: "whsec_new"}, 1},
+		{"rotated within the grace period", Endpoint{Secret: "whsec_new", PreviousSecret: "whsec_old", PreviousSecretExpiresAt: &later}, 2},
+		{"rotated past the grace period", Endpoint{Secret: "whsec_new", PreviousSecret: "whsec_old", PreviousSecretExpiresAt: &earlier}, 1},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.ep.URL = srv.URL
+			res := d.send(context.Background(), tt.ep, del)
+			if res.err != nil || res.code == nil || *res.code != http.StatusOK || res.body != "received" {
+				t.Fatalf("send = %+v", res)
+			}
+
+			req := rc.requests[i]
+			if string(req.body) != string(del.Payload) {
+				t.Errorf("body %s, want the payload", req.body)
+			}
+			for header, want := range map[string]string{
+				"X-GoPay-Event-ID":    "evt_1",
+				"X-GoPay-Event-Type":  "payment.captured",
+				"X-GoPay-Delivery-ID": "12",
+				"Content-Type":        "application/json",
+			} {
+				if got := req.header.Get(header); got != want {
+					t.Errorf("%s = %q, want %q", header, got, want)
+				}
+			}
+			if got := len(strings.Split(req.header.Get("X-GoPay-Signature"), ",")); got != tt.signatures {
+				t.Errorf("%d signatures, want %d", got, tt.signatures)
+			}
+			if verify(t, req, "whsec_new") != 1 {
+				t.Error("no signature verifies with the current secret")
+			}
+			if wantOld := tt.signatures - 1; verify(t, req, "whsec_old") != wantOld {
+				t.Errorf("previous secret verifies %d signatures, want %d", verify(t, req, "whsec_old"), wantOld)
+			}
+			// The timestamp is part of what is signed
+			replayed := req
+			replayed.header = req.header.Clone()
+			replayed.header.Set("X-GoPay-Timestamp", strconv.FormatInt(time.Now().Unix()+1, 10))
+			if verify(t, replayed, "whsec_new") != 0 {
+				t.Error("signature still verifies with another timestamp")
+			}
+		})
+	}
+}
+
+func TestSendFailures(t *testing.T) {
+	rc := &receiver{status: http.StatusInternalServerError}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+	ep := Endpoint{URL: srv.URL, Secret: "whsec_new"}
+	del := Delivery{ID: 1, EventID: "evt_1", Payload: json.RawMessage(`{}`)}
+
+	res := NewDispatcher(nil, Options{AllowPrivateNetworks: true}).send(context.Background(), ep, del)
+	if res.err == nil || res.code == nil || *res.code != http.StatusInternalServerError {
+		t.Errorf("500 response: %+v, want a failure with its code", res)
+	}
+
+	// A redirect could lead anywhere; it counts as an answer, not a success
+	redirect := httptest.NewServer(http.RedirectHandler(srv.URL, http.StatusFound))
+	defer redirect.Close()
+	ep.URL = redirect.URL
+	res = NewDispatcher(nil, Options{AllowPrivateNetworks: true}).send(context.Background(), ep, del)
+	if res.err == nil || res.code == nil || *res.code != http.StatusFound || len(rc.requests) != 1 {
+		t.Errorf("redirect: %+v after %d requests to its target, want a failure and none followed", res, len(rc.requests)-1)
+	}
+
+	// httptest listens on loopback, which production dispatchers refuse
+	ep.URL = srv.URL
+	res = NewDispatcher(nil, Options{}).send(context.Background(), ep, del)
+	if !errors.Is(res.err, errPrivateAddress) || len(rc.requests) != 1 {
+		t.Errorf("loopback endpoint: err = %v, want errPrivateAddress", res.err)
+	}
+}
+
+func TestDenyPrivateAddresses(t *testing.T) {
+	for address, denied := range map[string]bool{
+		"127.0.0.1:443":      true,
+		"[::1]:443":          true,
+		"10.1.2.3:443":       true,
+		"192.168.1.10:80":    true,
+		"[fd00::1]:443":      true,
+		"169.254.169.254:80": true, // cloud metadata
+		"0.0.0.0:443":        true,
+		"224.0.0.251:5353":   true,
+		"93.184.216.34:443":  false,
+		"[2606:4700::1]:443": false,
+	} {
+		if err := denyPrivateAddresses("tcp", address, nil); (err != nil) != denied {
+			t.Errorf("%s: err = %v, want denied %t", address, err, denied)
+		}
+	}
+}
+
+func TestPublishAndDeliver(t *testing.T) {
+	database := dbtest.Open(t)
+	ctx := context.Background()
+	rc := &receiver{}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	const user = 3
+	captures, err := CreateEndpoint(ctx, database, user, srv.URL, "captures", []string{string(events.PaymentCaptured)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CreateEndpoint(ctx, database, user, srv.URL, "failures", []string{string(events.PaymentFailed)}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CreateEndpoint(ctx, database, user+1, srv.URL, "someone else", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewDispatcher(database, Options{AllowPrivateNetworks: true})
+	e := events.Event{
+		EventID:       events.NewEventID(),
+		Type:          events.PaymentCaptured,
+		AggregateType: events.AggregatePayment,
+		AggregateID:   "42",
+		Data:          json.RawMessage(`{"payment_id":42,"user_id":3,"status":"captured"}`),
+		CreatedAt:     time.Now().UTC(),
+	}
+	// The outbox redelivers after a crash; the endpoint still gets it once
+	for range 2 {
+		if err := d.Publish(ctx, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	n, err := d.DeliverDue(ctx)
+	if err != nil || n != 1 {
+		t.Fatalf("delivered %d (%v), want 1", n, err)
+	}
+	if len(rc.requests) != 1 {
+		t.Fatalf("endpoint got %d requests, want 1", len(rc.requests))
+	}
+	if verify(t, rc.requests[0], captures.Secret) != 1 {
+		t.Error("delivery is not signed with the endpoint's secret")
+	}
+	var body events.Event
+	if err := json.Unmarshal(rc.requests[0].body, &body); err != nil || body.EventID != e.EventID || body.Type != e.Type {
+		t.Errorf("delivered %s (%v), want event %s", rc.requests[0].body, err, e.EventID)
+	}
+
+	list, err := ListDeliveries(ctx, database, user, DeliveryFilter{})
+	if err != nil || len(list) != 1 {
+		t.Fatalf("%d deliveries (%v), want 1", len(list), err)
+	}
+	del, attempts, err := GetDelivery(ctx, database, user, list[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if del.Status != DeliverySucceeded || del.Attempts != 1 || len(attempts) != 1 || attempts[0].ResponseBody != "received" {
+		t.Errorf("delivery %s after %d attempts (%+v), want succeeded after 1", del.Status, del.Attempts, attempts)
+	}
+}
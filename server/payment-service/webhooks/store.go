@@ -0,0 +1,311 @@
+// Package webhooks delivers payment events to merchant-registered HTTP
+// endpoints, signing each request and retrying failures with backoff.
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/RaginiSharma01/gopay-lite/payment-service/events"
+	"github.com/lib/pq"
+)
+
+// ErrNotFound is returned when an endpoint or delivery does not exist or
+// belongs to another user.
+var ErrNotFound = errors.New("webhook not found")
+
+// Endpoint is a merchant URL that receives event notifications.
+type Endpoint struct {
+	ID          int64
+	UserID      int
+	URL         string
+	Description string
+	// EventTypes filters deliveries; empty means every type.
+	EventTypes []string
+	Secret     string
+
+	// PreviousSecret keeps signing deliveries until PreviousSecretExpiresAt
+	// so receivers can roll over to a rotated secret.
+	PreviousSecret          string
+	PreviousSecretExpiresAt *time.Time
+
+	Active    bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Subscribes reports whether the endpoint wants events of type t.
+func (e Endpoint) Subscribes(t events.Type) bool {
+	return len(e.EventTypes) == 0 || slices.Contains(e.EventTypes, string(t))
+}
+
+// signingSecrets returns every secret a delivery made at now is signed with.
+func (e Endpoint) signingSecrets(now time.Time) []string {
+	secrets := []string{e.Secret}
+	if e.PreviousSecret != "" && e.PreviousSecretExpiresAt != nil && now.Before(*e.PreviousSecretExpiresAt) {
+		secrets = append(secrets, e.PreviousSecret)
+	}
+	return secrets
+}
+
+// DeliveryStatus is the state of a delivery.
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	// DeliveryFailed means automatic retries were exhausted.
+	DeliveryFailed DeliveryStatus = "failed"
+)
+
+// Delivery is one event sent to one endpoint, across all its attempts.
+type Delivery struct {
+	ID            int64
+	EndpointID    int64
+	EventID       string
+	EventType     string
+	Payload       json.RawMessage
+	Status        DeliveryStatus
+	Attempts      int
+	NextAttemptAt time.Time
+	LastAttemptAt *time.Time
+	ResponseCode  *int
+	LatencyMS     *int
+	LastError     *string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// Attempt is a single HTTP request made for a delivery.
+type Attempt struct {
+	Attempt      int
+	ResponseCode *int
+	ResponseBody string
+	LatencyMS    int
+	Error        string
+	CreatedAt    time.Time
+}
+
+// queryer is satisfied by *sql.DB and *sql.Tx.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+const endpointColumns = `id, user_id, url, COALESCE(description, ''), event_types, secret,
+	COALESCE(previous_secret, ''), previous_secret_expires_at, active, created_at, updated_at`
+
+func scanEndpoint(row interface{ Scan(...any) error }) (Endpoint, error) {
+	var e Endpoint
+	err := row.Scan(&e.ID, &e.UserID, &e.URL, &e.Description, pq.Array(&e.EventTypes), &e.Secret,
+		&e.PreviousSecret, &e.PreviousSecretExpiresAt, &e.Active, &e.CreatedAt, &e.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Endpoint{}, ErrNotFound
+	}
+	return e, err
+}
+
+const deliveryColumns = `id, endpoint_id, event_id, event_type, payload, status, attempts,
+	next_attempt_at, last_attempt_at, response_code, latency_ms, last_error, created_at, updated_at`
+
+func scanDelivery(row interface{ Scan(...any) error }) (Delivery, error) {
+	var d Delivery
+	var payload []byte
+	err := row.Scan(&d.ID, &d.EndpointID, &d.EventID, &d.EventType, &payload, &d.Status, &d.Attempts,
+		&d.NextAttemptAt, &d.LastAttemptAt, &d.ResponseCode, &d.LatencyMS, &d.LastError, &d.CreatedAt, &d.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Delivery{}, ErrNotFound
+	}
+	d.Payload = payload
+	return d, err
+}
+
+// NewSecret returns a random signing secret.
+func NewSecret() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return "whsec_" + hex.EncodeToString(b)
+}
+
+// CreateEndpoint registers url for userID with a fresh signing secret.
+func CreateEndpoint(ctx context.Context, q queryer, userID int, url, description string, eventTypes []string) (Endpoint, error) {
+	if eventTypes == nil {
+		eventTypes = []string{}
+	}
+	row := q.QueryRowContext(ctx, `INSERT INTO webhook_endpoints (user_id, url, description, event_types, secret)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5)
+		RETURNING `+endpointColumns,
+		userID, url, description, pq.Array(eventTypes), NewSecret())
+	return scanEndpoint(row)
+}
+
+// ListEndpoints returns userID's endpoints, newest first.
+func ListEndpoints(ctx context.Context, q queryer, userID int) ([]Endpoint, error) {
+	rows, err := q.QueryContext(ctx, `SELECT `+endpointColumns+` FROM webhook_endpoints
+		WHERE user_id = $1 ORDER BY id DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var endpoints []Endpoint
+	for rows.Next() {
+		e, err := scanEndpoint(rows)
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, e)
+	}
+	return endpoints, rows.Err()
+}
+
+// GetEndpoint returns one of userID's endpoints.
+func GetEndpoint(ctx context.Context, q queryer, userID int, id int64) (Endpoint, error) {
+	return scanEndpoint(q.QueryRowContext(ctx, `SELECT `+endpointColumns+` FROM webhook_endpoints
+		WHERE id = $1 AND user_id = $2`, id, userID))
+}
+
+// EndpointUpdate holds the fields to change; nil fields are left as is.
+type EndpointUpdate struct {
+	URL         *string
+	Description *string
+	EventTypes  *[]string
+	Active      *bool
+}
+
+// UpdateEndpoint applies u to one of userID's endpoints.
+func UpdateEndpoint(ctx context.Context, q queryer, userID int, id int64, u EndpointUpdate) (Endpoint, error) {
+	var eventTypes any
+	if u.EventTypes != nil {
+		types := *u.EventTypes
+		if types == nil {
+			types = []string{}
+		}
+		eventTypes = pq.Array(types)
+	}
+	return scanEndpoint(q.QueryRowContext(ctx, `UPDATE webhook_endpoints SET
+			url = COALESCE($3, url),
+			description = CASE WHEN $4::text IS NULL THEN description ELSE NULLIF($4, '') END,
+			event_types = COALESCE($5, event_types),
+			active = COALESCE($6, active)
+		WHERE id = $1 AND user_id = $2
+		RETURNING `+endpointColumns,
+		id, userID, u.URL, u.Description, eventTypes, u.Active))
+}
+
+// DeleteEndpoint removes one of userID's endpoints and its delivery history.
+func DeleteEndpoint(ctx context.Context, q queryer, userID int, id int64) error {
+	res, err := q.ExecContext(ctx, "DELETE FROM webhook_endpoints WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// RotateSecret replaces the endpoint's signing secret. The old secret keeps
+// signing deliveries alongside the new one for grace.
+func RotateSecret(ctx context.Context, q queryer, userID int, id int64, grace time.Duration) (Endpoint, error) {
+	return scanEndpoint(q.QueryRowContext(ctx, `UPDATE webhook_endpoints SET
+			previous_secret = secret,
+			previous_secret_expires_at = NOW() + make_interval(secs => $3),
+			secret = $4
+		WHERE id = $1 AND user_id = $2
+		RETURNING `+endpointColumns,
+		id, userID, grace.Seconds(), NewSecret()))
+}
+
+// Enqueue schedules e for delivery to endpointID. An event already queued
+// for the endpoint is not queued again; created reports whether a new
+// delivery was made.
+func Enqueue(ctx context.Context, q queryer, endpointID int64, e events.Event) (d Delivery, created bool, err error) {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return Delivery{}, false, err
+	}
+
+	d, err = scanDelivery(q.QueryRowContext(ctx, `INSERT INTO webhook_deliveries
+			(endpoint_id, event_id, event_type, payload)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (endpoint_id, event_id) DO NOTHING
+		RETURNING `+deliveryColumns,
+		endpointID, e.EventID, string(e.Type), body))
+	if errors.Is(err, ErrNotFound) {
+		return Delivery{}, false, nil
+	}
+	if err != nil {
+		return Delivery{}, false, fmt.Errorf("enqueue delivery: %w", err)
+	}
+	return d, true, nil
+}
+
+// DeliveryFilter narrows ListDeliveries; zero fields match everything.
+type DeliveryFilter struct {
+	EndpointID int64
+	Status     DeliveryStatus
+	Limit      int
+}
+
+// ListDeliveries returns userID's deliveries, newest first.
+func ListDeliveries(ctx context.Context, q queryer, userID int, f DeliveryFilter) ([]Delivery, error) {
+	if f.Limit <= 0 || f.Limit > 100 {
+		f.Limit = 50
+	}
+	rows, err := q.QueryContext(ctx, `SELECT `+deliveryColumns+` FROM webhook_deliveries
+		WHERE endpoint_id IN (SELECT id FROM webhook_endpoints WHERE user_id = $1)
+		  AND ($2 = 0 OR endpoint_id = $2)
+		  AND ($3 = '' OR status = $3)
+		ORDER BY id DESC
+		LIMIT $4`, userID, f.EndpointID, string(f.Status), f.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []Delivery
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// GetDelivery returns one of userID's deliveries with its attempts.
+func GetDelivery(ctx context.Context, q queryer, userID int, id int64) (Delivery, []Attempt, error) {
+	d, err := scanDelivery(q.QueryRowContext(ctx, `SELECT `+deliveryColumns+` FROM webhook_deliveries
+		WHERE id = $1 AND endpoint_id IN (SELECT id FROM webhook_endpoints WHERE user_id = $2)`, id, userID))
+	if err != nil {
+		return Delivery{}, nil, err
+	}
+
+	rows, err := q.QueryContext(ctx, `SELECT attempt, response_code, COALESCE(response_body, ''),
+			latency_ms, COALESCE(error, ''), created_at
+		FROM webhook_delivery_attempts WHERE delivery_id = $1 ORDER BY attempt`, id)
+	if err != nil {
+		return Delivery{}, nil, err
+	}
+	defer rows.Close()
+
+	var attempts []Attempt
+	for rows.Next() {
+		var a Attempt
+		if err := rows.Scan(&a.Attempt, &a.ResponseCode, &a.ResponseBody, &a.LatencyMS, &a.Error, &a.CreatedAt); err != nil {
+			return Delivery{}, nil, err
+		}
+		attempts = append(attempts, a)
+	}
+	return d, attempts, rows.Err()
+}